@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/ai"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleAIEval asks the questions of an eval suite about its own history,
+// in a throwaway database, and checks the SQL written for each. The mock
+// provider answers unless live, when the configured model does.
+func handleAIEval(suitePath string, live bool) {
+	suite, err := ai.DefaultEvalSuite()
+	if suitePath != "" {
+		suite, err = ai.LoadEvalSuite(suitePath)
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	var client ai.Querier
+	if live {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		if client, err = ai.NewSQLClient(cfg); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Evaluating %s\n", cfg.GetSQLModel())
+	}
+
+	db, err := storage.Open(storage.MemoryPath)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	results, err := ai.RunEval(db, suite, client, time.Now())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	passed := 0
+	for _, result := range results {
+		if result.Passed() {
+			passed++
+			i18n.Printf("PASS  %s\n", result.Question)
+			continue
+		}
+		i18n.Printf("FAIL  %s\n", result.Question)
+		if result.SQL != "" {
+			i18n.Printf("      SQL: %s\n", result.SQL)
+		}
+		for _, failure := range result.Failures {
+			fmt.Printf("      %s\n", failure)
+		}
+	}
+	i18n.Printf("\n%d of %d passed\n", passed, len(results))
+
+	if passed < len(results) {
+		os.Exit(exitError)
+	}
+}
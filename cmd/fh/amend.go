@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// amendOptions holds the fh --amend flags, nil fields were not given
+type amendOptions struct {
+	id         int64
+	exitCode   *int
+	durationMs *int64
+	note       *string
+}
+
+// handleAmend corrects or enriches an existing entry. The entry is picked with
+// --id, or by session and job for background jobs saved with --save --job,
+// which is how the shell hooks record a finished job.
+// With --id and nothing to change, the entry's amendment history is printed.
+func handleAmend(args []string, opts amendOptions) {
+	if (opts.id == 0 && len(args) != 2) || (opts.id != 0 && len(args) != 0) {
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --amend --id <id> [flags] or fh --amend [flags] <session> <job>\n")
+		os.Exit(exitUsage)
+	}
+
+	var jobID int64
+	if opts.id == 0 {
+		var err error
+		jobID, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || jobID <= 0 {
+			i18n.Fprintf(os.Stderr, "Error: invalid job id: %s\n", args[1])
+			os.Exit(exitUsage)
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// The entry of a job may still wait in the daemon's queue
+	dbPath := cfg.GetDatabasePath()
+	flushDaemon(dbPath)
+
+	// Open database
+	db, err := storage.OpenWithOptions(dbPath, cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	var entry *storage.HistoryEntry
+	if opts.id != 0 {
+		entry, err = db.GetByID(opts.id)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error finding entry %d: %v\n", opts.id, err)
+			os.Exit(exitCodeFor(err))
+		}
+
+		if opts.exitCode == nil && opts.durationMs == nil && opts.note == nil {
+			printAmendments(db, entry)
+			return
+		}
+	} else {
+		entry, err = findJob(db, args[0], jobID)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error finding job %d in session %s: %v\n", jobID, args[0], err)
+			os.Exit(exitCodeFor(err))
+		}
+
+		// A finished job always gets an exit code, and if no duration is
+		// given it has been running since the entry was saved at its start
+		if opts.exitCode == nil {
+			opts.exitCode = new(int)
+		}
+		if opts.durationMs == nil {
+			duration := time.Since(time.Unix(entry.Timestamp, 0)).Milliseconds()
+			if duration < 0 {
+				duration = 0
+			}
+			opts.durationMs = &duration
+		}
+	}
+
+	update := storage.EntryUpdate{
+		ExitCode:   opts.exitCode,
+		DurationMs: opts.durationMs,
+		Note:       opts.note,
+	}
+	if err := db.Update(entry.ID, update); err != nil {
+		i18n.Fprintf(os.Stderr, "Error amending entry: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// jobSaveWait is how long --amend waits for the entry of a job to be saved.
+// The hooks save a command and amend its job from separate background
+// processes, so a job that finishes quickly can be amended first.
+const jobSaveWait = 2 * time.Second
+
+// findJob returns the entry of job in session, waiting up to jobSaveWait for
+// it to be saved
+func findJob(db *storage.DB, session string, job int64) (*storage.HistoryEntry, error) {
+	deadline := time.Now().Add(jobSaveWait)
+	for {
+		entry, err := db.GetByJob(session, job)
+		if err == nil || storage.IsLocked(err) || time.Now().After(deadline) {
+			return entry, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// printAmendments prints an entry and the changes made to it
+func printAmendments(db *storage.DB, entry *storage.HistoryEntry) {
+	amendments, err := db.GetAmendments(entry.ID)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading amendments: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	fmt.Printf("%d  %s\n", entry.ID, entry.Command)
+	if entry.Note != "" {
+		i18n.Printf("    note: %s\n", entry.Note)
+	}
+
+	if len(amendments) == 0 {
+		i18n.Printf("    no amendments\n")
+		return
+	}
+
+	for _, a := range amendments {
+		when := time.Unix(a.AmendedAt, 0).Format("2006-01-02 15:04:05")
+		fmt.Printf("    %s  %s: %q -> %q\n", when, a.Field, a.OldValue, a.NewValue)
+	}
+}
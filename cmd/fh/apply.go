@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+)
+
+// defaultSetupFile is the setup file fh --apply reads when none is given
+const defaultSetupFile = "fh.setup.yaml"
+
+// handleApply reconciles the live config and shell hooks with a setup file.
+// With dryRun the changes are only printed.
+func handleApply(args []string, dryRun bool) {
+	if len(args) > 1 {
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --apply [--dry-run] [setup-file]\n")
+		os.Exit(exitUsage)
+	}
+
+	setupPath := defaultSetupFile
+	if len(args) == 1 {
+		setupPath = args[0]
+	}
+
+	setup, err := config.LoadSetup(setupPath)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	changes, err := setup.Apply(cfg)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error applying %s: %v\n", setupPath, err)
+		os.Exit(exitConfig)
+	}
+
+	prefix := ""
+	if dryRun {
+		prefix = i18n.T("would change ")
+	}
+	for _, c := range changes {
+		fmt.Printf("%s%s: %s -> %s\n", prefix, c.Field, c.Old, c.New)
+	}
+
+	if len(changes) > 0 && !dryRun {
+		configPath, err := config.DefaultPath()
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if err := cfg.Save(configPath); err != nil {
+			i18n.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		config.ClearCache()
+	}
+
+	changed := len(changes) > 0
+	for _, name := range setup.Shells {
+		if applyHook(capture.ShellType(name), cfg.GetKeybinding(), cfg.Search.CwdKeybinding, dryRun) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		i18n.Printf("Nothing to change\n")
+	}
+}
+
+// applyHook makes sure the shell's hooks are installed with the keybindings
+// and reports whether the RC file changed (or would change)
+func applyHook(shell capture.ShellType, keybinding, cwdKeybinding string, dryRun bool) bool {
+	rcFile, err := capture.GetRCFile(shell)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error getting RC file: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if dryRun {
+		installed, err := capture.IsHookInstalled(rcFile)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error checking hooks: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if !installed {
+			i18n.Printf("would install %s hooks in %s\n", shell, rcFile)
+		}
+		return !installed
+	}
+
+	result, err := capture.InstallHook(shell, rcFile, keybinding, cwdKeybinding)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error installing %s hooks: %v\n", shell, err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if result.Installed {
+		i18n.Printf("installed %s hooks in %s (backup: %s)\n", shell, rcFile, result.BackupFile)
+	} else if result.KeybindingUpdate {
+		i18n.Printf("updated %s keybinding to %s in %s (backup: %s)\n", shell, keybinding, rcFile, result.BackupFile)
+	}
+	return result.Installed || result.KeybindingUpdate
+}
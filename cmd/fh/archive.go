@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleArchive moves entries older than years into yearly archive files
+// next to the database
+func handleArchive(years int, profileName string) {
+	if years < 1 {
+		i18n.Fprintf(os.Stderr, "Error: --years must be at least 1\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(selectedProfile(profileName)), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	autoSnapshot(db, "archive")
+
+	archived, err := db.Archive(time.Now().AddDate(-years, 0, 0))
+	for _, year := range archived {
+		i18n.Printf("Archived %d entries from %d to %s\n", year.Entries, year.Year, year.Path)
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error archiving: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if len(archived) == 0 {
+		i18n.Printf("No entries older than %d years\n", years)
+	}
+}
+
+// splitArchivesFlag takes --include-archives out of the words of a search
+func splitArchivesFlag(args []string) (bool, []string) {
+	return splitBoolFlag(args, "--include-archives")
+}
+
+// splitBoolFlag removes the flag name from args, reporting whether it was
+// there, for commands whose other args are a query
+func splitBoolFlag(args []string, name string) (bool, []string) {
+	found := false
+	var rest []string
+	for _, arg := range args {
+		if arg == name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return found, rest
+}
+
+// withArchives returns db, or db together with its yearly archives when
+// include is set, and a function closing the archives
+func withArchives(db *storage.DB, include bool) (storage.Store, func()) {
+	if !include {
+		return db, func() {}
+	}
+
+	archives, err := db.OpenArchives()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening archives: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	return archives, func() {
+		if err := archives.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing archives: %v\n", err)
+		}
+	}
+}
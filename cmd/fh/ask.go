@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/ai"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/search"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"golang.org/x/term"
+)
+
+// pickAnswer offers the entries that answer query in the picker, most
+// relevant first, and prints the selected command the way search does
+func pickAnswer(db *storage.DB, cfg *config.Config, query string, debug bool) {
+	answer, err := ai.AskStructured(db, query, cfg, debug)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	// The answer only has what the model saw, the picker previews it all
+	var entries []*storage.HistoryEntry
+	var reasons []string
+	for _, a := range answer.Entries {
+		entry, err := db.GetByID(a.ID)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+		reasons = append(reasons, a.Reason)
+	}
+	if len(entries) == 0 {
+		i18n.Fprintf(os.Stderr, "No history entries found\n")
+		os.Exit(exitNoResults)
+	}
+
+	search.SetPathDisplay(cfg.DisplayPath())
+	selected, err := search.FzfPick(entries, reasons, cfg.GetSearchCase(), cfg.GetSearchUI())
+	if err != nil {
+		// Cancelling is not an error worth printing
+		code := exitCodeFor(err)
+		if code != exitCancelled {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
+	}
+
+	out := outputOptions{}.withConfig(cfg, term.IsTerminal(int(os.Stdout.Fd())))
+	if err := printCommand(os.Stdout, selected.Command, out); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
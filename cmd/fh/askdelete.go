@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/ai"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleAskDelete deletes the entries of the active profile a request in
+// plain words is about. The model only writes the query finding them; they
+// are shown, and deleted by ID once confirmed.
+func handleAskDelete(request string, yes, debug bool) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	profile := config.ActiveProfile()
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	preview, err := ai.PreviewDelete(db, request, profile, cfg, debug)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	i18n.Printf("Query: %s\n", strings.Join(strings.Fields(preview.SQL), " "))
+	if len(preview.Entries) == 0 {
+		i18n.Fprintf(os.Stderr, "No history entries found\n")
+		os.Exit(exitNoResults)
+	}
+
+	fmt.Println()
+	listDeleted(preview.Entries)
+	confirmDelete(len(preview.Entries), yes)
+
+	autoSnapshot(db, "ask-delete")
+	deleted, err := db.DeleteEntries(preview.IDs())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	i18n.Printf("Deleted %d entries\n", deleted)
+	i18n.Printf("Snapshots, archives and mirrors still have the old commands, see fh --snapshot list\n")
+}
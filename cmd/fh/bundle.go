@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spideyz0r/fh/pkg/bundle"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/export"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleBundle moves a whole fh setup between machines:
+// fh --bundle export <file> and fh --bundle import <file>
+func handleBundle(args []string) {
+	if len(args) != 2 || (args[0] != "export" && args[0] != "import") {
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --bundle export <file> or fh --bundle import <file>\n")
+		os.Exit(exitUsage)
+	}
+
+	var err error
+	if args[0] == "export" {
+		err = exportBundle(args[1])
+	} else {
+		err = importBundle(args[1])
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// exportBundle writes history from every database, the config and the
+// active profile to an encrypted bundle
+func exportBundle(outputPath string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	configPath, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	b := bundle.New(home, config.ActiveProfile())
+
+	b.Config, err = os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	total := 0
+	for key, path := range bundleDatabases(cfg) {
+		var buf bytes.Buffer
+		count, err := exportDatabase(path, &buf)
+		if err != nil {
+			return err
+		}
+		b.History[key] = buf.Bytes()
+		total += count
+	}
+
+	passphrase, err := promptForPassphrase()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if err := b.Encrypt(file, passphrase); err != nil {
+		return err
+	}
+
+	i18n.Fprintf(os.Stderr, "Bundled %d commands from %d databases and the config to %s\n", total, len(b.History), outputPath)
+	return nil
+}
+
+// bundleDatabases returns the database files to bundle, keyed by "" for the
+// main one and by profile name for profiles with their own file.
+// Files that don't exist yet and in-memory databases are left out.
+func bundleDatabases(cfg *config.Config) map[string]string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	databases := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, key := range append([]string{""}, names...) {
+		path := cfg.GetProfileDatabasePath(key)
+		if seen[path] || storage.IsMemoryPath(path) {
+			continue
+		}
+		seen[path] = true
+
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		databases[key] = path
+	}
+	return databases
+}
+
+// exportDatabase writes every entry of a database as JSON
+func exportDatabase(path string, buf *bytes.Buffer) (int, error) {
+	db, err := storage.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	count, err := db.Count()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count entries in %s: %w", path, err)
+	}
+
+	if err := export.Export(db, buf, export.Options{Format: export.FormatJSON}); err != nil {
+		return 0, fmt.Errorf("failed to export %s: %w", path, err)
+	}
+	return int(count), nil
+}
+
+// importBundle restores a bundle: the config (backing up the current one),
+// the active profile, and the history merged into the matching databases
+func importBundle(inputPath string) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	passphrase, err := promptForDecryptPassphrase()
+	if err != nil {
+		return err
+	}
+
+	b, err := bundle.Decrypt(file, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if b.Config != nil {
+		if err := restoreConfig(b); err != nil {
+			return err
+		}
+	}
+
+	if b.Manifest.Profile != "" {
+		if err := config.SetActiveProfile(b.Manifest.Profile); err != nil {
+			return fmt.Errorf("failed to restore profile: %w", err)
+		}
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load restored config: %w", err)
+	}
+
+	keys := make([]string, 0, len(b.History))
+	for key := range b.History {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := cfg.GetProfileDatabasePath(key)
+		count, err := importDatabase(path, b.History[key], cfg)
+		if err != nil {
+			return err
+		}
+		i18n.Fprintf(os.Stderr, "Imported %d commands into %s\n", count, path)
+	}
+
+	return nil
+}
+
+// restoreConfig writes the bundled config over the current one, keeping a
+// .bak copy, with database paths moved to this machine's home directory
+func restoreConfig(b *bundle.Bundle) error {
+	cfg, err := config.Parse(b.Config)
+	if err != nil {
+		return fmt.Errorf("bundled config: %w", err)
+	}
+
+	configPath, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	if current, err := os.ReadFile(configPath); err == nil {
+		if err := os.WriteFile(configPath+".bak", current, 0644); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	// Keep the file as written unless its paths need to move
+	if cfg.RelocateHome(b.Manifest.Home, home) {
+		err = cfg.Save(configPath)
+	} else {
+		if err = os.MkdirAll(filepath.Dir(configPath), 0755); err == nil {
+			err = os.WriteFile(configPath, b.Config, 0644)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	config.ClearCache()
+	i18n.Fprintf(os.Stderr, "Restored config to %s\n", configPath)
+	return nil
+}
+
+// importDatabase merges a bundled JSON export into a database
+func importDatabase(path string, data []byte, cfg *config.Config) (int, error) {
+	db, err := storage.OpenWithOptions(path, cfg.GetStorageOptions())
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	count, err := export.Import(db, bytes.NewReader(data), export.FormatJSON, cfg.GetDedupConfig())
+	if err != nil {
+		return 0, fmt.Errorf("failed to import into %s: %w", path, err)
+	}
+	return count, nil
+}
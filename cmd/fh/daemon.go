@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/daemon"
+	"github.com/spideyz0r/fh/pkg/filter"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// daemonBatch is how many queued saves are written without waiting for
+// the interval
+const daemonBatch = 100
+
+// handleDaemon keeps the database open and saves the entries fh --save and
+// the hooks hand it, every interval, until interrupted
+func handleDaemon(interval time.Duration) {
+	if interval <= 0 {
+		i18n.Fprintf(os.Stderr, "Error: --interval must be positive\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	dbPath := cfg.GetDatabasePath()
+	if storage.IsMemoryPath(dbPath) {
+		i18n.Fprintf(os.Stderr, "Error: an ephemeral session has no database to keep open\n")
+		os.Exit(exitUsage)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(dbPath, cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+	warnForeignHosts(db)
+
+	socket := daemon.SocketPath(dbPath)
+	listener, err := daemon.Listen(socket)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	ignore := ignoreFilter(cfg)
+	server := &daemon.Server{
+		Database: dbPath,
+		Interval: interval,
+		MaxBatch: daemonBatch,
+		Save: func(entries []*storage.HistoryEntry) {
+			// One commit for the batch, whose entries see each other to be
+			// debounced and deduplicated
+			err := db.Transaction(func(tx *storage.DB) error {
+				for _, entry := range entries {
+					if err := saveEntry(cfg, ignore, tx, entry); err != nil {
+						i18n.Fprintf(os.Stderr, "Error saving command: %v\n", err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				i18n.Fprintf(os.Stderr, "Error saving command: %v\n", err)
+			}
+			flushSinkAfterSave(cfg, db)
+			pruneAfterSave(cfg, db)
+		},
+		Build: buildRecord(cfg, ignore, dbPath),
+	}
+
+	// The hooks write their saves to the FIFO instead of starting fh
+	server.FIFO, err = openFIFO()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Warning: the hooks keep using fh --save: %v\n", err)
+	} else {
+		defer func() {
+			_ = server.FIFO.Close()
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Have the database in the page cache for the first search, without
+	// holding up the saves
+	go func() {
+		if _, err := db.WarmUp(ctx); err != nil && ctx.Err() == nil {
+			i18n.Fprintf(os.Stderr, "Error warming up database: %v\n", err)
+		}
+	}()
+
+	i18n.Printf("Saving to %s, listening on %s\n", dbPath, socket)
+	if err := server.Serve(ctx, listener); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// flushDaemon has a daemon saving to dbPath save the entries it was sent,
+// for reads that need the latest saves. Without one there is nothing to
+// wait for.
+func flushDaemon(dbPath string) {
+	if !storage.IsMemoryPath(dbPath) {
+		_ = daemon.Flush(daemon.SocketPath(dbPath), dbPath)
+	}
+}
+
+// openFIFO opens the FIFO of the hooks in fh's directory
+func openFIFO() (*daemon.FIFO, error) {
+	configPath, err := config.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	return daemon.OpenFIFO(dir)
+}
+
+// buildRecord returns the Build of the daemon saving to dbPath, making the
+// entry of a save the hooks wrote to the FIFO as fh --save would have in
+// their shell. A save for another database is saved there right away.
+func buildRecord(cfg *config.Config, ignore *filter.Filter, dbPath string) func(fields map[string]string) *storage.HistoryEntry {
+	return func(fields map[string]string) *storage.HistoryEntry {
+		if fields["cmd"] == "" {
+			return nil
+		}
+		exitCode, _ := strconv.Atoi(fields["exit"])
+		durationMs, _ := strconv.ParseInt(fields["duration"], 10, 64)
+		jobID, _ := strconv.ParseInt(fields["job"], 10, 64)
+
+		meta, err := capture.CollectIn(fields["cwd"], fields["cmd"], exitCode, durationMs)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error collecting metadata: %v\n", err)
+			return nil
+		}
+		if session := fields["session"]; session != "" {
+			meta.SessionID = session
+		}
+		if shell := fields["shell"]; shell != "" {
+			meta.Shell = shell
+		}
+		profile := config.ActiveProfileFor(fields["profile"])
+		entry := newEntry(cfg, meta, profile, jobID)
+
+		path := cfg.ProfileDatabasePathFor(profile, fields["db"])
+		switch {
+		case path == dbPath:
+			return entry
+		case storage.IsMemoryPath(path):
+			// An ephemeral session keeps nothing
+			return nil
+		}
+
+		db, err := storage.OpenWithOptions(path, cfg.GetStorageOptions())
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			return nil
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			}
+		}()
+		if err := saveOpened(cfg, ignore, db, entry); err != nil {
+			i18n.Fprintf(os.Stderr, "Error saving command: %v\n", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/clipboard"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/dashboard"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/search"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleDashboard opens the interactive stats dashboard and prints the
+// command picked through its drill-down, like search does
+func handleDashboard(profileName string, allProfiles bool, program string) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	profile := selectedProfile(profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	// Kept open for the related commands of the dashboard
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entries, err := db.Query(storage.QueryFilters{
+		Profile: profileFilter(profile, allProfiles),
+		Program: program,
+	})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if len(entries) == 0 {
+		i18n.Printf("No commands in history yet.\n")
+		os.Exit(exitNoResults)
+	}
+
+	search.SetPathDisplay(cfg.DisplayPath())
+	board := stats.NewDashboard(entries)
+	board.ShortenDirectories(cfg.DisplayPath())
+	selected, err := dashboard.Run(board, dashboard.Options{
+		Related: relatedFunc(db, defaultRelatedWindow, 50),
+		Copy:    clipboard.Copy,
+		Case:    cfg.GetSearchCase(),
+		UI:      cfg.GetSearchUI(),
+	})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if selected != nil {
+		fmt.Println(selected.Command)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"golang.org/x/term"
+)
+
+// deleteShown is how many of the entries to delete are listed
+const deleteShown = 20
+
+// deleteOptions are the flags of fh delete
+type deleteOptions struct {
+	search      string
+	before      string
+	cwd         string
+	exitCode    string
+	dryRun      bool
+	yes         bool
+	profileName string
+	allProfiles bool
+}
+
+// handleDelete deletes the entries matching the filters, once they are
+// counted and confirmed
+func handleDelete(opts deleteOptions) {
+	if opts.search == "" && opts.before == "" && opts.cwd == "" && opts.exitCode == "" {
+		i18n.Fprintf(os.Stderr, "Error: give at least one of --search, --before, --cwd or --exit-code\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	q, err := query.Parse(opts.search, cfg.QueryOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	profile := selectedProfile(opts.profileName)
+	filters := storage.QueryFilters{Profile: profileFilter(profile, opts.allProfiles)}
+	q.Apply(&filters)
+
+	if opts.before != "" {
+		before, err := parseBefore(opts.before, time.Now())
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// Before is inclusive in the filters
+		if filters.Before == 0 || before.Unix()-1 < filters.Before {
+			filters.Before = before.Unix() - 1
+		}
+	}
+	if opts.cwd != "" {
+		if filters.Cwd, err = filepath.Abs(opts.cwd); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	if opts.exitCode != "" {
+		code, err := strconv.Atoi(opts.exitCode)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: invalid --exit-code %q\n", opts.exitCode)
+			os.Exit(exitUsage)
+		}
+		filters.ExitCode = &code
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entries, err := db.Query(filters)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if len(entries) == 0 {
+		i18n.Fprintf(os.Stderr, "No history entries found\n")
+		os.Exit(exitNoResults)
+	}
+
+	listDeleted(entries)
+	if opts.dryRun {
+		i18n.Printf("Would delete %d entries\n", len(entries))
+		return
+	}
+	confirmDelete(len(entries), opts.yes)
+
+	autoSnapshot(db, "delete")
+	deleted, err := db.DeleteByFilter(filters)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	i18n.Printf("Deleted %d entries\n", deleted)
+	i18n.Printf("Snapshots, archives and mirrors still have the old commands, see fh --snapshot list\n")
+}
+
+// parseBefore parses --before: a date (2025-01-07) is its midnight, a
+// window (30d) that long before now
+func parseBefore(s string, now time.Time) (time.Time, error) {
+	if d, err := query.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --before %q: neither a date (2025-01-07) nor a window (30d)", s)
+	}
+	return day, nil
+}
+
+// listDeleted shows the leading entries about to be deleted
+func listDeleted(entries []*storage.HistoryEntry) {
+	for i, entry := range entries {
+		if i == deleteShown {
+			i18n.Printf("  ... and %d more\n", len(entries)-deleteShown)
+			break
+		}
+		when := time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04")
+		fmt.Printf("  %6d  %s  %s\n", entry.ID, when, entry.Command)
+	}
+	fmt.Println()
+}
+
+// confirmDelete asks before count entries are deleted, unless yes, and
+// exits when the answer isn't yes. Without a terminal to ask on, nothing
+// is deleted without yes.
+func confirmDelete(count int, yes bool) {
+	if yes {
+		return
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		i18n.Fprintf(os.Stderr, "Nothing deleted, pass --yes to delete these %d entries without asking\n", count)
+		os.Exit(exitUsage)
+	}
+	i18n.Printf("Delete these %d entries? [y/N] ", count)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		i18n.Printf("Nothing deleted\n")
+		os.Exit(exitCancelled)
+	}
+}
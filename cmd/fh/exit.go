@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/spideyz0r/fh/pkg/ai"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/search"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Exit codes, listed in the usage text so wrapper scripts can react to the
+// kind of failure. 2 matches what the flag package uses for bad flags.
+const (
+	exitError      = 1   // Any other failure
+	exitUsage      = 2   // Invalid flags or arguments
+	exitConfig     = 3   // Config or setup file can't be loaded or is invalid
+	exitDBLocked   = 4   // Database locked by another process past the busy timeout
+	exitNoResults  = 5   // Nothing to pick from or nothing matched the query
+	exitAIDisabled = 6   // --ask with AI disabled or no API key
+	exitCancelled  = 130 // Search closed without picking (Esc / Ctrl-C)
+)
+
+// exitCodeFor returns the exit code for a failure
+func exitCodeFor(err error) int {
+	switch {
+	case storage.IsLocked(err):
+		return exitDBLocked
+	case errors.Is(err, ai.ErrDisabled), errors.Is(err, ai.ErrNoAPIKey), errors.Is(err, ai.ErrNoAzureCredentials),
+		errors.Is(err, config.ErrOffline):
+		return exitAIDisabled
+	case errors.Is(err, search.ErrNoMatches):
+		return exitNoResults
+	case errors.Is(err, search.ErrCancelled):
+		return exitCancelled
+	default:
+		return exitError
+	}
+}
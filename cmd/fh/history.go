@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleHistoryOf shows when command was first and last run, a timeline of
+// runs per month and where it ran
+func handleHistoryOf(command string, profileName string, allProfiles bool) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	profile := selectedProfile(profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entries, err := db.Query(storage.QueryFilters{
+		Command: command,
+		Profile: profileFilter(profile, allProfiles),
+	})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if len(entries) == 0 {
+		i18n.Printf("No runs of %q in history.\n", command)
+		os.Exit(exitNoResults)
+	}
+
+	lifecycle := stats.NewLifecycle(command, entries)
+	lifecycle.Directories = stats.ShortenDirectories(lifecycle.Directories, cfg.DisplayPath())
+	fmt.Print(lifecycle.Format(5))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// warnForeignHosts tells, loudly, that other machines recently opened the
+// same database file and how to merge their history instead
+func warnForeignHosts(db *storage.DB) {
+	for _, h := range db.ForeignHosts() {
+		i18n.Fprintf(os.Stderr, "WARNING: this history database is also used by %s, last seen %s.\n",
+			h.Hostname, time.Unix(h.LastSeen, 0).Format("2006-01-02 15:04"))
+		i18n.Fprintf(os.Stderr, "Writing one database file from several machines, e.g. through a sync service, corrupts it.\n")
+		i18n.Fprintf(os.Stderr, "Give each machine its own database and merge them instead:\n")
+		i18n.Fprintf(os.Stderr, "  on %s:  fh --export --format json --output %s.json\n", h.Hostname, h.Hostname)
+		i18n.Fprintf(os.Stderr, "  here:    fh --import --input %s.json\n", h.Hostname)
+		i18n.Fprintf(os.Stderr, "If %s no longer uses this file: fh --hosts forget %s\n", h.Hostname, h.Hostname)
+	}
+}
+
+// handleHosts lists the machines that opened the database, or forgets one
+func handleHosts(args []string) {
+	if len(args) > 0 && (args[0] != "forget" || len(args) != 2) {
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --hosts [forget <hostname|id>]\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	if len(args) == 2 {
+		forgotten, err := db.ForgetHost(args[1])
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if forgotten == 0 {
+			i18n.Fprintf(os.Stderr, "No host %s\n", args[1])
+			os.Exit(exitNoResults)
+		}
+		i18n.Printf("Forgot %s\n", args[1])
+		return
+	}
+
+	hosts, err := db.Hosts()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	self := cfg.GetStorageOptions().HostID
+	for _, h := range hosts {
+		marker := " "
+		if h.ID == self {
+			marker = "*"
+		}
+		fmt.Printf("%s %-20s %s  %s  %s\n", marker, h.Hostname,
+			time.Unix(h.FirstSeen, 0).Format("2006-01-02"), time.Unix(h.LastSeen, 0).Format("2006-01-02 15:04"), h.ID)
+	}
+	warnForeignHosts(db)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/filter"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// ignoreFilter compiles the ignore settings of cfg, exiting on an invalid
+// pattern
+func ignoreFilter(cfg *config.Config) *filter.Filter {
+	ignore, err := filter.New(filter.Rules{
+		Patterns: cfg.Ignore.Patterns,
+		Deny:     cfg.Ignore.Deny,
+		Allow:    cfg.Ignore.Allow,
+	})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	return ignore
+}
+
+// skipIgnored reports whether entry matches an ignore pattern of ignore.
+// Matching entries are logged to the ignored table instead of the history,
+// so they can be reviewed with fh --ignored.
+func skipIgnored(ignore *filter.Filter, db *storage.DB, entry *storage.HistoryEntry) bool {
+	matches := ignore.Matches(entry.Command)
+	if len(matches) == 0 {
+		return false
+	}
+
+	ignored := &storage.IgnoredCommand{
+		Timestamp: entry.Timestamp,
+		Command:   entry.Command,
+		Cwd:       entry.Cwd,
+		Pattern:   matches[0],
+		Profile:   entry.Profile,
+	}
+	if err := db.LogIgnored(ignored); err != nil {
+		i18n.Fprintf(os.Stderr, "Error logging ignored command: %v\n", err)
+	}
+	return true
+}
+
+// handleTestIgnore prints the configured ignore patterns matching a command
+func handleTestIgnore(args []string) {
+	command := strings.Join(args, " ")
+	if command == "" {
+		i18n.Fprintf(os.Stderr, "Error: command required for --test-ignore\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	matches := ignoreFilter(cfg).Matches(command)
+	if len(matches) == 0 {
+		i18n.Printf("%q would be saved, no ignore pattern matches\n", command)
+		return
+	}
+
+	i18n.Printf("%q would be ignored, matched by:\n", command)
+	for _, pattern := range matches {
+		fmt.Printf("    %q\n", pattern)
+	}
+}
+
+// handleIgnored lists the commands skipped by ignore patterns in a time window
+func handleIgnored(since string, profileName string, allProfiles bool) {
+	window, err := parseWindow("--since", since)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	profile := selectedProfile(profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	ignored, err := db.GetIgnored(time.Now().Add(-window).Unix(), profileFilter(profile, allProfiles))
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying ignored commands: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if len(ignored) == 0 {
+		i18n.Printf("No commands ignored in the last %s.\n", since)
+		return
+	}
+
+	for _, c := range ignored {
+		when := time.Unix(c.Timestamp, 0).Format("2006-01-02 15:04:05")
+		fmt.Printf("%s  %-14q  %s\n", when, c.Pattern, c.Command)
+	}
+}
+
+// parseWindow parses the value of flag as a time window such as 30m, 12h,
+// 1d or 2w
+func parseWindow(flag, since string) (time.Duration, error) {
+	window, err := query.ParseDuration(since)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q (e.g. 30m, 12h, 1d, 2w)", flag, since)
+	}
+	return window, nil
+}
@@ -2,19 +2,28 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/spideyz0r/fh/pkg/ai"
 	"github.com/spideyz0r/fh/pkg/capture"
 	"github.com/spideyz0r/fh/pkg/config"
 	"github.com/spideyz0r/fh/pkg/crypto"
+	"github.com/spideyz0r/fh/pkg/daemon"
 	"github.com/spideyz0r/fh/pkg/export"
+	"github.com/spideyz0r/fh/pkg/filter"
+	"github.com/spideyz0r/fh/pkg/i18n"
 	"github.com/spideyz0r/fh/pkg/importer"
+	"github.com/spideyz0r/fh/pkg/paths"
+	"github.com/spideyz0r/fh/pkg/query"
 	"github.com/spideyz0r/fh/pkg/search"
 	"github.com/spideyz0r/fh/pkg/stats"
 	"github.com/spideyz0r/fh/pkg/storage"
@@ -31,23 +40,146 @@ func main() {
 	saveCommand := saveCmd.String("cmd", "", "Command to save")
 	saveExitCode := saveCmd.Int("exit-code", 0, "Exit code of the command")
 	saveDuration := saveCmd.Int64("duration", 0, "Duration in milliseconds")
+	saveJob := saveCmd.Int64("job", 0, "PID of the background job the command started")
+
+	amendCmd := flag.NewFlagSet("amend", flag.ExitOnError)
+	amendID := amendCmd.Int64("id", 0, "ID of the entry to amend")
+	amendExitCode := amendCmd.Int("exit-code", 0, "New exit code")
+	amendDuration := amendCmd.Int64("duration", 0, "New duration in milliseconds")
+	amendNote := amendCmd.String("note", "", "Note to attach to the entry")
 
 	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
-	exportFormat := exportCmd.String("format", "text", "Export format (text, json, csv)")
+	exportFormat := exportCmd.String("format", "text", "Export format (text, json, jsonl, csv, zsh, bash, binary, ipynb, parquet)")
 	exportOutput := exportCmd.String("output", "-", "Output file (- for stdout)")
-	exportSearch := exportCmd.String("search", "", "Filter by search term")
+	exportSearch := exportCmd.String("search", "", "Filter by search query (e.g. exit:!0 since:3d cwd:infra kubectl)")
 	exportLimit := exportCmd.Int("limit", 0, "Limit number of results (0 = unlimited)")
 	exportEncrypt := exportCmd.Bool("encrypt", false, "Encrypt the export with a passphrase")
+	exportAllProfiles := exportCmd.Bool("all-profiles", false, "Export entries from every profile")
+	exportProfile := exportCmd.String("profile", "", "Export this profile instead of the active one")
+	exportContainer := exportCmd.String("container", "", "Only export commands run inside this container or pod")
+	exportProgram := exportCmd.String("program", "", "Only export commands whose primary program is this one")
+	exportPrint0 := exportCmd.Bool("print0", false, "End each command with NUL instead of a newline (text format)")
+	exportQuote := exportCmd.Bool("quote", false, "Shell-quote each command (text format)")
+	exportRawPaths := exportCmd.Bool("raw-paths", false, "Write directories as stored, without ~ and path aliases")
+	exportSince := exportCmd.String("since", "", "last: only export entries saved since the last --since last export of the same kind")
 
 	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
-	importFormat := importCmd.String("format", "auto", "Import format (auto, text, json, csv)")
+	importFormat := importCmd.String("format", "auto", "Import format (auto, text, json, jsonl, csv, zsh, bash, binary)")
 	importInput := importCmd.String("input", "-", "Input file (- for stdin)")
 	importDecrypt := importCmd.Bool("decrypt", false, "Decrypt the import with a passphrase")
+	importIncludeIgnored := importCmd.Bool("include-ignored", false, "Import commands matching ignore patterns too")
+
+	topCmd := flag.NewFlagSet("top", flag.ExitOnError)
+	topLimit := topCmd.Int("limit", 20, "Number of commands to show")
+	topHalfLife := topCmd.Float64("half-life", -1, "Decay half-life in days (0 = no decay, default from config)")
+	topAllProfiles := topCmd.Bool("all-profiles", false, "Rank commands from every profile")
+	topProfile := topCmd.String("profile", "", "Rank this profile instead of the active one")
+
+	statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+	statsAllProfiles := statsCmd.Bool("all-profiles", false, "Include entries from every profile")
+	statsProfile := statsCmd.String("profile", "", "Show this profile instead of the active one")
+	statsProgram := statsCmd.String("program", "", "Only include commands whose primary program is this one")
+	statsCompare := statsCmd.String("compare", "", "Compare the last period (e.g. 1d, 1w) with the one before it")
+	statsFast := statsCmd.Bool("fast", false, "Read the counters kept on write instead of every entry")
+	statsIncludeArchives := statsCmd.Bool("include-archives", false, "Include entries moved to yearly archives")
+	statsRawPaths := statsCmd.Bool("raw-paths", false, "Show directories as stored, without ~ and path aliases")
+	statsExactDirs := statsCmd.Bool("exact-dirs", false, "Count each directory on its own instead of by git repository")
+
+	archiveCmd := flag.NewFlagSet("archive", flag.ExitOnError)
+	archiveYears := archiveCmd.Int("years", 2, "Archive entries older than this many years")
+	archiveProfile := archiveCmd.String("profile", "", "Archive the database of this profile instead of the active one")
+
+	pruneCmd := flag.NewFlagSet("prune", flag.ExitOnError)
+	pruneDryRun := pruneCmd.Bool("dry-run", false, "Count what would be pruned without removing it")
+	pruneProfile := pruneCmd.String("profile", "", "Prune the database of this profile instead of the active one")
+
+	recountCmd := flag.NewFlagSet("recount", flag.ExitOnError)
+	recountProfile := recountCmd.String("profile", "", "Recount the database of this profile instead of the active one")
+
+	timerCmd := flag.NewFlagSet("install-timer", flag.ExitOnError)
+	timerPrint := timerCmd.Bool("print", false, "Show the files instead of installing them")
+	timerRemove := timerCmd.Bool("remove", false, "Stop the job and delete its files")
+
+	scanCmd := flag.NewFlagSet("security-scan", flag.ExitOnError)
+	scanMinRisk := scanCmd.String("min-risk", "low", "Only report findings of this risk or higher: low, medium or high")
+	scanRedact := scanCmd.Bool("redact", false, "Mask the findings without asking")
+	scanDelete := scanCmd.Bool("delete", false, "Delete the entries with findings without asking")
+	scanProfile := scanCmd.String("profile", "", "Scan the database of this profile instead of the active one")
+
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyProfile := verifyCmd.String("profile", "", "Verify the database of this profile instead of the active one")
+
+	mirrorCmd := flag.NewFlagSet("mirror", flag.ExitOnError)
+	mirrorEvery := mirrorCmd.Duration("every", 0, "Keep refreshing the mirror at this interval (e.g. 5m)")
+	mirrorProfile := mirrorCmd.String("profile", "", "Mirror the database of this profile instead of the active one")
+
+	dashboardCmd := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	dashboardAllProfiles := dashboardCmd.Bool("all-profiles", false, "Include entries from every profile")
+	dashboardProfile := dashboardCmd.String("profile", "", "Show this profile instead of the active one")
+	dashboardProgram := dashboardCmd.String("program", "", "Only include commands whose primary program is this one")
+
+	historyOfCmd := flag.NewFlagSet("history-of", flag.ExitOnError)
+	historyOfAllProfiles := historyOfCmd.Bool("all-profiles", false, "Include entries from every profile")
+	historyOfProfile := historyOfCmd.String("profile", "", "Show this profile instead of the active one")
+
+	variantsCmd := flag.NewFlagSet("variants", flag.ExitOnError)
+	variantsLimit := variantsCmd.Int("limit", 10, "Number of groups to show (0 = all)")
+	variantsAllProfiles := variantsCmd.Bool("all-profiles", false, "Include commands from every profile")
+	variantsProfile := variantsCmd.String("profile", "", "Use this profile instead of the active one")
+
+	relatedCmd := flag.NewFlagSet("related", flag.ExitOnError)
+	relatedWindow := relatedCmd.String("window", "5m", "How close in time related commands ran (e.g. 5m, 1h)")
+	relatedLimit := relatedCmd.Int("limit", 20, "Number of commands to show (0 = all)")
+
+	showCmd := flag.NewFlagSet("show", flag.ExitOnError)
+	showJSON := showCmd.Bool("json", false, "Print the entry as JSON")
+
+	prevCmd := flag.NewFlagSet("prev", flag.ExitOnError)
+	prevSession := prevCmd.String("session", os.Getenv(capture.SessionEnv), "Shell session to go back in (default: $FH_SESSION)")
+	prevIndex := prevCmd.Int("index", 1, "How many commands to go back, 1 is the last one")
+	prevProfile := prevCmd.String("profile", "", "Use this profile instead of the active one")
+
+	remindCmd := flag.NewFlagSet("remind", flag.ExitOnError)
+	remindDaily := remindCmd.Bool("daily", false, "Only with remind.daily on, once a day (for the shell hooks)")
+	remindProfile := remindCmd.String("profile", "", "Use this profile instead of the active one")
+
+	runbookCmd := flag.NewFlagSet("runbook", flag.ExitOnError)
+	runbookFrom := runbookCmd.String("from", "", "Start of the window (e.g. \"2025-01-07 14:00\")")
+	runbookTo := runbookCmd.String("to", "", "End of the window, a time alone is on the day of --from (default: now)")
+	runbookCwd := runbookCmd.String("cwd", "", "Only commands run in this directory or below it")
+	runbookOutput := runbookCmd.String("output", "-", "Output file (- for stdout)")
+	runbookAllProfiles := runbookCmd.Bool("all-profiles", false, "Include commands from every profile")
+	runbookProfile := runbookCmd.String("profile", "", "Use this profile instead of the active one")
+
+	ignoredCmd := flag.NewFlagSet("ignored", flag.ExitOnError)
+	ignoredSince := ignoredCmd.String("since", "1d", "Time window to show (e.g. 30m, 12h, 1d, 2w)")
+	ignoredAllProfiles := ignoredCmd.Bool("all-profiles", false, "Include commands from every profile")
+	ignoredProfile := ignoredCmd.String("profile", "", "Show this profile instead of the active one")
+
+	daemonCmd := flag.NewFlagSet("daemon", flag.ExitOnError)
+	daemonInterval := daemonCmd.Duration("interval", time.Second, "How long saves wait to be written together")
+
+	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
+	deleteSearch := deleteCmd.String("search", "", "Only entries matching this query (same syntax as search)")
+	deleteBefore := deleteCmd.String("before", "", "Only entries run before a date (2025-01-07) or a window ago (30d)")
+	deleteCwd := deleteCmd.String("cwd", "", "Only entries run in this directory")
+	deleteExitCode := deleteCmd.String("exit-code", "", "Only entries that exited with this code")
+	deleteDryRun := deleteCmd.Bool("dry-run", false, "Show what would be deleted without deleting it")
+	deleteYes := deleteCmd.Bool("yes", false, "Delete without asking")
+	deleteProfile := deleteCmd.String("profile", "", "Use this profile instead of the active one")
+	deleteAllProfiles := deleteCmd.Bool("all-profiles", false, "Delete from every profile")
+
+	aiEvalCmd := flag.NewFlagSet("ai-eval", flag.ExitOnError)
+	aiEvalSuite := aiEvalCmd.String("suite", "", "YAML eval suite to run instead of the built-in one")
+	aiEvalLive := aiEvalCmd.Bool("live", false, "Ask the configured model instead of the mock provider")
+
+	applyCmd := flag.NewFlagSet("apply", flag.ExitOnError)
+	applyDryRun := applyCmd.Bool("dry-run", false, "Show what would change without changing it")
 
 	// Check if we have arguments
 	if len(os.Args) < 2 {
 		// No arguments - launch FZF search
-		handleSearch("")
+		handleSearch("", false, "", "", false, false, outputOptions{})
 		return
 	}
 
@@ -55,49 +187,403 @@ func main() {
 	switch os.Args[1] {
 	case "--save", "save":
 		if err := saveCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing save flags: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error parsing save flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleSave(*saveCommand, *saveExitCode, *saveDuration, *saveJob)
+
+	case "--daemon", "daemon":
+		if err := daemonCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing daemon flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleDaemon(*daemonInterval)
+
+	case "--ai-eval", "ai-eval":
+		if err := aiEvalCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing ai-eval flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleAIEval(*aiEvalSuite, *aiEvalLive)
+
+	case "--amend", "amend":
+		if err := amendCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing amend flags: %v\n", err)
+			os.Exit(exitUsage)
 		}
-		handleSave(*saveCommand, *saveExitCode, *saveDuration)
+		// Only flags that were given change the entry
+		opts := amendOptions{id: *amendID}
+		amendCmd.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "exit-code":
+				opts.exitCode = amendExitCode
+			case "duration":
+				opts.durationMs = amendDuration
+			case "note":
+				opts.note = amendNote
+			}
+		})
+		handleAmend(amendCmd.Args(), opts)
 
 	case "--init":
 		handleInit()
 
+	case "--import-sessions", "import-sessions":
+		handleImportSessions()
+
+	case "--apply", "apply":
+		if err := applyCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing apply flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleApply(applyCmd.Args(), *applyDryRun)
+
+	case "--capture-osc133":
+		handleCaptureOSC133()
+
+	case "--ssh", "ssh":
+		handleSSH(os.Args[2:])
+
 	case "--stats":
-		handleStats()
+		if err := statsCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing stats flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleStats(*statsProfile, *statsAllProfiles, *statsProgram, *statsCompare, *statsFast, *statsIncludeArchives, *statsRawPaths, *statsExactDirs)
+
+	case "--archive", "archive":
+		if err := archiveCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing archive flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleArchive(*archiveYears, *archiveProfile)
+
+	case "--prune", "prune":
+		if err := pruneCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing prune flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handlePrune(*pruneDryRun, *pruneProfile)
+
+	case "--recount", "recount":
+		if err := recountCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing recount flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleRecount(*recountProfile)
+
+	case "--mirror", "mirror":
+		if err := mirrorCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing mirror flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the path
+		args := mirrorCmd.Args()
+		if len(args) > 0 {
+			if err := mirrorCmd.Parse(args[1:]); err != nil {
+				i18n.Fprintf(os.Stderr, "Error parsing mirror flags: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+		if len(args) == 0 || mirrorCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --mirror <path> [--every 5m] [--profile name]\n")
+			os.Exit(exitUsage)
+		}
+		handleMirror(args[0], *mirrorEvery, *mirrorProfile)
+
+	case "--security-scan", "security-scan":
+		if err := scanCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing security-scan flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if scanCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --security-scan [--min-risk low|medium|high] [--redact | --delete] [--profile name]\n")
+			os.Exit(exitUsage)
+		}
+		handleSecurityScan(*scanMinRisk, *scanRedact, *scanDelete, *scanProfile)
+
+	case "--verify", "verify":
+		if err := verifyCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing verify flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if verifyCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --verify [--profile name]\n")
+			os.Exit(exitUsage)
+		}
+		handleVerify(*verifyProfile)
+
+	case "--dashboard", "dashboard":
+		if err := dashboardCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing dashboard flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleDashboard(*dashboardProfile, *dashboardAllProfiles, *dashboardProgram)
+
+	case "--history-of":
+		if err := historyOfCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing history-of flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if historyOfCmd.NArg() == 0 {
+			i18n.Fprintf(os.Stderr, "Error: command required for --history-of\n")
+			os.Exit(exitUsage)
+		}
+		handleHistoryOf(strings.Join(historyOfCmd.Args(), " "), *historyOfProfile, *historyOfAllProfiles)
+
+	case "--variants", "variants":
+		if err := variantsCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing variants flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleVariants(strings.Join(variantsCmd.Args(), " "), *variantsLimit, *variantsProfile, *variantsAllProfiles)
+
+	case "--related", "related":
+		if err := relatedCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing related flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the id
+		args := relatedCmd.Args()
+		if len(args) > 0 {
+			if err := relatedCmd.Parse(args[1:]); err != nil {
+				i18n.Fprintf(os.Stderr, "Error parsing related flags: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+		if len(args) == 0 || relatedCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --related <id> [--window 5m] [--limit 20]\n")
+			os.Exit(exitUsage)
+		}
+		handleRelated(args[0], *relatedWindow, *relatedLimit)
+
+	case "--show", "show":
+		if err := showCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing show flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the id
+		args := showCmd.Args()
+		if len(args) > 0 {
+			if err := showCmd.Parse(args[1:]); err != nil {
+				i18n.Fprintf(os.Stderr, "Error parsing show flags: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+		if len(args) == 0 || showCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --show <id> [--json]\n")
+			os.Exit(exitUsage)
+		}
+		handleShow(args[0], *showJSON)
+
+	case "--prev", "prev":
+		if err := prevCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing prev flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handlePrev(*prevSession, *prevIndex, *prevProfile)
+
+	case "--remind", "remind":
+		if err := remindCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing remind flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleRemind(*remindDaily, *remindProfile)
+
+	case "--runbook", "runbook":
+		if err := runbookCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing runbook flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleRunbook(runbookOptions{
+			from:        *runbookFrom,
+			to:          *runbookTo,
+			cwd:         *runbookCwd,
+			output:      *runbookOutput,
+			profileName: *runbookProfile,
+			allProfiles: *runbookAllProfiles,
+		})
+
+	case "--test-ignore":
+		handleTestIgnore(os.Args[2:])
+
+	case "ignore":
+		if len(os.Args) < 3 || os.Args[2] != "test" {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh ignore test <cmd>\n")
+			os.Exit(exitUsage)
+		}
+		handleTestIgnore(os.Args[3:])
+
+	case "--ignored":
+		if err := ignoredCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing ignored flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleIgnored(*ignoredSince, *ignoredProfile, *ignoredAllProfiles)
+
+	case "--top", "top":
+		if err := topCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing top flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleTop(*topLimit, *topHalfLife, *topProfile, *topAllProfiles)
+
+	case "--profile", "profile":
+		handleProfile(os.Args[2:])
+
+	case "--hosts", "hosts":
+		handleHosts(os.Args[2:])
+
+	case "--snapshot", "snapshot":
+		handleSnapshot(os.Args[2:])
+
+	case "--sink", "sink":
+		handleSink(os.Args[2:])
+
+	case "--install-timer", "install-timer":
+		if err := timerCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing install-timer flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		// Flags may also follow the job
+		args := timerCmd.Args()
+		if len(args) > 0 {
+			if err := timerCmd.Parse(args[1:]); err != nil {
+				i18n.Fprintf(os.Stderr, "Error parsing install-timer flags: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+		if len(args) == 0 || timerCmd.NArg() > 0 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --install-timer archive|backup|prune|sync [--print] [--remove]\n")
+			os.Exit(exitUsage)
+		}
+		handleInstallTimer(args[0], *timerPrint, *timerRemove)
+
+	case "--run-job", "run-job":
+		if len(os.Args) != 3 {
+			i18n.Fprintf(os.Stderr, "Error: usage: fh --run-job archive|backup|prune|sync\n")
+			os.Exit(exitUsage)
+		}
+		handleRunJob(os.Args[2])
+
+	case "--all-profiles":
+		// Search every profile, remaining args are the query
+		out, args := splitOutputFlags(os.Args[2:])
+		archives, args := splitArchivesFlag(args)
+		cwdOnly, args := splitBoolFlag(args, "--cwd-only")
+		handleSearch(strings.Join(args, " "), true, "", "", archives, cwdOnly, out)
+
+	case "--container":
+		if len(os.Args) < 3 {
+			i18n.Fprintf(os.Stderr, "Error: container or pod name required for --container\n")
+			os.Exit(exitUsage)
+		}
+		// Search commands exec'd into a container or pod, remaining args are the query
+		out, args := splitOutputFlags(os.Args[3:])
+		archives, args := splitArchivesFlag(args)
+		cwdOnly, args := splitBoolFlag(args, "--cwd-only")
+		handleSearch(strings.Join(args, " "), false, os.Args[2], "", archives, cwdOnly, out)
+
+	case "--program":
+		if len(os.Args) < 3 {
+			i18n.Fprintf(os.Stderr, "Error: program name required for --program\n")
+			os.Exit(exitUsage)
+		}
+		// Search commands run by one program, remaining args are the query
+		out, args := splitOutputFlags(os.Args[3:])
+		archives, args := splitArchivesFlag(args)
+		cwdOnly, args := splitBoolFlag(args, "--cwd-only")
+		handleSearch(strings.Join(args, " "), false, "", os.Args[2], archives, cwdOnly, out)
 
 	case "--ask":
 		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Error: query required for --ask\n")
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error: query required for --ask\n")
+			os.Exit(exitUsage)
 		}
-		// Check for --debug flag
-		debug := false
+		// Check for --debug, --json, --pick and --no-footer flags
+		debug, asJSON, pick, noFooter := false, false, false, false
 		args := os.Args[2:]
-		if len(args) > 0 && args[0] == "--debug" {
-			debug = true
+	flags:
+		for len(args) > 0 {
+			switch args[0] {
+			case "--debug":
+				debug = true
+			case "--json":
+				asJSON = true
+			case "--pick":
+				pick = true
+			case "--no-footer":
+				noFooter = true
+			default:
+				break flags
+			}
 			args = args[1:]
 		}
 		if len(args) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: query required for --ask\n")
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error: query required for --ask\n")
+			os.Exit(exitUsage)
+		}
+		if asJSON && pick {
+			i18n.Fprintf(os.Stderr, "Error: --json and --pick cannot be combined\n")
+			os.Exit(exitUsage)
 		}
 		query := strings.Join(args, " ")
-		handleAsk(query, debug)
+		handleAsk(query, debug, asJSON, pick, noFooter)
+
+	case "--delete", "delete":
+		if err := deleteCmd.Parse(os.Args[2:]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error parsing delete flags: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		handleDelete(deleteOptions{
+			search:      *deleteSearch,
+			before:      *deleteBefore,
+			cwd:         *deleteCwd,
+			exitCode:    *deleteExitCode,
+			dryRun:      *deleteDryRun,
+			yes:         *deleteYes,
+			profileName: *deleteProfile,
+			allProfiles: *deleteAllProfiles,
+		})
+
+	case "--ask-delete":
+		// Check for --yes and --debug flags
+		yes, debug := false, false
+		args := os.Args[2:]
+	deleteFlags:
+		for len(args) > 0 {
+			switch args[0] {
+			case "--yes":
+				yes = true
+			case "--debug":
+				debug = true
+			default:
+				break deleteFlags
+			}
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			i18n.Fprintf(os.Stderr, "Error: request required for --ask-delete\n")
+			os.Exit(exitUsage)
+		}
+		handleAskDelete(strings.Join(args, " "), yes, debug)
 
 	case "--export", "export":
 		if err := exportCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing export flags: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error parsing export flags: %v\n", err)
+			os.Exit(exitUsage)
 		}
-		handleExport(*exportFormat, *exportOutput, *exportSearch, *exportLimit, *exportEncrypt)
+		handleExport(*exportFormat, *exportOutput, *exportSearch, *exportLimit, *exportEncrypt, *exportProfile, *exportAllProfiles, *exportContainer, *exportProgram, *exportRawPaths, *exportSince, outputOptions{print0: *exportPrint0, quote: *exportQuote})
 
 	case "--import", "import":
 		if err := importCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing import flags: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error parsing import flags: %v\n", err)
+			os.Exit(exitUsage)
 		}
-		handleImport(*importFormat, *importInput, *importDecrypt)
+		handleImport(*importFormat, *importInput, *importDecrypt, *importIncludeIgnored)
+
+	case "--bundle", "bundle":
+		handleBundle(os.Args[2:])
 
 	case "--version", "-v":
 		fmt.Printf("fh version %s\n", version)
@@ -107,253 +593,443 @@ func main() {
 
 	default:
 		// Anything else is treated as a search query
-		query := strings.Join(os.Args[1:], " ")
-		handleSearch(query)
+		out, args := splitOutputFlags(os.Args[1:])
+		archives, args := splitArchivesFlag(args)
+		cwdOnly, args := splitBoolFlag(args, "--cwd-only")
+		handleSearch(strings.Join(args, " "), false, "", "", archives, cwdOnly, out)
 	}
 }
 
-func handleSave(command string, exitCode int, durationMs int64) {
+func handleSave(command string, exitCode int, durationMs int64, jobID int64) {
 	if command == "" {
-		fmt.Fprintf(os.Stderr, "Error: --cmd is required\n")
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error: --cmd is required\n")
+		os.Exit(exitUsage)
 	}
 
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
 	}
 
 	// Collect metadata
 	meta, err := capture.Collect(command, exitCode, durationMs)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error collecting metadata: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error collecting metadata: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	entry := newEntry(cfg, meta, config.ActiveProfile(), jobID)
+
+	// A running daemon saves it with the database already open. Once it
+	// may have queued the entry, saving it here too would save it twice.
+	dbPath := cfg.GetDatabasePath()
+	if !storage.IsMemoryPath(dbPath) {
+		err := daemon.Send(daemon.SocketPath(dbPath), dbPath, entry)
+		if err == nil || errors.Is(err, daemon.ErrNoReply) {
+			return
+		}
 	}
 
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(dbPath, cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
-	// Create history entry
+	if err := saveOpened(cfg, ignoreFilter(cfg), db, entry); err != nil {
+		i18n.Fprintf(os.Stderr, "Error saving command: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	// Success - silent exit (important for shell hooks)
+}
+
+// newEntry makes the history entry of a command run as meta describes, in
+// profile, with its secrets masked
+func newEntry(cfg *config.Config, meta *capture.Metadata, profile string, jobID int64) *storage.HistoryEntry {
 	entry := &storage.HistoryEntry{
-		Timestamp:  meta.Timestamp,
-		Command:    meta.Command,
-		Cwd:        meta.Cwd,
-		ExitCode:   meta.ExitCode,
-		Hostname:   meta.Hostname,
-		User:       meta.User,
-		Shell:      meta.Shell,
-		DurationMs: meta.DurationMs,
-		GitBranch:  meta.GitBranch,
-		SessionID:  meta.SessionID,
-	}
-
-	// Get deduplication config
-	dedupConfig := cfg.GetDedupConfig()
+		Timestamp:   meta.Timestamp,
+		Command:     meta.Command,
+		Cwd:         cfg.PathNormalizer().Normalize(meta.Cwd),
+		ExitCode:    meta.ExitCode,
+		Hostname:    meta.Hostname,
+		User:        meta.User,
+		Shell:       meta.Shell,
+		DurationMs:  meta.DurationMs,
+		GitBranch:   meta.GitBranch,
+		SessionID:   meta.SessionID,
+		Profile:     profile,
+		ExecRuntime: meta.ExecRuntime,
+		ExecTarget:  meta.ExecTarget,
+		JobID:       jobID,
+	}
 
-	// Insert with deduplication
-	if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving command: %v\n", err)
-		os.Exit(1)
+	// Secrets after flags like --password are masked before anything, the
+	// ignored log included, sees the command
+	entry.Redact()
+	return entry
+}
+
+// saveOpened saves entry in db, opened for it alone, and does what follows
+// a save: the sink and pruning
+func saveOpened(cfg *config.Config, ignore *filter.Filter, db *storage.DB, entry *storage.HistoryEntry) error {
+	// Another machine writing the same file is worth interrupting for
+	warnForeignHosts(db)
+
+	if err := saveEntry(cfg, ignore, db, entry); err != nil {
+		return err
 	}
 
-	// Success - silent exit (important for shell hooks)
+	flushSinkAfterSave(cfg, db)
+	pruneAfterSave(cfg, db)
+	return nil
+}
+
+// saveEntry saves entry unless ignore matches it or it is debounced,
+// deduplicating it as configured
+func saveEntry(cfg *config.Config, ignore *filter.Filter, db *storage.DB, entry *storage.HistoryEntry) error {
+	// Commands matching an ignore pattern are only logged
+	if skipIgnored(ignore, db, entry) {
+		return nil
+	}
+
+	// Identical saves in quick succession collapse into the first one
+	if skipDebounced(cfg, db, entry) {
+		return nil
+	}
+
+	// Insert with deduplication
+	return db.InsertWithDedup(entry, cfg.GetDedupConfig())
 }
 
-func handleSearch(query string) {
+// skipDebounced reports whether entry repeats a save made within the
+// configured debounce window. A failed check lets the save go ahead.
+func skipDebounced(cfg *config.Config, db *storage.DB, entry *storage.HistoryEntry) bool {
+	debounced, err := db.IsDebounced(entry, cfg.GetDebounce())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error checking recent saves: %v\n", err)
+		return false
+	}
+	return debounced
+}
+
+func handleSearch(queryText string, allProfiles bool, container, program string, includeArchives, cwdOnly bool, out outputOptions) {
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Parse the pre-filter before opening the picker
+	var filter *query.Query
+	if queryText != "" {
+		filter, err = query.Parse(queryText, cfg.QueryOptions())
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
 	}
 
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
+	warnForeignHosts(db)
 
 	// Search history with configured limit and deduplication
 	filters := storage.QueryFilters{
-		Limit:    cfg.Search.Limit,
-		Distinct: cfg.Search.Deduplicate,
+		Limit:      cfg.Search.Limit,
+		Distinct:   cfg.Search.Deduplicate,
+		Profile:    profileFilter(config.ActiveProfile(), allProfiles),
+		ExecTarget: container,
+		Program:    program,
 	}
-	entries, err := search.WithFilters(db, filters)
+
+	// Only what ran in the current directory or below it, as it would be
+	// saved from here
+	if cwdOnly {
+		cwd, err := os.Getwd()
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		filters.CwdUnder = cfg.PathNormalizer().Normalize(cwd)
+	}
+
+	// Frecency needs every run to score commands, so limit after ranking
+	frecency := cfg.Search.Ranking == search.RankFrecency
+	if frecency {
+		filters = storage.QueryFilters{Profile: filters.Profile, ExecTarget: filters.ExecTarget, Program: filters.Program, CwdUnder: filters.CwdUnder}
+	}
+
+	store, closeArchives := withArchives(db, includeArchives)
+	defer closeArchives()
+
+	entries, err := search.WithFilters(store, filters)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error searching history: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error searching history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if frecency {
+		entries = search.ByFrecency(entries, search.RankOptions{HalfLife: cfg.GetHalfLife()}, cfg.Search.Limit)
 	}
 
 	if len(entries) == 0 {
-		fmt.Fprintf(os.Stderr, "No history entries found\n")
-		os.Exit(0)
+		i18n.Fprintf(os.Stderr, "No history entries found\n")
+		os.Exit(exitNoResults)
 	}
 
 	// Launch FZF
-	selected, err := search.FzfSearch(entries, query)
+	search.SetPathDisplay(cfg.DisplayPath())
+	selected, err := search.FzfSearch(entries, filter, cfg.GetSearchCase(), cfg.GetSearchUI())
 	if err != nil {
-		// User canceled or error - exit silently
-		os.Exit(0)
+		// Cancelling is not an error worth printing
+		code := exitCodeFor(err)
+		if code != exitCancelled {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
 	}
 
 	// Print selected command to stdout
-	fmt.Println(selected.Command)
+	out = out.withConfig(cfg, term.IsTerminal(int(os.Stdout.Fd())))
+	if err := printCommand(os.Stdout, selected.Command, out); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
 }
 
 func handleInit() {
-	fmt.Println("fh - Fast History Setup")
-	fmt.Println("=======================")
+	title := i18n.T("fh - Fast History Setup")
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("=", utf8.RuneCountInString(title)))
 	fmt.Println()
 
 	// Load or create config
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
 	}
 
 	// Create .fh directory if it doesn't exist
 	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 
 	fhDir := filepath.Join(home, ".fh")
 	if err := os.MkdirAll(fhDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating .fh directory: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error creating .fh directory: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
-	fmt.Printf("✓ Created directory: %s\n", fhDir)
+	i18n.Printf("✓ Created directory: %s\n", fhDir)
 
 	// Initialize database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	_ = db.Close()
-	fmt.Printf("✓ Initialized database: %s\n", cfg.GetDatabasePath())
+	i18n.Printf("✓ Initialized database: %s\n", cfg.GetDatabasePath())
 
 	// Save default config if it doesn't exist
 	configPath := filepath.Join(fhDir, "config.yaml")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		if err := cfg.Save(configPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
-		fmt.Printf("✓ Created config file: %s\n", configPath)
+		i18n.Printf("✓ Created config file: %s\n", configPath)
 	} else {
-		fmt.Printf("✓ Config file already exists: %s\n", configPath)
+		i18n.Printf("✓ Config file already exists: %s\n", configPath)
 	}
 
 	// Detect shell
 	shell, err := capture.DetectShell()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error detecting shell: %v\n", err)
-		fmt.Fprintf(os.Stderr, "\nPlease set your SHELL environment variable.\n")
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error detecting shell: %v\n", err)
+		i18n.Fprintf(os.Stderr, "\nPlease set your SHELL environment variable.\n")
+		os.Exit(exitConfig)
 	}
-	fmt.Printf("✓ Detected shell: %s\n", shell)
+	i18n.Printf("✓ Detected shell: %s\n", shell)
 
 	// Get RC file
 	rcFile, err := capture.GetRCFile(shell)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting RC file: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error getting RC file: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 
 	// Install hooks with configured keybinding
-	result, err := capture.InstallHook(shell, rcFile, cfg.GetKeybinding())
+	result, err := capture.InstallHook(shell, rcFile, cfg.GetKeybinding(), cfg.Search.CwdKeybinding)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 
 	if result.Installed {
-		fmt.Printf("✓ Installed shell hooks (backup: %s)\n", result.BackupFile)
+		i18n.Printf("✓ Installed shell hooks (backup: %s)\n", result.BackupFile)
 	} else if result.KeybindingUpdate {
-		fmt.Printf("✓ Shell hooks already installed (updated keybinding to %s, backup: %s)\n", cfg.GetKeybinding(), result.BackupFile)
+		i18n.Printf("✓ Shell hooks already installed (updated keybinding to %s, backup: %s)\n", cfg.GetKeybinding(), result.BackupFile)
 	} else {
-		fmt.Printf("✓ Shell hooks already installed\n")
+		i18n.Printf("✓ Shell hooks already installed\n")
 	}
 
 	// Import existing history
-	db, err = storage.Open(cfg.GetDatabasePath())
+	db, err = storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
 	dedupConfig := cfg.GetDedupConfig()
-	importResult, err := importer.ImportHistory(db, shell, dedupConfig)
+	importResult, err := importer.ImportHistory(db, shell, importer.Options{
+		Dedup:        dedupConfig,
+		Ignore:       ignoreFilter(cfg).Ignored,
+		ApproxWindow: cfg.GetApproxWindow(),
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not import history: %v\n", err)
-		fmt.Fprintf(os.Stderr, "You can manually import later with: fh --import --input ~/.%s_history\n", strings.ToLower(string(shell)))
+		i18n.Fprintf(os.Stderr, "Warning: Could not import history: %v\n", err)
+		i18n.Fprintf(os.Stderr, "You can manually import later with: fh --import --input ~/.%s_history\n", strings.ToLower(string(shell)))
 	} else if importResult.ImportedEntries > 0 {
-		fmt.Printf("✓ Imported %d commands", importResult.ImportedEntries)
+		i18n.Printf("✓ Imported %d commands", importResult.ImportedEntries)
 		if importResult.SkippedEntries > 0 {
-			fmt.Printf(" (skipped %d due to errors)", importResult.SkippedEntries)
+			i18n.Printf(" (skipped %d due to errors)", importResult.SkippedEntries)
+		}
+		if importResult.IgnoredEntries > 0 {
+			i18n.Printf(" (left out %d matching ignore patterns)", importResult.IgnoredEntries)
+		}
+		if importResult.RepairedEntries > 0 {
+			i18n.Printf(" (repaired %d that were not valid UTF-8)", importResult.RepairedEntries)
 		}
 		fmt.Println()
 	} else {
-		fmt.Printf("✓ No commands to import (history file empty or already imported)\n")
+		i18n.Printf("✓ No commands to import (history file empty or already imported)\n")
+	}
+
+	// Older history on macOS may only be in Terminal's per-session files
+	if sessions, err := importSessions(db, cfg); err != nil {
+		i18n.Fprintf(os.Stderr, "Warning: Could not import macOS Terminal sessions: %v\n", err)
+	} else if sessions != nil {
+		printSessionsResult(sessions)
 	}
 
 	// Print success message
-	successMsg := "SUCCESS! Restart your shell and press Ctrl-R to search."
-	fmt.Println("\n" + strings.Repeat("=", len(successMsg)))
+	successMsg := i18n.T("SUCCESS! Restart your shell and press Ctrl-R to search.")
+	rule := strings.Repeat("=", utf8.RuneCountInString(successMsg))
+	fmt.Println("\n" + rule)
 	fmt.Println(successMsg)
-	fmt.Println(strings.Repeat("=", len(successMsg)) + "\n")
+	fmt.Println(rule + "\n")
 }
 
-func handleStats() {
+func handleStats(profileName string, allProfiles bool, program string, compare string, fast bool, includeArchives bool, rawPaths, exactDirs bool) {
+	if fast && (program != "" || compare != "" || includeArchives) {
+		i18n.Fprintf(os.Stderr, "Error: --fast cannot be combined with --program, --compare or --include-archives\n")
+		os.Exit(exitUsage)
+	}
+
+	var period time.Duration
+	if compare != "" {
+		var err error
+		if period, err = parseWindow("--compare", compare); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
 	}
 
+	profile := selectedProfile(profileName)
+
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
+	filters := storage.QueryFilters{
+		Profile: profileFilter(profile, allProfiles),
+		Program: program,
+	}
+
+	store, closeArchives := withArchives(db, includeArchives)
+	defer closeArchives()
+
+	if period > 0 {
+		comparison, err := stats.Compare(store, filters, period, time.Now())
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error collecting statistics: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		fmt.Print(comparison.Format(10))
+		return
+	}
+
+	if fast {
+		statistics, err := stats.Fast(db, filters.Profile, 10)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error collecting statistics: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		fmt.Print(statistics.Format(10))
+		return
+	}
+
 	// Collect statistics
-	statistics, err := stats.Collect(db)
+	statistics, err := stats.CollectFiltered(store, filters)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error collecting statistics: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error collecting statistics: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	// Subdirectories of a repository count for the repository
+	directory := func(dir string) string { return dir }
+	if !exactDirs {
+		directory = paths.ByProject()
+	}
+	if rawPaths {
+		statistics.CommandsByDir = stats.ShortenDirectories(statistics.CommandsByDir, directory)
+	} else {
+		display := cfg.DisplayPath()
+		statistics.CommandsByDir = stats.ShortenDirectories(statistics.CommandsByDir, func(dir string) string {
+			return display(directory(dir))
+		})
 	}
 
 	// Format and print
@@ -361,38 +1037,68 @@ func handleStats() {
 	fmt.Print(output)
 }
 
-func handleAsk(query string, debug bool) {
+func handleAsk(query string, debug, asJSON, pick, noFooter bool) {
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
 	}
 
 	// Check if AI is enabled
+	if cfg.AIOffline() {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", config.ErrOffline)
+		os.Exit(exitAIDisabled)
+	}
 	if !cfg.AI.Enabled {
-		fmt.Fprintf(os.Stderr, "Error: AI search is disabled in configuration\n")
-		fmt.Fprintf(os.Stderr, "Enable it in ~/.fh/config.yaml or set OPENAI_API_KEY environment variable\n")
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error: AI search is disabled in configuration\n")
+		i18n.Fprintf(os.Stderr, "Enable it in ~/.fh/config.yaml and set the API key of ai.provider, e.g. OPENAI_API_KEY\n")
+		os.Exit(exitAIDisabled)
 	}
 
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
+	if pick {
+		pickAnswer(db, cfg, query, debug)
+		return
+	}
+
+	if asJSON {
+		answer, err := ai.AskStructured(db, query, cfg, debug)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(answer); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		if len(answer.Entries) == 0 {
+			os.Exit(exitNoResults)
+		}
+		return
+	}
+
 	// Perform AI-powered search
+	if noFooter {
+		cfg.AI.Footer = false
+	}
 	result, err := ai.Ask(db, query, cfg, debug)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 
 	// Print result
@@ -402,7 +1108,7 @@ func handleAsk(query string, debug bool) {
 // promptForPassphrase prompts the user for a passphrase twice and confirms they match
 func promptForPassphrase() (string, error) {
 	// Prompt for passphrase
-	fmt.Fprint(os.Stderr, "Enter passphrase for encryption: ")
+	i18n.Fprintf(os.Stderr, "Enter passphrase for encryption: ")
 	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
 	fmt.Fprintln(os.Stderr)
 	if err != nil {
@@ -414,7 +1120,7 @@ func promptForPassphrase() (string, error) {
 	}
 
 	// Confirm passphrase
-	fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+	i18n.Fprintf(os.Stderr, "Confirm passphrase: ")
 	confirm, err := term.ReadPassword(int(os.Stdin.Fd()))
 	fmt.Fprintln(os.Stderr)
 	if err != nil {
@@ -452,37 +1158,96 @@ func exportWithEncryption(db *storage.DB, writer io.Writer, opts export.Options)
 	return nil
 }
 
-func handleExport(formatStr, outputPath, searchTerm string, limit int, encrypt bool) {
+func handleExport(formatStr, outputPath, searchTerm string, limit int, encrypt bool, profileName string, allProfiles bool, container, program string, rawPaths bool, since string, out outputOptions) {
 	// Parse format
 	format, err := export.ParseFormat(formatStr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if (out.print0 || out.quote) && format != export.FormatText {
+		i18n.Fprintf(os.Stderr, "Error: --print0 and --quote only apply to --format text\n")
+		os.Exit(exitUsage)
+	}
+	if (format == export.FormatParquet || format == export.FormatBinary) && (outputPath == "-" || outputPath == "") && term.IsTerminal(int(os.Stdout.Fd())) {
+		i18n.Fprintf(os.Stderr, "Error: --format %s is binary, write it to a file with --output\n", format)
+		os.Exit(exitUsage)
+	}
+	if since != "" && since != "last" {
+		i18n.Fprintf(os.Stderr, "Error: --since only takes last, use --search since:3d for a time window\n")
+		os.Exit(exitUsage)
+	}
+	if since != "" && limit > 0 {
+		i18n.Fprintf(os.Stderr, "Error: --since last exports every new entry and can't be combined with --limit\n")
+		os.Exit(exitUsage)
 	}
 
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	q, err := query.Parse(searchTerm, cfg.QueryOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
 	}
 
+	profile := selectedProfile(profileName)
+
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
 	// Build query filters
 	filters := storage.QueryFilters{
-		Search: searchTerm,
-		Limit:  limit,
+		Limit:      limit,
+		Profile:    profileFilter(profile, allProfiles),
+		ExecTarget: container,
+		Program:    program,
+	}
+	q.Apply(&filters)
+
+	// Incremental export: only what was saved since the last one
+	var state *export.State
+	var statePath, stateKey string
+	if since == "last" {
+		statePath, err = export.DefaultStatePath()
+		if err == nil {
+			state, err = export.LoadState(statePath)
+		}
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		stateKey = export.StateKey(cfg.GetProfileDatabasePath(profile), format, map[string]string{
+			"profile":   filters.Profile,
+			"search":    searchTerm,
+			"container": container,
+			"program":   program,
+		})
+
+		lastID, err := db.LastID()
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		filters.AfterID = state.Exports[stateKey].LastID
+		filters.UpToID = lastID
+		if lastID <= filters.AfterID {
+			i18n.Fprintf(os.Stderr, "Nothing new since the last export\n")
+			return
+		}
 	}
 
 	// Determine output writer
@@ -492,8 +1257,8 @@ func handleExport(formatStr, outputPath, searchTerm string, limit int, encrypt b
 	} else {
 		writer, err = os.Create(outputPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 		defer func() {
 			_ = writer.Close()
@@ -504,35 +1269,62 @@ func handleExport(formatStr, outputPath, searchTerm string, limit int, encrypt b
 	opts := export.Options{
 		Format:  format,
 		Filters: filters,
+		Quote:   out.quote,
+		Print0:  out.print0,
+	}
+	if !rawPaths {
+		opts.Path = cfg.ExportPath()
+	}
+
+	// Show what the search matched when a person reads the list
+	if searchTerm != "" && !encrypt && writer == os.Stdout && term.IsTerminal(int(os.Stdout.Fd())) && search.ColorEnabled() {
+		opts.Highlight = func(command string) string {
+			return search.Highlight(command, q.Spans(storage.FieldCommand, command))
+		}
 	}
 
 	// If encryption is requested, use encryption helper
 	if encrypt {
 		if err := exportWithEncryption(db, writer, opts); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 	} else {
 		// Normal export without encryption
 		if err := export.Export(db, writer, opts); err != nil {
-			fmt.Fprintf(os.Stderr, "Error exporting: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+	}
+
+	if state != nil {
+		if outputPath != "-" && outputPath != "" {
+			// Only move on once the export is safely written
+			if err := writer.Close(); err != nil {
+				i18n.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+				os.Exit(exitCodeFor(err))
+			}
+		}
+		state.Exports[stateKey] = export.StateEntry{LastID: filters.UpToID, ExportedAt: time.Now().Unix()}
+		if err := state.Save(statePath); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 	}
 
 	// Print success message to stderr if writing to file
 	if outputPath != "-" && outputPath != "" {
 		if encrypt {
-			fmt.Fprintf(os.Stderr, "Exported and encrypted to %s\n", outputPath)
+			i18n.Fprintf(os.Stderr, "Exported and encrypted to %s\n", outputPath)
 		} else {
-			fmt.Fprintf(os.Stderr, "Exported to %s\n", outputPath)
+			i18n.Fprintf(os.Stderr, "Exported to %s\n", outputPath)
 		}
 	}
 }
 
 // promptForDecryptPassphrase prompts for a decryption passphrase
 func promptForDecryptPassphrase() (string, error) {
-	fmt.Fprint(os.Stderr, "Enter passphrase to decrypt: ")
+	i18n.Fprintf(os.Stderr, "Enter passphrase to decrypt: ")
 	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
 	fmt.Fprintln(os.Stderr)
 	if err != nil {
@@ -569,7 +1361,7 @@ func decryptReader(reader io.Reader) (io.Reader, error) {
 }
 
 // importWithAutoDetect handles import with format auto-detection
-func importWithAutoDetect(db *storage.DB, reader io.Reader, dedupConfig storage.DedupConfig) error {
+func importWithAutoDetect(db *storage.DB, reader io.Reader, opts export.ImportOptions) error {
 	detectedFormat, newReader, err := export.DetectFormat(reader)
 	if err != nil {
 		return fmt.Errorf("error detecting format: %w", err)
@@ -581,35 +1373,49 @@ func importWithAutoDetect(db *storage.DB, reader io.Reader, dedupConfig storage.
 		return fmt.Errorf("error reading input: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Auto-detected format: %s\n", detectedFormat)
+	i18n.Fprintf(os.Stderr, "Auto-detected format: %s\n", detectedFormat)
 
 	// Import from buffer
-	count, err := export.Import(db, &buf, detectedFormat, dedupConfig)
+	result, err := export.ImportWithOptions(db, &buf, detectedFormat, opts)
 	if err != nil {
 		return fmt.Errorf("error importing: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Imported %d commands\n", count)
+	printImportResult(result)
 	return nil
 }
 
-func handleImport(formatStr, inputPath string, decrypt bool) {
+// printImportResult reports how many commands an import added and left out
+func printImportResult(result export.ImportResult) {
+	i18n.Fprintf(os.Stderr, "Imported %d commands\n", result.Imported)
+	if result.Ignored > 0 {
+		i18n.Fprintf(os.Stderr, "Left out %d commands matching ignore patterns (use --include-ignored to import them)\n", result.Ignored)
+	}
+	if result.Repaired > 0 {
+		i18n.Fprintf(os.Stderr, "Repaired %d commands that were not valid UTF-8\n", result.Repaired)
+	}
+	if result.Notes > 0 {
+		i18n.Fprintf(os.Stderr, "Restored %d notes onto commands already in history\n", result.Notes)
+	}
+}
+
+func handleImport(formatStr, inputPath string, decrypt, includeIgnored bool) {
 	// Load configuration
 	cfg, err := config.LoadDefault()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
 	}
 
 	// Open database
-	db, err := storage.Open(cfg.GetDatabasePath())
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
 		}
 	}()
 
@@ -621,12 +1427,12 @@ func handleImport(formatStr, inputPath string, decrypt bool) {
 	} else {
 		file, err = os.Open(inputPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening input file: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error opening input file: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 		defer func() {
 			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error closing input file: %v\n", err)
+				i18n.Fprintf(os.Stderr, "Error closing input file: %v\n", err)
 			}
 		}()
 		reader = file
@@ -636,18 +1442,24 @@ func handleImport(formatStr, inputPath string, decrypt bool) {
 	if decrypt {
 		reader, err = decryptReader(reader)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 	}
 
-	dedupConfig := cfg.GetDedupConfig()
+	// An import can merge thousands of entries in at once
+	autoSnapshot(db, "import")
+
+	opts := export.ImportOptions{Dedup: cfg.GetDedupConfig(), Path: cfg.ExpandPath()}
+	if !includeIgnored {
+		opts.Ignore = ignoreFilter(cfg).Ignored
+	}
 
 	// Handle auto-detect format
 	if formatStr == "auto" {
-		if err := importWithAutoDetect(db, reader, dedupConfig); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		if err := importWithAutoDetect(db, reader, opts); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
 		}
 		return
 	}
@@ -655,18 +1467,18 @@ func handleImport(formatStr, inputPath string, decrypt bool) {
 	// Parse explicit format
 	format, err := export.ParseFormat(formatStr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
 	}
 
 	// Import
-	count, err := export.Import(db, reader, format, dedupConfig)
+	result, err := export.ImportWithOptions(db, reader, format, opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error importing: %v\n", err)
-		os.Exit(1)
+		i18n.Fprintf(os.Stderr, "Error importing: %v\n", err)
+		os.Exit(exitCodeFor(err))
 	}
 
-	fmt.Fprintf(os.Stderr, "Imported %d commands\n", count)
+	printImportResult(result)
 }
 
 func printUsage() {
@@ -679,28 +1491,291 @@ USAGE:
 OPTIONS:
     --init              Initialize fh and setup shell integration
 
+    --import-sessions   Import the history macOS Terminal saved for each
+                        session in ~/.bash_sessions and ~/.zsh_sessions,
+                        keeping their session ids (also done by --init)
+
+    --apply [file]      Make the config and shell hooks match a setup file
+                        (default: fh.setup.yaml), e.g. from your dotfiles
+        --dry-run           Show what would change without changing it
+
     --save              Save a command to history
         --cmd <cmd>         Command to save (required)
         --exit-code <code>  Exit code (default: 0)
         --duration <ms>     Duration in milliseconds (default: 0)
+        --job <pid>         PID of the background job the command started
+
+    --amend --id <id>   Correct or annotate an entry; every change is kept
+                        in an audit trail, shown when no change is given
+        --exit-code <code>  New exit code
+        --duration <ms>     New duration in milliseconds
+        --note <text>       Attach a note to the entry
+    --amend <session> <job>
+                        Record the result of a finished background job
+                        (called by the shell hooks; --exit-code defaults
+                        to 0, --duration to the time since it started)
+
+    --capture-osc133    Save commands from a terminal stream on stdin using
+                        OSC 133 prompt markers (for shells without fh hooks)
+
+    ssh <args>          Run ssh and save the commands typed on the remote
+                        host, tagged with its hostname (uses script(1))
 
     --stats             Show statistics about your command history
+        --profile <name>    Show another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+        --program <name>    Only commands run by this program (e.g. git)
+        --compare <period>  Compare the last period (e.g. 1d, 1w) with the
+                            one before it: volume, success rate and new
+                            top commands
+        --fast              Read the counters kept on every write instead
+                            of each entry, without programs and directories
+        --include-archives  Include entries moved to yearly archives
+        --raw-paths         Show directories as stored, without ~ and
+                            paths.aliases
+        --exact-dirs        Count each directory on its own instead of by
+                            the git repository it is in
+
+    --recount           Rebuild the counters behind --stats --fast, --top
+                        and the unique search view from the history
+        --profile <name>    Recount another profile's database
+
+    --archive           Move old entries into one archive file per year
+                        next to the database, keeping it small
+        --years <n>         Archive entries older than this (default: 2)
+        --profile <name>    Archive another profile's database
+
+    --prune             Remove the entries past the retention config
+                        (retention.max_age_days, max_entries and
+                        prune_failed_after_days); saves do it once a day
+        --dry-run           Only count what would be removed
+        --profile <name>    Prune another profile's database
+
+    --dashboard         Browse statistics in an interactive dashboard with
+                        tabs for top commands, activity, failures and
+                        directories; enter opens the search picker on the
+                        commands behind a row and prints the one you pick;
+                        r opens it on the commands related to a command
+        --profile <name>    Show another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+        --program <name>    Only commands run by this program (e.g. git)
+
+    --history-of <cmd>  Show when a command was first and last run, a
+                        month-by-month timeline and the directories and
+                        branches it ran in (exact command match)
+        --profile <name>    Show another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+
+    --variants [<cmd>]  Group commands starting with <cmd> that differ in a
+                        single word, with the runs and last use of each,
+                        to spot what could become a snippet or alias
+        --limit <n>         Number of groups to show (default: 10, 0 = all)
+        --profile <name>    Use another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+
+    --security-scan     List saved commands that look like they hold
+                        credentials (AWS keys, GitHub tokens, JWTs,
+                        --password values, random strings) by risk,
+                        then offer to mask or delete them
+        --min-risk <level>  low, medium or high (default: low)
+        --redact            Mask the findings without asking
+        --delete            Delete the entries without asking
+        --profile <name>    Scan another profile instead of the active one
+
+    --verify            Check entry signatures (signing.enabled) and list
+                        entries changed outside fh since they were saved
+        --profile <name>    Verify another profile instead of the active one
+
+    --related <id>      Show the commands that usually run around the
+                        command of an entry, in the same shell session
+        --window <window>   How close in time they ran (default: 5m)
+        --limit <n>         Number of commands to show (default: 20)
+
+    --show <id>         Show every field of an entry, its amendments and
+                        the commands run around it in the same session
+        --json              Print it as JSON
+
+    --prev              Print a previous command of this shell session,
+                        for the up-arrow widgets of the shell hooks
+        --index <n>         How many commands back (default: 1, the last)
+        --session <id>      Session to read (default: $FH_SESSION)
+        --profile <name>    Use another profile instead of the active one
+
+    --remind            Print the command of the day: one you ran often
+                        but not for remind.idle_days (default: 90)
+        --daily             Only with remind.daily on, once a day, for the
+                            shell hooks
+        --profile <name>    Use another profile instead of the active one
+
+    --runbook           Write the commands run in a time window as a
+                        markdown runbook, folding retries into one step
+                        and marking failures
+        --from <time>       Start, e.g. "2025-01-07 14:00" (required)
+        --to <time>         End, a time alone is on the day of --from
+                            (default: now)
+        --cwd <dir>         Only commands run in this directory or below it
+        --output <file>     Output file (default: stdout)
+        --profile <name>    Use another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+
+    --test-ignore <cmd> Show which ignore patterns match a command
+    ignore test <cmd>   Same as --test-ignore
+
+    --ignored           Show commands skipped by ignore patterns
+        --since <window>    Time window, e.g. 30m, 12h, 1d, 2w (default: 1d)
+        --profile <name>    Show another profile instead of the active one
+        --all-profiles      Include every profile, not just the active one
+
+    --top               Show most used commands, ranked by frecency
+        --limit <n>         Number of commands (default: 20)
+        --half-life <days>  Decay half-life so stale commands sink
+                            (default: search.half_life_days, 0 = no decay)
+        --profile <name>    Rank another profile instead of the active one
+        --all-profiles      Rank commands from every profile
+
+    --hosts             List the machines that opened the database; more
+                        than one recent machine means a file synced
+                        between them, which corrupts it
+        forget <host>       Stop warning about a machine, e.g. one the
+                            database was copied from
+
+    --mirror <path>     Write a read-only SQLite copy of the history table
+                        for notebooks and BI tools; the live database is
+                        only read. Does nothing if the copy is up to date
+        --every <interval>  Keep refreshing it, e.g. 5m
+        --profile <name>    Mirror another profile's database
+
+    --daemon            Keep the database open and write the saves handed to
+                        it on ~/.fh/fh.sock, for slow disks. Saves write the
+                        database themselves when it isn't running
+        --interval <d>      How long saves wait to be written together (default: 1s)
+
+    --install-timer <job>
+                        Run a maintenance job on a schedule with a systemd
+                        user timer, or a launchd agent on macOS:
+                        archive (weekly --archive), backup (daily
+                        --snapshot backup), prune (daily --prune) or sync
+                        (--sink flush every 15 minutes)
+        --print             Show the files instead of installing them
+        --remove            Stop the job and delete its files
+
+    --run-job <job>     Run a maintenance job now, as its timer does,
+                        pinging hooks.healthcheck_url when it starts,
+                        succeeds or fails
+
+    --sink              Show how far the analytics sink (sink.url) has got
+        flush               Send every pending entry now
+
+    --snapshot          List the snapshots of the database
+        create <name>       Copy the database as it is now
+        backup              Take an automatic snapshot, for timers
+        restore <name>      Bring the database back to a snapshot
+        delete <name>       Remove a snapshot
+                        --import, --archive, --snapshot restore and schema
+                        upgrades take one by themselves first, see
+                        storage.auto_snapshots
+
+    --profile [name]    Show the active profile, or switch to <name>
+                        Search, stats, top and export only see the active
+                        profile unless --all-profiles is given. Profiles
+                        listed under "profiles:" in the config use their
+                        own database file
+
+    --all-profiles      Search history from every profile
+
+    --container <name>  Search commands run inside a container or pod via
+                        docker/podman/compose/kubectl exec (substring match)
+
+    --program <name>    Search commands whose primary program is <name>,
+                        e.g. "fh --program git" finds "sudo git pull"
+
+    --print0            Print the picked command ending in NUL instead of a
+                        newline, for xargs -0 (with any search)
+    --quote             Print the picked command shell-quoted, as one word
+    --copy              Copy the picked command to the clipboard instead of
+                        printing it (pbcopy, wl-copy, xclip, xsel or OSC 52,
+                        which is always used over SSH)
+    --tmux-buffer       Put the picked command in the tmux paste buffer
+    --tmux-pane[=<pane>]
+                        Type the picked command into a tmux pane without
+                        running it (default: search.tmux_pane, "{last}")
+    --include-archives  Search the yearly archives made by --archive too
+    --cwd-only          Only offer commands run in the current directory or
+                        below it (with any search), see search.cwd_keybinding
 
     --ask <query>       AI-powered natural language search
-                        Requires OPENAI_API_KEY environment variable
-        --debug         Show debug output (SQL query, responses, etc.)
+                        Requires the API key of ai.provider: OPENAI_API_KEY,
+                        GEMINI_API_KEY or ANTHROPIC_API_KEY (none for a
+                        local Ollama)
+        --debug         Show debug output (prompts as sent, SQL query, etc.)
+        --json          Answer with the matching entries as JSON: id, command,
+                        timestamp, cwd and the reason each one answers
+        --pick          Offer the matching entries in the picker and print
+                        the selected command, as search does
+        --no-footer     Leave out the line with the SQL the answer is based
+                        on and its row count (as ai.footer: false)
+
+    --delete            Delete the entries matching every filter given, after
+                        listing them and asking
+        --search <query>    Entries matching a query, as --export --search
+        --before <when>     Entries run before a date (2025-01-07) or a
+                            window ago (30d)
+        --cwd <dir>         Entries run in this directory
+        --exit-code <n>     Entries that exited with this code
+        --dry-run           Only show what would be deleted
+        --yes               Delete without asking
+        --profile <name>    Use another profile instead of the active one
+        --all-profiles      Delete from every profile
+
+    --ask-delete <request>
+                        Delete the entries a request in plain words is about,
+                        e.g. "everything mentioning acme.com". The model only
+                        writes a query finding them; they are listed, and
+                        deleted once you confirm
+        --yes           Delete without asking
+        --debug         Show debug output
+
+    --ai-eval           Check the SQL written for a suite of questions about a
+                        seeded history, answered by a mock provider
+        --live              Ask the configured model instead
+        --suite <file>      YAML suite to run instead of the built-in one
 
     --export            Export history to different formats
-        --format <fmt>      Format: text, json, csv (default: text)
+        --format <fmt>      Format: text, json, jsonl, csv, zsh, bash, binary, ipynb, parquet
+                            (default: text)
         --output <file>     Output file (default: stdout)
-        --search <term>     Filter by search term
+        --search <query>    Filter by search query, words match the command,
+                            directory, branch or note; cmd:, cwd:, branch:
+                            and note: limit a word to one field; program:,
+                            exit:, since:, until: and @<saved filter> add
+                            conditions, ! leaves a word out
         --limit <n>         Limit results (default: 0 = unlimited)
         --encrypt           Encrypt the export with AES-256-GCM
+        --profile <name>    Export another profile instead of the active one
+        --all-profiles      Export every profile, not just the active one
+        --container <name>  Only export commands run inside this container/pod
+        --program <name>    Only export commands run by this program
+        --print0            End each command with NUL (text format)
+        --quote             Shell-quote each command (text format)
+        --raw-paths         Write directories as stored, without ~ and
+                            paths.aliases (imports expand them back)
+        --since last        Only export entries saved since the last
+                            --since last export with the same format and
+                            filters, remembered in ~/.fh/export.state
 
     --import            Import history from file
-        --format <fmt>      Format: auto, text, json, csv (default: auto)
+        --format <fmt>      Format: auto, text, json, jsonl, csv, zsh, bash, binary (default: auto)
         --input <file>      Input file (default: stdin)
         --decrypt           Decrypt the import (AES-256-GCM)
+        --include-ignored   Import commands matching ignore patterns too
+
+    --bundle export <file>
+                        Write history from every database, the config and
+                        the active profile to one encrypted file
+    --bundle import <file>
+                        Restore a bundle on a new machine: the config is
+                        replaced (the old one kept as config.yaml.bak) and
+                        the history merged into the local databases
 
     --version, -v       Show version
     --help, -h          Show this help
@@ -709,33 +1784,124 @@ EXAMPLES:
     # Initialize fh (first time setup)
     fh --init
 
+    # Provision fh from your dotfiles
+    fh --apply ~/dotfiles/fh.setup.yaml
+
     # Save a command (typically called from shell hooks)
-    fh --save --cmd "ls -la" --exit-code 0 --duration 150
+    fh --save --cmd "make test" --exit-code 0 --duration 150
 
     # Search history with FZF
     fh
 
+    # Pre-filter: failed commands of the last 3 days under ~/work
+    fh exit:!0 since:3d dir:~/work
+
+    # Save a picked command as an alias, safely quoted
+    echo "alias redeploy=$(fh --quote deploy)" >> ~/.bash_aliases
+
+    # Copy a picked command to the clipboard
+    fh --copy kubectl
+
+    # Type a picked command into the pane on the right
+    fh --tmux-pane={right-of} deploy
+
+    # Export commands NUL-separated for xargs -0
+    fh --export --search program:make --print0 | xargs -0 -n1 echo
+
+    # Capture any shell that emits OSC 133 markers, via tmux
+    tmux pipe-pane -o 'fh --capture-osc133'
+
+    # Record what you run on a remote host
+    fh ssh alice@web1
+
+    # Fix up an entry after the fact, then review its changes
+    fh --amend --id 42 --exit-code 1 --note "failed on prod"
+    fh --amend --id 42
+
+    # Commands you ran inside the api pod or container
+    fh --container api
+
+    # Commands run in this project, here or below
+    fh --cwd-only
+
     # Show statistics
     fh --stats
+    fh --stats --compare 1w
+    fh --stats --fast
+    fh --archive --years 3
+    fh prune --dry-run
+    fh --include-archives terraform
+    fh --dashboard
+    fh --history-of "terraform apply"
+    fh --variants "kubectl logs"
+    fh --related 4242
+    fh --prev --index 2
+    fh --remind
+    fh --show 4242 --json
+
+    # Write a runbook of an incident
+    fh --runbook --from "2025-01-07 14:00" --to "15:30" --cwd ~/infra
+
+    # Debug ignore patterns
+    fh --test-ignore "git status"
+    fh --ignored --since 1d
+
+    # Find tokens that made it into the history, and mask them
+    fh --security-scan --min-risk medium --redact
+
+    # Top commands, with runs older than 30 days counting half
+    fh --top --half-life 30
 
     # AI-powered search (requires OPENAI_API_KEY)
     fh --ask "what git commands did I run today?"
     fh --ask "show me failed commands from last week"
     fh --ask "what docker commands did I use yesterday?"
+    fh --ask "what did I run inside the api pod yesterday?"
     fh --ask --debug "what testing commands did I run today?"  # With debug output
+    fh --ask --json "how did I deploy to staging?" | jq -r '.entries[0].command'
+    fh --ask --pick "how did I deploy to staging?"  # Pick one to run
+    fh delete --search "cmd:AKIA" --dry-run
+    fh delete --before 2024-01-01 --exit-code 127
+    fh --ask-delete "remove everything containing acme.com"
+    fh --ai-eval --live                                # Check the model's SQL
+
+    # Keep work and personal history apart
+    fh --profile work
+    fh --stats --all-profiles
+    fh --export --profile personal --format json
 
     # Export history as JSON
     fh --export --format json --output history.json
 
+    # Export typed columns for DuckDB or pandas
+    fh --export --format parquet --output history.parquet
+
     # Export recent 100 commands as CSV
     fh --export --format csv --limit 100 > recent.csv
 
     # Import history from JSON file
     fh --import --input history.json
 
+    # Keep a copy for a notebook up to date
+    fh --mirror ~/notebooks/fh.db --every 10m
+
+    # Write saves from a background process
+    fh --daemon &
+
+    # Snapshot the database every day
+    fh --install-timer backup
+
+    # Keep a copy before cleaning up, and go back to it
+    fh --snapshot create before-cleanup
+    fh --snapshot restore before-cleanup
+
     # Import from stdin (auto-detect format)
     cat history.csv | fh --import
 
+    # Move your whole setup to a new laptop
+    fh --bundle export fh-bundle.enc
+    fh --bundle import fh-bundle.enc
+
     # Create encrypted backup (export with encryption)
     fh --export --format json --output backup.json.enc --encrypt
 
@@ -747,7 +1913,29 @@ EXAMPLES:
 
 ENVIRONMENT:
     FH_DB_PATH          Override database path (default: ~/.fh/history.db)
+                        Use ":memory:" for an ephemeral session that saves nothing
+    FH_PROFILE          Active profile for this shell (overrides fh --profile)
+    FH_OFFLINE          Set to 1 to disable every network feature (as privacy.offline)
+    FH_SESSION          Session ID shared by the commands of one shell
+                        (set by the shell hooks)
     OPENAI_API_KEY      OpenAI API key (required for --ask command)
+    AZURE_OPENAI_API_KEY
+                        Azure OpenAI API key (for --ask with ai.provider azure)
+    GEMINI_API_KEY      Gemini API key (for --ask with ai.provider gemini,
+                        GOOGLE_API_KEY works too)
+    ANTHROPIC_API_KEY   Anthropic API key (for --ask with ai.provider anthropic)
+    FH_AI_RECORD        Record the AI provider's responses to a JSON cassette
+    FH_AI_REPLAY        Answer questions from a recorded cassette, for tests
+
+EXIT CODES:
+    0    Success
+    1    Any other error
+    2    Invalid flags or arguments
+    3    Config or setup file can't be loaded or is invalid
+    4    Database locked by another process
+    5    No history to search, or nothing matched the query
+    6    AI search disabled or the provider's API key not set
+    130  Search closed without picking a command
 
 For more information, visit: https://github.com/spideyz0r/fh
 `, version)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleMirror writes a read-only copy of the history to path, once or
+// every interval until interrupted
+func handleMirror(path string, every time.Duration, profileName string) {
+	if every < 0 {
+		i18n.Fprintf(os.Stderr, "Error: --every cannot be negative\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(selectedProfile(profileName)), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	for {
+		refreshed, err := db.Mirror(path)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error mirroring: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if refreshed {
+			i18n.Printf("Mirrored to %s\n", path)
+		} else if every == 0 {
+			i18n.Printf("%s is up to date\n", path)
+		}
+
+		if every == 0 {
+			return
+		}
+		time.Sleep(every)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleCaptureOSC133 reads a terminal output stream from stdin and saves every
+// command delimited by OSC 133 semantic prompt markers. It is meant to be fed
+// by a multiplexer, e.g. tmux pipe-pane -o 'fh --capture-osc133'
+func handleCaptureOSC133() {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database once, the stream lives as long as the pane
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	dedupConfig := cfg.GetDedupConfig()
+	ignore := ignoreFilter(cfg)
+	sessionID := fmt.Sprintf("osc133-%d-%d", os.Getpid(), time.Now().Unix())
+	normalizer := cfg.PathNormalizer()
+
+	parser := capture.NewOSC133Parser(func(cmd capture.OSC133Command) {
+		meta := cmd.Metadata(sessionID)
+		entry := &storage.HistoryEntry{
+			Timestamp:   meta.Timestamp,
+			Command:     meta.Command,
+			Cwd:         normalizer.Normalize(meta.Cwd),
+			ExitCode:    meta.ExitCode,
+			Hostname:    meta.Hostname,
+			User:        meta.User,
+			Shell:       meta.Shell,
+			DurationMs:  meta.DurationMs,
+			GitBranch:   meta.GitBranch,
+			SessionID:   meta.SessionID,
+			Profile:     config.ActiveProfile(),
+			ExecRuntime: meta.ExecRuntime,
+			ExecTarget:  meta.ExecTarget,
+		}
+		entry.Redact()
+
+		if skipIgnored(ignore, db, entry) || skipDebounced(cfg, db, entry) {
+			return
+		}
+
+		// Keep consuming the stream even if a single save fails
+		if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
+			i18n.Fprintf(os.Stderr, "Error saving command: %v\n", err)
+		}
+	})
+
+	if _, err := io.Copy(parser, os.Stdin); err != nil {
+		i18n.Fprintf(os.Stderr, "Error reading terminal stream: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
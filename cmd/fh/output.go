@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/clipboard"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/shellparse"
+	"github.com/spideyz0r/fh/pkg/tmux"
+)
+
+// Where a picked command goes besides stdout, as in search.output
+const (
+	outputClipboard  = "clipboard"
+	outputTmuxBuffer = "tmux-buffer"
+	outputTmuxPane   = "tmux-pane"
+)
+
+// outputOptions control how a picked command is printed for other programs
+type outputOptions struct {
+	print0 bool   // End the command with NUL instead of a newline, for xargs -0
+	quote  bool   // Shell-quote the command, to embed it in a script
+	to     string // Where it goes, empty for search.output
+	pane   string // Target pane of outputTmuxPane, empty for search.tmux_pane
+}
+
+// splitOutputFlags takes --print0, --quote, --copy, --tmux-buffer and
+// --tmux-pane[=<target>] out of the words of a search, the rest is the query
+func splitOutputFlags(args []string) (outputOptions, []string) {
+	var out outputOptions
+	var rest []string
+	for _, arg := range args {
+		switch {
+		case arg == "--print0":
+			out.print0 = true
+		case arg == "--quote":
+			out.quote = true
+		case arg == "--copy":
+			out.to = outputClipboard
+		case arg == "--tmux-buffer":
+			out.to = outputTmuxBuffer
+		case arg == "--tmux-pane":
+			out.to = outputTmuxPane
+		case strings.HasPrefix(arg, "--tmux-pane="):
+			out.to, out.pane = outputTmuxPane, strings.TrimPrefix(arg, "--tmux-pane=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return out, rest
+}
+
+// withConfig fills in what the flags left to the configuration.
+// search.output only applies when stdout is a terminal: the shell widgets
+// and $(fh) capture stdout and still get the command.
+func (out outputOptions) withConfig(cfg *config.Config, stdoutIsTerminal bool) outputOptions {
+	if out.to == "" && stdoutIsTerminal {
+		out.to = cfg.Search.Output
+	}
+	if out.pane == "" {
+		out.pane = cfg.Search.TmuxPane
+	}
+	if out.pane == "" {
+		out.pane = tmux.DefaultPane
+	}
+	return out
+}
+
+// printCommand writes command to w as out says, or hands it to the
+// clipboard or tmux and tells so on stderr
+func printCommand(w io.Writer, command string, out outputOptions) error {
+	if out.quote {
+		command = shellparse.Quote(command)
+	}
+
+	switch out.to {
+	case outputClipboard:
+		method, err := clipboard.Copy(command)
+		if err != nil {
+			return err
+		}
+		i18n.Fprintf(os.Stderr, "Copied to clipboard (%s)\n", method)
+		return nil
+	case outputTmuxBuffer:
+		if err := tmux.SetBuffer(command); err != nil {
+			return err
+		}
+		i18n.Fprintf(os.Stderr, "Copied to the tmux buffer\n")
+		return nil
+	case outputTmuxPane:
+		if err := tmux.SendKeys(out.pane, command); err != nil {
+			return err
+		}
+		i18n.Fprintf(os.Stderr, "Sent to tmux pane %s\n", out.pane)
+		return nil
+	}
+
+	if out.print0 {
+		_, err := fmt.Fprint(w, command+"\x00")
+		return err
+	}
+	_, err := fmt.Fprintln(w, command)
+	return err
+}
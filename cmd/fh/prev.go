@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handlePrev prints the index-th previous command of a shell session, for
+// the up-arrow widgets of the shell hooks. It stays quiet and exits with
+// exitNoResults when the session has nothing that far back.
+func handlePrev(session string, index int, profileName string) {
+	if session == "" {
+		i18n.Fprintf(os.Stderr, "Error: no session, run fh --prev from a shell with the fh hook or pass --session\n")
+		os.Exit(exitUsage)
+	}
+	if index < 1 {
+		i18n.Fprintf(os.Stderr, "Error: --index must be 1 or more\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// The last commands may still wait in the daemon's queue
+	dbPath := cfg.GetProfileDatabasePath(selectedProfile(profileName))
+	flushDaemon(dbPath)
+
+	// Open database
+	db, err := storage.OpenWithOptions(dbPath, cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	entry, err := db.SessionPrevious(session, index)
+	if closeErr := db.Close(); closeErr != nil {
+		i18n.Fprintf(os.Stderr, "Error closing database: %v\n", closeErr)
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error reading session: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if entry == nil {
+		os.Exit(exitNoResults)
+	}
+	fmt.Println(entry.Command)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+)
+
+// selectedProfile returns the profile given with a --profile flag, falling
+// back to the active profile. Exits on an invalid name.
+func selectedProfile(name string) string {
+	if name == "" {
+		return config.ActiveProfile()
+	}
+
+	if err := config.ValidateProfile(name); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	return name
+}
+
+// profileFilter returns the profile to filter by, or "" for every profile
+func profileFilter(profile string, allProfiles bool) string {
+	if allProfiles {
+		return ""
+	}
+	return profile
+}
+
+func handleProfile(args []string) {
+	// No name - show the active profile
+	if len(args) == 0 {
+		fmt.Println(config.ActiveProfile())
+		return
+	}
+
+	if len(args) > 1 {
+		i18n.Fprintf(os.Stderr, "Error: --profile takes a single profile name\n")
+		os.Exit(exitUsage)
+	}
+
+	name := args[0]
+	if err := config.SetActiveProfile(name); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	i18n.Fprintf(os.Stderr, "Switched to profile: %s\n", name)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// pruneSaveInterval is how often saves prune the database to the retention
+const pruneSaveInterval = 24 * time.Hour
+
+// pruneAfterSave removes what is past the retention, once a day. Saves
+// stay silent: a prune that can't take its snapshot first is skipped.
+func pruneAfterSave(cfg *config.Config, db *storage.DB) {
+	retention := cfg.GetRetention()
+	now := time.Now()
+	if !retention.Enabled() || !db.ClaimPrune(pruneSaveInterval, now) {
+		return
+	}
+
+	// Counting is cheap, the snapshot isn't: take it only when something
+	// is to be removed
+	if prunable, err := db.Prunable(retention, now); err != nil || prunable.Total() == 0 {
+		return
+	}
+	if _, err := db.AutoSnapshot("prune"); err != nil {
+		return
+	}
+	_, _ = db.Prune(retention, now)
+}
+
+// handlePrune removes the entries past the configured retention now, or
+// with dryRun only counts them
+func handlePrune(dryRun bool, profileName string) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	retention := cfg.GetRetention()
+	if !retention.Enabled() {
+		i18n.Fprintf(os.Stderr, "Error: no retention configured, set retention.max_age_days, retention.max_entries or retention.prune_failed_after_days\n")
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(selectedProfile(profileName)), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	var pruned *storage.Pruned
+	if dryRun {
+		pruned, err = db.Prunable(retention, time.Now())
+	} else {
+		autoSnapshot(db, "prune")
+		pruned, err = db.Prune(retention, time.Now())
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error pruning: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if pruned.Failed > 0 {
+		i18n.Printf("  %d failed entries older than %d days\n", pruned.Failed, cfg.Retention.PruneFailedAfterDays)
+	}
+	if pruned.Old > 0 {
+		i18n.Printf("  %d entries older than %d days\n", pruned.Old, cfg.Retention.MaxAgeDays)
+	}
+	if pruned.Excess > 0 {
+		i18n.Printf("  %d entries beyond the %d most recent\n", pruned.Excess, cfg.Retention.MaxEntries)
+	}
+	if dryRun {
+		i18n.Printf("Would prune %d entries\n", pruned.Total())
+	} else {
+		i18n.Printf("Pruned %d entries\n", pruned.Total())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/dashboard"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// defaultRelatedWindow is how far apart two commands can run and still count
+// as related
+const defaultRelatedWindow = 5 * time.Minute
+
+// handleRelated lists the commands that usually run within window of the
+// command of entry id in the same shell session
+func handleRelated(idArg string, window string, limit int) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil || id <= 0 {
+		i18n.Fprintf(os.Stderr, "Error: invalid entry id: %s\n", idArg)
+		os.Exit(exitUsage)
+	}
+
+	within, err := parseWindow("--window", window)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entry, err := db.GetByID(id)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error finding entry %d: %v\n", id, err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	related, err := db.Related(id, within, limit)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error finding related commands: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if len(related) == 0 {
+		i18n.Printf("No commands related to %q.\n", entry.Command)
+		os.Exit(exitNoResults)
+	}
+
+	for i, r := range related {
+		displayCmd := r.Entry.Command
+		if len(displayCmd) > 60 {
+			displayCmd = displayCmd[:57] + "..."
+		}
+		fmt.Printf("%3d. (%3d) %s\n", i+1, r.Count, displayCmd)
+	}
+}
+
+// relatedFunc returns the dashboard hook listing the commands related to an
+// entry, the most recent run of each
+func relatedFunc(db *storage.DB, window time.Duration, limit int) dashboard.RelatedFunc {
+	return func(entry *storage.HistoryEntry) ([]*storage.HistoryEntry, error) {
+		related, err := db.Related(entry.ID, window, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]*storage.HistoryEntry, len(related))
+		for i, r := range related {
+			entries[i] = r.Entry
+		}
+		return entries, nil
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// remindCandidates is how many of the most forgotten commands take turns as
+// the command of the day, so one you still don't need doesn't come back
+// every day
+const remindCandidates = 7
+
+// handleRemind prints the command of the day: one of the commands that ran
+// often but not for remind.idle_days, picked by the date. With daily it is
+// for the shell hooks: it only prints with remind.daily on, once a day, and
+// says nothing when there is nothing to remind of.
+func handleRemind(daily bool, profileName string) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		if daily {
+			return
+		}
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	if daily && !cfg.Remind.Daily {
+		return
+	}
+
+	profile := selectedProfile(profileName)
+	dbPath := cfg.GetProfileDatabasePath(profile)
+
+	// Every new shell asks, only the first one of the day opens the database
+	now := time.Now()
+	if daily && !storage.ClaimReminder(dbPath, now) {
+		return
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(dbPath, cfg.GetStorageOptions())
+	if err != nil {
+		if daily {
+			return
+		}
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	forgotten, err := db.Forgotten(profile, now, cfg.GetRemindIdle(), cfg.Remind.MinRuns, remindCandidates)
+	if err != nil {
+		if daily {
+			return
+		}
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if len(forgotten) == 0 {
+		if daily {
+			return
+		}
+		i18n.Printf("Nothing to remind of: no command run at least %d times has gone unused for %d days.\n", cfg.Remind.MinRuns, cfg.Remind.IdleDays)
+		os.Exit(exitNoResults)
+	}
+
+	// The same pick all day, the next one tomorrow
+	_, offset := now.Zone()
+	day := (now.Unix() + int64(offset)) / (24 * 60 * 60)
+	pick := forgotten[day%int64(len(forgotten))]
+
+	last := time.Unix(pick.Entry.Timestamp, 0)
+	i18n.Printf("Command of the day, run %d times, last on %s:\n", pick.Count, last.Format("2006-01-02"))
+	fmt.Printf("  %s\n", pick.Entry.Command)
+}
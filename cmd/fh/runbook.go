@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/runbook"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// runbookOptions holds the fh --runbook flags
+type runbookOptions struct {
+	from        string
+	to          string
+	cwd         string
+	output      string
+	profileName string
+	allProfiles bool
+}
+
+// handleRunbook writes the commands run in a time window as a markdown
+// runbook, optionally limited to a directory and the ones below it
+func handleRunbook(opts runbookOptions) {
+	if opts.from == "" {
+		i18n.Fprintf(os.Stderr, "Error: --from is required\n")
+		os.Exit(exitUsage)
+	}
+
+	now := time.Now()
+	from, err := runbook.ParseTime(opts.from, now)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// A time of day alone in --to is on the day of --from
+	to := now
+	if opts.to != "" {
+		if to, err = runbook.ParseTime(opts.to, from); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	if to.Before(from) {
+		i18n.Fprintf(os.Stderr, "Error: --to is before --from\n")
+		os.Exit(exitUsage)
+	}
+
+	cwd := ""
+	if opts.cwd != "" {
+		if cwd, err = filepath.Abs(opts.cwd); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	profile := selectedProfile(opts.profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entries, err := db.Query(storage.QueryFilters{
+		After:   from.Unix(),
+		Before:  to.Unix(),
+		Profile: profileFilter(profile, opts.allProfiles),
+	})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if cwd != "" {
+		entries = inDirectory(entries, cwd)
+	}
+
+	// Determine output writer
+	writer := os.Stdout
+	if opts.output != "-" && opts.output != "" {
+		writer, err = os.Create(opts.output)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		defer func() {
+			_ = writer.Close()
+		}()
+	}
+
+	if err := runbook.New(entries, from, to, cwd).WriteMarkdown(writer); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if opts.output != "-" && opts.output != "" {
+		i18n.Fprintf(os.Stderr, "Runbook written to %s\n", opts.output)
+	}
+}
+
+// inDirectory returns the entries run in dir or below it
+func inDirectory(entries []*storage.HistoryEntry, dir string) []*storage.HistoryEntry {
+	var matched []*storage.HistoryEntry
+	for _, entry := range entries {
+		if entry.Cwd == dir || strings.HasPrefix(entry.Cwd, strings.TrimSuffix(dir, "/")+"/") {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
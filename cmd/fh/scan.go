@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"golang.org/x/term"
+)
+
+// scanResult is an entry with credential-like parts
+type scanResult struct {
+	entry    *storage.HistoryEntry
+	findings []redact.Finding
+	risk     redact.Risk // Highest of the findings
+}
+
+// handleSecurityScan lists history entries that look like they hold
+// credentials, grouped by risk, and masks or deletes them when asked. The
+// commands are only ever printed masked.
+func handleSecurityScan(minRiskName string, redactAll, deleteAll bool, profileName string) {
+	minRisk, ok := redact.ParseRisk(minRiskName)
+	if !ok {
+		i18n.Fprintf(os.Stderr, "Error: invalid --min-risk %q (must be low, medium or high)\n", minRiskName)
+		os.Exit(exitUsage)
+	}
+	if redactAll && deleteAll {
+		i18n.Fprintf(os.Stderr, "Error: --redact and --delete cannot be combined\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(selectedProfile(profileName)), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entries, err := db.Query(storage.QueryFilters{})
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	var results []scanResult
+	for _, entry := range entries {
+		var findings []redact.Finding
+		risk := redact.Low
+		for _, f := range redact.Scan(entry.Command) {
+			if f.Risk >= minRisk {
+				findings = append(findings, f)
+				risk = max(risk, f.Risk)
+			}
+		}
+		if len(findings) > 0 {
+			results = append(results, scanResult{entry: entry, findings: findings, risk: risk})
+		}
+	}
+
+	if len(results) == 0 {
+		i18n.Printf("No credential-like commands found in %d entries.\n", len(entries))
+		return
+	}
+
+	for _, risk := range []redact.Risk{redact.High, redact.Medium, redact.Low} {
+		var group []scanResult
+		for _, r := range results {
+			if r.risk == risk {
+				group = append(group, r)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		switch risk {
+		case redact.High:
+			i18n.Printf("High risk (%d)\n", len(group))
+		case redact.Medium:
+			i18n.Printf("Medium risk (%d)\n", len(group))
+		default:
+			i18n.Printf("Low risk (%d)\n", len(group))
+		}
+		for _, r := range group {
+			kinds := make([]string, 0, len(r.findings))
+			for _, f := range r.findings {
+				kinds = append(kinds, f.Kind)
+			}
+			when := time.Unix(r.entry.Timestamp, 0).Format("2006-01-02 15:04")
+			fmt.Printf("  %6d  %s  %-22s %s\n", r.entry.ID, when, strings.Join(kinds, ","), redact.MaskFindings(r.entry.Command, r.findings))
+		}
+		fmt.Println()
+	}
+
+	action := ""
+	switch {
+	case redactAll:
+		action = "r"
+	case deleteAll:
+		action = "d"
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		i18n.Printf("Mask the findings (r), delete the %d entries (d) or leave them (N)? ", len(results))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		action = strings.ToLower(strings.TrimSpace(answer))
+	}
+
+	switch action {
+	case "r":
+		commands := make(map[int64]string, len(results))
+		for _, r := range results {
+			commands[r.entry.ID] = redact.MaskFindings(r.entry.Command, r.findings)
+		}
+		if err := db.RedactCommands(commands); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Masked %d entries\n", len(commands))
+	case "d":
+		ids := make([]int64, 0, len(results))
+		for _, r := range results {
+			ids = append(ids, r.entry.ID)
+		}
+		deleted, err := db.DeleteEntries(ids)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Deleted %d entries\n", deleted)
+	default:
+		return
+	}
+	i18n.Printf("Snapshots, archives and mirrors still have the old commands, see fh --snapshot list\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/importer"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// importSessions imports the per-session history macOS Terminal keeps,
+// returning nil when there was none
+func importSessions(db *storage.DB, cfg *config.Config) (*importer.ImportResult, error) {
+	dirs, err := importer.GetSessionDirs()
+	if err != nil {
+		return nil, err
+	}
+	result, err := importer.ImportSessions(db, dirs, importer.Options{
+		Dedup:        cfg.GetDedupConfig(),
+		Ignore:       ignoreFilter(cfg).Ignored,
+		ApproxWindow: cfg.GetApproxWindow(),
+	})
+	if err != nil || result.Sessions == 0 {
+		return nil, err
+	}
+	return result, nil
+}
+
+// printSessionsResult reports what importSessions imported
+func printSessionsResult(result *importer.ImportResult) {
+	i18n.Printf("✓ Imported %d commands from %d macOS Terminal sessions", result.ImportedEntries, result.Sessions)
+	if result.IgnoredEntries > 0 {
+		i18n.Printf(" (left out %d matching ignore patterns)", result.IgnoredEntries)
+	}
+	fmt.Println()
+}
+
+// handleImportSessions imports the history macOS Terminal saved for each of
+// its sessions under ~/.bash_sessions and ~/.zsh_sessions
+func handleImportSessions() {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	// An import can merge thousands of entries in at once
+	autoSnapshot(db, "import")
+
+	result, err := importSessions(db, cfg)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error importing sessions: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if result == nil {
+		i18n.Printf("No macOS Terminal session history in ~/.bash_sessions or ~/.zsh_sessions.\n")
+		os.Exit(exitNoResults)
+	}
+	printSessionsResult(result)
+}
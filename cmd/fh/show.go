@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// showNeighbors is how many session entries --show lists on each side
+const showNeighbors = 3
+
+// showRecord is the JSON form of fh --show
+type showRecord struct {
+	ID          int64           `json:"id"`
+	Command     string          `json:"command"`
+	Timestamp   int64           `json:"timestamp"`
+	ApproxTime  bool            `json:"approx_time"`
+	Redacted    bool            `json:"redacted"`
+	ExitCode    int             `json:"exit_code"`
+	DurationMs  int64           `json:"duration_ms"`
+	Cwd         string          `json:"cwd"`
+	GitBranch   string          `json:"git_branch"`
+	Hostname    string          `json:"hostname"`
+	User        string          `json:"user"`
+	Shell       string          `json:"shell"`
+	SessionID   string          `json:"session_id"`
+	Profile     string          `json:"profile"`
+	ExecRuntime string          `json:"exec_runtime"`
+	ExecTarget  string          `json:"exec_target"`
+	JobID       int64           `json:"job_id"`
+	Program     string          `json:"program"`
+	Note        string          `json:"note"`
+	Hash        string          `json:"hash"`
+	Amendments  []showAmendment `json:"amendments"`
+	Before      []showNeighbor  `json:"session_before"`
+	After       []showNeighbor  `json:"session_after"`
+}
+
+type showAmendment struct {
+	Field     string `json:"field"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	AmendedAt int64  `json:"amended_at"`
+}
+
+type showNeighbor struct {
+	ID        int64  `json:"id"`
+	Command   string `json:"command"`
+	Timestamp int64  `json:"timestamp"`
+	ExitCode  int    `json:"exit_code"`
+}
+
+// handleShow prints the complete record of one entry: every column, its
+// amendments and the commands run around it in the same session
+func handleShow(idArg string, asJSON bool) {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil || id <= 0 {
+		i18n.Fprintf(os.Stderr, "Error: invalid entry id: %s\n", idArg)
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	entry, err := db.GetByID(id)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error finding entry %d: %v\n", id, err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	amendments, err := db.GetAmendments(id)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading amendments: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	before, after, err := db.SessionNeighbors(entry, showNeighbors)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading session: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	record := newShowRecord(entry, amendments, before, after)
+	if !asJSON {
+		record.writeText(os.Stdout)
+		return
+	}
+	if err := record.writeJSON(os.Stdout); err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+func newShowRecord(entry *storage.HistoryEntry, amendments []*storage.Amendment, before, after []*storage.HistoryEntry) *showRecord {
+	r := &showRecord{
+		ID:          entry.ID,
+		Command:     entry.Command,
+		Timestamp:   entry.Timestamp,
+		ApproxTime:  entry.ApproxTime,
+		Redacted:    entry.Redacted,
+		ExitCode:    entry.ExitCode,
+		DurationMs:  entry.DurationMs,
+		Cwd:         entry.Cwd,
+		GitBranch:   entry.GitBranch,
+		Hostname:    entry.Hostname,
+		User:        entry.User,
+		Shell:       entry.Shell,
+		SessionID:   entry.SessionID,
+		Profile:     entry.Profile,
+		ExecRuntime: entry.ExecRuntime,
+		ExecTarget:  entry.ExecTarget,
+		JobID:       entry.JobID,
+		Program:     entry.Program,
+		Note:        entry.Note,
+		Hash:        entry.Hash,
+		Amendments:  []showAmendment{},
+		Before:      neighbors(before),
+		After:       neighbors(after),
+	}
+	for _, a := range amendments {
+		r.Amendments = append(r.Amendments, showAmendment{
+			Field:     a.Field,
+			OldValue:  a.OldValue,
+			NewValue:  a.NewValue,
+			AmendedAt: a.AmendedAt,
+		})
+	}
+	return r
+}
+
+func neighbors(entries []*storage.HistoryEntry) []showNeighbor {
+	out := []showNeighbor{}
+	for _, e := range entries {
+		out = append(out, showNeighbor{ID: e.ID, Command: e.Command, Timestamp: e.Timestamp, ExitCode: e.ExitCode})
+	}
+	return out
+}
+
+func (r *showRecord) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// writeText writes the record for people, empty fields left out
+func (r *showRecord) writeText(w io.Writer) {
+	const timeFormat = "2006-01-02 15:04:05"
+
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%-10s %s\n", label+":", value)
+		}
+	}
+
+	field("ID", strconv.FormatInt(r.ID, 10))
+	command := r.Command
+	if r.Redacted {
+		command += " (secrets masked)"
+	}
+	field("Command", command)
+	when := time.Unix(r.Timestamp, 0).Format(timeFormat)
+	if r.ApproxTime {
+		when += " (approximate, imported without a time)"
+	}
+	field("Time", when)
+	field("Exit", strconv.Itoa(r.ExitCode))
+	if r.DurationMs > 0 {
+		field("Duration", (time.Duration(r.DurationMs) * time.Millisecond).String())
+	}
+	field("Cwd", r.Cwd)
+	field("Branch", r.GitBranch)
+	field("Host", r.Hostname)
+	field("User", r.User)
+	field("Shell", r.Shell)
+	field("Session", r.SessionID)
+	field("Profile", r.Profile)
+	if r.ExecTarget != "" {
+		field("Inside", fmt.Sprintf("%s (%s)", r.ExecTarget, r.ExecRuntime))
+	}
+	if r.JobID != 0 {
+		field("Job", strconv.FormatInt(r.JobID, 10))
+	}
+	field("Program", r.Program)
+	field("Note", r.Note)
+
+	if len(r.Amendments) > 0 {
+		fmt.Fprintf(w, "\nAmendments:\n")
+		for _, a := range r.Amendments {
+			when := time.Unix(a.AmendedAt, 0).Format(timeFormat)
+			fmt.Fprintf(w, "  %s  %s: %q -> %q\n", when, a.Field, a.OldValue, a.NewValue)
+		}
+	}
+
+	if len(r.Before)+len(r.After) > 0 {
+		fmt.Fprintf(w, "\nSession:\n")
+		line := func(marker string, n showNeighbor) {
+			fmt.Fprintf(w, "%s %6d  %s  %s\n", marker, n.ID, time.Unix(n.Timestamp, 0).Format("15:04:05"), n.Command)
+		}
+		for _, n := range r.Before {
+			line(" ", n)
+		}
+		line(">", showNeighbor{ID: r.ID, Command: r.Command, Timestamp: r.Timestamp})
+		for _, n := range r.After {
+			line(" ", n)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/sink"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// sinkSaveTimeout bounds how long a save spends sending to the sink; the
+// hooks save in the background, so the prompt never waits for it
+const sinkSaveTimeout = 10 * time.Second
+
+// sinkSaveBatches is how many batches a save sends at most, a backlog is
+// worked off over the next saves
+const sinkSaveBatches = 4
+
+// flushOptions returns the sink of the config and how to flush db to it,
+// nil when no sink is configured. Offline mode fails with config.ErrOffline.
+func flushOptions(cfg *config.Config, db *storage.DB) (sink.Sink, sink.FlushOptions, error) {
+	if cfg.Sink.URL == "" {
+		return nil, sink.FlushOptions{}, nil
+	}
+	if cfg.Offline() {
+		return nil, sink.FlushOptions{}, config.ErrOffline
+	}
+	s, err := sink.Open(cfg.Sink.URL, sink.Options{TokenCommand: cfg.Sink.TokenCommand})
+	if err != nil {
+		return nil, sink.FlushOptions{}, err
+	}
+	return s, sink.FlushOptions{
+		Name:      sink.Name(cfg.Sink.URL),
+		Source:    sink.Source(capture.HostID(), db.Path()),
+		BatchSize: cfg.Sink.BatchSize,
+		Interval:  cfg.GetSinkInterval(),
+	}, nil
+}
+
+// flushSinkAfterSave sends new entries to the sink when one is configured
+// and due. Saves stay silent: failures are kept for fh --sink status.
+func flushSinkAfterSave(cfg *config.Config, db *storage.DB) {
+	s, opts, err := flushOptions(cfg, db)
+	if s == nil || err != nil || db.IsMemory() {
+		return
+	}
+	opts.MaxBatches = sinkSaveBatches
+
+	ctx, cancel := context.WithTimeout(context.Background(), sinkSaveTimeout)
+	defer cancel()
+	_, _ = sink.Flush(ctx, db, s, opts)
+}
+
+// handleSink shows how far the sink has got, or sends everything pending
+func handleSink(args []string) {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "status" && args[0] != "flush") {
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --sink [status | flush]\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	if cfg.Sink.URL == "" {
+		i18n.Fprintf(os.Stderr, "Error: no sink configured, set sink.url in the config\n")
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	if len(args) == 1 && args[0] == "flush" {
+		s, opts, err := flushOptions(cfg, db)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+
+		// Asked for, so not held back by the interval
+		opts.Interval = 0
+		sent, err := sink.Flush(context.Background(), db, s, opts)
+		i18n.Printf("Sent %d entries to %s\n", sent, opts.Name)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		return
+	}
+
+	state, err := db.GetSinkState(sink.Name(cfg.Sink.URL))
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	fmt.Printf("%-14s %s\n", i18n.T("Sink:"), state.Sink)
+	fmt.Printf("%-14s %d\n", i18n.T("Sent up to:"), state.LastID)
+	fmt.Printf("%-14s %d\n", i18n.T("Pending:"), state.Pending)
+	if state.LastAttempt > 0 {
+		fmt.Printf("%-14s %s\n", i18n.T("Last attempt:"), time.Unix(state.LastAttempt, 0).Format("2006-01-02 15:04:05"))
+	}
+	if state.LastError != "" {
+		fmt.Printf("%-14s %s\n", i18n.T("Last error:"), state.LastError)
+	}
+}
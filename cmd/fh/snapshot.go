@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleSnapshot lists, takes, restores or deletes snapshots of the
+// database
+func handleSnapshot(args []string) {
+	action := "list"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch {
+	case (action == "list" || action == "backup") && len(args) <= 1:
+	case (action == "create" || action == "restore" || action == "delete") && len(args) == 2:
+	default:
+		i18n.Fprintf(os.Stderr, "Error: usage: fh --snapshot [list | create <name> | backup | restore <name> | delete <name>]\n")
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	switch action {
+	case "create":
+		snapshot, err := db.CreateSnapshot(args[1])
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Created snapshot %s (%s)\n", snapshot.Name, snapshot.Path)
+
+	case "backup":
+		// Rotated with the automatic snapshots, so a timer can run it forever
+		snapshot, err := db.AutoSnapshot("backup")
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if snapshot == nil {
+			i18n.Fprintf(os.Stderr, "Error: automatic snapshots are off, set storage.auto_snapshots\n")
+			os.Exit(exitConfig)
+		}
+		i18n.Printf("Created snapshot %s (%s)\n", snapshot.Name, snapshot.Path)
+
+	case "restore":
+		if err := db.RestoreSnapshot(args[1]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Restored snapshot %s\n", args[1])
+
+	case "delete":
+		if err := db.DeleteSnapshot(args[1]); err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		i18n.Printf("Deleted snapshot %s\n", args[1])
+
+	default:
+		snapshots, err := db.Snapshots()
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if len(snapshots) == 0 {
+			i18n.Fprintf(os.Stderr, "No snapshots\n")
+			os.Exit(exitNoResults)
+		}
+		for _, s := range snapshots {
+			fmt.Printf("%-40s %s  %8d KiB\n", s.Name, s.Created.Format("2006-01-02 15:04:05"), s.Size/1024)
+		}
+	}
+}
+
+// autoSnapshot snapshots the database before a destructive command, as
+// storage.auto_snapshots asks, and stops the command when that fails
+func autoSnapshot(db *storage.DB, reason string) {
+	snapshot, err := db.AutoSnapshot(reason)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v (set storage.auto_snapshots: 0 to go on without one)\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	if snapshot != nil {
+		i18n.Fprintf(os.Stderr, "Took snapshot %s, undo with: fh --snapshot restore %s\n", snapshot.Name, snapshot.Name)
+	}
+}
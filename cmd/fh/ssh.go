@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleSSH runs ssh under script(1) and saves the commands typed in the
+// remote session, tagged with the remote hostname
+func handleSSH(args []string) {
+	host := capture.SSHHost(args)
+	if host == "" {
+		i18n.Fprintf(os.Stderr, "Error: no destination host in ssh arguments\n")
+		os.Exit(exitUsage)
+	}
+
+	// The typescript holds everything printed in the session, keep it private
+	typescript, err := os.CreateTemp("", "fh-ssh-*.typescript")
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error creating typescript file: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	_ = typescript.Close()
+	defer func() {
+		_ = os.Remove(typescript.Name())
+	}()
+
+	start := time.Now()
+	cmd := capture.ScriptCommand(typescript.Name(), append([]string{"ssh"}, args...))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			i18n.Fprintf(os.Stderr, "Error running ssh: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	if err := saveSSHSession(typescript.Name(), host, start); err != nil {
+		i18n.Fprintf(os.Stderr, "fh: %v\n", err)
+	}
+
+	os.Exit(exitCode)
+}
+
+// saveSSHSession parses the typescript and stores the remote commands
+func saveSSHSession(path, host string, start time.Time) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read typescript: %w", err)
+	}
+
+	commands := capture.ParseTypescript(data)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := storage.OpenWithOptions(cfg.GetDatabasePath(), cfg.GetStorageOptions())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	meta, err := capture.Collect("", 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to collect metadata: %w", err)
+	}
+
+	dedupConfig := cfg.GetDedupConfig()
+	ignore := ignoreFilter(cfg)
+	sessionID := fmt.Sprintf("ssh-%s-%d", host, start.Unix())
+	profile := config.ActiveProfile()
+
+	// The typescript has no timing, so commands get the session start time
+	// plus their position to keep them in order
+	saved := 0
+	for i, command := range commands {
+		entry := &storage.HistoryEntry{
+			Timestamp: start.Unix() + int64(i),
+			Command:   command,
+			Hostname:  host,
+			User:      meta.User,
+			Shell:     "ssh",
+			SessionID: sessionID,
+			Profile:   profile,
+		}
+		if ctx := capture.ParseExecContext(command); ctx != nil {
+			entry.ExecRuntime = ctx.Runtime
+			entry.ExecTarget = ctx.Target
+		}
+		entry.Redact()
+		if skipIgnored(ignore, db, entry) {
+			continue
+		}
+		if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
+			return fmt.Errorf("failed to save command: %w", err)
+		}
+		saved++
+	}
+
+	i18n.Fprintf(os.Stderr, "fh: saved %d commands from %s\n", saved, host)
+	return nil
+}
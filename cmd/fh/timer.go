@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/schedule"
+)
+
+// handleInstallTimer writes and starts a systemd user timer, or a launchd
+// agent on macOS, that runs a maintenance job. With print the files are
+// only shown, with remove the job is stopped and its files deleted.
+func handleInstallTimer(name string, print, remove bool) {
+	job, ok := schedule.Jobs[name]
+	if !ok {
+		i18n.Fprintf(os.Stderr, "Error: unknown job %q (must be %s)\n", name, strings.Join(schedule.JobNames(), ", "))
+		os.Exit(exitUsage)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	platform := schedule.CurrentPlatform()
+
+	if remove {
+		runAll(job.Deactivate(platform, home))
+		removed, err := job.Uninstall(platform, home)
+		if err != nil {
+			i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		if !removed {
+			i18n.Fprintf(os.Stderr, "No %s timer installed\n", name)
+			os.Exit(exitNoResults)
+		}
+		i18n.Printf("Removed the %s timer\n", name)
+		return
+	}
+
+	// The timer must find the same binary without the shell's PATH
+	fhPath, err := os.Executable()
+	if err == nil {
+		fhPath, err = filepath.EvalSymlinks(fhPath)
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error finding the fh binary: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if print {
+		for _, f := range job.Files(platform, fhPath, home) {
+			fmt.Printf("# %s\n%s\n", f.Path, f.Content)
+		}
+		return
+	}
+
+	files, err := job.Install(platform, fhPath, home)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	for _, f := range files {
+		i18n.Printf("✓ Wrote %s\n", f.Path)
+	}
+	if !runAll(job.Activate(platform, home)) {
+		os.Exit(exitConfig)
+	}
+	i18n.Printf("✓ %s runs %s\n", name, strings.Join(append([]string{"fh"}, job.Args...), " "))
+}
+
+// runAll runs commands in order, stopping at the first that fails and
+// telling how to run the rest by hand
+func runAll(commands [][]string) bool {
+	for i, args := range commands {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error running %s: %v\n", args[0], err)
+			i18n.Fprintf(os.Stderr, "Run it yourself:\n")
+			for _, rest := range commands[i:] {
+				fmt.Fprintf(os.Stderr, "  %s\n", strings.Join(rest, " "))
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// handleRunJob runs a maintenance job the way its timer does, pinging
+// hooks.healthcheck_url when it starts and when it succeeds or fails, so a
+// job that stopped working gets noticed
+func handleRunJob(name string) {
+	job, ok := schedule.Jobs[name]
+	if !ok {
+		i18n.Fprintf(os.Stderr, "Error: unknown job %q (must be %s)\n", name, strings.Join(schedule.JobNames(), ", "))
+		os.Exit(exitUsage)
+	}
+
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	healthcheck := cfg.Hooks.HealthcheckURL
+	if healthcheck != "" && cfg.Offline() {
+		i18n.Fprintf(os.Stderr, "Warning: %v\n", config.ErrOffline)
+		healthcheck = ""
+	}
+	ping := func(outcome schedule.Outcome, output []byte) {
+		if healthcheck == "" {
+			return
+		}
+		// A monitor that is down must not fail the job
+		if err := schedule.Ping(context.Background(), nil, healthcheck, name, outcome, output); err != nil {
+			i18n.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	fhPath, err := os.Executable()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error finding the fh binary: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	ping(schedule.Started, nil)
+
+	// The same writer for both streams keeps their lines in order
+	var output bytes.Buffer
+	out := io.MultiWriter(os.Stdout, &output)
+	cmd := exec.Command(fhPath, job.Args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	err = cmd.Run()
+	if err == nil {
+		ping(schedule.Succeeded, output.Bytes())
+		return
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		ping(schedule.Failed, output.Bytes())
+		os.Exit(exitErr.ExitCode())
+	}
+	i18n.Fprintf(os.Stderr, "Error running %s: %v\n", name, err)
+	fmt.Fprintf(&output, "%v\n", err)
+	ping(schedule.Failed, output.Bytes())
+	os.Exit(exitCodeFor(err))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/search"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+func handleTop(limit int, halfLifeDays float64, profileName string, allProfiles bool) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// A negative value means the flag was not given
+	halfLife := cfg.GetHalfLife()
+	if halfLifeDays >= 0 {
+		halfLife = time.Duration(halfLifeDays * float64(24*time.Hour))
+	}
+
+	profile := selectedProfile(profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	ranked, err := search.Top(db, profileFilter(profile, allProfiles), search.RankOptions{HalfLife: halfLife}, limit)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if len(ranked) == 0 {
+		i18n.Printf("No commands in history yet.\n")
+		os.Exit(exitNoResults)
+	}
+
+	for i, r := range ranked {
+		displayCmd := r.Entry.Command
+		if len(displayCmd) > 60 {
+			displayCmd = displayCmd[:57] + "..."
+		}
+		fmt.Printf("%3d. (%4d | %7.1f) %s\n", i+1, r.Count, r.Score, displayCmd)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleVariants lists the groups of commands starting with prefix that
+// differ in one word, at most limit of them (0 = all)
+func handleVariants(prefix string, limit int, profileName string, allProfiles bool) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	profile := selectedProfile(profileName)
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(profile), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	commands, err := db.TopCommands(profileFilter(profile, allProfiles), 0)
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error querying history: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	groups := stats.FindVariants(commands, prefix)
+	if len(groups) == 0 {
+		if prefix == "" {
+			i18n.Printf("No commands differing in a single word in history.\n")
+		} else {
+			i18n.Printf("No variants of %q in history.\n", prefix)
+		}
+		os.Exit(exitNoResults)
+	}
+
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(group.Format())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/i18n"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// handleVerify checks the signatures of the history and lists entries
+// changed outside fh since they were saved
+func handleVerify(profileName string) {
+	// Load configuration
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Open database
+	db, err := storage.OpenWithOptions(cfg.GetProfileDatabasePath(selectedProfile(profileName)), cfg.GetStorageOptions())
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			i18n.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	v, err := db.Verify()
+	if errors.Is(err, storage.ErrNoSigningKey) {
+		i18n.Fprintf(os.Stderr, "Error: entry signing is off, set signing.enabled in the config\n")
+		os.Exit(exitConfig)
+	}
+	if err != nil {
+		i18n.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	i18n.Printf("Checked %d entries: %d unsigned, %d changed outside fh\n", v.Checked, v.Unsigned, len(v.Tampered))
+	if len(v.Tampered) == 0 {
+		return
+	}
+	for _, entry := range v.Tampered {
+		when := time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04:05")
+		fmt.Printf("  %6d  %s  %s\n", entry.ID, when, entry.Command)
+	}
+	os.Exit(exitError)
+}
@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Answer is the answer to a question as entries of the history, for
+// scripts and pickers to use instead of prose
+type Answer struct {
+	Entries []AnswerEntry `json:"entries"`
+	Matched int           `json:"matched"` // Commands the query found
+	Sent    int           `json:"sent"`    // Of those, the ones within ai.max_rows and ai.max_total_tokens
+
+	// Fallback is set when the AI provider was unavailable and the entries
+	// come from a plain search for the words of the question
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// AnswerEntry is a command that answers the question, and why. Everything
+// but the reason comes from the database, not from the model.
+type AnswerEntry struct {
+	ID        int64  `json:"id"`
+	Command   string `json:"command"`
+	Timestamp int64  `json:"timestamp"`
+	Cwd       string `json:"cwd"`
+	Reason    string `json:"reason"`
+}
+
+// ErrInvalidAnswer is returned when the model's reply doesn't follow
+// answerSchema, even after being told what was wrong
+var ErrInvalidAnswer = errors.New("model did not return a valid structured answer")
+
+// answerAttempts is how often the model gets to reply before giving up
+const answerAttempts = 2
+
+// pick is an entry the model picked, by its number in the prompt
+type pick struct {
+	Ref    int    `json:"ref"`
+	Reason string `json:"reason"`
+}
+
+// AskStructured answers userQuery with the entries of the history that
+// answer it. Large results are picked from chunk by chunk. While the AI
+// provider is unavailable, the entries come from a plain search.
+func AskStructured(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (*Answer, error) {
+	if err := checkEnabled(cfg); err != nil {
+		return nil, err
+	}
+
+	b := newBreaker(cfg)
+	if until, open := b.openUntil(); open {
+		if debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] AI provider unavailable until %s, using plain search\n", until.Format(time.TimeOnly))
+		}
+		return heuristicStructured(db, userQuery)
+	}
+
+	answer, err := askStructured(db, userQuery, cfg, debug)
+	if _, tripped := b.record(err); tripped {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return heuristicStructured(db, userQuery)
+	}
+	return answer, err
+}
+
+// askStructured answers userQuery with the model
+func askStructured(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (*Answer, error) {
+	q, err := prepare(db, userQuery, cfg, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &Answer{Entries: []AnswerEntry{}, Matched: q.total, Sent: len(q.results)}
+	ctx := context.Background()
+	first := 1
+	for _, chunk := range chunkResults(q.sent, cfg.AI.MaxChunkTokens) {
+		picks, err := pickEntries(ctx, q.client, userQuery, chunk, first, cfg.GetAnswerLanguage(), debug)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range picks {
+			entry := q.results[p.Ref-1]
+			answer.Entries = append(answer.Entries, AnswerEntry{
+				ID:        entry.ID,
+				Command:   entry.Command,
+				Timestamp: entry.Timestamp,
+				Cwd:       entry.Cwd,
+				Reason:    p.Reason,
+			})
+		}
+		first += len(chunk)
+	}
+	return answer, nil
+}
+
+// pickEntries asks the model which entries of chunk, numbered from first,
+// answer userQuery, with reasons in language. A reply that breaks the schema
+// is sent back once with what was wrong.
+func pickEntries(ctx context.Context, client Querier, userQuery string, chunk []*storage.HistoryEntry, first int, language string, debug bool) ([]pick, error) {
+	prompt := GenerateStructuredPrompt(userQuery, chunk, first) + AnswerLanguagePrompt(language)
+	last := first + len(chunk) - 1
+
+	var lastErr error
+	for attempt := 1; attempt <= answerAttempts; attempt++ {
+		request := prompt
+		if lastErr != nil {
+			request = GenerateStructuredRetryPrompt(prompt, lastErr.Error())
+			if debug {
+				fmt.Fprintf(os.Stderr, "[DEBUG] Retry attempt %d/%d - Previous error: %s\n",
+					attempt, answerAttempts, lastErr)
+			}
+		}
+
+		response, err := client.Query(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get structured answer: %w", err)
+		}
+
+		picks, err := parseAnswer(response, first, last)
+		if err == nil {
+			return picks, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrInvalidAnswer, lastErr)
+}
+
+// parseAnswer parses a reply following answerSchema, checking that every
+// ref is one of first to last and picked once, with a reason
+func parseAnswer(response string, first, last int) ([]pick, error) {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+
+	var reply struct {
+		Entries *[]pick `json:"entries"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(strings.TrimSpace(response))))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&reply); err != nil {
+		return nil, fmt.Errorf("not a JSON object following the schema: %v", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("text after the JSON object")
+	}
+	if reply.Entries == nil {
+		return nil, errors.New(`missing "entries"`)
+	}
+
+	seen := map[int]bool{}
+	for _, p := range *reply.Entries {
+		if p.Ref < first || p.Ref > last {
+			return nil, fmt.Errorf("ref %d is not one of the commands (%d to %d)", p.Ref, first, last)
+		}
+		if seen[p.Ref] {
+			return nil, fmt.Errorf("ref %d is listed twice", p.Ref)
+		}
+		if strings.TrimSpace(p.Reason) == "" {
+			return nil, fmt.Errorf("ref %d has no reason", p.Ref)
+		}
+		seen[p.Ref] = true
+	}
+	return *reply.Entries, nil
+}
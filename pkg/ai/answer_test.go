@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnswer(t *testing.T) {
+	require.True(t, json.Valid([]byte(answerSchema)))
+
+	picks, err := parseAnswer(`{"entries": [{"ref": 3, "reason": "Deploys to staging"}, {"ref": 1, "reason": "Builds the image"}]}`, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []pick{{Ref: 3, Reason: "Deploys to staging"}, {Ref: 1, Reason: "Builds the image"}}, picks)
+
+	// Code blocks are tolerated, as models add them anyway
+	picks, err = parseAnswer("```json\n{\"entries\": []}\n```", 1, 3)
+	require.NoError(t, err)
+	assert.Empty(t, picks)
+}
+
+func TestParseAnswer_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{"prose", "The deploy command was kubectl apply", "not a JSON object"},
+		{"missing entries", `{}`, `missing "entries"`},
+		{"unknown field", `{"entries": [{"ref": 1, "reason": "x", "command": "make"}]}`, "unknown field"},
+		{"ref out of chunk", `{"entries": [{"ref": 4, "reason": "x"}]}`, "ref 4 is not one of the commands (1 to 3)"},
+		{"ref twice", `{"entries": [{"ref": 2, "reason": "x"}, {"ref": 2, "reason": "y"}]}`, "ref 2 is listed twice"},
+		{"no reason", `{"entries": [{"ref": 2, "reason": " "}]}`, "ref 2 has no reason"},
+		{"trailing text", `{"entries": []} {"entries": []}`, "text after"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAnswer(tt.response, 1, 3)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Anthropic API settings: ai.base_url replaces the URL, and answers are
+// capped at anthropicMaxTokens, which the API requires to be set
+const (
+	AnthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// AnthropicClient asks a Claude model through the Anthropic Messages API
+type AnthropicClient struct {
+	api     *httpAPI
+	baseURL string
+	key     string
+	model   string
+	debug   bool // Print every prompt sent
+}
+
+// NewAnthropicClient creates a client of the Claude model, authenticated
+// with ANTHROPIC_API_KEY
+func NewAnthropicClient(model string, opts ClientOptions) (*AnthropicClient, error) {
+	key, err := apiKey("ANTHROPIC_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	api, err := newHTTPAPI("Anthropic", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := AnthropicBaseURL
+	if opts.BaseURL != "" {
+		baseURL = opts.BaseURL
+	}
+	return &AnthropicClient{api: api, baseURL: strings.TrimSuffix(baseURL, "/"), key: key, model: model}, nil
+}
+
+// anthropicMessage is a message of the Messages API
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Query sends a prompt to Anthropic and returns the response
+func (c *AnthropicClient) Query(ctx context.Context, prompt string) (string, error) {
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Sending to %s:\n%s\n[DEBUG] End of prompt\n", c.model, prompt)
+	}
+
+	request := struct {
+		Model     string             `json:"model"`
+		MaxTokens int                `json:"max_tokens"`
+		Messages  []anthropicMessage `json:"messages"`
+	}{
+		Model:     c.model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	}
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", c.key)
+	header.Set("anthropic-version", anthropicVersion)
+	if err := c.api.post(ctx, c.baseURL+"/v1/messages", header, request, &response); err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no response from Anthropic: %w", errNoText)
+	}
+	return text.String(), nil
+}
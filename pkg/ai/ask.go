@@ -2,33 +2,123 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/redact"
 	"github.com/spideyz0r/fh/pkg/stats"
 	"github.com/spideyz0r/fh/pkg/storage"
 )
 
-// Ask performs an AI-powered search query
+// ErrDisabled is returned when AI search is turned off in the config
+var ErrDisabled = errors.New("AI search is disabled in configuration")
+
+// Ask performs an AI-powered search query. While the AI provider is
+// unavailable, the history is searched for the words of the question
+// instead.
 func Ask(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (string, error) {
-	// Check if AI is enabled
+	if err := checkEnabled(cfg); err != nil {
+		return "", err
+	}
+
+	b := newBreaker(cfg)
+	if until, open := b.openUntil(); open {
+		if debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] AI provider unavailable until %s, using plain search\n", until.Format(time.TimeOnly))
+		}
+		return heuristicAnswer(db, userQuery, until)
+	}
+
+	output, err := ask(db, userQuery, cfg, debug)
+	if until, tripped := b.record(err); tripped {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return heuristicAnswer(db, userQuery, until)
+	}
+	return output, err
+}
+
+// ask answers userQuery with the model
+func ask(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (string, error) {
+	q, err := prepare(db, userQuery, cfg, debug)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if we got results
+	if len(q.results) == 0 {
+		return withFooter("Could not find any data for that specific query", q, cfg), nil
+	}
+
+	// Phase 3: Format results (with chunking if needed)
+	output, err := formatResults(q.client, userQuery, q.sent, cfg.AI.MaxChunkTokens, cfg.GetAnswerLanguage())
+	if err != nil {
+		return "", err
+	}
+
+	if len(q.results) < q.total {
+		output += "\n\n" + truncationNote(len(q.results), q.total)
+	}
+	return withFooter(output, q, cfg), nil
+}
+
+// withFooter ends output with the footer of q, when ai.footer is on
+func withFooter(output string, q *question, cfg *config.Config) string {
+	if !cfg.AI.Footer {
+		return output
+	}
+	return output + "\n\n" + footer(q.sql, q.total)
+}
+
+// footer tells on one line which SQL an answer is based on, and how many
+// rows it found
+func footer(sqlQuery string, rows int) string {
+	unit := "rows"
+	if rows == 1 {
+		unit = "row"
+	}
+	return fmt.Sprintf("-- %d %s from: %s", rows, unit, strings.Join(strings.Fields(sqlQuery), " "))
+}
+
+// question is a question with the history found to answer it
+type question struct {
+	client  Querier
+	sql     string                  // The query the history was found with
+	results []*storage.HistoryEntry // As stored, within the caps
+	sent    []*storage.HistoryEntry // results as the model gets them
+	total   int                     // How many the query found
+}
+
+// checkEnabled returns why questions can't be asked, if they can't
+func checkEnabled(cfg *config.Config) error {
+	if cfg.AIOffline() {
+		return config.ErrOffline
+	}
 	if !cfg.AI.Enabled {
-		return "", fmt.Errorf("AI search is disabled in configuration")
+		return ErrDisabled
 	}
+	return nil
+}
 
+// prepare runs the phases every answer needs: the model turns userQuery
+// into SQL, and the SQL finds the history to answer it from. The client
+// answering is picked by ai.model_policy once the history is known.
+func prepare(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (*question, error) {
 	// Create OpenAI client
-	client, err := NewOpenAIClient(cfg.AI.Model)
+	opts := clientOptions(cfg)
+	sqlModel := cfg.GetSQLModel()
+	client, err := newClient(sqlModel, opts, debug)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Get database statistics
 	statistics, err := stats.Collect(db)
 	if err != nil {
-		return "", fmt.Errorf("failed to collect database stats: %w", err)
+		return nil, fmt.Errorf("failed to collect database stats: %w", err)
 	}
 
 	if debug {
@@ -37,10 +127,23 @@ func Ask(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (stri
 			statistics.TotalCommands, statistics.UniqueCommands)
 	}
 
+	// Scrub history before it goes to the model
+	var scrubber *redact.Scrubber
+	promptStats := statistics
+	if cfg.AI.RedactContext {
+		scrubber = newScrubber(db)
+		promptStats = scrubStats(scrubber, statistics)
+	}
+
+	sqlPrompt := GenerateSQLPrompt(promptStats, userQuery)
+	if c := collectContext(db, cfg); c != nil {
+		sqlPrompt += c.scrubbed(scrubber).prompt()
+	}
+
 	// Phase 1: Generate SQL query with retry
-	sqlQuery, err := generateSQLWithRetry(client, statistics, userQuery, cfg.AI.MaxSQLRetries, debug)
+	sqlQuery, err := generateSQLWithRetry(client, sqlPrompt, cfg.AI.MaxSQLRetries, debug)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if debug {
@@ -50,29 +153,91 @@ func Ask(db *storage.DB, userQuery string, cfg *config.Config, debug bool) (stri
 	// Phase 2: Execute SQL query
 	results, err := executeSQLQuery(db, sqlQuery, time.Duration(cfg.AI.SQLTimeoutSecs)*time.Second, debug)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
 	if debug {
 		fmt.Fprintf(os.Stderr, "[DEBUG] Query returned %d results\n", len(results))
 	}
 
-	// Check if we got results
-	if len(results) == 0 {
-		return "Could not find any data for that specific query", nil
+	// Cap what is sent, every chunk is another paid request
+	q := &question{client: client, sql: sqlQuery, total: len(results)}
+	q.results = capResults(results, cfg.AI.MaxRows, cfg.AI.MaxTotalTokens)
+	if debug && len(q.results) < q.total {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Sending %d of %d results (ai.max_rows %d, ai.max_total_tokens %d)\n",
+			len(q.results), q.total, cfg.AI.MaxRows, cfg.AI.MaxTotalTokens)
 	}
 
-	// Phase 3: Format results (with chunking if needed)
-	output, err := formatResults(client, userQuery, results, cfg.AI.MaxChunkTokens)
-	if err != nil {
-		return "", err
+	q.sent = q.results
+	if scrubber != nil {
+		q.sent = scrubResults(scrubber, q.results)
+	}
+
+	// Phase 3 may want another model, e.g. a better one for many results
+	tokens := estimateTokens(q.sent)
+	if answerModel := cfg.GetAnswerModel(tokens); answerModel != sqlModel {
+		if debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Answering with %s (~%d tokens of results, ai.model_policy %s)\n",
+				answerModel, tokens, cfg.AI.ModelPolicy)
+		}
+		q.client, err = newClient(answerModel, opts, debug)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return q, nil
+}
 
-	return output, nil
+// clientOptions returns the options of the configured provider
+func clientOptions(cfg *config.Config) ClientOptions {
+	opts := ClientOptions{
+		Provider:   cfg.AI.Provider,
+		Timeout:    time.Duration(cfg.AI.RequestTimeoutSecs) * time.Second,
+		MaxRetries: cfg.AI.MaxRetries,
+		BaseURL:    cfg.AI.BaseURL,
+		CACert:     cfg.GetAICACert(),
+	}
+	if cfg.AI.Provider == ProviderAzure {
+		opts.Azure = &cfg.AI.Azure
+	}
+	return opts
 }
 
-// generateSQLWithRetry attempts to generate a valid SQL query with retries
-func generateSQLWithRetry(client *OpenAIClient, statistics *stats.Stats, userQuery string, maxRetries int, debug bool) (string, error) {
+// NewSQLClient returns the client questions are turned into SQL with
+func NewSQLClient(cfg *config.Config) (Querier, error) {
+	if err := checkEnabled(cfg); err != nil {
+		return nil, err
+	}
+	return newClient(cfg.GetSQLModel(), clientOptions(cfg), false)
+}
+
+// capResults returns the leading results within maxRows and maxTokens, the
+// query's own order deciding what is kept. A limit of 0 is no limit.
+func capResults(results []*storage.HistoryEntry, maxRows, maxTokens int) []*storage.HistoryEntry {
+	if maxRows > 0 && len(results) > maxRows {
+		results = results[:maxRows]
+	}
+	if maxTokens > 0 {
+		tokens := 0
+		for i, entry := range results {
+			tokens += entryTokens(entry)
+			if tokens > maxTokens {
+				return results[:i]
+			}
+		}
+	}
+	return results
+}
+
+// truncationNote tells that the answer only covers part of the results
+func truncationNote(sent, total int) string {
+	return fmt.Sprintf("Note: this answer is based on the first %d of %d matching commands. "+
+		"Ask a narrower question, or raise ai.max_rows and ai.max_total_tokens in the config.", sent, total)
+}
+
+// generateSQLWithRetry attempts to generate a valid SQL query with retries,
+// prompt asking for it the first time
+func generateSQLWithRetry(client Querier, sqlPrompt string, maxRetries int, debug bool) (string, error) {
 	ctx := context.Background()
 	var lastSQL string
 	var lastError string
@@ -81,7 +246,7 @@ func generateSQLWithRetry(client *OpenAIClient, statistics *stats.Stats, userQue
 		var prompt string
 		if attempt == 1 {
 			// First attempt - use full prompt
-			prompt = GenerateSQLPrompt(statistics, userQuery)
+			prompt = sqlPrompt
 		} else {
 			// Retry - use error feedback
 			prompt = GenerateSQLRetryPrompt(lastSQL, lastError)
@@ -192,8 +357,9 @@ func executeSQLQuery(db *storage.DB, sqlQuery string, timeout time.Duration, deb
 	return results, nil
 }
 
-// formatResults formats query results using OpenAI, with chunking for large result sets
-func formatResults(client *OpenAIClient, userQuery string, results []*storage.HistoryEntry, maxChunkTokens int) (string, error) {
+// formatResults formats query results using OpenAI, with chunking for large
+// result sets, answering in language ("" = left to the model)
+func formatResults(client Querier, userQuery string, results []*storage.HistoryEntry, maxChunkTokens int, language string) (string, error) {
 	ctx := context.Background()
 
 	// Estimate tokens (rough: ~4 chars per token)
@@ -201,7 +367,7 @@ func formatResults(client *OpenAIClient, userQuery string, results []*storage.Hi
 
 	// If small enough, format in one go
 	if estimatedTokens < maxChunkTokens {
-		prompt := GenerateFormatPrompt(userQuery, results)
+		prompt := GenerateFormatPrompt(userQuery, results) + AnswerLanguagePrompt(language)
 		response, err := client.Query(ctx, prompt)
 		if err != nil {
 			return "", fmt.Errorf("failed to format results: %w", err)
@@ -223,7 +389,7 @@ func formatResults(client *OpenAIClient, userQuery string, results []*storage.Hi
 	}
 
 	// Final synthesis
-	finalPrompt := GenerateFinalSynthesisPrompt(userQuery, summaries)
+	finalPrompt := GenerateFinalSynthesisPrompt(userQuery, summaries) + AnswerLanguagePrompt(language)
 	finalResponse, err := client.Query(ctx, finalPrompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to synthesize final response: %w", err)
@@ -281,6 +447,11 @@ func estimateTokens(results []*storage.HistoryEntry) int {
 	return totalChars / 4
 }
 
+// entryTokens roughly estimates the tokens of a single result
+func entryTokens(entry *storage.HistoryEntry) int {
+	return (len(entry.Command) + len(entry.Cwd) + 30) / 4
+}
+
 // chunkResults splits results into chunks based on token limit
 func chunkResults(results []*storage.HistoryEntry, maxTokensPerChunk int) [][]*storage.HistoryEntry {
 	var chunks [][]*storage.HistoryEntry
@@ -288,15 +459,15 @@ func chunkResults(results []*storage.HistoryEntry, maxTokensPerChunk int) [][]*s
 	currentTokens := 0
 
 	for _, entry := range results {
-		entryTokens := (len(entry.Command) + len(entry.Cwd) + 30) / 4
-		if currentTokens+entryTokens > maxTokensPerChunk && len(currentChunk) > 0 {
+		tokens := entryTokens(entry)
+		if currentTokens+tokens > maxTokensPerChunk && len(currentChunk) > 0 {
 			// Chunk is full, start a new one
 			chunks = append(chunks, currentChunk)
 			currentChunk = []*storage.HistoryEntry{entry}
-			currentTokens = entryTokens
+			currentTokens = tokens
 		} else {
 			currentChunk = append(currentChunk, entry)
-			currentTokens += entryTokens
+			currentTokens += tokens
 		}
 	}
 
@@ -1,10 +1,12 @@
 package ai
 
 import (
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spideyz0r/fh/pkg/config"
 	"github.com/spideyz0r/fh/pkg/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -537,3 +539,42 @@ func TestExecuteSQLQuery(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestCapResults(t *testing.T) {
+	results := make([]*storage.HistoryEntry, 10)
+	for i := range results {
+		// 40 + 30 characters, 17 tokens each
+		results[i] = &storage.HistoryEntry{Command: fmt.Sprintf("%040d", i)}
+	}
+
+	assert.Len(t, capResults(results, 0, 0), 10)
+	assert.Len(t, capResults(results, 4, 0), 4)
+	assert.Len(t, capResults(results, 0, 50), 2)
+	assert.Len(t, capResults(results, 4, 1000), 4)
+
+	// The query's order decides what is kept
+	capped := capResults(results, 3, 0)
+	assert.Equal(t, results[0].Command, capped[0].Command)
+	assert.Equal(t, results[2].Command, capped[2].Command)
+}
+
+func TestTruncationNote(t *testing.T) {
+	note := truncationNote(2000, 8431)
+	assert.Contains(t, note, "first 2000 of 8431")
+	assert.Contains(t, note, "ai.max_rows")
+}
+
+func TestFooter(t *testing.T) {
+	assert.Equal(t, "-- 1 row from: SELECT id FROM history WHERE exit_code != 0",
+		footer("SELECT id\n  FROM history\n  WHERE exit_code != 0", 1))
+	assert.Equal(t, "-- 0 rows from: SELECT id FROM history", footer("SELECT id FROM history", 0))
+}
+
+func TestAsk_Offline(t *testing.T) {
+	cfg := config.Default()
+	cfg.Privacy.Offline = true
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	_, err := Ask(nil, "what did I run today?", cfg, false)
+	assert.ErrorIs(t, err, config.ErrOffline)
+}
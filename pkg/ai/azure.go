@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/openai/openai-go/option"
+	"github.com/spideyz0r/fh/pkg/config"
+)
+
+// AzureAPIKeyEnv is the environment variable holding the Azure OpenAI key
+const AzureAPIKeyEnv = "AZURE_OPENAI_API_KEY"
+
+// ErrNoAzureCredentials is returned when Azure OpenAI has neither a key
+// nor a command for a token
+var ErrNoAzureCredentials = errors.New("AZURE_OPENAI_API_KEY environment variable not set and no ai.azure.token_command configured")
+
+// azureRequestOptions points the client at the Azure OpenAI deployment,
+// ai.model when no deployment is named. Requests are authenticated with a
+// Microsoft Entra ID (AAD) token when there is a command for one, and with
+// AZURE_OPENAI_API_KEY otherwise.
+func azureRequestOptions(azure *config.AzureConfig, model string) ([]option.RequestOption, error) {
+	deployment := azure.Deployment
+	if deployment == "" {
+		deployment = model
+	}
+
+	requestOptions := []option.RequestOption{
+		option.WithBaseURL(strings.TrimSuffix(azure.Endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/"),
+		option.WithQuery("api-version", azure.APIVersion),
+		// OPENAI_API_KEY is for OpenAI, not for Azure
+		option.WithHeaderDel("authorization"),
+	}
+
+	if strings.TrimSpace(azure.TokenCommand) != "" {
+		token, err := azureToken(azure.TokenCommand)
+		if err != nil {
+			return nil, err
+		}
+		return append(requestOptions, option.WithHeader("authorization", "Bearer "+token)), nil
+	}
+
+	apiKey := os.Getenv(AzureAPIKeyEnv)
+	if apiKey == "" {
+		return nil, ErrNoAzureCredentials
+	}
+	return append(requestOptions, option.WithHeader("api-key", apiKey)), nil
+}
+
+// azureToken runs command for an access token, e.g. az account
+// get-access-token --resource https://cognitiveservices.azure.com --query
+// accessToken -o tsv
+func azureToken(command string) (string, error) {
+	fields := strings.Fields(command)
+	out, err := exec.Command(fields[0], fields[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("ai.azure.token_command %s: %w", command, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("ai.azure.token_command %s printed no token", command)
+	}
+	return token, nil
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIClient_Azure(t *testing.T) {
+	var request *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv(AzureAPIKeyEnv, "azure-key")
+
+	azure := &config.AzureConfig{Endpoint: server.URL + "/", Deployment: "fh-mini", APIVersion: "2024-10-21"}
+	client, err := NewOpenAIClientWithOptions("gpt-4o-mini", ClientOptions{Azure: azure})
+	require.NoError(t, err)
+	_, err = client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/openai/deployments/fh-mini/chat/completions", request.URL.Path)
+	assert.Equal(t, "2024-10-21", request.URL.Query().Get("api-version"))
+	assert.Equal(t, "azure-key", request.Header.Get("api-key"))
+	assert.Empty(t, request.Header.Get("Authorization"))
+
+	// A Microsoft Entra ID token instead of the key, to ai.model's deployment
+	azure.Deployment = ""
+	azure.TokenCommand = "echo entra-token"
+	client, err = NewOpenAIClientWithOptions("gpt-4o-mini", ClientOptions{Azure: azure})
+	require.NoError(t, err)
+	_, err = client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/openai/deployments/gpt-4o-mini/chat/completions", request.URL.Path)
+	assert.Equal(t, "Bearer entra-token", request.Header.Get("Authorization"))
+	assert.Empty(t, request.Header.Get("api-key"))
+}
+
+func TestOpenAIClient_AzureNoCredentials(t *testing.T) {
+	t.Setenv(AzureAPIKeyEnv, "")
+
+	azure := &config.AzureConfig{Endpoint: "https://fh.openai.azure.com", APIVersion: "2024-10-21"}
+	_, err := NewOpenAIClientWithOptions("gpt-4o-mini", ClientOptions{Azure: azure})
+	assert.ErrorIs(t, err, ErrNoAzureCredentials)
+
+	azure.TokenCommand = "false"
+	_, err = NewOpenAIClientWithOptions("gpt-4o-mini", ClientOptions{Azure: azure})
+	assert.ErrorContains(t, err, "token_command")
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// breakerFile keeps the breaker's state next to the database, every
+// question being a run of its own
+const breakerFile = "ai-breaker.json"
+
+// breaker counts the questions in a row the API was unavailable for, and
+// once there are threshold of them, stops asking it for cooldown. The
+// first question after that tries again, and trips it again if it fails.
+type breaker struct {
+	path      string
+	threshold int // 0 = never trips
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// breakerState is what is stored of a breaker
+type breakerState struct {
+	Failures  int   `json:"failures"`
+	OpenUntil int64 `json:"open_until"` // Unix time, 0 = closed
+}
+
+// newBreaker returns the breaker of the configured database. Ephemeral
+// sessions persist nothing, so their breaker never trips.
+func newBreaker(cfg *config.Config) *breaker {
+	b := &breaker{
+		threshold: cfg.AI.BreakerThreshold,
+		cooldown:  time.Duration(cfg.AI.BreakerCooldownSecs) * time.Second,
+		now:       time.Now,
+	}
+	if path := cfg.GetDatabasePath(); storage.IsMemoryPath(path) {
+		b.threshold = 0
+	} else {
+		b.path = filepath.Join(filepath.Dir(path), breakerFile)
+	}
+	return b
+}
+
+// openUntil returns until when the API is not to be asked, if it isn't
+func (b *breaker) openUntil() (time.Time, bool) {
+	if b.threshold <= 0 {
+		return time.Time{}, false
+	}
+	state := b.load()
+	until := time.Unix(state.OpenUntil, 0)
+	return until, state.OpenUntil != 0 && b.now().Before(until)
+}
+
+// record counts how a question went. It reports whether err tripped the
+// breaker, and until when.
+func (b *breaker) record(err error) (time.Time, bool) {
+	if b.threshold <= 0 {
+		return time.Time{}, false
+	}
+	if err == nil {
+		_ = os.Remove(b.path)
+		return time.Time{}, false
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		return time.Time{}, false
+	}
+
+	state := b.load()
+	state.Failures++
+	tripped := state.Failures >= b.threshold
+	if tripped {
+		state.OpenUntil = b.now().Add(b.cooldown).Unix()
+	}
+	b.save(state)
+	return time.Unix(state.OpenUntil, 0), tripped
+}
+
+// load reads the state, a missing or broken file being a closed breaker
+func (b *breaker) load() breakerState {
+	var state breakerState
+	data, err := os.ReadFile(b.path)
+	if err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+// save writes the state. The breaker is best effort: a question is not
+// failed because its state couldn't be written.
+func (b *breaker) save(state breakerState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return
+	}
+	_ = os.WriteFile(b.path, data, 0600)
+}
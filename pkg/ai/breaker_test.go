@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := &breaker{
+		path:      filepath.Join(t.TempDir(), breakerFile),
+		threshold: 2,
+		cooldown:  5 * time.Minute,
+		now:       func() time.Time { return now },
+	}
+	unavailable := fmt.Errorf("%w: 503 Service Unavailable", ErrUnavailable)
+
+	_, tripped := b.record(unavailable)
+	assert.False(t, tripped)
+	_, open := b.openUntil()
+	assert.False(t, open)
+
+	// Errors the API gives every time don't count
+	_, tripped = b.record(errors.New("could not generate valid query after 3 attempts"))
+	assert.False(t, tripped)
+
+	until, tripped := b.record(unavailable)
+	assert.True(t, tripped)
+	assert.Equal(t, now.Add(5*time.Minute), until)
+	_, open = b.openUntil()
+	assert.True(t, open)
+
+	// After the cooldown the API is tried again, one failure trips it again
+	now = now.Add(6 * time.Minute)
+	_, open = b.openUntil()
+	assert.False(t, open)
+	_, tripped = b.record(unavailable)
+	assert.True(t, tripped)
+
+	// A success closes it
+	b.record(nil)
+	assert.NoFileExists(t, b.path)
+	_, tripped = b.record(unavailable)
+	assert.False(t, tripped)
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := &breaker{path: filepath.Join(t.TempDir(), breakerFile), now: time.Now}
+
+	_, tripped := b.record(ErrUnavailable)
+	assert.False(t, tripped)
+	assert.NoFileExists(t, b.path)
+}
+
+func TestNewBreaker_Ephemeral(t *testing.T) {
+	t.Setenv(config.DatabasePathEnv, storage.MemoryPath)
+
+	_, tripped := newBreaker(config.Default()).record(ErrUnavailable)
+	assert.False(t, tripped)
+}
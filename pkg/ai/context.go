@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// contextCommandChars caps each recent command told to the model, and
+// contextChars all of them, so a pasted script can't crowd out the question
+const (
+	contextCommandChars = 200
+	contextChars        = 2000
+)
+
+// askContext is where the question is asked from: questions like "what
+// did I just run" or "the last deploy from here" are about it
+type askContext struct {
+	Cwd    string
+	Branch string
+	Recent []string // Commands of the shell session, oldest first
+}
+
+// collectContext returns where the question is asked from, or nil when
+// ai.include_cwd_context is off. The recent commands are those of the
+// session the shell hooks export, there are none outside a hooked shell.
+func collectContext(db *storage.DB, cfg *config.Config) *askContext {
+	if !cfg.AI.IncludeCwdContext {
+		return nil
+	}
+
+	c := &askContext{}
+	if cwd, err := os.Getwd(); err == nil {
+		c.Cwd = cwd
+		c.Branch = capture.GitBranch(cwd)
+	}
+
+	session := os.Getenv(capture.SessionEnv)
+	entries, err := db.SessionRecent(session, cfg.AI.ContextCommands)
+	if err != nil {
+		return c
+	}
+	for _, entry := range entries {
+		c.Recent = append(c.Recent, entry.Command)
+	}
+	return c
+}
+
+// scrubbed returns a copy of c fit to send: secrets are always masked, and
+// with a scrubber the home, user and host names are hidden too. Long
+// commands are cut, and the oldest are left out past contextChars.
+func (c *askContext) scrubbed(scrubber *redact.Scrubber) *askContext {
+	clean := func(text string) string {
+		if scrubber != nil {
+			return scrubber.Scrub(text)
+		}
+		text, _ = redact.Command(text)
+		return redact.MaskFindings(text, redact.Scan(text))
+	}
+
+	out := &askContext{Cwd: clean(c.Cwd), Branch: clean(c.Branch)}
+	total := 0
+	for i := len(c.Recent) - 1; i >= 0; i-- {
+		command := clean(c.Recent[i])
+		if len(command) > contextCommandChars {
+			command = command[:contextCommandChars] + "..."
+		}
+		if total += len(command); total > contextChars {
+			break
+		}
+		out.Recent = append([]string{command}, out.Recent...)
+	}
+	return out
+}
+
+// prompt returns the context as the SQL prompt ends with, "" when there is
+// nothing to tell
+func (c *askContext) prompt() string {
+	if c == nil || (c.Cwd == "" && c.Branch == "" && len(c.Recent) == 0) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nThe user is asking from (use it when the question says here, this repo, just or recently):\n")
+	if c.Cwd != "" {
+		fmt.Fprintf(&b, "  Current directory: %s\n", c.Cwd)
+		if strings.HasPrefix(c.Cwd, "~/") {
+			// The stored cwd has the home directory written out
+			fmt.Fprintf(&b, "  (~ is the home directory, match it with cwd LIKE '%%%s')\n", strings.TrimPrefix(c.Cwd, "~"))
+		}
+	}
+	if c.Branch != "" {
+		fmt.Fprintf(&b, "  Git branch: %s\n", c.Branch)
+	}
+	if len(c.Recent) > 0 {
+		b.WriteString("  Recent commands of this shell session, oldest first:\n")
+		for _, command := range c.Recent {
+			fmt.Fprintf(&b, "    - %s\n", command)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
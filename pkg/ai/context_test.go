@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectContext(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	cfg := replayConfig(t)
+	assert.Nil(t, collectContext(db, cfg), "off by default")
+
+	for i, command := range []string{"cd api", "git pull", "make test"} {
+		require.NoError(t, db.Insert(&storage.HistoryEntry{
+			Timestamp: int64(1000 + i),
+			Command:   command,
+			SessionID: "s1",
+			Hash:      storage.GenerateHash(command),
+		}))
+	}
+	t.Setenv(capture.SessionEnv, "s1")
+	cfg.AI.IncludeCwdContext = true
+	cfg.AI.ContextCommands = 2
+
+	c := collectContext(db, cfg)
+	require.NotNil(t, c)
+	assert.NotEmpty(t, c.Cwd)
+	assert.Equal(t, []string{"git pull", "make test"}, c.Recent)
+}
+
+func TestAskContext_Scrubbed(t *testing.T) {
+	c := &askContext{
+		Cwd:    "/home/alice/src/api",
+		Branch: "main",
+		Recent: []string{
+			"mysql --password hunter2",
+			"cat " + strings.Repeat("x", contextCommandChars+50),
+		},
+	}
+
+	// Secrets are masked even when the rest isn't scrubbed
+	plain := c.scrubbed(nil)
+	assert.Equal(t, "/home/alice/src/api", plain.Cwd)
+	assert.NotContains(t, plain.Recent[0], "hunter2")
+	assert.Len(t, plain.Recent[1], contextCommandChars+len("..."))
+
+	scrubbed := c.scrubbed(redact.NewScrubber("/home/alice", []string{"alice"}, nil))
+	prompt := scrubbed.prompt()
+	assert.Contains(t, prompt, "Current directory: ~/src/api")
+	assert.Contains(t, prompt, "cwd LIKE '%/src/api'")
+	assert.Contains(t, prompt, "Git branch: main")
+	assert.NotContains(t, prompt, "/home/alice")
+
+	// The oldest commands are left out past the total cap
+	long := &askContext{}
+	for range contextChars / 100 {
+		long.Recent = append(long.Recent, strings.Repeat("y", 150))
+	}
+	long.Recent = append(long.Recent, "make deploy")
+	cut := long.scrubbed(nil)
+	assert.Less(t, len(cut.Recent), len(long.Recent))
+	assert.Equal(t, "make deploy", cut.Recent[len(cut.Recent)-1])
+
+	assert.Empty(t, (*askContext)(nil).prompt())
+	assert.Empty(t, (&askContext{}).prompt())
+}
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// DeletePreview is what a request to delete history is about
+type DeletePreview struct {
+	SQL     string                  // The query the entries were found with
+	Entries []*storage.HistoryEntry // In the query's order
+}
+
+// IDs returns the IDs of the entries, to delete them by
+func (p *DeletePreview) IDs() []int64 {
+	ids := make([]int64, 0, len(p.Entries))
+	for _, entry := range p.Entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+// PreviewDelete finds the entries of profile request asks to delete, with
+// a query the model writes. Nothing is deleted: the SQL is a validated
+// SELECT, and the caller deletes the entries it found by ID once the user
+// agrees. The query can't be trusted to stay in the profile, so entries of
+// other profiles are left out after it ran.
+func PreviewDelete(db *storage.DB, request, profile string, cfg *config.Config, debug bool) (*DeletePreview, error) {
+	if err := checkEnabled(cfg); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(cfg.GetSQLModel(), clientOptions(cfg), debug)
+	if err != nil {
+		return nil, err
+	}
+
+	statistics, err := stats.Collect(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect database stats: %w", err)
+	}
+	var scrubber *redact.Scrubber
+	if cfg.AI.RedactContext {
+		scrubber = newScrubber(db)
+		statistics = scrubStats(scrubber, statistics)
+	}
+
+	sqlPrompt := GenerateDeleteSQLPrompt(statistics, request)
+	if c := collectContext(db, cfg); c != nil {
+		sqlPrompt += c.scrubbed(scrubber).prompt()
+	}
+
+	sqlQuery, err := generateSQLWithRetry(client, sqlPrompt, cfg.AI.MaxSQLRetries, debug)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Final SQL Query: %s\n", sqlQuery)
+	}
+
+	entries, err := executeSQLQuery(db, sqlQuery, time.Duration(cfg.AI.SQLTimeoutSecs)*time.Second, debug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	entries, err = inProfile(db, entries, profile)
+	if err != nil {
+		return nil, err
+	}
+	return &DeletePreview{SQL: sqlQuery, Entries: entries}, nil
+}
+
+// inProfile returns the entries that belong to profile, read again in full
+// as the model's query may not select the profile
+func inProfile(db *storage.DB, entries []*storage.HistoryEntry, profile string) ([]*storage.HistoryEntry, error) {
+	kept := make([]*storage.HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		full, err := db.GetByID(entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entry %d: %w", entry.ID, err)
+		}
+		if full.Profile == profile {
+			kept = append(kept, full)
+		}
+	}
+	return kept, nil
+}
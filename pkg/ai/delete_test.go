@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCassette writes a cassette of responses from model, for a replay
+func writeCassette(t *testing.T, model string, responses ...string) string {
+	c := NewCassette("")
+	for _, response := range responses {
+		c.Interactions = append(c.Interactions, Interaction{Model: model, Response: response})
+	}
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestPreviewDelete(t *testing.T) {
+	cfg := replayConfig(t)
+	t.Setenv(ReplayEnv, writeCassette(t, "gpt-4o-mini",
+		"DELETE FROM history WHERE program = 'docker'",
+		"SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, "+
+			"COALESCE(git_branch, '') as git_branch, COALESCE(hash, '') as hash, session_id "+
+			"FROM history WHERE program = 'docker' ORDER BY timestamp DESC"))
+	db := replayDB(t)
+
+	// Matched by the query, but of another profile
+	work := &storage.HistoryEntry{Command: "docker ps", Timestamp: 1700000000, Profile: "work"}
+	work.Hash = storage.GenerateProfileHash(work.Command, work.Profile)
+	require.NoError(t, db.Insert(work))
+
+	// The model's DELETE is sent back, only a SELECT is ever run
+	preview, err := PreviewDelete(db, "remove my docker commands", storage.DefaultProfile, cfg, false)
+	require.NoError(t, err)
+	assert.Contains(t, preview.SQL, "SELECT")
+	require.Len(t, preview.Entries, 2)
+	assert.Equal(t, "docker ps", preview.Entries[0].Command)
+	assert.Equal(t, storage.DefaultProfile, preview.Entries[0].Profile)
+	assert.NotContains(t, preview.IDs(), work.ID)
+
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestGenerateDeleteSQLPrompt(t *testing.T) {
+	prompt := GenerateDeleteSQLPrompt(&stats.Stats{}, "remove everything from acme.com")
+	assert.Contains(t, prompt, `User Query: "remove everything from acme.com"`)
+	assert.Contains(t, prompt, "do NOT add a LIMIT")
+}
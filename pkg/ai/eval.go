@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEvalSuite is the suite fh --ai-eval and the aieval tests run
+//
+//go:embed testdata/eval.yaml
+var defaultEvalSuite []byte
+
+// evalSQLRetries is the tries a model gets to write valid SQL, fewer than
+// ai.max_sql_retries so a failing case costs little, and evalSQLTimeout
+// bounds the SQL on the small seeded history
+const (
+	evalSQLRetries = 3
+	evalSQLTimeout = 5 * time.Second
+)
+
+// EvalSuite is a history and questions about it, with what the SQL
+// generated for each is expected to do
+type EvalSuite struct {
+	Seed  []EvalSeed `yaml:"seed"`
+	Cases []EvalCase `yaml:"cases"`
+}
+
+// EvalSeed is a command of the history a suite is run against
+type EvalSeed struct {
+	Command  string `yaml:"command"`
+	Ago      string `yaml:"ago"` // How long before the run it was run, e.g. "26h" ("" = at the run)
+	Cwd      string `yaml:"cwd"`
+	ExitCode int    `yaml:"exit_code"`
+	Branch   string `yaml:"branch"`
+}
+
+// EvalCase is a question and the properties of a good answer to it
+type EvalCase struct {
+	Question    string   `yaml:"question"`
+	MockSQL     string   `yaml:"mock_sql"`     // What the mock provider answers with
+	SQLContains []string `yaml:"sql_contains"` // Case-insensitive substrings of the SQL
+	Expect      []string `yaml:"expect"`       // Commands the SQL must find
+	Reject      []string `yaml:"reject"`       // Commands it must not
+}
+
+// EvalResult is how a case went
+type EvalResult struct {
+	Question string
+	SQL      string
+	Failures []string // Empty when it passed
+}
+
+// Passed reports whether the case got all its properties right
+func (r EvalResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// DefaultEvalSuite returns the suite shipped with fh
+func DefaultEvalSuite() (*EvalSuite, error) {
+	return parseEvalSuite(defaultEvalSuite)
+}
+
+// LoadEvalSuite reads a suite from a YAML file
+func LoadEvalSuite(path string) (*EvalSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval suite: %w", err)
+	}
+	return parseEvalSuite(data)
+}
+
+// parseEvalSuite decodes a suite, refusing unknown fields so a misspelled
+// property isn't a property silently not checked
+func parseEvalSuite(data []byte) (*EvalSuite, error) {
+	var suite EvalSuite
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&suite); err != nil {
+		return nil, fmt.Errorf("failed to parse eval suite: %w", err)
+	}
+	for i, c := range suite.Cases {
+		if strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("eval case %d has no question", i+1)
+		}
+	}
+	return &suite, nil
+}
+
+// mockQuerier is the provider of an offline run, answering every prompt
+// with the SQL the case says a good model would write
+type mockQuerier struct {
+	sql string
+}
+
+func (m mockQuerier) Query(_ context.Context, _ string) (string, error) {
+	return m.sql, nil
+}
+
+// RunEval seeds db with the suite's history as of now, then asks client
+// each question and checks the SQL it writes and what that SQL finds. A
+// nil client is the mock provider, answering with each case's mock_sql.
+func RunEval(db *storage.DB, suite *EvalSuite, client Querier, now time.Time) ([]EvalResult, error) {
+	for _, seed := range suite.Seed {
+		var ago time.Duration
+		if seed.Ago != "" {
+			var err error
+			if ago, err = time.ParseDuration(seed.Ago); err != nil {
+				return nil, fmt.Errorf("invalid ago %q for %q: %w", seed.Ago, seed.Command, err)
+			}
+		}
+		entry := &storage.HistoryEntry{
+			Timestamp: now.Add(-ago).Unix(),
+			Command:   seed.Command,
+			Cwd:       seed.Cwd,
+			ExitCode:  seed.ExitCode,
+			Hostname:  "eval",
+			User:      "eval",
+			Shell:     "bash",
+			GitBranch: seed.Branch,
+			Hash:      storage.GenerateHash(seed.Command),
+		}
+		if err := db.Insert(entry); err != nil {
+			return nil, fmt.Errorf("failed to seed %q: %w", seed.Command, err)
+		}
+	}
+
+	statistics, err := stats.Collect(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect database stats: %w", err)
+	}
+
+	results := make([]EvalResult, 0, len(suite.Cases))
+	for _, c := range suite.Cases {
+		querier := client
+		if querier == nil {
+			querier = mockQuerier{sql: c.MockSQL}
+		}
+		results = append(results, runEvalCase(db, statistics, querier, c))
+	}
+	return results, nil
+}
+
+// runEvalCase asks one question and checks the answer's properties
+func runEvalCase(db *storage.DB, statistics *stats.Stats, client Querier, c EvalCase) EvalResult {
+	result := EvalResult{Question: c.Question}
+	fail := func(format string, args ...any) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	sql, err := generateSQLWithRetry(client, GenerateSQLPrompt(statistics, c.Question), evalSQLRetries, false)
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+	result.SQL = sql
+
+	lower := strings.ToLower(sql)
+	for _, want := range c.SQLContains {
+		if !strings.Contains(lower, strings.ToLower(want)) {
+			fail("SQL does not contain %q", want)
+		}
+	}
+
+	found, err := executeSQLQuery(db, sql, evalSQLTimeout, false)
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+	commands := make([]string, 0, len(found))
+	for _, entry := range found {
+		commands = append(commands, entry.Command)
+	}
+	for _, want := range c.Expect {
+		if !slices.Contains(commands, want) {
+			fail("%q not found", want)
+		}
+	}
+	for _, unwanted := range c.Reject {
+		if slices.Contains(commands, unwanted) {
+			fail("%q found", unwanted)
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+//go:build aieval
+
+package ai
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEvalSuite runs the suite against the model when OPENAI_API_KEY is
+// set, FH_EVAL_MODEL picking it, and against the mock provider otherwise:
+//
+//	go test -tags aieval -run TestEvalSuite -v ./pkg/ai/
+func TestEvalSuite(t *testing.T) {
+	suite, err := DefaultEvalSuite()
+	if path := os.Getenv("FH_EVAL_SUITE"); path != "" {
+		suite, err = LoadEvalSuite(path)
+	}
+	require.NoError(t, err)
+
+	var client Querier
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		model := os.Getenv("FH_EVAL_MODEL")
+		if model == "" {
+			model = "gpt-4o-mini"
+		}
+		openaiClient, err := NewOpenAIClient(model)
+		require.NoError(t, err)
+		client = openaiClient
+		t.Logf("Evaluating %s", model)
+	} else {
+		t.Log("OPENAI_API_KEY not set, evaluating the mock provider")
+	}
+
+	results, err := RunEval(testutil.NewTestDB(t), suite, client, time.Now())
+	require.NoError(t, err)
+	for _, result := range results {
+		t.Run(result.Question, func(t *testing.T) {
+			t.Logf("SQL: %s", result.SQL)
+			assert.True(t, result.Passed(), "%v", result.Failures)
+		})
+	}
+}
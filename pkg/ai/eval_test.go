@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answering is a provider answering every prompt with the same SQL
+type answering string
+
+func (a answering) Query(_ context.Context, _ string) (string, error) {
+	return string(a), nil
+}
+
+func TestRunEval_DefaultSuite(t *testing.T) {
+	suite, err := DefaultEvalSuite()
+	require.NoError(t, err)
+	require.NotEmpty(t, suite.Cases)
+
+	// The mock SQL of every case has the properties asked of it
+	results, err := RunEval(testutil.NewTestDB(t), suite, nil, time.Now())
+	require.NoError(t, err)
+	require.Len(t, results, len(suite.Cases))
+	for _, result := range results {
+		assert.True(t, result.Passed(), "%s: %v", result.Question, result.Failures)
+	}
+}
+
+func TestRunEval_Failures(t *testing.T) {
+	suite, err := parseEvalSuite([]byte(`
+seed:
+  - {command: "git status", ago: 1h}
+  - {command: "make", exit_code: 2}
+cases:
+  - question: which commands failed
+    sql_contains: ["where"]
+    expect: ["make"]
+    reject: ["git status"]
+`))
+	require.NoError(t, err)
+
+	// SQL finding everything misses the filter and finds a rejected command
+	everything := answering(`SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms,
+		COALESCE(git_branch, '') as git_branch, COALESCE(hash, '') as hash, session_id FROM history`)
+	results, err := RunEval(testutil.NewTestDB(t), suite, everything, time.Now())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed())
+	assert.Equal(t, []string{`SQL does not contain "where"`, `"git status" found`}, results[0].Failures)
+
+	// SQL that never validates fails the case, not the run
+	results, err = RunEval(testutil.NewTestDB(t), suite, answering("DELETE FROM history"), time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, results[0].Failures[0], "could not generate valid query")
+}
+
+func TestParseEvalSuite_Invalid(t *testing.T) {
+	_, err := parseEvalSuite([]byte("cases:\n  - question: x\n    expected: [y]\n"))
+	assert.Error(t, err, "misspelled property")
+
+	_, err = parseEvalSuite([]byte("cases:\n  - sql_contains: [x]\n"))
+	assert.Error(t, err, "case without a question")
+
+	suite, err := parseEvalSuite([]byte("seed:\n  - {command: ls, ago: soon}\n"))
+	require.NoError(t, err)
+	_, err = RunEval(testutil.NewTestDB(t), suite, nil, time.Now())
+	assert.Error(t, err, "invalid ago")
+}
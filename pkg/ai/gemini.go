@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// GeminiBaseURL is the Gemini API, ai.base_url replaces it
+const GeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiClient asks a Gemini model through the Google AI API
+type GeminiClient struct {
+	api     *httpAPI
+	baseURL string
+	key     string
+	model   string
+	debug   bool // Print every prompt sent
+}
+
+// NewGeminiClient creates a client of the Gemini model, authenticated with
+// GEMINI_API_KEY or GOOGLE_API_KEY
+func NewGeminiClient(model string, opts ClientOptions) (*GeminiClient, error) {
+	key, err := apiKey("GEMINI_API_KEY", "GOOGLE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	api, err := newHTTPAPI("Gemini", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := GeminiBaseURL
+	if opts.BaseURL != "" {
+		baseURL = opts.BaseURL
+	}
+	return &GeminiClient{api: api, baseURL: strings.TrimSuffix(baseURL, "/"), key: key, model: model}, nil
+}
+
+// geminiPart is text of a Gemini message
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent is a Gemini message
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// Query sends a prompt to Gemini and returns the response
+func (c *GeminiClient) Query(ctx context.Context, prompt string) (string, error) {
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Sending to %s:\n%s\n[DEBUG] End of prompt\n", c.model, prompt)
+	}
+
+	request := struct {
+		Contents []geminiContent `json:"contents"`
+	}{
+		Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+	}
+	var response struct {
+		Candidates []struct {
+			Content geminiContent `json:"content"`
+		} `json:"candidates"`
+	}
+
+	endpoint := c.baseURL + "/models/" + url.PathEscape(c.model) + ":generateContent"
+	header := http.Header{}
+	header.Set("x-goog-api-key", c.key)
+	if err := c.api.post(ctx, endpoint, header, request, &response); err != nil {
+		return "", err
+	}
+
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("no response from Gemini")
+	}
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no response from Gemini: %w", errNoText)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// heuristicLimit is how many commands a plain search answers with
+const heuristicLimit = 20
+
+// stopwords are the words of a question that aren't worth searching for
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true, "to": true, "in": true,
+	"on": true, "at": true, "for": true, "with": true, "from": true, "by": true, "into": true,
+	"i": true, "me": true, "my": true, "we": true, "our": true, "you": true, "it": true, "that": true,
+	"this": true, "these": true, "those": true, "is": true, "are": true, "was": true, "were": true,
+	"be": true, "been": true, "do": true, "did": true, "does": true, "have": true, "has": true, "had": true,
+	"what": true, "which": true, "when": true, "where": true, "who": true, "how": true, "why": true,
+	"all": true, "any": true, "some": true, "last": true, "recent": true, "recently": true,
+	"command": true, "commands": true, "run": true, "ran": true, "running": true, "use": true,
+	"used": true, "type": true, "typed": true, "show": true, "find": true, "list": true,
+	"give": true, "tell": true, "about": true, "ago": true, "most": true, "often": true,
+}
+
+// failureWords ask for commands that failed
+var failureWords = map[string]bool{
+	"fail": true, "failed": true, "failing": true, "failure": true, "failures": true,
+	"error": true, "errors": true, "broke": true, "broken": true, "crashed": true,
+}
+
+// heuristicSearch answers userQuery without the model: the words of the
+// question are searched for in commands, those matching the most words
+// coming first, then the most recent. A few words narrow the search
+// instead, to a day, week or month, or to failed commands.
+func heuristicSearch(db *storage.DB, userQuery string, now time.Time) ([]*storage.HistoryEntry, map[int64][]string, error) {
+	var base storage.QueryFilters
+	var keywords []string
+	seen := map[string]bool{}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, word := range strings.FieldsFunc(strings.ToLower(userQuery), isQuerySeparator) {
+		switch {
+		case word == "today":
+			base.After = today.Unix()
+		case word == "yesterday":
+			base.After = today.AddDate(0, 0, -1).Unix()
+			base.Before = today.Unix()
+		case word == "week":
+			base.After = now.AddDate(0, 0, -7).Unix()
+		case word == "month":
+			base.After = now.AddDate(0, -1, 0).Unix()
+		case failureWords[word]:
+			zero := 0
+			base.NotExitCode = &zero
+		case len(word) < 2 || stopwords[word] || seen[word]:
+		default:
+			seen[word] = true
+			keywords = append(keywords, word)
+		}
+	}
+
+	// Without keywords, the time and exit code filters are the question
+	if len(keywords) == 0 {
+		if base.After == 0 && base.NotExitCode == nil {
+			return nil, nil, nil
+		}
+		base.Distinct = true
+		base.Limit = heuristicLimit
+		results, err := db.Query(base)
+		return results, map[int64][]string{}, err
+	}
+
+	byCommand := map[string]*storage.HistoryEntry{}
+	matched := map[string][]string{}
+	for _, keyword := range keywords {
+		filters := base
+		filters.Search = keyword
+		filters.Distinct = true
+		results, err := db.Query(filters)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to search for %q: %w", keyword, err)
+		}
+		for _, entry := range results {
+			if _, ok := byCommand[entry.Command]; !ok {
+				byCommand[entry.Command] = entry
+			}
+			matched[entry.Command] = append(matched[entry.Command], keyword)
+		}
+	}
+
+	results := make([]*storage.HistoryEntry, 0, len(byCommand))
+	for _, entry := range byCommand {
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := len(matched[results[i].Command]), len(matched[results[j].Command])
+		if a != b {
+			return a > b
+		}
+		return results[i].Timestamp > results[j].Timestamp
+	})
+	if len(results) > heuristicLimit {
+		results = results[:heuristicLimit]
+	}
+
+	keywordsOf := make(map[int64][]string, len(results))
+	for _, entry := range results {
+		keywordsOf[entry.ID] = matched[entry.Command]
+	}
+	return results, keywordsOf, nil
+}
+
+// isQuerySeparator splits a question into words, keeping the characters
+// commands are made of together
+func isQuerySeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_./:", r)
+}
+
+// fallbackNote tells that the answer is a plain search, and until when
+func fallbackNote(until time.Time) string {
+	return fmt.Sprintf("Note: the AI provider is unavailable, these are the commands matching the words of "+
+		"the question. It is asked again after %s.", until.Format("15:04"))
+}
+
+// heuristicAnswer is Ask's answer when the AI provider is unavailable
+func heuristicAnswer(db *storage.DB, userQuery string, until time.Time) (string, error) {
+	results, _, err := heuristicSearch(db, userQuery, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "Could not find any data for that specific query\n\n" + fallbackNote(until), nil
+	}
+
+	var b strings.Builder
+	for _, entry := range results {
+		fmt.Fprintf(&b, "[%s] %s\n", time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04"), entry.Command)
+	}
+	b.WriteString("\n" + fallbackNote(until))
+	return b.String(), nil
+}
+
+// heuristicStructured is AskStructured's answer when the AI provider is
+// unavailable
+func heuristicStructured(db *storage.DB, userQuery string) (*Answer, error) {
+	results, keywords, err := heuristicSearch(db, userQuery, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &Answer{Entries: []AnswerEntry{}, Matched: len(results), Fallback: true}
+	for _, entry := range results {
+		reason := "matches the time or exit status asked for"
+		if words := keywords[entry.ID]; len(words) > 0 {
+			reason = "contains " + strings.Join(words, ", ")
+		}
+		answer.Entries = append(answer.Entries, AnswerEntry{
+			ID:        entry.ID,
+			Command:   entry.Command,
+			Timestamp: entry.Timestamp,
+			Cwd:       entry.Cwd,
+			Reason:    reason,
+		})
+	}
+	return answer, nil
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeuristicSearch(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	for _, entry := range []*storage.HistoryEntry{
+		{Command: "docker logs api", Timestamp: now.Add(-48 * time.Hour).Unix()},
+		{Command: "docker ps", Timestamp: now.Add(-time.Hour).Unix()},
+		{Command: "kubectl logs api", Timestamp: now.Add(-2 * time.Hour).Unix(), ExitCode: 1},
+		{Command: "ls", Timestamp: now.Add(-time.Minute).Unix()},
+	} {
+		entry.Hash = storage.GenerateHash(entry.Command)
+		require.NoError(t, db.Insert(entry))
+	}
+
+	commands := func(results []*storage.HistoryEntry) []string {
+		var out []string
+		for _, entry := range results {
+			out = append(out, entry.Command)
+		}
+		return out
+	}
+
+	// Most words matched first, then the most recent
+	results, keywords, err := heuristicSearch(db, "Which docker logs commands did I run?", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker logs api", "docker ps", "kubectl logs api"}, commands(results))
+	assert.Equal(t, []string{"docker", "logs"}, keywords[results[0].ID])
+
+	results, _, err = heuristicSearch(db, "logs that failed today", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubectl logs api"}, commands(results))
+
+	results, _, err = heuristicSearch(db, "what did I run yesterday?", now)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, _, err = heuristicSearch(db, "what did I run?", now)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
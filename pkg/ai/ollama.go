@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// OllamaBaseURL is where a local Ollama listens, ai.base_url replaces it
+const OllamaBaseURL = "http://localhost:11434"
+
+// OllamaClient asks a model served by Ollama, usually on the same machine,
+// so questions never leave it. Ollama needs no key.
+type OllamaClient struct {
+	api     *httpAPI
+	baseURL string
+	model   string
+	debug   bool // Print every prompt sent
+}
+
+// NewOllamaClient creates a client of the Ollama model
+func NewOllamaClient(model string, opts ClientOptions) (*OllamaClient, error) {
+	api, err := newHTTPAPI("Ollama", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := OllamaBaseURL
+	if opts.BaseURL != "" {
+		baseURL = opts.BaseURL
+	}
+	return &OllamaClient{api: api, baseURL: strings.TrimSuffix(baseURL, "/"), model: model}, nil
+}
+
+// ollamaMessage is a message of the Ollama chat API
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Query sends a prompt to Ollama and returns the response
+func (c *OllamaClient) Query(ctx context.Context, prompt string) (string, error) {
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Sending to %s:\n%s\n[DEBUG] End of prompt\n", c.model, prompt)
+	}
+
+	request := struct {
+		Model    string          `json:"model"`
+		Messages []ollamaMessage `json:"messages"`
+		Stream   bool            `json:"stream"`
+	}{
+		Model:    c.model,
+		Messages: []ollamaMessage{{Role: "user", Content: prompt}},
+	}
+	var response struct {
+		Message ollamaMessage `json:"message"`
+	}
+
+	if err := c.api.post(ctx, c.baseURL+"/api/chat", http.Header{}, request, &response); err != nil {
+		return "", err
+	}
+	if response.Message.Content == "" {
+		return "", fmt.Errorf("no response from Ollama: %w", errNoText)
+	}
+	return response.Message.Content, nil
+}
@@ -2,27 +2,98 @@ package ai
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/spideyz0r/fh/pkg/config"
 )
 
+// Querier answers prompts. It is what every provider implements, for one
+// of its models: OpenAIClient, GeminiClient, AnthropicClient and
+// OllamaClient.
+type Querier interface {
+	Query(ctx context.Context, prompt string) (string, error)
+}
+
 // OpenAIClient wraps the OpenAI API client
 type OpenAIClient struct {
 	client openai.Client
 	model  openai.ChatModel
+	debug  bool // Print every prompt sent
+}
+
+// ErrNoAPIKey is returned when the key of the provider is not set, the
+// error naming the environment variable it is read from
+var ErrNoAPIKey = errors.New("API key environment variable not set")
+
+// ErrUnavailable is returned when the API could not be reached or kept
+// failing with rate limits or server errors after the retries
+var ErrUnavailable = errors.New("AI provider unavailable")
+
+// ClientOptions configures where the client finds the API and how patient
+// it is with it
+type ClientOptions struct {
+	Timeout    time.Duration // Per attempt, 0 = no limit
+	MaxRetries int           // Retries of rate limits, server and connection errors, with exponential backoff
+	Provider   string        // ai.provider, "" = openai
+	BaseURL    string        // API to use instead, e.g. an OpenAI compatible gateway ("" = the provider's, or OPENAI_BASE_URL)
+	CACert     string        // PEM bundle of CAs to trust besides the system's ("" = the system's only)
+
+	// Azure sends requests to an Azure OpenAI deployment instead, BaseURL
+	// and OPENAI_API_KEY being left unused
+	Azure *config.AzureConfig
 }
 
+// DefaultClientOptions are the options of NewOpenAIClient
+var DefaultClientOptions = ClientOptions{Timeout: 30 * time.Second, MaxRetries: 2}
+
 // NewOpenAIClient creates a new OpenAI client
 func NewOpenAIClient(modelName string) (*OpenAIClient, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	return NewOpenAIClientWithOptions(modelName, DefaultClientOptions)
+}
+
+// NewOpenAIClientWithOptions creates a new OpenAI client with the given
+// options. Requests go through the proxy in HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY, like those of every HTTP client in Go.
+func NewOpenAIClientWithOptions(modelName string, opts ClientOptions) (*OpenAIClient, error) {
+	// The SDK retries with backoff, honoring Retry-After
+	requestOptions := []option.RequestOption{option.WithMaxRetries(max(opts.MaxRetries, 0))}
+
+	if opts.Azure != nil {
+		azureOptions, err := azureRequestOptions(opts.Azure, modelName)
+		if err != nil {
+			return nil, err
+		}
+		requestOptions = append(requestOptions, azureOptions...)
+	} else {
+		key, err := apiKey("OPENAI_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		requestOptions = append(requestOptions, option.WithAPIKey(key))
+		if opts.BaseURL != "" {
+			requestOptions = append(requestOptions, option.WithBaseURL(opts.BaseURL))
+		}
 	}
 
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+	if opts.Timeout > 0 {
+		requestOptions = append(requestOptions, option.WithRequestTimeout(opts.Timeout))
+	}
+	if opts.CACert != "" {
+		httpClient, err := newHTTPClient(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		requestOptions = append(requestOptions, option.WithHTTPClient(httpClient))
+	}
+	client := openai.NewClient(requestOptions...)
 
 	// Map model name to openai.ChatModel constant
 	var model openai.ChatModel
@@ -48,6 +119,10 @@ func NewOpenAIClient(modelName string) (*OpenAIClient, error) {
 
 // Query sends a prompt to OpenAI and returns the response
 func (c *OpenAIClient) Query(ctx context.Context, prompt string) (string, error) {
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Sending to %s:\n%s\n[DEBUG] End of prompt\n", c.model, prompt)
+	}
+
 	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Model: c.model,
 		Messages: []openai.ChatCompletionMessageParamUnion{
@@ -56,6 +131,9 @@ func (c *OpenAIClient) Query(ctx context.Context, prompt string) (string, error)
 	})
 
 	if err != nil {
+		if transient(err) {
+			return "", fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
 		return "", fmt.Errorf("OpenAI API error: %w", err)
 	}
 
@@ -65,3 +143,41 @@ func (c *OpenAIClient) Query(ctx context.Context, prompt string) (string, error)
 
 	return resp.Choices[0].Message.Content, nil
 }
+
+// transient reports whether err is one the API may not give again: no
+// response, a timeout, a rate limit or a server error. Bad requests, bad
+// keys and untrusted certificates fail the same way every time.
+func transient(err error) bool {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var httpErr *apiError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	var certErr *tls.CertificateVerificationError
+	return !errors.As(err, &certErr) && !errors.Is(err, context.Canceled)
+}
+
+// newHTTPClient returns an HTTP client trusting the CAs in the PEM file at
+// caCert besides the system's, for networks that intercept TLS
+func newHTTPClient(caCert string) (*http.Client, error) {
+	pem, err := os.ReadFile(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ai.ca_cert: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in ai.ca_cert %s", caCert)
+	}
+
+	// A clone of the default transport keeps its proxy settings and timeouts
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
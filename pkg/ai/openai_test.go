@@ -1,11 +1,18 @@
 package ai
 
 import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewOpenAIClient_MissingAPIKey(t *testing.T) {
@@ -156,3 +163,75 @@ func TestOpenAIClient_Structure(t *testing.T) {
 // 1. API key validation
 // 2. Model mapping
 // 3. Client initialization
+
+func TestOpenAIClient_Retries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("retry-after-ms", "1")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"SELECT 1"}}]}`))
+		} else {
+			_, _ = w.Write([]byte(`{"error":{"message":"overloaded"}}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	client, err := NewOpenAIClientWithOptions("gpt-4o-mini", ClientOptions{Timeout: 5 * time.Second, MaxRetries: 2})
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", response)
+	assert.Equal(t, 2, calls)
+
+	// Out of retries, the provider is unavailable
+	calls = 0
+	statuses = []int{http.StatusTooManyRequests}
+	_, err = client.Query(context.Background(), "prompt")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 3, calls)
+
+	// A bad request isn't retried, and is no outage
+	calls = 0
+	statuses = []int{http.StatusBadRequest}
+	_, err = client.Query(context.Background(), "prompt")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOpenAIClient_BaseURLAndCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gateway/chat/completions", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	// The gateway's certificate isn't one the system trusts
+	opts := ClientOptions{BaseURL: server.URL + "/gateway/"}
+	client, err := NewOpenAIClientWithOptions("gpt-4o-mini", opts)
+	require.NoError(t, err)
+	_, err = client.Query(context.Background(), "prompt")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+
+	opts.CACert = filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(opts.CACert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644))
+	client, err = NewOpenAIClientWithOptions("gpt-4o-mini", opts)
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+
+	require.NoError(t, os.WriteFile(opts.CACert, []byte("not a certificate"), 0644))
+	_, err = NewOpenAIClientWithOptions("gpt-4o-mini", opts)
+	assert.ErrorContains(t, err, "no PEM certificates")
+}
@@ -22,7 +22,12 @@ const schemaPrompt = `Database Schema:
     - shell (TEXT)
     - duration_ms (INTEGER, command duration in milliseconds)
     - git_branch (TEXT)
-    - session_id (TEXT)`
+    - session_id (TEXT)
+    - profile (TEXT, e.g. 'default', 'work', 'personal')
+    - exec_runtime (TEXT, 'docker', 'podman', 'compose' or 'kubectl' for commands run via exec, else '')
+    - exec_target (TEXT, container, compose service or pod exec'd into, 'namespace/pod' when a namespace was given)
+    - note (TEXT, free-form note added by the user, '' if none)
+    - program (TEXT, primary program of the command without path or sudo/env prefixes, e.g. 'git' for 'sudo git pull')`
 
 // GenerateSQLPrompt creates a prompt for SQL query generation
 func GenerateSQLPrompt(statistics *stats.Stats, userQuery string) string {
@@ -62,6 +67,8 @@ Important Notes:
   SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms,
          COALESCE(git_branch, '') as git_branch, COALESCE(hash, '') as hash, session_id
   FROM history
+- To match commands by tool use program = 'name' (programs later in a pipeline are only in command)
+- For commands run inside a container or pod, filter with exec_target LIKE '%%name%%'
 - Use COALESCE for nullable columns (git_branch, hash) to convert NULL to empty string
 - Do NOT omit any columns, especially hash and session_id
 - Use strftime() for date math (timestamp is unix epoch in seconds)
@@ -84,6 +91,19 @@ Important Notes:
 	)
 }
 
+// GenerateDeleteSQLPrompt creates a prompt for the query finding the
+// entries a request to delete history is about. The query only selects
+// them, they are deleted by ID once the user has seen them.
+func GenerateDeleteSQLPrompt(statistics *stats.Stats, request string) string {
+	return GenerateSQLPrompt(statistics, request) + `
+
+The user wants to DELETE the entries this request is about. Write the SELECT
+that finds them, it is shown to the user before anything is deleted:
+- Select EVERY entry the request is about, do NOT add a LIMIT
+- Match as narrowly as the request says, an entry selected by mistake is lost
+- Never write DELETE, UPDATE or any statement other than SELECT`
+}
+
 // GenerateSQLRetryPrompt creates a prompt for retrying SQL generation after an error
 func GenerateSQLRetryPrompt(previousSQL, sqlError string) string {
 	return fmt.Sprintf(`The SQL query you generated had an error:
@@ -133,6 +153,41 @@ Instructions:
 	)
 }
 
+// languageNames names the languages of common locale codes, for prompts
+// to read naturally; other codes and names are passed on as given
+var languageNames = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// AnswerLanguagePrompt returns what answer prompts end with so the answer
+// is written in language, "" when language is
+func AnswerLanguagePrompt(language string) string {
+	if language == "" {
+		return ""
+	}
+	if name, ok := languageNames[strings.ToLower(language)]; ok {
+		language = name
+	}
+	return fmt.Sprintf(`
+
+Write your answer in %s, whatever the language of these instructions.
+Keep commands, paths and timestamps exactly as they are.`, language)
+}
+
 // GenerateChunkSummaryPrompt creates a prompt for summarizing a chunk of results
 func GenerateChunkSummaryPrompt(chunk []*storage.HistoryEntry) string {
 	var resultLines []string
@@ -168,3 +223,69 @@ Be concise and directly address their question.`,
 		strings.Join(summaries, "\n\n"),
 	)
 }
+
+// answerSchema is the JSON Schema of a structured answer, checked by
+// parseAnswer
+const answerSchema = `{
+  "type": "object",
+  "required": ["entries"],
+  "additionalProperties": false,
+  "properties": {
+    "entries": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["ref", "reason"],
+        "additionalProperties": false,
+        "properties": {
+          "ref": {"type": "integer"},
+          "reason": {"type": "string", "minLength": 1}
+        }
+      }
+    }
+  }
+}`
+
+// GenerateStructuredPrompt creates a prompt for picking the results that
+// answer the question, numbered from first
+func GenerateStructuredPrompt(userQuery string, results []*storage.HistoryEntry, first int) string {
+	var resultLines []string
+	for i, entry := range results {
+		timestamp := time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04:05")
+		line := fmt.Sprintf("%d. [%s] %s %s", first+i, timestamp, entry.Cwd, entry.Command)
+		resultLines = append(resultLines, line)
+	}
+
+	return fmt.Sprintf(`You are a shell history assistant. Pick the commands that answer the user's question.
+
+User asked: "%s"
+
+Commands (%d, numbered):
+%s
+
+Reply with ONLY a JSON object following this JSON Schema, no markdown, no code blocks:
+%s
+
+Instructions:
+- "ref" is the number of a command in the list above
+- "reason" says in one short sentence why the command answers the question
+- List the most relevant commands first, each at most once
+- If no command answers the question, reply {"entries": []}`,
+		userQuery,
+		len(results),
+		strings.Join(resultLines, "\n"),
+		answerSchema,
+	)
+}
+
+// GenerateStructuredRetryPrompt repeats prompt after a reply that didn't
+// follow the schema, saying what was wrong
+func GenerateStructuredRetryPrompt(prompt, answerError string) string {
+	return fmt.Sprintf(`%s
+
+Your previous reply was rejected: %s
+Reply again with ONLY the JSON object.`,
+		prompt,
+		answerError,
+	)
+}
@@ -39,6 +39,7 @@ func TestGenerateSQLPrompt(t *testing.T) {
 	assert.Contains(t, prompt, userQuery)
 	assert.Contains(t, prompt, "strftime")
 	assert.Contains(t, prompt, "LIMIT")
+	assert.Contains(t, prompt, "exec_target LIKE '%name%'")
 
 	// Verify date ranges are formatted
 	assert.Contains(t, prompt, "2024-01-01")
@@ -198,3 +199,29 @@ func TestPromptConsistency(t *testing.T) {
 	assert.Contains(t, strings.ToUpper(formatPrompt), "NO MARKDOWN")
 	assert.Contains(t, strings.ToUpper(synthesisPrompt), "NO MARKDOWN")
 }
+
+func TestGenerateStructuredPrompt(t *testing.T) {
+	results := []*storage.HistoryEntry{
+		{Timestamp: 1700000000, Cwd: "/srv/api", Command: "make deploy"},
+		{Timestamp: 1700000060, Cwd: "/srv/api", Command: "kubectl rollout status deploy/api"},
+	}
+
+	prompt := GenerateStructuredPrompt("how did I deploy?", results, 11)
+	assert.Contains(t, prompt, `User asked: "how did I deploy?"`)
+	assert.Contains(t, prompt, "11. [")
+	assert.Contains(t, prompt, "12. [")
+	assert.Contains(t, prompt, "kubectl rollout status deploy/api")
+	assert.Contains(t, prompt, answerSchema)
+
+	retry := GenerateStructuredRetryPrompt(prompt, "ref 4 is listed twice")
+	assert.True(t, strings.HasPrefix(retry, prompt))
+	assert.Contains(t, retry, "rejected: ref 4 is listed twice")
+}
+
+func TestAnswerLanguagePrompt(t *testing.T) {
+	assert.Empty(t, AnswerLanguagePrompt(""))
+	assert.Contains(t, AnswerLanguagePrompt("de"), "Write your answer in German")
+	assert.Contains(t, AnswerLanguagePrompt("ES"), "Write your answer in Spanish")
+	assert.Contains(t, AnswerLanguagePrompt("Brazilian Portuguese"), "Write your answer in Brazilian Portuguese")
+	assert.Contains(t, AnswerLanguagePrompt("fr"), "Keep commands, paths and timestamps exactly as they are")
+}
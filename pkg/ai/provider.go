@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Providers ai.provider can name
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAzure     = "azure"
+	ProviderGemini    = "gemini"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// retryBackoff is the wait before the first retry of a request to Gemini,
+// Anthropic or Ollama, doubled on every retry unless the API says how long
+const retryBackoff = 500 * time.Millisecond
+
+// newProvider returns the client asking model of the provider opts names,
+// printing every prompt with debug
+func newProvider(model string, opts ClientOptions, debug bool) (Querier, error) {
+	switch opts.Provider {
+	case "", ProviderOpenAI, ProviderAzure:
+		client, err := NewOpenAIClientWithOptions(model, opts)
+		if err != nil {
+			return nil, err
+		}
+		client.debug = debug
+		return client, nil
+	case ProviderGemini:
+		client, err := NewGeminiClient(model, opts)
+		if err != nil {
+			return nil, err
+		}
+		client.debug = debug
+		return client, nil
+	case ProviderAnthropic:
+		client, err := NewAnthropicClient(model, opts)
+		if err != nil {
+			return nil, err
+		}
+		client.debug = debug
+		return client, nil
+	case ProviderOllama:
+		client, err := NewOllamaClient(model, opts)
+		if err != nil {
+			return nil, err
+		}
+		client.debug = debug
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unsupported ai provider: %q (must be openai, azure, gemini, anthropic or ollama)", opts.Provider)
+	}
+}
+
+// apiKey returns the key in the first of envs that is set
+func apiKey(envs ...string) (string, error) {
+	for _, env := range envs {
+		if key := os.Getenv(env); key != "" {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNoAPIKey, strings.Join(envs, " or "))
+}
+
+// httpAPI sends JSON requests to the REST API of a provider without an SDK,
+// retrying like the OpenAI SDK does: rate limits, server errors and
+// requests that got no response, with exponential backoff honoring
+// Retry-After
+type httpAPI struct {
+	name       string // For errors, e.g. "Gemini"
+	client     *http.Client
+	timeout    time.Duration
+	maxRetries int
+}
+
+// newHTTPAPI returns the API named name with the timeouts, retries and
+// CAs of opts
+func newHTTPAPI(name string, opts ClientOptions) (*httpAPI, error) {
+	api := &httpAPI{name: name, client: http.DefaultClient, timeout: opts.Timeout, maxRetries: max(opts.MaxRetries, 0)}
+	if opts.CACert != "" {
+		client, err := newHTTPClient(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		api.client = client
+	}
+	return api, nil
+}
+
+// apiError is an error response of the API
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// post sends body to url with header and decodes the response into out
+func (a *httpAPI) post(ctx context.Context, url string, header http.Header, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= a.maxRetries; attempt++ {
+		var wait time.Duration
+		wait, lastErr = a.try(ctx, url, header, data, out)
+		if lastErr == nil {
+			return nil
+		}
+		if !transient(lastErr) || attempt == a.maxRetries {
+			break
+		}
+		if wait == 0 {
+			wait = retryBackoff << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrUnavailable, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	if transient(lastErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, lastErr)
+	}
+	return fmt.Errorf("%s API error: %w", a.name, lastErr)
+}
+
+// try sends one request, returning how long the API asked to wait before
+// the next when it failed
+func (a *httpAPI) try(ctx context.Context, url string, header http.Header, data []byte, out any) (time.Duration, error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var wait time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(secs) * time.Second
+		}
+		return wait, &apiError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return 0, fmt.Errorf("failed to parse %s response: %w", a.name, err)
+	}
+	return 0, nil
+}
+
+// errNoText is returned when a response has no text in it
+var errNoText = errors.New("no response text")
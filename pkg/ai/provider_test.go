@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiClient_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-2.0-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "g-key", r.Header.Get("x-goog-api-key"))
+
+		var request struct {
+			Contents []geminiContent `json:"contents"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "prompt", request.Contents[0].Parts[0].Text)
+
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"SELECT "},{"text":"1"}]}}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "g-key")
+
+	client, err := newProvider("gemini-2.0-flash", ClientOptions{Provider: ProviderGemini, BaseURL: server.URL + "/v1beta/"}, false)
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", response)
+}
+
+func TestAnthropicClient_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "a-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+
+		var request struct {
+			Model     string             `json:"model"`
+			MaxTokens int                `json:"max_tokens"`
+			Messages  []anthropicMessage `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "claude-3-5-haiku-latest", request.Model)
+		assert.Equal(t, anthropicMaxTokens, request.MaxTokens)
+		assert.Equal(t, []anthropicMessage{{Role: "user", Content: "prompt"}}, request.Messages)
+
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"SELECT 1"}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "a-key")
+
+	client, err := newProvider("claude-3-5-haiku-latest", ClientOptions{Provider: ProviderAnthropic, BaseURL: server.URL}, false)
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", response)
+}
+
+func TestHTTPAPI_Retries(t *testing.T) {
+	statuses := []int{http.StatusTooManyRequests, http.StatusOK}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}]}`))
+		} else {
+			_, _ = w.Write([]byte(`{"error":{"message":"slow down"}}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "a-key")
+
+	client, err := NewAnthropicClient("claude-3-5-haiku-latest", ClientOptions{BaseURL: server.URL, Timeout: 5 * time.Second, MaxRetries: 2})
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, 2, calls)
+
+	// Out of retries, the provider is unavailable
+	calls = 0
+	statuses = []int{http.StatusServiceUnavailable}
+	_, err = client.Query(context.Background(), "prompt")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 3, calls)
+
+	// A bad key isn't retried, and is no outage
+	calls = 0
+	statuses = []int{http.StatusUnauthorized}
+	_, err = client.Query(context.Background(), "prompt")
+	assert.ErrorContains(t, err, "Anthropic API error: 401")
+	assert.NotErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, calls)
+}
+
+func TestNewProvider_Errors(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GOOGLE_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	_, err := newProvider("gemini-2.0-flash", ClientOptions{Provider: ProviderGemini}, false)
+	assert.ErrorIs(t, err, ErrNoAPIKey)
+	assert.ErrorContains(t, err, "GEMINI_API_KEY or GOOGLE_API_KEY")
+
+	_, err = newProvider("claude-3-5-haiku-latest", ClientOptions{Provider: ProviderAnthropic}, false)
+	assert.ErrorIs(t, err, ErrNoAPIKey)
+	assert.ErrorContains(t, err, "ANTHROPIC_API_KEY")
+
+	_, err = newProvider("model", ClientOptions{Provider: "watson"}, false)
+	assert.ErrorContains(t, err, "unsupported ai provider")
+}
+
+func TestOllamaClient_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		var request struct {
+			Model    string          `json:"model"`
+			Messages []ollamaMessage `json:"messages"`
+			Stream   bool            `json:"stream"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "llama3.2", request.Model)
+		assert.False(t, request.Stream)
+		assert.Equal(t, "prompt", request.Messages[0].Content)
+
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"SELECT 1"},"done":true}`))
+	}))
+	defer server.Close()
+
+	client, err := newProvider("llama3.2", ClientOptions{Provider: ProviderOllama, BaseURL: server.URL}, false)
+	require.NoError(t, err)
+	response, err := client.Query(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", response)
+}
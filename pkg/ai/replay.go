@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ReplayEnv names a cassette to answer every prompt from instead of the
+// API, and RecordEnv one to record the API's responses to, for end-to-end
+// tests of questions without an API key
+const (
+	ReplayEnv = "FH_AI_REPLAY"
+	RecordEnv = "FH_AI_RECORD"
+)
+
+// ErrCassetteExhausted is returned when a replay asks more than was recorded
+var ErrCassetteExhausted = errors.New("cassette has no more recorded responses")
+
+// Interaction is a prompt sent to a model and its response
+type Interaction struct {
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"` // For reading, prompts hold the time and aren't compared
+	Response string `json:"response"`
+}
+
+// Cassette is the interactions of a run, in order. A replay gets the
+// responses in the order they were recorded, as long as each is asked of
+// the model that gave it.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+
+	path string
+	mu   sync.Mutex
+	next int // Interaction replayed next
+}
+
+// LoadCassette reads a recorded cassette to replay
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := &Cassette{path: path}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// NewCassette returns an empty cassette to record to path
+func NewCassette(path string) *Cassette {
+	return &Cassette{Interactions: []Interaction{}, path: path}
+}
+
+// Replay returns a client answering with the cassette's responses
+func (c *Cassette) Replay(model string) Querier {
+	return replayer{cassette: c, model: model}
+}
+
+// Record returns client with every interaction written to the cassette
+func (c *Cassette) Record(client Querier, model string) Querier {
+	return recorder{cassette: c, client: client, model: model}
+}
+
+// replayer is a Querier answering from a cassette
+type replayer struct {
+	cassette *Cassette
+	model    string
+}
+
+func (r replayer) Query(_ context.Context, _ string) (string, error) {
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.Interactions) {
+		return "", fmt.Errorf("%w: %s has %d", ErrCassetteExhausted, c.path, len(c.Interactions))
+	}
+	interaction := c.Interactions[c.next]
+	if interaction.Model != r.model {
+		return "", fmt.Errorf("cassette %s: response %d was recorded from %s, not %s",
+			c.path, c.next+1, interaction.Model, r.model)
+	}
+	c.next++
+	return interaction.Response, nil
+}
+
+// recorder is a Querier writing the interactions of another to a cassette
+type recorder struct {
+	cassette *Cassette
+	client   Querier
+	model    string
+}
+
+func (r recorder) Query(ctx context.Context, prompt string) (string, error) {
+	response, err := r.client.Query(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, Interaction{Model: r.model, Prompt: prompt, Response: response})
+
+	// Saved as it goes, a run may end with an error
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return response, nil
+}
+
+// cassettes are those of the environment, shared by the clients of a run
+var (
+	cassettesMu sync.Mutex
+	cassettes   = map[string]*Cassette{}
+)
+
+// envCassette returns the cassette path names, loading it once when it is
+// replayed and starting it empty when it is recorded
+func envCassette(path string, record bool) (*Cassette, error) {
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	if c, ok := cassettes[path]; ok {
+		return c, nil
+	}
+	var c *Cassette
+	if record {
+		c = NewCassette(path)
+	} else {
+		var err error
+		if c, err = LoadCassette(path); err != nil {
+			return nil, err
+		}
+	}
+	cassettes[path] = c
+	return c, nil
+}
+
+// newClient returns the client asking model, replaying or recording a
+// cassette when the environment names one
+func newClient(model string, opts ClientOptions, debug bool) (Querier, error) {
+	if path := os.Getenv(ReplayEnv); path != "" {
+		c, err := envCassette(path, false)
+		if err != nil {
+			return nil, err
+		}
+		return c.Replay(model), nil
+	}
+
+	client, err := newProvider(model, opts, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	if path := os.Getenv(RecordEnv); path != "" {
+		c, err := envCassette(path, true)
+		if err != nil {
+			return nil, err
+		}
+		return c.Record(client, model), nil
+	}
+	return client, nil
+}
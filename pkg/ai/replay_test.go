@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayDB is the history the cassettes in testdata were recorded against
+func replayDB(t *testing.T) *storage.DB {
+	db := testutil.NewTestDB(t)
+	now := time.Now()
+	for i, command := range []string{"docker ps", "docker compose up -d", "git status"} {
+		entry := &storage.HistoryEntry{
+			Command:   command,
+			Timestamp: now.Add(-time.Duration(i+1) * time.Hour).Unix(),
+			Cwd:       "/home/dev/app",
+			Hash:      storage.GenerateHash(command),
+		}
+		require.NoError(t, db.Insert(entry))
+	}
+	return db
+}
+
+// replayConfig is a config asking questions about an ephemeral database
+func replayConfig(t *testing.T) *config.Config {
+	cfg := config.Default()
+	cfg.Database.Path = storage.MemoryPath
+	t.Setenv(ReplayEnv, "")
+	t.Setenv(RecordEnv, "")
+	return cfg
+}
+
+// cassetteCopy copies a cassette of testdata, for a replay of its own
+func cassetteCopy(t *testing.T, name string) string {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestAsk_Replay(t *testing.T) {
+	cfg := replayConfig(t)
+	t.Setenv(ReplayEnv, cassetteCopy(t, "ask.cassette.json"))
+
+	// SQL generation, execution and formatting, without an API key
+	t.Setenv("OPENAI_API_KEY", "")
+	output, err := Ask(replayDB(t), "what docker commands did I run?", cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, "You ran `docker ps` and `docker compose up -d` in /home/dev/app.\n\n"+
+		"-- 2 rows from: SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, "+
+		"COALESCE(git_branch, '') as git_branch, COALESCE(hash, '') as hash, session_id "+
+		"FROM history WHERE program = 'docker' ORDER BY timestamp DESC LIMIT 100", output)
+}
+
+func TestAskStructured_Replay(t *testing.T) {
+	cfg := replayConfig(t)
+	t.Setenv(ReplayEnv, cassetteCopy(t, "ask-structured.cassette.json"))
+
+	answer, err := AskStructured(replayDB(t), "how did I start the containers?", cfg, false)
+	require.NoError(t, err)
+	require.Len(t, answer.Entries, 1)
+	assert.Equal(t, "docker compose up -d", answer.Entries[0].Command)
+	assert.Equal(t, "starts the compose services", answer.Entries[0].Reason)
+	assert.Equal(t, 2, answer.Matched)
+}
+
+func TestAsk_RecordAndReplay(t *testing.T) {
+	responses := []string{
+		"SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, " +
+			"COALESCE(git_branch, '') as git_branch, COALESCE(hash, '') as hash, session_id " +
+			"FROM history WHERE program = 'git'",
+		"You ran git status.",
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := json.Marshal(responses[min(calls, len(responses)-1)])
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` + string(content) + `}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := replayConfig(t)
+	cfg.AI.BaseURL = server.URL
+	cfg.AI.Footer = false
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	path := filepath.Join(t.TempDir(), "git.cassette.json")
+	t.Setenv(RecordEnv, path)
+
+	recorded, err := Ask(replayDB(t), "what git commands did I run?", cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, "You ran git status.", recorded)
+	assert.Equal(t, 2, calls)
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 2)
+	assert.Equal(t, "gpt-4o-mini", cassette.Interactions[0].Model)
+	assert.Contains(t, cassette.Interactions[0].Prompt, `User Query: "what git commands did I run?"`)
+
+	// The replay doesn't reach the API
+	t.Setenv(RecordEnv, "")
+	t.Setenv(ReplayEnv, path)
+	replayed, err := Ask(replayDB(t), "what git commands did I run?", cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCassette_Replay(t *testing.T) {
+	c := &Cassette{Interactions: []Interaction{
+		{Model: "gpt-4o-mini", Response: "first"},
+		{Model: "gpt-4o", Response: "second"},
+	}}
+	ctx := context.Background()
+
+	response, err := c.Replay("gpt-4o-mini").Query(ctx, "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "first", response)
+
+	// Asked of another model than recorded, e.g. after a model_policy change
+	_, err = c.Replay("gpt-4o-mini").Query(ctx, "prompt")
+	assert.ErrorContains(t, err, "recorded from gpt-4o, not gpt-4o-mini")
+
+	response, err = c.Replay("gpt-4o").Query(ctx, "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "second", response)
+
+	_, err = c.Replay("gpt-4o").Query(ctx, "prompt")
+	assert.ErrorIs(t, err, ErrCassetteExhausted)
+}
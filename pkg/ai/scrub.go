@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"os"
+	"os/user"
+
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// newScrubber returns the scrubber for what is sent to the model: the home
+// directory, the user and every machine that opened db are hidden
+func newScrubber(db *storage.DB) *redact.Scrubber {
+	home, _ := os.UserHomeDir()
+
+	var users []string
+	if u, err := user.Current(); err == nil {
+		users = append(users, u.Username)
+	}
+
+	var hosts []string
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	if known, err := db.Hosts(); err == nil {
+		for _, h := range known {
+			hosts = append(hosts, h.Hostname)
+		}
+	}
+
+	return redact.NewScrubber(home, users, hosts)
+}
+
+// scrubStats returns a copy of statistics with its top commands scrubbed
+func scrubStats(scrubber *redact.Scrubber, statistics *stats.Stats) *stats.Stats {
+	scrubbed := *statistics
+	scrubbed.TopCommands = make([]stats.CommandCount, len(statistics.TopCommands))
+	for i, cmd := range statistics.TopCommands {
+		cmd.Command = scrubber.Scrub(cmd.Command)
+		scrubbed.TopCommands[i] = cmd
+	}
+	return &scrubbed
+}
+
+// scrubResults returns copies of results with the command and directory
+// scrubbed, the only fields the prompts show
+func scrubResults(scrubber *redact.Scrubber, results []*storage.HistoryEntry) []*storage.HistoryEntry {
+	scrubbed := make([]*storage.HistoryEntry, len(results))
+	for i, entry := range results {
+		e := *entry
+		e.Command = scrubber.Scrub(e.Command)
+		e.Cwd = scrubber.Scrub(e.Cwd)
+		scrubbed[i] = &e
+	}
+	return scrubbed
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrubResults(t *testing.T) {
+	scrubber := redact.NewScrubber("/home/alice", []string{"alice"}, []string{"workbox"})
+	results := []*storage.HistoryEntry{
+		{Command: "ssh alice@workbox --password s3cret", Cwd: "/home/alice/src", Hostname: "workbox"},
+	}
+
+	scrubbed := scrubResults(scrubber, results)
+	require.Len(t, scrubbed, 1)
+	assert.Equal(t, "ssh <user>@<host> --password ****", scrubbed[0].Command)
+	assert.Equal(t, "~/src", scrubbed[0].Cwd)
+
+	// The entries read from the database are left alone
+	assert.Equal(t, "/home/alice/src", results[0].Cwd)
+}
+
+func TestScrubStats(t *testing.T) {
+	scrubber := redact.NewScrubber("/home/alice", nil, nil)
+	statistics := &stats.Stats{
+		TotalCommands: 10,
+		TopCommands:   []stats.CommandCount{{Command: "cd /home/alice/src", Count: 4}},
+	}
+
+	scrubbed := scrubStats(scrubber, statistics)
+	assert.Equal(t, int64(10), scrubbed.TotalCommands)
+	assert.Equal(t, "cd ~/src", scrubbed.TopCommands[0].Command)
+	assert.Equal(t, 4, scrubbed.TopCommands[0].Count)
+	assert.Equal(t, "cd /home/alice/src", statistics.TopCommands[0].Command)
+}
@@ -0,0 +1,219 @@
+// Package bundle packs everything fh keeps about a user (history from every
+// database, the config file and the active profile) into a single archive,
+// to move a whole setup to another machine
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/crypto"
+)
+
+// Version is the bundle format version written by Write
+const Version = 1
+
+// Archive member names
+const (
+	manifestFile    = "manifest.json"
+	configFile      = "config.yaml"
+	mainHistoryFile = "history/main.json"
+	profileDir      = "history/profiles/"
+)
+
+// Manifest describes where and when a bundle was made
+type Manifest struct {
+	Version   int    `json:"version"`
+	CreatedAt int64  `json:"created_at"`
+	Home      string `json:"home"`    // Home directory of the source machine, to relocate paths
+	Profile   string `json:"profile"` // Active profile on the source machine
+}
+
+// Bundle is the content of a bundle archive
+type Bundle struct {
+	Manifest Manifest
+	Config   []byte // config.yaml of the source machine, nil if it had none
+
+	// History holds a JSON export per database file, keyed by "" for the
+	// main database and by profile name for profiles with their own file
+	History map[string][]byte
+}
+
+// New returns an empty bundle made now on a machine with the given home
+func New(home, profile string) *Bundle {
+	return &Bundle{
+		Manifest: Manifest{
+			Version:   Version,
+			CreatedAt: time.Now().Unix(),
+			Home:      home,
+			Profile:   profile,
+		},
+		History: make(map[string][]byte),
+	}
+}
+
+// Write writes the bundle as a gzipped tar archive
+func (b *Bundle) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestFile, manifest); err != nil {
+		return err
+	}
+
+	if b.Config != nil {
+		if err := writeFile(tw, configFile, b.Config); err != nil {
+			return err
+		}
+	}
+
+	// Sorted for reproducible archives
+	keys := make([]string, 0, len(b.History))
+	for key := range b.History {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := writeFile(tw, historyFile(key), b.History[key]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+	return nil
+}
+
+// Read reads a bundle written by Write
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle archive: %w", err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	b := &Bundle{History: make(map[string][]byte)}
+	hasManifest := false
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+
+		switch name := header.Name; {
+		case name == manifestFile:
+			if err := json.Unmarshal(data, &b.Manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest: %w", err)
+			}
+			hasManifest = true
+		case name == configFile:
+			b.Config = data
+		case name == mainHistoryFile:
+			b.History[""] = data
+		case strings.HasPrefix(name, profileDir) && path.Ext(name) == ".json":
+			profile := strings.TrimSuffix(strings.TrimPrefix(name, profileDir), ".json")
+			if profile == "" || strings.ContainsAny(profile, "/\\") {
+				return nil, fmt.Errorf("invalid profile history in bundle: %s", name)
+			}
+			b.History[profile] = data
+		default:
+			return nil, fmt.Errorf("unexpected file in bundle: %s", name)
+		}
+	}
+
+	if !hasManifest {
+		return nil, fmt.Errorf("bundle has no manifest")
+	}
+	if b.Manifest.Version > Version {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", b.Manifest.Version, Version)
+	}
+
+	return b, nil
+}
+
+// Encrypt writes the bundle encrypted with a passphrase
+func (b *Bundle) Encrypt(w io.Writer, passphrase string) error {
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return err
+	}
+
+	encrypted, err := crypto.Encrypt(buf.Bytes(), passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+
+	if _, err := w.Write(encrypted); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Decrypt reads a bundle written by Encrypt
+func Decrypt(r io.Reader, passphrase string) (*Bundle, error) {
+	encrypted, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	data, err := crypto.Decrypt(encrypted, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bundle: %w", err)
+	}
+
+	return Read(bytes.NewReader(data))
+}
+
+// historyFile returns the archive member name of a history export
+func historyFile(key string) string {
+	if key == "" {
+		return mainHistoryFile
+	}
+	return profileDir + key + ".json"
+}
+
+// writeFile adds a regular file to the archive
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Unix(0, 0),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
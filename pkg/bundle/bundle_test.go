@@ -0,0 +1,92 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRead(t *testing.T) {
+	b := New("/home/alice", "work")
+	b.Config = []byte("search:\n  keybinding: ctrl-g\n")
+	b.History[""] = []byte(`[{"command":"ls"}]`)
+	b.History["work"] = []byte(`[{"command":"make"}]`)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+
+	got, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, b.Manifest, got.Manifest)
+	assert.Equal(t, Version, got.Manifest.Version)
+	assert.Equal(t, b.Config, got.Config)
+	assert.Equal(t, b.History, got.History)
+}
+
+func TestWriteRead_NoConfig(t *testing.T) {
+	b := New("/home/alice", "")
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+
+	got, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Nil(t, got.Config)
+	assert.Empty(t, got.History)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	b := New("/home/alice", "")
+	b.History[""] = []byte(`[{"command":"git status"}]`)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Encrypt(&buf, "secret"))
+	assert.NotContains(t, buf.String(), "git status")
+
+	encrypted := buf.Bytes()
+	got, err := Decrypt(bytes.NewReader(encrypted), "secret")
+	require.NoError(t, err)
+	assert.Equal(t, b.History, got.History)
+
+	_, err = Decrypt(bytes.NewReader(encrypted), "wrong")
+	assert.ErrorContains(t, err, "failed to decrypt bundle")
+}
+
+// archive builds a raw bundle archive from name/content pairs
+func archive(t *testing.T, files ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i < len(files); i += 2 {
+		require.NoError(t, writeFile(tw, files[i], []byte(files[i+1])))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *bytes.Buffer
+		wantErr string
+	}{
+		{"not gzip", bytes.NewBufferString("plain text"), "not a bundle archive"},
+		{"no manifest", archive(t, "config.yaml", ""), "bundle has no manifest"},
+		{"unknown file", archive(t, "manifest.json", `{"version":1}`, "../../etc/passwd", "x"), "unexpected file in bundle"},
+		{"nested profile", archive(t, "manifest.json", `{"version":1}`, "history/profiles/a/b.json", "[]"), "invalid profile history"},
+		{"newer version", archive(t, "manifest.json", `{"version":99}`), "bundle version 99 is newer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(tt.input)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
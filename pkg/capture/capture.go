@@ -21,16 +21,18 @@ var (
 
 // Metadata contains information about the command execution environment
 type Metadata struct {
-	Command    string
-	ExitCode   int
-	Cwd        string
-	Hostname   string
-	User       string
-	Shell      string
-	Timestamp  int64
-	DurationMs int64
-	GitBranch  string
-	SessionID  string
+	Command     string
+	ExitCode    int
+	Cwd         string
+	Hostname    string
+	User        string
+	Shell       string
+	Timestamp   int64
+	DurationMs  int64
+	GitBranch   string
+	SessionID   string
+	ExecRuntime string // Set when the command runs inside a container or pod
+	ExecTarget  string
 }
 
 // initMetadataCache initializes the cached metadata that doesn't change
@@ -63,12 +65,25 @@ func initMetadataCache() {
 
 // Collect gathers metadata about the command execution
 func Collect(command string, exitCode int, durationMs int64) (*Metadata, error) {
+	// Get current working directory (this can change)
+	cwd, err := os.Getwd()
+	if err != nil {
+		// Non-fatal, use empty string
+		cwd = ""
+	}
+	return CollectIn(cwd, command, exitCode, durationMs)
+}
+
+// CollectIn gathers metadata about a command that ran in cwd, such as one
+// the hooks handed to the daemon
+func CollectIn(cwd, command string, exitCode int, durationMs int64) (*Metadata, error) {
 	// Initialize cache once
 	metadataMutex.Do(initMetadataCache)
 
 	meta := &Metadata{
 		Command:    command,
 		ExitCode:   exitCode,
+		Cwd:        cwd,
 		Timestamp:  time.Now().Unix(),
 		DurationMs: durationMs,
 		Hostname:   cachedHostname,
@@ -76,26 +91,28 @@ func Collect(command string, exitCode int, durationMs int64) (*Metadata, error)
 		Shell:      cachedShell,
 	}
 
-	// Get current working directory (this can change)
-	cwd, err := os.Getwd()
-	if err != nil {
-		// Non-fatal, use empty string
-		meta.Cwd = ""
-	} else {
-		meta.Cwd = cwd
-	}
-
 	// Try to detect git branch (can change)
-	meta.GitBranch = detectGitBranch(meta.Cwd)
+	meta.GitBranch = GitBranch(meta.Cwd)
 
 	// Generate session ID from shell PID and start time
 	meta.SessionID = generateSessionID()
 
+	// Record the container or pod for docker/kubectl exec
+	meta.setExecContext()
+
 	return meta, nil
 }
 
-// detectGitBranch tries to detect the current git branch
-func detectGitBranch(cwd string) string {
+// setExecContext fills in ExecRuntime and ExecTarget from the command
+func (m *Metadata) setExecContext() {
+	if ctx := ParseExecContext(m.Command); ctx != nil {
+		m.ExecRuntime = ctx.Runtime
+		m.ExecTarget = ctx.Target
+	}
+}
+
+// GitBranch returns the git branch checked out in cwd, "" outside a repository
+func GitBranch(cwd string) string {
 	// Check if we're in a git repository
 	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
 	if cwd != "" {
@@ -111,8 +128,16 @@ func detectGitBranch(cwd string) string {
 	return branch
 }
 
+// SessionEnv is set by the shell hooks to a per-shell session identifier
+const SessionEnv = "FH_SESSION"
+
 // generateSessionID creates a unique session identifier
+// The shell hooks export SessionEnv so every command of a shell shares one ID.
 func generateSessionID() string {
+	if session := os.Getenv(SessionEnv); session != "" {
+		return session
+	}
+
 	// Use shell PID if available (PPID), otherwise use our PID
 	ppid := os.Getppid()
 	if ppid == 0 {
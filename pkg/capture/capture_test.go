@@ -37,6 +37,17 @@ func TestCollect_WithExitCode(t *testing.T) {
 	assert.Equal(t, int64(50), meta.DurationMs)
 }
 
+func TestCollectIn(t *testing.T) {
+	dir := t.TempDir()
+	meta, err := CollectIn(dir, "make", 2, 30)
+	require.NoError(t, err)
+
+	assert.Equal(t, dir, meta.Cwd)
+	assert.Equal(t, "make", meta.Command)
+	assert.Equal(t, 2, meta.ExitCode)
+	assert.Empty(t, meta.GitBranch, "not a repository")
+}
+
 func TestCollect_MetadataFields(t *testing.T) {
 	meta, err := Collect("pwd", 0, 10)
 	require.NoError(t, err)
@@ -64,7 +75,7 @@ func TestDetectGitBranch_InGitRepo(t *testing.T) {
 	cwd, err := os.Getwd()
 	require.NoError(t, err)
 
-	branch := detectGitBranch(cwd)
+	branch := GitBranch(cwd)
 
 	// If we're in a git repo, we should get a branch
 	// If not, branch will be empty (which is fine)
@@ -77,7 +88,7 @@ func TestDetectGitBranch_InGitRepo(t *testing.T) {
 
 func TestDetectGitBranch_NotInGitRepo(t *testing.T) {
 	// Test with /tmp which is unlikely to be a git repo
-	branch := detectGitBranch("/tmp")
+	branch := GitBranch("/tmp")
 
 	// Should be empty string
 	assert.Equal(t, "", branch)
@@ -97,6 +108,15 @@ func TestGenerateSessionID(t *testing.T) {
 	assert.Contains(t, id2, "-")
 }
 
+func TestGenerateSessionID_FromEnv(t *testing.T) {
+	t.Setenv(SessionEnv, "4242-1700000000")
+	assert.Equal(t, "4242-1700000000", generateSessionID())
+
+	meta, err := Collect("ls", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "4242-1700000000", meta.SessionID)
+}
+
 func TestCollect_SessionIDConsistency(t *testing.T) {
 	meta1, err := Collect("cmd1", 0, 10)
 	require.NoError(t, err)
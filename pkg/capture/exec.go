@@ -0,0 +1,155 @@
+package capture
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+)
+
+// Exec runtimes recognized by ParseExecContext
+const (
+	RuntimeDocker  = "docker"
+	RuntimePodman  = "podman"
+	RuntimeCompose = "compose" // docker compose, docker-compose and podman-compose
+	RuntimeKubectl = "kubectl" // kubectl and oc
+)
+
+// ExecContext is the container or pod a command was run inside of
+type ExecContext struct {
+	Runtime string // One of the Runtime constants
+	Target  string // Container, compose service or pod ("namespace/pod" when a namespace is given)
+}
+
+// Flags that consume the next argument, per tool. Anything else starting with
+// "-" is treated as a boolean flag.
+var (
+	dockerGlobalFlags = shellparse.FlagSet("-H", "--host", "-c", "--context", "--config", "-l", "--log-level",
+		"--tlscacert", "--tlscert", "--tlskey")
+	dockerExecFlags = shellparse.FlagSet("-e", "--env", "--env-file", "-u", "--user", "-w", "--workdir",
+		"--detach-keys")
+	composeGlobalFlags = shellparse.FlagSet("-f", "--file", "-p", "--project-name", "--project-directory",
+		"--profile", "--env-file", "--ansi", "--progress", "--parallel")
+	composeExecFlags = shellparse.FlagSet("-e", "--env", "-u", "--user", "-w", "--workdir", "--index")
+	kubectlFlags     = shellparse.FlagSet("-n", "--namespace", "-c", "--container", "--context", "--cluster",
+		"--kubeconfig", "-f", "--filename", "--pod-running-timeout", "-s", "--server", "--user",
+		"--request-timeout", "--as", "--as-group", "--token", "-v", "--v")
+)
+
+// ParseExecContext returns the container or pod targeted by a docker, podman,
+// docker compose or kubectl exec in command, or nil if there is none.
+// Only the first exec in a pipeline or command list is reported.
+func ParseExecContext(command string) *ExecContext {
+	for _, segment := range shellparse.Commands(command) {
+		if ctx := parseExecSegment(segment); ctx != nil {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// parseExecSegment parses a single simple command
+func parseExecSegment(args []string) *ExecContext {
+	args = shellparse.StripPrefixes(args)
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch filepath.Base(args[0]) {
+	case "docker", "podman":
+		runtime := filepath.Base(args[0])
+		rest := shellparse.SkipFlags(args[1:], dockerGlobalFlags)
+		if len(rest) > 0 && rest[0] == "compose" {
+			return parseComposeExec(rest[1:])
+		}
+		if len(rest) > 1 && rest[0] == "container" {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 || rest[0] != "exec" {
+			return nil
+		}
+		return execTarget(runtime, rest[1:], dockerExecFlags)
+	case "docker-compose", "podman-compose":
+		return parseComposeExec(args[1:])
+	case "kubectl", "oc":
+		return parseKubectlExec(args[1:])
+	}
+
+	return nil
+}
+
+// parseComposeExec parses the arguments following "docker compose"
+func parseComposeExec(args []string) *ExecContext {
+	rest := shellparse.SkipFlags(args, composeGlobalFlags)
+	if len(rest) == 0 || rest[0] != "exec" {
+		return nil
+	}
+	return execTarget(RuntimeCompose, rest[1:], composeExecFlags)
+}
+
+// parseKubectlExec parses the arguments following "kubectl". The namespace
+// may be given before or after the exec subcommand.
+func parseKubectlExec(args []string) *ExecContext {
+	namespace := ""
+	isExec := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return nil
+		}
+
+		if name, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(name, "--") {
+			if name == "--namespace" {
+				namespace = value
+			}
+			continue
+		}
+
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			if !kubectlFlags[arg] {
+				// -nprod style
+				if strings.HasPrefix(arg, "-n") && !strings.HasPrefix(arg, "--") {
+					namespace = arg[2:]
+				}
+				continue
+			}
+			if i+1 < len(args) {
+				if arg == "-n" || arg == "--namespace" {
+					namespace = args[i+1]
+				}
+				i++
+			}
+			continue
+		}
+
+		if !isExec {
+			if arg != "exec" {
+				return nil
+			}
+			isExec = true
+			continue
+		}
+
+		return &ExecContext{Runtime: RuntimeKubectl, Target: qualify(namespace, arg)}
+	}
+
+	return nil
+}
+
+// execTarget returns the first positional argument after the exec subcommand
+func execTarget(runtime string, args []string, valueFlags map[string]bool) *ExecContext {
+	rest := shellparse.SkipFlags(args, valueFlags)
+	if len(rest) == 0 || rest[0] == "--" {
+		return nil
+	}
+	return &ExecContext{Runtime: runtime, Target: rest[0]}
+}
+
+// qualify prefixes target with namespace if one was given
+func qualify(namespace, target string) string {
+	if namespace == "" {
+		return target
+	}
+	return namespace + "/" + target
+}
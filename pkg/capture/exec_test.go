@@ -0,0 +1,52 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExecContext(t *testing.T) {
+	tests := []struct {
+		command string
+		want    *ExecContext
+	}{
+		{"docker exec -it api sh", &ExecContext{RuntimeDocker, "api"}},
+		{"docker exec -e FOO=bar -u root -w /app api bash -c 'ls'", &ExecContext{RuntimeDocker, "api"}},
+		{"docker --context prod container exec db psql", &ExecContext{RuntimeDocker, "db"}},
+		{"sudo -u deploy podman exec -ti web sh", &ExecContext{RuntimePodman, "web"}},
+		{"/usr/local/bin/docker exec cache redis-cli", &ExecContext{RuntimeDocker, "cache"}},
+		{"docker compose -f dev.yml exec worker rails c", &ExecContext{RuntimeCompose, "worker"}},
+		{"docker-compose exec -T db pg_dump", &ExecContext{RuntimeCompose, "db"}},
+		{"kubectl exec -it api-7d9f -- sh", &ExecContext{RuntimeKubectl, "api-7d9f"}},
+		{"kubectl -n prod exec -it api-7d9f -c app -- bash", &ExecContext{RuntimeKubectl, "prod/api-7d9f"}},
+		{"kubectl exec --namespace=staging deploy/api -- env", &ExecContext{RuntimeKubectl, "staging/deploy/api"}},
+		{"kubectl exec -nprod -it api -- sh", &ExecContext{RuntimeKubectl, "prod/api"}},
+		{"KUBECONFIG=~/.kube/prod oc exec router -- ls", &ExecContext{RuntimeKubectl, "router"}},
+		{"cat query.sql | docker exec -i db psql", &ExecContext{RuntimeDocker, "db"}},
+		{"cd /srv && kubectl exec web -- ls", &ExecContext{RuntimeKubectl, "web"}},
+		{"docker ps", nil},
+		{"docker run -it ubuntu", nil},
+		{"kubectl get pods -n exec", nil},
+		{"kubectl exec", nil},
+		{"echo 'docker exec api sh'", nil},
+		{"git commit -m 'kubectl exec'", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseExecContext(tt.command), "command %q", tt.command)
+	}
+}
+
+func TestCollect_ExecContext(t *testing.T) {
+	meta, err := Collect("kubectl -n prod exec api -- sh", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, RuntimeKubectl, meta.ExecRuntime)
+	assert.Equal(t, "prod/api", meta.ExecTarget)
+
+	meta, err = Collect("ls", 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, meta.ExecRuntime)
+	assert.Empty(t, meta.ExecTarget)
+}
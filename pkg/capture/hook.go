@@ -14,6 +14,9 @@ var bashHook string
 //go:embed shell/zsh.sh
 var zshHook string
 
+//go:embed shell/fish.fish
+var fishHook string
+
 // ShellType represents the type of shell
 type ShellType string
 
@@ -49,8 +52,20 @@ func DetectShell() (ShellType, error) {
 	}
 }
 
-// GetHookContent returns the shell hook content for the given shell type with keybinding
-func GetHookContent(shell ShellType, keybinding string) (string, error) {
+// Widgets the hooks bind keys to
+const (
+	searchWidget = "__fh_widget"     // fh
+	cwdWidget    = "__fh_cwd_widget" // fh --cwd-only
+)
+
+// cwdUnbound stands in for the bind line of the directory widget when it has
+// no keybinding, so setting one later knows where it goes
+const cwdUnbound = "# __fh_cwd_widget is not bound to a key, set search.cwd_keybinding"
+
+// GetHookContent returns the shell hook content for the given shell type with
+// keybinding for search, and cwdKeybinding, "" for none, for the search of
+// the current directory
+func GetHookContent(shell ShellType, keybinding, cwdKeybinding string) (string, error) {
 	var hookTemplate string
 
 	switch shell {
@@ -59,7 +74,7 @@ func GetHookContent(shell ShellType, keybinding string) (string, error) {
 	case ShellZsh:
 		hookTemplate = zshHook
 	case ShellFish:
-		return "", fmt.Errorf("fish shell not yet supported")
+		hookTemplate = fishHook
 	default:
 		return "", fmt.Errorf("unsupported shell: %s", shell)
 	}
@@ -70,9 +85,15 @@ func GetHookContent(shell ShellType, keybinding string) (string, error) {
 		return "", err
 	}
 
+	cwdBind, err := bindLine(shell, cwdWidget, cwdKeybinding)
+	if err != nil {
+		return "", err
+	}
+
 	// Replace placeholders in template
 	content := strings.ReplaceAll(hookTemplate, "{{KEYBINDING_DISPLAY}}", display)
 	content = strings.ReplaceAll(content, "{{KEYBINDING_CODE}}", code)
+	content = strings.ReplaceAll(content, "{{CWD_BIND}}", cwdBind)
 
 	return content, nil
 }
@@ -101,6 +122,9 @@ func parseKeybinding(shell ShellType, keybinding string) (display string, code s
 		case ShellZsh:
 			// Zsh format: ^R, ^G, etc
 			code = "^" + strings.ToUpper(key)
+		case ShellFish:
+			// Fish format: \cr, \cg, etc
+			code = "\\c" + key
 		default:
 			code = "\\C-" + key
 		}
@@ -111,6 +135,47 @@ func parseKeybinding(shell ShellType, keybinding string) (display string, code s
 	return "", "", fmt.Errorf("unsupported keybinding format: %s (expected ctrl-X format like 'ctrl-r' or 'ctrl-g')", keybinding)
 }
 
+// bindLine returns the line of the hooks binding keybinding to widget. An
+// empty keybinding leaves the directory widget unbound.
+func bindLine(shell ShellType, widget, keybinding string) (string, error) {
+	if widget == cwdWidget && strings.TrimSpace(keybinding) == "" {
+		return cwdUnbound, nil
+	}
+
+	_, code, err := parseKeybinding(shell, keybinding)
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case ShellZsh:
+		return fmt.Sprintf("bindkey '%s' %s", code, widget), nil
+	case ShellFish:
+		return fmt.Sprintf("bind %s %s", code, widget), nil
+	default:
+		return fmt.Sprintf("bind -x '\"%s\": %s'", code, widget), nil
+	}
+}
+
+// isBindLine reports whether the trimmed line binds a key to widget, or
+// marks where the directory widget's binding goes
+func isBindLine(shell ShellType, line, widget string) bool {
+	if widget == cwdWidget && line == cwdUnbound {
+		return true
+	}
+	switch shell {
+	case ShellBash:
+		// bind -x '"\C-r": __fh_widget'
+		return strings.Contains(line, "bind -x") && strings.Contains(line, widget)
+	case ShellZsh:
+		// bindkey '^R' __fh_widget
+		return strings.Contains(line, "bindkey") && strings.Contains(line, widget)
+	case ShellFish:
+		// bind \cr __fh_widget
+		return strings.HasPrefix(line, "bind ") && strings.HasSuffix(line, " "+widget)
+	}
+	return false
+}
+
 // GetRCFile returns the RC file path for the given shell type
 func GetRCFile(shell ShellType) (string, error) {
 	home, err := os.UserHomeDir()
@@ -135,6 +200,14 @@ func GetRCFile(shell ShellType) (string, error) {
 		}
 		return filepath.Join(home, ".zshrc"), nil
 
+	case ShellFish:
+		// Fish reads its config from XDG_CONFIG_HOME, ~/.config by default
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		return filepath.Join(configHome, "fish", "config.fish"), nil
+
 	default:
 		return "", fmt.Errorf("unsupported shell: %s", shell)
 	}
@@ -162,8 +235,10 @@ type HookInstallResult struct {
 	KeybindingUpdate bool   // Whether the keybinding was updated
 }
 
-// InstallHook installs the fh hook into the RC file with the specified keybinding
-func InstallHook(shell ShellType, rcFile string, keybinding string) (*HookInstallResult, error) {
+// InstallHook installs the fh hook into the RC file with the specified
+// keybindings, see GetHookContent. Installed hooks get their keybindings
+// updated.
+func InstallHook(shell ShellType, rcFile string, keybinding, cwdKeybinding string) (*HookInstallResult, error) {
 	result := &HookInstallResult{
 		RCFile: rcFile,
 	}
@@ -177,21 +252,26 @@ func InstallHook(shell ShellType, rcFile string, keybinding string) (*HookInstal
 	if installed {
 		result.Installed = false
 
-		// Check if keybinding needs to be updated
-		currentKeybinding, err := extractCurrentKeybinding(rcFile, shell)
-		if err != nil {
-			// If we can't extract the current keybinding, just return
-			// (this might happen with old installations)
-			return result, nil
-		}
+		// Check which keybindings need to be updated. Those that can't be
+		// found are left alone, old installations don't have them all.
+		changed := map[string]string{}
+		for widget, keybinding := range map[string]string{searchWidget: keybinding, cwdWidget: cwdKeybinding} {
+			currentKeybinding, err := extractCurrentKeybinding(rcFile, shell, widget)
+			if err != nil {
+				continue
+			}
 
-		// Normalize keybindings for comparison
-		desired := strings.ToLower(strings.TrimSpace(keybinding))
-		current := strings.ToLower(strings.TrimSpace(currentKeybinding))
+			// Normalize keybindings for comparison
+			desired := strings.ToLower(strings.TrimSpace(keybinding))
+			current := strings.ToLower(strings.TrimSpace(currentKeybinding))
+			if desired != current {
+				changed[widget] = keybinding
+			}
+		}
 
-		if desired != current {
-			// Keybinding has changed, update it
-			if err := updateKeybinding(rcFile, shell, keybinding); err != nil {
+		if len(changed) > 0 {
+			// Keybindings have changed, update them
+			if err := updateKeybindings(rcFile, shell, changed); err != nil {
 				return nil, fmt.Errorf("failed to update keybinding: %w", err)
 			}
 			result.KeybindingUpdate = true
@@ -202,13 +282,16 @@ func InstallHook(shell ShellType, rcFile string, keybinding string) (*HookInstal
 	}
 
 	// Get hook content with keybinding
-	hookContent, err := GetHookContent(shell, keybinding)
+	hookContent, err := GetHookContent(shell, keybinding, cwdKeybinding)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create RC file if it doesn't exist
+	// Create RC file if it doesn't exist, fish's directory included
 	if _, err := os.Stat(rcFile); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(rcFile), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create RC file directory: %w", err)
+		}
 		if err := os.WriteFile(rcFile, []byte{}, 0644); err != nil {
 			return nil, fmt.Errorf("failed to create RC file: %w", err)
 		}
@@ -242,8 +325,9 @@ func InstallHook(shell ShellType, rcFile string, keybinding string) (*HookInstal
 	return result, nil
 }
 
-// extractCurrentKeybinding extracts the current keybinding from the RC file
-func extractCurrentKeybinding(rcFile string, shell ShellType) (string, error) {
+// extractCurrentKeybinding extracts the keybinding of widget from the RC
+// file, "" when the directory widget is unbound
+func extractCurrentKeybinding(rcFile string, shell ShellType, widget string) (string, error) {
 	content, err := os.ReadFile(rcFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to read RC file: %w", err)
@@ -253,31 +337,20 @@ func extractCurrentKeybinding(rcFile string, shell ShellType) (string, error) {
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
+		if !isBindLine(shell, line, widget) {
+			continue
+		}
+		if line == cwdUnbound {
+			return "", nil
+		}
 
-		switch shell {
-		case ShellBash:
-			// Look for: bind -x '"\C-r": __fh_widget'
-			if strings.Contains(line, "bind -x") && strings.Contains(line, "__fh_widget") {
-				// Extract \C-X from the line
-				if idx := strings.Index(line, `"\C-`); idx != -1 {
-					start := idx + 4 // Skip past "\C-
-					if start < len(line) {
-						key := string(line[start])
-						return "ctrl-" + strings.ToLower(key), nil
-					}
-				}
-			}
-		case ShellZsh:
-			// Look for: bindkey '^R' __fh_widget
-			if strings.Contains(line, "bindkey") && strings.Contains(line, "__fh_widget") {
-				// Extract ^X from the line
-				if idx := strings.Index(line, "'^"); idx != -1 {
-					start := idx + 2 // Skip past '^
-					if start < len(line) {
-						key := string(line[start])
-						return "ctrl-" + strings.ToLower(key), nil
-					}
-				}
+		// The key follows \C- in bash, ^ in zsh and \c in fish
+		prefix := map[ShellType]string{ShellBash: `"\C-`, ShellZsh: "'^", ShellFish: `\c`}[shell]
+		if idx := strings.Index(line, prefix); idx != -1 {
+			start := idx + len(prefix)
+			if start < len(line) {
+				key := string(line[start])
+				return "ctrl-" + strings.ToLower(key), nil
 			}
 		}
 	}
@@ -285,8 +358,9 @@ func extractCurrentKeybinding(rcFile string, shell ShellType) (string, error) {
 	return "", fmt.Errorf("keybinding not found in RC file")
 }
 
-// updateKeybinding updates the keybinding line in the RC file
-func updateKeybinding(rcFile string, shell ShellType, keybinding string) error {
+// updateKeybindings updates the bind lines of the widgets in keybindings,
+// each to its keybinding, in the RC file
+func updateKeybindings(rcFile string, shell ShellType, keybindings map[string]string) error {
 	// Create backup before modifying
 	backupFile := rcFile + ".fh.backup"
 	if err := copyFile(rcFile, backupFile); err != nil {
@@ -298,36 +372,25 @@ func updateKeybinding(rcFile string, shell ShellType, keybinding string) error {
 		return fmt.Errorf("failed to read RC file: %w", err)
 	}
 
-	// Get the new keybinding code
-	_, newCode, err := parseKeybinding(shell, keybinding)
-	if err != nil {
-		return err
-	}
-
 	lines := strings.Split(string(content), "\n")
-	modified := false
 
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
+	for widget, keybinding := range keybindings {
+		// Get the new bind line
+		newLine, err := bindLine(shell, widget, keybinding)
+		if err != nil {
+			return err
+		}
 
-		switch shell {
-		case ShellBash:
-			// Look for: bind -x '"\C-r": __fh_widget'
-			if strings.Contains(trimmed, "bind -x") && strings.Contains(trimmed, "__fh_widget") {
-				lines[i] = fmt.Sprintf("bind -x '\"%s\": __fh_widget'", newCode)
-				modified = true
-			}
-		case ShellZsh:
-			// Look for: bindkey '^R' __fh_widget
-			if strings.Contains(trimmed, "bindkey") && strings.Contains(trimmed, "__fh_widget") {
-				lines[i] = fmt.Sprintf("bindkey '%s' __fh_widget", newCode)
+		modified := false
+		for i, line := range lines {
+			if isBindLine(shell, strings.TrimSpace(line), widget) {
+				lines[i] = newLine
 				modified = true
 			}
 		}
-	}
-
-	if !modified {
-		return fmt.Errorf("keybinding line not found in RC file")
+		if !modified {
+			return fmt.Errorf("keybinding line not found in RC file")
+		}
 	}
 
 	// Write back to file
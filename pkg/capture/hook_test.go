@@ -63,7 +63,7 @@ func TestDetectShell(t *testing.T) {
 
 func TestGetHookContent(t *testing.T) {
 	t.Run("get bash hook", func(t *testing.T) {
-		content, err := GetHookContent(ShellBash, "ctrl-r")
+		content, err := GetHookContent(ShellBash, "ctrl-r", "")
 		require.NoError(t, err)
 		assert.NotEmpty(t, content)
 		assert.Contains(t, content, "bash")
@@ -72,7 +72,7 @@ func TestGetHookContent(t *testing.T) {
 	})
 
 	t.Run("get zsh hook", func(t *testing.T) {
-		content, err := GetHookContent(ShellZsh, "ctrl-g")
+		content, err := GetHookContent(ShellZsh, "ctrl-g", "")
 		require.NoError(t, err)
 		assert.NotEmpty(t, content)
 		assert.Contains(t, content, "zsh")
@@ -80,14 +80,26 @@ func TestGetHookContent(t *testing.T) {
 		assert.Contains(t, content, "'^G'")
 	})
 
-	t.Run("fish not supported", func(t *testing.T) {
-		_, err := GetHookContent(ShellFish, "ctrl-r")
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "fish shell not yet supported")
+	t.Run("directory keybinding", func(t *testing.T) {
+		content, err := GetHookContent(ShellZsh, "ctrl-r", "ctrl-e")
+		require.NoError(t, err)
+		assert.Contains(t, content, "\nbindkey '^E' __fh_cwd_widget\n")
+		assert.NotContains(t, content, cwdUnbound)
+
+		_, err = GetHookContent(ShellZsh, "ctrl-r", "alt-e")
+		assert.ErrorContains(t, err, "unsupported keybinding format")
+	})
+
+	t.Run("get fish hook", func(t *testing.T) {
+		content, err := GetHookContent(ShellFish, "ctrl-r", "")
+		require.NoError(t, err)
+		assert.Contains(t, content, "fish_postexec")
+		assert.Contains(t, content, "Ctrl-R")
+		assert.Contains(t, content, `bind \cr __fh_widget`)
 	})
 
 	t.Run("unsupported shell type", func(t *testing.T) {
-		_, err := GetHookContent(ShellType("unknown"), "ctrl-r")
+		_, err := GetHookContent(ShellType("unknown"), "ctrl-r", "")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unsupported shell")
 	})
@@ -150,6 +162,21 @@ func TestGetRCFile(t *testing.T) {
 		assert.Equal(t, filepath.Join(tempZdot, ".zshrc"), rcFile)
 	})
 
+	t.Run("get fish RC file", func(t *testing.T) {
+		tempHome := t.TempDir()
+		t.Setenv("HOME", tempHome)
+		t.Setenv("XDG_CONFIG_HOME", "")
+
+		rcFile, err := GetRCFile(ShellFish)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(tempHome, ".config", "fish", "config.fish"), rcFile)
+
+		t.Setenv("XDG_CONFIG_HOME", "/etc/xdg-alice")
+		rcFile, err = GetRCFile(ShellFish)
+		require.NoError(t, err)
+		assert.Equal(t, "/etc/xdg-alice/fish/config.fish", rcFile)
+	})
+
 	t.Run("unsupported shell", func(t *testing.T) {
 		_, err := GetRCFile(ShellType("unknown"))
 		assert.Error(t, err)
@@ -205,7 +232,7 @@ func TestInstallHook(t *testing.T) {
 		err := os.WriteFile(rcFile, []byte(initialContent), 0644)
 		require.NoError(t, err)
 
-		result, err := InstallHook(ShellBash, rcFile, "ctrl-r")
+		result, err := InstallHook(ShellBash, rcFile, "ctrl-r", "")
 		require.NoError(t, err)
 		assert.True(t, result.Installed)
 		assert.Equal(t, rcFile, result.RCFile)
@@ -236,7 +263,7 @@ hook content here
 		err := os.WriteFile(rcFile, []byte(content), 0644)
 		require.NoError(t, err)
 
-		result, err := InstallHook(ShellBash, rcFile, "ctrl-r")
+		result, err := InstallHook(ShellBash, rcFile, "ctrl-r", "")
 		require.NoError(t, err)
 		assert.False(t, result.Installed)
 		assert.Equal(t, rcFile, result.RCFile)
@@ -251,7 +278,7 @@ hook content here
 		tempDir := t.TempDir()
 		rcFile := filepath.Join(tempDir, ".bashrc")
 
-		result, err := InstallHook(ShellBash, rcFile, "ctrl-r")
+		result, err := InstallHook(ShellBash, rcFile, "ctrl-r", "")
 		require.NoError(t, err)
 		assert.True(t, result.Installed)
 
@@ -272,7 +299,7 @@ hook content here
 		err := os.WriteFile(rcFile, []byte(""), 0644)
 		require.NoError(t, err)
 
-		result, err := InstallHook(ShellZsh, rcFile, "ctrl-g")
+		result, err := InstallHook(ShellZsh, rcFile, "ctrl-g", "")
 		require.NoError(t, err)
 		assert.True(t, result.Installed)
 
@@ -282,6 +309,62 @@ hook content here
 		assert.Contains(t, string(content), "Ctrl-G")
 		assert.Contains(t, string(content), "'^G'")
 	})
+
+	t.Run("install fish hook", func(t *testing.T) {
+		// ~/.config/fish doesn't exist before fish first runs
+		rcFile := filepath.Join(t.TempDir(), ".config", "fish", "config.fish")
+
+		result, err := InstallHook(ShellFish, rcFile, "ctrl-r", "")
+		require.NoError(t, err)
+		assert.True(t, result.Installed)
+
+		content, err := os.ReadFile(rcFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "# fh - Fast History")
+		assert.Contains(t, string(content), `bind \cr __fh_widget`)
+
+		// Another keybinding rewrites the bind line
+		result, err = InstallHook(ShellFish, rcFile, "ctrl-g", "")
+		require.NoError(t, err)
+		assert.True(t, result.KeybindingUpdate)
+		content, err = os.ReadFile(rcFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "\nbind \\cg __fh_widget\n")
+		assert.NotContains(t, string(content), `bind \cr __fh_widget`)
+	})
+
+	t.Run("directory keybinding", func(t *testing.T) {
+		rcFile := filepath.Join(t.TempDir(), ".bashrc")
+
+		_, err := InstallHook(ShellBash, rcFile, "ctrl-r", "")
+		require.NoError(t, err)
+		content, err := os.ReadFile(rcFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "\n"+cwdUnbound+"\n")
+
+		// Setting it binds the widget, the search keybinding stays
+		result, err := InstallHook(ShellBash, rcFile, "ctrl-r", "ctrl-e")
+		require.NoError(t, err)
+		assert.True(t, result.KeybindingUpdate)
+		content, err = os.ReadFile(rcFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "\nbind -x '\"\\C-e\": __fh_cwd_widget'\n")
+		assert.Contains(t, string(content), "\nbind -x '\"\\C-r\": __fh_widget'\n")
+		assert.NotContains(t, string(content), cwdUnbound)
+
+		result, err = InstallHook(ShellBash, rcFile, "ctrl-r", "ctrl-e")
+		require.NoError(t, err)
+		assert.False(t, result.KeybindingUpdate)
+
+		// Clearing it unbinds the widget again
+		result, err = InstallHook(ShellBash, rcFile, "ctrl-g", "")
+		require.NoError(t, err)
+		assert.True(t, result.KeybindingUpdate)
+		content, err = os.ReadFile(rcFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "\n"+cwdUnbound+"\n")
+		assert.Contains(t, string(content), "\nbind -x '\"\\C-g\": __fh_widget'\n")
+	})
 }
 
 func TestCopyFile(t *testing.T) {
@@ -0,0 +1,79 @@
+package capture
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Replaced in tests
+var (
+	machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+	ioregOutput    = func() ([]byte, error) {
+		return exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	}
+	userCacheDir = os.UserCacheDir
+)
+
+// platformUUID matches the hardware UUID in the output of macOS ioreg
+var platformUUID = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+// HostID returns an identifier of this machine that stays the same across
+// reboots and hostname changes: the systemd/dbus machine id on Linux, the
+// hardware UUID on macOS, and the hostname when neither is available
+func HostID() string {
+	for _, path := range machineIDFiles {
+		if id := readID(path); id != "" {
+			return id
+		}
+	}
+
+	if id := platformID(); id != "" {
+		return id
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return "host:" + hostname
+}
+
+// platformID returns the macOS hardware UUID. Running ioreg takes a while,
+// so the UUID is kept in the user cache directory, which unlike ~/.fh is
+// not something people sync between machines.
+func platformID() string {
+	var cache string
+	if dir, err := userCacheDir(); err == nil {
+		cache = filepath.Join(dir, "fh", "host-id")
+		if id := readID(cache); id != "" {
+			return id
+		}
+	}
+
+	out, err := ioregOutput()
+	if err != nil {
+		return ""
+	}
+	m := platformUUID.FindSubmatch(out)
+	if m == nil {
+		return ""
+	}
+
+	id := string(m[1])
+	if cache != "" && os.MkdirAll(filepath.Dir(cache), 0755) == nil {
+		_ = os.WriteFile(cache, []byte(id+"\n"), 0644)
+	}
+	return id
+}
+
+// readID returns the trimmed contents of an id file, empty when missing
+func readID(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
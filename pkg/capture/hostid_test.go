@@ -0,0 +1,56 @@
+package capture
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostID(t *testing.T) {
+	origFiles, origIoreg, origCacheDir := machineIDFiles, ioregOutput, userCacheDir
+	t.Cleanup(func() {
+		machineIDFiles, ioregOutput, userCacheDir = origFiles, origIoreg, origCacheDir
+	})
+
+	dir := t.TempDir()
+	userCacheDir = func() (string, error) {
+		return filepath.Join(dir, "cache"), nil
+	}
+	machineID := filepath.Join(dir, "machine-id")
+	assert.NoError(t, os.WriteFile(machineID, []byte("4c4c4544004e\n"), 0644))
+	ioregOutput = func() ([]byte, error) {
+		return []byte(`+-o MacBookPro18,3  <class IOPlatformExpertDevice>
+    {
+      "IOPlatformUUID" = "1F2E3D4C-0000-1111-2222-333344445555"
+    }`), nil
+	}
+
+	t.Run("machine id", func(t *testing.T) {
+		machineIDFiles = []string{filepath.Join(dir, "missing"), machineID}
+		assert.Equal(t, "4c4c4544004e", HostID())
+	})
+
+	t.Run("macOS hardware uuid", func(t *testing.T) {
+		machineIDFiles = nil
+		assert.Equal(t, "1F2E3D4C-0000-1111-2222-333344445555", HostID())
+
+		// Later calls read it from the cache instead of running ioreg
+		ioregOutput = func() ([]byte, error) {
+			return nil, errors.New("executable file not found")
+		}
+		assert.Equal(t, "1F2E3D4C-0000-1111-2222-333344445555", HostID())
+	})
+
+	t.Run("hostname", func(t *testing.T) {
+		machineIDFiles = nil
+		userCacheDir = func() (string, error) {
+			return "", errors.New("no cache directory")
+		}
+		hostname, err := os.Hostname()
+		assert.NoError(t, err)
+		assert.Equal(t, "host:"+hostname, HostID())
+	})
+}
@@ -0,0 +1,262 @@
+package capture
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Semantic prompt markers (OSC 133, also emitted by iTerm2, kitty, WezTerm
+// and VS Code shell integration):
+//
+//	ESC ] 133 ; A ST    prompt start
+//	ESC ] 133 ; B ST    prompt end, user input starts
+//	ESC ] 133 ; C ST    input done, command output starts
+//	ESC ] 133 ; D ; N ST  command finished with exit code N
+//
+// ST is either BEL or ESC \. OSC 7 (ESC ] 7 ; file://host/path ST) reports
+// the working directory.
+
+// OSC133Command is a command whose boundaries were reported by prompt markers
+type OSC133Command struct {
+	Command  string
+	ExitCode int
+	Cwd      string // From the last OSC 7, empty if the shell never sent one
+	Start    time.Time
+	End      time.Time
+}
+
+// Metadata converts the command to capture metadata, filling in host details
+// the same way Collect does
+func (c OSC133Command) Metadata(sessionID string) *Metadata {
+	metadataMutex.Do(initMetadataCache)
+
+	meta := &Metadata{
+		Command:    c.Command,
+		ExitCode:   c.ExitCode,
+		Cwd:        c.Cwd,
+		Hostname:   cachedHostname,
+		User:       cachedUser,
+		Shell:      cachedShell,
+		Timestamp:  c.Start.Unix(),
+		DurationMs: c.End.Sub(c.Start).Milliseconds(),
+		SessionID:  sessionID,
+	}
+
+	if meta.Cwd != "" {
+		meta.GitBranch = GitBranch(meta.Cwd)
+	}
+	meta.setExecContext()
+
+	return meta
+}
+
+// parser states for escape sequence handling
+const (
+	stateText = iota
+	stateEscape
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// command phases driven by the OSC 133 markers
+const (
+	phaseIdle = iota
+	phaseInput
+	phaseRunning
+)
+
+// OSC133Parser extracts commands from a raw terminal output stream.
+// It implements io.Writer so it can be fed directly from a pipe.
+//
+// Without a cmdline parameter on the C marker, the command text is
+// reconstructed from the echoed input between B and C. Line editors that
+// move the cursor around can make that reconstruction imperfect.
+type OSC133Parser struct {
+	onCommand func(OSC133Command)
+	now       func() time.Time
+
+	state int
+	seq   []byte // Body of the escape sequence being read
+
+	phase     int
+	input     []byte // Echoed user input while in phaseInput
+	pendingCR bool   // Carriage return seen, waiting to see if a newline follows
+	current   OSC133Command
+	cwd       string
+}
+
+// NewOSC133Parser creates a parser that calls onCommand for every finished command
+func NewOSC133Parser(onCommand func(OSC133Command)) *OSC133Parser {
+	return &OSC133Parser{
+		onCommand: onCommand,
+		now:       time.Now,
+	}
+}
+
+// Write feeds terminal output to the parser. It never fails.
+func (p *OSC133Parser) Write(data []byte) (int, error) {
+	for _, b := range data {
+		p.feed(b)
+	}
+	return len(data), nil
+}
+
+// feed advances the state machine by one byte
+func (p *OSC133Parser) feed(b byte) {
+	switch p.state {
+	case stateText:
+		if b == 0x1b {
+			p.state = stateEscape
+			return
+		}
+		p.text(b)
+
+	case stateEscape:
+		switch b {
+		case '[':
+			p.state = stateCSI
+		case ']':
+			p.state = stateOSC
+			p.seq = p.seq[:0]
+		default:
+			// Two-byte escape, nothing to record
+			p.state = stateText
+		}
+
+	case stateCSI:
+		// Parameters and intermediates run until a final byte in 0x40-0x7e
+		if b >= 0x40 && b <= 0x7e {
+			p.state = stateText
+		}
+
+	case stateOSC:
+		switch b {
+		case 0x07:
+			p.osc(string(p.seq))
+			p.state = stateText
+		case 0x1b:
+			p.state = stateOSCEscape
+		default:
+			p.seq = append(p.seq, b)
+		}
+
+	case stateOSCEscape:
+		// ESC \ terminates the OSC; anything else aborts it
+		if b == '\\' {
+			p.osc(string(p.seq))
+		}
+		p.state = stateText
+	}
+}
+
+// text handles a plain output byte
+func (p *OSC133Parser) text(b byte) {
+	if p.phase != phaseInput {
+		return
+	}
+
+	// A carriage return not followed by a newline means the line editor is
+	// redrawing the line, so start over (repeated CRs come from \r\r\n)
+	if p.pendingCR && b != '\r' {
+		p.pendingCR = false
+		if b != '\n' {
+			p.input = p.input[:0]
+		}
+	}
+
+	switch b {
+	case '\b', 0x7f:
+		// Remove the last rune
+		if len(p.input) > 0 {
+			_, size := utf8.DecodeLastRune(p.input)
+			p.input = p.input[:len(p.input)-size]
+		}
+	case '\r':
+		p.pendingCR = true
+	case '\n', '\t':
+		p.input = append(p.input, b)
+	default:
+		if b >= 0x20 {
+			p.input = append(p.input, b)
+		}
+	}
+}
+
+// osc handles a complete OSC sequence body
+func (p *OSC133Parser) osc(body string) {
+	switch {
+	case strings.HasPrefix(body, "7;"):
+		if u, err := url.Parse(body[2:]); err == nil && u.Path != "" {
+			p.cwd = u.Path
+		}
+
+	case strings.HasPrefix(body, "133;"):
+		fields := strings.Split(body[4:], ";")
+		p.marker(fields[0], fields[1:])
+	}
+}
+
+// marker handles an OSC 133 marker with its parameters
+func (p *OSC133Parser) marker(kind string, params []string) {
+	switch kind {
+	case "A":
+		p.phase = phaseIdle
+
+	case "B":
+		p.phase = phaseInput
+		p.input = p.input[:0]
+		p.pendingCR = false
+
+	case "C":
+		command := strings.TrimSpace(string(p.input))
+		if cmdline, ok := cmdlineParam(params); ok {
+			command = cmdline
+		}
+
+		p.current = OSC133Command{
+			Command: command,
+			Cwd:     p.cwd,
+			Start:   p.now(),
+		}
+		p.phase = phaseRunning
+
+	case "D":
+		if p.phase != phaseRunning {
+			// Prompt aborted without running anything (e.g. Ctrl-C)
+			p.phase = phaseIdle
+			return
+		}
+		p.phase = phaseIdle
+
+		p.current.End = p.now()
+		if len(params) > 0 {
+			if code, err := strconv.Atoi(params[0]); err == nil {
+				p.current.ExitCode = code
+			}
+		}
+
+		if p.current.Command != "" && p.onCommand != nil {
+			p.onCommand(p.current)
+		}
+	}
+}
+
+// cmdlineParam extracts the command line some terminals attach to the C marker
+// (cmdline=<text> or cmdline_url=<percent-encoded text>)
+func cmdlineParam(params []string) (string, bool) {
+	for _, param := range params {
+		if value, ok := strings.CutPrefix(param, "cmdline_url="); ok {
+			if decoded, err := url.PathUnescape(value); err == nil {
+				return strings.TrimSpace(decoded), true
+			}
+		}
+		if value, ok := strings.CutPrefix(param, "cmdline="); ok {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,133 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	promptStart = "\x1b]133;A\x07"
+	promptEnd   = "\x1b]133;B\x07"
+	outputStart = "\x1b]133;C\x07"
+)
+
+func newTestParser(t *testing.T) (*OSC133Parser, *[]OSC133Command) {
+	t.Helper()
+	var commands []OSC133Command
+	p := NewOSC133Parser(func(c OSC133Command) {
+		commands = append(commands, c)
+	})
+
+	clock := time.Unix(1000, 0)
+	p.now = func() time.Time {
+		clock = clock.Add(250 * time.Millisecond)
+		return clock
+	}
+	return p, &commands
+}
+
+func TestOSC133Parser_BasicCommand(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	stream := promptStart + "user@host:~$ " + promptEnd + "ls -la\r\r\n" +
+		outputStart + "total 0\r\n" + "\x1b]133;D;2\x1b\\"
+	_, err := p.Write([]byte(stream))
+	require.NoError(t, err)
+
+	require.Len(t, *commands, 1)
+	cmd := (*commands)[0]
+	assert.Equal(t, "ls -la", cmd.Command)
+	assert.Equal(t, 2, cmd.ExitCode)
+	assert.Equal(t, 250*time.Millisecond, cmd.End.Sub(cmd.Start))
+}
+
+func TestOSC133Parser_SplitWrites(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	// Feed one byte at a time, as a pipe may deliver it
+	stream := promptStart + "$ " + promptEnd + "make test" + outputStart + "ok\n" + "\x1b]133;D;0\x07"
+	for i := 0; i < len(stream); i++ {
+		_, _ = p.Write([]byte{stream[i]})
+	}
+
+	require.Len(t, *commands, 1)
+	assert.Equal(t, "make test", (*commands)[0].Command)
+}
+
+func TestOSC133Parser_LineEditing(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	// Typo fixed with backspace, colors from syntax highlighting
+	stream := promptStart + "$ " + promptEnd +
+		"\x1b[32mgit\x1b[0m statsu\b\bus" + outputStart + "\x1b]133;D;0\x07"
+	_, _ = p.Write([]byte(stream))
+
+	require.Len(t, *commands, 1)
+	assert.Equal(t, "git status", (*commands)[0].Command)
+}
+
+func TestOSC133Parser_CmdlineParam(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	stream := promptStart + "$ " + promptEnd + "garbled" +
+		"\x1b]133;C;cmdline_url=echo%20%22hi%3B%20there%22\x07" + "\x1b]133;D;0\x07"
+	_, _ = p.Write([]byte(stream))
+
+	require.Len(t, *commands, 1)
+	assert.Equal(t, `echo "hi; there"`, (*commands)[0].Command)
+}
+
+func TestOSC133Parser_WorkingDirectory(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	stream := "\x1b]7;file://host/home/user/my%20project\x07" +
+		promptStart + "$ " + promptEnd + "pwd" + outputStart + "\x1b]133;D;0\x07"
+	_, _ = p.Write([]byte(stream))
+
+	require.Len(t, *commands, 1)
+	assert.Equal(t, "/home/user/my project", (*commands)[0].Cwd)
+}
+
+func TestOSC133Parser_SkipsAbortedAndEmpty(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	// Ctrl-C at the prompt: D without C
+	_, _ = p.Write([]byte(promptStart + "$ " + promptEnd + "half typed^C" + "\x1b]133;D;130\x07"))
+	// Enter on an empty line
+	_, _ = p.Write([]byte(promptStart + "$ " + promptEnd + "\r\n" + outputStart + "\x1b]133;D;0\x07"))
+	// Output without any markers
+	_, _ = p.Write([]byte("plain output\n"))
+
+	assert.Empty(t, *commands)
+}
+
+func TestOSC133Command_Metadata(t *testing.T) {
+	start := time.Unix(2000, 0)
+	cmd := OSC133Command{
+		Command:  "sleep 1",
+		ExitCode: 0,
+		Start:    start,
+		End:      start.Add(1500 * time.Millisecond),
+	}
+
+	meta := cmd.Metadata("pane-1")
+	assert.Equal(t, "sleep 1", meta.Command)
+	assert.Equal(t, int64(2000), meta.Timestamp)
+	assert.Equal(t, int64(1500), meta.DurationMs)
+	assert.Equal(t, "pane-1", meta.SessionID)
+	assert.NotEmpty(t, meta.Hostname)
+}
+
+func TestOSC133Parser_Redraw(t *testing.T) {
+	p, commands := newTestParser(t)
+
+	// The line editor redraws the whole line after a carriage return
+	stream := promptStart + "$ " + promptEnd + "gti" + "\rgit log" + "\r\n" + outputStart + "\x1b]133;D;0\x07"
+	_, _ = p.Write([]byte(stream))
+
+	require.Len(t, *commands, 1)
+	assert.Equal(t, "git log", (*commands)[0].Command)
+}
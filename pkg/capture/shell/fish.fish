@@ -0,0 +1,138 @@
+# fh - Fast History
+# Fish shell integration
+# This file is sourced by ~/.config/fish/config.fish
+
+# Session ID shared by every command of this shell
+set -gx FH_SESSION "$fish_pid-"(date +%s)
+
+# Hands a save to a running fh --daemon through its FIFO, without starting
+# fh. A save is written at once so it doesn't mix with another shell's,
+# commands spanning lines or too long for one write are left to fh --save.
+# fish has no kill builtin, so the daemon is looked up in /proc.
+function __fh_send
+    set -l fifo ~/.fh/fh.fifo
+    test -p $fifo -a -f ~/.fh/fh.pid; or return 1
+    string match -q -r '\n' -- $argv[1]; and return 1
+    read -l pid < ~/.fh/fh.pid; or return 1
+    test -n "$pid" -a -d /proc/$pid; or return 1
+
+    # Up to 4 bytes a character, writes of up to 4096 bytes are never split
+    test (string length -- "$argv[1]$PWD$FH_SESSION$FH_PROFILE$FH_DB_PATH") -lt 896; or return 1
+
+    string join0 -- "cmd=$argv[1]" "exit=$argv[2]" job=0 "duration=$argv[3]" "cwd=$PWD" shell=fish \
+        "session=$FH_SESSION" "profile=$FH_PROFILE" "db=$FH_DB_PATH" "" >$fifo
+end
+
+# fh save hook - captures command after execution
+# fish_postexec gets the command line, $status and $CMD_DURATION (in ms)
+# still describe it
+function __fh_save --on-event fish_postexec
+    set -l exit_code $status
+    set -l duration $CMD_DURATION
+    set -l last_cmd $argv[1]
+
+    # A new prompt starts browsing the session from its last command again
+    set -g __fh_prev_index 0
+
+    # Skip empty commands
+    if test -z "$last_cmd"
+        return
+    end
+
+    # Skip if this is the same command as last time (prevents duplicates)
+    if test "$last_cmd" = "$__fh_last_cmd"
+        return
+    end
+    set -g __fh_last_cmd $last_cmd
+
+    # Hand it to a running daemon, or save it with fh in the background to
+    # avoid blocking the prompt
+    set -l duration_ms (math "round(0$duration)")
+    if not __fh_send "$last_cmd" $exit_code $duration_ms
+        command fh --save \
+            --cmd "$last_cmd" \
+            --exit-code $exit_code \
+            --duration $duration_ms \
+            2>/dev/null &
+        disown 2>/dev/null
+    end
+end
+
+# Switching profiles with `fh --profile <name>` also exports FH_PROFILE,
+# so the current shell saves to the new profile right away
+function fh
+    if test "$argv[1]" = --profile; and test -n "$argv[2]"; and not string match -q -- '-*' "$argv[2]"
+        command fh $argv; and set -gx FH_PROFILE $argv[2]
+    else
+        command fh $argv
+    end
+end
+
+# fh widget for {{KEYBINDING_DISPLAY}}
+function __fh_widget
+    set -l selected (command fh | string collect)
+    if test -n "$selected"
+        commandline -r -- $selected
+    end
+    commandline -f repaint
+end
+
+# Bind {{KEYBINDING_DISPLAY}} to fh widget
+bind {{KEYBINDING_CODE}} __fh_widget
+
+# Directory widget: like {{KEYBINDING_DISPLAY}}, but only offers commands run
+# in the current directory or below it. Bound with search.cwd_keybinding.
+function __fh_cwd_widget
+    set -l selected (command fh --cwd-only | string collect)
+    if test -n "$selected"
+        commandline -r -- $selected
+    end
+    commandline -f repaint
+end
+
+{{CWD_BIND}}
+
+# Ask widget: replaces the question typed on the command line with the
+# command picked from the answer of fh --ask. Not bound to a key, bind it
+# with e.g.: bind \ea __fh_ask_widget
+function __fh_ask_widget
+    set -l question (commandline | string collect)
+    if test -z "$question"
+        return
+    end
+    set -l selected (command fh --ask --pick "$question" | string collect)
+    if test -n "$selected"
+        commandline -r -- $selected
+    end
+    commandline -f repaint
+end
+
+# Session history widgets: step back and forth through the commands of this
+# shell session as fh saved them, with fh --prev. Not bound to keys, bind
+# them to the arrows with e.g.:
+#   bind up __fh_prev_widget
+#   bind down __fh_next_widget
+set -g __fh_prev_index 0
+function __fh_prev_widget
+    set -l index (math $__fh_prev_index + 1)
+    set -l selected (command fh --prev --index $index 2>/dev/null | string collect)
+    or return
+    set -g __fh_prev_index $index
+    commandline -r -- $selected
+    commandline -f repaint
+end
+
+function __fh_next_widget
+    if test $__fh_prev_index -le 1
+        set -g __fh_prev_index 0
+        commandline -r ''
+    else
+        set -g __fh_prev_index (math $__fh_prev_index - 1)
+        commandline -r -- (command fh --prev --index $__fh_prev_index 2>/dev/null | string collect)
+    end
+    commandline -f repaint
+end
+
+# Command of the day, printed by the first shell of each day when
+# remind.daily is on
+command fh --remind --daily 2>/dev/null
@@ -2,10 +2,75 @@
 # Zsh shell integration
 # This file is sourced by ~/.zshrc
 
+# Session ID shared by every command of this shell, so background jobs can
+# be matched up with their entry once they finish
+export FH_SESSION="$$-$(date +%s)"
+
+# Background jobs started from this shell that haven't finished yet
+__fh_jobs=()
+__fh_last_bg="$!"
+
+# A running fh --daemon reads saves from its FIFO in this directory
+__fh_dir="$HOME/.fh"
+
+# Hands a save to a running fh --daemon through its FIFO, without starting
+# fh. A save is written at once so it doesn't mix with another shell's,
+# commands spanning lines or too long for one write are left to fh --save.
+__fh_send() {
+    local pid record fifo="$__fh_dir/fh.fifo"
+    [[ -p "$fifo" && "$1" != *$'\n'* ]] || return 1
+    read -r pid 2>/dev/null < "$__fh_dir/fh.pid" && kill -0 "$pid" 2>/dev/null || return 1
+
+    # Counted in bytes, writes of up to 4096 are never split
+    local LC_ALL=C
+    record="$1$PWD${FH_SESSION:-}${FH_PROFILE:-}${FH_DB_PATH:-}"
+    (( ${#record} < 3584 )) || return 1
+
+    printf '%s\0' "cmd=$1" "exit=$2" "job=$3" "duration=0" "cwd=$PWD" "shell=zsh" \
+        "session=${FH_SESSION:-}" "profile=${FH_PROFILE:-}" "db=${FH_DB_PATH:-}" "" 1<>"$fifo"
+}
+
+# fh job hook - records exit code and duration of finished background jobs
+__fh_reap_jobs() {
+    local pid code
+    local running=()
+
+    for pid in "${__fh_jobs[@]}"; do
+        if kill -0 "$pid" 2>/dev/null; then
+            running+=("$pid")
+            continue
+        fi
+
+        # zsh remembers the status of finished jobs, so wait returns it
+        wait "$pid" 2>/dev/null
+        code=$?
+        {
+            fh --amend --exit-code $code "$FH_SESSION" "$pid" 2>/dev/null
+        } &
+        disown
+        __fh_last_bg="$!"
+    done
+
+    __fh_jobs=("${running[@]}")
+}
+
 # fh save hook - captures command after execution
 __fh_save() {
     local exit_code=$?
     local last_cmd=$(fc -ln -1)
+    local job=0
+
+    # A new prompt starts browsing the session from its last command again
+    __fh_prev_index=0
+
+    # A new $! means the last command started a background job
+    if [[ -n "$!" && "$!" != "$__fh_last_bg" ]]; then
+        job="$!"
+        __fh_last_bg="$!"
+    fi
+
+    # Only jobs of earlier prompts, whose entries are saved already
+    __fh_reap_jobs
 
     # Skip empty commands
     if [[ -z "$last_cmd" ]]; then
@@ -19,19 +84,38 @@ __fh_save() {
     fi
     __fh_last_cmd="$last_cmd"
 
-    # Save to fh in background to avoid blocking the prompt
-    {
-        fh --save \
-            --cmd "$last_cmd" \
-            --exit-code $exit_code \
-            --duration 0 \
-            2>/dev/null
-    } &
-    disown
+    if [[ $job != 0 ]]; then
+        __fh_jobs+=("$job")
+    fi
+
+    # Hand it to a running daemon, or save it with fh in the background to
+    # avoid blocking the prompt
+    if ! __fh_send "$last_cmd" $exit_code $job; then
+        {
+            fh --save \
+                --cmd "$last_cmd" \
+                --exit-code $exit_code \
+                --duration 0 \
+                --job $job \
+                2>/dev/null
+        } &
+        disown
+        __fh_last_bg="$!"
+    fi
 
     return $exit_code
 }
 
+# Switching profiles with `fh --profile <name>` also exports FH_PROFILE,
+# so the current shell saves to the new profile right away
+fh() {
+    if [[ "$1" == "--profile" && -n "$2" && "$2" != -* ]]; then
+        command fh "$@" && export FH_PROFILE="$2"
+    else
+        command fh "$@"
+    fi
+}
+
 # Add to precmd_functions if not already present
 if (( ! ${precmd_functions[(I)__fh_save]} )); then
     precmd_functions+=(__fh_save)
@@ -52,3 +136,65 @@ zle -N __fh_widget
 
 # Bind {{KEYBINDING_DISPLAY}} to fh widget
 bindkey '{{KEYBINDING_CODE}}' __fh_widget
+
+# Directory widget: like {{KEYBINDING_DISPLAY}}, but only offers commands run
+# in the current directory or below it. Bound with search.cwd_keybinding.
+__fh_cwd_widget() {
+    local selected
+    selected=$(fh --cwd-only)
+    if [[ -n "$selected" ]]; then
+        LBUFFER="$selected"
+    fi
+    zle reset-prompt
+}
+
+zle -N __fh_cwd_widget
+{{CWD_BIND}}
+
+# Ask widget: replaces the question typed on the command line with the
+# command picked from the answer of fh --ask. Not bound to a key, bind it
+# with e.g.: bindkey '^[a' __fh_ask_widget
+__fh_ask_widget() {
+    local selected
+    [[ -z "$BUFFER" ]] && return
+    selected=$(fh --ask --pick "$BUFFER")
+    if [[ -n "$selected" ]]; then
+        BUFFER="$selected"
+        CURSOR=${#BUFFER}
+    fi
+    zle reset-prompt
+}
+
+zle -N __fh_ask_widget
+
+# Session history widgets: step back and forth through the commands of this
+# shell session as fh saved them, with fh --prev. Not bound to keys, bind
+# them to the arrows with e.g.:
+#   bindkey '^[[A' __fh_prev_widget
+#   bindkey '^[[B' __fh_next_widget
+__fh_prev_index=0
+__fh_prev_widget() {
+    local selected
+    selected=$(fh --prev --index $((__fh_prev_index + 1)) 2>/dev/null) || return
+    (( __fh_prev_index++ ))
+    BUFFER="$selected"
+    CURSOR=${#BUFFER}
+}
+
+__fh_next_widget() {
+    if (( __fh_prev_index <= 1 )); then
+        __fh_prev_index=0
+        BUFFER=""
+    else
+        (( __fh_prev_index-- ))
+        BUFFER=$(fh --prev --index $__fh_prev_index 2>/dev/null)
+    fi
+    CURSOR=${#BUFFER}
+}
+
+zle -N __fh_prev_widget
+zle -N __fh_next_widget
+
+# Command of the day, printed by the first shell of each day when
+# remind.daily is on
+fh --remind --daily 2>/dev/null
@@ -0,0 +1,184 @@
+package capture
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// sshFlagsWithValue lists ssh options that consume the following argument
+const sshFlagsWithValue = "BbcDEeFIiJLlmOopQRSWw"
+
+// SSHHost returns the destination host from ssh arguments, without the user
+// part, or "" if none is found
+func SSHHost(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return stripSSHUser(args[i+1])
+			}
+			return ""
+		}
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			// -p 22 takes the next argument, -p22 does not
+			if len(arg) == 2 && strings.ContainsRune(sshFlagsWithValue, rune(arg[1])) {
+				i++
+			}
+			continue
+		}
+		return stripSSHUser(arg)
+	}
+	return ""
+}
+
+// stripSSHUser turns user@host or ssh://user@host:port into host
+func stripSSHUser(dest string) string {
+	dest = strings.TrimPrefix(dest, "ssh://")
+	if idx := strings.LastIndex(dest, "@"); idx >= 0 {
+		dest = dest[idx+1:]
+	}
+	if idx := strings.LastIndex(dest, ":"); idx >= 0 && !strings.Contains(dest, "]") {
+		dest = dest[:idx]
+	}
+	return dest
+}
+
+// ScriptCommand returns a command that runs argv while recording the
+// terminal session to typescript, using the platform's script(1)
+func ScriptCommand(typescript string, argv []string) *exec.Cmd {
+	if runtime.GOOS == "linux" {
+		// util-linux: -e returns the child's exit code, -c takes one string
+		return exec.Command("script", "-q", "-e", "-c", shellJoin(argv), typescript)
+	}
+	// BSD/macOS: script [-q] file command...
+	return exec.Command("script", append([]string{"-q", typescript}, argv...)...)
+}
+
+// shellJoin quotes arguments for sh -c
+func shellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// promptPattern matches a typical interactive prompt followed by the typed
+// command: user@host:~/dir$ cmd, [user@host dir]$ cmd, (venv) ~/src % cmd,
+// root@host:/# cmd or a bare "$ cmd"
+var promptPattern = regexp.MustCompile(
+	`^(?:\([^)]*\) )?(?:\[[^\]]+\]|[\w.-]+@[\w.-]+(?::[^$#%>]*)?|[~/][^$#%>]*)?\s?[$#%>❯] (.+)$`,
+)
+
+// ParseTypescript extracts the commands typed during a recorded terminal
+// session. OSC 133 markers are used when the remote shell emits them,
+// otherwise commands are recognized by their prompt, which is best effort.
+func ParseTypescript(data []byte) []string {
+	if bytes.Contains(data, []byte("\x1b]133;")) {
+		var commands []string
+		parser := NewOSC133Parser(func(cmd OSC133Command) {
+			commands = append(commands, cmd.Command)
+		})
+		_, _ = parser.Write(data)
+		return commands
+	}
+
+	var commands []string
+	for _, line := range terminalLines(data) {
+		match := promptPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if command := strings.TrimSpace(match[1]); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// terminalLines renders raw terminal output into plain lines, dropping escape
+// sequences and applying carriage returns and backspaces
+func terminalLines(data []byte) []string {
+	var lines []string
+	var line []rune
+	pendingCR := false
+
+	text := string(data)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		if c == 0x1b {
+			i = skipEscape(text, i)
+			continue
+		}
+
+		// A carriage return followed by more text overwrites the line;
+		// pseudo-terminals turn \r\n into \r\r\n, so repeated CRs don't count
+		if pendingCR && c != '\r' {
+			pendingCR = false
+			if c != '\n' {
+				line = line[:0]
+			}
+		}
+
+		switch {
+		case c == '\n':
+			lines = append(lines, strings.TrimRight(string(line), " "))
+			line = line[:0]
+		case c == '\r':
+			pendingCR = true
+		case c == '\b' || c == 0x7f:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case c == '\t' || c >= 0x20:
+			r, size := utf8.DecodeRuneInString(text[i:])
+			line = append(line, r)
+			i += size - 1
+		}
+	}
+
+	if len(line) > 0 {
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	return lines
+}
+
+// skipEscape returns the index of the last byte of the escape sequence at i
+func skipEscape(text string, i int) int {
+	if i+1 >= len(text) {
+		return i
+	}
+
+	switch text[i+1] {
+	case '[':
+		// CSI runs until a final byte in 0x40-0x7e
+		for j := i + 2; j < len(text); j++ {
+			if text[j] >= 0x40 && text[j] <= 0x7e {
+				return j
+			}
+		}
+		return len(text) - 1
+	case ']':
+		// OSC runs until BEL or ESC \
+		for j := i + 2; j < len(text); j++ {
+			if text[j] == 0x07 {
+				return j
+			}
+			if text[j] == 0x1b && j+1 < len(text) && text[j+1] == '\\' {
+				return j + 1
+			}
+		}
+		return len(text) - 1
+	default:
+		return i + 1
+	}
+}
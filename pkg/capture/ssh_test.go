@@ -0,0 +1,63 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHHost(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"example.com"}, "example.com"},
+		{[]string{"alice@example.com"}, "example.com"},
+		{[]string{"-p", "2222", "alice@db1"}, "db1"},
+		{[]string{"-p2222", "-A", "db1", "uptime"}, "db1"},
+		{[]string{"-i", "~/.ssh/id", "-o", "StrictHostKeyChecking=no", "web"}, "web"},
+		{[]string{"ssh://bob@bastion:2200"}, "bastion"},
+		{[]string{"--", "host"}, "host"},
+		{[]string{"-v"}, ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SSHHost(tt.args), "args %v", tt.args)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(t, "ssh -p 22 host", shellJoin([]string{"ssh", "-p", "22", "host"}))
+	assert.Equal(t, `ssh host 'echo it'\''s'`, shellJoin([]string{"ssh", "host", "echo it's"}))
+	assert.Equal(t, "ssh ''", shellJoin([]string{"ssh", ""}))
+}
+
+func TestParseTypescript_Prompts(t *testing.T) {
+	typescript := "Welcome to Ubuntu\r\n" +
+		"\x1b[01;32malice@web1\x1b[00m:\x1b[01;34m~\x1b[00m$ ls -la\r\n" +
+		"total 0\r\r\n" +
+		"[alice@web1 src]$ git statsu\b\bus\r\n" +
+		"On branch main\r\n" +
+		"root@web1:/# systemctl restart nginx\r\n" +
+		"(venv) ~/app % python manage.py migrate\r\n" +
+		"alice@web1:~$ \r\n" +
+		"alice@web1:~$ exit\r\n" +
+		"logout\r\n"
+
+	commands := ParseTypescript([]byte(typescript))
+	assert.Equal(t, []string{
+		"ls -la",
+		"git status",
+		"systemctl restart nginx",
+		"python manage.py migrate",
+		"exit",
+	}, commands)
+}
+
+func TestParseTypescript_OSC133(t *testing.T) {
+	typescript := "\x1b]133;A\x07host$ \x1b]133;B\x07make deploy\r\n\x1b]133;C\x07ok\r\n\x1b]133;D;0\x07" +
+		"\x1b]133;A\x07host$ \x1b]133;B\x07exit\r\n\x1b]133;C\x07"
+
+	// The last command never finishes, so only the first one is reported
+	assert.Equal(t, []string{"make deploy"}, ParseTypescript([]byte(typescript)))
+}
@@ -0,0 +1,117 @@
+// Package clipboard puts text on the system clipboard. It runs the first
+// clipboard tool it finds (pbcopy, wl-copy, xclip or xsel) and otherwise
+// falls back to the OSC 52 escape sequence, which asks the terminal itself
+// to set the clipboard. Over SSH it goes straight to OSC 52, since a tool
+// would only reach the clipboard of the remote machine.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tool is a command that reads the clipboard contents from stdin
+type tool struct {
+	name string
+	args []string
+	env  string // Environment variable the tool needs, e.g. DISPLAY
+}
+
+// tools in order of preference
+var tools = []tool{
+	{name: "pbcopy"},
+	{name: "wl-copy", env: "WAYLAND_DISPLAY"},
+	{name: "xclip", args: []string{"-selection", "clipboard"}, env: "DISPLAY"},
+	{name: "xsel", args: []string{"--clipboard", "--input"}, env: "DISPLAY"},
+}
+
+// Replaced in tests
+var (
+	lookPath = exec.LookPath
+	run      = func(name string, args []string, stdin string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(stdin)
+		return cmd.Run()
+	}
+	openTTY = func() (io.WriteCloser, error) {
+		return os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	}
+)
+
+// MethodOSC52 is the method Copy reports when it used the terminal
+const MethodOSC52 = "OSC 52"
+
+// maxOSC52 is the longest encoded text sent through OSC 52. Terminals drop
+// longer sequences without a word, e.g. hterm stops at 100000 bytes.
+const maxOSC52 = 100000
+
+// Copy puts text on the clipboard and returns how: the name of the tool it
+// ran or MethodOSC52
+func Copy(text string) (string, error) {
+	if !remote() {
+		if t, path, ok := findTool(); ok {
+			if err := run(path, t.args, text); err != nil {
+				return "", fmt.Errorf("%s failed: %w", t.name, err)
+			}
+			return t.name, nil
+		}
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(encoded) > maxOSC52 {
+		return "", fmt.Errorf("text too long for OSC 52 (%d bytes encoded, limit %d)", len(encoded), maxOSC52)
+	}
+
+	tty, err := openTTY()
+	if err != nil {
+		return "", fmt.Errorf("no clipboard tool found and no terminal for OSC 52: %w", err)
+	}
+	defer func() {
+		_ = tty.Close()
+	}()
+
+	if _, err := io.WriteString(tty, osc52(encoded)); err != nil {
+		return "", fmt.Errorf("failed to write OSC 52: %w", err)
+	}
+	return MethodOSC52, nil
+}
+
+// findTool returns the first available clipboard tool and its path
+func findTool() (tool, string, bool) {
+	for _, t := range tools {
+		if t.env != "" && os.Getenv(t.env) == "" {
+			continue
+		}
+		path, err := lookPath(t.name)
+		if err != nil {
+			continue
+		}
+		return t, path, true
+	}
+	return tool{}, "", false
+}
+
+// remote reports whether fh runs in an SSH session
+func remote() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// osc52 returns the escape sequence that sets the clipboard to the base64
+// encoded text. Inside tmux or screen the sequence is wrapped so the
+// multiplexer passes it on to the outer terminal instead of swallowing it;
+// tmux 3.3 and later also need "set -g allow-passthrough on".
+func osc52(encoded string) string {
+	seq := "\x1b]52;c;" + encoded + "\a"
+	switch {
+	case os.Getenv("TMUX") != "":
+		// Escapes inside the passthrough are doubled
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
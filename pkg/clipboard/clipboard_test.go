@@ -0,0 +1,166 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTTY struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *fakeTTY) Close() error {
+	f.closed = true
+	return nil
+}
+
+// stub replaces the tool lookup, runner and terminal for one test
+func stub(t *testing.T, available map[string]bool, runErr error) (*[]string, *fakeTTY) {
+	t.Helper()
+	for _, name := range []string{"SSH_TTY", "SSH_CONNECTION", "TMUX", "TERM"} {
+		t.Setenv(name, "")
+	}
+	origLookPath, origRun, origOpenTTY := lookPath, run, openTTY
+	t.Cleanup(func() {
+		lookPath, run, openTTY = origLookPath, origRun, origOpenTTY
+	})
+
+	var ran []string
+	tty := &fakeTTY{}
+	lookPath = func(name string) (string, error) {
+		if available[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	run = func(name string, args []string, stdin string) error {
+		ran = append(ran, name+" <- "+stdin)
+		return runErr
+	}
+	openTTY = func() (io.WriteCloser, error) {
+		return tty, nil
+	}
+	return &ran, tty
+}
+
+func TestCopy_Tools(t *testing.T) {
+	t.Run("pbcopy first", func(t *testing.T) {
+		t.Setenv("DISPLAY", ":0")
+		ran, _ := stub(t, map[string]bool{"pbcopy": true, "xclip": true}, nil)
+
+		method, err := Copy("ls -la")
+		require.NoError(t, err)
+		assert.Equal(t, "pbcopy", method)
+		assert.Equal(t, []string{"/usr/bin/pbcopy <- ls -la"}, *ran)
+	})
+
+	t.Run("x11 tools need DISPLAY", func(t *testing.T) {
+		t.Setenv("DISPLAY", "")
+		t.Setenv("WAYLAND_DISPLAY", "")
+		ran, tty := stub(t, map[string]bool{"xclip": true, "xsel": true}, nil)
+
+		method, err := Copy("ls")
+		require.NoError(t, err)
+		assert.Equal(t, MethodOSC52, method)
+		assert.Empty(t, *ran)
+		assert.NotEmpty(t, tty.String())
+	})
+
+	t.Run("wayland", func(t *testing.T) {
+		t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+		t.Setenv("DISPLAY", ":0")
+		_, _ = stub(t, map[string]bool{"wl-copy": true, "xclip": true}, nil)
+
+		method, err := Copy("ls")
+		require.NoError(t, err)
+		assert.Equal(t, "wl-copy", method)
+	})
+
+	t.Run("tool failure is reported", func(t *testing.T) {
+		_, _ = stub(t, map[string]bool{"pbcopy": true}, errors.New("exit status 1"))
+
+		_, err := Copy("ls")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pbcopy failed")
+	})
+}
+
+func TestCopy_OSC52(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	_, tty := stub(t, nil, nil)
+
+	method, err := Copy("echo hi")
+	require.NoError(t, err)
+	assert.Equal(t, MethodOSC52, method)
+	assert.Equal(t, "\x1b]52;c;ZWNobyBoaQ==\a", tty.String())
+	assert.True(t, tty.closed)
+}
+
+func TestCopy_SSH(t *testing.T) {
+	// A tool on the server would only set the server's clipboard
+	ran, tty := stub(t, map[string]bool{"pbcopy": true}, nil)
+	t.Setenv("SSH_TTY", "/dev/pts/3")
+
+	method, err := Copy("uptime")
+	require.NoError(t, err)
+	assert.Equal(t, MethodOSC52, method)
+	assert.Empty(t, *ran)
+	assert.Equal(t, "\x1b]52;c;dXB0aW1l\a", tty.String())
+}
+
+func TestCopy_Multiplexers(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"tmux", map[string]string{"TMUX": "/tmp/tmux-0/default,1,0", "TERM": "screen-256color"}, "\x1bPtmux;\x1b\x1b]52;c;dXB0aW1l\a\x1b\\"},
+		{"screen", map[string]string{"TERM": "screen.xterm-256color"}, "\x1bP\x1b]52;c;dXB0aW1l\a\x1b\\"},
+		{"plain terminal", map[string]string{"TERM": "xterm-256color"}, "\x1b]52;c;dXB0aW1l\a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, tty := stub(t, nil, nil)
+			t.Setenv("SSH_CONNECTION", "10.0.0.1 52000 10.0.0.2 22")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Copy("uptime")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tty.String())
+		})
+	}
+}
+
+func TestCopy_TooLongForOSC52(t *testing.T) {
+	_, tty := stub(t, nil, nil)
+	t.Setenv("SSH_TTY", "/dev/pts/3")
+
+	_, err := Copy(strings.Repeat("x", maxOSC52))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too long")
+	assert.Empty(t, tty.String())
+}
+
+func TestCopy_NoTerminal(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	_, _ = stub(t, nil, nil)
+	openTTY = func() (io.WriteCloser, error) {
+		return nil, errors.New("no such device")
+	}
+
+	_, err := Copy("ls")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no clipboard tool found")
+}
@@ -4,7 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/search"
 	"github.com/spideyz0r/fh/pkg/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -263,6 +266,7 @@ func TestGetDedupConfig(t *testing.T) {
 }
 
 func TestGetDatabasePath(t *testing.T) {
+	t.Setenv(DatabasePathEnv, "")
 	cfg := &Config{
 		Database: DatabaseConfig{Path: "/custom/db/path.db"},
 	}
@@ -270,6 +274,18 @@ func TestGetDatabasePath(t *testing.T) {
 	assert.Equal(t, "/custom/db/path.db", cfg.GetDatabasePath())
 }
 
+func TestGetDatabasePath_EnvOverride(t *testing.T) {
+	cfg := &Config{
+		Database: DatabaseConfig{Path: "/custom/db/path.db"},
+	}
+
+	t.Setenv(DatabasePathEnv, "/env/db/path.db")
+	assert.Equal(t, "/env/db/path.db", cfg.GetDatabasePath())
+
+	t.Setenv(DatabasePathEnv, ":memory:")
+	assert.Equal(t, ":memory:", cfg.GetDatabasePath())
+}
+
 func TestLoadDefault(t *testing.T) {
 	// This test will use the actual home directory
 	// It should not fail even if config doesn't exist
@@ -396,3 +412,586 @@ func TestGetDedupConfig_AllStrategies(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_SearchRanking(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranking  string
+		halfLife float64
+		wantErr  bool
+	}{
+		{"default", "", 0, false},
+		{"recent", "recent", 0, false},
+		{"frecency with decay", "frecency", 30, false},
+		{"unknown ranking", "popular", 0, true},
+		{"negative half-life", "frecency", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Search.Ranking = tt.ranking
+			cfg.Search.HalfLife = tt.halfLife
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetSearchCase(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, query.CaseSmart, cfg.GetSearchCase())
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Search.Case = "sensitive"
+	assert.Equal(t, query.CaseSensitive, cfg.GetSearchCase())
+	assert.Equal(t, query.CaseSensitive, cfg.QueryOptions().Case)
+
+	cfg.Search.Case = "upper"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_SearchOutput(t *testing.T) {
+	for _, output := range []string{"", "stdout", "clipboard", "tmux-buffer", "tmux-pane"} {
+		cfg := Default()
+		cfg.Search.Output = output
+		assert.NoError(t, cfg.Validate(), output)
+	}
+
+	cfg := Default()
+	cfg.Search.Output = "printer"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_SearchUI(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, "builtin", cfg.Search.UI)
+
+	cfg.Search.UI = "fzf"
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, search.UIFzf, cfg.GetSearchUI())
+
+	cfg.Search.UI = "skim"
+	assert.ErrorContains(t, cfg.Validate(), "invalid search ui")
+}
+
+func TestValidate_SearchFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", map[string]string{"failed": "exit:!0", "work": "dir:~/work @failed"}, false},
+		{"bad query", map[string]string{"failed": "exit:maybe"}, true},
+		{"unknown filter", map[string]string{"work": "@missing"}, true},
+		{"loop", map[string]string{"a": "@b", "b": "@a"}, true},
+		{"bad name", map[string]string{"my filter": "exit:1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Search.Filters = tt.filters
+
+			err := cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetHalfLife(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, time.Duration(0), cfg.GetHalfLife())
+
+	cfg.Search.HalfLife = 1.5
+	assert.Equal(t, 36*time.Hour, cfg.GetHalfLife())
+}
+
+func TestGetStorageOptions(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, storage.DurabilityNormal, cfg.GetStorageOptions().Durability)
+
+	cfg.Storage.Durability = "full"
+	assert.Equal(t, storage.DurabilityFull, cfg.GetStorageOptions().Durability)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Storage.Durability = "always"
+	assert.ErrorContains(t, cfg.Validate(), "invalid durability: always")
+}
+
+func TestGetStorageOptions_CheckHosts(t *testing.T) {
+	cfg := Default()
+	assert.NotEmpty(t, cfg.GetStorageOptions().HostID)
+
+	cfg.Storage.CheckHosts = false
+	assert.Empty(t, cfg.GetStorageOptions().HostID)
+}
+
+func TestValidate_Sink(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, time.Minute, cfg.GetSinkInterval())
+
+	cfg.Sink.URL = "clickhouse://fh@localhost:8123/ops/shell_history"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Sink.BatchSize = 0
+	assert.ErrorContains(t, cfg.Validate(), "batch_size must be at least 1")
+
+	cfg.Sink.BatchSize = 500
+	cfg.Sink.URL = "kafka://localhost/fh"
+	assert.ErrorContains(t, cfg.Validate(), "unsupported sink")
+}
+
+func TestValidate_HealthcheckURL(t *testing.T) {
+	cfg := Default()
+	cfg.Hooks.HealthcheckURL = "https://hc-ping.com/key/fh-{job}"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Hooks.HealthcheckURL = "hc-ping.com/f1e2d3c4"
+	assert.ErrorContains(t, cfg.Validate(), "invalid healthcheck_url")
+}
+
+func TestValidate_AICaps(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 2000, cfg.AI.MaxRows)
+	assert.Equal(t, 50000, cfg.AI.MaxTotalTokens)
+
+	cfg.AI.MaxRows = 0
+	cfg.AI.MaxTotalTokens = 0
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AI.MaxRows = -1
+	assert.ErrorContains(t, cfg.Validate(), "max_rows cannot be negative")
+
+	cfg = Default()
+	assert.False(t, cfg.AI.IncludeCwdContext)
+	assert.Equal(t, 10, cfg.AI.ContextCommands)
+	cfg.AI.ContextCommands = -1
+	assert.ErrorContains(t, cfg.Validate(), "context_commands cannot be negative")
+}
+
+func TestValidate_AIResilience(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 30, cfg.AI.RequestTimeoutSecs)
+	assert.Equal(t, 2, cfg.AI.MaxRetries)
+	assert.Equal(t, 3, cfg.AI.BreakerThreshold)
+
+	cfg.AI.BreakerThreshold = 0
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AI.MaxRetries = -1
+	assert.ErrorContains(t, cfg.Validate(), "max_retries cannot be negative")
+}
+
+func TestModelPolicy(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, "gpt-4o-mini", cfg.GetSQLModel())
+	assert.Equal(t, "gpt-4o-mini", cfg.GetAnswerModel(100000))
+
+	cfg.AI.SQLModel = "gpt-4.1-nano"
+	cfg.AI.AnswerModel = "gpt-4.1-mini"
+	assert.Equal(t, "gpt-4.1-nano", cfg.GetSQLModel())
+	assert.Equal(t, "gpt-4.1-mini", cfg.GetAnswerModel(100000))
+
+	// Only large results get the better model
+	cfg.AI.ModelPolicy = "auto"
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, "gpt-4.1-mini", cfg.GetAnswerModel(7999))
+	assert.Equal(t, "gpt-4o", cfg.GetAnswerModel(8000))
+
+	cfg.AI.LargeAnswerModel = ""
+	assert.ErrorContains(t, cfg.Validate(), "large_answer_model cannot be empty")
+
+	cfg.AI.ModelPolicy = "cheapest"
+	assert.ErrorContains(t, cfg.Validate(), "invalid ai model_policy")
+}
+
+func TestValidate_AIBaseURL(t *testing.T) {
+	cfg := Default()
+	cfg.AI.BaseURL = "https://llm.internal.example.com/v1"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.AI.BaseURL = "llm.internal.example.com/v1"
+	assert.ErrorContains(t, cfg.Validate(), "invalid ai base_url")
+}
+
+func TestValidate_AIAzure(t *testing.T) {
+	cfg := Default()
+	cfg.AI.Provider = "azure"
+	assert.ErrorContains(t, cfg.Validate(), "invalid ai azure.endpoint")
+
+	cfg.AI.Azure.Endpoint = "https://fh.openai.azure.com"
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "2024-10-21", cfg.AI.Azure.APIVersion)
+
+	cfg.AI.Azure.APIVersion = ""
+	assert.ErrorContains(t, cfg.Validate(), "api_version cannot be empty")
+}
+
+func TestGetAICACert(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	cfg := Default()
+	assert.Equal(t, "", cfg.GetAICACert())
+
+	cfg.AI.CACert = "~/certs/corp.pem"
+	assert.Equal(t, filepath.Join(home, "certs", "corp.pem"), cfg.GetAICACert())
+}
+
+func TestLoad_AIRedactContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// On unless turned off, also in configs written before the setting
+	require.NoError(t, os.WriteFile(path, []byte("ai:\n  model: gpt-4o\n"), 0644))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.AI.RedactContext)
+
+	require.NoError(t, os.WriteFile(path, []byte("ai:\n  redact_context: false\n"), 0644))
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.False(t, cfg.AI.RedactContext)
+}
+
+func TestGetStorageOptions_AutoSnapshots(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 5, cfg.GetStorageOptions().AutoSnapshots)
+
+	cfg.Storage.AutoSnapshots = -1
+	assert.ErrorContains(t, cfg.Validate(), "auto_snapshots cannot be negative")
+}
+
+func TestGetStorageOptions_Signing(t *testing.T) {
+	cfg := Default()
+	assert.Nil(t, cfg.GetStorageOptions().SigningKey)
+
+	cfg.Signing.Enabled = true
+	cfg.Signing.KeyCommand = "echo 0123456789abcdef0123456789abcdef"
+	key, err := cfg.GetStorageOptions().SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), key)
+
+	cfg.Signing.KeyCommand = "echo short"
+	_, err = cfg.GetStorageOptions().SigningKey()
+	assert.ErrorContains(t, err, "shorter than 16 bytes")
+
+	cfg.Signing.KeyCommand = ""
+	assert.ErrorContains(t, cfg.Validate(), "key_command cannot be empty")
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte("search:\n  keybinding: ctrl-g\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "ctrl-g", cfg.Search.Keybinding)
+	assert.Equal(t, "keep_all", cfg.Storage.Deduplicate.Strategy) // Defaults kept
+
+	_, err = Parse([]byte("search:\n  ranking: random\n"))
+	assert.ErrorContains(t, err, "invalid configuration")
+}
+
+func TestRelocateHome(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Path = "/home/alice/.fh/history.db"
+	cfg.Profiles = ProfilesConfig{
+		"work":   "/home/alice/work/fh.db",
+		"shared": "/srv/fh/shared.db",
+		"tilde":  "~/.fh/tilde.db",
+	}
+
+	assert.True(t, cfg.RelocateHome("/home/alice", "/Users/alice"))
+	assert.Equal(t, "/Users/alice/.fh/history.db", cfg.Database.Path)
+	assert.Equal(t, "/Users/alice/work/fh.db", cfg.Profiles["work"])
+	assert.Equal(t, "/srv/fh/shared.db", cfg.Profiles["shared"])
+	assert.Equal(t, "~/.fh/tilde.db", cfg.Profiles["tilde"])
+
+	assert.False(t, cfg.RelocateHome("/home/alice", "/Users/alice"))
+	assert.False(t, cfg.RelocateHome("/Users/alice", "/Users/alice"))
+}
+
+func TestGetDebounce(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 2*time.Second, cfg.GetDebounce())
+
+	cfg.Storage.DebounceSecs = 0
+	assert.Equal(t, time.Duration(0), cfg.GetDebounce())
+
+	cfg.Storage.DebounceSecs = -1
+	assert.ErrorContains(t, cfg.Validate(), "debounce_secs cannot be negative")
+}
+
+func TestGetApproxWindow(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, 30*24*time.Hour, cfg.GetApproxWindow())
+
+	cfg.Import.ApproxWindowDays = -1
+	assert.ErrorContains(t, cfg.Validate(), "approx_window_days cannot be negative")
+}
+
+func TestGetRemindIdle(t *testing.T) {
+	cfg := Default()
+	assert.False(t, cfg.Remind.Daily)
+	assert.Equal(t, 90*24*time.Hour, cfg.GetRemindIdle())
+
+	cfg.Remind.MinRuns = -1
+	assert.ErrorContains(t, cfg.Validate(), "min_runs cannot be negative")
+}
+
+func TestValidate_IgnorePrograms(t *testing.T) {
+	cfg := Default()
+	cfg.Ignore.Deny = []string{"psql"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Ignore.Allow = []string{"/usr/bin/git"}
+	assert.ErrorContains(t, cfg.Validate(), "invalid ignore program")
+}
+
+func TestValidate_IgnorePatterns(t *testing.T) {
+	cfg := Default()
+	cfg.Ignore.Patterns = []string{"^ls$", "[invalid"}
+	assert.ErrorContains(t, cfg.Validate(), `invalid ignore pattern "[invalid"`)
+}
+
+func TestValidate_RedactPatterns(t *testing.T) {
+	cfg := Default()
+	cfg.Redact.Patterns = []string{`corp_[a-z0-9]{8}`}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, cfg.Redact.Patterns, cfg.GetStorageOptions().RedactPatterns)
+
+	cfg.Redact.Patterns = []string{"(unclosed"}
+	assert.ErrorContains(t, cfg.Validate(), `invalid redact pattern "(unclosed"`)
+}
+
+func TestGetAnswerLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	cfg := Default()
+	assert.Equal(t, "auto", cfg.AI.AnswerLanguage)
+
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	assert.Equal(t, "fr", cfg.GetAnswerLanguage())
+	t.Setenv("LANG", "en_US.UTF-8")
+	assert.Empty(t, cfg.GetAnswerLanguage(), "English is left to the model")
+	t.Setenv("LANG", "C")
+	assert.Empty(t, cfg.GetAnswerLanguage())
+
+	cfg.AI.AnswerLanguage = "Japanese"
+	assert.Equal(t, "Japanese", cfg.GetAnswerLanguage())
+}
+
+func TestParse_ProviderModels(t *testing.T) {
+	cfg, err := Parse([]byte("ai:\n  provider: anthropic\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "claude-3-5-haiku-latest", cfg.GetSQLModel())
+	assert.Equal(t, "claude-sonnet-4-0", cfg.AI.LargeAnswerModel)
+
+	cfg, err = Parse([]byte("ai:\n  provider: gemini\n  model: gemini-2.5-flash\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-flash", cfg.AI.Model, "a set model wins")
+	assert.Equal(t, "gemini-2.5-pro", cfg.AI.LargeAnswerModel)
+
+	_, err = Parse([]byte("ai:\n  provider: watson\n"))
+	assert.ErrorContains(t, err, "invalid ai provider: watson")
+}
+
+func TestValidate_Retention(t *testing.T) {
+	cfg := Default()
+	assert.False(t, cfg.GetRetention().Enabled())
+
+	cfg.Retention = RetentionConfig{MaxAgeDays: 365, MaxEntries: 1000, PruneFailedAfterDays: 30}
+	assert.NoError(t, cfg.Validate())
+	retention := cfg.GetRetention()
+	assert.Equal(t, 365*24*time.Hour, retention.MaxAge)
+	assert.Equal(t, int64(1000), retention.MaxEntries)
+	assert.Equal(t, 30*24*time.Hour, retention.FailedMaxAge)
+
+	cfg.Retention.MaxEntries = -1
+	assert.ErrorContains(t, cfg.Validate(), "retention limits cannot be negative")
+}
+
+func TestActiveProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(ProfileEnv, "")
+
+	assert.Equal(t, storage.DefaultProfile, ActiveProfile())
+
+	require.NoError(t, SetActiveProfile("work"))
+	assert.Equal(t, "work", ActiveProfile())
+
+	// The environment wins over the persisted selection
+	t.Setenv(ProfileEnv, "personal")
+	assert.Equal(t, "personal", ActiveProfile())
+}
+
+func TestSetActiveProfile_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, name := range []string{"", "-work", "my profile", "a/b"} {
+		assert.Error(t, SetActiveProfile(name), "profile %q", name)
+	}
+}
+
+func TestGetProfileDatabasePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(DatabasePathEnv, "")
+	t.Setenv(ProfileEnv, "")
+
+	cfg := Default()
+	cfg.Database.Path = "/data/history.db"
+	cfg.Profiles = ProfilesConfig{
+		"work":     "/data/work.db",
+		"personal": "~/personal.db",
+	}
+
+	assert.Equal(t, "/data/work.db", cfg.GetProfileDatabasePath("work"))
+	assert.Equal(t, filepath.Join(home, "personal.db"), cfg.GetProfileDatabasePath("personal"))
+	assert.Equal(t, "/data/history.db", cfg.GetProfileDatabasePath("oncall"), "unlisted profiles share the main database")
+
+	// GetDatabasePath follows the active profile
+	assert.Equal(t, "/data/history.db", cfg.GetDatabasePath())
+	t.Setenv(ProfileEnv, "work")
+	assert.Equal(t, "/data/work.db", cfg.GetDatabasePath())
+
+	// FH_DB_PATH still wins
+	t.Setenv(DatabasePathEnv, storage.MemoryPath)
+	assert.Equal(t, storage.MemoryPath, cfg.GetProfileDatabasePath("work"))
+}
+
+func TestValidate_Profiles(t *testing.T) {
+	cfg := Default()
+	cfg.Profiles = ProfilesConfig{"work": "/data/work.db"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Profiles = ProfilesConfig{"work": ""}
+	assert.Error(t, cfg.Validate())
+
+	cfg.Profiles = ProfilesConfig{"bad name": "/data/x.db"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoad_Profiles(t *testing.T) {
+	ClearCache()
+	defer ClearCache()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := "database:\n  path: /data/history.db\nprofiles:\n  work: /data/work.db\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(data), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/data/work.db", cfg.Profiles["work"])
+}
+
+func TestOffline(t *testing.T) {
+	cfg := Default()
+	t.Setenv(OfflineEnv, "")
+	assert.False(t, cfg.Offline())
+
+	cfg.Privacy.Offline = true
+	assert.True(t, cfg.Offline())
+
+	cfg.Privacy.Offline = false
+	for value, offline := range map[string]bool{"1": true, "true": true, "yes": true, "0": false, "FALSE": false} {
+		t.Setenv(OfflineEnv, value)
+		assert.Equal(t, offline, cfg.Offline(), value)
+	}
+}
+
+func TestAIOffline(t *testing.T) {
+	t.Setenv(OfflineEnv, "")
+	cfg := Default()
+	assert.False(t, cfg.AIOffline())
+
+	cfg.Privacy.Offline = true
+	assert.True(t, cfg.AIOffline())
+
+	// A local Ollama needs no network
+	cfg.AI.Provider = "ollama"
+	for baseURL, offline := range map[string]bool{
+		"":                          false,
+		"http://localhost:11434":    false,
+		"http://127.0.0.1:11434":    false,
+		"http://[::1]:11434":        false,
+		"http://gpu-box.lan:11434":  true,
+		"http://192.168.1.20:11434": true,
+	} {
+		cfg.AI.BaseURL = baseURL
+		assert.Equal(t, offline, cfg.AIOffline(), baseURL)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	cfg, err := Parse([]byte(`paths:
+  wsl: on
+  rewrite:
+    - from: /mnt/c/Users/me
+      to: /home/me/win
+  display:
+    - from: ~/src/fh
+      to: "fh:"
+    - from: /home/me/win
+      to: "win~"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "/home/me/win/src", cfg.PathNormalizer().Normalize(`C:\Users\me\src`))
+
+	display := cfg.DisplayPath()
+	assert.Equal(t, "win~/src", display(`C:\Users\me\src`), "stored windows paths are normalized first")
+	assert.Equal(t, "fh:/cmd", display(filepath.Join(home, "src/fh/cmd")))
+	assert.Equal(t, "/srv", display("/srv"))
+
+	assert.Equal(t, "auto", Default().Paths.WSL)
+	_, err = Parse([]byte("paths:\n  wsl: maybe\n"))
+	assert.ErrorContains(t, err, "invalid paths wsl")
+	_, err = Parse([]byte("paths:\n  display:\n    - from: /\n      to: root\n"))
+	assert.ErrorContains(t, err, "path rule from cannot be empty")
+}
+
+func TestValidate_CwdKeybinding(t *testing.T) {
+	cfg := Default()
+	cfg.Search.CwdKeybinding = "ctrl-e"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Search.CwdKeybinding = "Ctrl-R"
+	assert.ErrorContains(t, cfg.Validate(), "cannot be the same as search.keybinding")
+}
+
+func TestPaths_Abbreviations(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	cfg, err := Parse([]byte(`paths:
+  aliases:
+    infra: ~/src/infrastructure
+`))
+	require.NoError(t, err)
+
+	display, exported, expand := cfg.DisplayPath(), cfg.ExportPath(), cfg.ExpandPath()
+	assert.Equal(t, "~/work/serviceA", display("/home/me/work/serviceA"))
+	assert.Equal(t, "~infra/modules", display("/home/me/src/infrastructure/modules"))
+	assert.Equal(t, "~infra", exported("/home/me/src/infrastructure"))
+	assert.Equal(t, "/home/me/src/infrastructure/modules", expand("~infra/modules"))
+	assert.Equal(t, "/home/me/work", expand("~/work"))
+	assert.Equal(t, "/srv", expand("/srv"))
+
+	cfg.Paths.AbbreviateHome = false
+	assert.Equal(t, "/home/me/work", cfg.DisplayPath()("/home/me/work"))
+	assert.Equal(t, "~infra", cfg.DisplayPath()("/home/me/src/infrastructure"))
+
+	cfg.Paths.Aliases = map[string]string{"my infra": "/srv"}
+	assert.ErrorContains(t, cfg.Validate(), "invalid path alias name")
+	cfg.Paths.Aliases = map[string]string{"root": "/"}
+	assert.ErrorContains(t, cfg.Validate(), "cannot be empty or /")
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setup is a declarative fh setup, meant to be checked into dotfiles and
+// applied with fh --apply. Fields left out keep their live value.
+type Setup struct {
+	Keybinding     *string   `yaml:"keybinding"`      // Search keybinding, e.g. ctrl-r
+	CwdKeybinding  *string   `yaml:"cwd_keybinding"`  // Keybinding of the search of the current directory, "" for none
+	IgnorePatterns *[]string `yaml:"ignore_patterns"` // Replaces the configured ignore patterns
+	AI             SetupAI   `yaml:"ai"`
+	Shells         []string  `yaml:"shells"` // Shells to install hooks for (bash, zsh, fish)
+}
+
+// SetupAI holds the AI settings of a Setup
+type SetupAI struct {
+	Enabled  *bool   `yaml:"enabled"`
+	Provider *string `yaml:"provider"`
+	Model    *string `yaml:"model"`
+}
+
+// SetupChange is a config value changed by applying a Setup
+type SetupChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// LoadSetup reads a setup file
+func LoadSetup(path string) (*Setup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read setup file: %w", err)
+	}
+
+	setup := &Setup{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true) // A typo should fail, not be skipped silently
+	if err := decoder.Decode(setup); err != nil {
+		return nil, fmt.Errorf("failed to parse setup file: %w", err)
+	}
+
+	for _, shell := range setup.Shells {
+		if shell != "bash" && shell != "zsh" && shell != "fish" {
+			return nil, fmt.Errorf("unsupported shell in setup file: %s (must be bash, zsh or fish)", shell)
+		}
+	}
+
+	return setup, nil
+}
+
+// Apply changes cfg to match the setup and returns what changed, in a fixed
+// order. The result is validated, cfg is left as it was on error.
+func (s *Setup) Apply(cfg *Config) ([]SetupChange, error) {
+	updated := *cfg
+	updated.Ignore.Patterns = append([]string(nil), cfg.Ignore.Patterns...)
+
+	var changes []SetupChange
+	set := func(field string, dst interface{}, value interface{}) {
+		current := reflect.ValueOf(dst).Elem()
+		if reflect.DeepEqual(current.Interface(), value) {
+			return
+		}
+		changes = append(changes, SetupChange{
+			Field: field,
+			Old:   formatSetupValue(current.Interface()),
+			New:   formatSetupValue(value),
+		})
+		current.Set(reflect.ValueOf(value))
+	}
+
+	if s.Keybinding != nil {
+		set("search.keybinding", &updated.Search.Keybinding, *s.Keybinding)
+	}
+	if s.CwdKeybinding != nil {
+		set("search.cwd_keybinding", &updated.Search.CwdKeybinding, *s.CwdKeybinding)
+	}
+	if s.IgnorePatterns != nil {
+		set("ignore.patterns", &updated.Ignore.Patterns, *s.IgnorePatterns)
+	}
+	if s.AI.Enabled != nil {
+		set("ai.enabled", &updated.AI.Enabled, *s.AI.Enabled)
+	}
+	if s.AI.Provider != nil {
+		// A model left at the old provider's default moves to the new one's
+		old, oldOK := providerModels[updated.AI.Provider]
+		set("ai.provider", &updated.AI.Provider, *s.AI.Provider)
+		if models, ok := providerModels[updated.AI.Provider]; ok && oldOK && s.AI.Model == nil && updated.AI.Model == old.Model {
+			set("ai.model", &updated.AI.Model, models.Model)
+		}
+	}
+	if s.AI.Model != nil {
+		set("ai.model", &updated.AI.Model, *s.AI.Model)
+	}
+
+	if err := updated.Validate(); err != nil {
+		return nil, err
+	}
+
+	*cfg = updated
+	return changes, nil
+}
+
+// formatSetupValue formats a config value for display
+func formatSetupValue(value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return "[" + strings.Join(list, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSetup(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fh.setup.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadSetup(t *testing.T) {
+	path := writeSetup(t, `
+keybinding: ctrl-g
+ignore_patterns:
+  - ^ls
+ai:
+  model: gpt-4o
+shells: [bash, zsh]
+`)
+
+	setup, err := LoadSetup(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ctrl-g", *setup.Keybinding)
+	assert.Equal(t, []string{"^ls"}, *setup.IgnorePatterns)
+	assert.Equal(t, "gpt-4o", *setup.AI.Model)
+	assert.Nil(t, setup.AI.Provider)
+	assert.Equal(t, []string{"bash", "zsh"}, setup.Shells)
+}
+
+func TestLoadSetup_Invalid(t *testing.T) {
+	_, err := LoadSetup(writeSetup(t, "keybindng: ctrl-g\n"))
+	assert.ErrorContains(t, err, "failed to parse setup file")
+
+	_, err = LoadSetup(writeSetup(t, "shells: [ksh]\n"))
+	assert.ErrorContains(t, err, "unsupported shell in setup file: ksh")
+
+	_, err = LoadSetup(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read setup file")
+}
+
+func TestSetupApply(t *testing.T) {
+	setup, err := LoadSetup(writeSetup(t, `
+keybinding: ctrl-g
+ignore_patterns: [^ls$, ^cd]
+ai:
+  enabled: false
+  provider: openai
+`))
+	require.NoError(t, err)
+
+	cfg := Default()
+	changes, err := setup.Apply(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []SetupChange{
+		{Field: "search.keybinding", Old: "ctrl-r", New: "ctrl-g"},
+		{Field: "ignore.patterns", Old: "[^ls$, ^ls , ^cd$, ^cd , ^pwd$, ^exit$, ^clear$]", New: "[^ls$, ^cd]"},
+		{Field: "ai.enabled", Old: "true", New: "false"},
+	}, changes)
+	assert.Equal(t, "ctrl-g", cfg.Search.Keybinding)
+	assert.Equal(t, []string{"^ls$", "^cd"}, cfg.Ignore.Patterns)
+	assert.False(t, cfg.AI.Enabled)
+	assert.Equal(t, "gpt-4o-mini", cfg.AI.Model) // Not in the setup, untouched
+
+	// Applying again is a no-op
+	changes, err = setup.Apply(cfg)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestSetupApply_ProviderModel(t *testing.T) {
+	setup, err := LoadSetup(writeSetup(t, "ai:\n  provider: gemini\n"))
+	require.NoError(t, err)
+
+	cfg := Default()
+	changes, err := setup.Apply(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []SetupChange{
+		{Field: "ai.provider", Old: "openai", New: "gemini"},
+		{Field: "ai.model", Old: "gpt-4o-mini", New: "gemini-2.0-flash"},
+	}, changes)
+
+	// A model of one's own is kept
+	cfg = Default()
+	cfg.AI.Model = "gpt-4.1"
+	_, err = setup.Apply(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4.1", cfg.AI.Model)
+}
+
+func TestSetupApply_InvalidLeavesConfig(t *testing.T) {
+	setup, err := LoadSetup(writeSetup(t, "ignore_patterns: ['[invalid']\n"))
+	require.NoError(t, err)
+
+	cfg := Default()
+	_, err = setup.Apply(cfg)
+	assert.ErrorContains(t, err, "invalid ignore pattern")
+	assert.Equal(t, Default().Ignore.Patterns, cfg.Ignore.Patterns)
+}
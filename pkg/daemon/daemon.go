@@ -0,0 +1,285 @@
+// Package daemon keeps the database open in a background process. Saves
+// hand their entry to it over a unix socket, or the shell hooks write them
+// to its FIFO, instead of opening and writing the database themselves, and
+// it writes the entries it is sent in batches.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// SocketName is the daemon's socket, next to the database it serves
+const SocketName = "fh.sock"
+
+// sendTimeout bounds a request to the daemon
+const sendTimeout = 2 * time.Second
+
+// enqueueTimeout is how long a save waits for room in a full queue before
+// the daemon turns it down, well within sendTimeout so the sender hears
+// about it and saves the entry itself
+const enqueueTimeout = sendTimeout / 4
+
+// flushTimeout bounds the save of the queued entries for a Flush
+const flushTimeout = 2 * time.Second
+
+// queueSize is how many entries can wait for their batch before senders
+// are made to wait
+const queueSize = 1024
+
+// ErrRunning is returned by Listen and OpenFIFO when a daemon already
+// serves the socket or reads the FIFO
+var ErrRunning = errors.New("fh daemon is already running")
+
+// ErrNoReply is returned by Send when the entry went out but no reply came
+// back. The daemon may have queued it, so the caller must not save it too.
+var ErrNoReply = errors.New("no reply from fh daemon")
+
+// SocketPath returns the socket of the daemon serving database
+func SocketPath(database string) string {
+	return filepath.Join(filepath.Dir(database), SocketName)
+}
+
+// request is a save handed to the daemon, or a flush
+type request struct {
+	Database string                `json:"database"` // The database the entry is for
+	Entry    *storage.HistoryEntry `json:"entry,omitempty"`
+	Flush    bool                  `json:"flush,omitempty"` // Save the queued entries now
+}
+
+// reply tells the sender whether its entry was queued, or saved for a flush
+type reply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Send hands entry to the daemon listening on socket, to be saved in
+// database. It fails when no daemon is running, it serves another database
+// or its queue is full, and the caller then saves the entry itself, unless
+// the error is ErrNoReply.
+func Send(socket, database string, entry *storage.HistoryEntry) error {
+	return roundTrip(socket, request{Database: database, Entry: entry}, sendTimeout)
+}
+
+// Flush has the daemon listening on socket save the entries it was sent
+// for database so far, for reads that need them, such as fh --prev. It
+// fails when no daemon is running or it serves another database.
+func Flush(socket, database string) error {
+	return roundTrip(socket, request{Database: database, Flush: true}, sendTimeout+flushTimeout)
+}
+
+// roundTrip sends req to the daemon listening on socket and waits for its
+// reply, all within timeout
+func roundTrip(socket string, req request, timeout time.Duration) error {
+	conn, err := net.DialTimeout("unix", socket, sendTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	var r reply
+	if err := json.NewDecoder(conn).Decode(&r); err != nil {
+		return fmt.Errorf("%w: %v", ErrNoReply, err)
+	}
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	return nil
+}
+
+// Listen listens on socket, where only the user can connect. A socket left
+// behind by a daemon that died is replaced.
+func Listen(socket string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", socket, time.Second); err == nil {
+		_ = conn.Close()
+		return nil, ErrRunning
+	}
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Server queues the entries sent to it and saves them in batches
+type Server struct {
+	Database string                                // Path of the database served, saves for another are refused
+	Save     func(entries []*storage.HistoryEntry) // Saves a batch, one batch at a time
+	Interval time.Duration                         // How long an entry waits for others to be saved with
+	MaxBatch int                                   // Entries saved right away, without waiting
+
+	// FIFO, when set, has saves of the shell hooks to queue too, with the
+	// entry Build makes of their fields. Build returns nil for a save it
+	// took care of itself.
+	FIFO  *FIFO
+	Build func(fields map[string]string) *storage.HistoryEntry
+}
+
+// Serve accepts saves on listener, and reads those of the FIFO, until ctx
+// is done, then saves what is still queued and closes the listener
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	queue := make(chan *storage.HistoryEntry, queueSize)
+	flushes := make(chan chan struct{})
+	saved := make(chan struct{})
+	go s.saveBatches(queue, flushes, saved)
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	var handlers sync.WaitGroup
+	if s.FIFO != nil {
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			s.FIFO.records(func(fields map[string]string) {
+				if entry := s.Build(fields); entry != nil {
+					queue <- entry
+				}
+			})
+		}()
+	}
+
+	var err error
+	for {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			if ctx.Err() == nil {
+				err = fmt.Errorf("failed to accept: %w", acceptErr)
+				_ = listener.Close()
+			}
+			break
+		}
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			s.handle(conn, queue, flushes)
+		}()
+	}
+
+	// Entries already acknowledged, or written to the FIFO, are saved
+	// before returning
+	if s.FIFO != nil {
+		s.FIFO.stop()
+	}
+	handlers.Wait()
+	close(queue)
+	<-saved
+	return err
+}
+
+// handle queues the entry sent on conn and acknowledges it, or replies to a
+// flush once the queued entries are saved
+func (s *Server) handle(conn net.Conn, queue chan<- *storage.HistoryEntry, flushes chan<- chan struct{}) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	var req request
+	var r reply
+	switch err := json.NewDecoder(conn).Decode(&req); {
+	case err != nil:
+		r.Error = fmt.Sprintf("invalid request: %v", err)
+	case req.Database != s.Database:
+		r.Error = fmt.Sprintf("daemon saves to %s, not %s", s.Database, req.Database)
+	case req.Flush:
+		_ = conn.SetDeadline(time.Now().Add(sendTimeout + flushTimeout))
+		if !flush(flushes) {
+			r.Error = "timed out saving the queued entries"
+		}
+	case req.Entry == nil:
+		r.Error = "invalid request: no entry"
+	default:
+		// A full queue turns the entry down while the sender still waits
+		// for the reply, so it is saved once
+		select {
+		case queue <- req.Entry:
+		case <-time.After(enqueueTimeout):
+			r.Error = "too many entries queued"
+		}
+	}
+	_ = json.NewEncoder(conn).Encode(r)
+}
+
+// flush has saveBatches save the queued entries, false when that takes
+// longer than flushTimeout
+func flush(flushes chan<- chan struct{}) bool {
+	timeout := time.After(flushTimeout)
+	done := make(chan struct{})
+	select {
+	case flushes <- done:
+	case <-timeout:
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// saveBatches saves the queued entries every Interval, as soon as MaxBatch
+// are waiting, or when a flush asks for it, until queue is closed
+func (s *Server) saveBatches(queue <-chan *storage.HistoryEntry, flushes <-chan chan struct{}, saved chan<- struct{}) {
+	defer close(saved)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	var batch []*storage.HistoryEntry
+	save := func() {
+		if len(batch) > 0 {
+			s.Save(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case entry, ok := <-queue:
+			if !ok {
+				save()
+				return
+			}
+			batch = append(batch, entry)
+			if s.MaxBatch > 0 && len(batch) >= s.MaxBatch {
+				save()
+			}
+		case done := <-flushes:
+			// Entries queued before the flush was asked for are saved too
+			for len(queue) > 0 {
+				batch = append(batch, <-queue)
+			}
+			save()
+			close(done)
+		case <-ticker.C:
+			save()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketPath(t *testing.T) {
+	assert.Equal(t, "/home/alice/.fh/fh.sock", SocketPath("/home/alice/.fh/history.db"))
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	database := filepath.Join(dir, "history.db")
+	socket := SocketPath(database)
+
+	var mu sync.Mutex
+	var batches [][]string
+	server := &Server{
+		Database: database,
+		Interval: time.Hour, // Only full batches and shutdown save
+		MaxBatch: 2,
+		Save: func(entries []*storage.HistoryEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			var commands []string
+			for _, entry := range entries {
+				commands = append(commands, entry.Command)
+			}
+			batches = append(batches, commands)
+		},
+	}
+
+	listener, err := Listen(socket)
+	require.NoError(t, err)
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	_, err = Listen(socket)
+	assert.ErrorIs(t, err, ErrRunning)
+
+	for _, command := range []string{"ls", "make", "git status"} {
+		require.NoError(t, Send(socket, database, &storage.HistoryEntry{Command: command}))
+	}
+	assert.ErrorContains(t, Send(socket, filepath.Join(dir, "work.db"), &storage.HistoryEntry{Command: "ls"}), "daemon saves to")
+
+	// What is still queued is saved on the way out
+	cancel()
+	require.NoError(t, <-served)
+	assert.Equal(t, [][]string{{"ls", "make"}, {"git status"}}, batches)
+	assert.NoFileExists(t, socket)
+
+	assert.Error(t, Send(socket, database, &storage.HistoryEntry{Command: "ls"}), "no daemon running")
+}
+
+func TestFlush(t *testing.T) {
+	dir := t.TempDir()
+	database := filepath.Join(dir, "history.db")
+	socket := SocketPath(database)
+
+	var mu sync.Mutex
+	var saved []string
+	server := &Server{
+		Database: database,
+		Interval: time.Hour,
+		Save: func(entries []*storage.HistoryEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, entry := range entries {
+				saved = append(saved, entry.Command)
+			}
+		},
+	}
+	listener, err := Listen(socket)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	require.NoError(t, Send(socket, database, &storage.HistoryEntry{Command: "ls"}))
+	require.NoError(t, Send(socket, database, &storage.HistoryEntry{Command: "make"}))
+	require.NoError(t, Flush(socket, database))
+	mu.Lock()
+	assert.Equal(t, []string{"ls", "make"}, saved)
+	mu.Unlock()
+	assert.ErrorContains(t, Flush(socket, filepath.Join(dir, "work.db")), "daemon saves to")
+
+	cancel()
+	require.NoError(t, <-served)
+	assert.Error(t, Flush(socket, database), "no daemon running")
+}
+
+func TestSend_NoReply(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), SocketName)
+	listener, err := Listen(socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// A daemon that reads the entry and goes away may still save it
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		var req request
+		_ = json.NewDecoder(conn).Decode(&req)
+		_ = conn.Close()
+	}()
+	assert.ErrorIs(t, Send(socket, "history.db", &storage.HistoryEntry{Command: "ls"}), ErrNoReply)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), SocketName)
+	require.NoError(t, os.WriteFile(socket, nil, 0600))
+
+	listener, err := Listen(socket)
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+}
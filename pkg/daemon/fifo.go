@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FIFOName is where the shell hooks write their saves while a daemon runs,
+// in fh's directory. Shells can't connect to a unix socket without starting
+// a program, but they can write to a FIFO.
+const FIFOName = "fh.fifo"
+
+// PIDName holds the pid of the daemon reading FIFOName, for the hooks to
+// check that it still runs before writing to it
+const PIDName = "fh.pid"
+
+// fifoDrain is how long a stopping daemon still reads the saves written
+// before it removed the FIFO
+const fifoDrain = 100 * time.Millisecond
+
+// FIFO is the FIFO the shell hooks write their saves to. A save is a record
+// of key=value fields, each ended by a NUL, and ended itself by an empty
+// field. The hooks write a record in one write, so records of different
+// shells don't mix.
+type FIFO struct {
+	dir  string
+	file *os.File
+}
+
+// OpenFIFO creates the FIFO in dir and records this process as the daemon
+// reading it. A FIFO left behind by a daemon that died is replaced.
+func OpenFIFO(dir string) (*FIFO, error) {
+	pidFile := filepath.Join(dir, PIDName)
+	if data, err := os.ReadFile(pidFile); err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && pid != os.Getpid() && processAlive(pid) {
+			return nil, ErrRunning
+		}
+	}
+
+	path := filepath.Join(dir, FIFOName)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale FIFO: %w", err)
+	}
+	if err := mkfifo(path, 0600); err != nil {
+		return nil, fmt.Errorf("failed to create FIFO: %w", err)
+	}
+
+	// Open for writing too: reads then wait for the next save instead of
+	// ending when a hook is done, and hooks opening it never block
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("failed to open FIFO: %w", err)
+	}
+
+	// Written last, so hooks seeing the pid find the FIFO read
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("failed to write pid file: %w", err)
+	}
+	return &FIFO{dir: dir, file: file}, nil
+}
+
+// records passes the fields of every save written to the FIFO to handle,
+// until it is closed or stopped
+func (f *FIFO) records(handle func(fields map[string]string)) {
+	reader := bufio.NewReader(f.file)
+	fields := map[string]string{}
+	for {
+		field, err := reader.ReadString(0)
+		if err != nil {
+			return
+		}
+
+		field = strings.TrimSuffix(field, "\x00")
+		if field == "" {
+			if len(fields) > 0 {
+				handle(fields)
+			}
+			fields = map[string]string{}
+			continue
+		}
+		key, value, _ := strings.Cut(field, "=")
+		fields[key] = value
+	}
+}
+
+// stop removes the FIFO, so the hooks save with fh again, and ends records
+// once the saves written before are read
+func (f *FIFO) stop() {
+	_ = os.Remove(filepath.Join(f.dir, PIDName))
+	_ = os.Remove(filepath.Join(f.dir, FIFOName))
+	if err := f.file.SetReadDeadline(time.Now().Add(fifoDrain)); err != nil {
+		_ = f.file.Close()
+	}
+}
+
+// Close removes the FIFO and closes it
+func (f *FIFO) Close() error {
+	f.stop()
+	return f.file.Close()
+}
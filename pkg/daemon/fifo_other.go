@@ -0,0 +1,15 @@
+//go:build !unix
+
+package daemon
+
+import "errors"
+
+// mkfifo fails, there are no FIFOs to create here
+func mkfifo(path string, mode uint32) error {
+	return errors.ErrUnsupported
+}
+
+// processAlive reports false, no daemon reads a FIFO here
+func processAlive(pid int) bool {
+	return false
+}
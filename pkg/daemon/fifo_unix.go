@@ -0,0 +1,17 @@
+//go:build unix
+
+package daemon
+
+import "syscall"
+
+// mkfifo creates a FIFO at path
+func mkfifo(path string, mode uint32) error {
+	return syscall.Mkfifo(path, mode)
+}
+
+// processAlive reports whether process pid runs, EPERM meaning it does but
+// belongs to someone else
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,95 @@
+//go:build unix
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe_FIFO(t *testing.T) {
+	dir := t.TempDir()
+	database := filepath.Join(dir, "history.db")
+
+	var mu sync.Mutex
+	var saved []*storage.HistoryEntry
+	server := &Server{
+		Database: database,
+		Interval: time.Hour,
+		Save: func(entries []*storage.HistoryEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			saved = append(saved, entries...)
+		},
+		Build: func(fields map[string]string) *storage.HistoryEntry {
+			if fields["cmd"] == "" {
+				return nil
+			}
+			return &storage.HistoryEntry{Command: fields["cmd"], Cwd: fields["cwd"]}
+		},
+	}
+
+	fifo, err := OpenFIFO(dir)
+	require.NoError(t, err)
+	defer fifo.Close()
+	server.FIFO = fifo
+	pid, err := os.ReadFile(filepath.Join(dir, PIDName))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(pid))
+
+	listener, err := Listen(SocketPath(database))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	// Written like the hooks do, one record at a time
+	hook, err := os.OpenFile(filepath.Join(dir, FIFOName), os.O_WRONLY, 0)
+	require.NoError(t, err)
+	for _, record := range []string{
+		"cmd=git commit -m a=b\x00cwd=/src\x00\x00",
+		"cwd=/src\x00\x00", // Build skips it
+		"cmd=make\x00cwd=/tmp\x00\x00",
+	} {
+		_, err := hook.WriteString(record)
+		require.NoError(t, err)
+	}
+	require.NoError(t, hook.Close())
+
+	// Saves still in the FIFO are read before the daemon stops
+	cancel()
+	require.NoError(t, <-served)
+	require.Len(t, saved, 2)
+	assert.Equal(t, "git commit -m a=b", saved[0].Command)
+	assert.Equal(t, "/src", saved[0].Cwd)
+	assert.Equal(t, "make", saved[1].Command)
+
+	// Hooks save with fh again
+	assert.NoFileExists(t, filepath.Join(dir, FIFOName))
+	assert.NoFileExists(t, filepath.Join(dir, PIDName))
+}
+
+func TestOpenFIFO_Running(t *testing.T) {
+	dir := t.TempDir()
+
+	// The parent process is alive
+	require.NoError(t, os.WriteFile(filepath.Join(dir, PIDName), []byte(strconv.Itoa(os.Getppid())), 0600))
+	_, err := OpenFIFO(dir)
+	assert.ErrorIs(t, err, ErrRunning)
+
+	// One that died left its FIFO behind
+	require.NoError(t, os.WriteFile(filepath.Join(dir, PIDName), []byte("999999999"), 0600))
+	require.NoError(t, mkfifo(filepath.Join(dir, FIFOName), 0600))
+	fifo, err := OpenFIFO(dir)
+	require.NoError(t, err)
+	require.NoError(t, fifo.Close())
+}
@@ -0,0 +1,424 @@
+// Package dashboard is the interactive stats dashboard behind fh --dashboard.
+// Each tab lists groups of history entries, and picking a group opens the
+// search picker on the entries behind it.
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/search"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// RelatedFunc returns the commands that usually run around entry, one entry
+// per command
+type RelatedFunc func(entry *storage.HistoryEntry) ([]*storage.HistoryEntry, error)
+
+// CopyFunc puts a command on the clipboard and returns how, e.g. "xclip"
+type CopyFunc func(command string) (string, error)
+
+// Options are the optional parts of the dashboard
+type Options struct {
+	Related RelatedFunc // r on a command opens the picker on its related commands
+	Copy    CopyFunc    // c on a command copies it to the clipboard
+	Case    query.Case  // How the picker treats letter case
+	UI      search.UI   // Which picker
+}
+
+// Run shows the dashboard until the user quits, which returns a nil entry,
+// or picks an entry through the drill-down picker. Cancelling the picker
+// goes back to the dashboard.
+func Run(d *stats.Dashboard, opts Options) (*storage.HistoryEntry, error) {
+	v := newView(d, opts.Related)
+	v.copy = opts.Copy
+	for {
+		screen, err := tcell.NewScreen()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open terminal: %w", err)
+		}
+		if err := screen.Init(); err != nil {
+			return nil, fmt.Errorf("failed to open terminal: %w", err)
+		}
+
+		entries := v.run(screen)
+		screen.Fini()
+		if entries == nil {
+			return nil, nil
+		}
+
+		selected, err := search.FzfSearch(entries, nil, opts.Case, opts.UI)
+		if errors.Is(err, search.ErrCancelled) {
+			continue
+		}
+		return selected, err
+	}
+}
+
+type tab int
+
+const (
+	tabCommands tab = iota
+	tabActivity
+	tabFailures
+	tabDirectories
+	tabCount
+)
+
+var tabNames = [tabCount]string{"Commands", "Activity", "Failures", "Directories"}
+
+// shades fill activity cells, from no commands to the busiest hour
+var shades = []rune{'·', '░', '▒', '▓', '█'}
+
+var weekdays = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+const (
+	headerLines = 3 // Tabs, summary and a blank line
+	footerLines = 1 // Key help
+)
+
+// view is the dashboard state between key presses
+type view struct {
+	d      *stats.Dashboard
+	tab    tab
+	cursor [tabCount]int // Selected row of each list tab
+	offset [tabCount]int // First visible row of each list tab
+	day    int           // Selected activity cell
+	hour   int
+	height int // Rows available to lists, set by draw
+
+	related RelatedFunc
+	copy    CopyFunc
+	status  string // Shown instead of the key help until the next key
+}
+
+func newView(d *stats.Dashboard, related RelatedFunc) *view {
+	v := &view{d: d, related: related}
+
+	// Start the heatmap on the busiest hour
+	busiest := 0
+	for day, hours := range d.Activity {
+		for hour, cell := range hours {
+			if len(cell) > busiest {
+				busiest, v.day, v.hour = len(cell), day, hour
+			}
+		}
+	}
+	return v
+}
+
+// run draws and handles keys until the user quits (nil) or picks a group
+// of entries to drill into
+func (v *view) run(s tcell.Screen) []*storage.HistoryEntry {
+	for {
+		v.draw(s)
+		s.Show()
+
+		switch ev := s.PollEvent().(type) {
+		case nil:
+			// Screen finalized
+			return nil
+		case *tcell.EventResize:
+			s.Sync()
+		case *tcell.EventKey:
+			v.status = ""
+			switch v.handleKey(ev) {
+			case actionQuit:
+				return nil
+			case actionDrill:
+				if entries := v.selected(); len(entries) > 0 {
+					return entries
+				}
+			case actionRelated:
+				if entries := v.relatedEntries(); len(entries) > 0 {
+					return entries
+				}
+			}
+		}
+	}
+}
+
+// action is what a key press asks run to do beyond updating the view
+type action int
+
+const (
+	actionNone action = iota
+	actionQuit
+	actionDrill   // Pick from the entries behind the selection
+	actionRelated // Pick from the commands related to the selection
+)
+
+// handleKey updates the view for a key press and returns what else it asks for
+func (v *view) handleKey(ev *tcell.EventKey) action {
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		return actionQuit
+	case tcell.KeyEnter:
+		return actionDrill
+	case tcell.KeyTab:
+		v.tab = (v.tab + 1) % tabCount
+	case tcell.KeyBacktab:
+		v.tab = (v.tab + tabCount - 1) % tabCount
+	case tcell.KeyUp:
+		v.move(0, -1)
+	case tcell.KeyDown:
+		v.move(0, 1)
+	case tcell.KeyLeft:
+		v.move(-1, 0)
+	case tcell.KeyRight:
+		v.move(1, 0)
+	case tcell.KeyPgUp:
+		v.move(0, -v.page())
+	case tcell.KeyPgDn:
+		v.move(0, v.page())
+	case tcell.KeyRune:
+		switch r := ev.Rune(); r {
+		case 'q':
+			return actionQuit
+		case 'r':
+			return actionRelated
+		case 'c':
+			v.copySelected()
+		case 'k':
+			v.move(0, -1)
+		case 'j':
+			v.move(0, 1)
+		case 'h':
+			v.move(-1, 0)
+		case 'l':
+			v.move(1, 0)
+		case '1', '2', '3', '4':
+			v.tab = tab(r - '1')
+		}
+	}
+	return actionNone
+}
+
+// move moves the selection, dx only applies to the activity heatmap
+func (v *view) move(dx, dy int) {
+	if v.tab == tabActivity {
+		v.hour = clamp(v.hour+dx, 0, 23)
+		v.day = clamp(v.day+dy, 0, 6)
+		return
+	}
+
+	rows := v.rows()
+	if len(rows) == 0 {
+		return
+	}
+	v.cursor[v.tab] = clamp(v.cursor[v.tab]+dy, 0, len(rows)-1)
+}
+
+func (v *view) page() int {
+	if v.height > 1 {
+		return v.height - 1
+	}
+	return 1
+}
+
+// rows returns the rows of the current list tab
+func (v *view) rows() []stats.Row {
+	switch v.tab {
+	case tabCommands:
+		return v.d.Commands
+	case tabFailures:
+		return v.d.Failures
+	case tabDirectories:
+		return v.d.Directories
+	}
+	return nil
+}
+
+// selected returns the entries behind the current selection
+func (v *view) selected() []*storage.HistoryEntry {
+	if v.tab == tabActivity {
+		return v.d.Activity[v.day][v.hour]
+	}
+
+	rows := v.rows()
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[v.cursor[v.tab]].Entries
+}
+
+// relatedEntries returns the commands related to the selected command, and
+// explains in the status line when there are none
+func (v *view) relatedEntries() []*storage.HistoryEntry {
+	if v.related == nil || (v.tab != tabCommands && v.tab != tabFailures) {
+		return nil
+	}
+
+	entries := v.selected()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	related, err := v.related(entries[0])
+	if err != nil {
+		v.status = "Error: " + err.Error()
+		return nil
+	}
+	if len(related) == 0 {
+		v.status = "No related commands"
+	}
+	return related
+}
+
+// copySelected copies the selected command to the clipboard and reports
+// the result in the status line
+func (v *view) copySelected() {
+	if v.copy == nil || (v.tab != tabCommands && v.tab != tabFailures) {
+		return
+	}
+
+	entries := v.selected()
+	if len(entries) == 0 {
+		return
+	}
+
+	method, err := v.copy(entries[0].Command)
+	if err != nil {
+		v.status = "Error: " + err.Error()
+		return
+	}
+	v.status = fmt.Sprintf("Copied with %s: %s", method, entries[0].Command)
+}
+
+func (v *view) draw(s tcell.Screen) {
+	s.Clear()
+	width, height := s.Size()
+	v.height = height - headerLines - footerLines
+
+	x := 0
+	for i, name := range tabNames {
+		style := tcell.StyleDefault
+		if tab(i) == v.tab {
+			style = style.Reverse(true)
+		}
+		x = drawText(s, x, 0, width, style, fmt.Sprintf(" %d %s ", i+1, name))
+		x = drawText(s, x, 0, width, tcell.StyleDefault, " ")
+	}
+
+	summary := fmt.Sprintf("%d commands, %d unique, %.1f%% success", v.d.Total, v.d.Unique, v.d.SuccessRate)
+	drawText(s, 0, 1, width, tcell.StyleDefault.Dim(true), summary)
+
+	if v.tab == tabActivity {
+		v.drawActivity(s, width)
+	} else {
+		v.drawList(s, width)
+	}
+
+	help := "tab/1-4 switch  ↑↓ move  enter show commands  "
+	switch {
+	case v.tab == tabActivity:
+		help = "tab/1-4 switch  ←↑↓→ move  enter show commands  "
+	case v.tab != tabDirectories:
+		if v.related != nil {
+			help += "r related  "
+		}
+		if v.copy != nil {
+			help += "c copy  "
+		}
+	}
+	help += "q quit"
+	if v.status != "" {
+		help = v.status
+	}
+	drawText(s, 0, height-1, width, tcell.StyleDefault.Dim(true), help)
+}
+
+func (v *view) drawList(s tcell.Screen, width int) {
+	rows := v.rows()
+	if len(rows) == 0 {
+		drawText(s, 0, headerLines, width, tcell.StyleDefault, "Nothing to show")
+		return
+	}
+
+	// Keep the cursor on screen
+	cursor, offset := v.cursor[v.tab], v.offset[v.tab]
+	if cursor < offset {
+		offset = cursor
+	}
+	if v.height > 0 && cursor >= offset+v.height {
+		offset = cursor - v.height + 1
+	}
+	v.offset[v.tab] = offset
+
+	for i := offset; i < len(rows) && i-offset < v.height; i++ {
+		row := rows[i]
+		percentage := float64(row.Count()) / float64(v.d.Total) * 100
+		line := fmt.Sprintf("%5d %5.1f%%  %s", row.Count(), percentage, row.Label)
+
+		style := tcell.StyleDefault
+		if i == cursor {
+			style = style.Reverse(true)
+			line += strings.Repeat(" ", max(0, width-runewidth.StringWidth(line)))
+		}
+		drawText(s, 0, headerLines+i-offset, width, style, line)
+	}
+}
+
+func (v *view) drawActivity(s tcell.Screen, width int) {
+	const labelWidth = 5 // "Sun  "
+	y := headerLines
+
+	for hour := 0; hour < 24; hour += 3 {
+		drawText(s, labelWidth+hour*2, y, width, tcell.StyleDefault.Dim(true), fmt.Sprintf("%02d", hour))
+	}
+
+	busiest := v.d.MaxActivity()
+	for day := 0; day < 7; day++ {
+		drawText(s, 0, y+1+day, width, tcell.StyleDefault, weekdays[day])
+		for hour := 0; hour < 24; hour++ {
+			shade := shades[0]
+			if count := len(v.d.Activity[day][hour]); count > 0 {
+				// Round up so only empty hours look empty
+				shade = shades[(count*(len(shades)-1)+busiest-1)/busiest]
+			}
+
+			style := tcell.StyleDefault
+			if day == v.day && hour == v.hour {
+				style = style.Reverse(true)
+			}
+			drawText(s, labelWidth+hour*2, y+1+day, width, style, string(shade))
+		}
+	}
+
+	count := len(v.d.Activity[v.day][v.hour])
+	detail := fmt.Sprintf("%s %02d:00-%02d:59  %d commands", weekdays[v.day], v.hour, v.hour, count)
+	if count > 0 {
+		latest := v.d.Activity[v.day][v.hour][0]
+		detail += ", latest " + time.Unix(latest.Timestamp, 0).Format("2006-01-02")
+	}
+	drawText(s, 0, y+9, width, tcell.StyleDefault, detail)
+}
+
+// drawText draws text from x, clipped at maxX, and returns where it ended
+func drawText(s tcell.Screen, x, y, maxX int, style tcell.Style, text string) int {
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if x+w > maxX {
+			break
+		}
+		s.SetContent(x, y, r, nil, style)
+		x += w
+	}
+	return x
+}
+
+func clamp(n, low, high int) int {
+	if n < low {
+		return low
+	}
+	if n > high {
+		return high
+	}
+	return n
+}
@@ -0,0 +1,192 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/spideyz0r/fh/pkg/stats"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScreen(t *testing.T) tcell.SimulationScreen {
+	t.Helper()
+	s := tcell.NewSimulationScreen("UTF-8")
+	require.NoError(t, s.Init())
+	s.SetSize(80, 20)
+	t.Cleanup(s.Fini)
+	return s
+}
+
+// screenLines returns the screen contents as text, one string per line
+func screenLines(s tcell.SimulationScreen) []string {
+	cells, width, height := s.GetContents()
+	lines := make([]string, height)
+	for y := 0; y < height; y++ {
+		var line strings.Builder
+		for x := 0; x < width; x++ {
+			runes := cells[y*width+x].Runes
+			if len(runes) == 0 {
+				runes = []rune{' '}
+			}
+			line.WriteString(string(runes))
+		}
+		lines[y] = strings.TrimRight(line.String(), " ")
+	}
+	return lines
+}
+
+func testDashboard() *stats.Dashboard {
+	// Tuesday 14:30 local time
+	base := time.Date(2024, 1, 16, 14, 30, 0, 0, time.Local).Unix()
+
+	return stats.NewDashboard([]*storage.HistoryEntry{
+		{Command: "make test", Cwd: "/src/fh", ExitCode: 2, Timestamp: base + 300},
+		{Command: "git status", Cwd: "/src/fh", Timestamp: base + 200},
+		{Command: "make test", Cwd: "/src/fh", Timestamp: base + 100},
+		{Command: "ls", Cwd: "/tmp", Timestamp: base - 24*3600},
+	})
+}
+
+func TestView_Draw(t *testing.T) {
+	s := newTestScreen(t)
+	v := newView(testDashboard(), nil)
+
+	v.draw(s)
+	s.Show()
+	lines := screenLines(s)
+
+	assert.Equal(t, " 1 Commands   2 Activity   3 Failures   4 Directories", lines[0])
+	assert.Equal(t, "4 commands, 3 unique, 75.0% success", lines[1])
+	assert.Equal(t, "    2  50.0%  make test", lines[3])
+	assert.Equal(t, "    1  25.0%  git status", lines[4])
+	assert.Equal(t, "    1  25.0%  ls", lines[5])
+	assert.Contains(t, lines[19], "q quit")
+}
+
+func TestView_DrawActivity(t *testing.T) {
+	s := newTestScreen(t)
+	v := newView(testDashboard(), nil)
+	v.tab = tabActivity
+
+	v.draw(s)
+	s.Show()
+	lines := screenLines(s)
+
+	// Starts on the busiest hour
+	assert.Equal(t, "Tue 14:00-14:59  3 commands, latest 2024-01-16", lines[12])
+	assert.True(t, strings.HasPrefix(lines[6], "Tue"), lines[6])
+	assert.Equal(t, '█', []rune(lines[6])[5+14*2])
+	assert.Equal(t, '▒', []rune(lines[5])[5+14*2]) // Monday, 1 of 3
+	assert.Equal(t, '·', []rune(lines[6])[5])
+}
+
+func TestView_Run(t *testing.T) {
+	d := testDashboard()
+
+	tests := []struct {
+		name  string
+		keys  []tcell.Key
+		runes string
+		want  []*storage.HistoryEntry
+	}{
+		{"quit", nil, "q", nil},
+		{"escape", []tcell.Key{tcell.KeyEscape}, "", nil},
+		{"top command", []tcell.Key{tcell.KeyEnter}, "", d.Commands[0].Entries},
+		{"second command", []tcell.Key{tcell.KeyDown, tcell.KeyEnter}, "", d.Commands[1].Entries},
+		{"failures tab", []tcell.Key{tcell.KeyTab, tcell.KeyTab, tcell.KeyEnter}, "", d.Failures[0].Entries},
+		{"activity cell", nil, "2k\r", d.Activity[time.Monday][14]},
+		{"directories by number", nil, "4j\r", d.Directories[1].Entries},
+		{"empty cell does nothing", nil, "2h\rq", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScreen(t)
+			for _, key := range tt.keys {
+				s.InjectKey(key, 0, tcell.ModNone)
+			}
+			require.True(t, s.InjectKeyBytes([]byte(tt.runes)))
+
+			assert.Equal(t, tt.want, newView(d, nil).run(s))
+		})
+	}
+}
+
+func TestView_Related(t *testing.T) {
+	d := testDashboard()
+	companion := &storage.HistoryEntry{Command: "go vet ./..."}
+
+	var asked *storage.HistoryEntry
+	related := func(entry *storage.HistoryEntry) ([]*storage.HistoryEntry, error) {
+		asked = entry
+		if entry.Command == "make test" {
+			return []*storage.HistoryEntry{companion}, nil
+		}
+		return nil, nil
+	}
+
+	s := newTestScreen(t)
+	require.True(t, s.InjectKeyBytes([]byte("r")))
+	assert.Equal(t, []*storage.HistoryEntry{companion}, newView(d, related).run(s))
+	assert.Equal(t, d.Commands[0].Entries[0], asked)
+
+	// Nothing related keeps the dashboard open with a note
+	s = newTestScreen(t)
+	v := newView(d, related)
+	v.move(0, 1)
+	assert.Nil(t, v.relatedEntries())
+	v.draw(s)
+	s.Show()
+	assert.Equal(t, "No related commands", screenLines(s)[19])
+}
+
+func TestView_Copy(t *testing.T) {
+	var copied []string
+	v := newView(testDashboard(), nil)
+	v.copy = func(command string) (string, error) {
+		copied = append(copied, command)
+		return "xclip", nil
+	}
+
+	s := newTestScreen(t)
+	v.draw(s)
+	s.Show()
+	assert.Equal(t, "tab/1-4 switch  ↑↓ move  enter show commands  c copy  q quit", screenLines(s)[19])
+
+	assert.Equal(t, actionNone, v.handleKey(tcell.NewEventKey(tcell.KeyRune, 'c', tcell.ModNone)))
+	assert.Equal(t, []string{"make test"}, copied)
+	v.draw(s)
+	s.Show()
+	assert.Equal(t, "Copied with xclip: make test", screenLines(s)[19])
+
+	// Directories and activity are not commands
+	v.tab = tabDirectories
+	v.handleKey(tcell.NewEventKey(tcell.KeyRune, 'c', tcell.ModNone))
+	v.tab = tabActivity
+	v.handleKey(tcell.NewEventKey(tcell.KeyRune, 'c', tcell.ModNone))
+	assert.Len(t, copied, 1)
+}
+
+func TestView_ScrollsToCursor(t *testing.T) {
+	entries := make([]*storage.HistoryEntry, 30)
+	for i := range entries {
+		entries[i] = &storage.HistoryEntry{Command: strings.Repeat("x", i+1), Timestamp: int64(1000 - i)}
+	}
+
+	s := newTestScreen(t)
+	v := newView(stats.NewDashboard(entries), nil)
+	v.draw(s)
+
+	// 16 rows fit between the header and the help line
+	v.move(0, 20)
+	v.draw(s)
+	s.Show()
+	lines := screenLines(s)
+
+	assert.Equal(t, 5, v.offset[tabCommands])
+	assert.True(t, strings.HasSuffix(lines[18], strings.Repeat("x", 21)), lines[18])
+}
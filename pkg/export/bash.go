@@ -0,0 +1,44 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/importer"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// exportBash exports entries oldest first as a bash history file with
+// timestamps, #<timestamp> and command line pairs bash reads back with
+// history -r when HISTTIMEFORMAT is set
+func exportBash(entries []*storage.HistoryEntry, writer io.Writer) error {
+	// Query returns the most recent first, history files end with it
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		lines := importer.FormatBashLines(entry.Timestamp, entry.Command)
+		if _, err := io.WriteString(writer, lines+"\n"); err != nil {
+			return fmt.Errorf("failed to write entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// importBash imports from a bash history file, with timestamps or not
+func importBash(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
+	entries, err := importer.ParseBashHistoryReader(r, time.Now().Unix(), importer.DefaultApproxWindow)
+	if err != nil {
+		return err
+	}
+
+	for _, bashEntry := range entries {
+		entry := &storage.HistoryEntry{
+			Command:    bashEntry.Command,
+			Timestamp:  bashEntry.Timestamp,
+			ApproxTime: bashEntry.Approximate,
+			Shell:      "bash",
+		}
+		result.add(inserter, entry, opts)
+	}
+	return nil
+}
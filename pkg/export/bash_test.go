@@ -0,0 +1,74 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBash_RoundTrip(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	commands := []string{"git status", "for f in *.log; do\n  gzip $f\ndone", "echo → done"}
+	for i, command := range commands {
+		require.NoError(t, db.Insert(&storage.HistoryEntry{
+			Command:   command,
+			Timestamp: 1700000000 + int64(i)*60,
+			Hash:      storage.GenerateHash(command),
+		}))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatBash}))
+	assert.Equal(t, "#1700000000\ngit status\n#1700000060\nfor f in *.log; do\n  gzip $f\ndone\n#1700000120\necho → done\n", buf.String(), "oldest first")
+
+	format, reader, err := DetectFormat(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatBash, format)
+
+	imported, err := storage.Open(tempDir + "/imported.db")
+	require.NoError(t, err)
+	defer imported.Close()
+
+	result, err := ImportWithOptions(imported, reader, format, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+
+	entries, err := imported.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.Equal(t, commands[len(commands)-1-i], entry.Command)
+		assert.Equal(t, int64(1700000120)-int64(i)*60, entry.Timestamp)
+		assert.Equal(t, "bash", entry.Shell)
+		assert.False(t, entry.ApproxTime)
+	}
+}
+
+func TestImportBash_WithoutTimestamps(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	input := "make build\nmake test\n#1700000000\nmake deploy\n"
+	result, err := ImportWithOptions(db, bytes.NewReader([]byte(input)), FormatBash, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported, "one command per line before the first timestamp")
+
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "make deploy", entries[0].Command)
+	assert.False(t, entries[0].ApproxTime)
+	assert.Equal(t, "make test", entries[1].Command)
+	assert.True(t, entries[1].ApproxTime)
+	assert.Less(t, entries[1].Timestamp, int64(1700000000))
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+)
+
+const benchImportSize = 100_000
+
+// benchInput renders benchImportSize entries in format, with 10% repeated commands
+func benchInput(b *testing.B, format Format) []byte {
+	b.Helper()
+
+	src := testutil.NewTestDB(b)
+	defer src.Close()
+	keepAll := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+
+	for i := 0; i < benchImportSize; i++ {
+		entry := &storage.HistoryEntry{
+			Timestamp: int64(1_700_000_000 + i),
+			Command:   fmt.Sprintf("ssh host%d 'uptime'", i%(benchImportSize*9/10)),
+			Cwd:       "/home/user",
+			Shell:     "zsh",
+		}
+		if err := src.InsertWithDedup(entry, keepAll); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Export(src, &buf, Options{Format: format}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkImport100k(b *testing.B) {
+	for _, format := range []Format{FormatText, FormatJSON, FormatCSV, FormatBinary} {
+		b.Run(string(format), func(b *testing.B) {
+			input := benchInput(b, format)
+			dedup := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				db := testutil.NewTestDB(b)
+				b.StartTimer()
+
+				count, err := Import(db, bytes.NewReader(input), format, dedup)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if count != benchImportSize {
+					b.Fatalf("imported %d entries, want %d", count, benchImportSize)
+				}
+
+				b.StopTimer()
+				_ = db.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// binaryMagic starts every binary export, for DetectFormat and to refuse
+// other files before gob does with a less helpful error
+const binaryMagic = "FHBIN\x00"
+
+// binaryHeader follows binaryMagic: the schema version of the entries
+// after it, as in a JSON export
+type binaryHeader struct {
+	Schema int
+}
+
+// exportBinary exports entries as a gob stream of the same fields as JSON,
+// which decodes about ten times faster than JSON. It is meant for moving
+// history between machines, not for reading: the layout is only stable
+// through the schema version.
+func exportBinary(entries []*storage.HistoryEntry, writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	if _, err := io.WriteString(buffered, binaryMagic); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	encoder := gob.NewEncoder(buffered)
+	if err := encoder.Encode(binaryHeader{Schema: ExportSchema}); err != nil {
+		return fmt.Errorf("failed to encode header: %w", err)
+	}
+	for _, entry := range entries {
+		if err := encoder.Encode(newJSONEntry(entry)); err != nil {
+			return fmt.Errorf("failed to encode entry: %w", err)
+		}
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write entries: %w", err)
+	}
+	return nil
+}
+
+// importBinary imports a binary export an entry at a time
+func importBinary(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
+	buffered := bufio.NewReader(r)
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(buffered, magic); err != nil || !bytes.Equal(magic, []byte(binaryMagic)) {
+		return fmt.Errorf("not an fh binary export")
+	}
+
+	decoder := gob.NewDecoder(buffered)
+	var header binaryHeader
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("failed to decode header: %w", err)
+	}
+	if header.Schema < 1 || header.Schema > ExportSchema {
+		return &SchemaError{Schema: header.Schema}
+	}
+
+	for n := 1; ; n++ {
+		var je jsonEntry
+		if err := decoder.Decode(&je); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode entry %d: %w", n, err)
+		}
+		result.addJSON(inserter, je, opts)
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBinary_RoundTrip(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	original := &storage.HistoryEntry{
+		Command:    "for f in *.log; do\n  gzip $f\ndone",
+		Timestamp:  1700000000,
+		ExitCode:   1,
+		Cwd:        "/var/log",
+		Hostname:   "laptop",
+		User:       "me",
+		Shell:      "zsh",
+		DurationMs: 1500,
+		GitBranch:  "main",
+		SessionID:  "s1",
+		Note:       "rotate by hand",
+		ApproxTime: true,
+		Hash:       "h1",
+	}
+	require.NoError(t, db.Insert(original))
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "echo → done", Timestamp: 1700000060, Hash: "h2"}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatBinary}))
+	assert.True(t, strings.HasPrefix(buf.String(), binaryMagic))
+
+	format, reader, err := DetectFormat(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatBinary, format)
+
+	imported := testutil.NewTestDB(t)
+	defer imported.Close()
+	result, err := ImportWithOptions(imported, reader, format, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+
+	entries, err := imported.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "echo → done", entries[0].Command)
+
+	got := entries[1]
+	assert.Equal(t, original.Command, got.Command)
+	assert.Equal(t, original.Timestamp, got.Timestamp)
+	assert.Equal(t, original.ExitCode, got.ExitCode)
+	assert.Equal(t, original.Cwd, got.Cwd)
+	assert.Equal(t, original.Hostname, got.Hostname)
+	assert.Equal(t, original.Shell, got.Shell)
+	assert.Equal(t, original.DurationMs, got.DurationMs)
+	assert.Equal(t, original.GitBranch, got.GitBranch)
+	assert.Equal(t, original.SessionID, got.SessionID)
+	assert.Equal(t, original.Note, got.Note)
+	assert.True(t, got.ApproxTime)
+}
+
+func TestImportBinary_Errors(t *testing.T) {
+	newerSchema := func() string {
+		var buf bytes.Buffer
+		buf.WriteString(binaryMagic)
+		require.NoError(t, gob.NewEncoder(&buf).Encode(binaryHeader{Schema: ExportSchema + 1}))
+		return buf.String()
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"not binary", `[{"command": "ls"}]`, "not an fh binary export"},
+		{"newer schema", newerSchema(), "upgrade fh"},
+		{"truncated", binaryMagic + "\x03", "failed to decode header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+
+			_, err := ImportWithOptions(db, strings.NewReader(tt.input), FormatBinary, ImportOptions{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want, fmt.Sprintf("%q", tt.input))
+		})
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/spideyz0r/fh/pkg/importer"
+	"github.com/spideyz0r/fh/pkg/shellparse"
 	"github.com/spideyz0r/fh/pkg/storage"
 )
 
@@ -23,14 +26,34 @@ const (
 	FormatText Format = "text"
 	// FormatJSON exports commands as JSON with full metadata
 	FormatJSON Format = "json"
+	// FormatJSONL exports commands as JSON Lines, one entry per line, for
+	// exports too large to hold in memory as one JSON document
+	FormatJSONL Format = "jsonl"
 	// FormatCSV exports commands as CSV with all fields
 	FormatCSV Format = "csv"
+	// FormatZsh exports commands as a zsh extended_history file
+	FormatZsh Format = "zsh"
+	// FormatBash exports commands as a bash history file with timestamps
+	FormatBash Format = "bash"
+	// FormatBinary exports commands as a compact binary stream for fast
+	// transfer to another fh
+	FormatBinary Format = "binary"
+	// FormatNotebook exports commands as a Jupyter notebook of %%bash cells
+	// (export only)
+	FormatNotebook Format = "ipynb"
+	// FormatParquet exports commands as a typed Parquet file for DuckDB,
+	// pandas and the like (export only)
+	FormatParquet Format = "parquet"
 )
 
 // Options contains export configuration
 type Options struct {
-	Format  Format
-	Filters storage.QueryFilters
+	Format    Format
+	Filters   storage.QueryFilters
+	Highlight func(command string) string // Decorates commands in text output, if set
+	Quote     bool                        // Shell-quote commands in text output
+	Print0    bool                        // End commands with NUL instead of newline in text output
+	Path      func(dir string) string     // Rewrites directories, e.g. home as ~, nil writes them as stored
 }
 
 // Export writes history entries to the writer in the specified format
@@ -41,22 +64,54 @@ func Export(db storage.Store, writer io.Writer, opts Options) error {
 		return fmt.Errorf("failed to query entries: %w", err)
 	}
 
+	if opts.Path != nil {
+		for _, entry := range entries {
+			if entry.Cwd != "" {
+				entry.Cwd = opts.Path(entry.Cwd)
+			}
+		}
+	}
+
 	switch opts.Format {
 	case FormatText:
-		return exportText(entries, writer)
+		return exportText(entries, writer, opts)
 	case FormatJSON:
 		return exportJSON(entries, writer)
+	case FormatJSONL:
+		return exportJSONL(entries, writer)
 	case FormatCSV:
 		return exportCSV(entries, writer)
+	case FormatZsh:
+		return exportZsh(entries, writer)
+	case FormatBash:
+		return exportBash(entries, writer)
+	case FormatBinary:
+		return exportBinary(entries, writer)
+	case FormatNotebook:
+		return exportNotebook(entries, writer)
+	case FormatParquet:
+		return exportParquet(entries, writer)
 	default:
 		return fmt.Errorf("unsupported format: %s", opts.Format)
 	}
 }
 
 // exportText exports entries as plain text (one command per line)
-func exportText(entries []*storage.HistoryEntry, writer io.Writer) error {
+func exportText(entries []*storage.HistoryEntry, writer io.Writer, opts Options) error {
+	end := "\n"
+	if opts.Print0 {
+		end = "\x00"
+	}
+
 	for _, entry := range entries {
-		_, err := fmt.Fprintln(writer, entry.Command)
+		command := entry.Command
+		if opts.Quote {
+			command = shellparse.Quote(command)
+		}
+		if opts.Highlight != nil {
+			command = opts.Highlight(command)
+		}
+		_, err := io.WriteString(writer, command+end)
 		if err != nil {
 			return fmt.Errorf("failed to write entry: %w", err)
 		}
@@ -64,44 +119,88 @@ func exportText(entries []*storage.HistoryEntry, writer io.Writer) error {
 	return nil
 }
 
-// exportJSON exports entries as JSON array with full metadata
+// jsonEntry is the JSON representation of a history entry, shared by export and import
+type jsonEntry struct {
+	ID          int64  `json:"id"`
+	Command     string `json:"command"`
+	Timestamp   int64  `json:"timestamp"`
+	ExitCode    int    `json:"exit_code"`
+	Cwd         string `json:"cwd"`
+	Hostname    string `json:"hostname"`
+	User        string `json:"user"`
+	Shell       string `json:"shell"`
+	DurationMs  int64  `json:"duration_ms"`
+	GitBranch   string `json:"git_branch,omitempty"`
+	SessionID   string `json:"session_id"`
+	Profile     string `json:"profile,omitempty"`
+	ExecRuntime string `json:"exec_runtime,omitempty"`
+	ExecTarget  string `json:"exec_target,omitempty"`
+	Note        string `json:"note,omitempty"`
+	ApproxTime  bool   `json:"approx_time,omitempty"`
+	Redacted    bool   `json:"redacted,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	UUID        string `json:"uuid,omitempty"`
+}
+
+// newJSONEntry converts a history entry to its JSON representation
+func newJSONEntry(entry *storage.HistoryEntry) jsonEntry {
+	return jsonEntry{
+		ID:          entry.ID,
+		Command:     entry.Command,
+		Timestamp:   entry.Timestamp,
+		ExitCode:    entry.ExitCode,
+		Cwd:         entry.Cwd,
+		Hostname:    entry.Hostname,
+		User:        entry.User,
+		Shell:       entry.Shell,
+		DurationMs:  entry.DurationMs,
+		GitBranch:   entry.GitBranch,
+		SessionID:   entry.SessionID,
+		Profile:     entry.Profile,
+		ExecRuntime: entry.ExecRuntime,
+		ExecTarget:  entry.ExecTarget,
+		Note:        entry.Note,
+		ApproxTime:  entry.ApproxTime,
+		Redacted:    entry.Redacted,
+		UUID:        entry.UUID,
+	}
+}
+
+// toHistoryEntry converts a decoded JSON entry back to a history entry
+func (e jsonEntry) toHistoryEntry() *storage.HistoryEntry {
+	return &storage.HistoryEntry{
+		ID:          e.ID,
+		Command:     e.Command,
+		Timestamp:   e.Timestamp,
+		ExitCode:    e.ExitCode,
+		Cwd:         e.Cwd,
+		Hostname:    e.Hostname,
+		User:        e.User,
+		Shell:       e.Shell,
+		DurationMs:  e.DurationMs,
+		GitBranch:   e.GitBranch,
+		SessionID:   e.SessionID,
+		Profile:     e.Profile,
+		ExecRuntime: e.ExecRuntime,
+		ExecTarget:  e.ExecTarget,
+		Note:        e.Note,
+		ApproxTime:  e.ApproxTime,
+		Redacted:    e.Redacted,
+		UUID:        e.UUID,
+	}
+}
+
+// exportJSON exports entries with full metadata as JSON of schema
+// ExportSchema
 func exportJSON(entries []*storage.HistoryEntry, writer io.Writer) error {
-	// Convert entries to JSON-friendly format
-	type JSONEntry struct {
-		ID         int64  `json:"id"`
-		Command    string `json:"command"`
-		Timestamp  int64  `json:"timestamp"`
-		ExitCode   int    `json:"exit_code"`
-		Cwd        string `json:"cwd"`
-		Hostname   string `json:"hostname"`
-		User       string `json:"user"`
-		Shell      string `json:"shell"`
-		DurationMs int64  `json:"duration_ms"`
-		GitBranch  string `json:"git_branch,omitempty"`
-		SessionID  string `json:"session_id"`
-		CreatedAt  string `json:"created_at,omitempty"`
-	}
-
-	jsonEntries := make([]JSONEntry, len(entries))
+	export := jsonExport{Schema: ExportSchema, Entries: make([]jsonEntry, len(entries))}
 	for i, entry := range entries {
-		jsonEntries[i] = JSONEntry{
-			ID:         entry.ID,
-			Command:    entry.Command,
-			Timestamp:  entry.Timestamp,
-			ExitCode:   entry.ExitCode,
-			Cwd:        entry.Cwd,
-			Hostname:   entry.Hostname,
-			User:       entry.User,
-			Shell:      entry.Shell,
-			DurationMs: entry.DurationMs,
-			GitBranch:  entry.GitBranch,
-			SessionID:  entry.SessionID,
-		}
+		export.Entries[i] = newJSONEntry(entry)
 	}
 
 	encoder := json.NewEncoder(writer)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(jsonEntries); err != nil {
+	if err := encoder.Encode(export); err != nil {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
@@ -126,6 +225,11 @@ func exportCSV(entries []*storage.HistoryEntry, writer io.Writer) error {
 		"duration_ms",
 		"git_branch",
 		"session_id",
+		"profile",
+		"exec_runtime",
+		"exec_target",
+		"note",
+		"uuid",
 	}
 	if err := csvWriter.Write(header); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
@@ -145,6 +249,11 @@ func exportCSV(entries []*storage.HistoryEntry, writer io.Writer) error {
 			strconv.FormatInt(entry.DurationMs, 10),
 			entry.GitBranch,
 			entry.SessionID,
+			entry.Profile,
+			entry.ExecRuntime,
+			entry.ExecTarget,
+			entry.Note,
+			entry.UUID,
 		}
 		if err := csvWriter.Write(record); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
@@ -166,37 +275,123 @@ func ParseFormat(s string) (Format, error) {
 		return FormatText, nil
 	case "json":
 		return FormatJSON, nil
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
 	case "csv":
 		return FormatCSV, nil
+	case "zsh", "zsh_history":
+		return FormatZsh, nil
+	case "bash", "bash_history":
+		return FormatBash, nil
+	case "binary", "gob":
+		return FormatBinary, nil
+	case "ipynb", "notebook":
+		return FormatNotebook, nil
+	case "parquet":
+		return FormatParquet, nil
 	default:
-		return "", fmt.Errorf("unknown format: %s (supported: text, json, csv)", s)
+		return "", fmt.Errorf("unknown format: %s (supported: text, json, jsonl, csv, zsh, bash, binary, ipynb, parquet)", s)
 	}
 }
 
+// ImportOptions configures ImportWithOptions
+type ImportOptions struct {
+	Dedup  storage.DedupConfig
+	Ignore func(command string) bool // Commands to leave out, nil keeps all
+	Path   func(dir string) string   // Rewrites directories, e.g. ~ back to home, nil keeps them
+}
+
+// ImportResult counts what an import did with the entries it read
+type ImportResult struct {
+	Imported int
+	Ignored  int // Left out by ImportOptions.Ignore
+	Repaired int // Not valid UTF-8, transcoded or sanitized
+	Notes    int // Notes restored onto entries already in the database
+}
+
 // Import imports history from a reader with the given format
 func Import(db *storage.DB, r io.Reader, format Format, dedupConfig storage.DedupConfig) (int, error) {
+	result, err := ImportWithOptions(db, r, format, ImportOptions{Dedup: dedupConfig})
+	return result.Imported, err
+}
+
+// ImportWithOptions imports history from a reader with the given format,
+// leaving out the commands opts.Ignore matches
+func ImportWithOptions(db *storage.DB, r io.Reader, format Format, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return result, err
+	}
+
 	switch format {
 	case FormatText:
-		return importText(db, r, dedupConfig)
+		err = importText(inserter, r, opts, &result)
 	case FormatJSON:
-		return importJSON(db, r, dedupConfig)
+		err = importJSON(inserter, r, opts, &result)
+	case FormatJSONL:
+		err = importJSONL(inserter, r, opts, &result)
 	case FormatCSV:
-		return importCSV(db, r, dedupConfig)
+		err = importCSV(inserter, r, opts, &result)
+	case FormatZsh:
+		err = importZsh(inserter, r, opts, &result)
+	case FormatBash:
+		err = importBash(inserter, r, opts, &result)
+	case FormatBinary:
+		err = importBinary(inserter, r, opts, &result)
 	default:
-		return 0, fmt.Errorf("unsupported import format: %s", format)
+		err = fmt.Errorf("unsupported import format: %s", format)
 	}
+	return result, err
+}
+
+// add inserts entry unless opts ignores it or it is already in the
+// database, see MergeNote. Text that is not valid UTF-8 is repaired
+// first, as SQLite and JSON exports expect it.
+func (result *ImportResult) add(inserter *storage.BatchInserter, entry *storage.HistoryEntry, opts ImportOptions) {
+	repaired := false
+	for _, field := range []*string{&entry.Command, &entry.Cwd, &entry.Note} {
+		var changed bool
+		*field, changed = importer.RepairUTF8(*field)
+		repaired = repaired || changed
+	}
+	if repaired {
+		result.Repaired++
+	}
+	if opts.Path != nil && entry.Cwd != "" {
+		entry.Cwd = opts.Path(entry.Cwd)
+	}
+
+	if opts.Ignore != nil && opts.Ignore(entry.Command) {
+		result.Ignored++
+		return
+	}
+
+	// An entry already here gets its note back instead of a copy
+	if entry.UUID != "" || entry.Note != "" {
+		found, merged, err := inserter.MergeNote(entry)
+		if merged {
+			result.Notes++
+		}
+		if found || err != nil {
+			return
+		}
+	}
+	if err := inserter.Insert(entry); err != nil {
+		// Skip entries that fail to insert (e.g., duplicates)
+		return
+	}
+	result.Imported++
 }
 
 // importText imports from plain text format (one command per line)
-func importText(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (int, error) {
+func importText(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
 	scanner := bufio.NewScanner(r)
-	
+
 	// Increase buffer size to handle very long command lines (up to 1MB)
 	const maxScanTokenSize = 1024 * 1024 // 1MB
 	buf := make([]byte, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
-	
-	count := 0
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -217,49 +412,46 @@ func importText(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (i
 			SessionID:  "",
 		}
 
-		if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
-			// Skip entries that fail to insert (e.g., duplicates)
-			continue
-		}
-		count++
+		result.add(inserter, entry, opts)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return count, fmt.Errorf("error reading text: %w", err)
+		return fmt.Errorf("error reading text: %w", err)
 	}
 
-	return count, nil
+	return nil
 }
 
 // importJSON imports from JSON format
-func importJSON(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (int, error) {
-	var entries []*storage.HistoryEntry
+func importJSON(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
+	jsonEntries, err := decodeJSONExport(r)
+	if err != nil {
+		return err
+	}
 
-	decoder := json.NewDecoder(r)
-	if err := decoder.Decode(&entries); err != nil {
-		return 0, fmt.Errorf("failed to parse JSON: %w", err)
+	for _, je := range jsonEntries {
+		result.addJSON(inserter, je, opts)
 	}
 
-	count := 0
-	for _, entry := range entries {
-		// Validate entry
-		if entry.Command == "" {
-			continue
-		}
+	return nil
+}
 
-		// Ensure timestamp is set
-		if entry.Timestamp == 0 {
-			entry.Timestamp = time.Now().Unix()
-		}
+// addJSON adds a decoded JSON entry like add, skipping it without a
+// command and giving it the current time without a timestamp
+func (result *ImportResult) addJSON(inserter *storage.BatchInserter, je jsonEntry, opts ImportOptions) {
+	entry := je.toHistoryEntry()
 
-		if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
-			// Skip entries that fail to insert
-			continue
-		}
-		count++
+	// Validate entry
+	if entry.Command == "" {
+		return
 	}
 
-	return count, nil
+	// Ensure timestamp is set
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().Unix()
+	}
+
+	result.add(inserter, entry, opts)
 }
 
 // parseCSVRow parses a CSV record into a HistoryEntry
@@ -295,6 +487,11 @@ func parseCSVRow(record []string, colMap map[string]int) *storage.HistoryEntry {
 	parseCSVStringField(record, colMap, "shell", &entry.Shell)
 	parseCSVStringField(record, colMap, "git_branch", &entry.GitBranch)
 	parseCSVStringField(record, colMap, "session_id", &entry.SessionID)
+	parseCSVStringField(record, colMap, "profile", &entry.Profile)
+	parseCSVStringField(record, colMap, "exec_runtime", &entry.ExecRuntime)
+	parseCSVStringField(record, colMap, "exec_target", &entry.ExecTarget)
+	parseCSVStringField(record, colMap, "note", &entry.Note)
+	parseCSVStringField(record, colMap, "uuid", &entry.UUID)
 
 	if idx, ok := colMap["exit_code"]; ok && idx < len(record) {
 		if code, err := strconv.Atoi(record[idx]); err == nil {
@@ -318,13 +515,13 @@ func parseCSVStringField(record []string, colMap map[string]int, fieldName strin
 }
 
 // importCSV imports from CSV format
-func importCSV(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (int, error) {
+func importCSV(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
 	reader := csv.NewReader(r)
 
 	// Read header
 	header, err := reader.Read()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+		return fmt.Errorf("failed to read CSV header: %w", err)
 	}
 
 	// Build column index map
@@ -335,17 +532,16 @@ func importCSV(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (in
 
 	// Verify required columns
 	if _, ok := colMap["command"]; !ok {
-		return 0, fmt.Errorf("CSV missing required column: command")
+		return fmt.Errorf("CSV missing required column: command")
 	}
 
-	count := 0
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return count, fmt.Errorf("error reading CSV: %w", err)
+			return fmt.Errorf("error reading CSV: %w", err)
 		}
 
 		// Parse entry from CSV row
@@ -354,16 +550,18 @@ func importCSV(db *storage.DB, r io.Reader, dedupConfig storage.DedupConfig) (in
 			continue
 		}
 
-		if err := db.InsertWithDedup(entry, dedupConfig); err != nil {
-			// Skip entries that fail to insert
-			continue
-		}
-		count++
+		result.add(inserter, entry, opts)
 	}
 
-	return count, nil
+	return nil
 }
 
+// zshLine matches the first line of a zsh extended_history file
+var zshLine = regexp.MustCompile(`^: \d+:\d+;`)
+
+// bashLine matches the first line of a bash history file with timestamps
+var bashLine = regexp.MustCompile(`^#\d+\n`)
+
 // DetectFormat attempts to auto-detect the format from file content
 func DetectFormat(r io.Reader) (Format, io.Reader, error) {
 	// Read first few bytes to detect format
@@ -378,12 +576,32 @@ func DetectFormat(r io.Reader) (Format, io.Reader, error) {
 
 	content := string(buf[:n])
 
-	// Detect JSON (starts with [ or {)
+	// Detect binary exports by their magic
+	if strings.HasPrefix(content, binaryMagic) {
+		return FormatBinary, newReader, nil
+	}
+
+	// Detect JSON Lines (a whole JSON object on the first line)
 	trimmed := strings.TrimSpace(content)
+	if first, _, found := strings.Cut(trimmed, "\n"); found && strings.HasPrefix(first, "{") && json.Valid([]byte(first)) {
+		return FormatJSONL, newReader, nil
+	}
+
+	// Detect JSON (starts with [ or {)
 	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
 		return FormatJSON, newReader, nil
 	}
 
+	// Detect zsh extended_history (: <timestamp>:<duration>;<command>)
+	if zshLine.MatchString(content) {
+		return FormatZsh, newReader, nil
+	}
+
+	// Detect bash history with timestamps (#<timestamp>, then the command)
+	if bashLine.MatchString(content) {
+		return FormatBash, newReader, nil
+	}
+
 	// Detect CSV (has comma-separated values with headers)
 	if strings.Contains(content, ",") && strings.Contains(content, "command") {
 		lines := strings.Split(content, "\n")
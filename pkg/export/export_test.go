@@ -60,6 +60,19 @@ func TestExportText(t *testing.T) {
 	assert.Len(t, lines, 3)
 }
 
+func TestExportText_Highlight(t *testing.T) {
+	db := testutil.NewFakeStore()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "git status", Timestamp: 1000}))
+
+	var buf bytes.Buffer
+	opts := Options{
+		Format:    FormatText,
+		Highlight: strings.ToUpper,
+	}
+	require.NoError(t, Export(db, &buf, opts))
+	assert.Equal(t, "GIT STATUS\n", buf.String())
+}
+
 func TestExportJSON(t *testing.T) {
 	tempDir, cleanup := testutil.TempDir(t)
 	defer cleanup()
@@ -95,11 +108,16 @@ func TestExportJSON(t *testing.T) {
 	require.NoError(t, err)
 
 	// Parse JSON
-	var result []map[string]interface{}
-	err = json.Unmarshal(buf.Bytes(), &result)
+	var export struct {
+		Schema  int                      `json:"schema"`
+		Entries []map[string]interface{} `json:"entries"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &export)
 	require.NoError(t, err)
 
 	// Verify
+	assert.Equal(t, ExportSchema, export.Schema)
+	result := export.Entries
 	require.Len(t, result, 1)
 	assert.Equal(t, "echo test", result[0]["command"])
 	assert.Equal(t, float64(1234567890), result[0]["timestamp"])
@@ -283,6 +301,36 @@ func TestExportEmpty(t *testing.T) {
 	assert.Empty(t, buf.String())
 }
 
+func TestExportImport_Path(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "make", Timestamp: 1234567890, Cwd: "/home/me/src", Hash: storage.GenerateHash("make")}))
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "ls", Timestamp: 1234567891, Hash: storage.GenerateHash("ls")}))
+
+	var buf bytes.Buffer
+	shorten := func(dir string) string { return strings.Replace(dir, "/home/me", "~", 1) }
+	require.NoError(t, Export(db, &buf, Options{Format: FormatCSV, Path: shorten}))
+	assert.Contains(t, buf.String(), ",~/src,")
+
+	other, err := storage.Open(tempDir + "/other.db")
+	require.NoError(t, err)
+	defer other.Close()
+
+	expand := func(dir string) string { return strings.Replace(dir, "~", "/home/you", 1) }
+	_, err = ImportWithOptions(other, &buf, FormatCSV, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}, Path: expand})
+	require.NoError(t, err)
+	entries, err := other.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "", entries[0].Cwd, "empty directories stay empty")
+	assert.Equal(t, "/home/you/src", entries[1].Cwd)
+}
+
 func TestParseFormat(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -292,7 +340,17 @@ func TestParseFormat(t *testing.T) {
 		{"text", FormatText, false},
 		{"txt", FormatText, false},
 		{"json", FormatJSON, false},
+		{"jsonl", FormatJSONL, false},
+		{"ndjson", FormatJSONL, false},
 		{"csv", FormatCSV, false},
+		{"zsh", FormatZsh, false},
+		{"zsh_history", FormatZsh, false},
+		{"bash", FormatBash, false},
+		{"bash_history", FormatBash, false},
+		{"binary", FormatBinary, false},
+		{"ipynb", FormatNotebook, false},
+		{"notebook", FormatNotebook, false},
+		{"parquet", FormatParquet, false},
 		{"XML", "", true},
 		{"invalid", "", true},
 	}
@@ -404,8 +462,13 @@ func TestDetectFormat_EdgeCases(t *testing.T) {
 	}{
 		{"JSON array", `[{"command": "test"}]`, FormatJSON},
 		{"JSON object", `{"command": "test"}`, FormatJSON},
+		{"JSON Lines", "{\"schema\":2}\n{\"command\":\"test\"}\n", FormatJSONL},
+		{"indented JSON export", "{\n  \"schema\": 2,\n  \"entries\": []\n}\n", FormatJSON},
 		{"CSV with header", "timestamp,command,cwd\n1234567890,test,/tmp", FormatCSV},
 		{"plain text", "just some commands\nls -la\npwd", FormatText},
+		{"zsh extended history", ": 1700000000:0;git status\n", FormatZsh},
+		{"bash history with timestamps", "#1700000000\ngit status\n", FormatBash},
+		{"binary", binaryMagic + "\x1f\xff", FormatBinary},
 		{"empty content", "", FormatText},
 	}
 
@@ -457,3 +520,17 @@ func TestImportCSV_MissingColumns(t *testing.T) {
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, count, 0)
 }
+
+func TestExportText_QuotePrint0(t *testing.T) {
+	db := testutil.NewFakeStore()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "printf 'a\nb'", Timestamp: 2000}))
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "ls", Timestamp: 1000}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatText, Print0: true}))
+	assert.Equal(t, "printf 'a\nb'\x00ls\x00", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Export(db, &buf, Options{Format: FormatText, Quote: true}))
+	assert.Equal(t, "'printf '\\''a\nb'\\'''\nls\n", buf.String())
+}
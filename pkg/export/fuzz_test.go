@@ -0,0 +1,82 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+)
+
+// Run a target with: go test ./pkg/export -run=^$ -fuzz=FuzzImportCSV
+
+func FuzzImportCSV(f *testing.F) {
+	seeds := []string{
+		"id,timestamp,command,exit_code,cwd\n1,2009-02-13T23:31:30Z,ls -la,0,/home\n",
+		"command\nls\n\"echo \"\"quoted\"\"\"\n",
+		"command,timestamp\n\"multi\nline\",1234567890\n",
+		"timestamp,cwd\n1,/tmp\n",
+		"command,exit_code,duration_ms\nls,notanumber,-1\n",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	db := testutil.NewTestDB(f)
+	defer db.Close()
+	dedup := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		before, err := db.Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count, _ := Import(db, strings.NewReader(input), FormatCSV, dedup)
+
+		after, err := db.Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after-before != int64(count) {
+			t.Fatalf("reported %d imports but %d rows were added", count, after-before)
+		}
+	})
+}
+
+func FuzzImportJSON(f *testing.F) {
+	seeds := []string{
+		`[{"command":"ls -la","timestamp":1234567890,"exit_code":0}]`,
+		`[{"command":""},{"command":"git status"}]`,
+		`[{"command":"echo é中😀","cwd":"/tmp"}]`,
+		`{"command":"not an array"}`,
+		`[`,
+		`[]`,
+		`null`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	db := testutil.NewTestDB(f)
+	defer db.Close()
+	dedup := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		before, err := db.Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count, _ := Import(db, strings.NewReader(input), FormatJSON, dedup)
+
+		after, err := db.Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after-before != int64(count) {
+			t.Fatalf("reported %d imports but %d rows were added", count, after-before)
+		}
+	})
+}
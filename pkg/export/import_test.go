@@ -478,9 +478,135 @@ func TestImportRoundTrip(t *testing.T) {
 		if entry.Cwd != entries[i].Cwd {
 			t.Errorf("Entry %d: cwd mismatch: expected %q, got %q", i, entries[i].Cwd, entry.Cwd)
 		}
-		// Note: GitBranch may not be preserved in JSON export due to omitempty
-		if entry.GitBranch != "" && entry.GitBranch != entries[i].GitBranch {
+		if entry.GitBranch != entries[i].GitBranch {
 			t.Errorf("Entry %d: git_branch mismatch: expected %q, got %q", i, entries[i].GitBranch, entry.GitBranch)
 		}
+		if entry.ExitCode != entries[i].ExitCode || entry.DurationMs != entries[i].DurationMs || entry.SessionID != entries[i].SessionID {
+			t.Errorf("Entry %d: metadata mismatch: expected %+v, got %+v", i, entries[i], entry)
+		}
+	}
+}
+
+func TestImportWithOptions_Ignore(t *testing.T) {
+	ignore := func(command string) bool {
+		return strings.HasPrefix(command, "ls") || strings.HasPrefix(command, "cd ")
+	}
+
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{FormatText, "ls -la\ncd /tmp\nmake test\n"},
+		{FormatJSON, `[{"command":"ls -la"},{"command":"cd /tmp"},{"command":"make test"}]`},
+		{FormatCSV, "command\nls -la\ncd /tmp\nmake test\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+
+			result, err := ImportWithOptions(db, strings.NewReader(tt.input), tt.format, ImportOptions{Ignore: ignore})
+			assert.NoError(t, err)
+			assert.Equal(t, ImportResult{Imported: 1, Ignored: 2}, result)
+
+			entries, err := db.Query(storage.QueryFilters{})
+			assert.NoError(t, err)
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, "make test", entries[0].Command)
+			}
+		})
+	}
+}
+
+func TestImportWithOptions_RepairsEncoding(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	input := "command,cwd\necho caf\xe9,/home/jos\xe9\nls,/tmp\n"
+	opts := ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}}
+	result, err := ImportWithOptions(db, strings.NewReader(input), FormatCSV, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: 2, Repaired: 1}, result)
+
+	entries, err := db.Query(storage.QueryFilters{Command: "echo café"})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "/home/josé", entries[0].Cwd)
+	}
+}
+
+func TestImportJSON_RestoresNotes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	keepFirst := storage.DedupConfig{Enabled: true, Strategy: storage.KeepFirst}
+	for _, entry := range []*storage.HistoryEntry{
+		{Command: "terraform apply", Timestamp: 1000},
+		{Command: "make release", Timestamp: 2000, Note: "local note"},
+	} {
+		assert.NoError(t, db.InsertWithDedup(entry, keepFirst))
+	}
+
+	// An export from before the notes were lost, or from another machine
+	input := `[
+		{"command": "terraform apply", "timestamp": 1000, "note": "prod, after the freeze"},
+		{"command": "make release", "timestamp": 2000, "note": "exported note"},
+		{"command": "terraform apply", "timestamp": 3000, "note": "a later run"}
+	]`
+	result, err := ImportWithOptions(db, strings.NewReader(input), FormatJSON, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Notes)
+	assert.Equal(t, 1, result.Imported, "only the run that wasn't there")
+
+	entries, err := db.Query(storage.QueryFilters{})
+	assert.NoError(t, err)
+	notes := make(map[int64]string)
+	for _, entry := range entries {
+		notes[entry.Timestamp] = entry.Note
+	}
+	assert.Equal(t, map[int64]string{
+		1000: "prod, after the freeze",
+		2000: "local note",
+		3000: "a later run",
+	}, notes)
+
+	amendments, err := db.GetAmendments(entries[2].ID)
+	assert.NoError(t, err)
+	assert.Len(t, amendments, 1, "restored like an --amend --note")
+}
+
+func TestImportJSON_RestoresNotesByUUID(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	keepAll := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+	keepLast := storage.DedupConfig{Enabled: true, Strategy: storage.KeepLast}
+	first := &storage.HistoryEntry{Command: "make test", Timestamp: 1000}
+	second := &storage.HistoryEntry{Command: "make test", Timestamp: 1000, Note: "flaky"}
+	pull := &storage.HistoryEntry{Command: "git pull", Timestamp: 2000, Note: "before the rebase"}
+	assert.NoError(t, db.InsertWithDedup(first, keepAll))
+	assert.NoError(t, db.InsertWithDedup(second, keepAll))
+	assert.NoError(t, db.InsertWithDedup(pull, keepLast))
+
+	var buf bytes.Buffer
+	assert.NoError(t, Export(db, &buf, Options{Format: FormatJSON}))
+
+	// The notes get lost, and git pull runs again, moving its entry
+	empty := ""
+	for _, id := range []int64{second.ID, pull.ID} {
+		assert.NoError(t, db.Update(id, storage.EntryUpdate{Note: &empty}))
+	}
+	assert.NoError(t, db.InsertWithDedup(&storage.HistoryEntry{Command: "git pull", Timestamp: 3000}, keepLast))
+
+	result, err := ImportWithOptions(db, &buf, FormatJSON, ImportOptions{Dedup: keepAll})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Notes)
+	assert.Zero(t, result.Imported, "every entry was already there")
+
+	for id, want := range map[int64]string{first.ID: "", second.ID: "flaky", pull.ID: "before the rebase"} {
+		entry, err := db.GetByID(id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, entry.Note, "entry %d", id)
 	}
 }
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// jsonlHeader is the first line of a JSON Lines export: the schema version
+// of the entries on the lines after it, as in a JSON export
+type jsonlHeader struct {
+	Schema int `json:"schema"`
+}
+
+// exportJSONL exports entries as JSON Lines: a header with the schema
+// version, then one entry per line, each encoded as it is written
+func exportJSONL(entries []*storage.HistoryEntry, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(jsonlHeader{Schema: ExportSchema}); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	for _, entry := range entries {
+		if err := encoder.Encode(newJSONEntry(entry)); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+// importJSONL imports from JSON Lines a line at a time, so the size of the
+// export doesn't matter. The schema header is optional, files from other
+// tools without one are read as the current schema.
+func importJSONL(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
+	reader := bufio.NewReader(r)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error reading JSON Lines: %w", err)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var je jsonEntry
+			if jsonErr := json.Unmarshal(line, &je); jsonErr != nil {
+				return fmt.Errorf("failed to parse JSON on line %d: %w", lineNum, jsonErr)
+			}
+			if je.Command == "" {
+				var header struct {
+					Schema *int `json:"schema"`
+				}
+				if json.Unmarshal(line, &header) == nil && header.Schema != nil {
+					// Entries of older schemas only lack fields, the
+					// envelope of JSON exports is what changed
+					if *header.Schema < 1 || *header.Schema > ExportSchema {
+						return &SchemaError{Schema: *header.Schema}
+					}
+					continue
+				}
+			}
+			result.addJSON(inserter, je, opts)
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
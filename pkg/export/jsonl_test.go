@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportJSONL_RoundTrip(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	commands := []string{"git status", "for f in *.log; do\n  gzip $f\ndone", "echo → done"}
+	for i, command := range commands {
+		require.NoError(t, db.Insert(&storage.HistoryEntry{
+			Command:   command,
+			Timestamp: 1700000000 + int64(i)*60,
+			Cwd:       "/src",
+			Note:      fmt.Sprintf("note %d", i),
+			Hash:      storage.GenerateHash(command),
+		}))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatJSONL}))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4, "a header and one line per entry")
+	assert.Equal(t, `{"schema":3}`, lines[0])
+
+	format, reader, err := DetectFormat(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONL, format)
+
+	imported := testutil.NewTestDB(t)
+	defer imported.Close()
+	result, err := ImportWithOptions(imported, reader, format, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+
+	entries, err := imported.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		original := len(commands) - 1 - i
+		assert.Equal(t, commands[original], entry.Command)
+		assert.Equal(t, int64(1700000000)+int64(original)*60, entry.Timestamp)
+		assert.Equal(t, "/src", entry.Cwd)
+		assert.Equal(t, fmt.Sprintf("note %d", original), entry.Note)
+	}
+}
+
+func TestImportJSONL(t *testing.T) {
+	keepAll := ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}}
+
+	t.Run("without a header", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		defer db.Close()
+
+		input := "{\"command\":\"git pull\",\"timestamp\":1700000000}\n\n{\"command\":\"\"}\n{\"command\":\"make\",\"timestamp\":1700000060}"
+		result, err := ImportWithOptions(db, strings.NewReader(input), FormatJSONL, keepAll)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Imported, "blank lines and entries without a command skipped")
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		defer db.Close()
+
+		input := fmt.Sprintf("{\"schema\":%d}\n{\"command\":\"git pull\"}\n", ExportSchema+1)
+		_, err := ImportWithOptions(db, strings.NewReader(input), FormatJSONL, keepAll)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "upgrade fh")
+	})
+
+	t.Run("malformed line", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		defer db.Close()
+
+		input := "{\"schema\":2}\n{\"command\":\"git pull\"}\n{\"command\":\n"
+		result, err := ImportWithOptions(db, strings.NewReader(input), FormatJSONL, keepAll)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 3")
+		assert.Equal(t, 1, result.Imported, "lines before it are imported")
+	})
+}
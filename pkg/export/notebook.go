@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// notebook is the subset of the Jupyter notebook format (nbformat 4) that
+// exports write
+type notebook struct {
+	Cells         []any          `json:"cells"`
+	Metadata      map[string]any `json:"metadata"`
+	NBFormat      int            `json:"nbformat"`
+	NBFormatMinor int            `json:"nbformat_minor"`
+}
+
+type markdownCell struct {
+	CellType string         `json:"cell_type"`
+	Metadata map[string]any `json:"metadata"`
+	Source   []string       `json:"source"`
+}
+
+// codeCell is a cell that has never been run, so it has no execution count
+// (null) and no outputs
+type codeCell struct {
+	CellType       string         `json:"cell_type"`
+	ExecutionCount *int           `json:"execution_count"`
+	Metadata       map[string]any `json:"metadata"`
+	Outputs        []any          `json:"outputs"`
+	Source         []string       `json:"source"`
+}
+
+// exportNotebook exports entries oldest first as a Jupyter notebook, each
+// command in a %%bash cell after a markdown cell with when and where it ran
+func exportNotebook(entries []*storage.HistoryEntry, writer io.Writer) error {
+	nb := notebook{
+		Cells: []any{},
+		Metadata: map[string]any{
+			"kernelspec": map[string]any{
+				"display_name": "Python 3",
+				"language":     "python",
+				"name":         "python3",
+			},
+			"language_info": map[string]any{"name": "python"},
+		},
+		NBFormat:      4,
+		NBFormatMinor: 4,
+	}
+
+	// Query returns the most recent first, a notebook reads top to bottom
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		heading := "**" + time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04:05") + "**"
+		if entry.Cwd != "" {
+			heading += " in `" + entry.Cwd + "`"
+		}
+		if entry.ExitCode != 0 {
+			heading += fmt.Sprintf(" (exit %d)", entry.ExitCode)
+		}
+		if entry.Note != "" {
+			heading += "\n\n" + entry.Note
+		}
+
+		nb.Cells = append(nb.Cells,
+			markdownCell{
+				CellType: "markdown",
+				Metadata: map[string]any{},
+				Source:   sourceLines(heading),
+			},
+			codeCell{
+				CellType: "code",
+				Metadata: map[string]any{},
+				Outputs:  []any{},
+				Source:   sourceLines("%%bash\n" + entry.Command),
+			},
+		)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(nb); err != nil {
+		return fmt.Errorf("failed to encode notebook: %w", err)
+	}
+
+	return nil
+}
+
+// sourceLines splits text into notebook source lines, each keeping its
+// newline
+func sourceLines(text string) []string {
+	return strings.SplitAfter(text, "\n")
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportNotebook(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	base := time.Date(2025, 1, 7, 14, 0, 0, 0, time.Local).Unix()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{
+		Command:   "head data.csv",
+		Timestamp: base,
+		Cwd:       "/data",
+		Hash:      storage.GenerateHash("head data.csv"),
+	}))
+	require.NoError(t, db.Insert(&storage.HistoryEntry{
+		Command:   "wc -l data.csv\nsort data.csv | uniq -c",
+		Timestamp: base + 60,
+		ExitCode:  1,
+		Note:      "counts look off",
+		Hash:      storage.GenerateHash("wc"),
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatNotebook}))
+
+	var nb struct {
+		Cells []struct {
+			CellType       string          `json:"cell_type"`
+			ExecutionCount json.RawMessage `json:"execution_count"`
+			Outputs        []any           `json:"outputs"`
+			Source         []string        `json:"source"`
+		} `json:"cells"`
+		NBFormat int `json:"nbformat"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &nb))
+
+	assert.Equal(t, 4, nb.NBFormat)
+	require.Len(t, nb.Cells, 4)
+
+	// Oldest first, a markdown cell before each command
+	assert.Equal(t, "markdown", nb.Cells[0].CellType)
+	assert.Equal(t, []string{"**2025-01-07 14:00:00** in `/data`"}, nb.Cells[0].Source)
+	assert.Equal(t, "code", nb.Cells[1].CellType)
+	assert.Equal(t, []string{"%%bash\n", "head data.csv"}, nb.Cells[1].Source)
+	assert.Equal(t, "null", string(nb.Cells[1].ExecutionCount))
+	assert.NotNil(t, nb.Cells[1].Outputs)
+
+	assert.Equal(t, []string{"**2025-01-07 14:01:00** (exit 1)\n", "\n", "counts look off"}, nb.Cells[2].Source)
+	assert.Equal(t, []string{"%%bash\n", "wc -l data.csv\n", "sort data.csv | uniq -c"}, nb.Cells[3].Source)
+}
+
+func TestExportNotebook_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, exportNotebook(nil, &buf))
+	assert.Contains(t, buf.String(), `"cells": []`)
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// parquetRowGroup is how many entries go in one row group, so writing a
+// large history doesn't buffer all of it
+const parquetRowGroup = 100_000
+
+// parquetEntry is the Parquet schema of an export. Columns that repeat a
+// lot are dictionary encoded, which keeps the file small.
+type parquetEntry struct {
+	ID          int64     `parquet:"id"`
+	Timestamp   time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	ApproxTime  bool      `parquet:"approx_time"`
+	Command     string    `parquet:"command,dict"`
+	Program     string    `parquet:"program,dict"`
+	ExitCode    int32     `parquet:"exit_code"`
+	DurationMs  int64     `parquet:"duration_ms"`
+	Cwd         string    `parquet:"cwd,dict"`
+	GitBranch   string    `parquet:"git_branch,dict"`
+	Hostname    string    `parquet:"hostname,dict"`
+	User        string    `parquet:"user,dict"`
+	Shell       string    `parquet:"shell,dict"`
+	SessionID   string    `parquet:"session_id,dict"`
+	Profile     string    `parquet:"profile,dict"`
+	ExecRuntime string    `parquet:"exec_runtime,dict"`
+	ExecTarget  string    `parquet:"exec_target,dict"`
+	JobID       int64     `parquet:"job_id"`
+	Note        string    `parquet:"note"`
+}
+
+func newParquetEntry(entry *storage.HistoryEntry) parquetEntry {
+	return parquetEntry{
+		ID:          entry.ID,
+		Timestamp:   time.Unix(entry.Timestamp, 0).UTC(),
+		ApproxTime:  entry.ApproxTime,
+		Command:     entry.Command,
+		Program:     entry.Program,
+		ExitCode:    int32(entry.ExitCode),
+		DurationMs:  entry.DurationMs,
+		Cwd:         entry.Cwd,
+		GitBranch:   entry.GitBranch,
+		Hostname:    entry.Hostname,
+		User:        entry.User,
+		Shell:       entry.Shell,
+		SessionID:   entry.SessionID,
+		Profile:     entry.Profile,
+		ExecRuntime: entry.ExecRuntime,
+		ExecTarget:  entry.ExecTarget,
+		JobID:       entry.JobID,
+		Note:        entry.Note,
+	}
+}
+
+// exportParquet exports entries as a zstd compressed Parquet file, typed
+// so DuckDB and pandas read timestamps and numbers as such
+func exportParquet(entries []*storage.HistoryEntry, writer io.Writer) error {
+	pw := parquet.NewGenericWriter[parquetEntry](writer,
+		parquet.Compression(&parquet.Zstd),
+		parquet.CreatedBy("fh", "", ""),
+	)
+
+	rows := make([]parquetEntry, 0, min(len(entries), parquetRowGroup))
+	for start := 0; start < len(entries); start += parquetRowGroup {
+		rows = rows[:0]
+		for _, entry := range entries[start:min(start+parquetRowGroup, len(entries))] {
+			rows = append(rows, newParquetEntry(entry))
+		}
+		if _, err := pw.Write(rows); err != nil {
+			return fmt.Errorf("failed to write Parquet rows: %w", err)
+		}
+		if err := pw.Flush(); err != nil {
+			return fmt.Errorf("failed to write Parquet row group: %w", err)
+		}
+	}
+
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %w", err)
+	}
+	return nil
+}
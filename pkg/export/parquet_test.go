@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportParquet(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	base := time.Date(2025, 1, 7, 14, 0, 0, 0, time.UTC).Unix()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{
+		Command:    "make test",
+		Timestamp:  base,
+		ExitCode:   2,
+		DurationMs: 1500,
+		Cwd:        "/src",
+		Hash:       storage.GenerateHash("make test"),
+	}))
+	require.NoError(t, db.Insert(&storage.HistoryEntry{
+		Command:   "git push",
+		Timestamp: base + 60,
+		Note:      "release",
+		Hash:      storage.GenerateHash("git push"),
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatParquet}))
+
+	rows, err := parquet.Read[parquetEntry](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// Most recent first, like the other formats
+	assert.Equal(t, "git push", rows[0].Command)
+	assert.Equal(t, "git", rows[0].Program)
+	assert.Equal(t, "release", rows[0].Note)
+	assert.Equal(t, "make test", rows[1].Command)
+	assert.Equal(t, int32(2), rows[1].ExitCode)
+	assert.Equal(t, int64(1500), rows[1].DurationMs)
+	assert.True(t, rows[1].Timestamp.Equal(time.Unix(base, 0)))
+
+	// Timestamps are typed, not plain numbers
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	column, ok := file.Schema().Lookup("timestamp")
+	require.True(t, ok)
+	assert.NotNil(t, column.Node.Type().LogicalType().Timestamp)
+}
+
+func TestExportParquet_Empty(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatParquet}))
+
+	rows, err := parquet.Read[parquetEntry](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,162 @@
+package export
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripAlphabet mixes characters that tend to break serializers:
+// separators, quotes, escapes, newlines, and multi-byte unicode.
+// Carriage returns are excluded because encoding/csv normalizes \r\n to \n.
+var roundTripAlphabet = []rune("abcXYZ019 -_./~|;&$\"'`\\,\t\n│é中文😀🚀{}[]:=#%")
+
+// randomString returns a string of up to maxLen runes from roundTripAlphabet
+func randomString(r *rand.Rand, maxLen int) string {
+	n := r.Intn(maxLen + 1)
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = roundTripAlphabet[r.Intn(len(roundTripAlphabet))]
+	}
+	return string(runes)
+}
+
+// entryBatch is a set of arbitrary history entries for property tests
+type entryBatch []*storage.HistoryEntry
+
+// Generate implements quick.Generator
+func (entryBatch) Generate(r *rand.Rand, size int) reflect.Value {
+	n := 1 + r.Intn(size+1)
+	batch := make(entryBatch, n)
+	for i := range batch {
+		command := randomString(r, 40)
+		// Commands that are empty once trimmed are skipped by import by design
+		if strings.TrimSpace(command) == "" {
+			command = "cmd" + command
+		}
+		batch[i] = &storage.HistoryEntry{
+			Timestamp:  1 + r.Int63n(4_000_000_000),
+			Command:    command,
+			Cwd:        randomString(r, 20),
+			ExitCode:   r.Intn(512) - 256,
+			Hostname:   randomString(r, 10),
+			User:       randomString(r, 10),
+			Shell:      randomString(r, 5),
+			DurationMs: r.Int63n(10_000_000),
+			GitBranch:  randomString(r, 10),
+			SessionID:  randomString(r, 10),
+			// Empty profiles are stored as the default profile, so always set one
+			Profile:     "p" + randomString(r, 8),
+			ExecRuntime: randomString(r, 6),
+			ExecTarget:  randomString(r, 12),
+			Note:        randomString(r, 30),
+		}
+	}
+	return reflect.ValueOf(batch)
+}
+
+// comparableEntry strips fields that are not expected to survive a round trip
+// (the database assigns IDs and hashes, and derives the program on insert)
+func comparableEntry(entry *storage.HistoryEntry) storage.HistoryEntry {
+	copied := *entry
+	copied.ID = 0
+	copied.Hash = ""
+	copied.Program = ""
+	return copied
+}
+
+// sortedComparable returns entries in a deterministic order for comparison
+func sortedComparable(entries []*storage.HistoryEntry) []storage.HistoryEntry {
+	result := make([]storage.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = comparableEntry(entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Timestamp != result[j].Timestamp {
+			return result[i].Timestamp < result[j].Timestamp
+		}
+		return result[i].Command < result[j].Command
+	})
+	return result
+}
+
+// roundTrip exports batch in format from one database and imports it into another
+func roundTrip(t *testing.T, batch entryBatch, format Format) []*storage.HistoryEntry {
+	t.Helper()
+	keepAll := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+
+	src := testutil.NewTestDB(t)
+	defer src.Close()
+	for _, entry := range batch {
+		copied := *entry
+		require.NoError(t, src.InsertWithDedup(&copied, keepAll))
+		// The UUID given on insert must survive too
+		entry.UUID = copied.UUID
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(src, &buf, Options{Format: format}))
+
+	dst := testutil.NewTestDB(t)
+	defer dst.Close()
+	count, err := Import(dst, &buf, format, keepAll)
+	require.NoError(t, err)
+	require.Equal(t, len(batch), count)
+
+	imported, err := dst.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	return imported
+}
+
+func TestRoundTrip_Properties(t *testing.T) {
+	formats := []Format{FormatJSON, FormatJSONL, FormatCSV, FormatBinary}
+
+	for _, format := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			property := func(batch entryBatch) bool {
+				imported := roundTrip(t, batch, format)
+				return reflect.DeepEqual(sortedComparable(batch), sortedComparable(imported))
+			}
+
+			if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRoundTrip_TextPreservesCommands(t *testing.T) {
+	// Text only carries the command, and only single-line commands survive it
+	property := func(batch entryBatch) bool {
+		var expected []string
+		for _, entry := range batch {
+			entry.Command = strings.TrimSpace(strings.ReplaceAll(entry.Command, "\n", " "))
+			if entry.Command == "" {
+				entry.Command = "cmd"
+			}
+			expected = append(expected, entry.Command)
+		}
+
+		imported := roundTrip(t, batch, FormatText)
+		var got []string
+		for _, entry := range imported {
+			got = append(got, entry.Command)
+		}
+
+		sort.Strings(expected)
+		sort.Strings(got)
+		return reflect.DeepEqual(expected, got)
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
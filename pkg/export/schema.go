@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ExportSchema is the version of the layout of JSON exports, written as
+// their "schema" field. Bump it, with a shim in schemaShims, whenever an
+// export of the previous version would not import as is.
+//
+// Compatibility matrix:
+//
+//	schema  written by                layout
+//	1       fh before schema versions [entry, ...] without a schema field
+//	2       fh before entry UUIDs     {"schema": 2, "entries": [entry, ...]}
+//	3       fh since                  as 2, entries with a "uuid"
+//
+// Import reads every version up to ExportSchema, upgrading older exports
+// one version at a time. Exports of a newer version are refused with an
+// error saying which fh to upgrade to, rather than imported with fields
+// this version doesn't know dropped.
+const ExportSchema = 3
+
+// jsonExport is a JSON export of schema ExportSchema
+type jsonExport struct {
+	Schema  int         `json:"schema"`
+	Entries []jsonEntry `json:"entries"`
+}
+
+// schemaShims upgrade a JSON export of the schema version of the key to
+// the next version
+var schemaShims = map[int]func(data []byte) ([]byte, error){
+	1: upgradeSchema1,
+	2: upgradeSchema2,
+}
+
+// upgradeSchema1 puts the bare array of entries of schema 1 in the
+// envelope of schema 2
+func upgradeSchema1(data []byte) ([]byte, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return json.Marshal(map[string]any{"schema": 2, "entries": entries})
+}
+
+// upgradeSchema2 only changes the version: entries without a UUID are
+// matched by their command and time on import
+func upgradeSchema2(data []byte) ([]byte, error) {
+	var export map[string]json.RawMessage
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	export["schema"] = json.RawMessage("3")
+	return json.Marshal(export)
+}
+
+// SchemaError is returned for a JSON export of a schema version this fh
+// can't read
+type SchemaError struct {
+	Schema int
+}
+
+func (e *SchemaError) Error() string {
+	if e.Schema > ExportSchema {
+		return fmt.Sprintf("export has schema %d, this fh reads up to schema %d: upgrade fh on this machine to import it, or export it again as csv", e.Schema, ExportSchema)
+	}
+	return fmt.Sprintf("export has schema %d, which no fh writes: the file is not an fh export or is damaged", e.Schema)
+}
+
+// exportSchema returns the schema version of a JSON export
+func exportSchema(data []byte) (int, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, nil
+	}
+
+	var header struct {
+		Schema *int `json:"schema"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if header.Schema == nil {
+		return 0, fmt.Errorf("not an fh JSON export: no schema field and not a list of entries")
+	}
+	return *header.Schema, nil
+}
+
+// decodeJSONExport reads the entries of a JSON export of any schema
+// version up to ExportSchema
+func decodeJSONExport(r io.Reader) ([]jsonEntry, error) {
+	var data json.RawMessage
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	schema, err := exportSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	if schema < 1 || schema > ExportSchema {
+		return nil, &SchemaError{Schema: schema}
+	}
+
+	upgraded := []byte(data)
+	for version := schema; version < ExportSchema; version++ {
+		if upgraded, err = schemaShims[version](upgraded); err != nil {
+			return nil, fmt.Errorf("failed to upgrade export from schema %d: %w", version, err)
+		}
+	}
+
+	var export jsonExport
+	if err := json.Unmarshal(upgraded, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return export.Entries, nil
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaExports are an export of the same two entries as each schema
+// version wrote it
+var schemaExports = map[int]string{
+	1: `[
+		{"id": 7, "command": "make deploy", "timestamp": 1700000060, "exit_code": 2, "cwd": "/src", "note": "needs VPN"},
+		{"id": 3, "command": "git pull", "timestamp": 1700000000, "exit_code": 0, "cwd": "/src"}
+	]`,
+	2: `{"schema": 2, "entries": [
+		{"id": 7, "command": "make deploy", "timestamp": 1700000060, "exit_code": 2, "cwd": "/src", "note": "needs VPN"},
+		{"id": 3, "command": "git pull", "timestamp": 1700000000, "exit_code": 0, "cwd": "/src"}
+	]}`,
+	3: `{"schema": 3, "entries": [
+		{"id": 7, "command": "make deploy", "timestamp": 1700000060, "exit_code": 2, "cwd": "/src", "note": "needs VPN", "uuid": "0f8e1c52-7d3a-4b6e-9a41-52c7d0e3b9f4"},
+		{"id": 3, "command": "git pull", "timestamp": 1700000000, "exit_code": 0, "cwd": "/src", "uuid": "5b2d9e70-1c4f-4a8d-b3e6-7f0a1d2c8e95"}
+	]}`,
+}
+
+func TestSchemaShims_CoverEveryOlderVersion(t *testing.T) {
+	for version := 1; version < ExportSchema; version++ {
+		assert.Contains(t, schemaShims, version, "no shim upgrades schema %d", version)
+	}
+	for version := 1; version <= ExportSchema; version++ {
+		assert.Contains(t, schemaExports, version, "no test export of schema %d", version)
+	}
+}
+
+func TestImportJSON_EverySchema(t *testing.T) {
+	for version, input := range schemaExports {
+		t.Run(fmt.Sprintf("schema %d", version), func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+
+			schema, err := exportSchema([]byte(input))
+			require.NoError(t, err)
+			assert.Equal(t, version, schema)
+
+			result, err := ImportWithOptions(db, strings.NewReader(input), FormatJSON, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+			require.NoError(t, err)
+			assert.Equal(t, 2, result.Imported)
+
+			entries, err := db.Query(storage.QueryFilters{})
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "make deploy", entries[0].Command)
+			assert.Equal(t, int64(1700000060), entries[0].Timestamp)
+			assert.Equal(t, 2, entries[0].ExitCode)
+			assert.Equal(t, "/src", entries[0].Cwd)
+			assert.Equal(t, "needs VPN", entries[0].Note)
+			assert.Equal(t, "git pull", entries[1].Command)
+		})
+	}
+}
+
+func TestExportJSON_WritesCurrentSchema(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{Command: "git pull", Timestamp: 1700000000, Hash: storage.GenerateHash("git pull")}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatJSON}))
+	schema, err := exportSchema(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, ExportSchema, schema)
+}
+
+func TestImportJSON_UnreadableSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"newer", fmt.Sprintf(`{"schema": %d, "entries": []}`, ExportSchema+1), "upgrade fh"},
+		{"never written", `{"schema": 0, "entries": []}`, "not an fh export"},
+		{"no schema", `{"command": "git pull"}`, "no schema field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+
+			_, err := ImportWithOptions(db, strings.NewReader(tt.input), FormatJSON, ImportOptions{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// stateFile is where incremental exports are remembered, in ~/.fh
+const stateFile = "export.state"
+
+// State remembers how far each incremental export (--since last) got, so
+// the next run only writes the entries saved since. Exports are told apart
+// by StateKey.
+type State struct {
+	Exports map[string]StateEntry `json:"exports"`
+}
+
+// StateEntry is how far one incremental export got
+type StateEntry struct {
+	LastID     int64 `json:"last_id"` // Entries up to this ID have been exported
+	ExportedAt int64 `json:"exported_at"`
+}
+
+// DefaultStatePath returns the path of the export state (~/.fh/export.state)
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".fh", stateFile), nil
+}
+
+// StateKey identifies an incremental export: the database it reads and
+// what it writes of it. Exports with different filters or formats keep
+// their own place, so cron jobs writing different backups don't skip each
+// other's entries.
+func StateKey(dbPath string, format Format, filters map[string]string) string {
+	values := url.Values{"format": {string(format)}}
+	for name, value := range filters {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return dbPath + "?" + values.Encode()
+}
+
+// LoadState reads the export state, empty when there is none yet
+func LoadState(path string) (*State, error) {
+	state := &State{Exports: make(map[string]StateEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read export state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse export state %s: %w (remove it to export everything again)", path, err)
+	}
+	if state.Exports == nil {
+		state.Exports = make(map[string]StateEntry)
+	}
+	return state, nil
+}
+
+// Save writes the export state, replacing the file at once so a crash
+// can't leave it half written
+func (s *State) Save(path string) error {
+	// Keys are URLs, written with & rather than \u0026
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode export state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write export state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".fh", stateFile)
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Empty(t, state.Exports, "no state yet")
+
+	key := StateKey("/home/me/.fh/history.db", FormatJSONL, map[string]string{"profile": "work"})
+	state.Exports[key] = StateEntry{LastID: 42, ExportedAt: 1700000000}
+	require.NoError(t, state.Save(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, state.Exports, loaded.Exports)
+
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+	_, err = LoadState(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remove it")
+}
+
+func TestStateKey(t *testing.T) {
+	db := "/home/me/.fh/history.db"
+	base := StateKey(db, FormatJSON, map[string]string{"profile": "work", "search": ""})
+
+	assert.Equal(t, "/home/me/.fh/history.db?format=json&profile=work", base)
+	assert.Equal(t, base, StateKey(db, FormatJSON, map[string]string{"profile": "work"}), "empty filters don't count")
+	assert.NotEqual(t, base, StateKey(db, FormatCSV, map[string]string{"profile": "work"}))
+	assert.NotEqual(t, base, StateKey(db, FormatJSON, map[string]string{"profile": "work", "search": "git"}))
+	assert.NotEqual(t, base, StateKey("/other.db", FormatJSON, map[string]string{"profile": "work"}))
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spideyz0r/fh/pkg/importer"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// exportZsh exports entries oldest first as a zsh extended_history file,
+// : <timestamp>:<duration>;<command> lines zsh can read back with fc -R
+func exportZsh(entries []*storage.HistoryEntry, writer io.Writer) error {
+	// Query returns the most recent first, history files end with it
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		line := importer.FormatZshLine(entry.Timestamp, entry.DurationMs/1000, entry.Command)
+		if _, err := io.WriteString(writer, line+"\n"); err != nil {
+			return fmt.Errorf("failed to write entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// importZsh imports from a zsh history file, extended_history or not
+func importZsh(inserter *storage.BatchInserter, r io.Reader, opts ImportOptions, result *ImportResult) error {
+	entries, err := importer.ParseZshHistoryReader(r)
+	if err != nil {
+		return err
+	}
+
+	for _, zshEntry := range entries {
+		entry := &storage.HistoryEntry{
+			Command:    zshEntry.Command,
+			Timestamp:  zshEntry.Timestamp,
+			DurationMs: zshEntry.Duration * 1000,
+			Shell:      "zsh",
+		}
+		result.add(inserter, entry, opts)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportZsh_RoundTrip(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	commands := []string{"git status", "for f in *.log; do\n  gzip $f\ndone", "echo → done"}
+	for i, command := range commands {
+		require.NoError(t, db.Insert(&storage.HistoryEntry{
+			Command:    command,
+			Timestamp:  1700000000 + int64(i)*60,
+			DurationMs: int64(i) * 1500,
+			Hash:       storage.GenerateHash(command),
+		}))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(db, &buf, Options{Format: FormatZsh}))
+	lines := buf.String()
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte(": 1700000000:0;git status\n")), "oldest first")
+	assert.Contains(t, lines, ": 1700000060:1;for f in *.log; do\\\n  gzip $f\\\ndone\n")
+	assert.NotContains(t, lines, "→", "metafied like zsh writes it")
+
+	format, reader, err := DetectFormat(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatZsh, format)
+
+	imported, err := storage.Open(tempDir + "/imported.db")
+	require.NoError(t, err)
+	defer imported.Close()
+
+	result, err := ImportWithOptions(imported, reader, format, ImportOptions{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+
+	entries, err := imported.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		original := commands[len(commands)-1-i]
+		assert.Equal(t, original, entry.Command)
+		assert.Equal(t, "zsh", entry.Shell)
+	}
+	assert.Equal(t, int64(1700000060), entries[1].Timestamp)
+	assert.Equal(t, int64(1000), entries[1].DurationMs, "zsh keeps whole seconds")
+}
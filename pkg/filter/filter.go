@@ -0,0 +1,90 @@
+// Package filter decides which commands are left out of the history: those
+// matching an ignore pattern, running a denied program or not starting with
+// an allowed one. Saving and importing share it, so both leave out the same
+// commands.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+)
+
+// Rules are the ignore settings of the config
+type Rules struct {
+	Patterns []string // Regexes of commands to leave out
+	Deny     []string // Programs never saved, anywhere in the command
+	Allow    []string // Only commands starting with these programs are saved (empty = all)
+}
+
+// Filter matches commands against Rules compiled once, for checking many
+// commands. It is safe for concurrent use.
+type Filter struct {
+	patterns []*regexp.Regexp
+	deny     []string
+	allow    []string
+}
+
+// New compiles rules into a Filter
+func New(rules Rules) (*Filter, error) {
+	f := &Filter{deny: rules.Deny, allow: rules.Allow}
+	for _, pattern := range rules.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// Matches returns the patterns that match command, in order, followed by
+// the program rules that reject it as "deny:<program>" or
+// "allow:<program>". A command matched by any of them is not saved.
+func (f *Filter) Matches(command string) []string {
+	var matches []string
+	for _, re := range f.patterns {
+		if re.MatchString(command) {
+			matches = append(matches, re.String())
+		}
+	}
+	return append(matches, f.programMatches(command)...)
+}
+
+// Ignored reports whether command is left out
+func (f *Filter) Ignored(command string) bool {
+	return len(f.Matches(command)) > 0
+}
+
+// programMatches returns the deny and allow rules that reject command. A
+// denied program is caught anywhere in a pipeline or list, as database
+// clients often get credentials in their arguments; the allow list only
+// looks at the program the command starts with.
+func (f *Filter) programMatches(command string) []string {
+	if len(f.deny) == 0 && len(f.allow) == 0 {
+		return nil
+	}
+
+	var matches []string
+	programs := shellparse.Programs(command)
+	for _, program := range programs {
+		for _, denied := range f.deny {
+			if program == denied {
+				matches = append(matches, "deny:"+program)
+			}
+		}
+	}
+	if len(f.allow) > 0 && len(programs) > 0 {
+		allowed := false
+		for _, program := range f.allow {
+			if programs[0] == program {
+				allowed = true
+			}
+		}
+		if !allowed {
+			matches = append(matches, "allow:"+programs[0])
+		}
+	}
+	return matches
+}
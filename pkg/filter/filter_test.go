@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	f, err := New(Rules{Patterns: config.Default().Ignore.Patterns})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"^ls "}, f.Matches("ls -la"))
+	assert.Equal(t, []string{"^cd$"}, f.Matches("cd"))
+	assert.Empty(t, f.Matches("git status"))
+	assert.Empty(t, f.Matches("echo ls"))
+
+	f, err = New(Rules{Patterns: []string{"^git ", "status$"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"^git ", "status$"}, f.Matches("git status"))
+
+	_, err = New(Rules{Patterns: []string{"[invalid"}})
+	assert.ErrorContains(t, err, `invalid ignore pattern "[invalid"`)
+}
+
+func TestMatches_Programs(t *testing.T) {
+	rules := Rules{Patterns: config.Default().Ignore.Patterns, Deny: []string{"mysql", "vault"}}
+	f, err := New(rules)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deny:mysql"}, f.Matches("mysql -u root -ps3cret"))
+	assert.Equal(t, []string{"deny:mysql"}, f.Matches("MYSQL_PWD=s3cret /usr/bin/mysql prod"))
+	assert.Equal(t, []string{"^cd ", "deny:vault"}, f.Matches("cd infra && vault login -method=userpass password=x"))
+	assert.Empty(t, f.Matches("man mysql"))
+	assert.True(t, f.Ignored("sudo mysql"))
+
+	rules.Allow = []string{"git", "make"}
+	f, err = New(rules)
+	require.NoError(t, err)
+	assert.Empty(t, f.Matches("git status | less"))
+	assert.Equal(t, []string{"allow:docker"}, f.Matches("docker ps"))
+	assert.True(t, f.Ignored("docker ps"))
+	assert.False(t, f.Ignored("make test"))
+}
+
+func TestIgnored(t *testing.T) {
+	f, err := New(Rules{Patterns: config.Default().Ignore.Patterns})
+	require.NoError(t, err)
+	assert.True(t, f.Ignored("ls -la"))
+	assert.True(t, f.Ignored("cd"))
+	assert.False(t, f.Ignored("git status"))
+
+	f, err = New(Rules{})
+	require.NoError(t, err)
+	assert.False(t, f.Ignored("ls"))
+}
@@ -0,0 +1,264 @@
+package i18n
+
+// spanish holds the Spanish translations, keyed by English format string
+var spanish = map[string]string{
+	// Usage errors
+	"Error: usage: fh --amend --id <id> [flags] or fh --amend [flags] <session> <job>\n":    "Error: uso: fh --amend --id <id> [opciones] o fh --amend [opciones] <sesión> <trabajo>\n",
+	"✓ Imported %d commands from %d macOS Terminal sessions":                                "✓ Importados %d comandos de %d sesiones de Terminal de macOS",
+	"Error importing sessions: %v\n":                                                        "Error al importar las sesiones: %v\n",
+	"No macOS Terminal session history in ~/.bash_sessions or ~/.zsh_sessions.\n":           "No hay historial de sesiones de Terminal de macOS en ~/.bash_sessions ni en ~/.zsh_sessions.\n",
+	"Warning: Could not import macOS Terminal sessions: %v\n":                               "Advertencia: no se pudieron importar las sesiones de Terminal de macOS: %v\n",
+	"Restored %d notes onto commands already in history\n":                                  "Restauradas %d notas en comandos que ya estaban en el historial\n",
+	"Error: --since only takes last, use --search since:3d for a time window\n":             "Error: --since solo admite last, usa --search since:3d para una ventana de tiempo\n",
+	"Error: --since last exports every new entry and can't be combined with --limit\n":      "Error: --since last exporta todas las entradas nuevas y no se puede combinar con --limit\n",
+	"Nothing new since the last export\n":                                                   "Nada nuevo desde la última exportación\n",
+	"Error warming up database: %v\n":                                                       "Error al precargar la base de datos: %v\n",
+	"Error parsing variants flags: %v\n":                                                    "Error al analizar las opciones de variants: %v\n",
+	"No commands differing in a single word in history.\n":                                  "No hay comandos en el historial que difieran en una sola palabra.\n",
+	"No variants of %q in history.\n":                                                       "No hay variantes de %q en el historial.\n",
+	"Error parsing remind flags: %v\n":                                                      "Error al analizar las opciones de remind: %v\n",
+	"Nothing to remind of: no command run at least %d times has gone unused for %d days.\n": "Nada que recordar: ningún comando ejecutado al menos %d veces lleva %d días sin usarse.\n",
+	"Command of the day, run %d times, last on %s:\n":                                       "Comando del día, ejecutado %d veces, la última el %s:\n",
+	"Error parsing prev flags: %v\n":                                                        "Error al analizar las opciones de prev: %v\n",
+	"Error: no session, run fh --prev from a shell with the fh hook or pass --session\n":    "Error: no hay sesión, ejecuta fh --prev desde un shell con el hook de fh o pasa --session\n",
+	"Error: --index must be 1 or more\n":                                                    "Error: --index debe ser 1 o más\n",
+	"Error reading session: %v\n":                                                           "Error al leer la sesión: %v\n",
+	"Error: usage: fh --show <id> [--json]\n":                                               "Error: uso: fh --show <id> [--json]\n",
+	"Error: usage: fh --related <id> [--window 5m] [--limit 20]\n":                          "Error: uso: fh --related <id> [--window 5m] [--limit 20]\n",
+	"Error: usage: fh --apply [--dry-run] [setup-file]\n":                                   "Error: uso: fh --apply [--dry-run] [archivo-de-configuración]\n",
+	"Error: usage: fh --bundle export <file> or fh --bundle import <file>\n":                "Error: uso: fh --bundle export <archivo> o fh --bundle import <archivo>\n",
+	"Error: invalid job id: %s\n":                                                           "Error: id de trabajo no válido: %s\n",
+	"Error: invalid entry id: %s\n":                                                         "Error: id de entrada no válido: %s\n",
+	"Error: command required for --test-ignore\n":                                           "Error: --test-ignore requiere un comando\n",
+	"Error: usage: fh ignore test <cmd>\n":                                                  "Error: uso: fh ignore test <comando>\n",
+	"Error: container or pod name required for --container\n":                               "Error: --container requiere el nombre de un contenedor o pod\n",
+	"Error: program name required for --program\n":                                          "Error: --program requiere el nombre de un programa\n",
+	"Error: command required for --history-of\n":                                            "Error: --history-of requiere un comando\n",
+	"Error: query required for --ask\n":                                                     "Error: --ask requiere una consulta\n",
+	"Error: --cmd is required\n":                                                            "Error: --cmd es obligatorio\n",
+	"Copied to the tmux buffer\n":                                                           "Copiado al búfer de tmux\n",
+	"Sent to tmux pane %s\n":                                                                "Enviado al panel de tmux %s\n",
+	"Copied to clipboard (%s)\n":                                                            "Copiado al portapapeles (%s)\n",
+	"Error: --print0 and --quote only apply to --format text\n":                             "Error: --print0 y --quote solo se aplican a --format text\n",
+	"Error: --format %s is binary, write it to a file with --output\n":                      "Error: --format %s es binario, escríbelo en un archivo con --output\n",
+	"Error: --from is required\n":                                                           "Error: --from es obligatorio\n",
+	"Error: --to is before --from\n":                                                        "Error: --to es anterior a --from\n",
+	"Error: --profile takes a single profile name\n":                                        "Error: --profile acepta un único nombre de perfil\n",
+	"Error: no destination host in ssh arguments\n":                                         "Error: no hay host de destino en los argumentos de ssh\n",
+	"Error: AI search is disabled in configuration\n":                                       "Error: la búsqueda con IA está desactivada en la configuración\n",
+	"Error: %v\n":                       "Error: %v\n",
+	"Error parsing save flags: %v\n":    "Error al leer las opciones de save: %v\n",
+	"Error parsing amend flags: %v\n":   "Error al leer las opciones de amend: %v\n",
+	"Error parsing apply flags: %v\n":   "Error al leer las opciones de apply: %v\n",
+	"Error parsing stats flags: %v\n":   "Error al leer las opciones de stats: %v\n",
+	"Error parsing recount flags: %v\n": "Error al leer las opciones de recount: %v\n",
+	"Error parsing archive flags: %v\n": "Error al leer las opciones de archive: %v\n",
+	"Error parsing prune flags: %v\n":   "Error al leer las opciones de prune: %v\n",
+	"Error: no retention configured, set retention.max_age_days, retention.max_entries or retention.prune_failed_after_days\n": "Error: no hay retención configurada, define retention.max_age_days, retention.max_entries o retention.prune_failed_after_days\n",
+	"Error pruning: %v\n":                                                "Error al podar: %v\n",
+	"  %d failed entries older than %d days\n":                           "  %d entradas fallidas de hace más de %d días\n",
+	"  %d entries older than %d days\n":                                  "  %d entradas de hace más de %d días\n",
+	"  %d entries beyond the %d most recent\n":                           "  %d entradas más allá de las %d más recientes\n",
+	"Would prune %d entries\n":                                           "Se podarían %d entradas\n",
+	"Pruned %d entries\n":                                                "Podadas %d entradas\n",
+	"WARNING: this history database is also used by %s, last seen %s.\n": "AVISO: esta base de datos del historial también la usa %s, visto por última vez el %s.\n",
+	"Writing one database file from several machines, e.g. through a sync service, corrupts it.\n": "Escribir un mismo archivo de base de datos desde varias máquinas, p. ej. con un servicio de sincronización, lo corrompe.\n",
+	"Give each machine its own database and merge them instead:\n":                                 "Dale a cada máquina su propia base de datos y combínalas:\n",
+	"  on %s:  fh --export --format json --output %s.json\n":                                       "  en %s:  fh --export --format json --output %s.json\n",
+	"  here:    fh --import --input %s.json\n":                                                     "  aquí:    fh --import --input %s.json\n",
+	"If %s no longer uses this file: fh --hosts forget %s\n":                                       "Si %s ya no usa este archivo: fh --hosts forget %s\n",
+	"Error: usage: fh --hosts [forget <hostname|id>]\n":                                            "Error: uso: fh --hosts [forget <máquina|id>]\n",
+	"No host %s\n": "No hay ninguna máquina %s\n",
+	"Forgot %s\n":  "Olvidada %s\n",
+	"Error: usage: fh --snapshot [list | create <name> | backup | restore <name> | delete <name>]\n": "Error: uso: fh --snapshot [list | create <nombre> | backup | restore <nombre> | delete <nombre>]\n",
+	"Created snapshot %s (%s)\n": "Instantánea %s creada (%s)\n",
+	"Restored snapshot %s\n":     "Instantánea %s restaurada\n",
+	"Deleted snapshot %s\n":      "Instantánea %s eliminada\n",
+	"Error: automatic snapshots are off, set storage.auto_snapshots\n": "Error: las instantáneas automáticas están desactivadas, define storage.auto_snapshots\n",
+	"Error: unknown job %q (must be %s)\n":                             "Error: tarea desconocida %q (debe ser %s)\n",
+	"No %s timer installed\n":                                          "No hay ningún temporizador %s instalado\n",
+	"Removed the %s timer\n":                                           "Temporizador %s eliminado\n",
+	"Error finding the fh binary: %v\n":                                "Error al buscar el binario de fh: %v\n",
+	"✓ Wrote %s\n":                                                     "✓ Escrito %s\n",
+	"✓ %s runs %s\n":                                                   "✓ %s ejecuta %s\n",
+	"Error running %s: %v\n":                                           "Error al ejecutar %s: %v\n",
+	"Run it yourself:\n":                                               "Ejecútalo tú mismo:\n",
+	"Error parsing install-timer flags: %v\n":                          "Error al leer las opciones de install-timer: %v\n",
+	"Error: usage: fh --install-timer archive|backup|prune|sync [--print] [--remove]\n":                      "Error: uso: fh --install-timer archive|backup|prune|sync [--print] [--remove]\n",
+	"Error: usage: fh --run-job archive|backup|prune|sync\n":                                                 "Error: uso: fh --run-job archive|backup|prune|sync\n",
+	"Error parsing verify flags: %v\n":                                                                       "Error al analizar las opciones de verify: %v\n",
+	"Error: usage: fh --verify [--profile name]\n":                                                           "Error: uso: fh --verify [--profile nombre]\n",
+	"Error: entry signing is off, set signing.enabled in the config\n":                                       "Error: la firma de entradas está desactivada, configure signing.enabled\n",
+	"Checked %d entries: %d unsigned, %d changed outside fh\n":                                               "%d entradas comprobadas: %d sin firma, %d modificadas fuera de fh\n",
+	"Error parsing security-scan flags: %v\n":                                                                "Error al analizar las opciones de security-scan: %v\n",
+	"Error: usage: fh --security-scan [--min-risk low|medium|high] [--redact | --delete] [--profile name]\n": "Error: uso: fh --security-scan [--min-risk low|medium|high] [--redact | --delete] [--profile nombre]\n",
+	"Error: invalid --min-risk %q (must be low, medium or high)\n":                                           "Error: --min-risk %q no válido (debe ser low, medium o high)\n",
+	"Error: --redact and --delete cannot be combined\n":                                                      "Error: --redact y --delete no se pueden combinar\n",
+	"Error: --json and --pick cannot be combined\n":                                                          "Error: --json y --pick no se pueden combinar\n",
+	"No credential-like commands found in %d entries.\n":                                                     "No se encontraron comandos con credenciales en %d entradas.\n",
+	"High risk (%d)\n":   "Riesgo alto (%d)\n",
+	"Medium risk (%d)\n": "Riesgo medio (%d)\n",
+	"Low risk (%d)\n":    "Riesgo bajo (%d)\n",
+	"Mask the findings (r), delete the %d entries (d) or leave them (N)? ": "¿Enmascarar los hallazgos (r), borrar las %d entradas (d) o dejarlas (N)? ",
+	"Masked %d entries\n":              "%d entradas enmascaradas\n",
+	"Deleted %d entries\n":             "%d entradas borradas\n",
+	"Error parsing delete flags: %v\n": "Error al leer las opciones de delete: %v\n",
+	"Error: give at least one of --search, --before, --cwd or --exit-code\n": "Error: indica al menos uno de --search, --before, --cwd o --exit-code\n",
+	"Error: invalid --exit-code %q\n":                                        "Error: --exit-code %q no es válido\n",
+	"Would delete %d entries\n":                                              "Se borrarían %d entradas\n",
+	"Error: request required for --ask-delete\n":                             "Error: --ask-delete necesita una petición\n",
+	"Query: %s\n":         "Consulta: %s\n",
+	"  ... and %d more\n": "  ... y %d más\n",
+	"Nothing deleted, pass --yes to delete these %d entries without asking\n":               "No se borró nada, usa --yes para borrar estas %d entradas sin preguntar\n",
+	"Delete these %d entries? [y/N] ":                                                       "¿Borrar estas %d entradas? [y/N] ",
+	"Nothing deleted\n":                                                                     "No se borró nada\n",
+	"Snapshots, archives and mirrors still have the old commands, see fh --snapshot list\n": "Las instantáneas, archivos y espejos aún tienen los comandos antiguos, ver fh --snapshot list\n",
+	"Error: usage: fh --sink [status | flush]\n":                                            "Error: uso: fh --sink [status | flush]\n",
+	"Error: no sink configured, set sink.url in the config\n":                               "Error: no hay ningún destino configurado, define sink.url en la configuración\n",
+	"Sent %d entries to %s\n":                                                               "%d entradas enviadas a %s\n",
+	"Sink:":                                                                                 "Destino:",
+	"Sent up to:":                                                                           "Enviado hasta:",
+	"Pending:":                                                                              "Pendientes:",
+	"Last attempt:":                                                                         "Último intento:",
+	"Last error:":                                                                           "Último error:",
+	"Error parsing mirror flags: %v\n":                                                      "Error al leer las opciones de mirror: %v\n",
+	"Error: usage: fh --mirror <path> [--every 5m] [--profile name]\n": "Error: uso: fh --mirror <ruta> [--every 5m] [--profile nombre]\n",
+	"Error: --every cannot be negative\n":                              "Error: --every no puede ser negativo\n",
+	"Error mirroring: %v\n":                                            "Error al copiar: %v\n",
+	"Mirrored to %s\n":                                                 "Copiado a %s\n",
+	"%s is up to date\n":                                               "%s está al día\n",
+	"Error parsing daemon flags: %v\n":                                 "Error al leer las opciones de daemon: %v\n",
+	"Error parsing ai-eval flags: %v\n":                                "Error al leer las opciones de ai-eval: %v\n",
+	"Evaluating %s\n":                                                  "Evaluando %s\n",
+	"PASS  %s\n":                                                       "OK    %s\n",
+	"FAIL  %s\n":                                                       "FALLO %s\n",
+	"      SQL: %s\n":                                                  "      SQL: %s\n",
+	"\n%d of %d passed\n":                                              "\n%d de %d correctas\n",
+	"Error: --interval must be positive\n":                             "Error: --interval debe ser positivo\n",
+	"Error: an ephemeral session has no database to keep open\n":       "Error: una sesión efímera no tiene base de datos que mantener abierta\n",
+	"Saving to %s, listening on %s\n":                                  "Guardando en %s, escuchando en %s\n",
+	"No snapshots\n":                                                   "No hay instantáneas\n",
+	"Error: %v (set storage.auto_snapshots: 0 to go on without one)\n":                         "Error: %v (configura storage.auto_snapshots: 0 para seguir sin ella)\n",
+	"Took snapshot %s, undo with: fh --snapshot restore %s\n":                                  "Instantánea %s tomada, para deshacer: fh --snapshot restore %s\n",
+	"Error: --years must be at least 1\n":                                                      "Error: --years debe ser al menos 1\n",
+	"Archived %d entries from %d to %s\n":                                                      "Archivadas %d entradas de %d en %s\n",
+	"Error archiving: %v\n":                                                                    "Error al archivar: %v\n",
+	"No entries older than %d years\n":                                                         "No hay entradas de hace más de %d años\n",
+	"Error opening archives: %v\n":                                                             "Error al abrir los archivos: %v\n",
+	"Error closing archives: %v\n":                                                             "Error al cerrar los archivos: %v\n",
+	"Error: --fast cannot be combined with --program, --compare or --include-archives\n":       "Error: --fast no se puede combinar con --program, --compare ni --include-archives\n",
+	"Recounted %d entries\n":                                                                   "Recontadas %d entradas\n",
+	"Error parsing dashboard flags: %v\n":                                                      "Error al leer las opciones de dashboard: %v\n",
+	"Error parsing history-of flags: %v\n":                                                     "Error al leer las opciones de history-of: %v\n",
+	"Error parsing show flags: %v\n":                                                           "Error al leer las opciones de show: %v\n",
+	"Error parsing related flags: %v\n":                                                        "Error al leer las opciones de related: %v\n",
+	"Error parsing runbook flags: %v\n":                                                        "Error al leer las opciones de runbook: %v\n",
+	"Error parsing ignored flags: %v\n":                                                        "Error al leer las opciones de ignored: %v\n",
+	"Error parsing top flags: %v\n":                                                            "Error al leer las opciones de top: %v\n",
+	"Error parsing export flags: %v\n":                                                         "Error al leer las opciones de export: %v\n",
+	"Error parsing import flags: %v\n":                                                         "Error al leer las opciones de import: %v\n",
+	"Enable it in ~/.fh/config.yaml and set the API key of ai.provider, e.g. OPENAI_API_KEY\n": "Actívela en ~/.fh/config.yaml y defina la clave de API de ai.provider, p. ej. OPENAI_API_KEY\n",
+
+	// Runtime errors
+	"Error loading config: %v\n":                      "Error al cargar la configuración: %v\n",
+	"Error saving config: %v\n":                       "Error al guardar la configuración: %v\n",
+	"Error applying %s: %v\n":                         "Error al aplicar %s: %v\n",
+	"Error opening database: %v\n":                    "Error al abrir la base de datos: %v\n",
+	"Error closing database: %v\n":                    "Error al cerrar la base de datos: %v\n",
+	"Error initializing database: %v\n":               "Error al inicializar la base de datos: %v\n",
+	"Error finding entry %d: %v\n":                    "Error al buscar la entrada %d: %v\n",
+	"Error finding job %d in session %s: %v\n":        "Error al buscar el trabajo %d en la sesión %s: %v\n",
+	"Error amending entry: %v\n":                      "Error al modificar la entrada: %v\n",
+	"Error loading session: %v\n":                     "Error al cargar la sesión: %v\n",
+	"Error loading amendments: %v\n":                  "Error al cargar las modificaciones: %v\n",
+	"Error getting RC file: %v\n":                     "Error al obtener el archivo RC: %v\n",
+	"Error checking hooks: %v\n":                      "Error al comprobar los hooks: %v\n",
+	"Error installing %s hooks: %v\n":                 "Error al instalar los hooks de %s: %v\n",
+	"Error installing hooks: %v\n":                    "Error al instalar los hooks: %v\n",
+	"Error logging ignored command: %v\n":             "Error al registrar el comando ignorado: %v\n",
+	"Error querying ignored commands: %v\n":           "Error al consultar los comandos ignorados: %v\n",
+	"Error finding related commands: %v\n":            "Error al buscar comandos relacionados: %v\n",
+	"Error collecting metadata: %v\n":                 "Error al recopilar los metadatos: %v\n",
+	"Error saving command: %v\n":                      "Error al guardar el comando: %v\n",
+	"Error checking recent saves: %v\n":               "Error al comprobar los guardados recientes: %v\n",
+	"Error searching history: %v\n":                   "Error al buscar en el historial: %v\n",
+	"Error querying history: %v\n":                    "Error al consultar el historial: %v\n",
+	"Error getting home directory: %v\n":              "Error al obtener el directorio personal: %v\n",
+	"Error creating .fh directory: %v\n":              "Error al crear el directorio .fh: %v\n",
+	"Error detecting shell: %v\n":                     "Error al detectar el shell: %v\n",
+	"Error collecting statistics: %v\n":               "Error al recopilar las estadísticas: %v\n",
+	"Error creating output file: %v\n":                "Error al crear el archivo de salida: %v\n",
+	"Error exporting: %v\n":                           "Error al exportar: %v\n",
+	"Error opening input file: %v\n":                  "Error al abrir el archivo de entrada: %v\n",
+	"Error closing input file: %v\n":                  "Error al cerrar el archivo de entrada: %v\n",
+	"Error importing: %v\n":                           "Error al importar: %v\n",
+	"Error reading terminal stream: %v\n":             "Error al leer la salida de la terminal: %v\n",
+	"Error creating typescript file: %v\n":            "Error al crear el archivo de transcripción: %v\n",
+	"Error running ssh: %v\n":                         "Error al ejecutar ssh: %v\n",
+	"Warning: Could not import history: %v\n":         "Aviso: no se pudo importar el historial: %v\n",
+	"Warning: %v\n":                                   "Aviso: %v\n",
+	"Warning: the hooks keep using fh --save: %v\n":   "Aviso: los hooks siguen usando fh --save: %v\n",
+	"\nPlease set your SHELL environment variable.\n": "\nDefina la variable de entorno SHELL.\n",
+
+	// Amend
+	"    note: %s\n":      "    nota: %s\n",
+	"    no amendments\n": "    sin modificaciones\n",
+
+	// Apply
+	"would change ":                                    "cambiaría ",
+	"Nothing to change\n":                              "Nada que cambiar\n",
+	"would install %s hooks in %s\n":                   "instalaría los hooks de %s en %s\n",
+	"installed %s hooks in %s (backup: %s)\n":          "hooks de %s instalados en %s (copia de seguridad: %s)\n",
+	"updated %s keybinding to %s in %s (backup: %s)\n": "atajo de %s cambiado a %s en %s (copia de seguridad: %s)\n",
+
+	// Bundle
+	"Bundled %d commands from %d databases and the config to %s\n": "%d comandos de %d bases de datos y la configuración empaquetados en %s\n",
+	"Imported %d commands into %s\n":                               "%d comandos importados en %s\n",
+	"Restored config to %s\n":                                      "Configuración restaurada en %s\n",
+
+	// Ignore
+	"%q would be saved, no ignore pattern matches\n": "%q se guardaría, ningún patrón de exclusión coincide\n",
+	"%q would be ignored, matched by:\n":             "%q se ignoraría, coincide con:\n",
+	"No commands ignored in the last %s.\n":          "Ningún comando ignorado en los últimos %s.\n",
+
+	// Search and top
+	"No history entries found\n":    "No se encontraron entradas en el historial\n",
+	"No commands in history yet.\n": "Todavía no hay comandos en el historial.\n",
+	"No runs of %q in history.\n":   "%q no aparece en el historial.\n",
+	"No commands related to %q.\n":  "Ningún comando relacionado con %q.\n",
+
+	// Init
+	"fh - Fast History Setup":                                                  "fh - Configuración de Fast History",
+	"✓ Created directory: %s\n":                                                "✓ Directorio creado: %s\n",
+	"✓ Initialized database: %s\n":                                             "✓ Base de datos inicializada: %s\n",
+	"✓ Created config file: %s\n":                                              "✓ Archivo de configuración creado: %s\n",
+	"✓ Config file already exists: %s\n":                                       "✓ El archivo de configuración ya existe: %s\n",
+	"✓ Detected shell: %s\n":                                                   "✓ Shell detectado: %s\n",
+	"✓ Installed shell hooks (backup: %s)\n":                                   "✓ Hooks del shell instalados (copia de seguridad: %s)\n",
+	"✓ Shell hooks already installed (updated keybinding to %s, backup: %s)\n": "✓ Los hooks del shell ya estaban instalados (atajo cambiado a %s, copia de seguridad: %s)\n",
+	"✓ Shell hooks already installed\n":                                        "✓ Los hooks del shell ya estaban instalados\n",
+	"You can manually import later with: fh --import --input ~/.%s_history\n":  "Puede importarlo más tarde con: fh --import --input ~/.%s_history\n",
+	"✓ Imported %d commands":                                                   "✓ %d comandos importados",
+	" (left out %d matching ignore patterns)":                                  " (%d omitidos por patrones de ignorar)",
+	" (repaired %d that were not valid UTF-8)":                                 " (%d reparados por no ser UTF-8 válido)",
+	" (skipped %d due to errors)":                                              " (%d omitidos por errores)",
+	"✓ No commands to import (history file empty or already imported)\n":       "✓ No hay comandos para importar (historial vacío o ya importado)\n",
+	"SUCCESS! Restart your shell and press Ctrl-R to search.":                  "¡LISTO! Reinicie el shell y pulse Ctrl-R para buscar.",
+
+	// Export and import
+	"Enter passphrase for encryption: ": "Introduzca la frase de contraseña para cifrar: ",
+	"Confirm passphrase: ":              "Confirme la frase de contraseña: ",
+	"Enter passphrase to decrypt: ":     "Introduzca la frase de contraseña para descifrar: ",
+	"Exported and encrypted to %s\n":    "Exportado y cifrado en %s\n",
+	"Exported to %s\n":                  "Exportado en %s\n",
+	"Runbook written to %s\n":           "Runbook escrito en %s\n",
+	"Auto-detected format: %s\n":        "Formato detectado: %s\n",
+	"Left out %d commands matching ignore patterns (use --include-ignored to import them)\n": "Se omitieron %d comandos que coinciden con patrones de ignorar (usa --include-ignored para importarlos)\n",
+	"Repaired %d commands that were not valid UTF-8\n":                                       "Se repararon %d comandos que no eran UTF-8 válido\n",
+	"Imported %d commands\n": "%d comandos importados\n",
+
+	// Profiles and ssh
+	"Switched to profile: %s\n":       "Perfil activo: %s\n",
+	"fh: %v\n":                        "fh: %v\n",
+	"fh: saved %d commands from %s\n": "fh: %d comandos guardados de %s\n",
+}
@@ -0,0 +1,94 @@
+// Package i18n translates fh's user-facing messages. Messages are looked up
+// by their English format string, gettext style, so anything without a
+// translation is printed in English. Output meant for scripts (selected
+// commands, exports, listings) must not go through this package.
+package i18n
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the language messages are written in
+const DefaultLocale = "en"
+
+// catalogs maps a language to its translations, keyed by English format string
+var catalogs = map[string]map[string]string{
+	"es": spanish,
+}
+
+var (
+	localeOnce sync.Once
+	locale     string
+)
+
+// DetectLocale returns the message language from LC_ALL, LC_MESSAGES or
+// LANG (in that order), e.g. "es" for es_AR.UTF-8. Unsupported languages
+// and the C/POSIX locale give DefaultLocale.
+func DetectLocale() string {
+	if lang := EnvLanguage(); lang != "" {
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+	}
+	return DefaultLocale
+}
+
+// EnvLanguage returns the language of the environment's locale, whether or
+// not fh has messages in it, e.g. "de" for de_DE.UTF-8. The C/POSIX locale
+// and no locale at all give "".
+func EnvLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+
+		lang := strings.ToLower(value)
+		if i := strings.IndexAny(lang, "_.@-"); i >= 0 {
+			lang = lang[:i]
+		}
+		if lang == "c" || lang == "posix" {
+			return ""
+		}
+		return lang
+	}
+	return ""
+}
+
+// Locale returns the language messages are translated to, detected once
+func Locale() string {
+	localeOnce.Do(func() {
+		if locale == "" {
+			locale = DetectLocale()
+		}
+	})
+	return locale
+}
+
+// SetLocale overrides the detected language
+func SetLocale(lang string) {
+	localeOnce.Do(func() {})
+	locale = lang
+}
+
+// T returns the translation of an English message or format string
+func T(message string) string {
+	if translated, ok := catalogs[Locale()][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Printf prints a translated message to stdout
+func Printf(format string, args ...interface{}) {
+	fmt.Printf(T(format), args...)
+}
+
+// Fprintf writes a translated message to w
+func Fprintf(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(w, T(format), args...)
+}
@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLocale(t *testing.T) {
+	tests := []struct {
+		name       string
+		lcAll      string
+		lcMessages string
+		lang       string
+		want       string
+	}{
+		{"unset", "", "", "", "en"},
+		{"spanish", "", "", "es_AR.UTF-8", "es"},
+		{"bare language", "", "", "es", "es"},
+		{"lc_all wins", "en_US.UTF-8", "", "es_ES.UTF-8", "en"},
+		{"lc_messages over lang", "", "es_MX", "en_GB.UTF-8", "es"},
+		{"c locale", "", "", "C", "en"},
+		{"unsupported", "", "", "fr_FR.UTF-8", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.lang)
+			assert.Equal(t, tt.want, DetectLocale())
+		})
+	}
+}
+
+func TestEnvLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+
+	t.Setenv("LANG", "de_DE.UTF-8")
+	assert.Equal(t, "de", EnvLanguage())
+	assert.Equal(t, DefaultLocale, DetectLocale(), "no messages in German")
+
+	t.Setenv("LANG", "POSIX")
+	assert.Empty(t, EnvLanguage())
+	t.Setenv("LANG", "")
+	assert.Empty(t, EnvLanguage())
+}
+
+func TestT(t *testing.T) {
+	defer SetLocale(DefaultLocale)
+
+	SetLocale("es")
+	assert.Equal(t, "Nada que cambiar\n", T("Nothing to change\n"))
+	assert.Equal(t, "not in the catalog", T("not in the catalog"))
+
+	var buf bytes.Buffer
+	Fprintf(&buf, "Imported %d commands\n", 3)
+	assert.Equal(t, "3 comandos importados\n", buf.String())
+
+	SetLocale(DefaultLocale)
+	assert.Equal(t, "Nothing to change\n", T("Nothing to change\n"))
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogsKeepVerbs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for message, translated := range catalog {
+			assert.Equal(t, verbPattern.FindAllString(message, -1), verbPattern.FindAllString(translated, -1),
+				"%s translation of %q", lang, message)
+		}
+	}
+}
+
+// TestCatalogsCoverCLI checks every message fh passes through this package
+// has a translation in each catalog
+func TestCatalogsCoverCLI(t *testing.T) {
+	files, err := filepath.Glob("../../cmd/fh/*.go")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	fset := token.NewFileSet()
+	var messages []string
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		require.NoError(t, err)
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+				return true
+			}
+
+			arg := 0
+			switch sel.Sel.Name {
+			case "T", "Printf":
+			case "Fprintf":
+				arg = 1
+			default:
+				return true
+			}
+
+			lit, ok := call.Args[arg].(*ast.BasicLit)
+			if !assert.True(t, ok, "%s: message must be a string literal", fset.Position(call.Pos())) {
+				return true
+			}
+			message, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			messages = append(messages, message)
+			return true
+		})
+	}
+	require.NotEmpty(t, messages)
+
+	for lang, catalog := range catalogs {
+		for _, message := range messages {
+			assert.Contains(t, catalog, message, "missing %s translation", lang)
+		}
+	}
+}
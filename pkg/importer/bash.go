@@ -3,6 +3,7 @@ package importer
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -12,23 +13,32 @@ import (
 
 // BashHistoryEntry represents a parsed entry from bash history
 type BashHistoryEntry struct {
-	Timestamp int64
-	Command   string
+	Timestamp   int64
+	Command     string
+	Approximate bool // No timestamp in the file, Timestamp was inferred
 }
 
+// DefaultApproxWindow is how far back commands are spread when a history
+// file has no timestamps at all
+const DefaultApproxWindow = 30 * 24 * time.Hour
+
 // ParseBashHistory parses ~/.bash_history and returns all entries
 func ParseBashHistory() ([]*BashHistoryEntry, error) {
-	home, err := os.UserHomeDir()
+	historyPath, err := GetBashHistoryPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	historyPath := filepath.Join(home, ".bash_history")
 	return ParseBashHistoryFile(historyPath)
 }
 
-// ParseBashHistoryFile parses a bash history file at the given path
+// ParseBashHistoryFile parses a bash history file at the given path.
+// Commands without a timestamp (HISTTIMEFORMAT unset) get one inferred as
+// described in inferTimestamps, over DefaultApproxWindow.
 func ParseBashHistoryFile(path string) ([]*BashHistoryEntry, error) {
+	return parseBashHistoryFile(path, DefaultApproxWindow)
+}
+
+func parseBashHistoryFile(path string, window time.Duration) ([]*BashHistoryEntry, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -38,15 +48,36 @@ func ParseBashHistoryFile(path string) ([]*BashHistoryEntry, error) {
 	}
 	defer file.Close()
 
+	// Bash rewrites the file on exit, so its mtime is about when the last
+	// command ran
+	end := time.Now().Unix()
+	if info, err := file.Stat(); err == nil {
+		end = info.ModTime().Unix()
+	}
+	return parseBashHistory(file, end, window, false)
+}
+
+// ParseBashHistoryReader parses bash history from r as bash reads it back
+// with HISTTIMEFORMAT set and lithist on: a command runs from its #<timestamp>
+// line to the next one, so multi-line commands stay whole. Commands before
+// the first timestamp are one per line and get one inferred, ending at end.
+func ParseBashHistoryReader(r io.Reader, end int64, window time.Duration) ([]*BashHistoryEntry, error) {
+	return parseBashHistory(r, end, window, true)
+}
+
+// parseBashHistory parses bash history from r. With multiline, the lines
+// after a timestamped command up to the next timestamp are part of it.
+func parseBashHistory(r io.Reader, end int64, window time.Duration, multiline bool) ([]*BashHistoryEntry, error) {
 	var entries []*BashHistoryEntry
-	scanner := bufio.NewScanner(file)
-	
+	scanner := bufio.NewScanner(r)
+
 	// Increase buffer size to handle very long command lines (up to 1MB)
 	const maxScanTokenSize = 1024 * 1024 // 1MB
 	buf := make([]byte, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
 
 	var currentTimestamp int64
+	var continued *BashHistoryEntry // Timestamped command further lines belong to
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -55,14 +86,20 @@ func ParseBashHistoryFile(path string) ([]*BashHistoryEntry, error) {
 
 		// Check if this is a timestamp line (format: #1234567890)
 		if strings.HasPrefix(line, "#") && len(line) > 1 {
-			// Try to parse as timestamp
+			// Try to parse as timestamp, times before 1970 count as unknown
 			if ts, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
-				currentTimestamp = ts
+				currentTimestamp = max(ts, 0)
+				continued = nil
 				continue
 			}
 			// If parsing failed, treat it as a comment/command
 		}
 
+		if continued != nil {
+			continued.Command += "\n" + line
+			continue
+		}
+
 		// Skip empty lines
 		if strings.TrimSpace(line) == "" {
 			continue
@@ -73,13 +110,10 @@ func ParseBashHistoryFile(path string) ([]*BashHistoryEntry, error) {
 			Command:   line,
 			Timestamp: currentTimestamp,
 		}
-
-		// If no timestamp was set, use current time
-		if entry.Timestamp == 0 {
-			entry.Timestamp = time.Now().Unix()
-		}
-
 		entries = append(entries, entry)
+		if multiline && currentTimestamp > 0 {
+			continued = entry
+		}
 		currentTimestamp = 0 // Reset for next command
 	}
 
@@ -87,9 +121,60 @@ func ParseBashHistoryFile(path string) ([]*BashHistoryEntry, error) {
 		return nil, fmt.Errorf("error reading history file: %w", err)
 	}
 
+	// Blank lines between commands are not part of them
+	for _, entry := range entries {
+		entry.Command = strings.TrimRight(entry.Command, "\n")
+	}
+	inferTimestamps(entries, end, window)
+
 	return entries, nil
 }
 
+// FormatBashLines formats a command as bash writes it to its history file
+// with HISTTIMEFORMAT set: a #<timestamp> line, then the command, multi-line
+// commands as they are, like lithist
+func FormatBashLines(timestamp int64, command string) string {
+	return fmt.Sprintf("#%d\n%s", timestamp, command)
+}
+
+// inferTimestamps fills in the entries without a timestamp and marks them
+// Approximate. Each run of such entries is spread evenly between the
+// timestamps around it, so the order of the file is kept. A run at the end
+// ends at end, and a run at the start begins window before what follows it.
+func inferTimestamps(entries []*BashHistoryEntry, end int64, window time.Duration) {
+	if window <= 0 {
+		window = DefaultApproxWindow
+	}
+
+	for i := 0; i < len(entries); {
+		if entries[i].Timestamp != 0 {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(entries) && entries[j].Timestamp == 0 {
+			j++
+		}
+
+		high := end
+		if j < len(entries) {
+			high = entries[j].Timestamp
+		}
+		low := max(high-int64(window.Seconds()), 1)
+		if i > 0 {
+			low = min(entries[i-1].Timestamp, high)
+		}
+
+		step := (high - low) / int64(j-i+1)
+		for k := i; k < j; k++ {
+			entries[k].Timestamp = low + step*int64(k-i+1)
+			entries[k].Approximate = true
+		}
+		i = j
+	}
+}
+
 // GetBashHistoryPath returns the path to bash history file
 func GetBashHistoryPath() (string, error) {
 	home, err := os.UserHomeDir()
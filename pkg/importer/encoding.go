@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// RepairUTF8 returns s as valid UTF-8 and whether it had to change it.
+// Old history files are often Latin-1 (in practice Windows-1252), which is
+// transcoded when the text has no UTF-8 characters at all. Text mixing
+// both has its invalid bytes replaced with U+FFFD instead.
+func RepairUTF8(s string) (string, bool) {
+	if utf8.ValidString(s) {
+		return s, false
+	}
+
+	if !hasMultiByteRune(s) {
+		if decoded, err := charmap.Windows1252.NewDecoder().String(s); err == nil {
+			return decoded, true
+		}
+	}
+	return strings.ToValidUTF8(s, "\uFFFD"), true
+}
+
+// hasMultiByteRune reports whether s holds any valid multi-byte UTF-8 rune
+func hasMultiByteRune(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != utf8.RuneError && size > 1 {
+			return true
+		}
+		i += size
+	}
+	return false
+}
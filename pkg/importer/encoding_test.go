@@ -0,0 +1,31 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		repaired bool
+	}{
+		{"ascii", "ls -la", "ls -la", false},
+		{"utf-8", "echo café →", "echo café →", false},
+		{"latin-1", "echo caf\xe9", "echo café", true},
+		{"windows-1252 quotes", "echo \x93hi\x94", "echo “hi”", true},
+		{"mixed keeps utf-8", "echo café \xff", "echo café �", true},
+		{"broken utf-8 alone reads as latin-1", "echo \xe2\x86", "echo â†", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, repaired := RepairUTF8(tt.input)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.repaired, repaired)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FishHistoryEntry represents a parsed entry from fish history
+type FishHistoryEntry struct {
+	Timestamp int64
+	Command   string
+}
+
+// ParseFishHistory parses fish's history file and returns all entries
+func ParseFishHistory() ([]*FishHistoryEntry, error) {
+	historyPath, err := GetFishHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	return ParseFishHistoryFile(historyPath)
+}
+
+// ParseFishHistoryFile parses a fish history file at the given path.
+// Fish writes a YAML-like list with an entry per command: a "- cmd: <command>"
+// line, followed by indented "when: <unix time>" and "paths:" lines, the
+// latter listing files the command named.
+// A command spanning lines is kept on one, with newlines written as \n and
+// backslashes as \\; both are undone.
+func ParseFishHistoryFile(path string) ([]*FishHistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*FishHistoryEntry{}, nil // Return empty if file doesn't exist
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []*FishHistoryEntry
+	scanner := bufio.NewScanner(file)
+
+	// Increase buffer size to handle very long command lines (up to 1MB)
+	const maxScanTokenSize = 1024 * 1024 // 1MB
+	buf := make([]byte, maxScanTokenSize)
+	scanner.Buffer(buf, maxScanTokenSize)
+
+	var current *FishHistoryEntry
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if command, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			current = &FishHistoryEntry{Command: unescapeFish(command)}
+			entries = append(entries, current)
+			continue
+		}
+
+		// when: belongs to the entry above it, paths: and its items are skipped
+		if when, ok := strings.CutPrefix(strings.TrimSpace(line), "when: "); ok && current != nil {
+			if timestamp, err := strconv.ParseInt(strings.TrimSpace(when), 10, 64); err == nil {
+				current.Timestamp = timestamp
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history file: %w", err)
+	}
+
+	// Empty commands can't be searched for
+	kept := entries[:0]
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Command) != "" {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+// unescapeFish decodes a command as fish stores it, \n standing for a
+// newline and \\ for a backslash
+func unescapeFish(command string) string {
+	if !strings.Contains(command, `\`) {
+		return command
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' && i+1 < len(command) {
+			switch command[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(command[i])
+	}
+	return b.String()
+}
+
+// GetFishHistoryPath returns the path to fish history file
+func GetFishHistoryPath() (string, error) {
+	// Fish keeps its history under XDG_DATA_HOME, ~/.local/share by default
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "fish", "fish_history"), nil
+}
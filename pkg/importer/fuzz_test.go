@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run a target with: go test ./pkg/importer -run=^$ -fuzz=FuzzParseZshLine
+
+func FuzzParseZshLine(f *testing.F) {
+	seeds := []string{
+		": 1234567890:0;ls -la",
+		": 1234567890:15;git commit -m 'test; with semicolon'",
+		": 1234567890;missing duration",
+		": abc:def;bad metadata",
+		": ;",
+		"plain command",
+		": 1234567890:0;",
+		"\x83\x80",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entry := parseZshLine(line)
+		if entry == nil {
+			t.Fatalf("parseZshLine returned nil for %q", line)
+		}
+		// The command is always the tail of the line, never invented or reordered
+		if !strings.HasSuffix(line, entry.Command) {
+			t.Fatalf("command %q is not a suffix of %q", entry.Command, line)
+		}
+	})
+}
+
+func FuzzParseBashHistoryFile(f *testing.F) {
+	seeds := []string{
+		"#1234567890\nls -la\n#1234567900\ngit status\n",
+		"ls -la\ngit status\n",
+		"#not-a-timestamp\necho hi\n",
+		"#\n#\n\n\n",
+		"#1234567890\n#1234567891\n",
+		strings.Repeat("a", 4096),
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	dir := f.TempDir()
+	path := filepath.Join(dir, ".bash_history")
+
+	f.Fuzz(func(t *testing.T, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ParseBashHistoryFile(path)
+		if err != nil {
+			// Lines beyond the scanner buffer are the only expected failure
+			return
+		}
+
+		for _, entry := range entries {
+			if strings.TrimSpace(entry.Command) == "" {
+				t.Fatalf("empty command parsed from %q", content)
+			}
+			if entry.Timestamp == 0 {
+				t.Fatalf("zero timestamp parsed from %q", content)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package importer
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spideyz0r/fh/pkg/capture"
 	"github.com/spideyz0r/fh/pkg/storage"
@@ -12,27 +13,71 @@ type ImportResult struct {
 	TotalEntries    int
 	ImportedEntries int
 	SkippedEntries  int
+	IgnoredEntries  int // Matched the ignore filter, not imported
+	RepairedEntries int // Not valid UTF-8, transcoded or sanitized
+	Sessions        int // Session history files read by ImportSessions
 	Errors          []error
 }
 
+// IgnoreFunc reports whether a command should be left out of an import,
+// nil keeps every command
+type IgnoreFunc func(command string) bool
+
+// Options configures ImportHistory and ImportFromFile
+type Options struct {
+	Dedup        storage.DedupConfig
+	Ignore       IgnoreFunc
+	ApproxWindow time.Duration // See DefaultApproxWindow, 0 uses it
+}
+
+// repair returns command as valid UTF-8, counting it when it was not
+func (r *ImportResult) repair(command string) string {
+	command, repaired := RepairUTF8(command)
+	if repaired {
+		r.RepairedEntries++
+	}
+	return command
+}
+
+// ignored counts command as ignored when ignore matches it
+func (r *ImportResult) ignored(ignore IgnoreFunc, command string) bool {
+	if ignore == nil || !ignore(command) {
+		return false
+	}
+	r.IgnoredEntries++
+	return true
+}
+
 // ImportHistory imports history from shell-specific history files
-// It detects the shell type and imports from the appropriate file
-func ImportHistory(db *storage.DB, shell capture.ShellType, dedupConfig storage.DedupConfig) (*ImportResult, error) {
+// It detects the shell type and imports from the appropriate file, leaving
+// out the commands opts.Ignore matches
+func ImportHistory(db *storage.DB, shell capture.ShellType, opts Options) (*ImportResult, error) {
 	switch shell {
 	case capture.ShellBash:
-		return importBashHistory(db, dedupConfig)
+		return importBashHistory(db, opts)
 	case capture.ShellZsh:
-		return importZshHistory(db, dedupConfig)
+		return importZshHistory(db, opts)
+	case capture.ShellFish:
+		return importFishHistory(db, opts)
 	default:
 		return nil, fmt.Errorf("unsupported shell: %s", shell)
 	}
 }
 
 // importBashHistory imports bash history
-func importBashHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*ImportResult, error) {
+func importBashHistory(db *storage.DB, opts Options) (*ImportResult, error) {
 	result := &ImportResult{}
 
-	entries, err := ParseBashHistory()
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := GetBashHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseBashHistoryFile(path, opts.ApproxWindow)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse bash history: %w", err)
 	}
@@ -52,9 +97,14 @@ func importBashHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*Import
 	}
 
 	for _, entry := range entries {
+		command := result.repair(entry.Command)
+		if result.ignored(opts.Ignore, command) {
+			continue
+		}
+
 		historyEntry := &storage.HistoryEntry{
 			Timestamp:  entry.Timestamp,
-			Command:    entry.Command,
+			Command:    command,
 			Cwd:        meta.Cwd, // Use current cwd as we don't have historical cwd
 			ExitCode:   0,        // Unknown for historical entries
 			Hostname:   meta.Hostname,
@@ -63,11 +113,12 @@ func importBashHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*Import
 			DurationMs: 0,  // Unknown for bash history
 			GitBranch:  "", // Unknown for historical entries
 			SessionID:  "", // Not applicable for imports
+			ApproxTime: entry.Approximate,
 		}
 
 		// Insert with deduplication
-		if err := db.InsertWithDedup(historyEntry, dedupConfig); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to import command '%s': %w", entry.Command, err))
+		if err := inserter.Insert(historyEntry); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to import command '%s': %w", command, err))
 			result.SkippedEntries++
 		} else {
 			result.ImportedEntries++
@@ -78,9 +129,14 @@ func importBashHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*Import
 }
 
 // importZshHistory imports zsh history
-func importZshHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*ImportResult, error) {
+func importZshHistory(db *storage.DB, opts Options) (*ImportResult, error) {
 	result := &ImportResult{}
 
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
 	entries, err := ParseZshHistory()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse zsh history: %w", err)
@@ -101,9 +157,14 @@ func importZshHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*ImportR
 	}
 
 	for _, entry := range entries {
+		command := result.repair(entry.Command)
+		if result.ignored(opts.Ignore, command) {
+			continue
+		}
+
 		historyEntry := &storage.HistoryEntry{
 			Timestamp:  entry.Timestamp,
-			Command:    entry.Command,
+			Command:    command,
 			Cwd:        meta.Cwd, // Use current cwd as we don't have historical cwd
 			ExitCode:   0,        // Unknown for historical entries
 			Hostname:   meta.Hostname,
@@ -115,8 +176,8 @@ func importZshHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*ImportR
 		}
 
 		// Insert with deduplication
-		if err := db.InsertWithDedup(historyEntry, dedupConfig); err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to import command '%s': %w", entry.Command, err))
+		if err := inserter.Insert(historyEntry); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to import command '%s': %w", command, err))
 			result.SkippedEntries++
 		} else {
 			result.ImportedEntries++
@@ -126,19 +187,85 @@ func importZshHistory(db *storage.DB, dedupConfig storage.DedupConfig) (*ImportR
 	return result, nil
 }
 
-// ImportFromFile imports history from a specific file path
+// importFishHistory imports fish history
+func importFishHistory(db *storage.DB, opts Options) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseFishHistory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fish history: %w", err)
+	}
+
+	result.TotalEntries = len(entries)
+
+	// Get current user metadata for filling in missing fields
+	meta, err := capture.Collect("", 0, 0)
+	if err != nil {
+		// Continue with defaults if we can't collect metadata
+		meta = &capture.Metadata{
+			Cwd:      "",
+			Hostname: "",
+			User:     "",
+			Shell:    string(capture.ShellFish),
+		}
+	}
+
+	for _, entry := range entries {
+		command := result.repair(entry.Command)
+		if result.ignored(opts.Ignore, command) {
+			continue
+		}
+
+		historyEntry := &storage.HistoryEntry{
+			Timestamp:  entry.Timestamp,
+			Command:    command,
+			Cwd:        meta.Cwd, // Use current cwd as we don't have historical cwd
+			ExitCode:   0,        // Unknown for historical entries
+			Hostname:   meta.Hostname,
+			User:       meta.User,
+			Shell:      string(capture.ShellFish),
+			DurationMs: 0,  // Unknown for fish history
+			GitBranch:  "", // Unknown for historical entries
+			SessionID:  "", // Not applicable for imports
+		}
+
+		// Insert with deduplication
+		if err := inserter.Insert(historyEntry); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to import command '%s': %w", command, err))
+			result.SkippedEntries++
+		} else {
+			result.ImportedEntries++
+		}
+	}
+
+	return result, nil
+}
+
+// ImportFromFile imports history from a specific file path, leaving out the
+// commands opts.Ignore matches
 // Useful for importing from backups or other machines
-func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, dedupConfig storage.DedupConfig) (*ImportResult, error) {
+func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, opts Options) (*ImportResult, error) {
 	result := &ImportResult{}
 
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
 	var entries interface{}
-	var err error
 
 	switch shell {
 	case capture.ShellBash:
-		entries, err = ParseBashHistoryFile(filePath)
+		entries, err = parseBashHistoryFile(filePath, opts.ApproxWindow)
 	case capture.ShellZsh:
 		entries, err = ParseZshHistoryFile(filePath)
+	case capture.ShellFish:
+		entries, err = ParseFishHistoryFile(filePath)
 	default:
 		return nil, fmt.Errorf("unsupported shell: %s", shell)
 	}
@@ -167,9 +294,14 @@ func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, de
 		}
 		result.TotalEntries = len(bashEntries)
 		for _, entry := range bashEntries {
+			command := result.repair(entry.Command)
+			if result.ignored(opts.Ignore, command) {
+				continue
+			}
+
 			historyEntry := &storage.HistoryEntry{
 				Timestamp:  entry.Timestamp,
-				Command:    entry.Command,
+				Command:    command,
 				Cwd:        meta.Cwd,
 				ExitCode:   0,
 				Hostname:   meta.Hostname,
@@ -178,9 +310,10 @@ func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, de
 				DurationMs: 0,
 				GitBranch:  "",
 				SessionID:  "",
+				ApproxTime: entry.Approximate,
 			}
 
-			if err := db.InsertWithDedup(historyEntry, dedupConfig); err != nil {
+			if err := inserter.Insert(historyEntry); err != nil {
 				result.Errors = append(result.Errors, err)
 				result.SkippedEntries++
 			} else {
@@ -195,9 +328,14 @@ func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, de
 		}
 		result.TotalEntries = len(zshEntries)
 		for _, entry := range zshEntries {
+			command := result.repair(entry.Command)
+			if result.ignored(opts.Ignore, command) {
+				continue
+			}
+
 			historyEntry := &storage.HistoryEntry{
 				Timestamp:  entry.Timestamp,
-				Command:    entry.Command,
+				Command:    command,
 				Cwd:        meta.Cwd,
 				ExitCode:   0,
 				Hostname:   meta.Hostname,
@@ -208,7 +346,40 @@ func ImportFromFile(db *storage.DB, shell capture.ShellType, filePath string, de
 				SessionID:  "",
 			}
 
-			if err := db.InsertWithDedup(historyEntry, dedupConfig); err != nil {
+			if err := inserter.Insert(historyEntry); err != nil {
+				result.Errors = append(result.Errors, err)
+				result.SkippedEntries++
+			} else {
+				result.ImportedEntries++
+			}
+		}
+
+	case capture.ShellFish:
+		fishEntries, ok := entries.([]*FishHistoryEntry)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast entries to FishHistoryEntry")
+		}
+		result.TotalEntries = len(fishEntries)
+		for _, entry := range fishEntries {
+			command := result.repair(entry.Command)
+			if result.ignored(opts.Ignore, command) {
+				continue
+			}
+
+			historyEntry := &storage.HistoryEntry{
+				Timestamp:  entry.Timestamp,
+				Command:    command,
+				Cwd:        meta.Cwd,
+				ExitCode:   0,
+				Hostname:   meta.Hostname,
+				User:       meta.User,
+				Shell:      string(capture.ShellFish),
+				DurationMs: 0,
+				GitBranch:  "",
+				SessionID:  "",
+			}
+
+			if err := inserter.Insert(historyEntry); err != nil {
 				result.Errors = append(result.Errors, err)
 				result.SkippedEntries++
 			} else {
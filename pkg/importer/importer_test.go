@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spideyz0r/fh/pkg/capture"
 	"github.com/spideyz0r/fh/pkg/storage"
@@ -57,10 +58,42 @@ docker ps`
 		require.NoError(t, err)
 		assert.Len(t, entries, 3)
 
-		// All entries should have a timestamp (current time)
+		// Spread over the window before the file was last written
+		mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		require.NoError(t, os.Chtimes(histFile, mtime, mtime))
+		entries, err = ParseBashHistoryFile(histFile)
+		require.NoError(t, err)
+		start := mtime.Add(-DefaultApproxWindow).Unix()
+		for i, entry := range entries {
+			assert.True(t, entry.Approximate)
+			assert.Greater(t, entry.Timestamp, start)
+			assert.Less(t, entry.Timestamp, mtime.Unix())
+			if i > 0 {
+				assert.Greater(t, entry.Timestamp, entries[i-1].Timestamp)
+			}
+		}
+	})
+
+	t.Run("infer timestamps between recorded ones", func(t *testing.T) {
+		entries := []*BashHistoryEntry{
+			{Command: "a"},
+			{Command: "b", Timestamp: 10000},
+			{Command: "c"},
+			{Command: "d"},
+			{Command: "e", Timestamp: 10300},
+			{Command: "f"},
+		}
+		inferTimestamps(entries, 20000, time.Hour)
+
+		var got []int64
 		for _, entry := range entries {
-			assert.Greater(t, entry.Timestamp, int64(0))
+			got = append(got, entry.Timestamp)
 		}
+		// a starts the window before b, f ends before the file mtime
+		assert.Equal(t, []int64{8200, 10000, 10100, 10200, 10300, 15150}, got)
+		assert.True(t, entries[0].Approximate)
+		assert.False(t, entries[1].Approximate)
+		assert.True(t, entries[2].Approximate)
 	})
 
 	t.Run("parse bash history with empty lines", func(t *testing.T) {
@@ -204,6 +237,37 @@ docker ps`
 		require.NoError(t, err)
 		assert.Len(t, entries, 2) // Empty lines should be skipped
 	})
+
+	t.Run("parse zsh multi-line commands", func(t *testing.T) {
+		histFile := filepath.Join(t.TempDir(), ".zsh_history")
+
+		// A command typed with a line continuation keeps its own backslash
+		content := ": 1234567890:0;for f in *.go\\\ndo\\\n  gofmt -l $f\\\ndone\n" +
+			": 1234567900:0;echo a \\\\\nb\n" +
+			": 1234567920:0;ls\n"
+		require.NoError(t, os.WriteFile(histFile, []byte(content), 0644))
+
+		entries, err := ParseZshHistoryFile(histFile)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "for f in *.go\ndo\n  gofmt -l $f\ndone", entries[0].Command)
+		assert.Equal(t, int64(1234567890), entries[0].Timestamp)
+		assert.Equal(t, "echo a \\\nb", entries[1].Command)
+		assert.Equal(t, "ls", entries[2].Command)
+	})
+
+	t.Run("parse zsh metafied characters", func(t *testing.T) {
+		histFile := filepath.Join(t.TempDir(), ".zsh_history")
+
+		// "→" is e2 86 92, zsh writes 86 and 92 as 83 a6 and 83 b2
+		content := ": 1234567890:0;echo \xe2\x83\xa6\x83\xb2 done\n"
+		require.NoError(t, os.WriteFile(histFile, []byte(content), 0644))
+
+		entries, err := ParseZshHistoryFile(histFile)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "echo → done", entries[0].Command)
+	})
 }
 
 func TestImportHistory(t *testing.T) {
@@ -229,7 +293,7 @@ ls -la /tmp`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		assert.Equal(t, 2, result.TotalEntries)
@@ -263,7 +327,7 @@ ls -la /tmp`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellZsh, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellZsh, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		assert.Equal(t, 3, result.TotalEntries)
@@ -299,7 +363,7 @@ func TestImportFromFile(t *testing.T) {
 			Strategy: storage.KeepAll,
 		}
 
-		_, err := ImportFromFile(db, "unsupported", "/tmp/file", dedupConfig)
+		_, err := ImportFromFile(db, "unsupported", "/tmp/file", Options{Dedup: dedupConfig})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unsupported shell")
 	})
@@ -327,7 +391,7 @@ git status`
 			Strategy: storage.KeepFirst,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		assert.Equal(t, 3, result.TotalEntries)
@@ -339,6 +403,47 @@ git status`
 		assert.Equal(t, int64(2), count) // Should only have 2 unique commands
 	})
 
+	t.Run("import with ignore filter", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		defer db.Close()
+
+		histFile := filepath.Join(t.TempDir(), ".bash_history")
+		require.NoError(t, os.WriteFile(histFile, []byte("ls\ncd /tmp\nmake test\nls -la\n"), 0644))
+
+		ignore := func(command string) bool {
+			return command == "ls" || strings.HasPrefix(command, "ls ") || strings.HasPrefix(command, "cd ")
+		}
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Ignore: ignore})
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, result.TotalEntries)
+		assert.Equal(t, 1, result.ImportedEntries)
+		assert.Equal(t, 3, result.IgnoredEntries)
+
+		entries, err := db.Query(storage.QueryFilters{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "make test", entries[0].Command)
+	})
+
+	t.Run("import repairs invalid UTF-8", func(t *testing.T) {
+		db := testutil.NewTestDB(t)
+		defer db.Close()
+
+		histFile := filepath.Join(t.TempDir(), ".bash_history")
+		require.NoError(t, os.WriteFile(histFile, []byte("cd caf\xe9\nmake\n"), 0644))
+
+		opts := Options{Dedup: storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}}
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.ImportedEntries)
+		assert.Equal(t, 1, result.RepairedEntries)
+
+		entries, err := db.Query(storage.QueryFilters{Command: "cd café"})
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
 	t.Run("import non-existent file", func(t *testing.T) {
 		db := testutil.NewTestDB(t)
 		defer db.Close()
@@ -348,7 +453,7 @@ git status`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, "/nonexistent/file", dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, "/nonexistent/file", Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		assert.Equal(t, 0, result.TotalEntries)
@@ -469,7 +574,7 @@ docker ps -a`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		// Should have imported all unique commands
@@ -500,7 +605,7 @@ git status --short
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellZsh, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellZsh, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		assert.Equal(t, 4, result.TotalEntries)
@@ -533,13 +638,13 @@ func TestImportHistory_UnsupportedShell(t *testing.T) {
 	}
 
 	t.Run("unsupported shell type", func(t *testing.T) {
-		_, err := ImportHistory(db, "fish", dedupConfig)
+		_, err := ImportHistory(db, "ksh", Options{Dedup: dedupConfig})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unsupported shell")
 	})
 
 	t.Run("empty shell type", func(t *testing.T) {
-		_, err := ImportHistory(db, "", dedupConfig)
+		_, err := ImportHistory(db, "", Options{Dedup: dedupConfig})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unsupported shell")
 	})
@@ -585,7 +690,7 @@ ls -la /tmp/test`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportHistory(db, capture.ShellBash, dedupConfig)
+		result, err := ImportHistory(db, capture.ShellBash, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 		
 		// Should have imported the test commands
@@ -633,7 +738,7 @@ pwd`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportHistory(db, capture.ShellZsh, dedupConfig)
+		result, err := ImportHistory(db, capture.ShellZsh, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 		
 		// Should have imported the test commands
@@ -666,7 +771,7 @@ echo test3`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 
 		// Verify result structure
@@ -693,7 +798,7 @@ func TestImportFromFile_EdgeCases(t *testing.T) {
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 		assert.Equal(t, 0, result.TotalEntries)
 		assert.Equal(t, 0, result.ImportedEntries)
@@ -721,7 +826,7 @@ regular command`
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err, "Should handle very long command lines without scanner buffer errors")
 		assert.Equal(t, 2, result.TotalEntries)
 		assert.Equal(t, 2, result.ImportedEntries)
@@ -761,7 +866,7 @@ plain format command
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellZsh, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellZsh, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 		assert.Equal(t, 3, result.TotalEntries)
 		assert.Equal(t, 3, result.ImportedEntries)
@@ -786,9 +891,146 @@ plain format command
 			Strategy: storage.KeepAll,
 		}
 
-		result, err := ImportFromFile(db, capture.ShellBash, histFile, dedupConfig)
+		result, err := ImportFromFile(db, capture.ShellBash, histFile, Options{Dedup: dedupConfig})
 		require.NoError(t, err)
 		// Comments without following commands should be skipped or treated as commands
 		assert.GreaterOrEqual(t, result.TotalEntries, 0)
 	})
 }
+
+func TestParseFishHistoryFile(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "fish_history")
+	content := `- cmd: git commit -m "fix"
+  when: 1700000000
+  paths:
+    - README.md
+- cmd: for f in *.go\n    gofmt -l $f\nend
+  when: 1700000060
+- cmd: echo C:\\Users\\alice
+  when: 1700000120
+- cmd: 
+  when: 1700000130
+- cmd: printf '\t'
+  when: 1700000140
+`
+	require.NoError(t, os.WriteFile(histFile, []byte(content), 0644))
+
+	entries, err := ParseFishHistoryFile(histFile)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, `git commit -m "fix"`, entries[0].Command)
+	assert.Equal(t, int64(1700000000), entries[0].Timestamp)
+	assert.Equal(t, "for f in *.go\n    gofmt -l $f\nend", entries[1].Command)
+	assert.Equal(t, int64(1700000060), entries[1].Timestamp)
+	assert.Equal(t, `echo C:\Users\alice`, entries[2].Command)
+	assert.Equal(t, `printf '\t'`, entries[3].Command)
+
+	entries, err = ParseFishHistoryFile(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestImportHistory_Fish(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	path, err := GetFishHistoryPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dataHome, "fish", "fish_history"), path)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("- cmd: make test\n  when: 1700000000\n- cmd: ls\n  when: 1700000001\n"), 0644))
+
+	db := testutil.NewTestDB(t)
+	result, err := ImportHistory(db, capture.ShellFish, Options{Ignore: func(command string) bool { return command == "ls" }})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalEntries)
+	assert.Equal(t, 1, result.ImportedEntries)
+	assert.Equal(t, 1, result.IgnoredEntries)
+
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "make test", entries[0].Command)
+	assert.Equal(t, "fish", entries[0].Shell)
+}
+
+func TestImportFromFile_Fish(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "fish_history")
+	content := "- cmd: for f in *.log\\n    gzip $f\\nend\n  when: 1700000000\n  paths:\n    - *.log\n- cmd: git push\n  when: 1700000060\n"
+	require.NoError(t, os.WriteFile(histFile, []byte(content), 0644))
+
+	db := testutil.NewTestDB(t)
+	dedupConfig := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+	result, err := ImportFromFile(db, capture.ShellFish, histFile, Options{Dedup: dedupConfig})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalEntries)
+	assert.Equal(t, 2, result.ImportedEntries)
+
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "git push", entries[0].Command)
+	assert.Equal(t, "for f in *.log\n    gzip $f\nend", entries[1].Command)
+	assert.Equal(t, int64(1700000000), entries[1].Timestamp)
+	assert.Equal(t, "fish", entries[1].Shell)
+}
+
+func TestImportSessions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ZDOTDIR", "")
+
+	dirs, err := GetSessionDirs()
+	require.NoError(t, err)
+	require.Len(t, dirs, 2)
+	assert.Equal(t, filepath.Join(home, ".zsh_sessions"), dirs[1].Path)
+
+	files := map[string]string{
+		".bash_sessions/0A1B-BASH.history":   "#1600000000\nmake deploy\n#1600000060\nls\n",
+		".bash_sessions/0A1B-BASH.session":   "echo Restored session\n",
+		".zsh_sessions/9F8E-ZSH.historynew":  ": 1600000100:3;terraform plan\n",
+		".zsh_sessions/7C6D-PLAIN.history":   "git log\n",
+		".zsh_sessions/_expiration_check_ts": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	ended := time.Unix(1600000500, 0)
+	require.NoError(t, os.Chtimes(filepath.Join(home, ".zsh_sessions/7C6D-PLAIN.history"), ended, ended))
+
+	db := testutil.NewTestDB(t)
+	dedupConfig := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+	result, err := ImportSessions(db, dirs, Options{Dedup: dedupConfig, Ignore: func(command string) bool { return command == "ls" }})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Sessions)
+	assert.Equal(t, 4, result.TotalEntries)
+	assert.Equal(t, 3, result.ImportedEntries)
+	assert.Equal(t, 1, result.IgnoredEntries)
+
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	sessions := make(map[string]*storage.HistoryEntry)
+	for _, entry := range entries {
+		sessions[entry.SessionID] = entry
+	}
+	require.Len(t, sessions, 3)
+	assert.Equal(t, "make deploy", sessions["0A1B-BASH"].Command)
+	assert.Equal(t, "bash", sessions["0A1B-BASH"].Shell)
+	assert.Equal(t, int64(1600000000), sessions["0A1B-BASH"].Timestamp)
+	assert.Equal(t, "terraform plan", sessions["9F8E-ZSH"].Command)
+	assert.Equal(t, int64(3000), sessions["9F8E-ZSH"].DurationMs)
+	assert.Equal(t, "zsh", sessions["9F8E-ZSH"].Shell)
+
+	// No time in the file: the session ended when it was last written
+	assert.Equal(t, int64(1600000500), sessions["7C6D-PLAIN"].Timestamp)
+	assert.True(t, sessions["7C6D-PLAIN"].ApproxTime)
+
+	// No session directories at all
+	result, err = ImportSessions(db, []SessionDir{{Shell: capture.ShellZsh, Path: filepath.Join(home, "missing")}}, Options{Dedup: dedupConfig})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Sessions)
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/capture"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// sessionHistoryExts are the history files macOS Terminal keeps per window:
+// .history once the session ended, .historynew while it is still open
+var sessionHistoryExts = []string{".history", ".historynew"}
+
+// SessionDir is a directory where macOS Terminal saves the history of each
+// of its sessions, for shell
+type SessionDir struct {
+	Shell capture.ShellType
+	Path  string
+}
+
+// GetSessionDirs returns where macOS Terminal saves per-session history:
+// ~/.bash_sessions for bash and ${ZDOTDIR:-$HOME}/.zsh_sessions for zsh.
+// They don't need to exist.
+func GetSessionDirs() ([]SessionDir, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	zdotdir := os.Getenv("ZDOTDIR")
+	if zdotdir == "" {
+		zdotdir = home
+	}
+
+	return []SessionDir{
+		{Shell: capture.ShellBash, Path: filepath.Join(home, ".bash_sessions")},
+		{Shell: capture.ShellZsh, Path: filepath.Join(zdotdir, ".zsh_sessions")},
+	}, nil
+}
+
+// ImportSessions imports the per-session history macOS Terminal saved under
+// the session directories, each entry keeping the id of its session,
+// leaving out the commands opts.Ignore matches. Directories that don't
+// exist are skipped.
+func ImportSessions(db *storage.DB, dirs []SessionDir, opts Options) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	inserter, err := db.NewBatchInserter(opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get current user metadata for filling in missing fields
+	meta, err := capture.Collect("", 0, 0)
+	if err != nil {
+		meta = &capture.Metadata{}
+	}
+
+	for _, dir := range dirs {
+		files, err := sessionHistoryFiles(dir.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			entries, err := parseSessionHistory(dir.Shell, file, opts)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				continue
+			}
+			result.Sessions++
+			result.TotalEntries += len(entries)
+
+			session := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			for _, entry := range entries {
+				command := result.repair(entry.Command)
+				if result.ignored(opts.Ignore, command) {
+					continue
+				}
+				entry.Command = command
+				entry.Cwd = meta.Cwd
+				entry.Hostname = meta.Hostname
+				entry.User = meta.User
+				entry.Shell = string(dir.Shell)
+				entry.SessionID = session
+
+				if err := inserter.Insert(entry); err != nil {
+					result.Errors = append(result.Errors, err)
+					result.SkippedEntries++
+				} else {
+					result.ImportedEntries++
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// sessionHistoryFiles returns the history files in dir, by name
+func sessionHistoryFiles(dir string) ([]string, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	var files []string
+	for _, item := range items {
+		for _, ext := range sessionHistoryExts {
+			if !item.IsDir() && filepath.Ext(item.Name()) == ext {
+				files = append(files, filepath.Join(dir, item.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// parseSessionHistory reads a session history file in the history format
+// of shell
+func parseSessionHistory(shell capture.ShellType, path string, opts Options) ([]*storage.HistoryEntry, error) {
+	var entries []*storage.HistoryEntry
+	switch shell {
+	case capture.ShellBash:
+		bashEntries, err := parseBashHistoryFile(path, opts.ApproxWindow)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range bashEntries {
+			entries = append(entries, &storage.HistoryEntry{Timestamp: entry.Timestamp, Command: entry.Command, ApproxTime: entry.Approximate})
+		}
+	case capture.ShellZsh:
+		// Without extended_history lines get the time they are read at,
+		// the session ended when its file was last written instead
+		parsed := time.Now().Unix()
+		zshEntries, err := ParseZshHistoryFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var ended int64
+		if info, err := os.Stat(path); err == nil {
+			ended = info.ModTime().Unix()
+		}
+		for _, entry := range zshEntries {
+			historyEntry := &storage.HistoryEntry{Timestamp: entry.Timestamp, Command: entry.Command, DurationMs: entry.Duration * 1000}
+			if entry.Timestamp >= parsed && ended > 0 {
+				historyEntry.Timestamp, historyEntry.ApproxTime = ended, true
+			}
+			entries = append(entries, historyEntry)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported shell: %s", shell)
+	}
+	return entries, nil
+}
@@ -3,6 +3,7 @@ package importer
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -38,6 +39,9 @@ func ParseZshHistory() ([]*ZshHistoryEntry, error) {
 
 // ParseZshHistoryFile parses a zsh history file at the given path
 // Zsh extended_history format: : <timestamp>:<duration>;<command>
+// Zsh ends each line of a multi-line command but the last with a backslash,
+// and metafies some bytes (see unmetafy); both are undone so commands read
+// as fc -l shows them.
 func ParseZshHistoryFile(path string) ([]*ZshHistoryEntry, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -48,15 +52,23 @@ func ParseZshHistoryFile(path string) ([]*ZshHistoryEntry, error) {
 	}
 	defer file.Close()
 
+	return ParseZshHistoryReader(file)
+}
+
+// ParseZshHistoryReader parses zsh history from r, like ParseZshHistoryFile
+func ParseZshHistoryReader(r io.Reader) ([]*ZshHistoryEntry, error) {
 	var entries []*ZshHistoryEntry
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	// Increase buffer size to handle very long command lines (up to 1MB)
+	const maxScanTokenSize = 1024 * 1024 // 1MB
+	buf := make([]byte, maxScanTokenSize)
+	scanner.Buffer(buf, maxScanTokenSize)
 
+	add := func(line string) {
 		// Skip empty lines
 		if strings.TrimSpace(line) == "" {
-			continue
+			return
 		}
 
 		entry := parseZshLine(line)
@@ -65,13 +77,80 @@ func ParseZshHistoryFile(path string) ([]*ZshHistoryEntry, error) {
 		}
 	}
 
+	var current string
+	continued := false
+	for scanner.Scan() {
+		line := unmetafy(scanner.Text())
+		if continued {
+			line = current + "\n" + line
+		}
+
+		// A trailing backslash stands for a newline in the command
+		if strings.HasSuffix(line, "\\") {
+			current, continued = strings.TrimSuffix(line, "\\"), true
+			continue
+		}
+		continued = false
+		add(line)
+	}
+
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading history file: %w", err)
 	}
 
+	// The file ended in the middle of a command
+	if continued {
+		add(current)
+	}
+
 	return entries, nil
 }
 
+// zshMeta marks a metafied byte in zsh history files
+const zshMeta = 0x83
+
+// unmetafy decodes a line as zsh stores it: bytes zsh uses internally
+// (0x83 to 0xa2, and NUL) are written as zshMeta followed by the byte xor 32.
+// Multi-byte UTF-8 characters such as "→" contain such bytes.
+func unmetafy(line string) string {
+	if strings.IndexByte(line, zshMeta) == -1 {
+		return line
+	}
+
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == zshMeta && i+1 < len(line) {
+			i++
+			out = append(out, line[i]^32)
+			continue
+		}
+		out = append(out, line[i])
+	}
+	return string(out)
+}
+
+// FormatZshLine returns a command as a line of a zsh extended_history file,
+// without the trailing newline: the inverse of the parsing above, so lines
+// of a multi-line command end in a backslash and bytes zsh uses internally
+// are metafied
+func FormatZshLine(timestamp, duration int64, command string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ": %d:%d;", timestamp, duration)
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\n':
+			b.WriteString("\\\n")
+		case c == 0 || (c >= zshMeta && c <= 0xa2):
+			b.WriteByte(zshMeta)
+			b.WriteByte(c ^ 32)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
 // parseZshLine parses a single line from zsh history
 func parseZshLine(line string) *ZshHistoryEntry {
 	// Extended history format: : <timestamp>:<duration>;<command>
@@ -0,0 +1,193 @@
+// Package paths normalizes and shortens the directories commands ran in
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WSLMode is whether Windows paths are rewritten to their WSL mount
+type WSLMode string
+
+// WSL modes
+const (
+	WSLAuto WSLMode = "auto" // Only when running under WSL
+	WSLOn   WSLMode = "on"
+	WSLOff  WSLMode = "off"
+)
+
+// ParseWSLMode parses a WSL mode, "" is WSLAuto
+func ParseWSLMode(s string) (WSLMode, error) {
+	switch mode := WSLMode(s); mode {
+	case "":
+		return WSLAuto, nil
+	case WSLAuto, WSLOn, WSLOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid paths wsl %q (must be auto, on or off)", s)
+}
+
+// Enabled reports whether the mode rewrites Windows paths here
+func (m WSLMode) Enabled() bool {
+	switch m {
+	case WSLOn:
+		return true
+	case WSLOff:
+		return false
+	}
+	return IsWSL()
+}
+
+// procVersion is read to detect WSL, replaced in tests
+var procVersion = "/proc/version"
+
+// IsWSL reports whether fh runs under the Windows Subsystem for Linux
+func IsWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	data, err := os.ReadFile(procVersion)
+	return err == nil && strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
+
+// ToWSL returns the WSL path of a Windows path: C:\Users\me and C:/Users/me
+// are /mnt/c/Users/me, \\wsl$\Ubuntu\home\me is /home/me. Other paths are
+// returned as they are.
+func ToWSL(p string) string {
+	// OSC 7 from Windows shells reports file:///C:/Users/me as /C:/Users/me
+	if len(p) >= 3 && p[0] == '/' && isDrive(p[1:]) {
+		p = p[1:]
+	}
+
+	slashed := strings.ReplaceAll(p, `\`, "/")
+	if isDrive(slashed) {
+		rest := strings.TrimRight(slashed[2:], "/")
+		return "/mnt/" + strings.ToLower(slashed[:1]) + rest
+	}
+	for _, share := range []string{"//wsl$/", "//wsl.localhost/"} {
+		if len(slashed) > len(share) && strings.EqualFold(slashed[:len(share)], share) {
+			// Drop the distro name
+			_, rest, _ := strings.Cut(slashed[len(share):], "/")
+			return "/" + strings.TrimRight(rest, "/")
+		}
+	}
+	return p
+}
+
+// isDrive reports whether p starts with a drive like C: followed by a
+// separator or nothing
+func isDrive(p string) bool {
+	if len(p) < 2 || p[1] != ':' {
+		return false
+	}
+	letter := p[0] | 0x20
+	if letter < 'a' || letter > 'z' {
+		return false
+	}
+	return len(p) == 2 || p[2] == '/' || p[2] == '\\'
+}
+
+// Rule replaces the directory From, and what is under it, with To
+type Rule struct {
+	From string
+	To   string
+}
+
+// Rules rewrite paths by the longest From they are in
+type Rules []Rule
+
+// Apply returns p with the longest From it is in or under replaced by its
+// To. From matches whole directories: /home/me is not a prefix of /home/meg.
+func (r Rules) Apply(p string) string {
+	best := -1
+	for i, rule := range r {
+		from := strings.TrimRight(rule.From, "/")
+		if from == "" || (p != from && !strings.HasPrefix(p, from+"/")) {
+			continue
+		}
+		if best == -1 || len(from) > len(strings.TrimRight(r[best].From, "/")) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return p
+	}
+	return r[best].To + p[len(strings.TrimRight(r[best].From, "/")):]
+}
+
+// Reverse returns the rules undoing r, for rules whose To are distinct
+// directories like ~ and ~name
+func (r Rules) Reverse() Rules {
+	reversed := make(Rules, 0, len(r))
+	for _, rule := range r {
+		reversed = append(reversed, Rule{From: rule.To, To: strings.TrimRight(rule.From, "/")})
+	}
+	return reversed
+}
+
+// Normalizer rewrites the directory of a command as it is saved
+type Normalizer struct {
+	WSL   bool  // Windows paths become their WSL mount
+	Rules Rules // Applied after, e.g. /mnt/c/Users/me to /home/me
+}
+
+// Normalize returns p rewritten, "" stays ""
+func (n Normalizer) Normalize(p string) string {
+	if p == "" {
+		return p
+	}
+	if n.WSL {
+		p = ToWSL(p)
+	}
+	return n.Rules.Apply(p)
+}
+
+// ProjectRoot returns the nearest directory at or above dir with a .git in
+// it, "" outside a repository or when dir is not on this machine anymore
+func ProjectRoot(dir string) string {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ByProject returns a function grouping directories by ProjectRoot, each
+// looked up once. Directories outside a repository are their own group.
+func ByProject() func(dir string) string {
+	roots := make(map[string]string)
+	return func(dir string) string {
+		root, ok := roots[dir]
+		if !ok {
+			root = ProjectRoot(dir)
+			if root == "" {
+				root = dir
+			}
+			roots[dir] = root
+		}
+		return root
+	}
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,123 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToWSL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`C:\Users\me\src`, "/mnt/c/Users/me/src"},
+		{`c:/Users/me/`, "/mnt/c/Users/me"},
+		{`D:\`, "/mnt/d"},
+		{"/C:/Users/me", "/mnt/c/Users/me"},
+		{`\\wsl$\Ubuntu\home\me`, "/home/me"},
+		{`\\wsl.localhost\Debian\srv`, "/srv"},
+		{"/mnt/c/Users/me", "/mnt/c/Users/me"},
+		{"/home/me", "/home/me"},
+		{"C:file", "C:file"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ToWSL(tt.in), tt.in)
+	}
+}
+
+func TestRulesApply(t *testing.T) {
+	rules := Rules{
+		{From: "/home/me", To: "~"},
+		{From: "/home/me/src/fh/", To: "fh:"},
+	}
+
+	assert.Equal(t, "~", rules.Apply("/home/me"))
+	assert.Equal(t, "~/docs", rules.Apply("/home/me/docs"))
+	assert.Equal(t, "fh:/cmd", rules.Apply("/home/me/src/fh/cmd"), "the longest from wins")
+	assert.Equal(t, "/home/meg", rules.Apply("/home/meg"), "whole directories only")
+	assert.Equal(t, "/srv", rules.Apply("/srv"))
+	assert.Equal(t, "/srv", Rules(nil).Apply("/srv"))
+}
+
+func TestNormalizer(t *testing.T) {
+	n := Normalizer{WSL: true, Rules: Rules{{From: "/mnt/c/Users/me", To: "/home/me/win"}}}
+	assert.Equal(t, "/home/me/win/src", n.Normalize(`C:\Users\me\src`))
+	assert.Equal(t, "/home/me/win", n.Normalize("/mnt/c/Users/me"))
+	assert.Equal(t, "", n.Normalize(""))
+
+	n.WSL = false
+	assert.Equal(t, `C:\Users\me`, n.Normalize(`C:\Users\me`))
+}
+
+func TestWSLMode(t *testing.T) {
+	mode, err := ParseWSLMode("")
+	require.NoError(t, err)
+	assert.Equal(t, WSLAuto, mode)
+	_, err = ParseWSLMode("yes")
+	assert.ErrorContains(t, err, "invalid paths wsl")
+
+	assert.True(t, WSLOn.Enabled())
+	assert.False(t, WSLOff.Enabled())
+
+	version := filepath.Join(t.TempDir(), "version")
+	procVersion = version
+	t.Cleanup(func() { procVersion = "/proc/version" })
+	t.Setenv("WSL_DISTRO_NAME", "")
+
+	require.NoError(t, os.WriteFile(version, []byte("Linux version 6.6.0-generic"), 0600))
+	assert.False(t, WSLAuto.Enabled())
+	require.NoError(t, os.WriteFile(version, []byte("Linux version 5.15.153.1-microsoft-standard-WSL2"), 0600))
+	assert.True(t, WSLAuto.Enabled())
+
+	require.NoError(t, os.Remove(version))
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	assert.True(t, IsWSL())
+}
+
+func TestRulesReverse(t *testing.T) {
+	rules := Rules{{From: "/home/me", To: "~"}, {From: "/home/me/src/infra/", To: "~infra"}}
+	reversed := rules.Reverse()
+
+	for _, p := range []string{"/home/me", "/home/me/docs", "/home/me/src/infra/modules", "/srv"} {
+		assert.Equal(t, p, reversed.Apply(rules.Apply(p)), p)
+	}
+	assert.Equal(t, "~infrastructure", reversed.Apply("~infrastructure"), "unknown names are kept")
+}
+
+func TestProjectRoot(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "src", "mono")
+	pkg := filepath.Join(repo, "services", "api")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	require.NoError(t, os.MkdirAll(pkg, 0755))
+
+	// A worktree's .git is a file
+	worktree := filepath.Join(root, "src", "mono-fix")
+	require.NoError(t, os.MkdirAll(worktree, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../mono/.git/worktrees/fix"), 0600))
+
+	assert.Equal(t, repo, ProjectRoot(repo))
+	assert.Equal(t, repo, ProjectRoot(pkg))
+	assert.Equal(t, worktree, ProjectRoot(worktree))
+	assert.Equal(t, "", ProjectRoot("relative/dir"))
+
+	group := ByProject()
+	assert.Equal(t, repo, group(pkg))
+	assert.Equal(t, filepath.Join(root, "src"), group(filepath.Join(root, "src")), "outside a repository")
+	assert.Equal(t, "/gone/elsewhere", group("/gone/elsewhere"))
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	assert.Equal(t, home, ExpandHome("~"))
+	assert.Equal(t, filepath.Join(home, "src"), ExpandHome("~/src"))
+	assert.Equal(t, "~other/src", ExpandHome("~other/src"))
+	assert.Equal(t, "/tmp", ExpandHome("/tmp"))
+}
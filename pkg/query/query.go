@@ -0,0 +1,397 @@
+// Package query parses the search query language shared by the picker's
+// pre-filter, fh --export --search and saved filters:
+//
+//	exit:!0 since:3d dir:~/work "docker build"
+//
+// Words match the command, directory, git branch or note. A prefix limits a
+// word to one field or adds a condition:
+//
+//	cmd:<text>        command contains text
+//	cwd:<text>        directory contains text (also dir:, ~ is expanded)
+//	branch:<text>     git branch contains text
+//	note:<text>       note contains text
+//	program:<name>    primary program is name, e.g. program:git
+//	exit:<code>       exit code is code, exit:!<code> for any other
+//	since:<when>      run at or after when, a window back from now (30m,
+//	                  12h, 3d, 2w) or a date (2025-01-07)
+//	until:<when>      run before when, a date includes that whole day
+//	@<name>           the query saved as search.filters.<name>
+//
+// A ! before a word or a field value leaves out what it matches (!sudo,
+// cmd:!sudo). Double quotes keep spaces and special characters in a word
+// ("docker build", note:"flaky test", "!!"). A word with an unknown prefix,
+// such as 80:80, is searched as written.
+//
+// Text matches ignore case unless Options.Case says otherwise; with the
+// default smart case a word with an upper-case letter matches case exactly.
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/paths"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Query is a parsed query, every condition must hold
+type Query struct {
+	Text        string // The query as typed
+	Terms       []storage.SearchTerm
+	Program     string
+	ExitCode    *int
+	NotExitCode *int
+	After       int64 // Unix time, 0 = no lower bound
+	Before      int64 // Unix time, 0 = no upper bound (exclusive)
+}
+
+// Case is how text matches treat upper and lower case
+type Case string
+
+// Case modes
+const (
+	CaseSmart       Case = "smart"       // Exact if the text has an upper-case letter
+	CaseSensitive   Case = "sensitive"   // Always exact
+	CaseInsensitive Case = "insensitive" // Never exact
+)
+
+// ParseCase parses a case mode, "" is CaseSmart
+func ParseCase(s string) (Case, error) {
+	switch c := Case(s); c {
+	case "":
+		return CaseSmart, nil
+	case CaseSmart, CaseSensitive, CaseInsensitive:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid case %q (must be smart, sensitive or insensitive)", s)
+}
+
+// Sensitive reports whether text is matched case-sensitively
+func (c Case) Sensitive(text string) bool {
+	switch c {
+	case CaseSensitive:
+		return true
+	case CaseInsensitive:
+		return false
+	}
+	return strings.ToLower(text) != text
+}
+
+// Options are the context a query is parsed in
+type Options struct {
+	Now     time.Time         // Reference for since: and until:, zero = time.Now()
+	Filters map[string]string // Saved filters by name, for @name
+	Case    Case              // How text matches treat case, "" = CaseSmart
+}
+
+// textFields maps the prefixes of text conditions to the field they search
+var textFields = map[string]storage.SearchField{
+	"cmd":     storage.FieldCommand,
+	"command": storage.FieldCommand,
+	"cwd":     storage.FieldCwd,
+	"dir":     storage.FieldCwd,
+	"branch":  storage.FieldBranch,
+	"note":    storage.FieldNote,
+}
+
+// Parse parses a query, see the package documentation for the syntax
+func Parse(query string, opts Options) (*Query, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	q := &Query{Text: query}
+	if err := q.parse(query, opts, nil); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parse adds the conditions of query to q, expanding saved filters. using
+// lists the saved filters being expanded, to catch loops.
+func (q *Query) parse(query string, opts Options, using []string) error {
+	words, err := split(query)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range words {
+		if err := q.add(w, opts, using); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds the condition of one word to q
+func (q *Query) add(w word, opts Options, using []string) error {
+	if w.prefix == "" {
+		if strings.HasPrefix(w.text, "@") && !w.quoted {
+			if w.negate {
+				return fmt.Errorf("saved filter %s cannot be negated", w.text)
+			}
+			return q.addSaved(strings.TrimPrefix(w.text, "@"), opts, using)
+		}
+		if w.text != "" {
+			q.addTerm(storage.FieldAny, w.text, w.negate, opts)
+		}
+		return nil
+	}
+
+	prefix := strings.ToLower(w.prefix)
+	field, isText := textFields[prefix]
+	if !isText && !isCondition(prefix) {
+		// Not a field, keep the word as it was typed
+		text := w.prefix + ":" + w.text
+		if w.valueNegate {
+			text = w.prefix + ":!" + w.text
+		}
+		q.addTerm(storage.FieldAny, text, w.negate, opts)
+		return nil
+	}
+
+	if w.text == "" {
+		return fmt.Errorf("empty value for %s: in search query", w.prefix)
+	}
+	negate := w.negate != w.valueNegate
+
+	switch {
+	case isText:
+		text := w.text
+		if field == storage.FieldCwd {
+			text = paths.ExpandHome(text)
+		}
+		q.addTerm(field, text, negate, opts)
+
+	case prefix == "program":
+		if negate {
+			return fmt.Errorf("program: cannot be negated in search query")
+		}
+		q.Program = w.text
+
+	case prefix == "exit":
+		code, err := strconv.Atoi(w.text)
+		if err != nil {
+			return fmt.Errorf("invalid exit code %q in search query", w.text)
+		}
+		if negate {
+			q.NotExitCode, q.ExitCode = &code, nil
+		} else {
+			q.ExitCode, q.NotExitCode = &code, nil
+		}
+
+	case prefix == "since", prefix == "until":
+		if negate {
+			return fmt.Errorf("%s: cannot be negated in search query", prefix)
+		}
+		start, end, err := parseWhen(w.text, opts.Now)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", prefix, err)
+		}
+		if prefix == "since" {
+			q.After = start.Unix()
+		} else {
+			q.Before = end.Unix()
+		}
+	}
+
+	return nil
+}
+
+// addTerm adds a text match, case-sensitive as opts.Case says for text
+func (q *Query) addTerm(field storage.SearchField, text string, negate bool, opts Options) {
+	q.Terms = append(q.Terms, storage.SearchTerm{
+		Field:         field,
+		Text:          text,
+		Negate:        negate,
+		CaseSensitive: opts.Case.Sensitive(text),
+	})
+}
+
+func isCondition(prefix string) bool {
+	switch prefix {
+	case "program", "exit", "since", "until":
+		return true
+	}
+	return false
+}
+
+// addSaved adds the conditions of the saved filter name
+func (q *Query) addSaved(name string, opts Options, using []string) error {
+	saved, ok := opts.Filters[name]
+	if !ok {
+		return fmt.Errorf("unknown saved filter @%s", name)
+	}
+	for _, n := range using {
+		if n == name {
+			return fmt.Errorf("saved filter @%s refers to itself", name)
+		}
+	}
+	if err := q.parse(saved, opts, append(using, name)); err != nil {
+		return fmt.Errorf("saved filter @%s: %w", name, err)
+	}
+	return nil
+}
+
+// ParseDuration parses a time window such as 30m, 12h, 3d or 2w
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if s != "" {
+		if unit, ok := units[s[len(s)-1]]; ok {
+			n, err := strconv.Atoi(s[:len(s)-1])
+			if err == nil && n > 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 30m, 12h, 1d, 2w)", s)
+	}
+	return d, nil
+}
+
+// parseWhen parses a since: or until: value into the span it stands for: a
+// window is the single instant that long before now, a date its whole day
+func parseWhen(s string, now time.Time) (time.Time, time.Time, error) {
+	if d, err := ParseDuration(s); err == nil {
+		t := now.Add(-d)
+		return t, t, nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%q is neither a window (3d) nor a date (2025-01-07)", s)
+	}
+	return day, day.AddDate(0, 0, 1), nil
+}
+
+// Apply adds the query's conditions to filters
+func (q *Query) Apply(filters *storage.QueryFilters) {
+	filters.Terms = append(filters.Terms, q.Terms...)
+	if q.Program != "" {
+		filters.Program = q.Program
+	}
+	if q.ExitCode != nil {
+		filters.ExitCode = q.ExitCode
+	}
+	if q.NotExitCode != nil {
+		filters.NotExitCode = q.NotExitCode
+	}
+	if q.After > filters.After {
+		filters.After = q.After
+	}
+	// Before is inclusive in QueryFilters
+	if q.Before > 0 && (filters.Before == 0 || q.Before-1 < filters.Before) {
+		filters.Before = q.Before - 1
+	}
+}
+
+// Matches reports whether entry meets every condition of the query
+func (q *Query) Matches(entry *storage.HistoryEntry) bool {
+	for _, term := range q.Terms {
+		if !term.Matches(entry) {
+			return false
+		}
+	}
+	if q.Program != "" && entry.Program != q.Program {
+		return false
+	}
+	if q.ExitCode != nil && entry.ExitCode != *q.ExitCode {
+		return false
+	}
+	if q.NotExitCode != nil && entry.ExitCode == *q.NotExitCode {
+		return false
+	}
+	if q.After > 0 && entry.Timestamp < q.After {
+		return false
+	}
+	if q.Before > 0 && entry.Timestamp >= q.Before {
+		return false
+	}
+	return true
+}
+
+// Spans returns where the query's terms match text, the value of field, in
+// order and without overlaps
+func (q *Query) Spans(field storage.SearchField, text string) []storage.Span {
+	var spans []storage.Span
+	for _, term := range q.Terms {
+		spans = append(spans, term.Spans(field, text)...)
+	}
+	if len(spans) < 2 {
+		return spans
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span.Start <= last.End {
+			last.End = max(last.End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// word is a word of a query, split from its prefix
+type word struct {
+	prefix      string // Text before an unquoted colon, if any
+	text        string
+	negate      bool // Unquoted ! before the word
+	valueNegate bool // Unquoted ! right after the prefix
+	quoted      bool // Any part of the word was quoted
+}
+
+// split splits a query on spaces outside double quotes
+func split(query string) ([]word, error) {
+	var words []word
+	var current strings.Builder
+	var w word
+	inQuotes, inWord := false, false
+
+	flush := func() {
+		if inWord {
+			w.text = current.String()
+			words = append(words, w)
+		}
+		current.Reset()
+		w = word{}
+		inWord = false
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inWord, w.quoted = true, true
+		case inQuotes:
+			current.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '!' && !inWord:
+			w.negate, inWord = true, true
+		case r == '!' && w.prefix != "" && current.Len() == 0 && !w.valueNegate:
+			w.valueNegate = true
+		case r == ':' && !w.quoted && w.prefix == "" && current.Len() > 0:
+			// Only the first colon, before any quote, starts a value
+			w.prefix = current.String()
+			current.Reset()
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in search query")
+	}
+	flush()
+
+	return words, nil
+}
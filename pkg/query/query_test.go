@@ -0,0 +1,271 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 1, 7, 14, 0, 0, 0, time.Local)
+
+func intPtr(i int) *int { return &i }
+
+func TestParse_Terms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []storage.SearchTerm
+	}{
+		{"", nil},
+		{"kubectl", []storage.SearchTerm{{Text: "kubectl"}}},
+		{"cwd:infra branch:main kubectl", []storage.SearchTerm{
+			{Field: storage.FieldCwd, Text: "infra"},
+			{Field: storage.FieldBranch, Text: "main"},
+			{Text: "kubectl"},
+		}},
+		{"cmd:git dir:src NOTE:flaky", []storage.SearchTerm{
+			{Field: storage.FieldCommand, Text: "git"},
+			{Field: storage.FieldCwd, Text: "src"},
+			{Field: storage.FieldNote, Text: "flaky"},
+		}},
+		{`"docker build" note:"flaky test"`, []storage.SearchTerm{
+			{Text: "docker build"},
+			{Field: storage.FieldNote, Text: "flaky test"},
+		}},
+		{"  extra   spaces  ", []storage.SearchTerm{{Text: "extra"}, {Text: "spaces"}}},
+		// Unknown prefixes and quoted colons are plain text
+		{"docker run -p 80:80", []storage.SearchTerm{{Text: "docker"}, {Text: "run"}, {Text: "-p"}, {Text: "80:80"}}},
+		{"http://example.com", []storage.SearchTerm{{Text: "http://example.com"}}},
+		{`"cwd:infra"`, []storage.SearchTerm{{Text: "cwd:infra"}}},
+		{"cwd:a:b", []storage.SearchTerm{{Field: storage.FieldCwd, Text: "a:b"}}},
+		{`""`, nil},
+		// Negation
+		{"!sudo", []storage.SearchTerm{{Text: "sudo", Negate: true}}},
+		{"cmd:!sudo !note:wip", []storage.SearchTerm{
+			{Field: storage.FieldCommand, Text: "sudo", Negate: true},
+			{Field: storage.FieldNote, Text: "wip", Negate: true},
+		}},
+		{"!cmd:!sudo", []storage.SearchTerm{{Field: storage.FieldCommand, Text: "sudo"}}},
+		{`"!!" a!b`, []storage.SearchTerm{{Text: "!!"}, {Text: "a!b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query, Options{Now: now})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.Terms)
+			assert.Equal(t, tt.query, q.Text)
+		})
+	}
+}
+
+func TestParse_Conditions(t *testing.T) {
+	q, err := Parse("program:git exit:!0 since:3d until:2025-01-06", Options{Now: now})
+	require.NoError(t, err)
+
+	assert.Equal(t, "git", q.Program)
+	assert.Nil(t, q.ExitCode)
+	assert.Equal(t, intPtr(0), q.NotExitCode)
+	assert.Equal(t, now.AddDate(0, 0, -3).Unix(), q.After)
+	// A date includes the whole day
+	assert.Equal(t, time.Date(2025, 1, 7, 0, 0, 0, 0, time.Local).Unix(), q.Before)
+	assert.Empty(t, q.Terms)
+
+	q, err = Parse("exit:127 since:2025-01-06", Options{Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(127), q.ExitCode)
+	assert.Equal(t, time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local).Unix(), q.After)
+}
+
+func TestParse_Home(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+
+	q, err := Parse("dir:~/work cwd:~ cmd:~/bin", Options{Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.SearchTerm{
+		{Field: storage.FieldCwd, Text: "/home/me/work"},
+		{Field: storage.FieldCwd, Text: "/home/me"},
+		{Field: storage.FieldCommand, Text: "~/bin"},
+	}, q.Terms)
+}
+
+func TestParse_SavedFilters(t *testing.T) {
+	opts := Options{
+		Now: now,
+		Filters: map[string]string{
+			"failed": "exit:!0",
+			"work":   "dir:/src @failed",
+			"loop":   "kubectl @loop",
+			"broken": "cwd:",
+		},
+	}
+
+	q, err := Parse("@work docker", opts)
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(0), q.NotExitCode)
+	assert.Equal(t, []storage.SearchTerm{
+		{Field: storage.FieldCwd, Text: "/src"},
+		{Text: "docker"},
+	}, q.Terms)
+
+	// Quoted, it is only text
+	q, err = Parse(`"@work"`, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.SearchTerm{{Text: "@work"}}, q.Terms)
+
+	_, err = Parse("@loop", opts)
+	assert.EqualError(t, err, "saved filter @loop: saved filter @loop refers to itself")
+
+	_, err = Parse("@missing", opts)
+	assert.EqualError(t, err, "unknown saved filter @missing")
+
+	_, err = Parse("@broken", opts)
+	assert.EqualError(t, err, "saved filter @broken: empty value for cwd: in search query")
+
+	_, err = Parse("!@failed", opts)
+	assert.EqualError(t, err, "saved filter @failed cannot be negated")
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`"docker build`, "unterminated quote in search query"},
+		{"cwd: kubectl", "empty value for cwd: in search query"},
+		{"exit:ok", `invalid exit code "ok" in search query`},
+		{"program:!git", "program: cannot be negated in search query"},
+		{"!since:3d", "since: cannot be negated in search query"},
+		{"until:soon", `invalid until: "soon" is neither a window (3d) nor a date (2025-01-07)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query, Options{Now: now})
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30m", 30 * time.Minute},
+		{"12h", 12 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+
+	for _, value := range []string{"", "0d", "-1h", "3x", "d"} {
+		_, err := ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "git push", Program: "git", Cwd: "/src/app", ExitCode: 1, Timestamp: now.Add(-time.Hour).Unix()},
+		{Command: "git status", Program: "git", Cwd: "/src/app", Timestamp: now.Add(-time.Hour).Unix()},
+		{Command: "sudo git push", Program: "sudo", Cwd: "/src/app", ExitCode: 1, Timestamp: now.Add(-time.Hour).Unix()},
+		{Command: "git push", Program: "git", Cwd: "/src/app", ExitCode: 1, Timestamp: now.AddDate(0, 0, -5).Unix()},
+	}
+
+	match := func(query string) []*storage.HistoryEntry {
+		q, err := Parse(query, Options{Now: now})
+		require.NoError(t, err)
+
+		var got []*storage.HistoryEntry
+		for _, e := range entries {
+			if q.Matches(e) {
+				got = append(got, e)
+			}
+		}
+		return got
+	}
+
+	assert.Equal(t, entries[:1], match("push exit:!0 since:3d !sudo"))
+	assert.Equal(t, entries[1:2], match("program:git exit:0"))
+	assert.Equal(t, entries[3:], match("until:2d"))
+	assert.Len(t, match(""), 4)
+}
+
+func TestApply(t *testing.T) {
+	q, err := Parse("program:git exit:!0 since:3d until:1d kubectl", Options{Now: now})
+	require.NoError(t, err)
+
+	filters := storage.QueryFilters{Cwd: "/src", Limit: 10}
+	q.Apply(&filters)
+
+	assert.Equal(t, "/src", filters.Cwd)
+	assert.Equal(t, 10, filters.Limit)
+	assert.Equal(t, "git", filters.Program)
+	assert.Equal(t, intPtr(0), filters.NotExitCode)
+	assert.Equal(t, []storage.SearchTerm{{Text: "kubectl"}}, filters.Terms)
+	assert.Equal(t, now.AddDate(0, 0, -3).Unix(), filters.After)
+	// until: is exclusive, QueryFilters.Before inclusive
+	assert.Equal(t, now.AddDate(0, 0, -1).Unix()-1, filters.Before)
+
+	// The narrower bound wins
+	filters = storage.QueryFilters{After: now.Unix() - 60, Before: now.AddDate(0, 0, -2).Unix()}
+	q.Apply(&filters)
+	assert.Equal(t, now.Unix()-60, filters.After)
+	assert.Equal(t, now.AddDate(0, 0, -2).Unix(), filters.Before)
+}
+
+func TestSpans(t *testing.T) {
+	q, err := Parse("dock docker cmd:build branch:main !push", Options{Now: now})
+	require.NoError(t, err)
+
+	// Overlapping matches merge, other fields' terms and negated terms are left out
+	assert.Equal(t, []storage.Span{{Start: 0, End: 6}, {Start: 7, End: 12}, {Start: 16, End: 22}},
+		q.Spans(storage.FieldCommand, "docker build && DOCKER push"))
+	assert.Equal(t, []storage.Span{{Start: 0, End: 4}}, q.Spans(storage.FieldBranch, "main"))
+	assert.Empty(t, q.Spans(storage.FieldCwd, "/src/build"))
+}
+
+func TestParse_Case(t *testing.T) {
+	tests := []struct {
+		mode Case
+		want []bool
+	}{
+		{"", []bool{false, true}},
+		{CaseSmart, []bool{false, true}},
+		{CaseSensitive, []bool{true, true}},
+		{CaseInsensitive, []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			q, err := Parse("make cwd:Src", Options{Now: now, Case: tt.mode})
+			require.NoError(t, err)
+			require.Len(t, q.Terms, 2)
+			assert.Equal(t, tt.want[0], q.Terms[0].CaseSensitive)
+			assert.Equal(t, tt.want[1], q.Terms[1].CaseSensitive)
+		})
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	for _, s := range []string{"", "smart"} {
+		mode, err := ParseCase(s)
+		require.NoError(t, err)
+		assert.Equal(t, CaseSmart, mode)
+	}
+
+	mode, err := ParseCase("insensitive")
+	require.NoError(t, err)
+	assert.Equal(t, CaseInsensitive, mode)
+
+	_, err = ParseCase("ignore")
+	assert.EqualError(t, err, `invalid case "ignore" (must be smart, sensitive or insensitive)`)
+}
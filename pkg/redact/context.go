@@ -0,0 +1,118 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Masks for who and where a command ran
+const (
+	UserMask = "<user>"
+	HostMask = "<host>"
+)
+
+// homeDir matches a home directory and its owner
+var homeDir = regexp.MustCompile(`(/home|/Users)/([A-Za-z0-9._-]+)`)
+
+// userAt matches user@host, in ssh targets, scp paths and email addresses
+// alike, but also package@version
+var userAt = regexp.MustCompile(`(^|[\s:'"=/<])([A-Za-z_][A-Za-z0-9._+-]*)@([A-Za-z0-9][A-Za-z0-9.-]*)`)
+
+// domain matches a host name ending in a top level domain
+var domain = regexp.MustCompile(`\.[A-Za-z]{2,}$`)
+
+// Scrubber hides secrets, the home directory and user and host names in
+// history before it leaves the machine
+type Scrubber struct {
+	home  string
+	users map[string]bool
+	hosts *regexp.Regexp
+}
+
+// NewScrubber returns a scrubber that writes home as ~, masks users where
+// they log in as user@host and masks hosts wherever they appear. Host names
+// too short or too common to tell from other words are left alone.
+func NewScrubber(home string, users, hosts []string) *Scrubber {
+	s := &Scrubber{home: strings.TrimSuffix(home, "/"), users: map[string]bool{}}
+	for _, user := range users {
+		if user != "" {
+			s.users[user] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		if len(host) < 3 || host == "localhost" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		names = append(names, regexp.QuoteMeta(host))
+		// laptop.local is often typed as laptop
+		if short, _, found := strings.Cut(host, "."); found && len(short) >= 3 && !seen[short] {
+			seen[short] = true
+			names = append(names, regexp.QuoteMeta(short))
+		}
+	}
+	if len(names) > 0 {
+		// Longest first, so laptop.local is masked whole
+		sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+		s.hosts = regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)\b`)
+	}
+	return s
+}
+
+// Scrub returns text with secrets masked, the home directory written as ~,
+// other home directories masked, known users and email addresses masked in
+// user@host, and the known host names masked
+func (s *Scrubber) Scrub(text string) string {
+	text, _ = Command(text)
+	text = MaskFindings(text, Scan(text))
+
+	if s.home != "" && s.home != "/" {
+		text = replaceHome(text, s.home)
+	}
+	text = homeDir.ReplaceAllString(text, "${1}/"+UserMask)
+	text = userAt.ReplaceAllStringFunc(text, func(match string) string {
+		parts := userAt.FindStringSubmatch(match)
+		lead, user, host := parts[1], parts[2], parts[3]
+		if !s.users[user] && !domain.MatchString(host) {
+			return match
+		}
+		return lead + UserMask + "@" + host
+	})
+
+	if s.hosts != nil {
+		text = s.hosts.ReplaceAllString(text, HostMask)
+	}
+	return text
+}
+
+// replaceHome writes home as ~ where it is a whole path component, so
+// /home/al does not turn /home/alice into ~ice
+func replaceHome(text, home string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(text, home)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		end := i + len(home)
+		if end == len(text) || !isPathChar(text[end]) {
+			b.WriteString(text[:i])
+			b.WriteString("~")
+		} else {
+			b.WriteString(text[:end])
+		}
+		text = text[end:]
+	}
+}
+
+// isPathChar reports whether c can continue a path component
+func isPathChar(c byte) bool {
+	return c == '.' || c == '-' || c == '_' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
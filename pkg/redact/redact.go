@@ -0,0 +1,126 @@
+// Package redact masks secrets that commands commonly carry in their
+// arguments, such as the value of a --password flag or an Authorization
+// header, before the command is stored.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+)
+
+// Mask replaces every redacted value
+const Mask = "****"
+
+// sensitiveFlag matches a flag naming a secret, --password, --db-password,
+// -token or --api-key, and its value after = or a space. Quoted values are
+// taken whole.
+var sensitiveFlag = regexp.MustCompile(`(?i)(^|\s)(--?(?:[a-z0-9]+[-_])*(?:password|passwd|pass|token|api[-_]?key|secret))(=|\s+)('[^']*'|"[^"]*"|[^\s'";|&]+)`)
+
+// authorization matches an Authorization header value, keeping its scheme
+var authorization = regexp.MustCompile(`(?i)(authorization:\s*)((?:bearer|basic|token|digest)\s+)?([^\s'"]+)`)
+
+// attachedPassword matches -psecret, the MySQL client's way of passing a
+// password. Elsewhere -p is a port, a parent flag or a patch mode.
+var attachedPassword = regexp.MustCompile(`(^|\s)-p([^\s;|&]+)`)
+
+// mysqlClients take their password attached to -p
+var mysqlClients = map[string]bool{
+	"mysql": true, "mysqldump": true, "mysqladmin": true, "mysqlimport": true,
+	"mysqlshow": true, "mysqlcheck": true, "mariadb": true, "mariadb-dump": true,
+	"mariadb-admin": true,
+}
+
+// Command returns command with the values of well-known secret flags, the
+// credentials of well-known formats such as AWS access keys, and passwords
+// in URLs masked, and whether anything was masked
+func Command(command string) (string, bool) {
+	redacted := sensitiveFlag.ReplaceAllStringFunc(command, func(match string) string {
+		parts := sensitiveFlag.FindStringSubmatch(match)
+		lead, flag, sep, value := parts[1], parts[2], parts[3], parts[4]
+		// --no-password --verbose has no value to hide
+		if sep != "=" && strings.HasPrefix(value, "-") {
+			return match
+		}
+		return lead + flag + sep + Mask
+	})
+
+	redacted = authorization.ReplaceAllString(redacted, "${1}${2}"+Mask)
+
+	if usesMySQL(redacted) {
+		redacted = attachedPassword.ReplaceAllString(redacted, "${1}-p"+Mask)
+	}
+
+	for _, format := range tokenFormats {
+		// The marker of a private key is no secret, its lines are
+		if format.kind != "private-key" {
+			redacted = format.re.ReplaceAllString(redacted, Mask)
+		}
+	}
+	redacted = maskGroup(urlPassword, redacted)
+
+	return redacted, redacted != command
+}
+
+// Redactor masks what Command does and the matches of extra patterns
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor for patterns, regexes of secrets. Only the
+// first capture group of a pattern having one is masked, so the secret can
+// be told apart from what surrounds it.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Command returns command with its secrets masked, and whether anything
+// was masked
+func (r *Redactor) Command(command string) (string, bool) {
+	redacted, _ := Command(command)
+	for _, re := range r.patterns {
+		redacted = maskGroup(re, redacted)
+	}
+	return redacted, redacted != command
+}
+
+// maskGroup masks the matches of re in command, or only their first group
+// when re has one
+func maskGroup(re *regexp.Regexp, command string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllString(command, Mask)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(command, -1) {
+		if m[2] < 0 {
+			continue
+		}
+		b.WriteString(command[last:m[2]])
+		b.WriteString(Mask)
+		last = m[3]
+	}
+	b.WriteString(command[last:])
+	return b.String()
+}
+
+// usesMySQL reports whether command runs a MySQL client
+func usesMySQL(command string) bool {
+	for _, program := range shellparse.Programs(command) {
+		if mysqlClients[program] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package redact
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Risk is how likely a finding is a live credential
+type Risk int
+
+const (
+	Low    Risk = iota // A long random-looking word
+	Medium             // A value in a place secrets go, such as --password
+	High               // A known token format
+)
+
+// String returns the name of risk, as --min-risk takes it
+func (r Risk) String() string {
+	switch r {
+	case High:
+		return "high"
+	case Medium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// ParseRisk parses low, medium or high
+func ParseRisk(s string) (Risk, bool) {
+	for _, r := range []Risk{Low, Medium, High} {
+		if r.String() == s {
+			return r, true
+		}
+	}
+	return Low, false
+}
+
+// Finding is a credential-like part of a command
+type Finding struct {
+	Kind  string // e.g. aws-access-key, password-flag, high-entropy
+	Risk  Risk
+	Start int // Byte offsets of the value in the command
+	End   int
+}
+
+// tokenFormats are credentials recognizable by their shape
+var tokenFormats = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{16,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+}
+
+// urlPassword matches the password of credentials in a URL
+var urlPassword = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s/:@'"]+:([^\s/@'"]+)@`)
+
+// randomWord matches candidates for the entropy check
+var randomWord = regexp.MustCompile(`[A-Za-z0-9+/_-]{20,}={0,2}`)
+
+// Scan returns the credential-like parts of command, in order. Masked
+// values and $VARIABLES are not findings.
+func Scan(command string) []Finding {
+	var findings []Finding
+	add := func(kind string, risk Risk, start, end int) {
+		value := command[start:end]
+		if value == Mask || strings.HasPrefix(value, "$") {
+			return
+		}
+		for _, f := range findings {
+			if start < f.End && f.Start < end {
+				return
+			}
+		}
+		findings = append(findings, Finding{Kind: kind, Risk: risk, Start: start, End: end})
+	}
+
+	for _, format := range tokenFormats {
+		for _, loc := range format.re.FindAllStringIndex(command, -1) {
+			add(format.kind, High, loc[0], loc[1])
+		}
+	}
+
+	for _, m := range sensitiveFlag.FindAllStringSubmatchIndex(command, -1) {
+		sep, value := command[m[6]:m[7]], command[m[8]:m[9]]
+		if sep != "=" && strings.HasPrefix(value, "-") {
+			continue
+		}
+		add("secret-flag", Medium, m[8], m[9])
+	}
+	for _, m := range authorization.FindAllStringSubmatchIndex(command, -1) {
+		add("authorization-header", Medium, m[6], m[7])
+	}
+	for _, m := range urlPassword.FindAllStringSubmatchIndex(command, -1) {
+		add("url-password", Medium, m[2], m[3])
+	}
+	if usesMySQL(command) {
+		for _, m := range attachedPassword.FindAllStringSubmatchIndex(command, -1) {
+			add("secret-flag", Medium, m[4], m[5])
+		}
+	}
+
+	for _, loc := range randomWord.FindAllStringIndex(command, -1) {
+		if looksRandom(command[loc[0]:loc[1]]) {
+			add("high-entropy", Low, loc[0], loc[1])
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// MaskFindings returns command with every finding replaced by Mask
+func MaskFindings(command string, findings []Finding) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(command[last:f.Start])
+		b.WriteString(Mask)
+		last = f.End
+	}
+	b.WriteString(command[last:])
+	return b.String()
+}
+
+// looksRandom reports whether word mixes letters and digits and has close to
+// the Shannon entropy of random characters of its length. Names, paths, git
+// hashes and UUIDs stay below it.
+func looksRandom(word string) bool {
+	var upper, lower, digit bool
+	counts := make(map[rune]int)
+	for _, r := range word {
+		counts[r]++
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	if !digit || !(upper || lower) {
+		return false
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(word))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= 0.85*math.Log2(math.Min(float64(len(word)), 64))
+}
@@ -0,0 +1,176 @@
+// Package runbook turns the commands run during a time window into a
+// markdown runbook, such as right after firefighting an incident.
+package runbook
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Step is a command of the runbook, with its retries folded in
+type Step struct {
+	Entry    *storage.HistoryEntry // Last attempt
+	Attempts int
+	Failures int // Attempts that exited non-zero
+}
+
+// Failed reports whether the last attempt failed
+func (s Step) Failed() bool {
+	return s.Entry.ExitCode != 0
+}
+
+// Runbook is the sequence of steps run between From and To
+type Runbook struct {
+	From  time.Time
+	To    time.Time
+	Cwd   string // Directory the commands were limited to, if any
+	Steps []Step
+}
+
+// New builds a runbook from entries in any order. A command run again right
+// after itself is a retry and folds into one step.
+func New(entries []*storage.HistoryEntry, from, to time.Time, cwd string) *Runbook {
+	sorted := make([]*storage.HistoryEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Timestamp != sorted[j].Timestamp {
+			return sorted[i].Timestamp < sorted[j].Timestamp
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	r := &Runbook{From: from, To: to, Cwd: cwd}
+	for _, entry := range sorted {
+		if n := len(r.Steps); n > 0 && r.Steps[n-1].Entry.Command == entry.Command {
+			r.Steps[n-1].Entry = entry
+		} else {
+			r.Steps = append(r.Steps, Step{Entry: entry})
+		}
+
+		step := &r.Steps[len(r.Steps)-1]
+		step.Attempts++
+		if entry.ExitCode != 0 {
+			step.Failures++
+		}
+	}
+
+	return r
+}
+
+// WriteMarkdown writes the runbook as markdown
+func (r *Runbook) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Runbook: %s to %s\n\n", r.From.Format("2006-01-02 15:04"), formatEnd(r.From, r.To))
+	if r.Cwd != "" {
+		fmt.Fprintf(&b, "Commands run in `%s`.\n\n", r.Cwd)
+	}
+
+	if len(r.Steps) == 0 {
+		b.WriteString("No commands were run in this window.\n")
+	}
+
+	cwd := r.Cwd
+	for i, step := range r.Steps {
+		entry := step.Entry
+		fmt.Fprintf(&b, "## %d. %s\n\n", i+1, time.Unix(entry.Timestamp, 0).Format("15:04:05"))
+
+		// Only mention the directory when it changes
+		if entry.Cwd != "" && entry.Cwd != cwd {
+			fmt.Fprintf(&b, "In `%s`:\n\n", entry.Cwd)
+			cwd = entry.Cwd
+		}
+
+		fence := codeFence(entry.Command)
+		fmt.Fprintf(&b, "%sbash\n%s\n%s\n\n", fence, entry.Command, fence)
+
+		if notes := step.notes(); len(notes) > 0 {
+			for _, note := range notes {
+				fmt.Fprintf(&b, "> %s\n", note)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write runbook: %w", err)
+	}
+	return nil
+}
+
+// notes returns the annotations shown under a step
+func (s Step) notes() []string {
+	var notes []string
+
+	switch {
+	case s.Failed() && s.Attempts > 1:
+		notes = append(notes, fmt.Sprintf("**Failed** with exit code %d after %d attempts.", s.Entry.ExitCode, s.Attempts))
+	case s.Failed():
+		notes = append(notes, fmt.Sprintf("**Failed** with exit code %d.", s.Entry.ExitCode))
+	case s.Failures > 0:
+		notes = append(notes, fmt.Sprintf("Succeeded after %d failed %s.", s.Failures, plural(s.Failures, "attempt", "attempts")))
+	case s.Attempts > 1:
+		notes = append(notes, fmt.Sprintf("Run %d times.", s.Attempts))
+	}
+
+	if s.Entry.Note != "" {
+		notes = append(notes, "Note: "+s.Entry.Note)
+	}
+
+	return notes
+}
+
+// formatEnd formats to, leaving out the date when it is the same as from
+func formatEnd(from, to time.Time) string {
+	if from.Year() == to.Year() && from.YearDay() == to.YearDay() {
+		return to.Format("15:04")
+	}
+	return to.Format("2006-01-02 15:04")
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in
+// command, so the command cannot close its own code block
+func codeFence(command string) string {
+	longest, run := 0, 0
+	for _, r := range command {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// ParseTime parses a runbook bound such as "2025-01-07 14:00" in local time.
+// A time of day alone, such as "15:30", is taken on the day of ref.
+func ParseTime(value string, ref time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, ref.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, ref.Location()); err == nil {
+			return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), t.Second(), 0, ref.Location()), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (e.g. \"2025-01-07 14:00\" or \"15:30\")", value)
+}
@@ -0,0 +1,111 @@
+package runbook
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	// Most recent first, as Query returns them
+	entries := []*storage.HistoryEntry{
+		{ID: 6, Command: "kubectl get pods", Timestamp: 600},
+		{ID: 5, Command: "kubectl rollout restart deploy/api", Timestamp: 500},
+		{ID: 4, Command: "kubectl rollout restart deploy/api", ExitCode: 1, Timestamp: 400},
+		{ID: 3, Command: "kubectl get pods", Timestamp: 300},
+		{ID: 2, Command: "kubectl get pods", Timestamp: 300},
+		{ID: 1, Command: "kubectl logs api", ExitCode: 1, Timestamp: 100},
+	}
+
+	r := New(entries, time.Unix(0, 0), time.Unix(1000, 0), "")
+
+	require.Len(t, r.Steps, 4)
+	assert.Equal(t, "kubectl logs api", r.Steps[0].Entry.Command)
+	assert.True(t, r.Steps[0].Failed())
+
+	// Retries fold into the last attempt
+	assert.Equal(t, int64(3), r.Steps[1].Entry.ID)
+	assert.Equal(t, 2, r.Steps[1].Attempts)
+	assert.Equal(t, int64(5), r.Steps[2].Entry.ID)
+	assert.Equal(t, 1, r.Steps[2].Failures)
+	assert.False(t, r.Steps[2].Failed())
+
+	// The same command later on is a new step
+	assert.Equal(t, "kubectl get pods", r.Steps[3].Entry.Command)
+	assert.Equal(t, 1, r.Steps[3].Attempts)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	from := time.Date(2025, 1, 7, 14, 0, 0, 0, time.Local)
+	at := func(minute int) int64 {
+		return from.Add(time.Duration(minute) * time.Minute).Unix()
+	}
+
+	entries := []*storage.HistoryEntry{
+		{Command: "terraform plan", Cwd: "/infra", ExitCode: 1, Timestamp: at(2)},
+		{Command: "terraform plan", Cwd: "/infra", Timestamp: at(3)},
+		{Command: "echo `date`", Cwd: "/infra/modules", Timestamp: at(4), Note: "for the log"},
+		{Command: "terraform apply", Cwd: "/infra", ExitCode: 2, Timestamp: at(10)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New(entries, from, from.Add(90*time.Minute), "/infra").WriteMarkdown(&buf))
+
+	want := "# Runbook: 2025-01-07 14:00 to 15:30\n\n" +
+		"Commands run in `/infra`.\n\n" +
+		"## 1. 14:03:00\n\n" +
+		"```bash\nterraform plan\n```\n\n" +
+		"> Succeeded after 1 failed attempt.\n\n" +
+		"## 2. 14:04:00\n\n" +
+		"In `/infra/modules`:\n\n" +
+		"```bash\necho `date`\n```\n\n" +
+		"> Note: for the log\n\n" +
+		"## 3. 14:10:00\n\n" +
+		"In `/infra`:\n\n" +
+		"```bash\nterraform apply\n```\n\n" +
+		"> **Failed** with exit code 2.\n\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteMarkdown_Empty(t *testing.T) {
+	from := time.Date(2025, 1, 7, 14, 0, 0, 0, time.Local)
+
+	var buf bytes.Buffer
+	require.NoError(t, New(nil, from, from.AddDate(0, 0, 1), "").WriteMarkdown(&buf))
+
+	assert.Equal(t, "# Runbook: 2025-01-07 14:00 to 2025-01-08 14:00\n\nNo commands were run in this window.\n", buf.String())
+}
+
+func TestCodeFence(t *testing.T) {
+	assert.Equal(t, "```", codeFence("ls"))
+	assert.Equal(t, "````", codeFence("echo ```"))
+}
+
+func TestParseTime(t *testing.T) {
+	ref := time.Date(2025, 1, 7, 14, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2025-01-07 14:00", ref},
+		{"2025-01-06 09:15:30", time.Date(2025, 1, 6, 9, 15, 30, 0, time.Local)},
+		{"2025-01-06", time.Date(2025, 1, 6, 0, 0, 0, 0, time.Local)},
+		{"15:30", time.Date(2025, 1, 7, 15, 30, 0, 0, time.Local)},
+		{"15:30:15", time.Date(2025, 1, 7, 15, 30, 15, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, ref)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+
+	_, err := ParseTime("yesterday", ref)
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pingTimeout bounds a health check ping, an unreachable monitor must not
+// hold up the job
+const pingTimeout = 10 * time.Second
+
+// maxPingBody is how much of the job's output goes along with a ping,
+// healthchecks.io keeps the first 100 KB
+const maxPingBody = 10 * 1024
+
+// Outcome is the state of a job a ping reports
+type Outcome string
+
+const (
+	Started   Outcome = "start"
+	Succeeded Outcome = ""
+	Failed    Outcome = "fail"
+)
+
+// PingURL returns the URL that reports outcome of job to a
+// healthchecks.io-style check at base. {job} in base is replaced by the
+// job name, so each job can have its own check.
+func PingURL(base, job string, outcome Outcome) string {
+	base = strings.ReplaceAll(base, "{job}", url.PathEscape(job))
+	if outcome == Succeeded {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + string(outcome)
+}
+
+// ValidateHealthcheckURL checks that base is an http(s) URL
+func ValidateHealthcheckURL(base string) error {
+	u, err := url.Parse(strings.ReplaceAll(base, "{job}", "job"))
+	if err != nil {
+		return fmt.Errorf("invalid healthcheck_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid healthcheck_url %q: must be an http or https URL", base)
+	}
+	return nil
+}
+
+// Ping reports outcome of job to the check at base, sending the tail of
+// the job's output as the body
+func Ping(ctx context.Context, client *http.Client, base, job string, outcome Outcome, output []byte) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(output) > maxPingBody {
+		output = output[len(output)-maxPingBody:]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, PingURL(base, job, outcome), bytes.NewReader(output))
+	if err != nil {
+		return fmt.Errorf("failed to ping health check: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to ping health check: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("health check ping returned %s", resp.Status)
+	}
+	return nil
+}
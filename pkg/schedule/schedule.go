@@ -0,0 +1,229 @@
+// Package schedule writes systemd user timers and launchd agents that run
+// fh maintenance jobs on a schedule, so nobody has to wire up cron.
+package schedule
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Job is a maintenance command and how often it runs. Timers start it
+// with fh --run-job, which pings the health check around it.
+type Job struct {
+	Name        string
+	Description string
+	Args        []string // Arguments to fh that do the work
+	OnCalendar  string   // systemd calendar expression
+	Interval    int      // launchd StartInterval in seconds, same rhythm
+}
+
+// Jobs are the jobs --install-timer knows
+var Jobs = map[string]Job{
+	"backup": {
+		Name:        "backup",
+		Description: "Snapshot the fh history database",
+		Args:        []string{"--snapshot", "backup"},
+		OnCalendar:  "daily",
+		Interval:    24 * 60 * 60,
+	},
+	"archive": {
+		Name:        "archive",
+		Description: "Archive old fh history entries",
+		Args:        []string{"--archive"},
+		OnCalendar:  "weekly",
+		Interval:    7 * 24 * 60 * 60,
+	},
+	"prune": {
+		Name:        "prune",
+		Description: "Remove fh history entries past the retention",
+		Args:        []string{"--prune"},
+		OnCalendar:  "daily",
+		Interval:    24 * 60 * 60,
+	},
+	"sync": {
+		Name:        "sync",
+		Description: "Send new fh history entries to the analytics sink",
+		Args:        []string{"--sink", "flush"},
+		OnCalendar:  "*:0/15",
+		Interval:    15 * 60,
+	},
+}
+
+// JobNames returns the names of Jobs, sorted
+func JobNames() []string {
+	names := make([]string, 0, len(Jobs))
+	for name := range Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Platform is the service manager the files are written for
+type Platform string
+
+const (
+	Systemd Platform = "systemd"
+	Launchd Platform = "launchd"
+)
+
+// CurrentPlatform returns launchd on macOS and systemd everywhere else
+func CurrentPlatform() Platform {
+	if runtime.GOOS == "darwin" {
+		return Launchd
+	}
+	return Systemd
+}
+
+// File is a unit or agent file to write
+type File struct {
+	Path    string
+	Content string
+}
+
+// label names the job for the service manager
+func (j Job) label(platform Platform) string {
+	if platform == Launchd {
+		return "io.github.spideyz0r.fh." + j.Name
+	}
+	return "fh-" + j.Name
+}
+
+// Files returns the files that run job with the fh binary at fhPath, under
+// the home directory
+func (j Job) Files(platform Platform, fhPath, home string) []File {
+	if platform == Launchd {
+		return []File{{
+			Path:    filepath.Join(home, "Library", "LaunchAgents", j.label(platform)+".plist"),
+			Content: j.plist(fhPath, home),
+		}}
+	}
+
+	dir := filepath.Join(home, ".config", "systemd", "user")
+	return []File{
+		{Path: filepath.Join(dir, j.label(platform)+".service"), Content: j.service(fhPath)},
+		{Path: filepath.Join(dir, j.label(platform)+".timer"), Content: j.timer()},
+	}
+}
+
+// Activate returns the commands that start the installed job
+func (j Job) Activate(platform Platform, home string) [][]string {
+	if platform == Launchd {
+		plist := j.Files(platform, "", home)[0].Path
+		return [][]string{{"launchctl", "load", "-w", plist}}
+	}
+	return [][]string{
+		{"systemctl", "--user", "daemon-reload"},
+		{"systemctl", "--user", "enable", "--now", j.label(platform) + ".timer"},
+	}
+}
+
+// Deactivate returns the commands that stop the job before its files go
+func (j Job) Deactivate(platform Platform, home string) [][]string {
+	if platform == Launchd {
+		plist := j.Files(platform, "", home)[0].Path
+		return [][]string{{"launchctl", "unload", "-w", plist}}
+	}
+	return [][]string{{"systemctl", "--user", "disable", "--now", j.label(platform) + ".timer"}}
+}
+
+// quoteSystemd quotes a word of an ExecStart line
+func quoteSystemd(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\$%") {
+		return word
+	}
+	word = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$", "%", "%%").Replace(word)
+	return `"` + word + `"`
+}
+
+// command returns the command line a timer runs
+func (j Job) command(fhPath string) []string {
+	return []string{fhPath, "--run-job", j.Name}
+}
+
+func (j Job) service(fhPath string) string {
+	var words []string
+	for _, arg := range j.command(fhPath) {
+		words = append(words, quoteSystemd(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=%s
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, j.Description, strings.Join(words, " "))
+}
+
+func (j Job) timer() string {
+	return fmt.Sprintf(`[Unit]
+Description=%s on a schedule
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=5min
+
+[Install]
+WantedBy=timers.target
+`, j.Description, j.OnCalendar)
+}
+
+func (j Job) plist(fhPath, home string) string {
+	var args strings.Builder
+	for _, arg := range j.command(fhPath) {
+		fmt.Fprintf(&args, "\n        <string>%s</string>", html.EscapeString(arg))
+	}
+	log := html.EscapeString(filepath.Join(home, ".fh", j.Name+".log"))
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>%s</string>
+    <key>ProgramArguments</key>
+    <array>%s
+    </array>
+    <key>StartInterval</key>
+    <integer>%d</integer>
+    <key>StandardOutPath</key>
+    <string>%s</string>
+    <key>StandardErrorPath</key>
+    <string>%s</string>
+</dict>
+</plist>
+`, j.label(Launchd), args.String(), j.Interval, log, log)
+}
+
+// Install writes the files of job, replacing earlier ones
+func (j Job) Install(platform Platform, fhPath, home string) ([]File, error) {
+	files := j.Files(platform, fhPath, home)
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(f.Path, []byte(f.Content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+	return files, nil
+}
+
+// Uninstall removes the files of job, reporting whether there were any
+func (j Job) Uninstall(platform Platform, home string) (bool, error) {
+	removed := false
+	for _, f := range j.Files(platform, "", home) {
+		err := os.Remove(f.Path)
+		if err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobNames(t *testing.T) {
+	assert.Equal(t, []string{"archive", "backup", "prune", "sync"}, JobNames())
+}
+
+func TestFiles_Systemd(t *testing.T) {
+	files := Jobs["prune"].Files(Systemd, "/home/alice/go/bin/fh", "/home/alice")
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/home/alice/.config/systemd/user/fh-prune.service", files[0].Path)
+	assert.Contains(t, files[0].Content, "Type=oneshot\nExecStart=/home/alice/go/bin/fh --run-job prune\n")
+
+	assert.Equal(t, "/home/alice/.config/systemd/user/fh-prune.timer", files[1].Path)
+	assert.Contains(t, files[1].Content, "OnCalendar=daily\nPersistent=true\n")
+	assert.Contains(t, files[1].Content, "WantedBy=timers.target")
+
+	assert.Equal(t, [][]string{
+		{"systemctl", "--user", "daemon-reload"},
+		{"systemctl", "--user", "enable", "--now", "fh-prune.timer"},
+	}, Jobs["prune"].Activate(Systemd, "/home/alice"))
+}
+
+func TestFiles_SystemdQuoting(t *testing.T) {
+	files := Jobs["sync"].Files(Systemd, "/Users/a b/fh", "/Users/a b")
+	assert.Contains(t, files[0].Content, `ExecStart="/Users/a b/fh" --run-job sync`)
+	assert.Equal(t, `"100%% \\$$HOME"`, quoteSystemd(`100% \$HOME`))
+}
+
+func TestFiles_Launchd(t *testing.T) {
+	files := Jobs["backup"].Files(Launchd, "/opt/homebrew/bin/fh", "/Users/alice")
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "/Users/alice/Library/LaunchAgents/io.github.spideyz0r.fh.backup.plist", files[0].Path)
+	assert.Contains(t, files[0].Content, "<string>io.github.spideyz0r.fh.backup</string>")
+	assert.Contains(t, files[0].Content, "<string>/opt/homebrew/bin/fh</string>\n        <string>--run-job</string>\n        <string>backup</string>")
+	assert.Contains(t, files[0].Content, "<integer>86400</integer>")
+	assert.Contains(t, files[0].Content, "<string>/Users/alice/.fh/backup.log</string>")
+}
+
+func TestInstallUninstall(t *testing.T) {
+	home := t.TempDir()
+	job := Jobs["sync"]
+
+	files, err := job.Install(Systemd, "/usr/bin/fh", home)
+	require.NoError(t, err)
+	for _, f := range files {
+		content, err := os.ReadFile(f.Path)
+		require.NoError(t, err)
+		assert.Equal(t, f.Content, string(content))
+	}
+
+	removed, err := job.Uninstall(Systemd, home)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.NoFileExists(t, filepath.Join(home, ".config", "systemd", "user", "fh-sync.timer"))
+
+	removed, err = job.Uninstall(Systemd, home)
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestPingURL(t *testing.T) {
+	base := "https://hc-ping.com/f1e2d3c4"
+	assert.Equal(t, base, PingURL(base, "backup", Succeeded))
+	assert.Equal(t, base+"/fail", PingURL(base, "backup", Failed))
+	assert.Equal(t, base+"/start", PingURL(base+"/", "backup", Started))
+	assert.Equal(t, "https://hc-ping.com/key/fh-sync/fail", PingURL("https://hc-ping.com/key/fh-{job}", "sync", Failed))
+}
+
+func TestValidateHealthcheckURL(t *testing.T) {
+	assert.NoError(t, ValidateHealthcheckURL("https://hc-ping.com/key/fh-{job}"))
+	assert.Error(t, ValidateHealthcheckURL("hc-ping.com/f1e2d3c4"))
+	assert.Error(t, ValidateHealthcheckURL("ftp://example.com/check"))
+}
+
+func TestPing(t *testing.T) {
+	var path, body string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	output := []byte(strings.Repeat("x", maxPingBody) + "Error: disk full\n")
+	require.NoError(t, Ping(context.Background(), nil, server.URL+"/uuid", "backup", Failed, output))
+	assert.Equal(t, "/uuid/fail", path)
+	assert.Len(t, body, maxPingBody)
+	assert.True(t, strings.HasSuffix(body, "Error: disk full\n"))
+
+	status = http.StatusNotFound
+	assert.ErrorContains(t, Ping(context.Background(), nil, server.URL+"/uuid", "backup", Succeeded, nil), "404")
+}
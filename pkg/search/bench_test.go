@@ -0,0 +1,54 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+func BenchmarkFormatEntry(b *testing.B) {
+	cases := []struct {
+		name  string
+		entry *storage.HistoryEntry
+	}{
+		{"short", &storage.HistoryEntry{
+			Timestamp: 1234567890,
+			Command:   "ls -la",
+			Cwd:       "/home/user",
+		}},
+		{"long", &storage.HistoryEntry{
+			Timestamp: 1234567890,
+			Command:   strings.Repeat("kubectl get pods -n production ", 5),
+			Cwd:       "/home/user/very/long/path/to/project/subdirectory/nested/deeper",
+			ExitCode:  1,
+			GitBranch: "feature/some-branch",
+		}},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = FormatEntry(tc.entry)
+			}
+		})
+	}
+}
+
+func BenchmarkFilterEntries(b *testing.B) {
+	entries := make([]*storage.HistoryEntry, 40_000)
+	for i := range entries {
+		entries[i] = &storage.HistoryEntry{Command: "docker compose up -d service" + strings.Repeat("x", i%10)}
+	}
+
+	filter, err := query.Parse("SERVICEX", query.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = filterEntries(entries, filter)
+	}
+}
@@ -1,74 +1,161 @@
 package search
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/mattn/go-runewidth"
+	"github.com/spideyz0r/fh/pkg/query"
 	"github.com/spideyz0r/fh/pkg/storage"
 )
 
-// FzfSearch launches an interactive FZF selector using ktr0731/go-fuzzyfinder.
-func FzfSearch(entries []*storage.HistoryEntry, preFilter string) (*storage.HistoryEntry, error) {
+// ErrNoMatches is returned when no entry matches the search query
+var ErrNoMatches = errors.New("no entries match filter")
+
+// ErrCancelled is returned when the user closes the finder without picking
+var ErrCancelled = fuzzyfinder.ErrAbort
+
+// find runs the finder, replaced in tests since the real one needs a terminal
+var find = fuzzyfinder.Find
+
+// displayPath rewrites directories where entries are shown
+var displayPath = func(p string) string { return p }
+
+// SetPathDisplay sets how the finder shows the directories entries ran in,
+// such as with a project root shortened to its name. nil shows them as
+// stored.
+func SetPathDisplay(display func(string) string) {
+	if display == nil {
+		display = func(p string) string { return p }
+	}
+	displayPath = display
+}
+
+// FzfSearch launches an interactive FZF selector using ktr0731/go-fuzzyfinder,
+// or the fzf binary when ui is UIFzf and fzf is installed.
+// If filter is set, only the entries it matches are offered. Typing in the
+// finder treats letter case as mode says.
+func FzfSearch(entries []*storage.HistoryEntry, filter *query.Query, mode query.Case, ui UI) (*storage.HistoryEntry, error) {
 	if len(entries) == 0 {
 		return nil, fmt.Errorf("no history entries found")
 	}
 
-	// If preFilter is provided, filter entries first
+	// If a filter is provided, filter entries first
 	filteredEntries := entries
-	if preFilter != "" {
-		filteredEntries = filterEntries(entries, preFilter)
+	if filter != nil {
+		filteredEntries = filterEntries(entries, filter)
 		if len(filteredEntries) == 0 {
-			return nil, fmt.Errorf("no entries match filter: %s", preFilter)
+			return nil, fmt.Errorf("%w: %s", ErrNoMatches, filter.Text)
+		}
+	}
+
+	idx, err := choose(filteredEntries, nil, filter, mode, ui)
+	if err != nil {
+		return nil, fmt.Errorf("fzf search failed: %w", err)
+	}
+
+	return filteredEntries[idx], nil
+}
+
+// FzfPick launches the finder on entries in the order given, such as the
+// commands that answer a question to --ask. reasons[i] says why entries[i]
+// is offered and heads its preview.
+func FzfPick(entries []*storage.HistoryEntry, reasons []string, mode query.Case, ui UI) (*storage.HistoryEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no history entries found")
+	}
+
+	idx, err := choose(entries, reasons, nil, mode, ui)
+	if err != nil {
+		return nil, fmt.Errorf("fzf search failed: %w", err)
+	}
+
+	return entries[idx], nil
+}
+
+// choose runs the picker ui names on entries and returns the index of the
+// one picked. Without fzf installed, the built-in finder is used.
+func choose(entries []*storage.HistoryEntry, reasons []string, filter *query.Query, mode query.Case, ui UI) (int, error) {
+	if ui == UIFzf {
+		if path, err := lookFzf(); err == nil {
+			return fzfFind(path, entries, reasons, mode)
 		}
 	}
 
 	// Use ktr0731/go-fuzzyfinder
-	idx, err := fuzzyfinder.Find(
-		filteredEntries,
+	return find(
+		entries,
 		func(i int) string {
 			// Return the display string for fuzzy matching
-			return FormatEntry(filteredEntries[i])
+			return FormatEntry(entries[i])
 		},
 		fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
 			if i == -1 {
 				return ""
 			}
-			entry := filteredEntries[i]
-
-			// Build preview
-			preview := fmt.Sprintf("Command: %s\n\n", entry.Command)
-			preview += fmt.Sprintf("Time:     %s\n", time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04:05"))
-			preview += fmt.Sprintf("Cwd:      %s\n", entry.Cwd)
-			preview += fmt.Sprintf("Exit:     %d\n", entry.ExitCode)
-			if entry.DurationMs > 0 {
-				preview += fmt.Sprintf("Duration: %dms\n", entry.DurationMs)
-			}
-			if entry.GitBranch != "" {
-				preview += fmt.Sprintf("Branch:   %s\n", entry.GitBranch)
+			why := ""
+			if i < len(reasons) && reasons[i] != "" {
+				why = fmt.Sprintf("Why: %s\n\n", reasons[i])
 			}
-			preview += fmt.Sprintf("Host:     %s\n", entry.Hostname)
-			preview += fmt.Sprintf("User:     %s\n", entry.User)
-			preview += fmt.Sprintf("Shell:    %s\n", entry.Shell)
-
-			return preview
+			return why + preview(entries[i], filter, ColorEnabled())
 		}),
+		finderMode(mode),
 	)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("fzf search failed: %w", err)
+// finderMode sets the finder's matching mode for a case mode
+func finderMode(mode query.Case) fuzzyfinder.Option {
+	switch mode {
+	case query.CaseSensitive:
+		return fuzzyfinder.WithMode(fuzzyfinder.ModeCaseSensitive)
+	case query.CaseInsensitive:
+		return fuzzyfinder.WithMode(fuzzyfinder.ModeCaseInsensitive)
 	}
+	return fuzzyfinder.WithMode(fuzzyfinder.ModeSmart)
+}
 
-	return filteredEntries[idx], nil
+// preview describes entry for the preview window. With color, the parts
+// of its fields that filter matched are highlighted.
+func preview(entry *storage.HistoryEntry, filter *query.Query, color bool) string {
+	mark := func(field storage.SearchField, text string) string {
+		if filter == nil || !color {
+			return text
+		}
+		return Highlight(text, filter.Spans(field, text))
+	}
+
+	preview := fmt.Sprintf("Command: %s\n\n", mark(storage.FieldCommand, entry.Command))
+	preview += fmt.Sprintf("ID:       %d (fh --show %d)\n", entry.ID, entry.ID)
+	preview += fmt.Sprintf("Time:     %s\n", time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	preview += fmt.Sprintf("Cwd:      %s\n", mark(storage.FieldCwd, displayPath(entry.Cwd)))
+	preview += fmt.Sprintf("Exit:     %d\n", entry.ExitCode)
+	if entry.DurationMs > 0 {
+		preview += fmt.Sprintf("Duration: %dms\n", entry.DurationMs)
+	}
+	if entry.GitBranch != "" {
+		preview += fmt.Sprintf("Branch:   %s\n", mark(storage.FieldBranch, entry.GitBranch))
+	}
+	if entry.ExecTarget != "" {
+		preview += fmt.Sprintf("Inside:   %s (%s)\n", entry.ExecTarget, entry.ExecRuntime)
+	}
+	preview += fmt.Sprintf("Host:     %s\n", entry.Hostname)
+	preview += fmt.Sprintf("User:     %s\n", entry.User)
+	preview += fmt.Sprintf("Shell:    %s\n", entry.Shell)
+	if entry.Note != "" {
+		preview += fmt.Sprintf("Note:     %s\n", mark(storage.FieldNote, entry.Note))
+	}
+
+	return preview
 }
 
-// filterEntries filters entries by command text.
-func filterEntries(entries []*storage.HistoryEntry, query string) []*storage.HistoryEntry {
-	query = strings.ToLower(query)
+// filterEntries returns the entries filter matches.
+func filterEntries(entries []*storage.HistoryEntry, filter *query.Query) []*storage.HistoryEntry {
 	var filtered []*storage.HistoryEntry
 	for _, entry := range entries {
-		if strings.Contains(strings.ToLower(entry.Command), query) {
+		if filter.Matches(entry) {
 			filtered = append(filtered, entry)
 		}
 	}
@@ -80,7 +167,8 @@ func filterEntries(entries []*storage.HistoryEntry, query string) []*storage.His
 // Command is first to prioritize fuzzy matching on it, which combined with
 // the list being sorted by recency (most recent first) naturally biases
 // results toward recent commands when fuzzy scores are similar.
-// Uses fixed-width columns for clean alignment.
+// Uses fixed-width columns for clean alignment, measured in terminal cells
+// so wide characters (CJK, emoji) keep the columns lined up.
 func FormatEntry(entry *storage.HistoryEntry) string {
 	const (
 		commandWidth = 60 // Standard width for command column
@@ -88,11 +176,8 @@ func FormatEntry(entry *storage.HistoryEntry) string {
 	)
 
 	// Format command - pad or truncate to fixed width
-	cmd := entry.Command
-	if len(cmd) > commandWidth {
-		cmd = cmd[:commandWidth-3] + "..."
-	}
-	cmd = fmt.Sprintf("%-*s", commandWidth, cmd) // Left-aligned, padded
+	cmd := runewidth.Truncate(entry.Command, commandWidth, "...")
+	cmd = runewidth.FillRight(cmd, commandWidth) // Left-aligned, padded
 
 	// Format timestamp - always show full date and time (fixed 19 chars)
 	ts := time.Unix(entry.Timestamp, 0)
@@ -103,11 +188,7 @@ func FormatEntry(entry *storage.HistoryEntry) string {
 
 	// Add cwd if present (truncate if too long)
 	if entry.Cwd != "" {
-		cwd := entry.Cwd
-		if len(cwd) > cwdWidth {
-			cwd = "..." + cwd[len(cwd)-(cwdWidth-3):]
-		}
-		parts = append(parts, cwd)
+		parts = append(parts, truncateLeft(displayPath(entry.Cwd), cwdWidth))
 	}
 
 	// Add metadata badges
@@ -126,8 +207,29 @@ func FormatEntry(entry *storage.HistoryEntry) string {
 	return strings.Join(parts, " │ ")
 }
 
+// truncateLeft shortens s to width cells by cutting its start, keeping the
+// end of a path visible
+func truncateLeft(s string, width int) string {
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	start, used := len(runes), 0
+	for start > 0 {
+		w := runewidth.RuneWidth(runes[start-1])
+		if used+w > width-3 {
+			break
+		}
+		used += w
+		start--
+	}
+	return "..." + string(runes[start:])
+}
+
 // ExtractCommand extracts the command from a formatted entry line.
-// This is useful if you need to parse FZF output back to command.
+// Commands wider than the column come back truncated with "...", so use
+// the entry FzfSearch returns to get the stored command.
 func ExtractCommand(formattedEntry string) string {
 	// Split by separator
 	parts := strings.Split(formattedEntry, " │ ")
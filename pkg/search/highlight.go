@@ -0,0 +1,38 @@
+package search
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Bold red, the same as grep --color
+const (
+	highlightStart = "\x1b[1;31m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// ColorEnabled reports whether output may be colored, see https://no-color.org
+func ColorEnabled() bool {
+	return os.Getenv("NO_COLOR") == ""
+}
+
+// Highlight colors the spans of text, as returned by query.Query.Spans
+func Highlight(text string, spans []storage.Span) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(text[last:span.Start])
+		b.WriteString(highlightStart)
+		b.WriteString(text[span.Start:span.End])
+		b.WriteString(highlightEnd)
+		last = span.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "git status", Highlight("git status", nil))
+	assert.Equal(t, "\x1b[1;31mgit\x1b[0m st\x1b[1;31matus\x1b[0m",
+		Highlight("git status", []storage.Span{{Start: 0, End: 3}, {Start: 6, End: 10}}))
+}
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	assert.True(t, ColorEnabled())
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, ColorEnabled())
+}
+
+func TestPreview_Highlight(t *testing.T) {
+	entry := &storage.HistoryEntry{
+		Command:   "kubectl get pods",
+		Cwd:       "/src/infra",
+		GitBranch: "main",
+		Note:      "check pods",
+	}
+	filter := parseQuery(t, "cmd:pods infra")
+
+	got := preview(entry, filter, true)
+	assert.Contains(t, got, "Command: kubectl get \x1b[1;31mpods\x1b[0m\n")
+	assert.Contains(t, got, "Cwd:      /src/\x1b[1;31minfra\x1b[0m\n")
+	// cmd: only highlights the command
+	assert.Contains(t, got, "Note:     check pods\n")
+
+	assert.NotContains(t, preview(entry, filter, false), "\x1b[")
+	assert.NotContains(t, preview(entry, nil, true), "\x1b[")
+}
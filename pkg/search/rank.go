@@ -0,0 +1,122 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Ranking modes for the interactive search
+const (
+	RankRecent   = "recent"   // Most recent first (default)
+	RankFrecency = "frecency" // Frequency weighted by recency
+)
+
+// RankOptions controls how Rank scores commands
+type RankOptions struct {
+	// HalfLife is the age at which a run counts half as much as a run made now.
+	// Zero disables decay, so every run counts as 1 regardless of age.
+	HalfLife time.Duration
+	// Now is the reference time for ages (defaults to time.Now())
+	Now time.Time
+}
+
+// RankedCommand is a unique command with its run count and frecency score
+type RankedCommand struct {
+	Entry *storage.HistoryEntry // Most recent run of the command
+	Count int
+	Score float64
+}
+
+// Weight returns the contribution of a single run of the given age.
+// Runs in the future (clock skew) count fully.
+func Weight(age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / float64(halfLife))
+}
+
+// Rank groups entries by command and orders them by decayed frequency,
+// highest score first. Ties are broken by the most recent run.
+func Rank(entries []*storage.HistoryEntry, opts RankOptions) []RankedCommand {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	index := make(map[string]int)
+	var ranked []RankedCommand
+
+	for _, entry := range entries {
+		age := now.Sub(time.Unix(entry.Timestamp, 0))
+		weight := Weight(age, opts.HalfLife)
+
+		i, ok := index[entry.Command]
+		if !ok {
+			index[entry.Command] = len(ranked)
+			ranked = append(ranked, RankedCommand{Entry: entry, Count: 1, Score: weight})
+			continue
+		}
+
+		ranked[i].Count++
+		ranked[i].Score += weight
+		if entry.Timestamp > ranked[i].Entry.Timestamp {
+			ranked[i].Entry = entry
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Entry.Timestamp > ranked[j].Entry.Timestamp
+	})
+
+	return ranked
+}
+
+// ByFrecency returns one entry per command ordered by Rank, keeping at most
+// limit entries (0 = unlimited)
+func ByFrecency(entries []*storage.HistoryEntry, opts RankOptions, limit int) []*storage.HistoryEntry {
+	ranked := Rank(entries, opts)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	result := make([]*storage.HistoryEntry, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.Entry
+	}
+	return result
+}
+
+// Top returns the limit highest ranked commands of profile ("" = all
+// profiles), 0 meaning all of them. Without decay the score is the run
+// count the database keeps per command, so only one row per command is
+// read; with decay every run is.
+func Top(db *storage.DB, profile string, opts RankOptions, limit int) ([]RankedCommand, error) {
+	if opts.HalfLife <= 0 {
+		counts, err := db.TopCommands(profile, limit)
+		if err != nil {
+			return nil, err
+		}
+		ranked := make([]RankedCommand, len(counts))
+		for i, c := range counts {
+			ranked[i] = RankedCommand{Entry: c.Entry, Count: c.Count, Score: float64(c.Count)}
+		}
+		return ranked, nil
+	}
+
+	entries, err := db.Query(storage.QueryFilters{Profile: profile})
+	if err != nil {
+		return nil, err
+	}
+	ranked := Rank(entries, opts)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeight(t *testing.T) {
+	day := 24 * time.Hour
+
+	assert.Equal(t, 1.0, Weight(100*day, 0), "no decay without half-life")
+	assert.Equal(t, 1.0, Weight(0, day))
+	assert.Equal(t, 1.0, Weight(-time.Hour, day), "future runs count fully")
+	assert.InDelta(t, 0.5, Weight(30*day, 30*day), 1e-9)
+	assert.InDelta(t, 0.25, Weight(60*day, 30*day), 1e-9)
+}
+
+func TestRank(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	day := int64(24 * 60 * 60)
+
+	var entries []*storage.HistoryEntry
+	// 100 runs of an old-job command, a year ago
+	for i := int64(0); i < 100; i++ {
+		entries = append(entries, &storage.HistoryEntry{Command: "make deploy-old", Timestamp: now.Unix() - 365*day - i})
+	}
+	// 5 runs of a current command, this week
+	for i := int64(0); i < 5; i++ {
+		entries = append(entries, &storage.HistoryEntry{Command: "go test ./...", Timestamp: now.Unix() - i*day})
+	}
+
+	t.Run("without decay frequency wins", func(t *testing.T) {
+		ranked := Rank(entries, RankOptions{Now: now})
+		require.Len(t, ranked, 2)
+		assert.Equal(t, "make deploy-old", ranked[0].Entry.Command)
+		assert.Equal(t, 100, ranked[0].Count)
+		assert.Equal(t, 100.0, ranked[0].Score)
+	})
+
+	t.Run("with decay stale commands sink", func(t *testing.T) {
+		ranked := Rank(entries, RankOptions{Now: now, HalfLife: 30 * 24 * time.Hour})
+		require.Len(t, ranked, 2)
+		assert.Equal(t, "go test ./...", ranked[0].Entry.Command)
+		assert.Equal(t, 5, ranked[0].Count)
+		assert.Equal(t, 100, ranked[1].Count, "counts are not decayed")
+	})
+
+	t.Run("keeps most recent run", func(t *testing.T) {
+		ranked := Rank(entries, RankOptions{Now: now})
+		assert.Equal(t, now.Unix()-365*day, ranked[0].Entry.Timestamp)
+	})
+}
+
+func TestRank_TieBreaksOnRecency(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "older", Timestamp: 100},
+		{Command: "newer", Timestamp: 200},
+	}
+
+	ranked := Rank(entries, RankOptions{Now: time.Unix(300, 0)})
+	require.Len(t, ranked, 2)
+	assert.Equal(t, "newer", ranked[0].Entry.Command)
+}
+
+func TestByFrecency(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "a", Timestamp: 100},
+		{Command: "b", Timestamp: 100},
+		{Command: "b", Timestamp: 90},
+		{Command: "c", Timestamp: 100},
+	}
+
+	result := ByFrecency(entries, RankOptions{Now: time.Unix(100, 0)}, 2)
+	require.Len(t, result, 2)
+	assert.Equal(t, "b", result[0].Command)
+	assert.Equal(t, int64(100), result[0].Timestamp)
+
+	assert.Len(t, ByFrecency(entries, RankOptions{}, 0), 3)
+	assert.Empty(t, ByFrecency(nil, RankOptions{}, 0))
+}
+
+func TestTop(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	var entries []*storage.HistoryEntry
+	for i, command := range []string{"make", "ls", "make", "git pull", "ls", "make"} {
+		entry := &storage.HistoryEntry{Command: command, Timestamp: now - int64(600-i*100)}
+		require.NoError(t, db.InsertWithDedup(entry, storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}))
+		entries = append(entries, entry)
+	}
+
+	for _, opts := range []RankOptions{{}, {HalfLife: time.Hour}} {
+		want := Rank(entries, opts)[:2]
+		got, err := Top(db, "", opts, 2)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		for i := range want {
+			assert.Equal(t, want[i].Entry.ID, got[i].Entry.ID)
+			assert.Equal(t, want[i].Count, got[i].Count)
+			assert.InDelta(t, want[i].Score, got[i].Score, 0.01)
+		}
+	}
+}
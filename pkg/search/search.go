@@ -27,7 +27,7 @@ func All(db *storage.DB, limit int) ([]*storage.HistoryEntry, error) {
 }
 
 // WithFilters searches with custom filters.
-func WithFilters(db *storage.DB, filters storage.QueryFilters) ([]*storage.HistoryEntry, error) {
+func WithFilters(db storage.Store, filters storage.QueryFilters) ([]*storage.HistoryEntry, error) {
 	entries, err := db.Query(filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query history: %w", err)
@@ -1,10 +1,16 @@
 package search
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+	"github.com/mattn/go-runewidth"
+	"github.com/spideyz0r/fh/pkg/query"
 	"github.com/spideyz0r/fh/pkg/storage"
 	"github.com/spideyz0r/fh/pkg/testutil"
 	"github.com/stretchr/testify/assert"
@@ -234,6 +240,14 @@ func ptr(i int) *int {
 	return &i
 }
 
+// parseQuery parses a query that is known to be valid
+func parseQuery(t *testing.T, q string) *query.Query {
+	t.Helper()
+	parsed, err := query.Parse(q, query.Options{})
+	require.NoError(t, err)
+	return parsed
+}
+
 func TestFilterEntries(t *testing.T) {
 	entries := []*storage.HistoryEntry{
 		{Command: "git status", Cwd: "/home/user"},
@@ -244,38 +258,62 @@ func TestFilterEntries(t *testing.T) {
 	}
 
 	t.Run("filter with matching query", func(t *testing.T) {
-		filtered := filterEntries(entries, "git")
+		filtered := filterEntries(entries, parseQuery(t, "git"))
 		assert.Len(t, filtered, 2)
 		assert.Contains(t, filtered[0].Command, "git")
 		assert.Contains(t, filtered[1].Command, "git")
 	})
 
 	t.Run("filter with no matches", func(t *testing.T) {
-		filtered := filterEntries(entries, "nonexistent")
+		filtered := filterEntries(entries, parseQuery(t, "nonexistent"))
 		assert.Empty(t, filtered)
 	})
 
 	t.Run("filter with empty query", func(t *testing.T) {
-		filtered := filterEntries(entries, "")
+		filtered := filterEntries(entries, parseQuery(t, ""))
 		assert.Len(t, filtered, 5) // All entries match empty query
 	})
 
 	t.Run("filter is case insensitive", func(t *testing.T) {
-		filtered := filterEntries(entries, "DOCKER")
+		filter, err := query.Parse("DOCKER", query.Options{Case: query.CaseInsensitive})
+		require.NoError(t, err)
+		filtered := filterEntries(entries, filter)
 		assert.Len(t, filtered, 2)
 	})
 
 	t.Run("filter with partial match", func(t *testing.T) {
-		filtered := filterEntries(entries, "doc")
+		filtered := filterEntries(entries, parseQuery(t, "doc"))
 		assert.Len(t, filtered, 2)
 	})
 
 	t.Run("filter empty entries", func(t *testing.T) {
-		filtered := filterEntries([]*storage.HistoryEntry{}, "test")
+		filtered := filterEntries([]*storage.HistoryEntry{}, parseQuery(t, "test"))
 		assert.Empty(t, filtered)
 	})
 }
 
+func TestFilterEntries_Query(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "kubectl apply -f app.yaml", Cwd: "/src/infra", GitBranch: "main", ExitCode: 1},
+		{Command: "kubectl get pods", Cwd: "/src/infra", GitBranch: "feature"},
+		{Command: "make infra", Cwd: "/src/app", Note: "needs VPN"},
+	}
+
+	assert.Equal(t, entries[:1], filterEntries(entries, parseQuery(t, "cwd:infra branch:main kubectl")))
+
+	// A word without a prefix matches any field
+	assert.Len(t, filterEntries(entries, parseQuery(t, "infra")), 3)
+	assert.Equal(t, entries[2:], filterEntries(entries, parseQuery(t, "cmd:infra")))
+	assert.Equal(t, entries[2:], filterEntries(entries, parseQuery(t, "vpn")))
+
+	assert.Equal(t, entries[:1], filterEntries(entries, parseQuery(t, "exit:!0")))
+
+	// Smart case: an upper-case letter makes the word exact
+	assert.Equal(t, entries[2:], filterEntries(entries, parseQuery(t, "VPN")))
+	assert.Empty(t, filterEntries(entries, parseQuery(t, "Kubectl")))
+	assert.Equal(t, entries[1:], filterEntries(entries, parseQuery(t, "!apply")))
+}
+
 func TestFormatEntry(t *testing.T) {
 	t.Run("format complete entry", func(t *testing.T) {
 		entry := &storage.HistoryEntry{
@@ -476,6 +514,49 @@ func TestFormatEntry_Padding(t *testing.T) {
 	})
 }
 
+func TestFormatEntry_WideCharacters(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"CJK", "echo 你好世界"},
+		{"emoji", "git commit -m '🚀 ship it'"},
+		{"long CJK", "echo " + strings.Repeat("日本語", 15)},
+		{"long emoji", "echo " + strings.Repeat("🎉", 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted := FormatEntry(&storage.HistoryEntry{Command: tt.command, Timestamp: 1234567890})
+			parts := strings.Split(formatted, " │ ")
+			assert.Equal(t, 60, runewidth.StringWidth(parts[0]), "command column should be 60 cells wide")
+		})
+	}
+
+	t.Run("long cwd keeps its end", func(t *testing.T) {
+		cwd := "/home/user/" + strings.Repeat("文档", 20) + "/项目"
+		formatted := FormatEntry(&storage.HistoryEntry{Command: "ls", Cwd: cwd, Timestamp: 1234567890})
+		parts := strings.Split(formatted, " │ ")
+		assert.LessOrEqual(t, runewidth.StringWidth(parts[2]), 50)
+		assert.True(t, strings.HasPrefix(parts[2], "..."))
+		assert.True(t, strings.HasSuffix(parts[2], "/项目"))
+	})
+}
+
+func TestSetPathDisplay(t *testing.T) {
+	SetPathDisplay(func(p string) string { return strings.Replace(p, "/home/user/src/fh", "fh:", 1) })
+	t.Cleanup(func() { SetPathDisplay(nil) })
+
+	entry := &storage.HistoryEntry{Command: "make", Cwd: "/home/user/src/fh/cmd", Timestamp: 1234567890}
+	parts := strings.Split(FormatEntry(entry), " │ ")
+	assert.Equal(t, "fh:/cmd", parts[2])
+	assert.Contains(t, preview(entry, nil, false), "Cwd:      fh:/cmd\n")
+	assert.Equal(t, "/home/user/src/fh/cmd", entry.Cwd, "the entry keeps its stored cwd")
+
+	SetPathDisplay(nil)
+	assert.Contains(t, FormatEntry(entry), "/home/user/src/fh/cmd")
+}
+
 func TestFormatEntry_CwdHandling(t *testing.T) {
 	t.Run("very long cwd gets truncated", func(t *testing.T) {
 		longCwd := "/Users/username/very/long/path/to/deeply/nested/directory/that/exceeds/fifty/chars"
@@ -547,3 +628,134 @@ func TestFormatEntry_BadgesCombinations(t *testing.T) {
 		assert.NotContains(t, formatted, "[")
 	})
 }
+
+func TestFzfSearch_LongCommand(t *testing.T) {
+	long := "kubectl argo rollouts promote very-long-deployment-name-exceeds-sixty --namespace production"
+	entries := []*storage.HistoryEntry{
+		{Command: "ls", Timestamp: 1234567890},
+		{Command: long, Timestamp: 1234567800},
+	}
+
+	// Pick the line shown with "..." the way a user would
+	var shown []string
+	find = func(slice interface{}, itemFunc func(int) string, opts ...fuzzyfinder.Option) (int, error) {
+		shown = nil
+		for i := 0; i < len(slice.([]*storage.HistoryEntry)); i++ {
+			shown = append(shown, itemFunc(i))
+		}
+		for i, line := range shown {
+			if strings.Contains(line, "...") {
+				return i, nil
+			}
+		}
+		return 0, fuzzyfinder.ErrAbort
+	}
+	defer func() { find = fuzzyfinder.Find }()
+
+	selected, err := FzfSearch(entries, nil, query.CaseSmart, UIBuiltin)
+	require.NoError(t, err)
+	assert.Equal(t, long, selected.Command)
+	assert.NotEqual(t, long, ExtractCommand(shown[1]), "the line itself is truncated")
+
+	// The pre-filter sees the whole command, not the truncated column
+	selected, err = FzfSearch(entries, parseQuery(t, "production"), query.CaseSmart, UIBuiltin)
+	require.NoError(t, err)
+	assert.Len(t, shown, 1)
+	assert.Equal(t, long, selected.Command)
+}
+
+func TestFzfPick(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "make deploy", Timestamp: 1234567800},
+		{Command: "kubectl rollout status deploy/api", Timestamp: 1234567890},
+	}
+
+	// Offered in the order of the answer, not by time
+	var shown []string
+	find = func(slice interface{}, itemFunc func(int) string, opts ...fuzzyfinder.Option) (int, error) {
+		shown = nil
+		for i := 0; i < len(slice.([]*storage.HistoryEntry)); i++ {
+			shown = append(shown, itemFunc(i))
+		}
+		return 1, nil
+	}
+	defer func() { find = fuzzyfinder.Find }()
+
+	selected, err := FzfPick(entries, []string{"Deploys", "Waits for the rollout"}, query.CaseSmart, UIBuiltin)
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl rollout status deploy/api", selected.Command)
+	require.Len(t, shown, 2)
+	assert.Equal(t, "make deploy", ExtractCommand(shown[0]))
+
+	find = func(slice interface{}, itemFunc func(int) string, opts ...fuzzyfinder.Option) (int, error) {
+		return 0, fuzzyfinder.ErrAbort
+	}
+	_, err = FzfPick(entries, nil, query.CaseSmart, UIBuiltin)
+	assert.ErrorIs(t, err, ErrCancelled)
+
+	_, err = FzfPick(nil, nil, query.CaseSmart, UIBuiltin)
+	assert.Error(t, err)
+}
+
+func TestParseUI(t *testing.T) {
+	ui, err := ParseUI("")
+	require.NoError(t, err)
+	assert.Equal(t, UIBuiltin, ui)
+
+	ui, err = ParseUI("fzf")
+	require.NoError(t, err)
+	assert.Equal(t, UIFzf, ui)
+
+	_, err = ParseUI("skim")
+	assert.ErrorContains(t, err, "invalid search ui")
+}
+
+func TestFzfSearch_Binary(t *testing.T) {
+	// A stand-in for fzf that picks the second line, or exits as fzf does on Esc
+	dir := t.TempDir()
+	fzf := filepath.Join(dir, "fzf")
+	script := "#!/bin/sh\necho \"$@\" > \"$0.args\"\ncat > \"$0.input\"\n[ -n \"$FZF_ABORT\" ] && exit 130\nsed -n 2p \"$0.input\"\n"
+	require.NoError(t, os.WriteFile(fzf, []byte(script), 0755))
+	lookFzf = func() (string, error) { return fzf, nil }
+	defer func() { lookFzf = func() (string, error) { return exec.LookPath("fzf") } }()
+
+	entries := []*storage.HistoryEntry{
+		{ID: 7, Command: "make deploy", Timestamp: 1234567800},
+		{ID: 9, Command: "kubectl rollout status\tdeploy/api", Timestamp: 1234567890},
+	}
+	selected, err := FzfPick(entries, []string{"Deploys", "Waits for\nthe rollout"}, query.CaseSensitive, UIFzf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), selected.ID)
+
+	input, err := os.ReadFile(fzf + ".input")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(input), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[1], "1\t9\tWaits for the rollout\tkubectl rollout status deploy/api"))
+
+	args, err := os.ReadFile(fzf + ".args")
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--with-nth=4..")
+	assert.Contains(t, string(args), "+i")
+	assert.Contains(t, string(args), "--show {2}")
+
+	t.Setenv("FZF_ABORT", "1")
+	_, err = FzfSearch(entries, nil, query.CaseSmart, UIFzf)
+	assert.ErrorIs(t, err, ErrCancelled)
+}
+
+func TestFzfSearch_BinaryMissing(t *testing.T) {
+	lookFzf = func() (string, error) { return "", exec.ErrNotFound }
+	defer func() { lookFzf = func() (string, error) { return exec.LookPath("fzf") } }()
+
+	called := false
+	find = func(slice interface{}, itemFunc func(int) string, opts ...fuzzyfinder.Option) (int, error) {
+		called = true
+		return 0, nil
+	}
+	defer func() { find = fuzzyfinder.Find }()
+
+	_, err := FzfSearch([]*storage.HistoryEntry{{Command: "ls"}}, nil, query.CaseSmart, UIFzf)
+	require.NoError(t, err)
+	assert.True(t, called, "falls back to the built-in finder")
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/query"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// UI is the picker entries are chosen in
+type UI string
+
+// Pickers
+const (
+	UIBuiltin UI = "builtin" // The finder built into fh, nothing to install
+	UIFzf     UI = "fzf"     // The fzf binary, with the user's FZF_DEFAULT_OPTS
+)
+
+// ParseUI parses a picker name, "" is UIBuiltin
+func ParseUI(s string) (UI, error) {
+	switch ui := UI(s); ui {
+	case "":
+		return UIBuiltin, nil
+	case UIBuiltin, UIFzf:
+		return ui, nil
+	}
+	return "", fmt.Errorf("invalid search ui %q (must be builtin or fzf)", s)
+}
+
+// lookFzf finds the fzf binary
+var lookFzf = func() (string, error) { return exec.LookPath("fzf") }
+
+// fzfFind offers entries in the fzf binary at path and returns the index of
+// the one picked. Each line carries the entry's index, ID and reason in
+// hidden fields, for the result and the preview, which is fh --show.
+func fzfFind(path string, entries []*storage.HistoryEntry, reasons []string, mode query.Case) (int, error) {
+	var input bytes.Buffer
+	for i, entry := range entries {
+		reason := ""
+		if i < len(reasons) {
+			reason = reasons[i]
+		}
+		fmt.Fprintf(&input, "%d\t%d\t%s\t%s\n", i, entry.ID, oneLine(reason), oneLine(FormatEntry(entry)))
+	}
+
+	args := []string{"--delimiter=\t", "--with-nth=4..", "--tiebreak=index"}
+	switch mode {
+	case query.CaseSensitive:
+		args = append(args, "+i")
+	case query.CaseInsensitive:
+		args = append(args, "-i")
+	}
+	if self, err := os.Executable(); err == nil {
+		// fzf quotes the fields it puts in place of {2} and {3}
+		show := shellQuote(self) + " --show {2}"
+		if len(reasons) > 0 {
+			show = "echo Why: {3}; echo; " + show
+		}
+		args = append(args, "--preview="+show)
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// 1 is no match, 130 is Esc or Ctrl-C
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+			return 0, ErrCancelled
+		}
+		return 0, fmt.Errorf("fzf: %w", err)
+	}
+
+	field, _, _ := strings.Cut(string(out), "\t")
+	idx, err := strconv.Atoi(field)
+	if err != nil || idx < 0 || idx >= len(entries) {
+		return 0, fmt.Errorf("fzf returned an unknown line: %q", strings.TrimSpace(string(out)))
+	}
+	return idx, nil
+}
+
+// oneLine keeps s on its line, fzf reading an entry per line
+func oneLine(s string) string {
+	return strings.NewReplacer("\n", " ", "\t", " ").Replace(s)
+}
+
+// shellQuote quotes s for the shell fzf runs the preview in
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
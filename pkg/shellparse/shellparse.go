@@ -0,0 +1,267 @@
+// Package shellparse is a lightweight shell command line parser. It is not a
+// full shell grammar, just enough to split stored commands into simple
+// commands and find the program each one runs.
+package shellparse
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Token is a word or control operator of a command line
+type Token struct {
+	Text     string
+	Operator bool // |, |&, ||, &, &&, ;, newline, ( or )
+}
+
+// Tokenize splits a command line into words and control operators, honoring
+// quotes, backslash escapes and comments. Quotes are removed from words.
+func Tokenize(command string) []Token {
+	var tokens []Token
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, Token{Text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+	operator := func(op string) {
+		flush()
+		tokens = append(tokens, Token{Text: op, Operator: true})
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && next != 0 {
+				i++
+				word.WriteRune(next)
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && next != 0:
+			i++
+			if next != '\n' {
+				word.WriteRune(next)
+				inWord = true
+			}
+		case r == '#' && !inWord:
+			// Comment until the end of the line
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case r == ' ' || r == '\t':
+			flush()
+		case r == '\n':
+			operator("\n")
+		case r == '&' && (next == '>' || strings.HasSuffix(word.String(), ">") || strings.HasSuffix(word.String(), "<")):
+			// Redirections such as 2>&1 and &>file are part of the word
+			word.WriteRune(r)
+			inWord = true
+		case r == '|' && (next == '|' || next == '&'), r == '&' && next == '&':
+			i++
+			operator(string(r) + string(next))
+		case r == '|' || r == '&' || r == ';' || r == '(' || r == ')':
+			operator(string(r))
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// Fields returns the words of a command line, dropping control operators
+func Fields(command string) []string {
+	var words []string
+	for _, token := range Tokenize(command) {
+		if !token.Operator {
+			words = append(words, token.Text)
+		}
+	}
+	return words
+}
+
+// Commands splits a command line into its simple commands (pipeline stages,
+// list members and subshell contents), each returned as its words
+func Commands(command string) [][]string {
+	var commands [][]string
+	var current []string
+	for _, token := range Tokenize(command) {
+		if token.Operator {
+			if len(current) > 0 {
+				commands = append(commands, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, token.Text)
+	}
+	if len(current) > 0 {
+		commands = append(commands, current)
+	}
+	return commands
+}
+
+// prefixFlags lists the commands that run another command, with the flags
+// of each that take a value
+var prefixFlags = map[string]map[string]bool{
+	"sudo": FlagSet("-u", "--user", "-g", "--group", "-h", "--host", "-p", "--prompt",
+		"-C", "--close-from", "-D", "--chdir", "-R", "--chroot", "-T", "--command-timeout", "-U", "--other-user"),
+	"doas":    FlagSet("-u", "-C"),
+	"env":     FlagSet("-u", "--unset", "-C", "--chdir", "-S", "--split-string"),
+	"nice":    FlagSet("-n", "--adjustment"),
+	"nohup":   nil,
+	"time":    FlagSet("-f", "--format", "-o", "--output"),
+	"command": nil,
+	"builtin": nil,
+	"exec":    FlagSet("-a"),
+}
+
+// keywords are shell reserved words that can start a simple command
+var keywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"do": true, "done": true, "while": true, "until": true,
+	"{": true, "}": true, "!": true, "esac": true,
+}
+
+// FlagSet builds a lookup set of flag names, for SkipFlags
+func FlagSet(flags ...string) map[string]bool {
+	set := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		set[f] = true
+	}
+	return set
+}
+
+// StripPrefixes drops reserved words, VAR=value assignments, redirections
+// and wrappers such as sudo, env and nohup from the front of a simple
+// command, leaving the program and its arguments
+func StripPrefixes(args []string) []string {
+	for len(args) > 0 {
+		arg := args[0]
+		if flags, ok := prefixFlags[arg]; ok {
+			args = SkipFlags(args[1:], flags)
+			continue
+		}
+
+		switch {
+		case keywords[arg]:
+			args = args[1:]
+		case isAssignment(arg):
+			args = args[1:]
+		case isRedirection(arg):
+			// A bare operator like > takes the next word as its target
+			args = args[1:]
+			if strings.TrimLeft(arg, "0123456789&<>|") == "" && len(args) > 0 {
+				args = args[1:]
+			}
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// SkipFlags drops leading flags, and the values of flags in valueFlags
+func SkipFlags(args []string, valueFlags map[string]bool) []string {
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return args
+		}
+		args = args[1:]
+		if valueFlags[arg] && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// Programs returns the program run by each simple command of a command line,
+// in order, e.g. "cat log | grep err | wc -l" gives cat, grep and wc.
+// Paths are reduced to the binary name. for, case and select loops are skipped.
+func Programs(command string) []string {
+	var programs []string
+	for _, args := range Commands(command) {
+		if len(args) > 0 && (args[0] == "for" || args[0] == "case" || args[0] == "select") {
+			continue
+		}
+
+		args = StripPrefixes(args)
+		if len(args) == 0 || args[0] == "" {
+			continue
+		}
+
+		programs = append(programs, filepath.Base(args[0]))
+	}
+	return programs
+}
+
+// Program returns the primary program of a command line (the first one it
+// runs), or "" if there is none
+func Program(command string) string {
+	programs := Programs(command)
+	if len(programs) == 0 {
+		return ""
+	}
+	return programs[0]
+}
+
+// Quote returns s as a single shell word, in single quotes unless it only
+// has characters no shell treats specially. Newlines are kept as they are.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isAssignment reports whether arg is a VAR=value environment prefix
+func isAssignment(arg string) bool {
+	name, _, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isRedirection reports whether arg is a redirection such as >file or 2>&1
+func isRedirection(arg string) bool {
+	rest := strings.TrimLeft(arg, "0123456789")
+	return strings.HasPrefix(rest, ">") || strings.HasPrefix(rest, "<") || strings.HasPrefix(rest, "&>")
+}
@@ -0,0 +1,96 @@
+package shellparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize(`echo "a | b" | grep -v x && ls; (cd /tmp) &`)
+	var texts []string
+	var operators []bool
+	for _, token := range tokens {
+		texts = append(texts, token.Text)
+		operators = append(operators, token.Operator)
+	}
+	assert.Equal(t, []string{"echo", "a | b", "|", "grep", "-v", "x", "&&", "ls", ";", "(", "cd", "/tmp", ")", "&"}, texts)
+	assert.Equal(t, []bool{false, false, true, false, false, false, true, false, true, true, false, false, true, true}, operators)
+}
+
+func TestFields(t *testing.T) {
+	assert.Equal(t, []string{"echo", "a b", "c\"d", "e f"}, Fields(`echo 'a b' "c\"d" e\ f`))
+	assert.Equal(t, []string{"make", "2>&1", "tee", "log"}, Fields("make 2>&1 | tee log"))
+	assert.Equal(t, []string{"ls"}, Fields("ls # list files"))
+	assert.Equal(t, []string{"echo", "a#b"}, Fields("echo a#b"))
+	assert.Empty(t, Fields(""))
+}
+
+func TestCommands(t *testing.T) {
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, Commands("a|b && c;d\ne"))
+	assert.Equal(t, [][]string{{"x"}, {"y"}}, Commands("x |& y"))
+	assert.Equal(t, [][]string{{"cd", "src"}, {"make"}}, Commands("(cd src && make)"))
+	assert.Equal(t, [][]string{{"cmd", "&>/dev/null"}}, Commands("cmd &>/dev/null"))
+}
+
+func TestStripPrefixes(t *testing.T) {
+	assert.Equal(t, []string{"apt", "update"}, StripPrefixes([]string{"sudo", "-u", "root", "apt", "update"}))
+	assert.Equal(t, []string{"make"}, StripPrefixes([]string{"FOO=1", "env", "-u", "X", "BAR=2", "make"}))
+	assert.Equal(t, []string{"ls"}, StripPrefixes([]string{"nohup", "nice", "-n", "10", "ls"}))
+	assert.Equal(t, []string{"cat"}, StripPrefixes([]string{"<", "in.txt", "cat"}))
+	assert.Equal(t, []string{"grep", "x"}, StripPrefixes([]string{"if", "!", "grep", "x"}))
+	assert.Empty(t, StripPrefixes([]string{"A=1"}))
+}
+
+func TestPrograms(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la", []string{"ls"}},
+		{"cat log | grep err | wc -l", []string{"cat", "grep", "wc"}},
+		{"/usr/bin/git status", []string{"git"}},
+		{"sudo -E docker ps", []string{"docker"}},
+		{"GOOS=linux go build ./...", []string{"go"}},
+		{"make 2>&1 | tee build.log", []string{"make", "tee"}},
+		{"cd src && make && ./bin/app", []string{"cd", "make", "app"}},
+		{"echo $(date)", []string{"echo", "date"}},
+		{"for f in *.go; do gofmt -l $f; done", []string{"gofmt"}},
+		{"if grep -q x f; then echo yes; fi", []string{"grep", "echo"}},
+		{`\ls`, []string{"ls"}},
+		{`echo "a | grep b"`, []string{"echo"}},
+		{"FOO=bar", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Programs(tt.command), "command %q", tt.command)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	assert.Equal(t, "kubectl", Program("KUBECONFIG=x kubectl get pods | grep api"))
+	assert.Equal(t, "", Program("  "))
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"ls", "ls"},
+		{"/usr/bin/env", "/usr/bin/env"},
+		{"git status", "'git status'"},
+		{`echo "it's done"`, `'echo "it'\''s done"'`},
+		{"printf 'a\nb'", `'printf '\''a` + "\n" + `b'\'''`},
+		{"echo $HOME; rm *", "'echo $HOME; rm *'"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Quote(tt.in), tt.in)
+	}
+
+	// Quoting round-trips through the tokenizer
+	for _, tt := range tests {
+		assert.Equal(t, []string{tt.in}, Fields(Quote(tt.in)), tt.in)
+	}
+}
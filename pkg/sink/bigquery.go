@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// bigQueryEndpoint is the BigQuery REST API, replaced in tests
+var bigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+// DefaultTokenCommand prints an access token of the gcloud login
+const DefaultTokenCommand = "gcloud auth print-access-token"
+
+// bigQuery streams rows with tabledata.insertAll
+type bigQuery struct {
+	insertURL    string
+	tokenCommand string
+	client       *http.Client
+}
+
+func newBigQuery(u *url.URL, opts Options) (*bigQuery, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid bigquery url: want bigquery://project/dataset/table")
+	}
+
+	tokenCommand := opts.TokenCommand
+	if tokenCommand == "" {
+		tokenCommand = DefaultTokenCommand
+	}
+	return &bigQuery{
+		insertURL: fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigQueryEndpoint,
+			url.PathEscape(u.Host), url.PathEscape(parts[0]), url.PathEscape(parts[1])),
+		tokenCommand: tokenCommand,
+		client:       opts.Client,
+	}, nil
+}
+
+// runTokenCommand is replaced in tests
+var runTokenCommand = func(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty token command")
+	}
+	out, err := exec.Command(fields[0], fields[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", command, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type insertAllRow struct {
+	InsertID string `json:"insertId"`
+	JSON     Record `json:"json"`
+}
+
+type insertAllResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// Send streams records with their key as insertId, which BigQuery uses to
+// drop rows of a retried batch on a best-effort basis
+func (b *bigQuery) Send(ctx context.Context, records []Record) error {
+	token, err := runTokenCommand(b.tokenCommand)
+	if err != nil {
+		return fmt.Errorf("bigquery: failed to get an access token: %w", err)
+	}
+
+	rows := make([]insertAllRow, len(records))
+	for i, r := range records {
+		rows[i] = insertAllRow{InsertID: r.Key, JSON: r}
+	}
+	body, err := json.Marshal(map[string]any{"rows": rows})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.insertURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bigquery: %s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 512)])))
+	}
+
+	// Rows of a batch with errors are all rejected, the batch is sent again
+	var result insertAllResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("bigquery: invalid response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 && len(result.InsertErrors[0].Errors) > 0 {
+		first := result.InsertErrors[0]
+		return fmt.Errorf("bigquery: %d rows rejected, row %d: %s: %s", len(result.InsertErrors),
+			first.Index, first.Errors[0].Reason, first.Errors[0].Message)
+	}
+	return nil
+}
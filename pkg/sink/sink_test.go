@@ -0,0 +1,209 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink keeps what it was sent and fails while failing is set
+type fakeSink struct {
+	batches [][]Record
+	failing error
+}
+
+func (f *fakeSink) Send(ctx context.Context, records []Record) error {
+	if f.failing != nil {
+		return f.failing
+	}
+	f.batches = append(f.batches, records)
+	return nil
+}
+
+func insertEntries(t *testing.T, db *storage.DB, n int) {
+	t.Helper()
+	keepAll := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+	for i := 0; i < n; i++ {
+		entry := &storage.HistoryEntry{Command: fmt.Sprintf("make %d", i), Timestamp: 1700000000 + int64(i)}
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+}
+
+func TestFlush(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	insertEntries(t, db, 5)
+
+	s := &fakeSink{failing: errors.New("warehouse down")}
+	opts := FlushOptions{Name: "test", Source: "abc", BatchSize: 2}
+
+	sent, err := Flush(context.Background(), db, s, opts)
+	assert.EqualError(t, err, "warehouse down")
+	assert.Equal(t, 0, sent)
+	state, err := db.GetSinkState("test")
+	require.NoError(t, err)
+	assert.Equal(t, "warehouse down", state.LastError)
+	assert.Equal(t, int64(5), state.Pending)
+
+	// Nothing was lost, the next flush sends everything
+	s.failing = nil
+	sent, err = Flush(context.Background(), db, s, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 5, sent)
+	require.Len(t, s.batches, 3)
+	assert.Equal(t, "abc-1", s.batches[0][0].Key)
+	assert.Equal(t, "make 0", s.batches[0][0].Command)
+	assert.Equal(t, "2023-11-14 22:13:20", s.batches[0][0].Timestamp)
+	assert.Equal(t, "abc-5", s.batches[2][0].Key)
+
+	state, err = db.GetSinkState("test")
+	require.NoError(t, err)
+	assert.Empty(t, state.LastError)
+	assert.Equal(t, int64(5), state.LastID)
+	assert.Zero(t, state.Pending)
+
+	// Only new entries go out
+	insertEntries(t, db, 1)
+	sent, err = Flush(context.Background(), db, s, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "abc-6", s.batches[3][0].Key)
+}
+
+func TestFlush_Interval(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	insertEntries(t, db, 3)
+
+	s := &fakeSink{}
+	opts := FlushOptions{Name: "test", BatchSize: 1, MaxBatches: 2, Interval: time.Hour}
+
+	sent, err := Flush(context.Background(), db, s, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	// Another save within the interval leaves the rest for later
+	sent, err = Flush(context.Background(), db, s, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	opts.Interval = 0
+	sent, err = Flush(context.Background(), db, s, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestClickHouse(t *testing.T) {
+	var query, token, user, password string
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		token = r.URL.Query().Get("insert_deduplication_token")
+		user, password, _ = r.BasicAuth()
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("CH_PASSWORD", "s3cret")
+	s, err := Open("clickhouse://fh:${CH_PASSWORD}@"+strings.TrimPrefix(server.URL, "http://")+"/ops/shell_history", Options{})
+	require.NoError(t, err)
+
+	records := []Record{{Key: "abc-1", Command: "make"}, {Key: "abc-2", Command: "make test"}}
+	require.NoError(t, s.Send(context.Background(), records))
+	assert.Equal(t, "INSERT INTO `ops`.`shell_history` FORMAT JSONEachRow", query)
+	assert.Equal(t, "abc-1..abc-2", token)
+	assert.Equal(t, "fh", user)
+	assert.Equal(t, "s3cret", password)
+	require.Len(t, lines, 2)
+	var first Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, records[0], first)
+}
+
+func TestClickHouse_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "Code: 60. DB::Exception: Table ops.shell_history does not exist\n")
+	}))
+	defer server.Close()
+
+	s, err := Open("clickhouse://"+strings.TrimPrefix(server.URL, "http://")+"/ops/shell_history", Options{})
+	require.NoError(t, err)
+	err = s.Send(context.Background(), []Record{{Key: "abc-1"}})
+	assert.ErrorContains(t, err, "does not exist")
+}
+
+func TestBigQuery(t *testing.T) {
+	var path, auth string
+	var body struct {
+		Rows []insertAllRow `json:"rows"`
+	}
+	response := `{"kind": "bigquery#tableDataInsertAllResponse"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = io.WriteString(w, response)
+	}))
+	defer server.Close()
+
+	origEndpoint, origToken := bigQueryEndpoint, runTokenCommand
+	t.Cleanup(func() {
+		bigQueryEndpoint, runTokenCommand = origEndpoint, origToken
+	})
+	bigQueryEndpoint = server.URL
+	var ranCommand string
+	runTokenCommand = func(command string) (string, error) {
+		ranCommand = command
+		return "ya29.token", nil
+	}
+
+	s, err := Open("bigquery://my-project/ops/shell_history", Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Send(context.Background(), []Record{{Key: "abc-1", Command: "make"}}))
+	assert.Equal(t, DefaultTokenCommand, ranCommand)
+	assert.Equal(t, "/projects/my-project/datasets/ops/tables/shell_history/insertAll", path)
+	assert.Equal(t, "Bearer ya29.token", auth)
+	require.Len(t, body.Rows, 1)
+	assert.Equal(t, "abc-1", body.Rows[0].InsertID)
+	assert.Equal(t, "make", body.Rows[0].JSON.Command)
+
+	response = `{"insertErrors": [{"index": 0, "errors": [{"reason": "invalid", "message": "no such field: program"}]}]}`
+	err = s.Send(context.Background(), []Record{{Key: "abc-1"}})
+	assert.ErrorContains(t, err, "no such field: program")
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	for _, rawURL := range []string{
+		"postgres://localhost/fh",
+		"clickhouse://localhost:8123/ops",
+		"bigquery://my-project/ops",
+	} {
+		_, err := Open(rawURL, Options{})
+		assert.Error(t, err, rawURL)
+	}
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "clickhouse://fh:xxxxx@ch:8123/ops/h", Name("clickhouse://fh:s3cret@ch:8123/ops/h"))
+	assert.Equal(t, "bigquery://p/d/t", Name("bigquery://p/d/t"))
+}
+
+func TestSource(t *testing.T) {
+	assert.Len(t, Source("machine", "/home/alice/.fh/history.db"), 16)
+	assert.NotEqual(t, Source("machine", "/a.db"), Source("machine", "/b.db"))
+}
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Comparison holds statistics for a period and the period right before it
+type Comparison struct {
+	Period   time.Duration
+	Current  *Stats // Ending now
+	Previous *Stats // Ending where Current starts
+}
+
+// Compare gathers statistics for the last period up to now and for the
+// period before it, with filters applied to both. Any After or Before in
+// filters is replaced by the period bounds.
+func Compare(db storage.Store, filters storage.QueryFilters, period time.Duration, now time.Time) (*Comparison, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("invalid comparison period %s", period)
+	}
+
+	end := now.Unix()
+	start := now.Add(-period).Unix()
+	previousStart := now.Add(-2 * period).Unix()
+
+	current := filters
+	current.After, current.Before = start, end
+	currentStats, err := CollectFiltered(db, current)
+	if err != nil {
+		return nil, err
+	}
+
+	// Before is inclusive, stop one second short so no entry counts twice
+	previous := filters
+	previous.After, previous.Before = previousStart, start-1
+	previousStats, err := CollectFiltered(db, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{
+		Period:   period,
+		Current:  currentStats,
+		Previous: previousStats,
+	}, nil
+}
+
+// VolumeChange returns how many more commands ran in the current period
+// (negative for fewer)
+func (c *Comparison) VolumeChange() int64 {
+	return c.Current.TotalCommands - c.Previous.TotalCommands
+}
+
+// VolumeChangePercent returns VolumeChange relative to the previous period,
+// and false when the previous period had no commands
+func (c *Comparison) VolumeChangePercent() (float64, bool) {
+	if c.Previous.TotalCommands == 0 {
+		return 0, false
+	}
+	return float64(c.VolumeChange()) / float64(c.Previous.TotalCommands) * 100, true
+}
+
+// SuccessRateChange returns the change in success rate in percentage points
+func (c *Comparison) SuccessRateChange() float64 {
+	return c.Current.SuccessRate - c.Previous.SuccessRate
+}
+
+// NewTopCommands returns the current top n commands that were not among the
+// previous period's top n, in current order
+func (c *Comparison) NewTopCommands(n int) []CommandCount {
+	before := make(map[string]bool)
+	for i := 0; i < min(n, len(c.Previous.TopCommands)); i++ {
+		before[c.Previous.TopCommands[i].Command] = true
+	}
+
+	var added []CommandCount
+	for i := 0; i < min(n, len(c.Current.TopCommands)); i++ {
+		if cmd := c.Current.TopCommands[i]; !before[cmd.Command] {
+			added = append(added, cmd)
+		}
+	}
+	return added
+}
+
+// Format formats the comparison for display
+func (c *Comparison) Format(topN int) string {
+	if c.Current.TotalCommands == 0 && c.Previous.TotalCommands == 0 {
+		return "No commands in either period.\n"
+	}
+
+	result := "fh - History Statistics Compared\n"
+	result += "================================\n\n"
+
+	result += fmt.Sprintf("%-18s %10s %10s %10s\n", "", "Previous", "Current", "Change")
+
+	volume := fmt.Sprintf("%+d", c.VolumeChange())
+	if percent, ok := c.VolumeChangePercent(); ok {
+		volume += fmt.Sprintf(" (%+.1f%%)", percent)
+	}
+	result += fmt.Sprintf("%-18s %10d %10d %s\n", "Total Commands:", c.Previous.TotalCommands, c.Current.TotalCommands, volume)
+	result += fmt.Sprintf("%-18s %10d %10d %+d\n", "Unique Commands:", c.Previous.UniqueCommands, c.Current.UniqueCommands, c.Current.UniqueCommands-c.Previous.UniqueCommands)
+
+	// A rate over no commands means nothing, leave it out
+	successRate := func(s *Stats) string {
+		if s.TotalCommands == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", s.SuccessRate)
+	}
+	successChange := "-"
+	if c.Current.TotalCommands > 0 && c.Previous.TotalCommands > 0 {
+		successChange = fmt.Sprintf("%+.1f pts", c.SuccessRateChange())
+	}
+	result += fmt.Sprintf("%-18s %10s %10s %s\n\n", "Success Rate:", successRate(c.Previous), successRate(c.Current), successChange)
+
+	// Top N commands of the current period, with their previous count
+	if len(c.Current.TopCommands) > 0 {
+		previous := make(map[string]int)
+		for _, cmd := range c.Previous.TopCommands {
+			previous[cmd.Command] = cmd.Count
+		}
+		added := make(map[string]bool)
+		for _, cmd := range c.NewTopCommands(topN) {
+			added[cmd.Command] = true
+		}
+
+		result += fmt.Sprintf("Top %d Commands:\n", min(topN, len(c.Current.TopCommands)))
+		result += "----------------\n"
+		for i := 0; i < min(topN, len(c.Current.TopCommands)); i++ {
+			cmd := c.Current.TopCommands[i]
+			// Truncate long commands
+			displayCmd := cmd.Command
+			if len(displayCmd) > 60 {
+				displayCmd = displayCmd[:57] + "..."
+			}
+			marker := ""
+			if added[cmd.Command] {
+				marker = "  (new)"
+			}
+			result += fmt.Sprintf("%3d. (%3d | %+4d) %s%s\n", i+1, cmd.Count, cmd.Count-previous[cmd.Command], displayCmd, marker)
+		}
+		result += "\n"
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	week := 7 * 24 * time.Hour
+	lastWeek := now.Add(-week - time.Hour).Unix()
+	thisWeek := now.Add(-time.Hour).Unix()
+
+	commands := []struct {
+		cmd       string
+		exitCode  int
+		timestamp int64
+	}{
+		// Previous week: 4 commands, 1 failed
+		{"git status", 0, lastWeek},
+		{"git status", 0, lastWeek + 1},
+		{"make test", 1, lastWeek + 2},
+		{"git status", 0, lastWeek + 3},
+		// Current week: 6 commands, all succeeded
+		{"make test", 0, thisWeek},
+		{"make test", 0, thisWeek + 1},
+		{"make test", 0, thisWeek + 2},
+		{"docker ps", 0, thisWeek + 3},
+		{"docker ps", 0, thisWeek + 4},
+		{"git status", 0, thisWeek + 5},
+		// Too old for either period
+		{"old", 0, now.Add(-3 * week).Unix()},
+	}
+	for i, c := range commands {
+		require.NoError(t, db.Insert(&storage.HistoryEntry{
+			Command:   c.cmd,
+			Timestamp: c.timestamp,
+			ExitCode:  c.exitCode,
+			Hash:      storage.GenerateHash(fmt.Sprintf("%s-%d", c.cmd, i)),
+		}))
+	}
+
+	comparison, err := Compare(db, storage.QueryFilters{}, week, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(6), comparison.Current.TotalCommands)
+	assert.Equal(t, int64(4), comparison.Previous.TotalCommands)
+	assert.Equal(t, int64(2), comparison.VolumeChange())
+
+	percent, ok := comparison.VolumeChangePercent()
+	require.True(t, ok)
+	assert.Equal(t, 50.0, percent)
+	assert.Equal(t, 25.0, comparison.SuccessRateChange())
+
+	// docker ps is new to the top 2; make test was 2nd last week
+	assert.Equal(t, []CommandCount{{Command: "docker ps", Count: 2}}, comparison.NewTopCommands(2))
+
+	output := comparison.Format(2)
+	assert.Contains(t, output, "+2 (+50.0%)")
+	assert.Contains(t, output, "+25.0 pts")
+	assert.Contains(t, output, "(  3 |   +2) make test")
+	assert.Contains(t, output, "(  2 |   +2) docker ps  (new)")
+}
+
+func TestCompare_EmptyPrevious(t *testing.T) {
+	tempDir, cleanup := testutil.TempDir(t)
+	defer cleanup()
+
+	db, err := storage.Open(tempDir + "/test.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	require.NoError(t, db.Insert(&storage.HistoryEntry{
+		Command:   "echo test",
+		Timestamp: now.Add(-time.Hour).Unix(),
+		Hash:      storage.GenerateHash("echo test"),
+	}))
+
+	comparison, err := Compare(db, storage.QueryFilters{}, 24*time.Hour, now)
+	require.NoError(t, err)
+
+	_, ok := comparison.VolumeChangePercent()
+	assert.False(t, ok)
+	assert.Len(t, comparison.NewTopCommands(10), 1)
+
+	output := comparison.Format(10)
+	assert.Contains(t, output, "Success Rate:")
+	assert.NotContains(t, output, "pts")
+}
+
+func TestCompare_InvalidPeriod(t *testing.T) {
+	_, err := Compare(nil, storage.QueryFilters{}, 0, time.Now())
+	assert.Error(t, err)
+}
+
+func TestComparison_FormatEmpty(t *testing.T) {
+	comparison := &Comparison{Current: &Stats{}, Previous: &Stats{}}
+	assert.Equal(t, "No commands in either period.\n", comparison.Format(10))
+}
@@ -0,0 +1,128 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Row is one line of a dashboard list and the entries behind it,
+// most recent first
+type Row struct {
+	Label   string
+	Entries []*storage.HistoryEntry
+}
+
+// Count returns how many entries the row stands for
+func (r Row) Count() int {
+	return len(r.Entries)
+}
+
+// Dashboard groups history entries for the interactive stats dashboard
+type Dashboard struct {
+	Total       int
+	Unique      int
+	SuccessRate float64
+	Commands    []Row // Entries grouped by command
+	Failures    []Row // Failed entries grouped by command
+	Directories []Row // Entries grouped by working directory
+	// Activity holds the entries run in each weekday (Sunday first) and hour
+	Activity [7][24][]*storage.HistoryEntry
+}
+
+// NewDashboard builds the dashboard for entries, which are expected most
+// recent first as returned by Query
+func NewDashboard(entries []*storage.HistoryEntry) *Dashboard {
+	d := &Dashboard{Total: len(entries)}
+
+	var commands, failures, directories rowIndex
+	successCount := 0
+	for _, entry := range entries {
+		commands.add(entry.Command, entry)
+		if entry.ExitCode == 0 {
+			successCount++
+		} else {
+			failures.add(entry.Command, entry)
+		}
+		if entry.Cwd != "" {
+			directories.add(entry.Cwd, entry)
+		}
+
+		// Inferred times would pile up in made-up hours
+		if !entry.ApproxTime {
+			t := time.Unix(entry.Timestamp, 0)
+			d.Activity[t.Weekday()][t.Hour()] = append(d.Activity[t.Weekday()][t.Hour()], entry)
+		}
+	}
+
+	d.Unique = len(commands.rows)
+	if d.Total > 0 {
+		d.SuccessRate = float64(successCount) / float64(d.Total) * 100
+	}
+	d.Commands = commands.sorted()
+	d.Failures = failures.sorted()
+	d.Directories = directories.sorted()
+
+	return d
+}
+
+// ShortenDirectories labels the directory rows as path writes them, like
+// ShortenDirectories does the counts of Stats
+func (d *Dashboard) ShortenDirectories(path func(string) string) {
+	var directories rowIndex
+	for _, row := range d.Directories {
+		for _, entry := range row.Entries {
+			directories.add(path(row.Label), entry)
+		}
+	}
+
+	// Merged rows keep their entries most recent first
+	for _, row := range directories.rows {
+		sort.SliceStable(row.Entries, func(i, j int) bool {
+			return row.Entries[i].Timestamp > row.Entries[j].Timestamp
+		})
+	}
+	d.Directories = directories.sorted()
+}
+
+// MaxActivity returns the largest number of entries in one activity cell
+func (d *Dashboard) MaxActivity() int {
+	busiest := 0
+	for _, hours := range d.Activity {
+		for _, cell := range hours {
+			if len(cell) > busiest {
+				busiest = len(cell)
+			}
+		}
+	}
+	return busiest
+}
+
+// rowIndex groups entries into rows by label, keeping first-seen order
+type rowIndex struct {
+	rows  []Row
+	index map[string]int
+}
+
+func (ri *rowIndex) add(label string, entry *storage.HistoryEntry) {
+	if ri.index == nil {
+		ri.index = make(map[string]int)
+	}
+	i, ok := ri.index[label]
+	if !ok {
+		i = len(ri.rows)
+		ri.index[label] = i
+		ri.rows = append(ri.rows, Row{Label: label})
+	}
+	ri.rows[i].Entries = append(ri.rows[i].Entries, entry)
+}
+
+// sorted returns the rows by count (descending), most recently used first
+// on ties
+func (ri *rowIndex) sorted() []Row {
+	sort.SliceStable(ri.rows, func(i, j int) bool {
+		return ri.rows[i].Count() > ri.rows[j].Count()
+	})
+	return ri.rows
+}
@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDashboard_Empty(t *testing.T) {
+	d := NewDashboard(nil)
+
+	assert.Equal(t, 0, d.Total)
+	assert.Equal(t, 0.0, d.SuccessRate)
+	assert.Empty(t, d.Commands)
+	assert.Empty(t, d.Failures)
+	assert.Empty(t, d.Directories)
+	assert.Equal(t, 0, d.MaxActivity())
+}
+
+func TestNewDashboard(t *testing.T) {
+	// Tuesday 14:30 local time
+	base := time.Date(2024, 1, 16, 14, 30, 0, 0, time.Local).Unix()
+
+	// Most recent first, as Query returns them
+	entries := []*storage.HistoryEntry{
+		{Command: "make test", Cwd: "/src/fh", ExitCode: 2, Timestamp: base + 300},
+		{Command: "git status", Cwd: "/src/fh", Timestamp: base + 200},
+		{Command: "make test", Cwd: "/src/fh", ExitCode: 2, Timestamp: base + 100},
+		{Command: "ls", Cwd: "/tmp", Timestamp: base},
+		{Command: "git status", Timestamp: base - 24*3600},
+	}
+
+	d := NewDashboard(entries)
+
+	assert.Equal(t, 5, d.Total)
+	assert.Equal(t, 3, d.Unique)
+	assert.Equal(t, 60.0, d.SuccessRate)
+
+	// Ties keep the most recently used first
+	require.Len(t, d.Commands, 3)
+	assert.Equal(t, "make test", d.Commands[0].Label)
+	assert.Equal(t, []*storage.HistoryEntry{entries[0], entries[2]}, d.Commands[0].Entries)
+	assert.Equal(t, "git status", d.Commands[1].Label)
+	assert.Equal(t, "ls", d.Commands[2].Label)
+
+	require.Len(t, d.Failures, 1)
+	assert.Equal(t, "make test", d.Failures[0].Label)
+	assert.Equal(t, 2, d.Failures[0].Count())
+
+	// Entries without a directory are left out
+	require.Len(t, d.Directories, 2)
+	assert.Equal(t, "/src/fh", d.Directories[0].Label)
+	assert.Equal(t, 3, d.Directories[0].Count())
+	assert.Equal(t, "/tmp", d.Directories[1].Label)
+
+	assert.Len(t, d.Activity[time.Tuesday][14], 4)
+	assert.Equal(t, []*storage.HistoryEntry{entries[4]}, d.Activity[time.Monday][14])
+	assert.Equal(t, 4, d.MaxActivity())
+
+	// Inferred times stay out of the heatmap but count everywhere else
+	entries[4].ApproxTime = true
+	d = NewDashboard(entries)
+	assert.Empty(t, d.Activity[time.Monday][14])
+	assert.Equal(t, 5, d.Total)
+}
+
+func TestDashboard_ShortenDirectories(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "make", Cwd: "/home/me/src/fh", Timestamp: 400},
+		{Command: "ls", Cwd: "/tmp", Timestamp: 300},
+		{Command: "ls", Cwd: "/tmp", Timestamp: 250},
+		{Command: "make", Cwd: "/mnt/c/Users/me/src/fh", Timestamp: 200},
+		{Command: "make", Cwd: "/home/me/src/fh", Timestamp: 100},
+	}
+	d := NewDashboard(entries)
+
+	// Both spellings of the checkout end up in one row
+	d.ShortenDirectories(func(dir string) string {
+		if dir == "/tmp" {
+			return dir
+		}
+		return "~fh"
+	})
+	require.Len(t, d.Directories, 2)
+	assert.Equal(t, "~fh", d.Directories[0].Label)
+	require.Equal(t, 3, d.Directories[0].Count())
+	assert.Equal(t, []int64{400, 200, 100}, []int64{d.Directories[0].Entries[0].Timestamp, d.Directories[0].Entries[1].Timestamp, d.Directories[0].Entries[2].Timestamp})
+	assert.Equal(t, "/tmp", d.Directories[1].Label)
+}
@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Fast gathers statistics of profile ("" = all profiles) from the counters
+// the database keeps on every write instead of reading each entry. It fills
+// in everything but the top programs and directories, which need the
+// entries, and keeps topN top commands. Hours of day are those of the local
+// time zone, rounded down to a whole hour in zones with a fractional offset.
+func Fast(db *storage.DB, profile string, topN int) (*Stats, error) {
+	counts, err := db.Counts(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read counts: %w", err)
+	}
+
+	stats := &Stats{
+		TimeDistribution: make(map[int]int),
+	}
+
+	var failed int64
+	for _, h := range counts.Hours {
+		stats.TotalCommands += h.Total
+		stats.ApproxCommands += h.Approx
+		failed += h.Failed
+		if exact := h.Total - h.Approx; exact > 0 {
+			stats.TimeDistribution[time.Unix(h.Hour, 0).Hour()] += int(exact)
+		}
+	}
+
+	if stats.TotalCommands == 0 {
+		return stats, nil
+	}
+
+	stats.UniqueCommands = counts.UniqueCommands
+	stats.SuccessRate = float64(stats.TotalCommands-failed) / float64(stats.TotalCommands) * 100
+
+	stats.FirstCommand = time.Unix(counts.First, 0)
+	stats.LastCommand = time.Unix(counts.Last, 0)
+	daysDiff := stats.LastCommand.Sub(stats.FirstCommand).Hours() / 24
+	if daysDiff > 0 {
+		stats.AvgPerDay = float64(stats.TotalCommands) / daysDiff
+	} else {
+		stats.AvgPerDay = float64(stats.TotalCommands)
+	}
+
+	top, err := db.TopCommands(profile, topN)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range top {
+		stats.TopCommands = append(stats.TopCommands, CommandCount{Command: c.Entry.Command, Count: c.Count})
+	}
+
+	return stats, nil
+}
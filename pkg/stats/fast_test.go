@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/spideyz0r/fh/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFast_MatchesCollect(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	keepAll := storage.DedupConfig{Enabled: true, Strategy: storage.KeepAll}
+	base := int64(1700000000)
+	for i, command := range []string{"ls", "make", "ls", "git push", "ls", "make", "false"} {
+		entry := &storage.HistoryEntry{
+			Command:   command,
+			Timestamp: base + int64(i)*5000,
+			Cwd:       "/src",
+		}
+		if command == "false" {
+			entry.ExitCode = 1
+		}
+		entry.ApproxTime = command == "git push"
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+
+	want, err := Collect(db)
+	require.NoError(t, err)
+	got, err := Fast(db, "", 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, want.TotalCommands, got.TotalCommands)
+	assert.Equal(t, want.UniqueCommands, got.UniqueCommands)
+	assert.InDelta(t, want.SuccessRate, got.SuccessRate, 0.001)
+	assert.InDelta(t, want.AvgPerDay, got.AvgPerDay, 0.001)
+	assert.Equal(t, want.FirstCommand, got.FirstCommand)
+	assert.Equal(t, want.LastCommand, got.LastCommand)
+	assert.Equal(t, want.ApproxCommands, got.ApproxCommands)
+	assert.Equal(t, want.TimeDistribution, got.TimeDistribution)
+	require.NotEmpty(t, got.TopCommands)
+	assert.Equal(t, CommandCount{Command: "ls", Count: 3}, got.TopCommands[0])
+
+	// Programs and directories need every entry
+	assert.Empty(t, got.TopPrograms)
+	assert.Empty(t, got.CommandsByDir)
+}
+
+func TestFast_EmptyDatabase(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+
+	stats, err := Fast(db, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.TotalCommands)
+	assert.Equal(t, "No commands in history yet.", stats.Format(10))
+}
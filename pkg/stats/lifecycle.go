@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Lifecycle describes how one command has been used over time
+type Lifecycle struct {
+	Command     string
+	Count       int
+	SuccessRate float64
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Months      []MonthCount // Every month from first to last seen, oldest first
+	Directories []DirectoryCount
+	Branches    []BranchCount
+}
+
+// MonthCount represents a calendar month and how many times the command ran
+// in it
+type MonthCount struct {
+	Month time.Time // First day of the month, local time
+	Count int
+}
+
+// BranchCount represents a git branch and command count
+type BranchCount struct {
+	Branch string
+	Count  int
+}
+
+// NewLifecycle builds the lifecycle of command from its entries, in any order
+func NewLifecycle(command string, entries []*storage.HistoryEntry) *Lifecycle {
+	l := &Lifecycle{Command: command, Count: len(entries)}
+	if len(entries) == 0 {
+		return l
+	}
+
+	months := make(map[time.Time]int)
+	directories := make(map[string]int)
+	branches := make(map[string]int)
+	successCount := 0
+
+	first, last := entries[0].Timestamp, entries[0].Timestamp
+	for _, entry := range entries {
+		if entry.Timestamp < first {
+			first = entry.Timestamp
+		}
+		if entry.Timestamp > last {
+			last = entry.Timestamp
+		}
+
+		months[monthOf(time.Unix(entry.Timestamp, 0))]++
+
+		if entry.Cwd != "" {
+			directories[entry.Cwd]++
+		}
+		if entry.GitBranch != "" {
+			branches[entry.GitBranch]++
+		}
+		if entry.ExitCode == 0 {
+			successCount++
+		}
+	}
+
+	l.SuccessRate = float64(successCount) / float64(l.Count) * 100
+	l.FirstSeen = time.Unix(first, 0)
+	l.LastSeen = time.Unix(last, 0)
+
+	// Keep quiet months so the timeline shows the gaps
+	for month := monthOf(l.FirstSeen); !month.After(l.LastSeen); month = month.AddDate(0, 1, 0) {
+		l.Months = append(l.Months, MonthCount{Month: month, Count: months[month]})
+	}
+
+	l.Directories = make([]DirectoryCount, 0, len(directories))
+	for dir, count := range directories {
+		l.Directories = append(l.Directories, DirectoryCount{Directory: dir, Count: count})
+	}
+	sort.Slice(l.Directories, func(i, j int) bool {
+		if l.Directories[i].Count != l.Directories[j].Count {
+			return l.Directories[i].Count > l.Directories[j].Count
+		}
+		return l.Directories[i].Directory < l.Directories[j].Directory
+	})
+
+	l.Branches = make([]BranchCount, 0, len(branches))
+	for branch, count := range branches {
+		l.Branches = append(l.Branches, BranchCount{Branch: branch, Count: count})
+	}
+	sort.Slice(l.Branches, func(i, j int) bool {
+		if l.Branches[i].Count != l.Branches[j].Count {
+			return l.Branches[i].Count > l.Branches[j].Count
+		}
+		return l.Branches[i].Branch < l.Branches[j].Branch
+	})
+
+	return l
+}
+
+// monthOf returns the first day of t's month
+func monthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Format formats the lifecycle for display, listing at most topN
+// directories and branches
+func (l *Lifecycle) Format(topN int) string {
+	if l.Count == 0 {
+		return fmt.Sprintf("%q is not in history.\n", l.Command)
+	}
+
+	result := fmt.Sprintf("History of: %s\n", l.Command)
+	result += strings.Repeat("=", min(72, len("History of: ")+len(l.Command))) + "\n\n"
+
+	result += fmt.Sprintf("Runs:             %d\n", l.Count)
+	result += fmt.Sprintf("Success Rate:     %.1f%%\n", l.SuccessRate)
+	result += fmt.Sprintf("First Seen:       %s\n", l.FirstSeen.Format("2006-01-02 15:04:05"))
+	result += fmt.Sprintf("Last Seen:        %s\n\n", l.LastSeen.Format("2006-01-02 15:04:05"))
+
+	// Timeline, scaled like the hour distribution
+	maxCount := 0
+	for _, month := range l.Months {
+		if month.Count > maxCount {
+			maxCount = month.Count
+		}
+	}
+	result += "Runs by Month:\n"
+	result += "--------------\n"
+	for _, month := range l.Months {
+		bar := strings.Repeat("█", month.Count*40/maxCount)
+		result += fmt.Sprintf("%s (%3d) %s\n", month.Month.Format("2006-01"), month.Count, bar)
+	}
+	result += "\n"
+
+	if len(l.Directories) > 0 {
+		result += fmt.Sprintf("Top %d Directories:\n", min(topN, len(l.Directories)))
+		result += "-------------------\n"
+		for i := 0; i < min(topN, len(l.Directories)); i++ {
+			dir := l.Directories[i]
+			percentage := float64(dir.Count) / float64(l.Count) * 100
+			result += fmt.Sprintf("%3d. (%3d | %5.1f%%) %s\n", i+1, dir.Count, percentage, dir.Directory)
+		}
+		result += "\n"
+	}
+
+	if len(l.Branches) > 0 {
+		result += fmt.Sprintf("Top %d Branches:\n", min(topN, len(l.Branches)))
+		result += "----------------\n"
+		for i := 0; i < min(topN, len(l.Branches)); i++ {
+			branch := l.Branches[i]
+			percentage := float64(branch.Count) / float64(l.Count) * 100
+			result += fmt.Sprintf("%3d. (%3d | %5.1f%%) %s\n", i+1, branch.Count, percentage, branch.Branch)
+		}
+		result += "\n"
+	}
+
+	return result
+}
@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLifecycle_Empty(t *testing.T) {
+	l := NewLifecycle("terraform apply", nil)
+
+	assert.Equal(t, 0, l.Count)
+	assert.Empty(t, l.Months)
+	assert.Equal(t, "\"terraform apply\" is not in history.\n", l.Format(5))
+}
+
+func TestNewLifecycle(t *testing.T) {
+	at := func(year int, month time.Month, day int) int64 {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.Local).Unix()
+	}
+
+	entries := []*storage.HistoryEntry{
+		{Command: "terraform apply", Cwd: "/infra/prod", GitBranch: "main", Timestamp: at(2024, 3, 20)},
+		{Command: "terraform apply", Cwd: "/infra/prod", GitBranch: "main", Timestamp: at(2024, 1, 5)},
+		{Command: "terraform apply", Cwd: "/infra/dev", GitBranch: "feature", ExitCode: 1, Timestamp: at(2024, 1, 9)},
+		{Command: "terraform apply", Timestamp: at(2024, 3, 1)},
+	}
+
+	l := NewLifecycle("terraform apply", entries)
+
+	assert.Equal(t, 4, l.Count)
+	assert.Equal(t, 75.0, l.SuccessRate)
+	assert.Equal(t, at(2024, 1, 5), l.FirstSeen.Unix())
+	assert.Equal(t, at(2024, 3, 20), l.LastSeen.Unix())
+
+	// February had no runs but stays in the timeline
+	require.Len(t, l.Months, 3)
+	assert.Equal(t, "2024-01", l.Months[0].Month.Format("2006-01"))
+	assert.Equal(t, 2, l.Months[0].Count)
+	assert.Equal(t, 0, l.Months[1].Count)
+	assert.Equal(t, 2, l.Months[2].Count)
+
+	assert.Equal(t, []DirectoryCount{{"/infra/prod", 2}, {"/infra/dev", 1}}, l.Directories)
+	assert.Equal(t, []BranchCount{{"main", 2}, {"feature", 1}}, l.Branches)
+
+	output := l.Format(5)
+	assert.Contains(t, output, "First Seen:       2024-01-05 12:00:00")
+	assert.Contains(t, output, "2024-02 (  0) \n")
+	assert.Contains(t, output, "  1. (  2 |  50.0%) /infra/prod")
+	assert.Contains(t, output, "  2. (  1 |  25.0%) feature")
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/spideyz0r/fh/pkg/shellparse"
 	"github.com/spideyz0r/fh/pkg/storage"
 )
 
@@ -15,8 +16,10 @@ type Stats struct {
 	SuccessRate      float64
 	AvgPerDay        float64
 	TopCommands      []CommandCount
+	TopPrograms      []ProgramCount
 	CommandsByDir    []DirectoryCount
-	TimeDistribution map[int]int // hour -> count
+	TimeDistribution map[int]int // hour -> count, without ApproxCommands
+	ApproxCommands   int64       // Imported without a recorded time
 	FirstCommand     time.Time
 	LastCommand      time.Time
 }
@@ -27,6 +30,13 @@ type CommandCount struct {
 	Count   int
 }
 
+// ProgramCount represents a program and how many commands ran it, counting
+// every pipeline stage (grep in "ps aux | grep x" counts as grep)
+type ProgramCount struct {
+	Program string
+	Count   int
+}
+
 // DirectoryCount represents a directory and command count
 type DirectoryCount struct {
 	Directory string
@@ -75,10 +85,12 @@ func Collect(db storage.Store) (*Stats, error) {
 			successCount++
 		}
 
-		// Time distribution (hour of day)
-		t := time.Unix(entry.Timestamp, 0)
-		hour := t.Hour()
-		stats.TimeDistribution[hour]++
+		// Time distribution (hour of day), inferred times would skew it
+		if entry.ApproxTime {
+			stats.ApproxCommands++
+		} else {
+			stats.TimeDistribution[time.Unix(entry.Timestamp, 0).Hour()]++
+		}
 
 		// Track first/last timestamps
 		if entry.Timestamp < firstTimestamp {
@@ -116,6 +128,8 @@ func Collect(db storage.Store) (*Stats, error) {
 		return stats.TopCommands[i].Count > stats.TopCommands[j].Count
 	})
 
+	stats.TopPrograms = countPrograms(entries)
+
 	// Build directories list
 	stats.CommandsByDir = make([]DirectoryCount, 0, len(directories))
 	for dir, count := range directories {
@@ -174,9 +188,11 @@ func CollectFiltered(db storage.Store, filters storage.QueryFilters) (*Stats, er
 			successCount++
 		}
 
-		t := time.Unix(entry.Timestamp, 0)
-		hour := t.Hour()
-		stats.TimeDistribution[hour]++
+		if entry.ApproxTime {
+			stats.ApproxCommands++
+		} else {
+			stats.TimeDistribution[time.Unix(entry.Timestamp, 0).Hour()]++
+		}
 
 		if entry.Timestamp < firstTimestamp {
 			firstTimestamp = entry.Timestamp
@@ -212,6 +228,8 @@ func CollectFiltered(db storage.Store, filters storage.QueryFilters) (*Stats, er
 		return stats.TopCommands[i].Count > stats.TopCommands[j].Count
 	})
 
+	stats.TopPrograms = countPrograms(entries)
+
 	// Build directories list
 	stats.CommandsByDir = make([]DirectoryCount, 0, len(directories))
 	for dir, count := range directories {
@@ -227,6 +245,56 @@ func CollectFiltered(db storage.Store, filters storage.QueryFilters) (*Stats, er
 	return stats, nil
 }
 
+// ShortenDirectories returns dirs with each directory as path writes it,
+// such as ~/src for the home directory's src. Directories written the same
+// are counted together. Sorted by count (descending) then directory.
+func ShortenDirectories(dirs []DirectoryCount, path func(string) string) []DirectoryCount {
+	counts := make(map[string]int, len(dirs))
+	for _, dir := range dirs {
+		counts[path(dir.Directory)] += dir.Count
+	}
+
+	shortened := make([]DirectoryCount, 0, len(counts))
+	for dir, count := range counts {
+		shortened = append(shortened, DirectoryCount{Directory: dir, Count: count})
+	}
+	sort.Slice(shortened, func(i, j int) bool {
+		if shortened[i].Count != shortened[j].Count {
+			return shortened[i].Count > shortened[j].Count
+		}
+		return shortened[i].Directory < shortened[j].Directory
+	})
+	return shortened
+}
+
+// countPrograms counts the commands that run each program, sorted by count
+// (descending) then name. A program used twice in one command counts once.
+func countPrograms(entries []*storage.HistoryEntry) []ProgramCount {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		seen := make(map[string]bool)
+		for _, program := range shellparse.Programs(entry.Command) {
+			if !seen[program] {
+				seen[program] = true
+				counts[program]++
+			}
+		}
+	}
+
+	programs := make([]ProgramCount, 0, len(counts))
+	for program, count := range counts {
+		programs = append(programs, ProgramCount{Program: program, Count: count})
+	}
+	sort.Slice(programs, func(i, j int) bool {
+		if programs[i].Count != programs[j].Count {
+			return programs[i].Count > programs[j].Count
+		}
+		return programs[i].Program < programs[j].Program
+	})
+
+	return programs
+}
+
 // Format formats statistics for display
 func (s *Stats) Format(topN int) string {
 	if s.TotalCommands == 0 {
@@ -260,6 +328,18 @@ func (s *Stats) Format(topN int) string {
 		result += "\n"
 	}
 
+	// Top programs, including every pipeline stage
+	if len(s.TopPrograms) > 0 {
+		result += fmt.Sprintf("Top %d Programs:\n", min(topN, len(s.TopPrograms)))
+		result += "----------------\n"
+		for i := 0; i < min(topN, len(s.TopPrograms)); i++ {
+			program := s.TopPrograms[i]
+			percentage := float64(program.Count) / float64(s.TotalCommands) * 100
+			result += fmt.Sprintf("%3d. (%3d | %5.1f%%) %s\n", i+1, program.Count, percentage, program.Program)
+		}
+		result += "\n"
+	}
+
 	// Top directories
 	if len(s.CommandsByDir) > 0 {
 		result += fmt.Sprintf("Top %d Directories:\n", min(5, len(s.CommandsByDir)))
@@ -276,7 +356,10 @@ func (s *Stats) Format(topN int) string {
 	if len(s.TimeDistribution) > 0 {
 		result += "Commands by Hour:\n"
 		result += "-----------------\n"
-		result += formatHourDistribution(s.TimeDistribution, s.TotalCommands)
+		result += formatHourDistribution(s.TimeDistribution, s.TotalCommands-s.ApproxCommands)
+		if s.ApproxCommands > 0 {
+			result += fmt.Sprintf("(%d imported commands without a recorded time not shown)\n", s.ApproxCommands)
+		}
 	}
 
 	return result
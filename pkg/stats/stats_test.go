@@ -1,6 +1,7 @@
 package stats
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -124,6 +125,10 @@ func TestCollect_MultipleCommands(t *testing.T) {
 	assert.Equal(t, 4, stats.CommandsByDir[0].Count)
 	assert.Equal(t, "/home", stats.CommandsByDir[1].Directory) // 2 commands
 	assert.Equal(t, 2, stats.CommandsByDir[1].Count)
+
+	// Programs group the echo commands together
+	require.NotEmpty(t, stats.TopPrograms)
+	assert.Equal(t, ProgramCount{Program: "echo", Count: 4}, stats.TopPrograms[0])
 }
 
 func TestCollect_TimeDistribution(t *testing.T) {
@@ -165,6 +170,21 @@ func TestCollect_TimeDistribution(t *testing.T) {
 	assert.Equal(t, 1, stats.TimeDistribution[18]) // 6pm: 1 command
 	assert.Equal(t, 1, stats.TimeDistribution[22]) // 10pm: 1 command
 	assert.Equal(t, 0, stats.TimeDistribution[12]) // 12pm: 0 commands
+
+	// Imported commands with an inferred time are left out
+	approx := &storage.HistoryEntry{
+		Command:    "make",
+		Timestamp:  time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location()).Unix(),
+		ApproxTime: true,
+	}
+	require.NoError(t, db.Insert(approx))
+
+	stats, err = Collect(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats.TotalCommands)
+	assert.Equal(t, int64(1), stats.ApproxCommands)
+	assert.Equal(t, 0, stats.TimeDistribution[12])
+	assert.Contains(t, stats.Format(5), "(1 imported commands without a recorded time not shown)")
 }
 
 func TestCollect_AveragePerDay(t *testing.T) {
@@ -227,6 +247,10 @@ func TestFormat_WithData(t *testing.T) {
 			{Command: "git status", Count: 20},
 			{Command: "echo test", Count: 15},
 		},
+		TopPrograms: []ProgramCount{
+			{Program: "git", Count: 60},
+			{Program: "grep", Count: 50},
+		},
 		CommandsByDir: []DirectoryCount{
 			{Directory: "/tmp", Count: 50},
 			{Directory: "/home", Count: 30},
@@ -253,6 +277,10 @@ func TestFormat_WithData(t *testing.T) {
 	assert.Contains(t, output, "git status")
 	assert.Contains(t, output, "echo test")
 
+	// Verify programs
+	assert.Contains(t, output, "Top 2 Programs:")
+	assert.Contains(t, output, " 50.0%) grep")
+
 	// Verify directories
 	assert.Contains(t, output, "Top 2 Directories:")
 	assert.Contains(t, output, "/tmp")
@@ -311,3 +339,36 @@ func TestCollectFiltered(t *testing.T) {
 func roundToOneDecimal(f float64) float64 {
 	return float64(int(f*10+0.5)) / 10
 }
+
+func TestCountPrograms_Pipelines(t *testing.T) {
+	entries := []*storage.HistoryEntry{
+		{Command: "ps aux | grep nginx"},
+		{Command: "grep -r TODO ."},
+		{Command: "cat log | grep err | grep -v debug"},
+		{Command: "FOO=1"},
+	}
+
+	assert.Equal(t, []ProgramCount{
+		{Program: "grep", Count: 3},
+		{Program: "cat", Count: 1},
+		{Program: "ps", Count: 1},
+	}, countPrograms(entries))
+}
+
+func TestShortenDirectories(t *testing.T) {
+	dirs := []DirectoryCount{
+		{Directory: "/home/me/src", Count: 3},
+		{Directory: "/srv", Count: 4},
+		{Directory: "/home/me", Count: 2},
+		{Directory: "/home/me/src/", Count: 1},
+	}
+	short := func(dir string) string {
+		return strings.Replace(strings.TrimSuffix(dir, "/"), "/home/me", "~", 1)
+	}
+
+	assert.Equal(t, []DirectoryCount{
+		{Directory: "/srv", Count: 4},
+		{Directory: "~/src", Count: 4},
+		{Directory: "~", Count: 2},
+	}, ShortenDirectories(dirs, short))
+}
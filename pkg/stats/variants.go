@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// VariantPlaceholder stands for the word the commands of a VariantGroup
+// differ in
+const VariantPlaceholder = "<arg>"
+
+// VariantGroup is a set of commands that are the same but for one word,
+// like kubectl logs api and kubectl logs worker: a candidate for a snippet
+type VariantGroup struct {
+	Template string    // The command with the differing word as VariantPlaceholder
+	Count    int       // Runs of all the variants
+	Variants []Variant // Most run first
+}
+
+// Variant is one command of a VariantGroup
+type Variant struct {
+	Command  string
+	Value    string // The word it has in place of VariantPlaceholder
+	Count    int
+	LastUsed time.Time
+}
+
+// variantWords is a command split for comparing: its tokens, operators
+// included so a | b and a ; b differ
+type variantWords struct {
+	count  *storage.CommandCount
+	tokens []shellparse.Token
+}
+
+// FindVariants groups the commands starting with the words of prefix ("" =
+// every command) that differ from each other in a single word, the largest
+// groups first. A command is in one group at most: the largest it fits in.
+func FindVariants(commands []*storage.CommandCount, prefix string) []*VariantGroup {
+	prefixTokens := shellparse.Tokenize(prefix)
+
+	var words []variantWords
+	for _, command := range commands {
+		tokens := shellparse.Tokenize(command.Entry.Command)
+		if hasTokenPrefix(tokens, prefixTokens) {
+			words = append(words, variantWords{count: command, tokens: tokens})
+		}
+	}
+
+	// Commands with the same words but one land in the same bucket
+	type bucket struct {
+		position int
+		members  []int // Indexes into words
+	}
+	buckets := make(map[string]*bucket)
+	var keys []string
+	for i, w := range words {
+		for position := len(prefixTokens); position < len(w.tokens); position++ {
+			if w.tokens[position].Operator {
+				continue
+			}
+			key := variantKey(w.tokens, position)
+			b, ok := buckets[key]
+			if !ok {
+				b = &bucket{position: position}
+				buckets[key] = b
+				keys = append(keys, key)
+			}
+			b.members = append(b.members, i)
+		}
+	}
+
+	runs := func(b *bucket) int {
+		total := 0
+		for _, i := range b.members {
+			total += words[i].count.Count
+		}
+		return total
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := buckets[keys[i]], buckets[keys[j]]
+		if len(a.members) != len(b.members) {
+			return len(a.members) > len(b.members)
+		}
+		if runs(a) != runs(b) {
+			return runs(a) > runs(b)
+		}
+		return keys[i] < keys[j]
+	})
+
+	grouped := make(map[int]bool)
+	var groups []*VariantGroup
+	for _, key := range keys {
+		b := buckets[key]
+		var members []int
+		for _, i := range b.members {
+			if !grouped[i] {
+				members = append(members, i)
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+
+		group := &VariantGroup{Template: variantTemplate(words[members[0]].tokens, b.position)}
+		for _, i := range members {
+			grouped[i] = true
+			entry := words[i].count.Entry
+			group.Count += words[i].count.Count
+			group.Variants = append(group.Variants, Variant{
+				Command:  entry.Command,
+				Value:    words[i].tokens[b.position].Text,
+				Count:    words[i].count.Count,
+				LastUsed: time.Unix(entry.Timestamp, 0),
+			})
+		}
+		sort.SliceStable(group.Variants, func(i, j int) bool {
+			a, b := group.Variants[i], group.Variants[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.LastUsed.After(b.LastUsed)
+		})
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Variants) != len(groups[j].Variants) {
+			return len(groups[i].Variants) > len(groups[j].Variants)
+		}
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
+// hasTokenPrefix reports whether tokens start with prefix
+func hasTokenPrefix(tokens, prefix []shellparse.Token) bool {
+	if len(tokens) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if tokens[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// variantKey returns tokens with the word at position blanked out
+func variantKey(tokens []shellparse.Token, position int) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		switch {
+		case i == position:
+			b.WriteString("\x00")
+		case token.Operator:
+			b.WriteString("\x01" + token.Text)
+		default:
+			b.WriteString(token.Text)
+		}
+		b.WriteString("\x1f")
+	}
+	return b.String()
+}
+
+// variantTemplate returns tokens as a command line, the word at position
+// written as VariantPlaceholder
+func variantTemplate(tokens []shellparse.Token, position int) string {
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		switch {
+		case i == position:
+			words[i] = VariantPlaceholder
+		case token.Operator:
+			words[i] = token.Text
+		default:
+			words[i] = shellparse.Quote(token.Text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Format formats the group for display: the template, then each variant
+// with its runs and when it last ran
+func (g *VariantGroup) Format() string {
+	result := fmt.Sprintf("%s  (%d variants, %d runs)\n", g.Template, len(g.Variants), g.Count)
+	for _, variant := range g.Variants {
+		result += fmt.Sprintf("  %5d  %s  %s\n", variant.Count, variant.LastUsed.Format("2006-01-02"), shellparse.Quote(variant.Value))
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindVariants(t *testing.T) {
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	counts := func(commands map[string]int) []*storage.CommandCount {
+		var result []*storage.CommandCount
+		i := 0
+		for command, count := range commands {
+			i++
+			result = append(result, &storage.CommandCount{
+				Entry: &storage.HistoryEntry{Command: command, Timestamp: day.AddDate(0, 0, i).Unix()},
+				Count: count,
+			})
+		}
+		return result
+	}
+
+	commands := counts(map[string]int{
+		"kubectl logs api":          20,
+		"kubectl logs worker":       5,
+		"kubectl logs 'web server'": 2,
+		"kubectl logs api -f":       7,
+		"kubectl logs worker -f":    3,
+		"kubectl get pods":          9,
+		"git push":                  4,
+	})
+
+	groups := FindVariants(commands, "kubectl logs")
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "kubectl logs <arg>", groups[0].Template)
+	assert.Equal(t, 27, groups[0].Count)
+	require.Len(t, groups[0].Variants, 3)
+	assert.Equal(t, "api", groups[0].Variants[0].Value)
+	assert.Equal(t, "web server", groups[0].Variants[2].Value)
+
+	assert.Equal(t, "kubectl logs <arg> -f", groups[1].Template)
+	assert.Equal(t, 10, groups[1].Count)
+
+	output := groups[0].Format()
+	assert.Contains(t, output, "kubectl logs <arg>  (3 variants, 27 runs)\n")
+	assert.Contains(t, output, "     20  ")
+	assert.Contains(t, output, "'web server'\n")
+
+	// kubectl get pods differs from each in two words
+	groups = FindVariants(commands, "")
+	assert.Len(t, groups, 2)
+	groups = FindVariants(append(commands, counts(map[string]int{"kubectl get nodes": 1})...), "kubectl get")
+	require.Len(t, groups, 1)
+	assert.Equal(t, "kubectl get <arg>", groups[0].Template)
+
+	assert.Empty(t, FindVariants(commands, "git"), "a single command has no variants")
+	assert.Empty(t, FindVariants(counts(map[string]int{"a | b": 1, "a ; b": 1}), ""), "operators are not words")
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+)
+
+// EntryUpdate lists the fields Update changes, nil fields are left as they are
+type EntryUpdate struct {
+	ExitCode   *int
+	DurationMs *int64
+	Note       *string
+}
+
+// fieldChange is a single column changed by Update
+type fieldChange struct {
+	column   string
+	value    interface{}
+	oldValue string
+	newValue string
+}
+
+// changes returns the fields of update that differ from entry
+func (u EntryUpdate) changes(entry *HistoryEntry) []fieldChange {
+	var changes []fieldChange
+
+	if u.ExitCode != nil && *u.ExitCode != entry.ExitCode {
+		changes = append(changes, fieldChange{
+			column:   "exit_code",
+			value:    *u.ExitCode,
+			oldValue: strconv.Itoa(entry.ExitCode),
+			newValue: strconv.Itoa(*u.ExitCode),
+		})
+	}
+
+	if u.DurationMs != nil && *u.DurationMs != entry.DurationMs {
+		changes = append(changes, fieldChange{
+			column:   "duration_ms",
+			value:    *u.DurationMs,
+			oldValue: strconv.FormatInt(entry.DurationMs, 10),
+			newValue: strconv.FormatInt(*u.DurationMs, 10),
+		})
+	}
+
+	if u.Note != nil && *u.Note != entry.Note {
+		changes = append(changes, fieldChange{
+			column:   "note",
+			value:    *u.Note,
+			oldValue: entry.Note,
+			newValue: *u.Note,
+		})
+	}
+
+	return changes
+}
+
+// Update changes fields of an existing entry and records every changed value
+// in the amendments audit log. Fields set to their current value are skipped.
+func (db *DB) Update(id int64, update EntryUpdate) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	entry, err := scanEntry(tx.QueryRow("SELECT "+selectColumns("")+" FROM history WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("entry not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	for _, change := range update.changes(entry) {
+		// column comes from the fixed list in changes, never from input
+		if _, err := tx.Exec("UPDATE history SET "+change.column+" = ? WHERE id = ?", change.value, id); err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO amendments (entry_id, field, old_value, new_value) VALUES (?, ?, ?, ?)",
+			id, change.column, change.oldValue, change.newValue,
+		); err != nil {
+			return fmt.Errorf("failed to record amendment: %w", err)
+		}
+	}
+	if err := db.resign(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAmendments returns the audit log of an entry, oldest first
+func (db *DB) GetAmendments(entryID int64) ([]*Amendment, error) {
+	rows, err := db.conn.Query(
+		"SELECT id, entry_id, field, old_value, new_value, amended_at FROM amendments WHERE entry_id = ? ORDER BY id",
+		entryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query amendments: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var amendments []*Amendment
+	for rows.Next() {
+		a := &Amendment{}
+		if err := rows.Scan(&a.ID, &a.EntryID, &a.Field, &a.OldValue, &a.NewValue, &a.AmendedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan amendment: %w", err)
+		}
+		amendments = append(amendments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return amendments, nil
+}
+
+// RedactCommands replaces the command of entries, keyed by id, with a
+// masked one and marks them redacted. The amendment recorded for each
+// leaves the old command out, it is what is being removed.
+func (db *DB) RedactCommands(commands map[int64]string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for id, command := range commands {
+		var profile string
+		var hash sql.NullString
+		err := tx.QueryRow("SELECT profile, hash FROM history WHERE id = ?", id).Scan(&profile, &hash)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+
+		// Deduplicated entries get the hash of the masked command, unless
+		// another entry already has it
+		newHash := hash
+		if hash.Valid && hash.String != "" {
+			newHash.String = GenerateProfileHash(command, profile)
+			var taken bool
+			if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM history WHERE hash = ? AND id != ?)", newHash.String, id).Scan(&taken); err != nil {
+				return fmt.Errorf("failed to check hash: %w", err)
+			}
+			newHash.Valid = !taken
+		}
+
+		if _, err := tx.Exec(
+			"UPDATE history SET command = ?, program = ?, hash = ?, redacted = 1 WHERE id = ?",
+			command, shellparse.Program(command), newHash, id,
+		); err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO amendments (entry_id, field, old_value, new_value) VALUES (?, 'command', '', ?)",
+			id, command,
+		); err != nil {
+			return fmt.Errorf("failed to record amendment: %w", err)
+		}
+		if err := db.resign(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
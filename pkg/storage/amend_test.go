@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(createTestEntry(t, "make test &", 1000)))
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	id := entries[0].ID
+
+	exitCode := 2
+	duration := int64(45000)
+	note := "flaky on CI"
+	require.NoError(t, db.Update(id, EntryUpdate{ExitCode: &exitCode, DurationMs: &duration, Note: &note}))
+
+	entry, err := db.GetByID(id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.ExitCode)
+	assert.Equal(t, int64(45000), entry.DurationMs)
+	assert.Equal(t, "flaky on CI", entry.Note)
+
+	amendments, err := db.GetAmendments(id)
+	require.NoError(t, err)
+	require.Len(t, amendments, 3)
+	assert.Equal(t, "exit_code", amendments[0].Field)
+	assert.Equal(t, "0", amendments[0].OldValue)
+	assert.Equal(t, "2", amendments[0].NewValue)
+	assert.Equal(t, "duration_ms", amendments[1].Field)
+	assert.Equal(t, "100", amendments[1].OldValue)
+	assert.Equal(t, "note", amendments[2].Field)
+	assert.Equal(t, "", amendments[2].OldValue)
+	assert.Greater(t, amendments[0].AmendedAt, int64(0))
+}
+
+func TestUpdate_OnlyChangedFields(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(createTestEntry(t, "ls", 1000)))
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	id := entries[0].ID
+
+	// Same exit code as stored, only the note changes
+	exitCode := 0
+	note := "checked"
+	require.NoError(t, db.Update(id, EntryUpdate{ExitCode: &exitCode, Note: &note}))
+
+	amendments, err := db.GetAmendments(id)
+	require.NoError(t, err)
+	require.Len(t, amendments, 1)
+	assert.Equal(t, "note", amendments[0].Field)
+
+	// No fields is a no-op
+	require.NoError(t, db.Update(id, EntryUpdate{}))
+	amendments, err = db.GetAmendments(id)
+	require.NoError(t, err)
+	assert.Len(t, amendments, 1)
+}
+
+func TestUpdate_NotFound(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	note := "x"
+	assert.EqualError(t, db.Update(9999, EntryUpdate{Note: &note}), "entry not found")
+}
+
+func TestAmendments_DeletedWithEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(createTestEntry(t, "ls", 1000)))
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	id := entries[0].ID
+
+	note := "temporary"
+	require.NoError(t, db.Update(id, EntryUpdate{Note: &note}))
+	require.NoError(t, db.Delete(id))
+
+	amendments, err := db.GetAmendments(id)
+	require.NoError(t, err)
+	assert.Empty(t, amendments)
+}
+
+func TestRedactCommands(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepFirst := DedupConfig{Enabled: true, Strategy: KeepFirst}
+	for i, command := range []string{"export TOKEN=Zk8qP2xR7vL4mN9tB3wY6cJ1", "export TOKEN=Qm3vT8kL1xN6pR4wZ9bY2cH7", "export TOKEN=****"} {
+		entry := createTestEntry(t, command, 1000+int64(i))
+		entry.Hash = ""
+		require.NoError(t, db.InsertWithDedup(entry, keepFirst))
+	}
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	require.NoError(t, db.RedactCommands(map[int64]string{
+		entries[2].ID: "export TOKEN=****",
+		entries[1].ID: "export TOKEN=****",
+	}))
+
+	for _, e := range entries[1:] {
+		entry, err := db.GetByID(e.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "export TOKEN=****", entry.Command)
+		assert.True(t, entry.Redacted)
+
+		amendments, err := db.GetAmendments(e.ID)
+		require.NoError(t, err)
+		require.Len(t, amendments, 1)
+		assert.Equal(t, "command", amendments[0].Field)
+		assert.Empty(t, amendments[0].OldValue)
+	}
+
+	// The masked command was saved already, so only one entry keeps the hash
+	var hashed int
+	require.NoError(t, db.conn.QueryRow("SELECT COUNT(*) FROM history WHERE hash = ?", GenerateProfileHash("export TOKEN=****", DefaultProfile)).Scan(&hashed))
+	assert.Equal(t, 1, hashed)
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchivedYear is how many entries of one year Archive moved, and where
+type ArchivedYear struct {
+	Year    int
+	Path    string
+	Entries int64
+}
+
+// archivePattern returns the glob of the archive files of the database at
+// path: history.db archives 2019 into archive/history-2019.db next to it
+func archivePattern(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), "archive", base+"-*.db")
+}
+
+// archivePath returns the archive file of year for the database at path
+func archivePath(path string, year int) string {
+	return strings.Replace(archivePattern(path), "*", strconv.Itoa(year), 1)
+}
+
+// ArchivePaths returns the archive files of the database, oldest year first
+func (db *DB) ArchivePaths() ([]string, error) {
+	if IsMemoryPath(db.path) {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(archivePattern(db.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Archive moves the entries older than before, with their amendments, into
+// one archive file per year (local time) next to the database, and
+// vacuums it. Archives are regular fh databases, read back with
+// OpenArchives. Moving a year is
+// one transaction, and entries keep their ids, so running it again after
+// an interruption picks up where it stopped.
+func (db *DB) Archive(before time.Time) ([]ArchivedYear, error) {
+	if IsMemoryPath(db.path) {
+		return nil, fmt.Errorf("an in-memory database can't be archived")
+	}
+
+	var oldest *int64
+	if err := db.conn.QueryRow("SELECT MIN(timestamp) FROM history").Scan(&oldest); err != nil {
+		return nil, fmt.Errorf("failed to find the oldest entry: %w", err)
+	}
+	if oldest == nil || *oldest >= before.Unix() {
+		return nil, nil
+	}
+
+	var archived []ArchivedYear
+	for year := time.Unix(*oldest, 0).Year(); year <= before.Year(); year++ {
+		from := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		to := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.Local)
+		if to.After(before) {
+			to = before
+		}
+
+		moved, err := db.archiveRange(archivePath(db.path, year), from.Unix(), to.Unix())
+		if err != nil {
+			return archived, fmt.Errorf("failed to archive %d: %w", year, err)
+		}
+		if moved > 0 {
+			archived = append(archived, ArchivedYear{Year: year, Path: archivePath(db.path, year), Entries: moved})
+		}
+	}
+
+	// Give the space back so the database file shrinks too
+	if len(archived) > 0 {
+		if _, err := db.conn.Exec("VACUUM"); err != nil {
+			return archived, fmt.Errorf("failed to vacuum: %w", err)
+		}
+	}
+
+	return archived, nil
+}
+
+// archiveRange moves the entries from from up to to into the archive file
+// at path and returns how many it moved
+func (db *DB) archiveRange(path string, from, to int64) (int64, error) {
+	var count int64
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM history WHERE timestamp >= ? AND timestamp < ?", from, to).Scan(&count)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+
+	// Opening the archive once creates it with the current schema
+	archive, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+
+	// ATTACH only applies to one connection of the pool
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS archive", path); err != nil {
+		return 0, fmt.Errorf("failed to attach %s: %w", path, err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(ctx, "DETACH DATABASE archive")
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// A hash already in the archive, from an entry deduplicated against
+	// before it was archived, is dropped rather than the entry
+	columns := make([]string, len(entryColumns))
+	for i, col := range entryColumns {
+		columns[i] = "h." + col
+		if col == "hash" {
+			columns[i] = "CASE WHEN EXISTS (SELECT 1 FROM archive.history a WHERE a.hash = h.hash) THEN NULL ELSE h.hash END"
+		}
+	}
+	inRange := "SELECT id FROM main.history WHERE timestamp >= ? AND timestamp < ?"
+
+	for _, step := range []string{
+		`INSERT INTO archive.history (` + strings.Join(entryColumns, ", ") + `)
+		SELECT ` + strings.Join(columns, ", ") + ` FROM main.history h
+		WHERE h.timestamp >= ? AND h.timestamp < ?
+		ON CONFLICT(id) DO NOTHING`,
+		`INSERT INTO archive.amendments (id, entry_id, field, old_value, new_value, amended_at)
+		SELECT id, entry_id, field, old_value, new_value, amended_at FROM main.amendments
+		WHERE entry_id IN (` + inRange + `)
+		ON CONFLICT(id) DO NOTHING`,
+		`DELETE FROM main.amendments WHERE entry_id IN (` + inRange + `)`,
+		`DELETE FROM main.history WHERE timestamp >= ? AND timestamp < ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, step, from, to); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Archives is a Store over a database and its archive files. Queries and
+// counts see the entries of all of them; writes only go to the database.
+type Archives struct {
+	*DB
+	archives []*DB
+}
+
+// OpenArchives opens the archive files of db. Closing the result closes
+// the archives, db stays open.
+func (db *DB) OpenArchives() (*Archives, error) {
+	paths, err := db.ArchivePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archives{DB: db}
+	for _, path := range paths {
+		archive, err := Open(path)
+		if err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+		}
+		a.archives = append(a.archives, archive)
+	}
+	return a, nil
+}
+
+// Query runs filters on the database and every archive and merges the
+// results, most recent first
+func (a *Archives) Query(filters QueryFilters) ([]*HistoryEntry, error) {
+	// Each file is asked for enough entries to fill the page after merging
+	each := filters
+	each.Offset = 0
+	if filters.Limit > 0 {
+		each.Limit = filters.Limit + filters.Offset
+	}
+
+	entries, err := a.DB.Query(each)
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range a.archives {
+		more, err := archive.Query(each)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, more...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp > entries[j].Timestamp
+	})
+
+	if filters.Distinct {
+		seen := make(map[string]bool)
+		unique := entries[:0]
+		for _, entry := range entries {
+			if !seen[entry.Command] {
+				seen[entry.Command] = true
+				unique = append(unique, entry)
+			}
+		}
+		entries = unique
+	}
+
+	if filters.Offset > 0 {
+		entries = entries[min(filters.Offset, len(entries)):]
+	}
+	if filters.Limit > 0 && len(entries) > filters.Limit {
+		entries = entries[:filters.Limit]
+	}
+
+	return entries, nil
+}
+
+// GetByID looks the entry up in the database, then in the archives
+func (a *Archives) GetByID(id int64) (*HistoryEntry, error) {
+	entry, err := a.DB.GetByID(id)
+	for _, archive := range a.archives {
+		if err == nil {
+			break
+		}
+		entry, err = archive.GetByID(id)
+	}
+	return entry, err
+}
+
+// Count returns the number of entries in the database and the archives
+func (a *Archives) Count() (int64, error) {
+	total, err := a.DB.Count()
+	if err != nil {
+		return 0, err
+	}
+	for _, archive := range a.archives {
+		count, err := archive.Count()
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Close closes the archives
+func (a *Archives) Close() error {
+	var firstErr error
+	for _, archive := range a.archives {
+		if err := archive.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
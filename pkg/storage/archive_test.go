@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	at := func(year int, month time.Month) int64 {
+		return time.Date(year, month, 15, 12, 0, 0, 0, time.Local).Unix()
+	}
+	old := createTestEntry(t, "make old", at(2019, time.March))
+	for _, entry := range []*HistoryEntry{
+		old,
+		createTestEntry(t, "make", at(2019, time.June)),
+		createTestEntry(t, "make", at(2021, time.June)),
+		createTestEntry(t, "make", at(2024, time.June)),
+	} {
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+	note := "the first build"
+	require.NoError(t, db.Update(old.ID, EntryUpdate{Note: &note}))
+
+	archived, err := db.Archive(time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.Equal(t, []ArchivedYear{
+		{Year: 2019, Path: filepath.Join(dir, "archive", "history-2019.db"), Entries: 2},
+		{Year: 2021, Path: filepath.Join(dir, "archive", "history-2021.db"), Entries: 1},
+	}, archived)
+
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	paths, err := db.ArchivePaths()
+	require.NoError(t, err)
+	assert.Equal(t, []string{archived[0].Path, archived[1].Path}, paths)
+
+	// Nothing left to move the second time
+	archived, err = db.Archive(time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.Empty(t, archived)
+
+	archives, err := db.OpenArchives()
+	require.NoError(t, err)
+	defer archives.Close()
+
+	count, err = archives.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	// Entries keep their ids and amendments
+	entry, err := archives.GetByID(old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "the first build", entry.Note)
+	amendments, err := archives.archives[0].GetAmendments(old.ID)
+	require.NoError(t, err)
+	assert.Len(t, amendments, 1)
+}
+
+func TestArchives_Query(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for i, command := range []string{"ls", "make", "ls", "git pull", "make", "ls"} {
+		ts := time.Date(2018+i, time.June, 1, 0, 0, 0, 0, time.Local).Unix()
+		require.NoError(t, db.InsertWithDedup(createTestEntry(t, command, ts), keepAll))
+	}
+	want, err := db.Query(QueryFilters{Distinct: true})
+	require.NoError(t, err)
+
+	_, err = db.Archive(time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+
+	archives, err := db.OpenArchives()
+	require.NoError(t, err)
+	defer archives.Close()
+
+	got, err := archives.Query(QueryFilters{Distinct: true})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	page, err := archives.Query(QueryFilters{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "make", page[0].Command)
+	assert.Equal(t, "git pull", page[1].Command)
+
+	// The hot database alone only has what was not archived
+	hot, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	assert.Len(t, hot, 2)
+}
+
+func TestArchive_InMemory(t *testing.T) {
+	db, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Archive(time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+// seedBenchDB fills db with n entries spread over uniqueCommands distinct commands
+func seedBenchDB(b *testing.B, db *DB, n, uniqueCommands int) {
+	b.Helper()
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+
+	for i := 0; i < n; i++ {
+		entry := &HistoryEntry{
+			Timestamp: int64(1_700_000_000 + i),
+			Command:   fmt.Sprintf("git commit -m 'change %d'", i%uniqueCommands),
+			Cwd:       fmt.Sprintf("/home/user/project%d", i%20),
+			Hostname:  "localhost",
+			User:      "bench",
+			Shell:     "bash",
+		}
+		if err := db.InsertWithDedup(entry, keepAll); err != nil {
+			b.Fatalf("seed failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	db := setupBenchDB(b)
+	defer db.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry := &HistoryEntry{
+			Timestamp: int64(i),
+			Command:   fmt.Sprintf("echo %d", i),
+			Cwd:       "/home/user",
+			Hash:      fmt.Sprintf("hash-%d", i),
+		}
+		if err := db.Insert(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInsertWithDedup(b *testing.B) {
+	strategies := []DedupStrategy{KeepFirst, KeepLast, KeepAll}
+
+	for _, strategy := range strategies {
+		b.Run(string(strategy), func(b *testing.B) {
+			db := setupBenchDB(b)
+			defer db.Close()
+			config := DedupConfig{Enabled: true, Strategy: strategy}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Half of the saves are repeats, like real shell usage
+				entry := &HistoryEntry{
+					Timestamp: int64(i),
+					Command:   fmt.Sprintf("make test-%d", i%(b.N/2+1)),
+					Cwd:       "/home/user",
+				}
+				if err := db.InsertWithDedup(entry, config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkImport compares importing into a populated database entry by
+// entry and through a BatchInserter
+func BenchmarkImport(b *testing.B) {
+	config := DedupConfig{Enabled: true, Strategy: KeepFirst}
+	entry := func(i int) *HistoryEntry {
+		return &HistoryEntry{Timestamp: int64(i), Command: fmt.Sprintf("git commit -m 'change %d'", i%2_000)}
+	}
+
+	b.Run("insert_with_dedup", func(b *testing.B) {
+		db := setupBenchDB(b)
+		defer db.Close()
+		seedBenchDB(b, db, 10_000, 1_000)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := db.InsertWithDedup(entry(i), config); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("batch_inserter", func(b *testing.B) {
+		db := setupBenchDB(b)
+		defer db.Close()
+		seedBenchDB(b, db, 10_000, 1_000)
+
+		b.ResetTimer()
+		inserter, err := db.NewBatchInserter(config)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			if err := inserter.Insert(entry(i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkQuery(b *testing.B) {
+	db := setupBenchDB(b)
+	defer db.Close()
+	seedBenchDB(b, db, 10_000, 1_000)
+
+	cases := []struct {
+		name    string
+		filters QueryFilters
+	}{
+		{"all", QueryFilters{}},
+		{"distinct", QueryFilters{Distinct: true}},
+		{"search", QueryFilters{Search: "change 42"}},
+		{"search_distinct", QueryFilters{Search: "change 42", Distinct: true}},
+		{"limit", QueryFilters{Limit: 100}},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := db.Query(tc.filters); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// setupBenchDB creates an on-disk database so benchmarks include real I/O
+func setupBenchDB(b *testing.B) *DB {
+	b.Helper()
+	db, err := Open(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
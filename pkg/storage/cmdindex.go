@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommandCount is a unique command with the number of times it ran
+type CommandCount struct {
+	Entry *HistoryEntry // Most recent run of the command
+	Count int
+}
+
+// indexedDistinct reports whether a Distinct query can be answered from
+// command_index, which only knows the profile of each command
+func (f QueryFilters) indexedDistinct() bool {
+	return f.Distinct && f.Search == "" && len(f.Terms) == 0 && f.Command == "" &&
+		f.Cwd == "" && f.CwdUnder == "" && f.After == 0 && f.Before == 0 && f.AfterID == 0 && f.UpToID == 0 &&
+		f.ExitCode == nil && f.NotExitCode == nil && f.ExecTarget == "" && f.Program == ""
+}
+
+// commandIndexQuery selects the latest run and total count of each command
+// in profile ("" = all profiles) from command_index
+func commandIndexQuery(profile string) (string, []interface{}) {
+	var conditions string
+	args := []interface{}{}
+	if profile != "" {
+		conditions = " WHERE profile = ?"
+		args = append(args, profile)
+	}
+
+	// The bare last_id comes from the row with the highest last_ts
+	return `
+		SELECT last_id, MAX(last_ts) AS last_ts, SUM(count) AS total
+		FROM command_index` + conditions + `
+		GROUP BY command`, args
+}
+
+// TopCommands returns the commands of profile ("" = all profiles) that ran
+// most, ties going to the most recently used. It reads the counts kept in
+// command_index, one row per command. A limit of 0 returns every command.
+func (db *DB) TopCommands(profile string, limit int) ([]*CommandCount, error) {
+	latest, args := commandIndexQuery(profile)
+	query := `SELECT latest.total, ` + selectColumns("h") + `
+		FROM (` + latest + `) latest
+		JOIN history h ON h.id = latest.last_id
+		ORDER BY latest.total DESC, h.timestamp DESC`
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	top, err := db.commandCounts(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top commands: %w", err)
+	}
+	return top, nil
+}
+
+// Forgotten returns the commands of profile ("" = all profiles) that ran at
+// least minRuns times but not in the idle before now, most worth
+// remembering first: ranked by how often they ran times how long ago they
+// last did. Commands whose last run failed are left out. A limit of 0
+// returns every command.
+func (db *DB) Forgotten(profile string, now time.Time, idle time.Duration, minRuns, limit int) ([]*CommandCount, error) {
+	latest, args := commandIndexQuery(profile)
+	query := `SELECT latest.total, ` + selectColumns("h") + `
+		FROM (` + latest + `) latest
+		JOIN history h ON h.id = latest.last_id
+		WHERE latest.last_ts < ? AND latest.total >= ? AND h.exit_code = 0
+		ORDER BY latest.total * (? - latest.last_ts) DESC, h.timestamp DESC`
+	args = append(args, now.Add(-idle).Unix(), minRuns, now.Unix())
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	forgotten, err := db.commandCounts(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query forgotten commands: %w", err)
+	}
+	return forgotten, nil
+}
+
+// commandCounts runs a query selecting a count then the columns of an entry
+func (db *DB) commandCounts(query string, args ...interface{}) ([]*CommandCount, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var top []*CommandCount
+	for rows.Next() {
+		var count int64
+		entry, err := scanEntry(anchoredRow{rows, &count})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		top = append(top, &CommandCount{Entry: entry, Count: int(count)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return top, nil
+}
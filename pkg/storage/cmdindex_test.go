@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topCounts returns command -> count as TopCommands sees it
+func topCounts(t *testing.T, db *DB, profile string) map[string]int {
+	t.Helper()
+	top, err := db.TopCommands(profile, 0)
+	require.NoError(t, err)
+	counts := make(map[string]int)
+	for _, c := range top {
+		counts[c.Entry.Command] = c.Count
+	}
+	return counts
+}
+
+func TestCommandIndex_FollowsHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	var entries []*HistoryEntry
+	for i, command := range []string{"ls", "git status", "ls", "make", "ls"} {
+		entry := createTestEntry(t, command, int64(1000+i))
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+		entries = append(entries, entry)
+	}
+	assert.Equal(t, map[string]int{"ls": 3, "git status": 1, "make": 1}, topCounts(t, db, ""))
+
+	// Deleting the latest run falls back to the one before
+	require.NoError(t, db.Delete(entries[4].ID))
+	require.NoError(t, db.Delete(entries[3].ID))
+	assert.Equal(t, map[string]int{"ls": 2, "git status": 1}, topCounts(t, db, ""))
+
+	top, err := db.TopCommands("", 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, entries[2].ID, top[0].Entry.ID)
+
+	// keep_last moves the run instead of adding one
+	keepLast := DedupConfig{Enabled: true, Strategy: KeepLast}
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "pwd", 2000), keepLast))
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "pwd", 3000), keepLast))
+	assert.Equal(t, map[string]int{"ls": 2, "git status": 1, "pwd": 1}, topCounts(t, db, ""))
+
+	latest, err := db.Query(QueryFilters{Distinct: true, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	assert.Equal(t, "pwd", latest[0].Command)
+	assert.Equal(t, int64(3000), latest[0].Timestamp)
+}
+
+func TestCommandIndex_Profiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for i, profile := range []string{"work", "work", "home"} {
+		entry := createTestEntry(t, "git pull", int64(1000+i))
+		entry.Profile = profile
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+
+	assert.Equal(t, map[string]int{"git pull": 2}, topCounts(t, db, "work"))
+	assert.Equal(t, map[string]int{"git pull": 3}, topCounts(t, db, ""))
+
+	// All profiles: one row per command, its latest run
+	results, err := db.Query(QueryFilters{Distinct: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "home", results[0].Profile)
+}
+
+func TestQuery_DistinctFromIndexMatchesGrouping(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for i := 0; i < 50; i++ {
+		entry := createTestEntry(t, fmt.Sprintf("cmd %d", i%7), int64(1000+i))
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+
+	// A filter the index can't answer takes the grouping query
+	grouped, err := db.Query(QueryFilters{Distinct: true, Search: "cmd"})
+	require.NoError(t, err)
+	indexed, err := db.Query(QueryFilters{Distinct: true})
+	require.NoError(t, err)
+	assert.Equal(t, grouped, indexed)
+
+	limited, err := db.Query(QueryFilters{Distinct: true, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, indexed[1:3], limited)
+}
+
+func TestMigrate_BackfillsCommandIndex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// A version 8 database with history written before the index existed
+	conn, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	for version := SchemaVersion1; version <= SchemaVersion8; version++ {
+		_, err = conn.Exec(GetSchema(version))
+		require.NoError(t, err)
+		_, err = conn.Exec("INSERT INTO schema_version (version, applied_at) VALUES (?, 0)", version)
+		require.NoError(t, err)
+	}
+	for i, command := range []string{"ls", "ls", "make"} {
+		_, err = conn.Exec(`INSERT INTO history (timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, git_branch, session_id)
+			VALUES (?, ?, '/src', 0, 'host', 'me', 'bash', 5, '', 's1')`, 1000+i, command)
+		require.NoError(t, err)
+	}
+	require.NoError(t, conn.Close())
+
+	db, err := Open(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, map[string]int{"ls": 2, "make": 1}, topCounts(t, db, DefaultProfile))
+}
+
+func TestForgotten(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	day := int64(24 * 60 * 60)
+	now := time.Unix(1000*day, 0)
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	runs := []struct {
+		command  string
+		daysAgo  int64
+		exitCode int
+	}{
+		{"openssl s_client -connect host:443", 200, 0},
+		{"openssl s_client -connect host:443", 150, 0},
+		{"openssl s_client -connect host:443", 120, 0},
+		{"tar xzf backup.tgz", 400, 0},
+		{"tar xzf backup.tgz", 300, 0},
+		{"git status", 2, 0}, // Still in use
+		{"git status", 1, 0},
+		{"make flaky", 200, 0},
+		{"make flaky", 100, 1},  // Last run failed
+		{"ssh old-box", 500, 0}, // Ran only once
+	}
+	for i, run := range runs {
+		entry := createTestEntry(t, run.command, now.Unix()-run.daysAgo*day)
+		entry.Hash = fmt.Sprintf("%s-%d", run.command, i)
+		entry.ExitCode = run.exitCode
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+
+	forgotten, err := db.Forgotten("", now, 90*24*time.Hour, 2, 0)
+	require.NoError(t, err)
+	var got []string
+	for _, c := range forgotten {
+		got = append(got, fmt.Sprintf("%s x%d", c.Entry.Command, c.Count))
+	}
+	// 2 runs 300 days ago outrank 3 runs 120 days ago
+	assert.Equal(t, []string{"tar xzf backup.tgz x2", "openssl s_client -connect host:443 x3"}, got)
+
+	forgotten, err = db.Forgotten("", now, 90*24*time.Hour, 2, 1)
+	require.NoError(t, err)
+	require.Len(t, forgotten, 1)
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// HourCount is what was saved in one hour
+type HourCount struct {
+	Hour   int64 // Unix time the hour starts at
+	Total  int64
+	Failed int64 // Entries with a non-zero exit code
+	Approx int64 // Entries imported with an inferred time
+}
+
+// Counts are the aggregates the database keeps up to date on every write,
+// so reading them doesn't touch each entry
+type Counts struct {
+	Hours          []HourCount // Hours with entries, oldest first
+	UniqueCommands int64
+	First          int64 // Timestamp of the oldest entry, 0 without entries
+	Last           int64 // Timestamp of the newest entry, 0 without entries
+}
+
+// Counts returns the aggregates of profile ("" = all profiles)
+func (db *DB) Counts(profile string) (*Counts, error) {
+	var conditions string
+	args := []interface{}{}
+	if profile != "" {
+		conditions = " WHERE profile = ?"
+		args = append(args, profile)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT hour, SUM(total), SUM(failed), SUM(approx)
+		FROM hourly_counts`+conditions+`
+		GROUP BY hour ORDER BY hour`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query counts: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := &Counts{}
+	for rows.Next() {
+		var h HourCount
+		if err := rows.Scan(&h.Hour, &h.Total, &h.Failed, &h.Approx); err != nil {
+			return nil, fmt.Errorf("failed to scan counts: %w", err)
+		}
+		h.Hour *= 3600
+		counts.Hours = append(counts.Hours, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	err = db.conn.QueryRow("SELECT COUNT(DISTINCT command) FROM command_index"+conditions, args...).
+		Scan(&counts.UniqueCommands)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unique commands: %w", err)
+	}
+
+	// Separate subqueries so each can use the timestamp index
+	var first, last sql.NullInt64
+	err = db.conn.QueryRow(`
+		SELECT
+			(SELECT MIN(timestamp) FROM history`+conditions+`),
+			(SELECT MAX(timestamp) FROM history`+conditions+`)`,
+		append(args, args...)...).Scan(&first, &last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query first and last entry: %w", err)
+	}
+	counts.First, counts.Last = first.Int64, last.Int64
+
+	return counts, nil
+}
+
+// Recount rebuilds the command index and hourly counts from the history,
+// in case they drifted, e.g. after the database was edited by hand
+func (db *DB) Recount() error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, query := range []string{
+		"DELETE FROM command_index",
+		commandIndexFill,
+		"DELETE FROM hourly_counts",
+		hourlyCountsFill,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to recount: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
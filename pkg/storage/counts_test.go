@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounts_FollowHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	var entries []*HistoryEntry
+	for _, ts := range []int64{7200, 7300, 10900} {
+		entry := createTestEntry(t, "make", ts)
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+		entries = append(entries, entry)
+	}
+
+	counts, err := db.Counts("")
+	require.NoError(t, err)
+	assert.Equal(t, []HourCount{{Hour: 7200, Total: 2}, {Hour: 10800, Total: 1}}, counts.Hours)
+	assert.Equal(t, int64(1), counts.UniqueCommands)
+	assert.Equal(t, int64(7200), counts.First)
+	assert.Equal(t, int64(10900), counts.Last)
+
+	// Amending the exit code moves the entry to the failures
+	exitCode := 2
+	require.NoError(t, db.Update(entries[0].ID, EntryUpdate{ExitCode: &exitCode}))
+	require.NoError(t, db.Delete(entries[2].ID))
+
+	counts, err = db.Counts("")
+	require.NoError(t, err)
+	assert.Equal(t, []HourCount{{Hour: 7200, Total: 2, Failed: 1}}, counts.Hours)
+	assert.Equal(t, int64(7300), counts.Last)
+}
+
+func TestCounts_Profiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for i, profile := range []string{"work", "home", "home"} {
+		entry := createTestEntry(t, "ls", int64(1000+i))
+		entry.Profile = profile
+		require.NoError(t, db.InsertWithDedup(entry, keepAll))
+	}
+
+	counts, err := db.Counts("home")
+	require.NoError(t, err)
+	assert.Equal(t, []HourCount{{Hour: 0, Total: 2}}, counts.Hours)
+	assert.Equal(t, int64(1001), counts.First)
+
+	counts, err = db.Counts("")
+	require.NoError(t, err)
+	assert.Equal(t, []HourCount{{Hour: 0, Total: 3}}, counts.Hours)
+	assert.Equal(t, int64(1), counts.UniqueCommands)
+
+	counts, err = db.Counts("none")
+	require.NoError(t, err)
+	assert.Empty(t, counts.Hours)
+	assert.Zero(t, counts.First)
+}
+
+func TestRecount(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for i, command := range []string{"ls", "ls", "make"} {
+		require.NoError(t, db.InsertWithDedup(createTestEntry(t, command, int64(1000+i)), keepAll))
+	}
+	want, err := db.Counts("")
+	require.NoError(t, err)
+
+	// Counters that drifted, e.g. from editing the database by hand
+	_, err = db.conn.Exec("UPDATE hourly_counts SET total = 99")
+	require.NoError(t, err)
+	_, err = db.conn.Exec("DELETE FROM command_index WHERE command = 'make'")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Recount())
+
+	got, err := db.Counts("")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, map[string]int{"ls": 2, "make": 1}, topCounts(t, db, ""))
+}
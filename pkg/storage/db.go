@@ -6,33 +6,181 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/shellparse"
 )
 
+// MemoryPath is the special database path that keeps all data in memory.
+// Nothing is written to disk and the data is gone once the DB is closed.
+const MemoryPath = ":memory:"
+
+// memoryDBCounter gives every in-memory database its own name so separate
+// Open calls never see each other's data.
+var memoryDBCounter atomic.Int64
+
 // DB wraps the database connection
 type DB struct {
-	conn *sql.DB
-	path string
+	conn          *sql.DB
+	path          string
+	dsn           string // Opens path again, see restoreFrom
+	foreignHosts  []*Host
+	autoSnapshots int
+	signer        *signer // nil when entries are not signed
+	redactor      *redact.Redactor
+	dropSecrets   bool
+	tracked       bool    // Counted in openPaths until closed
+	tx            *sql.Tx // Saves go through it in the DB of a Transaction
+	held          *heldFiles
+}
+
+// Durability controls when SQLite waits for writes to reach the disk
+type Durability string
+
+const (
+	// DurabilityFull syncs on every commit, nothing is lost on power failure
+	DurabilityFull Durability = "full"
+
+	// DurabilityNormal syncs at WAL checkpoints. The database survives any
+	// crash, but the last commits can roll back on power failure.
+	DurabilityNormal Durability = "normal"
+
+	// DurabilityOff never syncs. A process crash is still safe, an OS crash
+	// or power failure can corrupt the database.
+	DurabilityOff Durability = "off"
+)
+
+// Options holds settings for opening a database
+type Options struct {
+	Durability Durability // Empty uses the driver default (normal)
+	HostID     string     // This machine, recorded to notice other writers; empty skips it
+	Hostname   string     // Shown to the other machines
+
+	// AutoSnapshots is how many automatic snapshots to keep, taken before
+	// migrations and other destructive changes; 0 takes none
+	AutoSnapshots int
+
+	// SigningKey, when set, signs every entry written for Verify to check
+	SigningKey KeyFunc
+
+	// RedactPatterns are regexes of secrets masked in commands on insert,
+	// besides those redact.Command masks
+	RedactPatterns []string
+
+	// DropSecrets leaves out entries with a secret instead of masking it
+	DropSecrets bool
 }
 
 // Open opens or creates a SQLite database at the given path
+// Use MemoryPath (":memory:") for an ephemeral database that is never persisted.
 func Open(path string) (*DB, error) {
+	return OpenWithOptions(path, Options{})
+}
+
+// OpenWithOptions opens or creates a SQLite database at the given path
+func OpenWithOptions(path string, opts Options) (*DB, error) {
+	redactor, err := redact.NewRedactor(opts.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsMemoryPath(path) {
+		db, err := openMemory()
+		if err != nil {
+			return nil, err
+		}
+		db.redactor, db.dropSecrets = redactor, opts.DropSecrets
+		return db, nil
+	}
+
+	// The DSN applies the setting to every pooled connection
+	dsn := path
+	switch opts.Durability {
+	case "":
+	case DurabilityFull, DurabilityNormal, DurabilityOff:
+		dsn += "?_sync=" + strings.ToUpper(string(opts.Durability))
+	default:
+		return nil, fmt.Errorf("invalid durability: %s (must be full, normal, or off)", opts.Durability)
+	}
+
 	// Ensure parent directory exists
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	// A crash can leave a -shm behind that gets saves stuck on "database
+	// is locked", repair it before SQLite sees it
+	repaired, orphanedWAL := recoverOrphans(path)
+
 	// Open database connection
-	conn, err := sql.Open("sqlite3", path)
+	conn, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	db, err := newDB(conn, path, opts.AutoSnapshots)
+	if err != nil {
+		return nil, err
+	}
+	db.dsn = dsn
+	trackOpen(path)
+	db.tracked = true
+
+	if orphanedWAL > 0 {
+		if line, ok := db.checkpointOrphanedWAL(orphanedWAL); ok {
+			repaired = append(repaired, line)
+		}
+	}
+	if len(repaired) > 0 {
+		logRecovery(path, repaired, time.Now())
+	}
+
+	if opts.HostID != "" {
+		db.checkHosts(opts.HostID, opts.Hostname, time.Now())
+	}
+	if opts.SigningKey != nil {
+		db.signer = &signer{keyFunc: opts.SigningKey}
+		if err := db.markSigningStart(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	db.redactor, db.dropSecrets = redactor, opts.DropSecrets
+
+	return db, nil
+}
+
+// openMemory opens a private in-memory database.
+// A shared cache is used so every pooled connection sees the same data.
+func openMemory() (*DB, error) {
+	name := fmt.Sprintf("file:fh-memory-%d?mode=memory&cache=shared", memoryDBCounter.Add(1))
+
+	conn, err := sql.Open("sqlite3", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+
+	// The in-memory database is destroyed when its last connection closes,
+	// so never let the pool drop idle connections.
+	conn.SetConnMaxLifetime(0)
+	conn.SetConnMaxIdleTime(0)
+	conn.SetMaxIdleConns(1)
+
+	return newDB(conn, MemoryPath, 0)
+}
+
+// newDB wraps an open connection and initializes the schema
+func newDB(conn *sql.DB, path string, autoSnapshots int) (*DB, error) {
 	db := &DB{
-		conn: conn,
-		path: path,
+		conn:          conn,
+		path:          path,
+		autoSnapshots: autoSnapshots,
+		held:          &heldFiles{},
 	}
 
 	// Initialize database
@@ -74,12 +222,77 @@ func (db *DB) migrate() error {
 
 	// Apply migrations if needed
 	if currentVersion < CurrentSchema {
-		return db.applyMigrations(currentVersion, CurrentSchema)
+		// A new database has nothing to lose
+		if currentVersion > 0 {
+			if _, err := db.AutoSnapshot(fmt.Sprintf("migrate-v%d", currentVersion)); err != nil {
+				return err
+			}
+		}
+		if err := db.applyMigrations(currentVersion, CurrentSchema); err != nil {
+			return err
+		}
+	}
+
+	// Rows written before the program column existed need it parsed in Go
+	if currentVersion > 0 && currentVersion < SchemaVersion6 {
+		if err := db.backfillPrograms(); err != nil {
+			return fmt.Errorf("failed to backfill programs: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// backfillPrograms fills in the program column of entries that don't have one
+func (db *DB) backfillPrograms() error {
+	rows, err := db.conn.Query("SELECT id, command FROM history WHERE program = ''")
+	if err != nil {
+		return err
+	}
+
+	programs := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var command string
+		if err := rows.Scan(&id, &command); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if program := shellparse.Program(command); program != "" {
+			programs[id] = program
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	_ = rows.Close()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.Prepare("UPDATE history SET program = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+
+	for id, program := range programs {
+		if _, err := stmt.Exec(program, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 // getSchemaVersion returns the current schema version
 func (db *DB) getSchemaVersion() (int, error) {
 	// Check if schema_version table exists
@@ -144,14 +357,70 @@ func (db *DB) applyMigrations(from, to int) error {
 	return tx.Commit()
 }
 
+// IsLocked reports whether err comes from the database being busy or locked
+// by another process for longer than the busy timeout. The driver's error
+// text is matched, as its error type only exists in cgo builds.
+func IsLocked(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || // SQLITE_BUSY
+		strings.Contains(msg, "database table is locked") // SQLITE_LOCKED
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
+	if db.tracked {
+		defer trackClose(db.path)
+		db.tracked = false
+	}
 	if db.conn != nil {
-		return db.conn.Close()
+		err := db.conn.Close()
+		db.held.close()
+		return err
 	}
 	return nil
 }
 
+// Transaction runs fn with a DB whose saves, InsertWithDedup, IsDebounced
+// and LogIgnored, go through one transaction, so they wait on one commit
+// and see each other. It is committed when fn returns nil and rolled back
+// otherwise. The DB passed to fn is only valid until fn returns.
+func (db *DB) Transaction(fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	inTx := *db
+	inTx.tx, inTx.tracked = tx, false
+	if err := fn(&inTx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// execer returns what saves go through, the transaction of a Transaction
+// or else the connection
+func (db *DB) execer() rowExecer {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.conn
+}
+
 // QueryContext executes a query with context (for timeout support)
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	return db.conn.QueryContext(ctx, query, args...)
@@ -161,3 +430,13 @@ func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{
 func (db *DB) Path() string {
 	return db.path
 }
+
+// IsMemory reports whether the database lives only in memory
+func (db *DB) IsMemory() bool {
+	return IsMemoryPath(db.path)
+}
+
+// IsMemoryPath reports whether path refers to an in-memory database
+func IsMemoryPath(path string) bool {
+	return path == MemoryPath
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -40,6 +42,163 @@ func TestOpen_CreatesParentDirectory(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestOpenWithOptions_Durability(t *testing.T) {
+	tests := []struct {
+		durability Durability
+		want       int // PRAGMA synchronous: 0 = off, 1 = normal, 2 = full
+	}{
+		{DurabilityFull, 2},
+		{DurabilityNormal, 1},
+		{DurabilityOff, 0},
+		{"", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.durability), func(t *testing.T) {
+			db, err := OpenWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{Durability: tt.durability})
+			require.NoError(t, err)
+			defer db.Close()
+
+			// Every pooled connection gets the setting, not just the first
+			ctx := context.Background()
+			first, err := db.conn.Conn(ctx)
+			require.NoError(t, err)
+			defer first.Close()
+			second, err := db.conn.Conn(ctx)
+			require.NoError(t, err)
+			defer second.Close()
+
+			for _, conn := range []*sql.Conn{first, second} {
+				var mode int
+				require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA synchronous").Scan(&mode))
+				assert.Equal(t, tt.want, mode)
+			}
+		})
+	}
+}
+
+func TestOpenWithOptions_InvalidDurability(t *testing.T) {
+	_, err := OpenWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{Durability: "extra"})
+	assert.EqualError(t, err, "invalid durability: extra (must be full, normal, or off)")
+}
+
+func TestIsLocked(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Hold a write lock from a second connection with no busy timeout
+	other, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=0")
+	require.NoError(t, err)
+	defer other.Close()
+	locker, err := db.conn.Begin()
+	require.NoError(t, err)
+	defer func() {
+		_ = locker.Rollback()
+	}()
+	_, err = locker.Exec("DELETE FROM history")
+	require.NoError(t, err)
+
+	_, err = other.Exec("INSERT INTO history (timestamp, command) VALUES (1, 'ls')")
+	require.Error(t, err)
+	assert.True(t, IsLocked(fmt.Errorf("failed to insert entry: %w", err)))
+
+	assert.False(t, IsLocked(fmt.Errorf("entry not found")))
+	assert.False(t, IsLocked(nil))
+}
+
+func TestTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	dedup := DedupConfig{Enabled: true, Strategy: KeepFirst}
+
+	err := db.Transaction(func(tx *DB) error {
+		require.NoError(t, tx.InsertWithDedup(createTestEntry(t, "make test", 1000), dedup))
+
+		// Saves in the transaction see each other
+		debounced, err := tx.IsDebounced(createTestEntry(t, "make test", 1001), 2*time.Second)
+		require.NoError(t, err)
+		assert.True(t, debounced)
+
+		// Until the commit, the others don't
+		count, err := db.Count()
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		return nil
+	})
+	require.NoError(t, err)
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// An error rolls it all back
+	err = db.Transaction(func(tx *DB) error {
+		require.NoError(t, tx.InsertWithDedup(createTestEntry(t, "make lint", 1002), dedup))
+		return fmt.Errorf("save failed")
+	})
+	assert.EqualError(t, err, "save failed")
+	count, err = db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOpen_Memory(t *testing.T) {
+	db, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.True(t, db.IsMemory())
+	assert.Equal(t, MemoryPath, db.Path())
+
+	// Nothing should be written to the working directory
+	_, err = os.Stat(MemoryPath)
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, db.Insert(&HistoryEntry{Timestamp: 1000, Command: "ls", Hash: "ls"}))
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOpen_MemoryIsolated(t *testing.T) {
+	db1, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db1.Close()
+
+	db2, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db2.Close()
+
+	require.NoError(t, db1.Insert(&HistoryEntry{Timestamp: 1000, Command: "ls", Hash: "ls"}))
+
+	count, err := db2.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "separate in-memory databases must not share data")
+}
+
+func TestOpen_MemorySharedAcrossConnections(t *testing.T) {
+	db, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(&HistoryEntry{Timestamp: 1000, Command: "ls", Hash: "ls"}))
+
+	// Force a second pooled connection while the first one is held
+	ctx := context.Background()
+	c1, err := db.conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c1.Close()
+
+	c2, err := db.conn.Conn(ctx)
+	require.NoError(t, err)
+	defer c2.Close()
+
+	var count int
+	require.NoError(t, c2.QueryRowContext(ctx, "SELECT COUNT(*) FROM history").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
 func TestInitialize_EnablesWAL(t *testing.T) {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test.db")
@@ -212,3 +371,71 @@ func TestQueryContext(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestMigrate_V1ToV2AddsProfile(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// Build a version 1 database by hand, as written by older releases
+	conn, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = conn.Exec(GetSchema(SchemaVersion1))
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO schema_version (version, applied_at) VALUES (1, 0)")
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO history (timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, git_branch, session_id)
+		VALUES (1000, 'make build', '/src', 0, 'host', 'me', 'bash', 5, '', 's1')`)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	db, err := Open(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := db.getSchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchema, version)
+
+	// Existing entries land in the default profile
+	entries, err := db.Query(QueryFilters{Profile: DefaultProfile})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "make build", entries[0].Command)
+	assert.Equal(t, DefaultProfile, entries[0].Profile)
+	assert.Empty(t, entries[0].ExecRuntime)
+	assert.Empty(t, entries[0].ExecTarget)
+
+	// The program column is backfilled for existing entries
+	assert.Equal(t, "make", entries[0].Program)
+}
+
+func TestMigrate_GivesEntriesUUIDs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	conn, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = conn.Exec(GetSchema(SchemaVersion1))
+	require.NoError(t, err)
+	_, err = conn.Exec("INSERT INTO schema_version (version, applied_at) VALUES (1, 0)")
+	require.NoError(t, err)
+	for _, command := range []string{"make build", "make test"} {
+		_, err = conn.Exec(`INSERT INTO history (timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, git_branch, session_id)
+			VALUES (1000, ?, '/src', 0, 'host', 'me', 'bash', 5, '', 's1')`, command)
+		require.NoError(t, err)
+	}
+	require.NoError(t, conn.Close())
+
+	db, err := Open(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Insert(createTestEntry(t, "make deploy", 2000)))
+
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	uuids := make(map[string]bool)
+	for _, entry := range entries {
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, entry.UUID)
+		uuids[entry.UUID] = true
+	}
+	assert.Len(t, uuids, 3, "each its own")
+}
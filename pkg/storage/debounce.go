@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// IsDebounced reports whether the same command was already saved in the same
+// session and profile within window of entry, e.g. from a key held down or a
+// hook firing in a loop. Background jobs are never debounced, each needs its
+// own entry to be amended when it finishes.
+func (db *DB) IsDebounced(entry *HistoryEntry, window time.Duration) (bool, error) {
+	if window <= 0 || entry.JobID != 0 {
+		return false, nil
+	}
+
+	profile := entry.Profile
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	var exists bool
+	err := db.execer().QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM history WHERE command = ? AND session_id = ? AND profile = ? AND timestamp >= ?)",
+		entry.Command, entry.SessionID, profile, entry.Timestamp-int64(window/time.Second),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check recent saves: %w", err)
+	}
+
+	return exists, nil
+}
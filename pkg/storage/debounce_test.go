@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDebounced(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	saved := createTestEntry(t, "make test", 1000)
+	require.NoError(t, db.Insert(saved))
+
+	next := func(command string, timestamp int64) *HistoryEntry {
+		entry := createTestEntry(t, command, timestamp)
+		entry.SessionID = saved.SessionID
+		return entry
+	}
+
+	tests := []struct {
+		name   string
+		entry  *HistoryEntry
+		window time.Duration
+		want   bool
+	}{
+		{"same command within window", next("make test", 1001), 2 * time.Second, true},
+		{"same command after window", next("make test", 1003), 2 * time.Second, false},
+		{"different command", next("make lint", 1001), 2 * time.Second, false},
+		{"debounce disabled", next("make test", 1000), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.IsDebounced(tt.entry, tt.window)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	other := next("make test", 1001)
+	other.SessionID = "other-session"
+	got, err := db.IsDebounced(other, 2*time.Second)
+	require.NoError(t, err)
+	assert.False(t, got, "other sessions are not debounced")
+
+	job := next("make test", 1001)
+	job.JobID = 4242
+	got, err = db.IsDebounced(job, 2*time.Second)
+	require.NoError(t, err)
+	assert.False(t, got, "background jobs are not debounced")
+}
@@ -25,8 +25,31 @@ type DedupConfig struct {
 	Strategy DedupStrategy
 }
 
+// Duplicate handling of KeepFirst and KeepLast, done by SQLite in the
+// INSERT itself so concurrent writers cannot both see a hash as new
+const (
+	// Leave the existing entry where it is, only pointing it at a new
+	// background job so the job can amend it later
+	onConflictKeepFirst = `ON CONFLICT(hash) DO UPDATE SET
+		session_id = excluded.session_id,
+		job_id = excluded.job_id
+		WHERE excluded.job_id != 0`
+
+	// Move the existing entry to the new time, and point it at the new
+	// background job so the job can amend it later
+	onConflictKeepLast = `ON CONFLICT(hash) DO UPDATE SET
+		timestamp = excluded.timestamp,
+		session_id = CASE WHEN excluded.job_id != 0 THEN excluded.session_id ELSE session_id END,
+		job_id = CASE WHEN excluded.job_id != 0 THEN excluded.job_id ELSE job_id END`
+)
+
 // InsertWithDedup inserts an entry with deduplication logic
 func (db *DB) InsertWithDedup(entry *HistoryEntry, config DedupConfig) error {
+	// The hash is of the command as stored
+	if !db.redact(entry) {
+		return nil
+	}
+
 	// If deduplication is disabled, insert normally
 	if !config.Enabled {
 		return db.Insert(entry)
@@ -34,29 +57,30 @@ func (db *DB) InsertWithDedup(entry *HistoryEntry, config DedupConfig) error {
 
 	// Generate hash if not already set
 	if entry.Hash == "" {
-		entry.Hash = GenerateHash(entry.Command)
-	}
-
-	// Check if entry with same hash exists
-	exists, existingID, err := db.checkHashExists(entry.Hash)
-	if err != nil {
-		return fmt.Errorf("failed to check for duplicates: %w", err)
+		entry.Hash = GenerateProfileHash(entry.Command, entry.Profile)
 	}
 
-	if !exists {
-		// No duplicate, insert normally
-		return db.Insert(entry)
-	}
+	return db.insertDeduped(entry, config)
+}
 
-	// Handle duplicate based on strategy
+// insertDeduped inserts entry, handling an existing entry with the same hash
+// as the strategy says. With KeepLast the ID of entry is set to the kept one.
+func (db *DB) insertDeduped(entry *HistoryEntry, config DedupConfig) error {
 	switch config.Strategy {
 	case KeepFirst:
-		// Ignore the new entry
-		return nil
+		written, err := db.insert(entry, onConflictKeepFirst)
+		if err != nil || !written || entry.JobID == 0 {
+			return err
+		}
+		// An existing entry may have been given the job
+		return db.resign(db.execer(), entry.ID)
 
 	case KeepLast:
-		// Update the existing entry's timestamp
-		return db.updateEntryTimestamp(existingID, entry.Timestamp)
+		if _, err := db.insert(entry, onConflictKeepLast); err != nil {
+			return err
+		}
+		// An existing entry moved to the new time needs a new signature
+		return db.resign(db.execer(), entry.ID)
 
 	case KeepAll:
 		// Allow duplicate by removing hash constraint temporarily
@@ -68,45 +92,139 @@ func (db *DB) InsertWithDedup(entry *HistoryEntry, config DedupConfig) error {
 	}
 }
 
-// checkHashExists checks if an entry with the given hash exists
-func (db *DB) checkHashExists(hash string) (bool, int64, error) {
-	var id int64
-	err := db.conn.QueryRow("SELECT id FROM history WHERE hash = ?", hash).Scan(&id)
+// BatchInserter inserts many entries with the same deduplication as
+// InsertWithDedup, for imports. It loads the hashes already in the database
+// once and skips KeepFirst duplicates it knows of without writing, keeping
+// track of what it inserts. Anything else goes through the same INSERT as
+// InsertWithDedup, so entries saved by other processes meanwhile are still
+// deduplicated.
+type BatchInserter struct {
+	db     *DB
+	config DedupConfig
+	ids    map[string]int64 // Hash to entry id
+}
 
-	if err == sql.ErrNoRows {
-		return false, 0, nil
+// NewBatchInserter returns a BatchInserter for config
+func (db *DB) NewBatchInserter(config DedupConfig) (*BatchInserter, error) {
+	b := &BatchInserter{db: db, config: config, ids: make(map[string]int64)}
+	if !config.Enabled {
+		return b, nil
 	}
+
+	rows, err := db.conn.Query("SELECT hash, id FROM history WHERE hash IS NOT NULL AND hash != ''")
 	if err != nil {
-		return false, 0, err
+		return nil, fmt.Errorf("failed to load hashes: %w", err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	return true, id, nil
+	for rows.Next() {
+		var hash string
+		var id int64
+		if err := rows.Scan(&hash, &id); err != nil {
+			return nil, fmt.Errorf("failed to load hashes: %w", err)
+		}
+		b.ids[hash] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load hashes: %w", err)
+	}
+
+	return b, nil
 }
 
-// updateEntryTimestamp updates the timestamp of an existing entry
-func (db *DB) updateEntryTimestamp(id int64, timestamp int64) error {
-	_, err := db.conn.Exec(
-		"UPDATE history SET timestamp = ? WHERE id = ?",
-		timestamp, id,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update timestamp: %w", err)
+// Insert inserts entry like InsertWithDedup
+func (b *BatchInserter) Insert(entry *HistoryEntry) error {
+	if !b.db.redact(entry) {
+		return nil
+	}
+
+	if !b.config.Enabled {
+		return b.db.Insert(entry)
+	}
+
+	if entry.Hash == "" {
+		entry.Hash = GenerateProfileHash(entry.Command, entry.Profile)
+	}
+
+	// Known duplicates are left alone without a round trip
+	if _, exists := b.ids[entry.Hash]; exists && b.config.Strategy == KeepFirst {
+		return nil
+	}
+	if err := b.db.insertDeduped(entry, b.config); err != nil {
+		return err
+	}
+	if entry.ID != 0 {
+		b.ids[entry.Hash] = entry.ID
 	}
 	return nil
 }
 
-// insertWithoutHashCheck inserts an entry, allowing duplicate hashes
+// MergeNote looks for the entry already in the database that entry is an
+// export of and reports whether it is there. It is found by its UUID, or
+// for exports from before UUIDs and with a note, by the command and time
+// when a single entry ran that command at that time. Its note is set to
+// the one of entry when it has none, a note already there is kept. Imports
+// use it so entries come back with their notes instead of as copies.
+func (b *BatchInserter) MergeNote(entry *HistoryEntry) (found, merged bool, err error) {
+	var id int64
+	var note string
+	switch {
+	case entry.UUID != "":
+		err = b.db.conn.QueryRow("SELECT id, note FROM history WHERE uuid = ?", entry.UUID).Scan(&id, &note)
+	case entry.Note != "":
+		var matches int
+		err = b.db.conn.QueryRow(
+			"SELECT COUNT(*), COALESCE(MIN(id), 0), COALESCE(MIN(note), '') FROM history WHERE command = ? AND timestamp = ?",
+			entry.Command, entry.Timestamp,
+		).Scan(&matches, &id, &note)
+		if err == nil && matches == 0 {
+			err = sql.ErrNoRows
+		}
+		if err == nil && matches > 1 {
+			// There, but which one the note was on is anyone's guess
+			return true, false, nil
+		}
+	default:
+		return false, false, nil
+	}
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to look up entry: %w", err)
+	}
+
+	if note != "" || entry.Note == "" {
+		return true, false, nil
+	}
+	if err := b.db.Update(id, EntryUpdate{Note: &entry.Note}); err != nil {
+		return true, false, err
+	}
+	return true, true, nil
+}
+
+// insertWithoutHashCheck inserts an entry, allowing duplicate hashes, and
+// sets its ID
 // This is used for KeepAll strategy
 func (db *DB) insertWithoutHashCheck(entry *HistoryEntry) error {
+	entry.setDefaults()
+	if err := db.sign(entry); err != nil {
+		return err
+	}
+
 	// Insert without hash to bypass UNIQUE constraint
 	query := `
 		INSERT INTO history (
 			timestamp, command, cwd, exit_code, hostname,
-			user, shell, duration_ms, git_branch, session_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			user, shell, duration_ms, git_branch, session_id, profile,
+			exec_runtime, exec_target, job_id, note, program, approx_time, redacted,
+			signature, uuid
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := db.conn.Exec(
+	result, err := db.execer().Exec(
 		query,
 		entry.Timestamp,
 		entry.Command,
@@ -118,20 +236,33 @@ func (db *DB) insertWithoutHashCheck(entry *HistoryEntry) error {
 		entry.DurationMs,
 		entry.GitBranch,
 		entry.SessionID,
+		entry.Profile,
+		entry.ExecRuntime,
+		entry.ExecTarget,
+		entry.JobID,
+		entry.Note,
+		entry.Program,
+		entry.ApproxTime,
+		entry.Redacted,
+		entry.Signature,
+		entry.UUID,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert entry: %w", err)
 	}
 
+	if id, err := result.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+
 	return nil
 }
 
 // GetDuplicates returns all entries with duplicate commands
 func (db *DB) GetDuplicates() ([]*HistoryEntry, error) {
 	query := `
-		SELECT h.id, h.timestamp, h.command, h.cwd, h.exit_code, h.hostname,
-		       h.user, h.shell, h.duration_ms, h.git_branch, h.hash, h.session_id, h.created_at
+		SELECT ` + selectColumns("h") + `
 		FROM history h
 		INNER JOIN (
 			SELECT hash
@@ -153,33 +284,10 @@ func (db *DB) GetDuplicates() ([]*HistoryEntry, error) {
 
 	var entries []*HistoryEntry
 	for rows.Next() {
-		entry := &HistoryEntry{}
-		var createdAt int64
-		var hash sql.NullString
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Timestamp,
-			&entry.Command,
-			&entry.Cwd,
-			&entry.ExitCode,
-			&entry.Hostname,
-			&entry.User,
-			&entry.Shell,
-			&entry.DurationMs,
-			&entry.GitBranch,
-			&hash,
-			&entry.SessionID,
-			&createdAt,
-		)
+		entry, err := scanEntry(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entry: %w", err)
 		}
-
-		if hash.Valid {
-			entry.Hash = hash.String
-		}
-
 		entries = append(entries, entry)
 	}
 
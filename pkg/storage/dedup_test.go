@@ -2,8 +2,9 @@ package storage
 
 import (
 	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -134,6 +135,75 @@ func TestInsertWithDedup_KeepLast(t *testing.T) {
 	results, err := db.Query(QueryFilters{})
 	require.NoError(t, err)
 	assert.Equal(t, int64(2000), results[0].Timestamp)
+	assert.Equal(t, entry1.ID, entry2.ID)
+}
+
+func TestInsertWithDedup_ConcurrentWriters(t *testing.T) {
+	for _, strategy := range []DedupStrategy{KeepFirst, KeepLast} {
+		t.Run(string(strategy), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.db")
+			config := DedupConfig{Enabled: true, Strategy: strategy}
+
+			// Each writer has its own connection, like separate shells
+			const writers = 8
+			dbs := make([]*DB, writers)
+			for i := range dbs {
+				db, err := Open(path)
+				require.NoError(t, err)
+				defer db.Close()
+				dbs[i] = db
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, writers)
+			for i, db := range dbs {
+				wg.Add(1)
+				go func(i int, db *DB) {
+					defer wg.Done()
+					errs[i] = db.InsertWithDedup(createTestEntry(t, "git status", int64(1000+i)), config)
+				}(i, db)
+			}
+			wg.Wait()
+
+			for _, err := range errs {
+				assert.NoError(t, err)
+			}
+			count, err := dbs[0].Count()
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+		})
+	}
+}
+
+func TestInsertWithDedup_BackgroundJob(t *testing.T) {
+	for strategy, timestamp := range map[DedupStrategy]int64{KeepFirst: 1000, KeepLast: 2000} {
+		t.Run(string(strategy), func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			config := DedupConfig{
+				Enabled:  true,
+				Strategy: strategy,
+			}
+
+			require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make build &", 1000), config))
+
+			rerun := createTestEntry(t, "make build &", 2000)
+			rerun.SessionID = "session-456"
+			rerun.JobID = 777
+			require.NoError(t, db.InsertWithDedup(rerun, config))
+
+			// The kept entry now belongs to the latest job
+			entry, err := db.GetByJob("session-456", 777)
+			require.NoError(t, err)
+			assert.Equal(t, timestamp, entry.Timestamp)
+
+			// A rerun in the foreground leaves the job alone
+			require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make build &", 3000), config))
+			_, err = db.GetByJob("session-456", 777)
+			assert.NoError(t, err)
+		})
+	}
 }
 
 func TestInsertWithDedup_KeepAll(t *testing.T) {
@@ -226,27 +296,6 @@ func TestInsertWithDedup_KeepAll_PreservesContext(t *testing.T) {
 	assert.Equal(t, 1, failedCommands)
 }
 
-func TestCheckHashExists(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
-
-	entry := createTestEntry(t, "test", time.Now().Unix())
-	entry.Hash = GenerateHash("test") // Explicitly set hash
-	require.NoError(t, db.Insert(entry))
-
-	hash := GenerateHash("test")
-
-	exists, id, err := db.checkHashExists(hash)
-	require.NoError(t, err)
-	assert.True(t, exists)
-	assert.NotZero(t, id)
-
-	// Non-existent hash
-	exists, _, err = db.checkHashExists("nonexistent")
-	require.NoError(t, err)
-	assert.False(t, exists)
-}
-
 func TestGetDuplicates(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -270,7 +319,17 @@ func TestGetDuplicates(t *testing.T) {
 			git_branch TEXT,
 			hash TEXT,
 			session_id TEXT,
-			created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+			created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+			profile TEXT NOT NULL DEFAULT 'default',
+			exec_runtime TEXT NOT NULL DEFAULT '',
+			exec_target TEXT NOT NULL DEFAULT '',
+			job_id INTEGER NOT NULL DEFAULT 0,
+			note TEXT NOT NULL DEFAULT '',
+			program TEXT NOT NULL DEFAULT '',
+			approx_time INTEGER NOT NULL DEFAULT 0,
+			redacted INTEGER NOT NULL DEFAULT 0,
+			signature TEXT NOT NULL DEFAULT '',
+			uuid TEXT NOT NULL DEFAULT ''
 		)
 	`)
 	require.NoError(t, err)
@@ -338,7 +397,17 @@ func TestDeduplicateExisting(t *testing.T) {
 			git_branch TEXT,
 			hash TEXT,
 			session_id TEXT,
-			created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+			created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+			profile TEXT NOT NULL DEFAULT 'default',
+			exec_runtime TEXT NOT NULL DEFAULT '',
+			exec_target TEXT NOT NULL DEFAULT '',
+			job_id INTEGER NOT NULL DEFAULT 0,
+			note TEXT NOT NULL DEFAULT '',
+			program TEXT NOT NULL DEFAULT '',
+			approx_time INTEGER NOT NULL DEFAULT 0,
+			redacted INTEGER NOT NULL DEFAULT 0,
+			signature TEXT NOT NULL DEFAULT '',
+			uuid TEXT NOT NULL DEFAULT ''
 		)
 	`)
 	require.NoError(t, err)
@@ -407,3 +476,76 @@ func TestInsertWithDedup_AutoGeneratesHash(t *testing.T) {
 	assert.NotEmpty(t, results[0].Hash)
 	assert.Equal(t, GenerateHash("ls -la"), results[0].Hash)
 }
+
+func TestInsertWithDedup_ScopedToProfile(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	config := DedupConfig{Enabled: true, Strategy: KeepFirst}
+
+	for _, profile := range []string{"", "work", "personal", "work"} {
+		entry := createTestEntry(t, "make deploy", 1000)
+		entry.Hash = "" // Let InsertWithDedup derive the profile-scoped hash
+		entry.Profile = profile
+		require.NoError(t, db.InsertWithDedup(entry, config))
+	}
+
+	// One entry per profile, the repeated work entry is deduplicated
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestGenerateProfileHash(t *testing.T) {
+	assert.Equal(t, GenerateHash("ls"), GenerateProfileHash("ls", ""))
+	assert.Equal(t, GenerateHash("ls"), GenerateProfileHash("ls", DefaultProfile))
+	assert.NotEqual(t, GenerateHash("ls"), GenerateProfileHash("ls", "work"))
+	assert.NotEqual(t, GenerateProfileHash("ls", "work"), GenerateProfileHash("ls", "personal"))
+}
+
+func TestBatchInserter(t *testing.T) {
+	for _, strategy := range []DedupStrategy{KeepFirst, KeepLast, KeepAll} {
+		t.Run(string(strategy), func(t *testing.T) {
+			config := DedupConfig{Enabled: true, Strategy: strategy}
+			commands := []string{"make", "ls", "make", "git status", "ls"}
+
+			// Both start from a database that already has "make"
+			want := setupTestDB(t)
+			defer want.Close()
+			got := setupTestDB(t)
+			defer got.Close()
+			for _, db := range []*DB{want, got} {
+				require.NoError(t, db.InsertWithDedup(&HistoryEntry{Command: "make", Timestamp: 1}, config))
+			}
+
+			inserter, err := got.NewBatchInserter(config)
+			require.NoError(t, err)
+			for i, command := range commands {
+				require.NoError(t, want.InsertWithDedup(&HistoryEntry{Command: command, Timestamp: int64(i + 10)}, config))
+				require.NoError(t, inserter.Insert(&HistoryEntry{Command: command, Timestamp: int64(i + 10)}))
+			}
+
+			wantEntries, err := want.Query(QueryFilters{})
+			require.NoError(t, err)
+			gotEntries, err := got.Query(QueryFilters{})
+			require.NoError(t, err)
+			require.Len(t, gotEntries, len(wantEntries))
+			for i := range wantEntries {
+				assert.Equal(t, wantEntries[i].Command, gotEntries[i].Command)
+				assert.Equal(t, wantEntries[i].Timestamp, gotEntries[i].Timestamp)
+				assert.Equal(t, wantEntries[i].Hash, gotEntries[i].Hash)
+			}
+		})
+	}
+}
+
+func TestBatchInserter_Disabled(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	inserter, err := db.NewBatchInserter(DedupConfig{})
+	require.NoError(t, err)
+	entry := createTestEntry(t, "ls", 1)
+	require.NoError(t, inserter.Insert(entry))
+	assert.NotZero(t, entry.ID)
+}
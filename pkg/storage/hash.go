@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
 	"strings"
@@ -19,6 +20,16 @@ func GenerateHash(command string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// GenerateProfileHash creates a deduplication hash scoped to a profile, so the
+// same command can be kept once per profile. The default profile uses the plain
+// command hash, which keeps hashes written before profiles existed valid.
+func GenerateProfileHash(command, profile string) string {
+	if profile == "" || profile == DefaultProfile {
+		return GenerateHash(command)
+	}
+	return GenerateHashWithContext(command, "profile="+profile)
+}
+
 // GenerateHashWithContext creates a hash including context
 // This can be used for context-aware deduplication
 func GenerateHashWithContext(command, cwd string) string {
@@ -28,3 +39,12 @@ func GenerateHashWithContext(command, cwd string) string {
 	hash := sha256.Sum256([]byte(combined))
 	return fmt.Sprintf("%x", hash)
 }
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Host is a machine that opened the database
+type Host struct {
+	ID        string
+	Hostname  string
+	FirstSeen int64
+	LastSeen  int64
+}
+
+// hostSeenInterval is how often a machine records that it still opens the
+// database, so most opens only read
+const hostSeenInterval = time.Hour
+
+// ForeignHostWindow is how recently another machine must have opened the
+// database to count as writing it too. Older ones are taken as a machine
+// the file was moved from.
+const ForeignHostWindow = 7 * 24 * time.Hour
+
+// checkHosts records that the machine id opened the database and keeps the
+// other machines that opened it within ForeignHostWindow. It is only a
+// warning, so failing to read or write the hosts doesn't fail the open.
+func (db *DB) checkHosts(id, hostname string, now time.Time) {
+	hosts, err := db.Hosts()
+	if err != nil {
+		return
+	}
+
+	var self *Host
+	db.foreignHosts = nil
+	for _, h := range hosts {
+		switch {
+		case h.ID == id:
+			self = h
+		case now.Sub(time.Unix(h.LastSeen, 0)) < ForeignHostWindow:
+			db.foreignHosts = append(db.foreignHosts, h)
+		}
+	}
+
+	if self != nil && self.Hostname == hostname && now.Sub(time.Unix(self.LastSeen, 0)) < hostSeenInterval {
+		return
+	}
+	_, _ = db.conn.Exec(`
+		INSERT INTO hosts (host_id, hostname, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT(host_id) DO UPDATE SET hostname = excluded.hostname, last_seen = excluded.last_seen
+	`, id, hostname, now.Unix(), now.Unix())
+}
+
+// ForeignHosts returns the other machines that recently opened the
+// database, as found when it was opened with Options.HostID. More than
+// one machine writing the same file, usually through a file sync service,
+// ends up corrupting it.
+func (db *DB) ForeignHosts() []*Host {
+	return db.foreignHosts
+}
+
+// Hosts returns every machine that opened the database, most recent first
+func (db *DB) Hosts() ([]*Host, error) {
+	rows, err := db.conn.Query("SELECT host_id, hostname, first_seen, last_seen FROM hosts ORDER BY last_seen DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hosts: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var hosts []*Host
+	for rows.Next() {
+		h := &Host{}
+		if err := rows.Scan(&h.ID, &h.Hostname, &h.FirstSeen, &h.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan host: %w", err)
+		}
+		hosts = append(hosts, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return hosts, nil
+}
+
+// ForgetHost removes the machines with the given id or hostname, e.g. one
+// the database was copied from, and returns how many it removed
+func (db *DB) ForgetHost(idOrHostname string) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM hosts WHERE host_id = ? OR hostname = ?", idOrHostname, idOrHostname)
+	if err != nil {
+		return 0, fmt.Errorf("failed to forget host: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForeignHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	open := func(id, hostname string) *DB {
+		t.Helper()
+		db, err := OpenWithOptions(path, Options{HostID: id, Hostname: hostname})
+		require.NoError(t, err)
+		return db
+	}
+
+	laptop := open("laptop-id", "laptop")
+	assert.Empty(t, laptop.ForeignHosts())
+	require.NoError(t, laptop.Close())
+
+	// The desktop opens the synced file and sees the laptop
+	desktop := open("desktop-id", "desktop")
+	require.Len(t, desktop.ForeignHosts(), 1)
+	assert.Equal(t, "laptop", desktop.ForeignHosts()[0].Hostname)
+	require.NoError(t, desktop.Close())
+
+	laptop = open("laptop-id", "laptop")
+	require.Len(t, laptop.ForeignHosts(), 1)
+	assert.Equal(t, "desktop-id", laptop.ForeignHosts()[0].ID)
+
+	forgotten, err := laptop.ForgetHost("desktop")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), forgotten)
+	require.NoError(t, laptop.Close())
+
+	laptop = open("laptop-id", "laptop")
+	defer laptop.Close()
+	assert.Empty(t, laptop.ForeignHosts())
+
+	// Opening without a host id records nothing
+	plain, err := Open(path)
+	require.NoError(t, err)
+	defer plain.Close()
+	hosts, err := plain.Hosts()
+	require.NoError(t, err)
+	assert.Len(t, hosts, 1)
+}
+
+func TestCheckHosts_OldMachines(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Unix(1700000000, 0)
+	db.checkHosts("old-laptop", "old", now.Add(-2*ForeignHostWindow))
+	db.checkHosts("new-laptop", "new", now)
+
+	// A machine the file was moved from long ago is no writer
+	assert.Empty(t, db.ForeignHosts())
+
+	// Last seen is only refreshed once per interval
+	db.checkHosts("new-laptop", "new", now.Add(time.Minute))
+	hosts, err := db.Hosts()
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Equal(t, now.Unix(), hosts[0].LastSeen)
+	assert.Equal(t, now.Unix(), hosts[0].FirstSeen)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// IgnoredRetention is how long skipped commands are kept in the ignored log
+const IgnoredRetention = 30 * 24 * time.Hour
+
+// LogIgnored records a command skipped by an ignore pattern, so that
+// over-aggressive patterns can be spotted with fh --ignored. Entries older
+// than IgnoredRetention are pruned on the way.
+func (db *DB) LogIgnored(cmd *IgnoredCommand) error {
+	if cmd.Profile == "" {
+		cmd.Profile = DefaultProfile
+	}
+
+	if _, err := db.execer().Exec(
+		"INSERT INTO ignored (timestamp, command, cwd, pattern, profile) VALUES (?, ?, ?, ?, ?)",
+		cmd.Timestamp, cmd.Command, cmd.Cwd, cmd.Pattern, cmd.Profile,
+	); err != nil {
+		return fmt.Errorf("failed to log ignored command: %w", err)
+	}
+
+	cutoff := time.Now().Add(-IgnoredRetention).Unix()
+	if _, err := db.execer().Exec("DELETE FROM ignored WHERE timestamp < ?", cutoff); err != nil {
+		return fmt.Errorf("failed to prune ignored commands: %w", err)
+	}
+
+	return nil
+}
+
+// GetIgnored returns the commands skipped since the given Unix time, oldest
+// first. An empty profile returns every profile.
+func (db *DB) GetIgnored(since int64, profile string) ([]*IgnoredCommand, error) {
+	query := "SELECT id, timestamp, command, cwd, pattern, profile FROM ignored WHERE timestamp >= ?"
+	args := []interface{}{since}
+	if profile != "" {
+		query += " AND profile = ?"
+		args = append(args, profile)
+	}
+	query += " ORDER BY timestamp, id"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ignored commands: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var ignored []*IgnoredCommand
+	for rows.Next() {
+		c := &IgnoredCommand{}
+		if err := rows.Scan(&c.ID, &c.Timestamp, &c.Command, &c.Cwd, &c.Pattern, &c.Profile); err != nil {
+			return nil, fmt.Errorf("failed to scan ignored command: %w", err)
+		}
+		ignored = append(ignored, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ignored, nil
+}
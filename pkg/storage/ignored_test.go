@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogIgnored(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now().Unix()
+	require.NoError(t, db.LogIgnored(&IgnoredCommand{Timestamp: now - 7200, Command: "ls", Cwd: "/tmp", Pattern: "^ls$"}))
+	require.NoError(t, db.LogIgnored(&IgnoredCommand{Timestamp: now - 60, Command: "cd src", Pattern: "^cd ", Profile: "work"}))
+
+	ignored, err := db.GetIgnored(now-3600, "")
+	require.NoError(t, err)
+	require.Len(t, ignored, 1)
+	assert.Equal(t, "cd src", ignored[0].Command)
+	assert.Equal(t, "^cd ", ignored[0].Pattern)
+	assert.Equal(t, "work", ignored[0].Profile)
+
+	ignored, err = db.GetIgnored(0, DefaultProfile)
+	require.NoError(t, err)
+	require.Len(t, ignored, 1)
+	assert.Equal(t, "ls", ignored[0].Command)
+	assert.Equal(t, "/tmp", ignored[0].Cwd)
+
+	// Ignored commands never show up in the history
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestLogIgnored_PrunesOldEntries(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	old := time.Now().Add(-IgnoredRetention - time.Hour).Unix()
+	require.NoError(t, db.LogIgnored(&IgnoredCommand{Timestamp: old, Command: "ls", Pattern: "^ls$"}))
+	require.NoError(t, db.LogIgnored(&IgnoredCommand{Timestamp: time.Now().Unix(), Command: "pwd", Pattern: "^pwd$"}))
+
+	ignored, err := db.GetIgnored(0, "")
+	require.NoError(t, err)
+	require.Len(t, ignored, 1)
+	assert.Equal(t, "pwd", ignored[0].Command)
+}
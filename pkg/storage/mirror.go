@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mirrorIndexes are created in a mirror for the usual analysis queries
+var mirrorIndexes = []string{
+	"CREATE INDEX mirror.idx_history_timestamp ON history(timestamp)",
+	"CREATE INDEX mirror.idx_history_program ON history(program)",
+	"CREATE INDEX mirror.idx_history_profile ON history(profile)",
+}
+
+// rowQuerier is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// fingerprint sums up the history table cheaply: it changes with every
+// insert, delete, amendment and deduplicated re-run
+func fingerprint(q rowQuerier) (string, error) {
+	var count, maxID, timestamps, amendments int64
+	err := q.QueryRowContext(context.Background(), `
+		SELECT COUNT(*), COALESCE(MAX(id), 0), COALESCE(SUM(timestamp), 0),
+			(SELECT COALESCE(MAX(id), 0) FROM amendments)
+		FROM history
+	`).Scan(&count, &maxID, &timestamps, &amendments)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the history: %w", err)
+	}
+	return fmt.Sprintf("%d:%d:%d:%d", count, maxID, timestamps, amendments), nil
+}
+
+// mirrorFingerprint returns the fingerprint the mirror at path was made
+// from, empty when there is no mirror there yet
+func mirrorFingerprint(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var fingerprint string
+	if err := conn.QueryRow("SELECT fingerprint FROM mirror_info").Scan(&fingerprint); err != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+// Mirror writes a read-only copy of the history table to the SQLite file at
+// path, for notebooks and BI tools, and reports whether it had to. A
+// mirror that is already up to date is left alone. The copy is built
+// next to path and renamed over it, so readers of the old mirror are never
+// disturbed, and the live database is only read.
+func (db *DB) Mirror(path string) (bool, error) {
+	if filepath.Clean(path) == filepath.Clean(db.path) {
+		return false, fmt.Errorf("the mirror can't be the database itself")
+	}
+
+	current, err := fingerprint(db.conn)
+	if err != nil {
+		return false, err
+	}
+	if current == mirrorFingerprint(path) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
+	// ATTACH only applies to one connection of the pool
+	ctx := context.Background()
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS mirror", tmp); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	attached := true
+	detach := func() error {
+		if !attached {
+			return nil
+		}
+		attached = false
+		_, err := conn.ExecContext(ctx, "DETACH DATABASE mirror")
+		return err
+	}
+	defer func() {
+		_ = detach()
+	}()
+
+	// One transaction reads one consistent state of the history
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Taken again, the history may have changed since the check
+	current, err = fingerprint(tx)
+	if err != nil {
+		return false, err
+	}
+
+	steps := []string{
+		"CREATE TABLE mirror.history AS SELECT " + strings.Join(entryColumns, ", ") + " FROM main.history ORDER BY id",
+	}
+	steps = append(steps, mirrorIndexes...)
+	steps = append(steps, "CREATE TABLE mirror.mirror_info (source TEXT, refreshed_at INTEGER, fingerprint TEXT)")
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step); err != nil {
+			return false, fmt.Errorf("failed to write the mirror: %w", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO mirror.mirror_info VALUES (?, ?, ?)", db.path, time.Now().Unix(), current)
+	if err != nil {
+		return false, fmt.Errorf("failed to write the mirror: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if err := detach(); err != nil {
+		return false, fmt.Errorf("failed to close the mirror: %w", err)
+	}
+
+	if err := os.Chmod(tmp, 0444); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return false, fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return true, nil
+}
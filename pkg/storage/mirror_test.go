@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make", 1700000000), keepAll))
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make test", 1700000100), keepAll))
+
+	path := filepath.Join(dir, "analytics", "fh.db")
+	mirrorCommands := func() []string {
+		t.Helper()
+		conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rows, err := conn.Query("SELECT command FROM history ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+		var commands []string
+		for rows.Next() {
+			var command string
+			require.NoError(t, rows.Scan(&command))
+			commands = append(commands, command)
+		}
+		require.NoError(t, rows.Err())
+		return commands
+	}
+
+	refreshed, err := db.Mirror(path)
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	assert.Equal(t, []string{"make", "make test"}, mirrorCommands())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	// Nothing changed, nothing to write
+	refreshed, err = db.Mirror(path)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+
+	note := "flaky"
+	require.NoError(t, db.Update(1, EntryUpdate{Note: &note}))
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make install", 1700000200), keepAll))
+	refreshed, err = db.Mirror(path)
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	assert.Equal(t, []string{"make", "make test", "make install"}, mirrorCommands())
+
+	_, err = db.Mirror(filepath.Join(dir, "history.db"))
+	assert.ErrorContains(t, err, "the database itself")
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recoveryLogFile is where Open notes what it repaired, next to the
+// database: the saves of the shell hooks have nowhere else to say it
+const recoveryLogFile = "recovery.log"
+
+// openPaths counts the databases this process has open by path. Locks of
+// the same process never conflict, so the orphan check can't see them, and
+// closing a file drops every lock the process holds on it: the check must
+// leave a database this process has open alone.
+var openPaths = struct {
+	sync.Mutex
+	count map[string]int
+}{count: make(map[string]int)}
+
+// trackOpen records that this process opened path
+func trackOpen(path string) {
+	openPaths.Lock()
+	defer openPaths.Unlock()
+	openPaths.count[absPath(path)]++
+}
+
+// trackClose records that this process closed path
+func trackClose(path string) {
+	openPaths.Lock()
+	defer openPaths.Unlock()
+	key := absPath(path)
+	if openPaths.count[key]--; openPaths.count[key] <= 0 {
+		delete(openPaths.count, key)
+	}
+}
+
+// openInProcess reports whether this process has path open
+func openInProcess(path string) bool {
+	openPaths.Lock()
+	defer openPaths.Unlock()
+	return openPaths.count[absPath(path)] > 0
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// checkpointOrphanedWAL makes sure the write-ahead log a crash left behind,
+// size bytes of it, is in the database and the log empty again. Opening
+// the database may have done it already.
+func (db *DB) checkpointOrphanedWAL(size int64) (string, bool) {
+	var busy, logFrames, checkpointed int
+	err := db.conn.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed)
+	if err != nil || busy != 0 {
+		return "", false
+	}
+	return fmt.Sprintf("checkpointed the write-ahead log left behind (%d bytes) into the database", size), true
+}
+
+// logRecovery appends what Open repaired to the recovery log next to the
+// database
+func logRecovery(path string, repaired []string, now time.Time) {
+	logPath := filepath.Join(filepath.Dir(path), recoveryLogFile)
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	for _, line := range repaired {
+		_, _ = fmt.Fprintf(file, "%s %s: %s\n", now.Format(time.RFC3339), filepath.Base(path), line)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package storage
+
+// recoverOrphans does nothing where the -shm lock can't be checked, SQLite
+// handles what a crash leaves behind by itself
+func recoverOrphans(path string) (repaired []string, orphanedWAL int64) {
+	return nil, 0
+}
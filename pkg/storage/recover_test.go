@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crashedCopy copies the database of db with its -wal and -shm as they are
+// while it is open, which is what a crash leaves on disk
+func crashedCopy(t *testing.T, db *DB) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		data, err := os.ReadFile(db.path + suffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "history.db"+suffix), data, 0600))
+	}
+	return filepath.Join(dir, "history.db")
+}
+
+func TestOpen_RecoversOrphanedWAL(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	for _, command := range []string{"git status", "make test"} {
+		require.NoError(t, db.Insert(createTestEntry(t, command, 1700000000)))
+	}
+	path := crashedCopy(t, db)
+
+	recovered, err := Open(path)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	// Nothing committed before the crash is lost
+	count, err := recovered.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// A -shm fh can write is SQLite's to reset
+	_, err = os.Stat(filepath.Join(filepath.Dir(path), recoveryLogFile))
+	assert.True(t, os.IsNotExist(err), "nothing was repaired")
+}
+
+func TestOpen_LeavesDatabasesInUseAlone(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	require.NoError(t, db.Insert(createTestEntry(t, "git status", 1700000000)))
+
+	// This process holds db open, which its own locks can't tell
+	again, err := Open(db.path)
+	require.NoError(t, err)
+	require.NoError(t, again.Close())
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(db.path), recoveryLogFile))
+	assert.True(t, os.IsNotExist(err), "nothing was repaired")
+
+	// Still usable after the second connection closed
+	require.NoError(t, db.Insert(createTestEntry(t, "make test", 1700000060)))
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
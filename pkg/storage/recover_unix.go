@@ -0,0 +1,67 @@
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// shmDMSOffset is the byte of the -shm file every connection to a WAL
+// database holds a shared lock on while it is open (UNIX_SHM_DMS in
+// SQLite's os_unix.c). Nobody holding it means nobody has the file open.
+const shmDMSOffset = 128
+
+// recoverOrphans removes a -shm file a crash left next to the database at
+// path that fh can't write, e.g. one left by fh run with sudo, when no
+// process has the database open. SQLite can't open the database past such
+// a file. A -shm fh can write is left to SQLite, which resets it itself
+// when it is the first to open the database. It returns what it did and
+// the size of the write-ahead log left behind, to checkpoint once open.
+func recoverOrphans(path string) (repaired []string, orphanedWAL int64) {
+	if openInProcess(path) {
+		return nil, 0
+	}
+
+	shm := path + "-shm"
+	file, err := os.OpenFile(shm, os.O_RDWR, 0)
+	if err == nil {
+		_ = file.Close()
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		return nil, 0
+	}
+
+	// Without write access the lock can only be tested, not taken
+	if held, err := dmsHeld(shm); err != nil || held {
+		return nil, 0
+	}
+	if info, err := os.Stat(path + "-wal"); err == nil {
+		orphanedWAL = info.Size()
+	}
+	if err := os.Remove(shm); err != nil {
+		return nil, 0
+	}
+	return []string{fmt.Sprintf("removed %s, which was not writable and not in use", filepath.Base(shm))}, orphanedWAL
+}
+
+// dmsHeld reports whether another process holds the lock of open
+// connections on the -shm file at path
+func dmsHeld(shm string) (bool, error) {
+	file, err := os.Open(shm)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	lock := syscall.Flock_t{Type: syscall.F_WRLCK, Start: shmDMSOffset, Len: 1}
+	if err := syscall.FcntlFlock(file.Fd(), syscall.F_GETLK, &lock); err != nil {
+		return false, err
+	}
+	return lock.Type != syscall.F_UNLCK, nil
+}
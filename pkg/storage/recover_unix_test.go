@@ -0,0 +1,50 @@
+//go:build unix
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_RemovesUnwritableOrphanedSHM(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write any file")
+	}
+
+	db := setupTestDB(t)
+	defer db.Close()
+	require.NoError(t, db.Insert(createTestEntry(t, "git status", 1700000000)))
+	path := crashedCopy(t, db)
+	require.NoError(t, os.Chmod(path+"-shm", 0400))
+
+	recovered, err := Open(path)
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	count, err := recovered.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	log, err := os.ReadFile(filepath.Join(filepath.Dir(path), recoveryLogFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "removed history.db-shm")
+}
+
+func TestDMSHeld(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	require.NoError(t, db.Insert(createTestEntry(t, "git status", 1700000000)))
+
+	// Only the locks of other processes show, so a copy is never held
+	held, err := dmsHeld(crashedCopy(t, db) + "-shm")
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	_, err = dmsHeld(filepath.Join(t.TempDir(), "missing.db-shm"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RelatedCommand is a command that ran close to another one in the same
+// shell session
+type RelatedCommand struct {
+	Entry *HistoryEntry // Most recent run near the other command
+	Count int           // Runs of the other command it ran near
+}
+
+// Related returns the commands run in the same session within window of any
+// run of the command of entry id, most frequent companion first. Ties go to
+// the most recently used. A limit of 0 returns every related command.
+func (db *DB) Related(id int64, window time.Duration, limit int) ([]*RelatedCommand, error) {
+	entry, err := db.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds := int64(window / time.Second)
+	rows, err := db.conn.Query(`
+		SELECT a.id, `+selectColumns("o")+`
+		FROM history a
+		JOIN history o
+			ON o.session_id = a.session_id
+			AND o.timestamp BETWEEN a.timestamp - ? AND a.timestamp + ?
+			AND o.command != a.command
+		WHERE a.command = ? AND a.session_id != ''
+	`, seconds, seconds, entry.Command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related commands: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	// Count each run of the command once per companion, however often the
+	// companion ran around it
+	var related []*RelatedCommand
+	index := make(map[string]int)
+	seen := make(map[string]map[int64]bool)
+	for rows.Next() {
+		var anchorID int64
+		companion, err := scanEntry(anchoredRow{rows, &anchorID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan related command: %w", err)
+		}
+
+		i, ok := index[companion.Command]
+		if !ok {
+			i = len(related)
+			index[companion.Command] = i
+			related = append(related, &RelatedCommand{Entry: companion})
+			seen[companion.Command] = make(map[int64]bool)
+		}
+		if companion.Timestamp > related[i].Entry.Timestamp {
+			related[i].Entry = companion
+		}
+		if !seen[companion.Command][anchorID] {
+			seen[companion.Command][anchorID] = true
+			related[i].Count++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read related commands: %w", err)
+	}
+
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Count != related[j].Count {
+			return related[i].Count > related[j].Count
+		}
+		return related[i].Entry.Timestamp > related[j].Entry.Timestamp
+	})
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+
+	return related, nil
+}
+
+// anchoredRow scans a leading integer, like an anchor id or a count, before
+// the selectColumns of a row
+type anchoredRow struct {
+	rows     *sql.Rows
+	anchorID *int64
+}
+
+func (r anchoredRow) Scan(dest ...interface{}) error {
+	return r.rows.Scan(append([]interface{}{r.anchorID}, dest...)...)
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelated(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	insert := func(command, session string, timestamp int64) {
+		entry := createTestEntry(t, command, timestamp)
+		entry.SessionID = session
+		entry.Hash = fmt.Sprintf("%s-%s-%d", command, session, timestamp)
+		require.NoError(t, db.Insert(entry))
+	}
+
+	// Two deploys in different sessions, each with its companion steps
+	insert("terraform init", "s1", 1000)
+	insert("terraform apply", "s1", 1100)
+	insert("terraform output", "s1", 1200)
+	insert("terraform output", "s1", 1250) // Same deploy, counts once
+	insert("vim main.tf", "s1", 2000)      // Too late
+
+	insert("terraform init", "s2", 5000)
+	insert("terraform apply", "s2", 5060)
+	insert("terraform init", "s3", 5070) // Other session
+
+	// No session, never related
+	insert("terraform apply", "", 9000)
+	insert("make", "", 9010)
+
+	deploys, err := db.Query(QueryFilters{Command: "terraform apply", Before: 1100})
+	require.NoError(t, err)
+	require.Len(t, deploys, 1)
+	deploy := deploys[0]
+
+	related, err := db.Related(deploy.ID, 5*time.Minute, 0)
+	require.NoError(t, err)
+	require.Len(t, related, 2)
+
+	assert.Equal(t, "terraform init", related[0].Entry.Command)
+	assert.Equal(t, 2, related[0].Count)
+	assert.Equal(t, int64(5000), related[0].Entry.Timestamp)
+
+	assert.Equal(t, "terraform output", related[1].Entry.Command)
+	assert.Equal(t, 1, related[1].Count)
+	assert.Equal(t, int64(1250), related[1].Entry.Timestamp)
+
+	related, err = db.Related(deploy.ID, 5*time.Minute, 1)
+	require.NoError(t, err)
+	assert.Len(t, related, 1)
+
+	_, err = db.Related(12345, 5*time.Minute, 0)
+	assert.Error(t, err)
+}
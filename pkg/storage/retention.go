@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stamp files mark when a save last pruned the database next to them, and
+// when the command of the day was last shown
+const (
+	pruneStampFile  = "prune-stamp"
+	remindStampFile = "remind-stamp"
+)
+
+// Retention is how much history is kept. Zero fields keep everything.
+type Retention struct {
+	MaxAge       time.Duration // Entries older than this are removed
+	MaxEntries   int64         // Only the most recent this many entries are kept
+	FailedMaxAge time.Duration // Failed entries older than this are removed
+}
+
+// Enabled reports whether the retention removes anything
+func (r Retention) Enabled() bool {
+	return r.MaxAge > 0 || r.MaxEntries > 0 || r.FailedMaxAge > 0
+}
+
+// Pruned is how many entries each rule of a Retention removed
+type Pruned struct {
+	Failed int64 // Failed entries past FailedMaxAge
+	Old    int64 // Entries past MaxAge
+	Excess int64 // Entries past MaxEntries
+}
+
+// Total returns how many entries were removed
+func (p *Pruned) Total() int64 {
+	return p.Failed + p.Old + p.Excess
+}
+
+// retentionRule is a rule of a Retention removing the entries matching a
+// condition, counted into count
+type retentionRule struct {
+	where string
+	arg   int64
+	count *int64
+}
+
+// rules returns the age rules r has on, in the order they apply. Each one
+// only sees what the ones before it left. MaxEntries, applied last, keeps
+// the most recent of what is left.
+func (r Retention) rules(now time.Time, pruned *Pruned) []retentionRule {
+	var rules []retentionRule
+	if r.FailedMaxAge > 0 {
+		rules = append(rules, retentionRule{"exit_code != 0 AND timestamp < ?", now.Add(-r.FailedMaxAge).Unix(), &pruned.Failed})
+	}
+	if r.MaxAge > 0 {
+		rules = append(rules, retentionRule{"timestamp < ?", now.Add(-r.MaxAge).Unix(), &pruned.Old})
+	}
+	return rules
+}
+
+// Prune removes the entries r doesn't keep as of now, in one transaction
+func (db *DB) Prune(r Retention, now time.Time) (*Pruned, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	pruned := &Pruned{}
+	for _, rule := range r.rules(now, pruned) {
+		if *rule.count, err = rowsAffected(tx.Exec("DELETE FROM history WHERE "+rule.where, rule.arg)); err != nil {
+			return nil, fmt.Errorf("failed to prune entries: %w", err)
+		}
+	}
+	if r.MaxEntries > 0 {
+		pruned.Excess, err = rowsAffected(tx.Exec(`DELETE FROM history WHERE id NOT IN (
+			SELECT id FROM history ORDER BY timestamp DESC, id DESC LIMIT ?
+		)`, r.MaxEntries))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune entries: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return pruned, nil
+}
+
+// Prunable counts what Prune would remove, without writing anything
+func (db *DB) Prunable(r Retention, now time.Time) (*Pruned, error) {
+	pruned := &Pruned{}
+	kept := "1"
+	var keptArgs []interface{}
+	for _, rule := range r.rules(now, pruned) {
+		args := append(append([]interface{}{}, keptArgs...), rule.arg)
+		err := db.conn.QueryRow("SELECT COUNT(*) FROM history WHERE "+kept+" AND "+rule.where, args...).Scan(rule.count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count entries: %w", err)
+		}
+		kept += " AND NOT (" + rule.where + ")"
+		keptArgs = append(keptArgs, rule.arg)
+	}
+	if r.MaxEntries > 0 {
+		var left int64
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM history WHERE "+kept, keptArgs...).Scan(&left); err != nil {
+			return nil, fmt.Errorf("failed to count entries: %w", err)
+		}
+		pruned.Excess = max(left-r.MaxEntries, 0)
+	}
+	return pruned, nil
+}
+
+// rowsAffected returns how many rows the statement result is of changed
+func rowsAffected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClaimPrune reports whether a save should prune the database, which it
+// does at most once every interval: the first save to ask after that gets
+// it. Ephemeral databases are never pruned on save.
+func (db *DB) ClaimPrune(interval time.Duration, now time.Time) bool {
+	return claimStamp(db.path, pruneStampFile, now, func(last time.Time) bool {
+		return now.Sub(last) < interval
+	})
+}
+
+// ClaimReminder reports whether the command of the day should be shown for
+// the database at path, which it is by the first shell to ask each day. It
+// doesn't open the database, so the shells that don't get it never do.
+func ClaimReminder(path string, now time.Time) bool {
+	return claimStamp(path, remindStampFile, now, func(last time.Time) bool {
+		y1, m1, d1 := last.Date()
+		y2, m2, d2 := now.Date()
+		return y1 == y2 && m1 == m2 && d1 == d2
+	})
+}
+
+// claimStamp touches the stamp file name next to the database at path
+// unless it was last touched at a time taken reports as too recent,
+// reporting whether it did. Ephemeral databases have nowhere to keep a
+// stamp and never claim.
+func claimStamp(path, name string, now time.Time, taken func(last time.Time) bool) bool {
+	if IsMemoryPath(path) {
+		return false
+	}
+
+	stamp := filepath.Join(filepath.Dir(path), name)
+	if info, err := os.Stat(stamp); err == nil && taken(info.ModTime()) {
+		return false
+	}
+	if err := os.WriteFile(stamp, nil, 0600); err != nil {
+		return false
+	}
+	_ = os.Chtimes(stamp, now, now)
+	return true
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Unix(100*86400, 0)
+	day := int64(86400)
+	insert := func(command string, daysAgo int64, exitCode int) {
+		entry := createTestEntry(t, command, now.Unix()-daysAgo*day)
+		entry.ExitCode = exitCode
+		require.NoError(t, db.Insert(entry))
+	}
+	insert("ancient", 50, 0)
+	insert("old typo", 20, 127)
+	insert("old build", 20, 0)
+	insert("recent typo", 2, 127)
+	for i := range 5 {
+		insert(fmt.Sprintf("recent %d", i), 1, 0)
+	}
+
+	r := Retention{MaxAge: 30 * 24 * time.Hour, MaxEntries: 5, FailedMaxAge: 7 * 24 * time.Hour}
+	preview, err := db.Prunable(r, now)
+	require.NoError(t, err)
+	assert.Equal(t, &Pruned{Failed: 1, Old: 1, Excess: 2}, preview)
+
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), count, "a preview removes nothing")
+
+	pruned, err := db.Prune(r, now)
+	require.NoError(t, err)
+	assert.Equal(t, preview, pruned)
+	assert.Equal(t, int64(4), pruned.Total())
+
+	preview, err = db.Prunable(r, now)
+	require.NoError(t, err)
+	assert.Zero(t, preview.Total(), "nothing left to prune")
+
+	entries, err := db.Query(QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	for _, entry := range entries {
+		assert.Contains(t, entry.Command, "recent ")
+	}
+
+	// Nothing configured, nothing removed
+	pruned, err = db.Prune(Retention{}, now)
+	require.NoError(t, err)
+	assert.Zero(t, pruned.Total())
+	assert.False(t, Retention{}.Enabled())
+}
+
+func TestClaimPrune(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	assert.True(t, db.ClaimPrune(time.Hour, now))
+	assert.False(t, db.ClaimPrune(time.Hour, now.Add(30*time.Minute)))
+	assert.True(t, db.ClaimPrune(time.Hour, now.Add(2*time.Hour)))
+
+	memory, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer memory.Close()
+	assert.False(t, memory.ClaimPrune(time.Hour, now))
+}
+
+func TestClaimReminder(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	morning := time.Date(2025, 3, 4, 9, 0, 0, 0, time.Local)
+	assert.True(t, ClaimReminder(db.path, morning))
+	assert.False(t, ClaimReminder(db.path, morning.Add(12*time.Hour)), "same day")
+	assert.True(t, ClaimReminder(db.path, morning.Add(23*time.Hour)), "next day, even within 24 hours")
+	assert.False(t, ClaimReminder(MemoryPath, morning))
+
+	// Pruning keeps its own stamp
+	assert.True(t, db.ClaimPrune(time.Hour, morning.Add(23*time.Hour)))
+}
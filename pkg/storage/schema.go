@@ -2,24 +2,70 @@ package storage
 
 // HistoryEntry represents a single command in the history
 type HistoryEntry struct {
-	ID         int64  `db:"id"`
-	Timestamp  int64  `db:"timestamp"`
-	Command    string `db:"command"`
-	Cwd        string `db:"cwd"`
-	ExitCode   int    `db:"exit_code"`
-	Hostname   string `db:"hostname"`
-	User       string `db:"user"`
-	Shell      string `db:"shell"`
-	DurationMs int64  `db:"duration_ms"`
-	GitBranch  string `db:"git_branch"`
-	Hash       string `db:"hash"` // Can be empty for KeepAll strategy
-	SessionID  string `db:"session_id"`
+	ID          int64  `db:"id"`
+	Timestamp   int64  `db:"timestamp"`
+	Command     string `db:"command"`
+	Cwd         string `db:"cwd"`
+	ExitCode    int    `db:"exit_code"`
+	Hostname    string `db:"hostname"`
+	User        string `db:"user"`
+	Shell       string `db:"shell"`
+	DurationMs  int64  `db:"duration_ms"`
+	GitBranch   string `db:"git_branch"`
+	Hash        string `db:"hash"` // Can be empty for KeepAll strategy
+	SessionID   string `db:"session_id"`
+	Profile     string `db:"profile"`
+	ExecRuntime string `db:"exec_runtime"` // docker, podman, compose or kubectl when run via exec
+	ExecTarget  string `db:"exec_target"`  // Container, service or pod the command ran in
+	JobID       int64  `db:"job_id"`       // PID of a background job (cmd &), 0 otherwise
+	Note        string `db:"note"`         // Free-form note added with fh --amend
+	Program     string `db:"program"`      // Primary program of the command, filled in on insert
+	ApproxTime  bool   `db:"approx_time"`  // Timestamp inferred on import, not recorded
+	Redacted    bool   `db:"redacted"`     // Secrets in the command were masked before saving
+	Signature   string `db:"signature"`    // HMAC of the entry when signing is on, see Verify
+	UUID        string `db:"uuid"`         // Identifies the entry in exports, set on insert
+}
+
+// Amendment is an audit log record of a field changed by Update
+type Amendment struct {
+	ID        int64  `db:"id"`
+	EntryID   int64  `db:"entry_id"`
+	Field     string `db:"field"`
+	OldValue  string `db:"old_value"`
+	NewValue  string `db:"new_value"`
+	AmendedAt int64  `db:"amended_at"`
+}
+
+// IgnoredCommand is a command that was not saved because it matched an
+// ignore pattern
+type IgnoredCommand struct {
+	ID        int64  `db:"id"`
+	Timestamp int64  `db:"timestamp"`
+	Command   string `db:"command"`
+	Cwd       string `db:"cwd"`
+	Pattern   string `db:"pattern"` // First configured pattern that matched
+	Profile   string `db:"profile"`
 }
 
 // Schema versions for migration tracking
 const (
-	SchemaVersion1 = 1
-	CurrentSchema  = SchemaVersion1
+	SchemaVersion1  = 1
+	SchemaVersion2  = 2
+	SchemaVersion3  = 3
+	SchemaVersion4  = 4
+	SchemaVersion5  = 5
+	SchemaVersion6  = 6
+	SchemaVersion7  = 7
+	SchemaVersion8  = 8
+	SchemaVersion9  = 9
+	SchemaVersion10 = 10
+	SchemaVersion11 = 11
+	SchemaVersion12 = 12
+	SchemaVersion13 = 13
+	SchemaVersion14 = 14
+	SchemaVersion15 = 15
+	SchemaVersion16 = 16
+	CurrentSchema   = SchemaVersion16
 )
 
 // SQL schema for version 1
@@ -52,11 +98,280 @@ CREATE INDEX IF NOT EXISTS idx_session ON history(session_id);
 CREATE INDEX IF NOT EXISTS idx_cwd ON history(cwd);
 `
 
+// SQL schema for version 2: per-entry profiles
+const schemaV2 = `
+ALTER TABLE history ADD COLUMN profile TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_profile ON history(profile);
+`
+
+// SQL schema for version 3: container/pod exec context
+const schemaV3 = `
+ALTER TABLE history ADD COLUMN exec_runtime TEXT NOT NULL DEFAULT '';
+ALTER TABLE history ADD COLUMN exec_target TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_exec_target ON history(exec_target);
+`
+
+// SQL schema for version 4: background job tracking
+const schemaV4 = `
+ALTER TABLE history ADD COLUMN job_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_session_job ON history(session_id, job_id);
+`
+
+// SQL schema for version 5: entry notes and the amendment audit log
+const schemaV5 = `
+ALTER TABLE history ADD COLUMN note TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS amendments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL REFERENCES history(id) ON DELETE CASCADE,
+    field TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    amended_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_amendments_entry ON amendments(entry_id);
+`
+
+// SQL schema for version 6: primary program of each command.
+// Existing rows are filled in by backfillPrograms after the migration.
+const schemaV6 = `
+ALTER TABLE history ADD COLUMN program TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_program ON history(program);
+`
+
+// SQL schema for version 7: log of commands skipped by ignore patterns
+const schemaV7 = `
+CREATE TABLE IF NOT EXISTS ignored (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp INTEGER NOT NULL,
+    command TEXT NOT NULL,
+    cwd TEXT NOT NULL DEFAULT '',
+    pattern TEXT NOT NULL,
+    profile TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE INDEX IF NOT EXISTS idx_ignored_timestamp ON ignored(timestamp DESC);
+`
+
+// SQL schema for version 8: entries imported with an inferred timestamp
+const schemaV8 = `
+ALTER TABLE history ADD COLUMN approx_time INTEGER NOT NULL DEFAULT 0;
+`
+
+// commandIndexRemoveOld takes the OLD row of history out of command_index,
+// finding the latest run again when it was the latest
+const commandIndexRemoveOld = `
+    UPDATE command_index SET count = count - 1
+    WHERE profile = OLD.profile AND command = OLD.command;
+
+    DELETE FROM command_index
+    WHERE profile = OLD.profile AND command = OLD.command AND count <= 0;
+
+    UPDATE command_index SET (last_id, last_ts) = (
+        SELECT id, timestamp FROM history
+        WHERE profile = OLD.profile AND command = OLD.command
+        ORDER BY timestamp DESC, id DESC LIMIT 1
+    )
+    WHERE profile = OLD.profile AND command = OLD.command AND last_id = OLD.id;
+`
+
+// commandIndexAddNew counts the NEW row of history in command_index
+const commandIndexAddNew = `
+    INSERT INTO command_index (profile, command, last_id, last_ts, count)
+    VALUES (NEW.profile, NEW.command, NEW.id, NEW.timestamp, 1)
+    ON CONFLICT(profile, command) DO UPDATE SET
+        count = count + 1,
+        last_id = CASE
+            WHEN excluded.last_ts > last_ts OR (excluded.last_ts = last_ts AND excluded.last_id > last_id)
+            THEN excluded.last_id ELSE last_id END,
+        last_ts = MAX(last_ts, excluded.last_ts);
+`
+
+// commandIndexFill counts the whole history into an empty command_index.
+// The bare id comes from the row with the highest timestamp.
+const commandIndexFill = `
+INSERT INTO command_index (profile, command, last_id, last_ts, count)
+SELECT profile, command, id, MAX(timestamp), COUNT(*)
+FROM history
+GROUP BY profile, command;
+`
+
+// SQL schema for version 9: one row per command and profile with its run
+// count and latest run, kept up to date by triggers so unique-command views
+// don't have to group every run
+const schemaV9 = `
+CREATE TABLE IF NOT EXISTS command_index (
+    profile TEXT NOT NULL,
+    command TEXT NOT NULL,
+    last_id INTEGER NOT NULL,
+    last_ts INTEGER NOT NULL,
+    count INTEGER NOT NULL,
+    PRIMARY KEY (profile, command)
+);
+
+CREATE INDEX IF NOT EXISTS idx_command_index_last_ts ON command_index(last_ts DESC);
+
+` + commandIndexFill + `
+
+CREATE TRIGGER IF NOT EXISTS command_index_insert AFTER INSERT ON history
+BEGIN` + commandIndexAddNew + `END;
+
+CREATE TRIGGER IF NOT EXISTS command_index_delete AFTER DELETE ON history
+BEGIN` + commandIndexRemoveOld + `END;
+
+CREATE TRIGGER IF NOT EXISTS command_index_update AFTER UPDATE OF command, profile, timestamp ON history
+BEGIN` + commandIndexRemoveOld + commandIndexAddNew + `END;
+`
+
+// hourlyCountsRemoveOld takes the OLD row of history out of hourly_counts
+const hourlyCountsRemoveOld = `
+    UPDATE hourly_counts SET
+        total = total - 1,
+        failed = failed - (OLD.exit_code != 0),
+        approx = approx - (OLD.approx_time != 0)
+    WHERE profile = OLD.profile AND hour = OLD.timestamp / 3600;
+
+    DELETE FROM hourly_counts
+    WHERE profile = OLD.profile AND hour = OLD.timestamp / 3600 AND total <= 0;
+`
+
+// hourlyCountsAddNew counts the NEW row of history in hourly_counts
+const hourlyCountsAddNew = `
+    INSERT INTO hourly_counts (profile, hour, total, failed, approx)
+    VALUES (NEW.profile, NEW.timestamp / 3600, 1, NEW.exit_code != 0, NEW.approx_time != 0)
+    ON CONFLICT(profile, hour) DO UPDATE SET
+        total = total + 1,
+        failed = failed + excluded.failed,
+        approx = approx + excluded.approx;
+`
+
+// hourlyCountsFill counts the whole history into an empty hourly_counts
+const hourlyCountsFill = `
+INSERT INTO hourly_counts (profile, hour, total, failed, approx)
+SELECT profile, timestamp / 3600, COUNT(*), SUM(exit_code != 0), SUM(approx_time != 0)
+FROM history
+GROUP BY profile, timestamp / 3600;
+`
+
+// SQL schema for version 10: entries, failures and inferred times per
+// profile and hour, kept up to date by triggers for fh --stats --fast
+const schemaV10 = `
+CREATE TABLE IF NOT EXISTS hourly_counts (
+    profile TEXT NOT NULL,
+    hour INTEGER NOT NULL,
+    total INTEGER NOT NULL,
+    failed INTEGER NOT NULL,
+    approx INTEGER NOT NULL,
+    PRIMARY KEY (profile, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_timestamp ON history(profile, timestamp);
+` + hourlyCountsFill + `
+CREATE TRIGGER IF NOT EXISTS hourly_counts_insert AFTER INSERT ON history
+BEGIN` + hourlyCountsAddNew + `END;
+
+CREATE TRIGGER IF NOT EXISTS hourly_counts_delete AFTER DELETE ON history
+BEGIN` + hourlyCountsRemoveOld + `END;
+
+CREATE TRIGGER IF NOT EXISTS hourly_counts_update AFTER UPDATE OF profile, timestamp, exit_code, approx_time ON history
+BEGIN` + hourlyCountsRemoveOld + hourlyCountsAddNew + `END;
+`
+
+// SQL schema for version 11: machines that opened the database, to notice
+// one file synced between several of them
+const schemaV11 = `
+CREATE TABLE IF NOT EXISTS hosts (
+    host_id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL
+);
+`
+
+// SQL schema for version 12: how far each analytics sink has got, entries
+// with a larger id are still to be sent
+const schemaV12 = `
+CREATE TABLE IF NOT EXISTS sink_state (
+    sink TEXT PRIMARY KEY,
+    last_id INTEGER NOT NULL DEFAULT 0,
+    last_attempt INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+`
+
+// SQL schema for version 13: entries whose command had secrets masked
+const schemaV13 = `
+ALTER TABLE history ADD COLUMN redacted INTEGER NOT NULL DEFAULT 0;
+`
+
+// SQL schema for version 14: signatures for tamper-evidence
+const schemaV14 = `
+ALTER TABLE history ADD COLUMN signature TEXT NOT NULL DEFAULT '';
+`
+
+// SQL schema for version 15: facts about the database itself, such as the
+// first entry saved with signing on
+const schemaV15 = `
+CREATE TABLE IF NOT EXISTS metadata (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+`
+
+// SQL schema for version 16: a random UUID per entry, which exports carry
+// so imports find the entries they came from. Existing entries get theirs
+// here, in the format of newUUID.
+const schemaV16 = `
+ALTER TABLE history ADD COLUMN uuid TEXT NOT NULL DEFAULT '';
+
+UPDATE history SET uuid = lower(
+    hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+    substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_uuid ON history(uuid);
+`
+
 // GetSchema returns the SQL schema for the given version
 func GetSchema(version int) string {
 	switch version {
 	case SchemaVersion1:
 		return schemaV1
+	case SchemaVersion2:
+		return schemaV2
+	case SchemaVersion3:
+		return schemaV3
+	case SchemaVersion4:
+		return schemaV4
+	case SchemaVersion5:
+		return schemaV5
+	case SchemaVersion6:
+		return schemaV6
+	case SchemaVersion7:
+		return schemaV7
+	case SchemaVersion8:
+		return schemaV8
+	case SchemaVersion9:
+		return schemaV9
+	case SchemaVersion10:
+		return schemaV10
+	case SchemaVersion11:
+		return schemaV11
+	case SchemaVersion12:
+		return schemaV12
+	case SchemaVersion13:
+		return schemaV13
+	case SchemaVersion14:
+		return schemaV14
+	case SchemaVersion15:
+		return schemaV15
+	case SchemaVersion16:
+		return schemaV16
 	default:
 		return ""
 	}
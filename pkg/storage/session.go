@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SessionNeighbors returns up to n entries run right before and right after
+// entry in the same shell session, both oldest first. Entries without a
+// session have no neighbors.
+func (db *DB) SessionNeighbors(entry *HistoryEntry, n int) ([]*HistoryEntry, []*HistoryEntry, error) {
+	if entry.SessionID == "" || n <= 0 {
+		return nil, nil, nil
+	}
+
+	// Entries saved in the same second are ordered by id
+	before, err := db.sessionEntries(`
+		SELECT `+selectColumns("")+` FROM history
+		WHERE session_id = ? AND (timestamp < ? OR (timestamp = ? AND id < ?))
+		ORDER BY timestamp DESC, id DESC LIMIT ?
+	`, entry.SessionID, entry.Timestamp, entry.Timestamp, entry.ID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, j := 0, len(before)-1; i < j; i, j = i+1, j-1 {
+		before[i], before[j] = before[j], before[i]
+	}
+
+	after, err := db.sessionEntries(`
+		SELECT `+selectColumns("")+` FROM history
+		WHERE session_id = ? AND (timestamp > ? OR (timestamp = ? AND id > ?))
+		ORDER BY timestamp, id LIMIT ?
+	`, entry.SessionID, entry.Timestamp, entry.Timestamp, entry.ID, n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+// SessionRecent returns the last n entries of a shell session, oldest first
+func (db *DB) SessionRecent(session string, n int) ([]*HistoryEntry, error) {
+	if session == "" || n <= 0 {
+		return nil, nil
+	}
+
+	entries, err := db.sessionEntries(`
+		SELECT `+selectColumns("")+` FROM history
+		WHERE session_id = ?
+		ORDER BY timestamp DESC, id DESC LIMIT ?
+	`, session, n)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// SessionPrevious returns the index-th previous command of a shell session,
+// 1 being the last one run, or nil past its first. A command run several
+// times in a row counts once, like the ignoredups of shells' own history.
+func (db *DB) SessionPrevious(session string, index int) (*HistoryEntry, error) {
+	if session == "" || index <= 0 {
+		return nil, nil
+	}
+
+	entries, err := db.sessionEntries(`
+		SELECT `+selectColumns("")+` FROM (
+			SELECT *, LAG(command) OVER (ORDER BY timestamp, id) AS previous_command
+			FROM history WHERE session_id = ?
+		)
+		WHERE previous_command IS NULL OR previous_command != command
+		ORDER BY timestamp DESC, id DESC LIMIT 1 OFFSET ?
+	`, session, index-1)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+func (db *DB) sessionEntries(query string, args ...interface{}) ([]*HistoryEntry, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entries []*HistoryEntry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionNeighbors(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	insert := func(command, session string, timestamp int64) {
+		entry := createTestEntry(t, command, timestamp)
+		entry.SessionID = session
+		entry.Hash = fmt.Sprintf("%s-%s-%d", command, session, timestamp)
+		require.NoError(t, db.Insert(entry))
+	}
+
+	insert("cd api", "s1", 1000)
+	insert("git pull", "s1", 1100)
+	insert("make build", "s1", 1200)
+	insert("make test", "s1", 1200) // Same second, after by id
+	insert("make deploy", "s1", 1300)
+	insert("ls", "s2", 1150) // Other session
+	insert("vim notes", "", 1250)
+
+	commands := func(entries []*HistoryEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Command)
+		}
+		return out
+	}
+
+	found, err := db.Query(QueryFilters{Command: "make build"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	before, after, err := db.SessionNeighbors(found[0], 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cd api", "git pull"}, commands(before))
+	assert.Equal(t, []string{"make test", "make deploy"}, commands(after))
+
+	before, after, err = db.SessionNeighbors(found[0], 10)
+	require.NoError(t, err)
+	assert.Len(t, before, 2)
+	assert.Len(t, after, 2)
+
+	// No session, no neighbors
+	found, err = db.Query(QueryFilters{Command: "vim notes"})
+	require.NoError(t, err)
+	before, after, err = db.SessionNeighbors(found[0], 2)
+	require.NoError(t, err)
+	assert.Empty(t, before)
+	assert.Empty(t, after)
+}
+
+func TestSessionRecent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for i, command := range []string{"cd api", "git pull", "make build", "make test"} {
+		entry := createTestEntry(t, command, int64(1000+i))
+		entry.SessionID = "s1"
+		entry.Hash = fmt.Sprintf("%s-s1", command)
+		require.NoError(t, db.Insert(entry))
+	}
+	other := createTestEntry(t, "ls", 2000)
+	other.SessionID = "s2"
+	require.NoError(t, db.Insert(other))
+
+	recent, err := db.SessionRecent("s1", 2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "make build", recent[0].Command)
+	assert.Equal(t, "make test", recent[1].Command)
+
+	recent, err = db.SessionRecent("", 2)
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+}
+
+func TestSessionPrevious(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for i, command := range []string{"cd api", "make test", "make test", "vim main.go", "make test"} {
+		entry := createTestEntry(t, command, int64(1000+i))
+		entry.SessionID = "s1"
+		entry.Hash = fmt.Sprintf("%s-%d", command, i)
+		require.NoError(t, db.Insert(entry))
+	}
+	other := createTestEntry(t, "ls", 2000)
+	other.SessionID = "s2"
+	require.NoError(t, db.Insert(other))
+
+	var got []string
+	for index := 1; ; index++ {
+		entry, err := db.SessionPrevious("s1", index)
+		require.NoError(t, err)
+		if entry == nil {
+			break
+		}
+		got = append(got, entry.Command)
+	}
+	assert.Equal(t, []string{"make test", "vim main.go", "make test", "cd api"}, got, "repeats in a row count once")
+
+	entry, err := db.SessionPrevious("s1", 0)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+	entry, err = db.SessionPrevious("", 1)
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// KeyFunc returns the key entries are signed with. It is called once, on
+// the first write or verification, so commands that only read never ask the
+// keyring.
+type KeyFunc func() ([]byte, error)
+
+// signatureVersion prefixes signatures, so the canonical form can change
+const signatureVersion = "v1:"
+
+// ErrNoSigningKey is returned by Verify when signing is not configured
+var ErrNoSigningKey = errors.New("entry signing is not configured")
+
+// signer fetches the signing key on first use
+type signer struct {
+	keyFunc KeyFunc
+	once    sync.Once
+	key     []byte
+	err     error
+}
+
+// signingKey returns the key to sign with, nil when signing is off
+func (db *DB) signingKey() ([]byte, error) {
+	if db.signer == nil {
+		return nil, nil
+	}
+	db.signer.once.Do(func() {
+		db.signer.key, db.signer.err = db.signer.keyFunc()
+		if db.signer.err != nil {
+			db.signer.err = fmt.Errorf("failed to get signing key: %w", db.signer.err)
+		}
+	})
+	return db.signer.key, db.signer.err
+}
+
+// signature returns the HMAC of the fields of entry that record what ran.
+// The id, hash and program are left out: they are bookkeeping that copies,
+// archives and deduplication may change.
+func signature(key []byte, entry *HistoryEntry) string {
+	canonical, _ := json.Marshal([]interface{}{
+		entry.Timestamp, entry.Command, entry.Cwd, entry.ExitCode, entry.Hostname,
+		entry.User, entry.Shell, entry.DurationMs, entry.GitBranch, entry.SessionID,
+		entry.Profile, entry.ExecRuntime, entry.ExecTarget, entry.JobID, entry.Note,
+		entry.ApproxTime, entry.Redacted,
+	})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(canonical)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign sets the signature of entry when signing is on
+func (db *DB) sign(entry *HistoryEntry) error {
+	key, err := db.signingKey()
+	if err != nil || key == nil {
+		return err
+	}
+	entry.Signature = signature(key, entry)
+	return nil
+}
+
+// signingSinceKey is the metadata holding the id of the first entry saved
+// with signing on
+const signingSinceKey = "signing_since_id"
+
+// markSigningStart records the id the next entry gets as the first one
+// saved with signing on, unless one is recorded already. Verify reports an
+// unsigned entry from there on as tampered: fh never saves one.
+func (db *DB) markSigningStart() error {
+	if _, err := db.signingSince(); err != sql.ErrNoRows {
+		return err
+	}
+	_, err := db.conn.Exec(
+		"INSERT OR IGNORE INTO metadata (key, value) SELECT ?, COALESCE(MAX(id), 0) + 1 FROM history",
+		signingSinceKey,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record the start of signing: %w", err)
+	}
+	return nil
+}
+
+// signingSince returns the id of the first entry saved with signing on,
+// sql.ErrNoRows when signing was never on
+func (db *DB) signingSince() (int64, error) {
+	var since int64
+	err := db.conn.QueryRow("SELECT value FROM metadata WHERE key = ?", signingSinceKey).Scan(&since)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to read the start of signing: %w", err)
+	}
+	return since, err
+}
+
+// rowExecer is implemented by *sql.DB and *sql.Tx
+type rowExecer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// resign signs entry id again after fh itself changed it, such as an
+// amendment or a deduplicated save moving its time
+func (db *DB) resign(q rowExecer, id int64) error {
+	key, err := db.signingKey()
+	if err != nil || key == nil {
+		return err
+	}
+	entry, err := scanEntry(q.QueryRow("SELECT "+selectColumns("")+" FROM history WHERE id = ?", id))
+	if err != nil {
+		return fmt.Errorf("failed to get entry: %w", err)
+	}
+	if _, err := q.Exec("UPDATE history SET signature = ? WHERE id = ?", signature(key, entry), id); err != nil {
+		return fmt.Errorf("failed to sign entry: %w", err)
+	}
+	return nil
+}
+
+// Verification is the result of checking the signatures of the history
+type Verification struct {
+	Checked  int64           // Entries read
+	Unsigned int64           // Entries without a signature, saved before signing was on
+	Tampered []*HistoryEntry // Entries whose signature does not match or was removed
+}
+
+// Verify checks the signature of every entry, finding entries changed
+// outside fh after they were saved
+func (db *DB) Verify() (*Verification, error) {
+	key, err := db.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	since, err := db.signingSince()
+	if err == sql.ErrNoRows {
+		since = 0
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query("SELECT " + selectColumns("") + " FROM history ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	v := &Verification{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		v.Checked++
+		switch {
+		case entry.Signature == "" && (since == 0 || entry.ID < since):
+			v.Unsigned++
+		case entry.Signature == "" || !hmac.Equal([]byte(entry.Signature), []byte(signature(key, entry))):
+			v.Tampered = append(v.Tampered, entry)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	// Saved before signing was turned on
+	db, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, db.Insert(createTestEntry(t, "ls", 1000)))
+	require.NoError(t, db.Close())
+
+	calls := 0
+	key := func() ([]byte, error) {
+		calls++
+		return []byte("0123456789abcdef0123456789abcdef"), nil
+	}
+	db, err = OpenWithOptions(path, Options{SigningKey: key})
+	require.NoError(t, err)
+	defer db.Close()
+
+	keepLast := DedupConfig{Enabled: true, Strategy: KeepLast}
+	for i, command := range []string{"make", "make test", "make"} {
+		entry := createTestEntry(t, command, 2000+int64(i))
+		entry.Hash = ""
+		require.NoError(t, db.InsertWithDedup(entry, keepLast))
+	}
+	entries, err := db.Query(QueryFilters{Command: "make test"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Signature, "v1:")
+
+	// Changes made by fh keep the entry valid
+	exitCode := 2
+	require.NoError(t, db.Update(entries[0].ID, EntryUpdate{ExitCode: &exitCode}))
+	require.NoError(t, db.RedactCommands(map[int64]string{entries[0].ID: "make ****"}))
+
+	v, err := db.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v.Checked)
+	assert.Equal(t, int64(1), v.Unsigned)
+	assert.Empty(t, v.Tampered)
+	assert.Equal(t, 1, calls)
+
+	// Changes made behind its back do not
+	_, err = db.conn.Exec("UPDATE history SET exit_code = 0 WHERE id = ?", entries[0].ID)
+	require.NoError(t, err)
+	v, err = db.Verify()
+	require.NoError(t, err)
+	require.Len(t, v.Tampered, 1)
+	assert.Equal(t, entries[0].ID, v.Tampered[0].ID)
+
+	// A removed signature is no older entry, fh signed everything since
+	_, err = db.conn.Exec("UPDATE history SET signature = '' WHERE command = 'make'")
+	require.NoError(t, err)
+	v, err = db.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v.Unsigned)
+	assert.Len(t, v.Tampered, 2)
+
+	// Opening it again with signing on keeps where signing started
+	require.NoError(t, db.Close())
+	reopened, err := OpenWithOptions(path, Options{SigningKey: key})
+	require.NoError(t, err)
+	defer reopened.Close()
+	v, err = reopened.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v.Unsigned)
+	assert.Len(t, v.Tampered, 2)
+}
+
+func TestVerify_NoKey(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Verify()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestSign_KeyError(t *testing.T) {
+	db, err := OpenWithOptions(filepath.Join(t.TempDir(), "history.db"), Options{
+		SigningKey: func() ([]byte, error) { return nil, errors.New("keyring locked") },
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Insert(createTestEntry(t, "ls", 1000))
+	assert.ErrorContains(t, err, "failed to get signing key: keyring locked")
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SinkState is how far an analytics sink has got
+type SinkState struct {
+	Sink        string
+	LastID      int64  // Entries up to this id were delivered
+	LastAttempt int64  // When a flush last started, unix seconds
+	LastError   string // Why the last batch failed, empty when it didn't
+	Pending     int64  // Entries still to be sent
+}
+
+// ClaimSinkFlush starts a flush of sink unless one started within
+// interval, so of many concurrent saves only one talks to the warehouse.
+// It returns the id the flush continues after.
+func (db *DB) ClaimSinkFlush(sink string, interval time.Duration, now time.Time) (int64, bool, error) {
+	if _, err := db.conn.Exec("INSERT INTO sink_state (sink) VALUES (?) ON CONFLICT(sink) DO NOTHING", sink); err != nil {
+		return 0, false, fmt.Errorf("failed to read sink state: %w", err)
+	}
+
+	var lastID int64
+	err := db.conn.QueryRow(`
+		UPDATE sink_state SET last_attempt = ?
+		WHERE sink = ? AND last_attempt <= ?
+		RETURNING last_id
+	`, now.Unix(), sink, now.Add(-interval).Unix()).Scan(&lastID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to claim sink flush: %w", err)
+	}
+	return lastID, true, nil
+}
+
+// EntriesAfter returns up to limit entries with an id above afterID,
+// oldest first
+func (db *DB) EntriesAfter(afterID int64, limit int) ([]*HistoryEntry, error) {
+	rows, err := db.conn.Query("SELECT "+selectColumns("")+" FROM history WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entries []*HistoryEntry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// RecordSinkResult records that sink delivered the entries up to lastID,
+// or the error that stopped it; a lastID below the current one is ignored
+func (db *DB) RecordSinkResult(sink string, lastID int64, sendErr error) error {
+	message := ""
+	if sendErr != nil {
+		message = sendErr.Error()
+	}
+	_, err := db.conn.Exec(
+		"UPDATE sink_state SET last_id = MAX(last_id, ?), last_error = ? WHERE sink = ?",
+		lastID, message, sink,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record sink state: %w", err)
+	}
+	return nil
+}
+
+// GetSinkState returns how far sink has got, a sink that never ran starts
+// at the first entry
+func (db *DB) GetSinkState(sink string) (*SinkState, error) {
+	state := &SinkState{Sink: sink}
+	err := db.conn.QueryRow(
+		"SELECT last_id, last_attempt, last_error FROM sink_state WHERE sink = ?", sink,
+	).Scan(&state.LastID, &state.LastAttempt, &state.LastError)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read sink state: %w", err)
+	}
+
+	err = db.conn.QueryRow("SELECT COUNT(*) FROM history WHERE id > ?", state.LastID).Scan(&state.Pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending entries: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSinkState(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	for _, ts := range []int64{1700000000, 1700000100, 1700000200} {
+		require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make", ts), keepAll))
+	}
+
+	state, err := db.GetSinkState("warehouse")
+	require.NoError(t, err)
+	assert.Equal(t, &SinkState{Sink: "warehouse", Pending: 3}, state)
+
+	now := time.Unix(1700001000, 0)
+	lastID, ok, err := db.ClaimSinkFlush("warehouse", time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Zero(t, lastID)
+
+	_, ok, err = db.ClaimSinkFlush("warehouse", time.Minute, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	entries, err := db.EntriesAfter(1, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(2), entries[0].ID)
+
+	require.NoError(t, db.RecordSinkResult("warehouse", 2, nil))
+	// A slower flush finishing later doesn't move it back
+	require.NoError(t, db.RecordSinkResult("warehouse", 1, errors.New("timeout")))
+
+	state, err = db.GetSinkState("warehouse")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), state.LastID)
+	assert.Equal(t, "timeout", state.LastError)
+	assert.Equal(t, int64(1), state.Pending)
+
+	lastID, ok, err = db.ClaimSinkFlush("warehouse", time.Minute, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), lastID)
+}
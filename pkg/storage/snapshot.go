@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is a copy of the database taken with CreateSnapshot
+type Snapshot struct {
+	Name    string
+	Path    string
+	Created time.Time
+	Size    int64
+}
+
+// autoSnapshotPrefix starts the names of the snapshots fh takes by itself
+const autoSnapshotPrefix = "auto-"
+
+// validSnapshotName keeps snapshot names usable as file names
+var validSnapshotName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// snapshotDir returns the directory of the snapshots of the database at
+// path: history.db keeps them in snapshots/history/ next to it
+func snapshotDir(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), "snapshots", base)
+}
+
+// snapshotPath returns the file of the snapshot name, checking the name
+func (db *DB) snapshotPath(name string) (string, error) {
+	if db.IsMemory() {
+		return "", fmt.Errorf("an in-memory database has no snapshots")
+	}
+	if !validSnapshotName.MatchString(name) {
+		return "", fmt.Errorf("invalid snapshot name: %q (use letters, digits, '.', '-' and '_')", name)
+	}
+	return filepath.Join(snapshotDir(db.path), name+".db"), nil
+}
+
+// CreateSnapshot copies the database into the snapshot name with VACUUM
+// INTO, which reads a consistent state while others keep writing. An
+// existing snapshot is never overwritten.
+func (db *DB) CreateSnapshot(name string) (*Snapshot, error) {
+	path, err := db.snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	// Written under a temporary name, so an interrupted snapshot is never
+	// mistaken for a complete one
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if _, err := db.conn.Exec("VACUUM INTO ?", tmp); err != nil {
+		_ = os.Remove(tmp)
+		return nil, fmt.Errorf("failed to snapshot to %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, fmt.Errorf("failed to snapshot to %s: %w", path, err)
+	}
+	return readSnapshot(path)
+}
+
+// readSnapshot describes the snapshot file at path
+func readSnapshot(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".db"),
+		Path:    path,
+		Created: info.ModTime(),
+		Size:    info.Size(),
+	}, nil
+}
+
+// Snapshots returns the snapshots of the database, oldest first
+func (db *DB) Snapshots() ([]*Snapshot, error) {
+	if db.IsMemory() {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(snapshotDir(db.path), "*.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	for _, path := range paths {
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// DeleteSnapshot removes the snapshot name
+func (db *DB) DeleteSnapshot(name string) error {
+	path, err := db.snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no snapshot %s", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the contents of the database with the snapshot
+// name. The state being replaced is taken as an automatic snapshot first.
+func (db *DB) RestoreSnapshot(name string) error {
+	path, err := db.snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no snapshot %s", name)
+	}
+
+	// Pruning must not remove the automatic snapshot being restored
+	if _, err := db.autoSnapshot("restore", name); err != nil {
+		return err
+	}
+
+	if err := db.restoreFrom(path); err != nil {
+		return fmt.Errorf("failed to restore snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// AutoSnapshot takes a snapshot named after reason before a destructive
+// change and drops the oldest automatic snapshots beyond
+// Options.AutoSnapshots. It does nothing when that is 0.
+func (db *DB) AutoSnapshot(reason string) (*Snapshot, error) {
+	return db.autoSnapshot(reason, "")
+}
+
+// autoSnapshot is AutoSnapshot sparing the snapshot named spare from pruning
+func (db *DB) autoSnapshot(reason, spare string) (*Snapshot, error) {
+	if db.autoSnapshots <= 0 || db.IsMemory() {
+		return nil, nil
+	}
+
+	name := autoSnapshotPrefix + reason + "-" + time.Now().Format("20060102-150405.000")
+	snapshot, err := db.CreateSnapshot(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take a snapshot before %s: %w", reason, err)
+	}
+
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		return snapshot, err
+	}
+	var auto []*Snapshot
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, autoSnapshotPrefix) && s.Name != spare {
+			auto = append(auto, s)
+		}
+	}
+	for len(auto) > db.autoSnapshots {
+		if err := os.Remove(auto[0].Path); err != nil {
+			return snapshot, fmt.Errorf("failed to remove old snapshot %s: %w", auto[0].Name, err)
+		}
+		auto = auto[1:]
+	}
+
+	return snapshot, nil
+}
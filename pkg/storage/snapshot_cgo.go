@@ -0,0 +1,54 @@
+//go:build cgo
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// restoreFrom replaces the database with the snapshot at path through
+// SQLite's backup API, so other processes that have the file open see the
+// change like any other write
+func (db *DB) restoreFrom(path string) error {
+	// Opened without Open so an older snapshot isn't migrated in place
+	src, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = srcConn.Close()
+	}()
+	destConn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = destConn.Close()
+	}()
+
+	return destConn.Raw(func(dest any) error {
+		return srcConn.Raw(func(src any) error {
+			backup, err := dest.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				_ = backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,58 @@
+//go:build !cgo
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// restoreFrom replaces the database with the snapshot at path. Without cgo
+// there is no backup API, so the snapshot is copied next to the database
+// with VACUUM INTO and renamed over it. Other processes that have the
+// database open keep seeing the old contents until they open it again.
+func (db *DB) restoreFrom(path string) error {
+	// Opened without Open so an older snapshot isn't migrated in place
+	src, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	tmp := db.path + ".restore"
+	_ = os.Remove(tmp)
+	if _, err := src.Exec("VACUUM INTO ?", tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// Nothing may be left in the write-ahead log to be applied on top of
+	// the restored file
+	if _, err := db.conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := db.conn.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	renameErr := os.Rename(tmp, db.path)
+	if renameErr != nil {
+		_ = os.Remove(tmp)
+	}
+
+	// Opened again either way, the old connection is closed
+	conn, err := sql.Open("sqlite3", db.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	db.conn = conn
+	if err := db.initialize(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return renameErr
+}
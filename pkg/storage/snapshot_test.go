@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_CreateRestore(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenWithOptions(filepath.Join(dir, "history.db"), Options{AutoSnapshots: 1})
+	require.NoError(t, err)
+	defer db.Close()
+
+	keepAll := DedupConfig{Enabled: true, Strategy: KeepAll}
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make", 1700000000), keepAll))
+
+	snapshot, err := db.CreateSnapshot("before-cleanup")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "snapshots", "history", "before-cleanup.db"), snapshot.Path)
+	assert.Positive(t, snapshot.Size)
+
+	_, err = db.CreateSnapshot("before-cleanup")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = db.CreateSnapshot("../escape")
+	assert.ErrorContains(t, err, "invalid snapshot name")
+
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make test", 1700000100), keepAll))
+	require.NoError(t, db.InsertWithDedup(createTestEntry(t, "make install", 1700000200), keepAll))
+
+	require.NoError(t, db.RestoreSnapshot("before-cleanup"))
+	count, err := db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// The replaced state was kept, so the restore can be undone
+	snapshots, err := db.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "before-cleanup", snapshots[0].Name)
+	assert.True(t, strings.HasPrefix(snapshots[1].Name, "auto-restore-"))
+
+	require.NoError(t, db.RestoreSnapshot(snapshots[1].Name))
+	count, err = db.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	require.NoError(t, db.DeleteSnapshot("before-cleanup"))
+	assert.ErrorContains(t, db.DeleteSnapshot("before-cleanup"), "no snapshot")
+	assert.ErrorContains(t, db.RestoreSnapshot("before-cleanup"), "no snapshot")
+}
+
+func TestAutoSnapshot(t *testing.T) {
+	t.Run("keeps the newest", func(t *testing.T) {
+		db, err := OpenWithOptions(filepath.Join(t.TempDir(), "history.db"), Options{AutoSnapshots: 2})
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = db.CreateSnapshot("mine")
+		require.NoError(t, err)
+		var names []string
+		for _, reason := range []string{"import", "archive", "import"} {
+			snapshot, err := db.AutoSnapshot(reason)
+			require.NoError(t, err)
+			names = append(names, snapshot.Name)
+		}
+
+		snapshots, err := db.Snapshots()
+		require.NoError(t, err)
+		var kept []string
+		for _, s := range snapshots {
+			kept = append(kept, s.Name)
+		}
+		assert.ElementsMatch(t, []string{"mine", names[1], names[2]}, kept)
+	})
+
+	t.Run("off", func(t *testing.T) {
+		db := setupTestDB(t)
+		defer db.Close()
+
+		snapshot, err := db.AutoSnapshot("import")
+		require.NoError(t, err)
+		assert.Nil(t, snapshot)
+	})
+
+	t.Run("before migrating", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.db")
+		db, err := Open(path)
+		require.NoError(t, err)
+		// Undo the last migration, so opening applies it again
+		_, err = db.conn.Exec("DELETE FROM schema_version WHERE version = ?", CurrentSchema)
+		require.NoError(t, err)
+		_, err = db.conn.Exec("DROP INDEX idx_uuid")
+		require.NoError(t, err)
+		_, err = db.conn.Exec("ALTER TABLE history DROP COLUMN uuid")
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db, err = OpenWithOptions(path, Options{AutoSnapshots: 1})
+		require.NoError(t, err)
+		defer db.Close()
+
+		snapshots, err := db.Snapshots()
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+		assert.True(t, strings.HasPrefix(snapshots[0].Name, fmt.Sprintf("auto-migrate-v%d-", CurrentSchema-1)))
+	})
+}
@@ -3,6 +3,10 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+
+	"github.com/spideyz0r/fh/pkg/redact"
+	"github.com/spideyz0r/fh/pkg/shellparse"
 )
 
 // Store defines the interface for history storage operations
@@ -15,28 +19,394 @@ type Store interface {
 	Close() error
 }
 
+// DefaultProfile is the profile entries are stored in when none is set
+const DefaultProfile = "default"
+
+// Redact masks the secrets redact.Command knows of in the command and
+// marks the entry as redacted when it did. Inserts do it before anything
+// else, with the database's own patterns; callers only need it to see the
+// command about as it will be stored.
+func (e *HistoryEntry) Redact() {
+	command, redacted := redact.Command(e.Command)
+	if redacted {
+		e.Command = command
+		e.Redacted = true
+	}
+}
+
+// redact masks the secrets of entry, those of Redact and the database's
+// own patterns. It reports false when the entry had one and the database
+// drops such entries instead.
+func (db *DB) redact(entry *HistoryEntry) bool {
+	entry.Redact()
+	if db.redactor != nil {
+		if command, redacted := db.redactor.Command(entry.Command); redacted {
+			entry.Command = command
+			entry.Redacted = true
+		}
+	}
+	return !db.dropSecrets || !entry.Redacted
+}
+
+// setDefaults fills in the fields Insert derives when they are not set
+func (e *HistoryEntry) setDefaults() {
+	e.Redact()
+	if e.Profile == "" {
+		e.Profile = DefaultProfile
+	}
+	if e.Program == "" {
+		e.Program = shellparse.Program(e.Command)
+	}
+	if e.UUID == "" {
+		e.UUID = newUUID()
+	}
+}
+
 // QueryFilters defines filters for querying history
 type QueryFilters struct {
-	Search   string // Text search in command
-	Cwd      string // Filter by directory
-	After    int64  // After timestamp
-	Before   int64  // Before timestamp
-	ExitCode *int   // Filter by exit code
-	Limit    int    // Max results
-	Offset   int    // Pagination offset
-	Distinct bool   // Only return unique commands (most recent entry for each)
+	Search      string       // Text search in command
+	Terms       []SearchTerm // Text searches that must all match, each in its own field
+	Command     string       // Exact command
+	Cwd         string       // Filter by directory
+	CwdUnder    string       // Filter by directory or any directory below it
+	After       int64        // After timestamp
+	Before      int64        // Before timestamp
+	AfterID     int64        // Only entries with a greater ID, i.e. saved later
+	UpToID      int64        // Only entries with this ID or a lower one
+	ExitCode    *int         // Filter by exit code
+	NotExitCode *int         // Leave out this exit code
+	Profile     string       // Filter by profile ("" = all profiles)
+	ExecTarget  string       // Filter by container/pod the command was exec'd in (substring match)
+	Program     string       // Filter by primary program, e.g. "git"
+	Limit       int          // Max results
+	Offset      int          // Pagination offset
+	Distinct    bool         // Only return unique commands (most recent entry for each)
+}
+
+// SearchField is a field a SearchTerm looks in
+type SearchField string
+
+// Searchable fields
+const (
+	FieldAny     SearchField = ""        // Any of the fields below
+	FieldCommand SearchField = "command" // The command line
+	FieldCwd     SearchField = "cwd"     // The working directory
+	FieldBranch  SearchField = "branch"  // The git branch
+	FieldNote    SearchField = "note"    // The note attached with amend
+)
+
+// searchColumns maps each field to the column it searches
+var searchColumns = map[SearchField]string{
+	FieldCommand: "command",
+	FieldCwd:     "cwd",
+	FieldBranch:  "git_branch",
+	FieldNote:    "note",
+}
+
+// searchFields lists the fields FieldAny searches, in order
+var searchFields = []SearchField{FieldCommand, FieldCwd, FieldBranch, FieldNote}
+
+// SearchTerm is a substring search in one field
+type SearchTerm struct {
+	Field         SearchField
+	Text          string
+	Negate        bool // Match entries that do not contain Text
+	CaseSensitive bool // Match Text's case exactly
+}
+
+// value returns the field of entry the term searches, "" for FieldAny
+func (t SearchTerm) value(entry *HistoryEntry) string {
+	switch t.Field {
+	case FieldCommand:
+		return entry.Command
+	case FieldCwd:
+		return entry.Cwd
+	case FieldBranch:
+		return entry.GitBranch
+	case FieldNote:
+		return entry.Note
+	}
+	return ""
+}
+
+// Matches reports whether entry contains the term's text in its field (or
+// not, if negated), the way Query does for ASCII text
+func (t SearchTerm) Matches(entry *HistoryEntry) bool {
+	return t.contains(entry) != t.Negate
+}
+
+func (t SearchTerm) contains(entry *HistoryEntry) bool {
+	fold := strings.ToLower
+	if t.CaseSensitive {
+		fold = func(s string) string { return s }
+	}
+
+	text := fold(t.Text)
+	if t.Field != FieldAny {
+		return strings.Contains(fold(t.value(entry)), text)
+	}
+
+	for _, field := range searchFields {
+		if strings.Contains(fold(SearchTerm{Field: field}.value(entry)), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// condition returns the SQL condition for the term and its arguments
+func (t SearchTerm) condition() (string, []interface{}) {
+	// COALESCE so a NULL column counts as empty, not unknown, under NOT.
+	// LIKE ignores ASCII case, instr matches it exactly.
+	match := func(column string) (string, interface{}) {
+		if t.CaseSensitive {
+			return "instr(COALESCE(" + column + ", ''), ?) > 0", t.Text
+		}
+		return "COALESCE(" + column + ", '') LIKE ? ESCAPE '\\'", likeContains(t.Text)
+	}
+
+	fields := searchFields
+	if t.Field != FieldAny {
+		fields = []SearchField{t.Field}
+	}
+
+	var matches []string
+	var args []interface{}
+	for _, field := range fields {
+		condition, arg := match(searchColumns[field])
+		matches = append(matches, condition)
+		args = append(args, arg)
+	}
+
+	condition := "(" + strings.Join(matches, " OR ") + ")"
+	if t.Negate {
+		condition = "NOT " + condition
+	}
+	return condition, args
+}
+
+// Span is the byte range [Start, End) of a match in a field's text
+type Span struct {
+	Start, End int
+}
+
+// Spans returns where the term matches text, the value of field. Negated
+// terms and terms for other fields match nowhere.
+func (t SearchTerm) Spans(field SearchField, text string) []Span {
+	if t.Negate || t.Text == "" || (t.Field != FieldAny && t.Field != field) {
+		return nil
+	}
+
+	// Offsets in the lowered text only hold if lowering kept every length
+	lower, needle := text, t.Text
+	if !t.CaseSensitive {
+		lower, needle = strings.ToLower(text), strings.ToLower(t.Text)
+		if len(lower) != len(text) {
+			return nil
+		}
+	}
+
+	var spans []Span
+	for start := 0; ; {
+		i := strings.Index(lower[start:], needle)
+		if i < 0 {
+			return spans
+		}
+		spans = append(spans, Span{Start: start + i, End: start + i + len(needle)})
+		start += i + len(needle)
+	}
+}
+
+// entryColumns lists the columns read by scanEntry, in scan order
+var entryColumns = []string{
+	"id", "timestamp", "command", "cwd", "exit_code", "hostname", "user",
+	"shell", "duration_ms", "git_branch", "hash", "session_id", "created_at", "profile",
+	"exec_runtime", "exec_target", "job_id", "note", "program", "approx_time",
+	"redacted", "signature", "uuid",
+}
+
+// selectColumns returns entryColumns for a SELECT list, qualified with alias if set
+func selectColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(entryColumns, ", ")
+	}
+
+	qualified := make([]string, len(entryColumns))
+	for i, col := range entryColumns {
+		qualified[i] = alias + "." + col
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEntry reads a row selected with selectColumns into a HistoryEntry
+func scanEntry(row rowScanner) (*HistoryEntry, error) {
+	entry := &HistoryEntry{}
+	var createdAt int64
+	var hash sql.NullString
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.Timestamp,
+		&entry.Command,
+		&entry.Cwd,
+		&entry.ExitCode,
+		&entry.Hostname,
+		&entry.User,
+		&entry.Shell,
+		&entry.DurationMs,
+		&entry.GitBranch,
+		&hash,
+		&entry.SessionID,
+		&createdAt,
+		&entry.Profile,
+		&entry.ExecRuntime,
+		&entry.ExecTarget,
+		&entry.JobID,
+		&entry.Note,
+		&entry.Program,
+		&entry.ApproxTime,
+		&entry.Redacted,
+		&entry.Signature,
+		&entry.UUID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash.Valid {
+		entry.Hash = hash.String
+	}
+
+	return entry, nil
+}
+
+// likeReplacer escapes the wildcards of LIKE, for ESCAPE '\'
+var likeReplacer = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likeContains returns the LIKE pattern matching text anywhere, taking %
+// and _ in it literally
+func likeContains(text string) string {
+	return "%" + likeReplacer.Replace(text) + "%"
+}
+
+// filterConditions builds the " AND ..." conditions shared by Query and DeleteByFilter
+func filterConditions(filters QueryFilters) (string, []interface{}) {
+	var conditions string
+	args := []interface{}{}
+
+	if filters.Search != "" {
+		conditions += " AND command LIKE ? ESCAPE '\\'"
+		args = append(args, likeContains(filters.Search))
+	}
+
+	for _, term := range filters.Terms {
+		condition, termArgs := term.condition()
+		conditions += " AND " + condition
+		args = append(args, termArgs...)
+	}
+
+	if filters.Command != "" {
+		conditions += " AND command = ?"
+		args = append(args, filters.Command)
+	}
+
+	if filters.Cwd != "" {
+		conditions += " AND cwd = ?"
+		args = append(args, filters.Cwd)
+	}
+
+	if filters.CwdUnder != "" {
+		// A prefix compare, so _ and % in the path are taken literally
+		dir := strings.TrimSuffix(filters.CwdUnder, "/") + "/"
+		conditions += " AND (cwd = ? OR substr(cwd, 1, ?) = ?)"
+		args = append(args, strings.TrimSuffix(filters.CwdUnder, "/"), len(dir), dir)
+	}
+
+	if filters.After > 0 {
+		conditions += " AND timestamp >= ?"
+		args = append(args, filters.After)
+	}
+
+	if filters.Before > 0 {
+		conditions += " AND timestamp <= ?"
+		args = append(args, filters.Before)
+	}
+
+	if filters.AfterID > 0 {
+		conditions += " AND id > ?"
+		args = append(args, filters.AfterID)
+	}
+
+	if filters.UpToID > 0 {
+		conditions += " AND id <= ?"
+		args = append(args, filters.UpToID)
+	}
+
+	if filters.ExitCode != nil {
+		conditions += " AND exit_code = ?"
+		args = append(args, *filters.ExitCode)
+	}
+
+	if filters.NotExitCode != nil {
+		conditions += " AND exit_code != ?"
+		args = append(args, *filters.NotExitCode)
+	}
+
+	if filters.Profile != "" {
+		conditions += " AND profile = ?"
+		args = append(args, filters.Profile)
+	}
+
+	if filters.ExecTarget != "" {
+		conditions += " AND exec_target LIKE ? ESCAPE '\\'"
+		args = append(args, likeContains(filters.ExecTarget))
+	}
+
+	if filters.Program != "" {
+		conditions += " AND program = ?"
+		args = append(args, filters.Program)
+	}
+
+	return conditions, args
 }
 
-// Insert adds a new history entry to the database
+// Insert adds a new history entry to the database and sets its ID
+// Entries without a profile are stored in DefaultProfile, and the program is
+// parsed from the command when not set. Secrets in the command are masked.
 func (db *DB) Insert(entry *HistoryEntry) error {
+	_, err := db.insert(entry, "")
+	return err
+}
+
+// insert adds entry with the given ON CONFLICT clause and sets its ID to
+// the row it inserted or updated. It reports false when the clause left the
+// table as it was.
+func (db *DB) insert(entry *HistoryEntry, onConflict string) (bool, error) {
+	if !db.redact(entry) {
+		return false, nil
+	}
+	entry.setDefaults()
+	if err := db.sign(entry); err != nil {
+		return false, err
+	}
+
 	query := `
 		INSERT INTO history (
 			timestamp, command, cwd, exit_code, hostname,
-			user, shell, duration_ms, git_branch, hash, session_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			user, shell, duration_ms, git_branch, hash, session_id, profile,
+			exec_runtime, exec_target, job_id, note, program, approx_time, redacted,
+			signature, uuid
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		` + onConflict + `
+		RETURNING id
 	`
 
-	_, err := db.conn.Exec(
+	err := db.execer().QueryRow(
 		query,
 		entry.Timestamp,
 		entry.Command,
@@ -49,91 +419,55 @@ func (db *DB) Insert(entry *HistoryEntry) error {
 		entry.GitBranch,
 		entry.Hash,
 		entry.SessionID,
-	)
+		entry.Profile,
+		entry.ExecRuntime,
+		entry.ExecTarget,
+		entry.JobID,
+		entry.Note,
+		entry.Program,
+		entry.ApproxTime,
+		entry.Redacted,
+		entry.Signature,
+		entry.UUID,
+	).Scan(&entry.ID)
 
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to insert entry: %w", err)
+		return false, fmt.Errorf("failed to insert entry: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
 
 // Query retrieves history entries matching the given filters
 func (db *DB) Query(filters QueryFilters) ([]*HistoryEntry, error) {
-	var query string
-	args := []interface{}{}
+	conditions, args := filterConditions(filters)
 
-	if filters.Distinct {
+	var query string
+	if filters.indexedDistinct() {
+		// Only the profile is filtered, one row per command is enough
+		latest, indexArgs := commandIndexQuery(filters.Profile)
+		query = `SELECT ` + selectColumns("h") + `
+		FROM (` + latest + `) latest
+		JOIN history h ON h.id = latest.last_id
+		ORDER BY h.timestamp DESC`
+		args = indexArgs
+	} else if filters.Distinct {
 		// Use subquery to get only unique commands (most recent entry for each)
-		query = `SELECT h.id, h.timestamp, h.command, h.cwd, h.exit_code, h.hostname, h.user, h.shell, h.duration_ms, h.git_branch, h.hash, h.session_id, h.created_at
+		query = `SELECT ` + selectColumns("h") + `
 		FROM history h
 		INNER JOIN (
 			SELECT command, MAX(timestamp) as max_ts, MAX(id) as max_id
 			FROM history
-			WHERE 1=1`
-
-		// Apply filters to subquery
-		if filters.Search != "" {
-			query += " AND command LIKE ?"
-			args = append(args, "%"+filters.Search+"%")
-		}
-
-		if filters.Cwd != "" {
-			query += " AND cwd = ?"
-			args = append(args, filters.Cwd)
-		}
-
-		if filters.After > 0 {
-			query += " AND timestamp >= ?"
-			args = append(args, filters.After)
-		}
-
-		if filters.Before > 0 {
-			query += " AND timestamp <= ?"
-			args = append(args, filters.Before)
-		}
-
-		if filters.ExitCode != nil {
-			query += " AND exit_code = ?"
-			args = append(args, *filters.ExitCode)
-		}
-
-		query += `
+			WHERE 1=1` + conditions + `
 			GROUP BY command
 		) latest ON h.command = latest.command AND h.timestamp = latest.max_ts AND h.id = latest.max_id
 		ORDER BY h.timestamp DESC`
 	} else {
-		// Standard query - return all entries
-		query = "SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, git_branch, hash, session_id, created_at FROM history WHERE 1=1"
-
-		// Build WHERE clause
-		if filters.Search != "" {
-			query += " AND command LIKE ?"
-			args = append(args, "%"+filters.Search+"%")
-		}
-
-		if filters.Cwd != "" {
-			query += " AND cwd = ?"
-			args = append(args, filters.Cwd)
-		}
-
-		if filters.After > 0 {
-			query += " AND timestamp >= ?"
-			args = append(args, filters.After)
-		}
-
-		if filters.Before > 0 {
-			query += " AND timestamp <= ?"
-			args = append(args, filters.Before)
-		}
-
-		if filters.ExitCode != nil {
-			query += " AND exit_code = ?"
-			args = append(args, *filters.ExitCode)
-		}
-
-		// Order by timestamp descending (most recent first)
-		query += " ORDER BY timestamp DESC"
+		// Standard query - return all entries, most recent first
+		query = "SELECT " + selectColumns("") + " FROM history WHERE 1=1" + conditions + " ORDER BY timestamp DESC"
 	}
 
 	// Pagination (applies to both queries)
@@ -157,33 +491,10 @@ func (db *DB) Query(filters QueryFilters) ([]*HistoryEntry, error) {
 
 	var entries []*HistoryEntry
 	for rows.Next() {
-		entry := &HistoryEntry{}
-		var createdAt int64
-		var hash sql.NullString
-
-		err := rows.Scan(
-			&entry.ID,
-			&entry.Timestamp,
-			&entry.Command,
-			&entry.Cwd,
-			&entry.ExitCode,
-			&entry.Hostname,
-			&entry.User,
-			&entry.Shell,
-			&entry.DurationMs,
-			&entry.GitBranch,
-			&hash,
-			&entry.SessionID,
-			&createdAt,
-		)
+		entry, err := scanEntry(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entry: %w", err)
 		}
-
-		if hash.Valid {
-			entry.Hash = hash.String
-		}
-
 		entries = append(entries, entry)
 	}
 
@@ -196,28 +507,9 @@ func (db *DB) Query(filters QueryFilters) ([]*HistoryEntry, error) {
 
 // GetByID retrieves a single history entry by ID
 func (db *DB) GetByID(id int64) (*HistoryEntry, error) {
-	query := "SELECT id, timestamp, command, cwd, exit_code, hostname, user, shell, duration_ms, git_branch, hash, session_id, created_at FROM history WHERE id = ?"
-
-	entry := &HistoryEntry{}
-	var createdAt int64
-	var hash sql.NullString
-
-	err := db.conn.QueryRow(query, id).Scan(
-		&entry.ID,
-		&entry.Timestamp,
-		&entry.Command,
-		&entry.Cwd,
-		&entry.ExitCode,
-		&entry.Hostname,
-		&entry.User,
-		&entry.Shell,
-		&entry.DurationMs,
-		&entry.GitBranch,
-		&hash,
-		&entry.SessionID,
-		&createdAt,
-	)
+	query := "SELECT " + selectColumns("") + " FROM history WHERE id = ?"
 
+	entry, err := scanEntry(db.conn.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("entry not found")
 	}
@@ -225,8 +517,20 @@ func (db *DB) GetByID(id int64) (*HistoryEntry, error) {
 		return nil, fmt.Errorf("failed to get entry: %w", err)
 	}
 
-	if hash.Valid {
-		entry.Hash = hash.String
+	return entry, nil
+}
+
+// GetByJob retrieves the entry of a background job started in a session
+// PIDs can be reused, so the most recent matching entry wins.
+func (db *DB) GetByJob(sessionID string, jobID int64) (*HistoryEntry, error) {
+	query := "SELECT " + selectColumns("") + " FROM history WHERE session_id = ? AND job_id = ? ORDER BY timestamp DESC, id DESC LIMIT 1"
+
+	entry, err := scanEntry(db.conn.QueryRow(query, sessionID, jobID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("entry not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
 	}
 
 	return entry, nil
@@ -242,6 +546,17 @@ func (db *DB) Count() (int64, error) {
 	return count, nil
 }
 
+// LastID returns the ID of the entry saved last, 0 for an empty database.
+// IDs are never reused, so entries with a greater ID were saved after it.
+func (db *DB) LastID() (int64, error) {
+	var id int64
+	err := db.conn.QueryRow("SELECT COALESCE(MAX(id), 0) FROM history").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last entry ID: %w", err)
+	}
+	return id, nil
+}
+
 // Delete removes a history entry by ID
 func (db *DB) Delete(id int64) error {
 	result, err := db.conn.Exec("DELETE FROM history WHERE id = ?", id)
@@ -261,36 +576,40 @@ func (db *DB) Delete(id int64) error {
 	return nil
 }
 
-// DeleteByFilter removes history entries matching filters
-func (db *DB) DeleteByFilter(filters QueryFilters) (int64, error) {
-	query := "DELETE FROM history WHERE 1=1"
-	args := []interface{}{}
-
-	// Build WHERE clause (same as Query)
-	if filters.Search != "" {
-		query += " AND command LIKE ?"
-		args = append(args, "%"+filters.Search+"%")
-	}
-
-	if filters.Cwd != "" {
-		query += " AND cwd = ?"
-		args = append(args, filters.Cwd)
+// DeleteEntries removes the entries with the given ids and returns how many
+// there were
+func (db *DB) DeleteEntries(ids []int64) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	if filters.After > 0 {
-		query += " AND timestamp >= ?"
-		args = append(args, filters.After)
+	var deleted int64
+	for _, id := range ids {
+		result, err := tx.Exec("DELETE FROM history WHERE id = ?", id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete entry: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		deleted += n
 	}
 
-	if filters.Before > 0 {
-		query += " AND timestamp <= ?"
-		args = append(args, filters.Before)
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
 	}
+	return deleted, nil
+}
 
-	if filters.ExitCode != nil {
-		query += " AND exit_code = ?"
-		args = append(args, *filters.ExitCode)
-	}
+// DeleteByFilter removes history entries matching filters
+func (db *DB) DeleteByFilter(filters QueryFilters) (int64, error) {
+	conditions, args := filterConditions(filters)
+	query := "DELETE FROM history WHERE 1=1" + conditions
 
 	result, err := db.conn.Exec(query, args...)
 	if err != nil {
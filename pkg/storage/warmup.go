@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// warmUpChunk is how much of the database WarmUp reads at a time, between
+// checks for cancellation
+const warmUpChunk = 1 << 20
+
+// WarmUp reads the database and its write-ahead log through once, so the
+// operating system has them in its page cache before the first search.
+// Every search is its own fh process with its own connection and prepared
+// statements, the page cache is what carries over from a long-running one
+// like the daemon. On a spinning disk or a network home directory it spares
+// the first Ctrl-R of the day the slow reads. It returns how many bytes it
+// read, stopping early when ctx is done.
+func (db *DB) WarmUp(ctx context.Context) (int64, error) {
+	if IsMemoryPath(db.path) {
+		return 0, nil
+	}
+
+	var total int64
+	for _, path := range []string{db.path, db.path + "-wal"} {
+		n, err := warmUpFile(ctx, path, db.held)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// heldFiles keeps files of the database open until it is closed. Closing
+// any descriptor of a file drops every POSIX lock the process has on it,
+// SQLite's included, and another process closing the database would then
+// take itself for the last one and delete the write-ahead log in use.
+type heldFiles struct {
+	mu     sync.Mutex
+	files  []*os.File
+	closed bool
+}
+
+// hold keeps file open until close, or closes it now if that was called
+func (h *heldFiles) hold(file *os.File) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		_ = file.Close()
+		return
+	}
+	h.files = append(h.files, file)
+}
+
+// close closes the held files, once SQLite has released its locks
+func (h *heldFiles) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, file := range h.files {
+		_ = file.Close()
+	}
+	h.files, h.closed = nil, true
+}
+
+// warmUpFile reads path sequentially, which slow disks do best, and
+// discards what it read. A file that doesn't exist is nothing to warm.
+// The file is left to held rather than closed.
+func warmUpFile(ctx context.Context, path string, held *heldFiles) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer held.hold(file)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := io.CopyN(io.Discard, file, warmUpChunk)
+		total += n
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
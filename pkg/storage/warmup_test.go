@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUp(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for _, command := range []string{"git status", "make test", "make deploy"} {
+		require.NoError(t, db.Insert(createTestEntry(t, command, 1700000000)))
+	}
+
+	size := func(path string) int64 {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return info.Size()
+	}
+	want := size(db.path) + size(db.path+"-wal")
+
+	read, err := db.WarmUp(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, read, "the database and its log, whole")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	read, err = db.WarmUp(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, read)
+}
+
+func TestWarmUp_Memory(t *testing.T) {
+	db, err := Open(MemoryPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	read, err := db.WarmUp(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, read)
+}
+
+func TestWarmUp_HoldsFiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	require.NoError(t, db.Insert(createTestEntry(t, "git status", 1700000000)))
+	_, err := db.WarmUp(context.Background())
+	require.NoError(t, err)
+
+	// Closing them would drop the process's locks on the database
+	files := db.held.files
+	require.Len(t, files, 2, "the database and its log")
+	require.NoError(t, db.Close())
+	for _, file := range files {
+		assert.ErrorIs(t, file.Close(), os.ErrClosed, "closed with the database")
+	}
+
+	_, err = db.WarmUp(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, db.held.files, "nothing held after Close")
+}
@@ -0,0 +1,298 @@
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spideyz0r/fh/pkg/shellparse"
+	"github.com/spideyz0r/fh/pkg/storage"
+)
+
+// Injectable errors with the text the SQLite driver returns. The driver's
+// error type only exists in cgo builds, so errors are told apart by text.
+var (
+	// ErrBusy mimics SQLITE_BUSY
+	ErrBusy = errors.New("database is locked")
+
+	// ErrConstraint mimics a UNIQUE constraint violation on the hash column
+	ErrConstraint = errors.New("UNIQUE constraint failed: history.hash")
+)
+
+// Operation names used for failure injection
+const (
+	OpInsert  = "insert"
+	OpQuery   = "query"
+	OpGetByID = "get_by_id"
+	OpCount   = "count"
+	OpDelete  = "delete"
+	OpClose   = "close"
+)
+
+// FakeStore is an in-memory storage.Store double for unit tests.
+// It mirrors the behavior of storage.DB closely enough for callers of the
+// interface, and lets tests inject failures per operation.
+type FakeStore struct {
+	mu       sync.Mutex
+	entries  []*storage.HistoryEntry
+	nextID   int64
+	closed   bool
+	failures map[string][]error
+	calls    map[string]int
+}
+
+// NewFakeStore creates an empty FakeStore
+func NewFakeStore() *FakeStore {
+	return &FakeStore{
+		nextID:   1,
+		failures: make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// Compile-time check that FakeStore implements storage.Store
+var _ storage.Store = (*FakeStore)(nil)
+
+// FailNext queues errors to be returned by the next calls to op, in order.
+// Once the queue is drained the operation behaves normally again.
+func (s *FakeStore) FailNext(op string, errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[op] = append(s.failures[op], errs...)
+}
+
+// Calls returns how many times op has been called (including failed calls)
+func (s *FakeStore) Calls(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[op]
+}
+
+// Entries returns a copy of all stored entries in insertion order
+func (s *FakeStore) Entries() []*storage.HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*storage.HistoryEntry, len(s.entries))
+	for i, entry := range s.entries {
+		copied := *entry
+		result[i] = &copied
+	}
+	return result
+}
+
+// begin records a call to op and returns the injected error, if any.
+// Must be called with s.mu held.
+func (s *FakeStore) begin(op string) error {
+	s.calls[op]++
+
+	if s.closed && op != OpClose {
+		return fmt.Errorf("sql: database is closed")
+	}
+
+	if queue := s.failures[op]; len(queue) > 0 {
+		err := queue[0]
+		s.failures[op] = queue[1:]
+		return err
+	}
+	return nil
+}
+
+// Insert adds a new history entry, enforcing hash uniqueness like the real schema
+// and defaulting the profile and program the same way
+func (s *FakeStore) Insert(entry *storage.HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpInsert); err != nil {
+		return fmt.Errorf("failed to insert entry: %w", err)
+	}
+
+	if entry.Hash != "" {
+		for _, existing := range s.entries {
+			if existing.Hash == entry.Hash {
+				return fmt.Errorf("failed to insert entry: %w", ErrConstraint)
+			}
+		}
+	}
+
+	if entry.Profile == "" {
+		entry.Profile = storage.DefaultProfile
+	}
+	if entry.Program == "" {
+		entry.Program = shellparse.Program(entry.Command)
+	}
+
+	stored := *entry
+	stored.ID = s.nextID
+	s.nextID++
+	s.entries = append(s.entries, &stored)
+
+	return nil
+}
+
+// Query retrieves entries matching the filters, most recent first
+func (s *FakeStore) Query(filters storage.QueryFilters) ([]*storage.HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpQuery); err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+
+	var matched []*storage.HistoryEntry
+	for _, entry := range s.entries {
+		if matchesFilters(entry, filters) {
+			copied := *entry
+			matched = append(matched, &copied)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Timestamp != matched[j].Timestamp {
+			return matched[i].Timestamp > matched[j].Timestamp
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	if filters.Distinct {
+		seen := make(map[string]bool)
+		unique := matched[:0]
+		for _, entry := range matched {
+			if seen[entry.Command] {
+				continue
+			}
+			seen[entry.Command] = true
+			unique = append(unique, entry)
+		}
+		matched = unique
+	}
+
+	if filters.Offset > 0 {
+		if filters.Offset >= len(matched) {
+			return nil, nil
+		}
+		matched = matched[filters.Offset:]
+	}
+
+	if filters.Limit > 0 && len(matched) > filters.Limit {
+		matched = matched[:filters.Limit]
+	}
+
+	return matched, nil
+}
+
+// matchesFilters applies QueryFilters the same way the SQL WHERE clause does
+func matchesFilters(entry *storage.HistoryEntry, filters storage.QueryFilters) bool {
+	// SQLite LIKE is case-insensitive for ASCII
+	if filters.Search != "" && !strings.Contains(strings.ToLower(entry.Command), strings.ToLower(filters.Search)) {
+		return false
+	}
+	for _, term := range filters.Terms {
+		if !term.Matches(entry) {
+			return false
+		}
+	}
+	if filters.Command != "" && entry.Command != filters.Command {
+		return false
+	}
+	if under := strings.TrimSuffix(filters.CwdUnder, "/"); filters.CwdUnder != "" && entry.Cwd != under && !strings.HasPrefix(entry.Cwd, under+"/") {
+		return false
+	}
+	if filters.Cwd != "" && entry.Cwd != filters.Cwd {
+		return false
+	}
+	if filters.After > 0 && entry.Timestamp < filters.After {
+		return false
+	}
+	if filters.Before > 0 && entry.Timestamp > filters.Before {
+		return false
+	}
+	if filters.AfterID > 0 && entry.ID <= filters.AfterID {
+		return false
+	}
+	if filters.UpToID > 0 && entry.ID > filters.UpToID {
+		return false
+	}
+	if filters.ExitCode != nil && entry.ExitCode != *filters.ExitCode {
+		return false
+	}
+	if filters.NotExitCode != nil && entry.ExitCode == *filters.NotExitCode {
+		return false
+	}
+	if filters.Profile != "" && entry.Profile != filters.Profile {
+		return false
+	}
+	if filters.ExecTarget != "" && !strings.Contains(strings.ToLower(entry.ExecTarget), strings.ToLower(filters.ExecTarget)) {
+		return false
+	}
+	if filters.Program != "" && entry.Program != filters.Program {
+		return false
+	}
+	return true
+}
+
+// GetByID retrieves a single entry by ID
+func (s *FakeStore) GetByID(id int64) (*storage.HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpGetByID); err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	for _, entry := range s.entries {
+		if entry.ID == id {
+			copied := *entry
+			return &copied, nil
+		}
+	}
+
+	return nil, fmt.Errorf("entry not found")
+}
+
+// Count returns the number of stored entries
+func (s *FakeStore) Count() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpCount); err != nil {
+		return 0, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	return int64(len(s.entries)), nil
+}
+
+// Delete removes an entry by ID
+func (s *FakeStore) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpDelete); err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+
+	for i, entry := range s.entries {
+		if entry.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entry not found")
+}
+
+// Close marks the store as closed; further calls return an error
+func (s *FakeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(OpClose); err != nil {
+		return err
+	}
+
+	s.closed = true
+	return nil
+}
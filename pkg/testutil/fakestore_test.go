@@ -0,0 +1,147 @@
+package testutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spideyz0r/fh/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeStore_InsertAndQuery(t *testing.T) {
+	store := NewFakeStore()
+
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Timestamp: 100, Command: "git status", Cwd: "/a"}))
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Timestamp: 300, Command: "docker ps", Cwd: "/b", ExitCode: 1}))
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Timestamp: 200, Command: "git status", Cwd: "/a"}))
+
+	t.Run("most recent first", func(t *testing.T) {
+		entries, err := store.Query(storage.QueryFilters{})
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, int64(300), entries[0].Timestamp)
+		assert.Equal(t, int64(100), entries[2].Timestamp)
+	})
+
+	t.Run("search is case insensitive", func(t *testing.T) {
+		entries, err := store.Query(storage.QueryFilters{Search: "GIT"})
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("distinct keeps most recent", func(t *testing.T) {
+		entries, err := store.Query(storage.QueryFilters{Distinct: true})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "git status", entries[1].Command)
+		assert.Equal(t, int64(200), entries[1].Timestamp)
+	})
+
+	t.Run("exit code and limit", func(t *testing.T) {
+		code := 1
+		entries, err := store.Query(storage.QueryFilters{ExitCode: &code, Limit: 5})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "docker ps", entries[0].Command)
+	})
+
+	t.Run("offset past end", func(t *testing.T) {
+		entries, err := store.Query(storage.QueryFilters{Offset: 10})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestFakeStore_Profiles(t *testing.T) {
+	store := NewFakeStore()
+
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls"}))
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "kubectl", Profile: "work"}))
+
+	entries, err := store.Query(storage.QueryFilters{Profile: "work"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "kubectl", entries[0].Command)
+
+	entries, err = store.Query(storage.QueryFilters{Profile: storage.DefaultProfile})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ls", entries[0].Command)
+}
+
+func TestFakeStore_ExecTarget(t *testing.T) {
+	store := NewFakeStore()
+
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls"}))
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "psql", ExecRuntime: "kubectl", ExecTarget: "prod/API-7d9f"}))
+
+	entries, err := store.Query(storage.QueryFilters{ExecTarget: "api"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "psql", entries[0].Command)
+}
+
+func TestFakeStore_Program(t *testing.T) {
+	store := NewFakeStore()
+
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "sudo git pull"}))
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls | grep go"}))
+
+	entries, err := store.Query(storage.QueryFilters{Program: "git"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "sudo git pull", entries[0].Command)
+}
+
+func TestFakeStore_HashConstraint(t *testing.T) {
+	store := NewFakeStore()
+
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls", Hash: "h1"}))
+	err := store.Insert(&storage.HistoryEntry{Command: "ls", Hash: "h1"})
+	require.Error(t, err)
+
+	assert.True(t, errors.Is(err, ErrConstraint))
+	assert.Contains(t, err.Error(), "UNIQUE constraint failed")
+}
+
+func TestFakeStore_FailureInjection(t *testing.T) {
+	store := NewFakeStore()
+	store.FailNext(OpInsert, ErrBusy)
+
+	err := store.Insert(&storage.HistoryEntry{Command: "ls"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBusy))
+	assert.True(t, storage.IsLocked(err))
+
+	// Queue is drained, the next insert succeeds
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls"}))
+	assert.Equal(t, 2, store.Calls(OpInsert))
+
+	count, err := store.Count()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestFakeStore_GetByIDAndDelete(t *testing.T) {
+	store := NewFakeStore()
+	require.NoError(t, store.Insert(&storage.HistoryEntry{Command: "ls"}))
+
+	entry, err := store.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "ls", entry.Command)
+
+	require.NoError(t, store.Delete(1))
+	assert.Error(t, store.Delete(1))
+
+	_, err = store.GetByID(1)
+	assert.Error(t, err)
+}
+
+func TestFakeStore_Closed(t *testing.T) {
+	store := NewFakeStore()
+	require.NoError(t, store.Close())
+
+	_, err := store.Count()
+	assert.Error(t, err)
+}
@@ -65,8 +65,22 @@ func AssertEqual(t *testing.T, got, want interface{}) {
 	}
 }
 
-// NewTestDB creates a test database in a temporary directory
-func NewTestDB(t *testing.T) *storage.DB {
+// NewTestDB creates an in-memory test database
+// Each call returns an isolated database that disappears when closed
+func NewTestDB(t testing.TB) *storage.DB {
+	t.Helper()
+
+	db, err := storage.Open(storage.MemoryPath)
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+
+	return db
+}
+
+// NewTestFileDB creates a test database file in a temporary directory
+// Use this when a test needs the on-disk behavior (WAL files, reopening)
+func NewTestFileDB(t testing.TB) *storage.DB {
 	t.Helper()
 
 	tempDir := t.TempDir()
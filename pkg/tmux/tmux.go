@@ -0,0 +1,42 @@
+// Package tmux hands text to a running tmux server: into its paste buffer
+// or typed into a pane, so a command found in one pane can be run in another.
+package tmux
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultPane is the pane SendKeys types into without a target: the
+// previously active pane of the current window
+const DefaultPane = "{last}"
+
+// run is replaced in tests
+var run = func(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("tmux", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("tmux %s: %s", args[0], msg)
+		}
+		return fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// SetBuffer puts text in the tmux paste buffer, pasted with prefix + ]
+func SetBuffer(text string) error {
+	return run("set-buffer", "--", text)
+}
+
+// SendKeys types text into the target pane without pressing enter, so it
+// can be reviewed before it runs. An empty target means DefaultPane.
+func SendKeys(target, text string) error {
+	if target == "" {
+		target = DefaultPane
+	}
+	return run("send-keys", "-t", target, "-l", "--", text)
+}
@@ -0,0 +1,40 @@
+package tmux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubRun(t *testing.T) *[][]string {
+	t.Helper()
+	orig := run
+	t.Cleanup(func() { run = orig })
+
+	var calls [][]string
+	run = func(args ...string) error {
+		calls = append(calls, args)
+		return nil
+	}
+	return &calls
+}
+
+func TestSetBuffer(t *testing.T) {
+	calls := stubRun(t)
+
+	require.NoError(t, SetBuffer("-n 'x'"))
+	assert.Equal(t, [][]string{{"set-buffer", "--", "-n 'x'"}}, *calls)
+}
+
+func TestSendKeys(t *testing.T) {
+	calls := stubRun(t)
+
+	require.NoError(t, SendKeys("", "make deploy"))
+	require.NoError(t, SendKeys("server:1.0", "Enter"))
+	assert.Equal(t, [][]string{
+		{"send-keys", "-t", "{last}", "-l", "--", "make deploy"},
+		// -l types key names like Enter as text
+		{"send-keys", "-t", "server:1.0", "-l", "--", "Enter"},
+	}, *calls)
+}
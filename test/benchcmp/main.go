@@ -0,0 +1,143 @@
+// Command benchcmp compares two `go test -bench` outputs and exits non-zero
+// when any benchmark got slower than the allowed threshold.
+//
+// Usage: go run ./test/benchcmp [-threshold 20] baseline.txt current.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 20, "maximum allowed slowdown in percent")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: benchcmp [-threshold PERCENT] BASELINE CURRENT")
+		os.Exit(2)
+	}
+
+	baseline, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	current, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	regressions := 0
+	fmt.Printf("%-50s %14s %14s %9s\n", "benchmark", "old ns/op", "new ns/op", "delta")
+	for _, name := range names {
+		old, ok := baseline[name]
+		if !ok {
+			fmt.Printf("%-50s %14s %14.0f %9s\n", name, "-", current[name], "new")
+			continue
+		}
+
+		delta := (current[name] - old) / old * 100
+		marker := ""
+		if delta > *threshold {
+			marker = "  REGRESSION"
+			regressions++
+		}
+		fmt.Printf("%-50s %14.0f %14.0f %+8.1f%%%s\n", name, old, current[name], delta, marker)
+	}
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d benchmark(s) regressed by more than %.0f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+// parseFile reads benchmark results, averaging ns/op over repeated runs (-count)
+func parseFile(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	pkg := ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		}
+
+		name, nsPerOp, ok := parseLine(line)
+		if !ok {
+			continue
+		}
+
+		// Qualify with the package so identically named benchmarks don't collide
+		key := name
+		if pkg != "" {
+			key = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+		}
+		sums[key] += nsPerOp
+		counts[key]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	result := make(map[string]float64, len(sums))
+	for key, sum := range sums {
+		result[key] = sum / float64(counts[key])
+	}
+	return result, nil
+}
+
+// parseLine extracts the name and ns/op from a line like
+// "BenchmarkInsert-8   1000   167932 ns/op"
+func parseLine(line string) (string, float64, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return "", 0, false
+	}
+
+	for i := 2; i < len(fields); i++ {
+		if fields[i] != "ns/op" {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[i-1], 64)
+		if err != nil || value <= 0 {
+			return "", 0, false
+		}
+		return stripProcs(fields[0]), value, true
+	}
+	return "", 0, false
+}
+
+// stripProcs removes the -GOMAXPROCS suffix so results compare across machines
+func stripProcs(name string) string {
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[idx+1:]); err != nil {
+		return name
+	}
+	return name[:idx]
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -24,18 +25,18 @@ func TestShellHookGeneration(t *testing.T) {
 		{
 			name:      "bash hook",
 			shellType: capture.ShellBash,
-			wantFuncs: []string{"__fh_save", "__fh_widget", "PROMPT_COMMAND", "bind"},
+			wantFuncs: []string{"__fh_save", "__fh_widget", "PROMPT_COMMAND", "bind", "FH_PROFILE"},
 		},
 		{
 			name:      "zsh hook",
 			shellType: capture.ShellZsh,
-			wantFuncs: []string{"__fh_save", "__fh_widget", "precmd_functions", "bindkey"},
+			wantFuncs: []string{"__fh_save", "__fh_widget", "precmd_functions", "bindkey", "FH_PROFILE"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			content, err := capture.GetHookContent(tt.shellType, "ctrl-r")
+			content, err := capture.GetHookContent(tt.shellType, "ctrl-r", "")
 			require.NoError(t, err)
 			require.NotEmpty(t, content)
 
@@ -169,7 +170,7 @@ func TestSaveWithSpecialCharacters(t *testing.T) {
 	}{
 		{"single quotes", "echo 'test'"},
 		{"double quotes", `echo "test"`},
-		{"pipes", "ls | grep test"},
+		{"pipes", "cat log | grep test"},
 		{"redirects", "echo test > /tmp/file"},
 		{"ampersand", "echo test && echo done"},
 		{"semicolon", "echo test; echo done"},
@@ -255,6 +256,102 @@ func TestRapidSaves(t *testing.T) {
 	t.Logf("Saved %d/20 rapid commands", len(entries))
 }
 
+// TestKilledWritesKeepDatabaseIntact kills fh with SIGKILL in the middle of
+// imports and saves, then checks the database is intact and still writable
+func TestKilledWritesKeepDatabaseIntact(t *testing.T) {
+	tempDir := t.TempDir()
+	fhBinary := buildFhBinary(t)
+	env := []string{
+		"HOME=" + tempDir,
+		"SHELL=/bin/bash",
+		"PATH=" + os.Getenv("PATH"),
+	}
+
+	initCmd := exec.Command(fhBinary, "--init")
+	initCmd.Env = env
+	_, err := initCmd.CombinedOutput()
+	require.NoError(t, err)
+
+	configYAML := "storage:\n  durability: full\n  debounce_secs: 0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".fh", "config.yaml"), []byte(configYAML), 0644))
+
+	// Large enough that the import is still writing when it gets killed
+	var lines strings.Builder
+	for i := 0; i < 20000; i++ {
+		lines.WriteString("echo crash " + strconv.Itoa(i) + "\n")
+	}
+	importFile := filepath.Join(tempDir, "import.txt")
+	require.NoError(t, os.WriteFile(importFile, []byte(lines.String()), 0644))
+
+	// Committed before anything gets killed, so it must survive
+	beforeCmd := exec.Command(fhBinary, "--save", "--cmd", "before crash")
+	beforeCmd.Env = env
+	output, err := beforeCmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	for round := 0; round < 3; round++ {
+		var procs []*exec.Cmd
+
+		importCmd := exec.Command(fhBinary, "--import", "--format", "text", "--input", importFile)
+		importCmd.Env = env
+		require.NoError(t, importCmd.Start())
+		procs = append(procs, importCmd)
+
+		for i := 0; i < 10; i++ {
+			saveCmd := exec.Command(fhBinary, "--save", "--cmd", "killed save "+strconv.Itoa(round*10+i))
+			saveCmd.Env = env
+			require.NoError(t, saveCmd.Start())
+			procs = append(procs, saveCmd)
+		}
+
+		time.Sleep(time.Duration(300+round*100) * time.Millisecond)
+		for _, p := range procs {
+			_ = p.Process.Kill()
+			_ = p.Wait()
+		}
+	}
+
+	db, err := storage.Open(filepath.Join(tempDir, ".fh", "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.QueryContext(t.Context(), "PRAGMA integrity_check")
+	require.NoError(t, err)
+	var results []string
+	for rows.Next() {
+		var result string
+		require.NoError(t, rows.Scan(&result))
+		results = append(results, result)
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, []string{"ok"}, results)
+
+	// Imports were cut off part way, and every committed row is complete
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	assert.Less(t, len(entries), 3*20000)
+	for _, e := range entries {
+		assert.NotEmpty(t, e.Command)
+		assert.NotZero(t, e.Timestamp)
+	}
+	t.Logf("%d entries survived the kills", len(entries))
+
+	before, err := db.Query(storage.QueryFilters{Search: "before crash"})
+	require.NoError(t, err)
+	assert.Len(t, before, 1)
+
+	// And the database still takes new writes
+	saveCmd := exec.Command(fhBinary, "--save", "--cmd", "after crash")
+	saveCmd.Env = env
+	output, err = saveCmd.CombinedOutput()
+	require.NoError(t, err, "save after crash should succeed: %s", output)
+
+	after, err := db.Query(storage.QueryFilters{Search: "after crash"})
+	require.NoError(t, err)
+	assert.Len(t, after, 1)
+}
+
 // TestMetadataCapture tests that metadata is captured correctly
 func TestMetadataCapture(t *testing.T) {
 	tempDir := t.TempDir()
@@ -304,6 +401,141 @@ func TestMetadataCapture(t *testing.T) {
 	assert.Greater(t, entry.Timestamp, int64(0), "should have timestamp")
 }
 
+func TestProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	fhBinary := buildFhBinary(t)
+
+	run := func(env []string, args ...string) string {
+		cmd := exec.Command(fhBinary, args...)
+		cmd.Env = append([]string{
+			"HOME=" + tempDir,
+			"PATH=" + os.Getenv("PATH"),
+		}, env...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "fh %v should succeed: %s", args, output)
+		return string(output)
+	}
+
+	// Saved to the default profile
+	run(nil, "--save", "--cmd", "echo personal")
+
+	// Switch persistently, then save
+	run(nil, "--profile", "work")
+	assert.Equal(t, "work\n", run(nil, "--profile"))
+	run(nil, "--save", "--cmd", "kubectl get pods")
+
+	// FH_PROFILE overrides the persisted selection
+	run([]string{"FH_PROFILE=oncall"}, "--save", "--cmd", "pagerduty ack")
+
+	db, err := storage.Open(filepath.Join(tempDir, ".fh", "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for profile, command := range map[string]string{
+		storage.DefaultProfile: "echo personal",
+		"work":                 "kubectl get pods",
+		"oncall":               "pagerduty ack",
+	} {
+		entries, err := db.Query(storage.QueryFilters{Profile: profile})
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "profile %s", profile)
+		assert.Equal(t, command, entries[0].Command)
+	}
+
+	// Export is scoped to the active profile unless --all-profiles is given
+	assert.Equal(t, "kubectl get pods\n", run(nil, "--export"))
+	assert.Len(t, strings.Split(strings.TrimSpace(run(nil, "--export", "--all-profiles")), "\n"), 3)
+}
+
+func TestProfileDatabases(t *testing.T) {
+	tempDir := t.TempDir()
+	fhBinary := buildFhBinary(t)
+
+	workDB := filepath.Join(tempDir, "work.db")
+	configYAML := "profiles:\n  work: " + workDB + "\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, ".fh"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".fh", "config.yaml"), []byte(configYAML), 0644))
+
+	run := func(env []string, args ...string) string {
+		cmd := exec.Command(fhBinary, args...)
+		cmd.Env = append([]string{
+			"HOME=" + tempDir,
+			"PATH=" + os.Getenv("PATH"),
+		}, env...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "fh %v should succeed: %s", args, output)
+		return string(output)
+	}
+
+	run(nil, "--save", "--cmd", "echo home")
+	run([]string{"FH_PROFILE=work"}, "--save", "--cmd", "terraform plan")
+
+	// The work profile has its own database file
+	db, err := storage.Open(workDB)
+	require.NoError(t, err)
+	defer db.Close()
+
+	entries, err := db.Query(storage.QueryFilters{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "terraform plan", entries[0].Command)
+
+	// --profile picks the database for export and stats
+	assert.Equal(t, "terraform plan\n", run(nil, "--export", "--profile", "work"))
+	assert.Equal(t, "echo home\n", run(nil, "--export"))
+	assert.Contains(t, run(nil, "--stats", "--profile", "work"), "Total Commands:   1")
+}
+
+func TestBackgroundJobAmend(t *testing.T) {
+	tempDir := t.TempDir()
+	fhBinary := buildFhBinary(t)
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(fhBinary, args...)
+		cmd.Env = []string{
+			"HOME=" + tempDir,
+			"PATH=" + os.Getenv("PATH"),
+			"FH_SESSION=1234-1700000000",
+		}
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	// The hook saves a backgrounded command with the job's PID...
+	output, err := run("--save", "--cmd", "make test &", "--job", "4321")
+	require.NoError(t, err, output)
+
+	// ...and amends it once the job is gone
+	output, err = run("--amend", "--exit-code", "2", "--duration", "1500", "1234-1700000000", "4321")
+	require.NoError(t, err, output)
+
+	db, err := storage.Open(filepath.Join(tempDir, ".fh", "history.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	entry, err := db.GetByJob("1234-1700000000", 4321)
+	require.NoError(t, err)
+	assert.Equal(t, "make test &", entry.Command)
+	assert.Equal(t, 2, entry.ExitCode)
+	assert.Equal(t, int64(1500), entry.DurationMs)
+
+	// Unknown jobs are an error
+	_, err = run("--amend", "1234-1700000000", "9999")
+	assert.Error(t, err)
+
+	// Amend by ID with a note, the audit trail lists every change
+	idArg := strconv.FormatInt(entry.ID, 10)
+	output, err = run("--amend", "--id", idArg, "--note", "flaky test")
+	require.NoError(t, err, output)
+
+	output, err = run("--amend", "--id", idArg)
+	require.NoError(t, err, output)
+	assert.Contains(t, output, "note: flaky test")
+	assert.Contains(t, output, `exit_code: "0" -> "2"`)
+	assert.Contains(t, output, `duration_ms: "0" -> "1500"`)
+	assert.Contains(t, output, `note: "" -> "flaky test"`)
+}
+
 // TestHookIdempotency tests that running --init twice doesn't break things
 func TestHookIdempotency(t *testing.T) {
 	tempDir := t.TempDir()